@@ -0,0 +1,121 @@
+package apply
+
+import (
+	"context"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/stats"
+	"jonnyzzz.com/devrig.dev/summary"
+)
+
+// OptionalStep is implemented by steps whose failure does not fail the whole apply
+type OptionalStep interface {
+	Optional() bool
+}
+
+func isRequired(step Step) bool {
+	if optional, ok := step.(OptionalStep); ok {
+		return !optional.Optional()
+	}
+	return true
+}
+
+// Run executes the selected steps in order and records them in the summary. Steps completed by a previous run
+// with the same key are skipped if their result is still in place. The journal is saved after every step.
+// It returns the error of the first failed required step, the following steps are not executed then.
+func Run(ctx context.Context, env Environment, steps []Step, selection Selection, journal *Journal, s *summary.Summary) error {
+	if err := selection.Validate(steps); err != nil {
+		return err
+	}
+
+	for i, step := range steps {
+		required := isRequired(step)
+
+		if reason := selection.excludeReason(step); reason != "" {
+			s.Skip(step.Name(), reason)
+			continue
+		}
+
+		key, err := step.Key(env)
+		if err == nil && journal.IsCompleted(step.ID(), key) && step.Verify(ctx, env) {
+			s.Skip(step.Name(), "already done")
+			continue
+		}
+
+		err = s.Run(step.Name(), required, func() error {
+			if err != nil {
+				return err
+			}
+			if err := step.Run(ctx, env); err != nil {
+				return err
+			}
+			return journal.MarkCompleted(step.ID(), key)
+		})
+
+		if err != nil && required {
+			for _, rest := range steps[i+1:] {
+				s.Skip(rest.Name(), "not executed after the failure")
+			}
+			break
+		}
+	}
+	return s.Err()
+}
+
+// Options configure Provision
+type Options struct {
+	Selection Selection
+	// FromScratch forgets the journal of previous runs, so all steps are executed again
+	FromScratch bool
+}
+
+// Provision loads the journal of the project and runs the steps as Run does.
+// The run is recorded in the statistics of the project, see `devrig stats export`
+func Provision(ctx context.Context, env Environment, steps []Step, options Options, s *summary.Summary) error {
+	journal, err := LoadJournal(env.DevrigHome)
+	if err != nil {
+		return err
+	}
+
+	if options.FromScratch {
+		if err := journal.Reset(); err != nil {
+			return err
+		}
+	}
+
+	ctx, recorder := stats.WithRecorder(ctx)
+	start := time.Now()
+	err = Run(ctx, env, steps, options.Selection, journal, s)
+	recordRun(ctx, env, steps, start, err == nil, s, recorder)
+	return err
+}
+
+// recordRun appends the run to the statistics, a failure to record is not a failure of the run
+func recordRun(ctx context.Context, env Environment, steps []Step, start time.Time, success bool, s *summary.Summary, recorder *stats.Recorder) {
+	ids := map[string]string{}
+	for _, step := range steps {
+		ids[step.Name()] = step.ID()
+	}
+
+	run := stats.Run{
+		Command:   "apply",
+		StartedAt: start.UTC(),
+		Duration:  time.Since(start),
+		Success:   success,
+		Artifacts: recorder.Artifacts(),
+	}
+	for _, step := range s.Steps {
+		run.Steps = append(run.Steps, stats.Step{
+			ID:       ids[step.Name],
+			Name:     step.Name,
+			Status:   step.Status,
+			Duration: step.Duration,
+			Message:  step.Message,
+		})
+	}
+
+	if err := stats.Append(env.DevrigHome, run); err != nil {
+		logging.FromContext(ctx).Warn("Failed to record the run statistics", "error", err)
+	}
+}
@@ -0,0 +1,48 @@
+package apply
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/summary"
+)
+
+// NewApplyCommand creates the apply command that provisions the environment described in devrig.yaml
+func NewApplyCommand(configPath func() string, steps []Step) *cobra.Command {
+	var options Options
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Provision the environment described in devrig.yaml",
+		Long: `Provision the environment described in devrig.yaml.
+
+The completed steps are recorded in .devrig/apply-journal.json, so a run after
+an interruption resumes from the last completed step and skips the results
+that are still in place. Use --from-scratch to run all steps again.
+
+Use --only and --skip with step IDs to run a part of the steps, e.g. to
+prepare the downloads on one machine and finish on another:
+  devrig apply --only devrig-binary
+  devrig apply --skip config`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var s summary.Summary
+			err := Provision(cmd.Context(), NewEnvironment(configPath()), steps, options, &s)
+			s.Print(cmd.Context(), cmd.OutOrStdout())
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&options.FromScratch, "from-scratch", false, "Ignore the journal of previous runs and execute all steps")
+	cmd.Flags().StringSliceVar(&options.Selection.Only, "only", nil, "Execute only the steps with the given IDs: "+stepIDs(steps))
+	cmd.Flags().StringSliceVar(&options.Selection.Skip, "skip", nil, "Do not execute the steps with the given IDs")
+	return cmd
+}
+
+func stepIDs(steps []Step) string {
+	var ids []string
+	for _, step := range steps {
+		ids = append(ids, step.ID())
+	}
+	return strings.Join(ids, ", ")
+}
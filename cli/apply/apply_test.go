@@ -0,0 +1,186 @@
+package apply
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/stats"
+	"jonnyzzz.com/devrig.dev/summary"
+)
+
+// fakeStep counts its executions and fails while failures is positive
+type fakeStep struct {
+	id       string
+	key      string
+	runs     int
+	failures int
+	verified bool
+}
+
+func (s *fakeStep) ID() string                                   { return s.id }
+func (s *fakeStep) Name() string                                 { return "step " + s.id }
+func (s *fakeStep) Key(_ Environment) (string, error)            { return s.key, nil }
+func (s *fakeStep) Verify(_ context.Context, _ Environment) bool { return s.verified }
+
+func (s *fakeStep) Run(_ context.Context, _ Environment) error {
+	s.runs++
+	if s.failures > 0 {
+		s.failures--
+		return fmt.Errorf("interrupted")
+	}
+	return nil
+}
+
+func TestRun_ResumesAfterFailure(t *testing.T) {
+	devrigHome := t.TempDir()
+	env := Environment{DevrigHome: devrigHome}
+	first := &fakeStep{id: "first", key: "1", verified: true}
+	second := &fakeStep{id: "second", key: "1", verified: true, failures: 1}
+	steps := []Step{first, second}
+
+	journal, err := LoadJournal(devrigHome)
+	if err != nil {
+		t.Fatalf("Failed to load journal: %v", err)
+	}
+	if err := Run(context.Background(), env, steps, Selection{}, journal, &summary.Summary{}); err == nil {
+		t.Fatal("Expected the first run to fail")
+	}
+
+	// A new process reads the journal from disk
+	journal, err = LoadJournal(devrigHome)
+	if err != nil {
+		t.Fatalf("Failed to load journal: %v", err)
+	}
+	var s summary.Summary
+	if err := Run(context.Background(), env, steps, Selection{}, journal, &s); err != nil {
+		t.Fatalf("Expected the second run to succeed: %v", err)
+	}
+
+	if first.runs != 1 || second.runs != 2 {
+		t.Errorf("Expected the first step to run once and the second twice, got %d and %d", first.runs, second.runs)
+	}
+	if s.Steps[0].Status != summary.StatusSkipped || s.Steps[1].Status != summary.StatusOK {
+		t.Errorf("Expected the first step to be skipped: %+v", s.Steps)
+	}
+}
+
+func TestRun_RerunsChangedOrMissingResults(t *testing.T) {
+	devrigHome := t.TempDir()
+	env := Environment{DevrigHome: devrigHome}
+	changed := &fakeStep{id: "changed", key: "1", verified: true}
+	missing := &fakeStep{id: "missing", key: "1", verified: true}
+	steps := []Step{changed, missing}
+
+	journal, _ := LoadJournal(devrigHome)
+	if err := Run(context.Background(), env, steps, Selection{}, journal, &summary.Summary{}); err != nil {
+		t.Fatalf("Failed to run: %v", err)
+	}
+
+	changed.key = "2"
+	missing.verified = false
+	if err := Run(context.Background(), env, steps, Selection{}, journal, &summary.Summary{}); err != nil {
+		t.Fatalf("Failed to run: %v", err)
+	}
+
+	if changed.runs != 2 || missing.runs != 2 {
+		t.Errorf("Expected both steps to run again, got %d and %d", changed.runs, missing.runs)
+	}
+}
+
+func TestJournal_Reset(t *testing.T) {
+	devrigHome := t.TempDir()
+	journal, _ := LoadJournal(devrigHome)
+	if err := journal.MarkCompleted("step", "key"); err != nil {
+		t.Fatalf("Failed to save journal: %v", err)
+	}
+	if err := journal.Reset(); err != nil {
+		t.Fatalf("Failed to reset journal: %v", err)
+	}
+
+	journal, _ = LoadJournal(devrigHome)
+	if journal.IsCompleted("step", "key") {
+		t.Error("Expected the journal to be empty after reset")
+	}
+}
+
+// optionalStep is a fakeStep whose failure does not stop the apply
+type optionalStep struct {
+	fakeStep
+}
+
+func (s *optionalStep) Optional() bool { return true }
+
+func TestRun_OptionalStepFailure(t *testing.T) {
+	env := Environment{DevrigHome: t.TempDir()}
+	optional := &optionalStep{fakeStep{id: "fonts", key: "1", failures: 1}}
+	last := &fakeStep{id: "last", key: "1"}
+
+	journal, _ := LoadJournal(env.DevrigHome)
+	var s summary.Summary
+	if err := Run(context.Background(), env, []Step{optional, last}, Selection{}, journal, &s); err != nil {
+		t.Fatalf("Expected optional failures not to fail the run: %v", err)
+	}
+	if last.runs != 1 || s.Steps[0].Status != summary.StatusFailed {
+		t.Errorf("Expected the run to continue after the optional failure: %+v", s.Steps)
+	}
+}
+
+func TestRun_Selection(t *testing.T) {
+	env := Environment{DevrigHome: t.TempDir()}
+	download := &fakeStep{id: "download", key: "1"}
+	install := &fakeStep{id: "install", key: "1"}
+	steps := []Step{download, install}
+
+	journal, _ := LoadJournal(env.DevrigHome)
+	var s summary.Summary
+	if err := Run(context.Background(), env, steps, Selection{Only: []string{"download"}}, journal, &s); err != nil {
+		t.Fatalf("Failed to run: %v", err)
+	}
+	if download.runs != 1 || install.runs != 0 {
+		t.Errorf("Expected only the download step to run, got %d and %d runs", download.runs, install.runs)
+	}
+	if s.Steps[1].Status != summary.StatusSkipped {
+		t.Errorf("Expected the install step to be reported as skipped: %+v", s.Steps)
+	}
+
+	if err := Run(context.Background(), env, steps, Selection{Skip: []string{"download"}}, journal, &summary.Summary{}); err != nil {
+		t.Fatalf("Failed to run: %v", err)
+	}
+	if download.runs != 1 || install.runs != 1 {
+		t.Errorf("Expected only the install step to run, got %d and %d runs", download.runs, install.runs)
+	}
+
+	err := Run(context.Background(), env, steps, Selection{Skip: []string{"unpack"}}, journal, &summary.Summary{})
+	if err == nil || !strings.Contains(err.Error(), "download, install") {
+		t.Errorf("Expected an error listing the known steps, got: %v", err)
+	}
+}
+
+func TestProvision_RecordsStatistics(t *testing.T) {
+	devrigHome := t.TempDir()
+	env := Environment{DevrigHome: devrigHome}
+	steps := []Step{&fakeStep{id: "first", key: "1", verified: true}}
+
+	for i := 0; i < 2; i++ {
+		if err := Provision(context.Background(), env, steps, Options{}, &summary.Summary{}); err != nil {
+			t.Fatalf("Failed to provision: %v", err)
+		}
+	}
+
+	runs, err := stats.Load(devrigHome, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to load statistics: %v", err)
+	}
+	if len(runs) != 2 || !runs[0].Success || runs[0].Command != "apply" {
+		t.Fatalf("Expected two successful apply runs, got %+v", runs)
+	}
+	if runs[0].Steps[0].ID != "first" || runs[0].Steps[0].Status != summary.StatusOK {
+		t.Errorf("Expected the step to run first: %+v", runs[0].Steps)
+	}
+	if runs[1].Steps[0].Status != summary.StatusSkipped {
+		t.Errorf("Expected the step to be reused by the second run: %+v", runs[1].Steps)
+	}
+}
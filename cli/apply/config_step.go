@@ -0,0 +1,29 @@
+package apply
+
+import (
+	"context"
+)
+
+// ConfigStep validates devrig.yaml before anything is provisioned
+type ConfigStep struct{}
+
+func (s *ConfigStep) ID() string {
+	return "config"
+}
+
+func (s *ConfigStep) Name() string {
+	return "Validate devrig.yaml"
+}
+
+func (s *ConfigStep) Key(_ Environment) (string, error) {
+	return "", nil
+}
+
+// Verify always returns false, the validation is cheap and must see the current file
+func (s *ConfigStep) Verify(_ context.Context, _ Environment) bool {
+	return false
+}
+
+func (s *ConfigStep) Run(_ context.Context, env Environment) error {
+	return env.Configs.EnsureValidConfig()
+}
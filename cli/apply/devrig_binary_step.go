@@ -0,0 +1,246 @@
+package apply
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/cache"
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/filelock"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/progress"
+	"jonnyzzz.com/devrig.dev/stats"
+	"jonnyzzz.com/devrig.dev/tempfile"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// DevrigBinaryStep downloads the devrig binary pinned in devrig.yaml for the current platform
+// into the .devrig folder, where the bootstrap scripts look for it
+type DevrigBinaryStep struct {
+	// System overrides the current OS and architecture, used in tests
+	System updates.SystemInfo
+	// Source is recorded in the metadata of the binary, layout.DevrigBinarySourceApply by default
+	Source string
+}
+
+func (s *DevrigBinaryStep) ID() string {
+	return "devrig-binary"
+}
+
+func (s *DevrigBinaryStep) Name() string {
+	return "Download devrig binary"
+}
+
+func (s *DevrigBinaryStep) Key(env Environment) (string, error) {
+	_, binary, _, err := s.resolve(env)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(binary.SHA512), nil
+}
+
+func (s *DevrigBinaryStep) Verify(_ context.Context, env Environment) bool {
+	target, binary, _, err := s.resolve(env)
+	if err != nil {
+		return false
+	}
+	return verifySHA512(target, binary.SHA512) == nil
+}
+
+func (s *DevrigBinaryStep) Run(ctx context.Context, env Environment) error {
+	target, binary, version, err := s.resolve(env)
+	if err != nil {
+		return err
+	}
+
+	name := filepath.Base(filepath.Dir(target))
+	if verifySHA512(target, binary.SHA512) == nil {
+		logging.FromContext(ctx).Debug("devrig binary is up to date", "path", target)
+		recordArtifact(ctx, name, target, true)
+		return nil
+	}
+
+	// Concurrent runs share the temporary file, the one waiting for the lock finds the binary in place
+	lock, err := filelock.Acquire(ctx, layout.ResolveLockFile(env.DevrigHome, name), "downloading "+name)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	if verifySHA512(target, binary.SHA512) == nil {
+		recordArtifact(ctx, name, target, true)
+		return nil
+	}
+
+	if err := offline.Check("devrig binary "+name, binary.URL,
+		fmt.Sprintf("download %s, check its SHA-512 is %s and copy it to %s", binary.URL, strings.ToLower(binary.SHA512), target)); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(env.DevrigHome, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", env.DevrigHome, err)
+	}
+
+	// Same temporary name as the bootstrap scripts use
+	tempFile := filepath.Dir(target) + "-downloading"
+	tempfile.Track(tempFile)
+	//goland:noinspection GoUnhandledErrorResult
+	defer tempfile.Remove(tempFile)
+
+	if err := downloadFromMirrors(ctx, binary, tempFile); err != nil {
+		return err
+	}
+
+	system := s.system()
+	if _, err := layout.InstallDevrigBinary(env.DevrigHome, tempFile, layout.DevrigBinaryMetadata{
+		OS:      system.OS(),
+		Arch:    system.Arch(),
+		SHA512:  binary.SHA512,
+		Version: version,
+		URL:     binary.URL,
+		Source:  s.source(),
+	}); err != nil {
+		return err
+	}
+
+	logging.FromContext(ctx).Info("Installed devrig binary to " + target)
+	recordArtifact(ctx, name, target, false)
+
+	// Every upgrade adds a binary to .devrig, the old ones are not needed once the new one is in place
+	if err := cache.EnforceDevrigBinaryRetention(ctx, env.Configs, env.DevrigHome); err != nil {
+		logging.FromContext(ctx).Warn("Failed to remove old devrig binaries", "error", err)
+	}
+	return nil
+}
+
+// recordArtifact reports the binary to the statistics of the run
+func recordArtifact(ctx context.Context, name string, path string, cached bool) {
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	stats.RecordArtifact(ctx, stats.Artifact{Name: name, Size: size, Cached: cached})
+}
+
+func (s *DevrigBinaryStep) source() string {
+	if s.Source == "" {
+		return layout.DevrigBinarySourceApply
+	}
+	return s.Source
+}
+
+func (s *DevrigBinaryStep) system() updates.SystemInfo {
+	if s.System == nil {
+		return updates.CurrentSystem{}
+	}
+	return s.System
+}
+
+// resolve returns the path of the binary in the .devrig folder, the pinned binary and the pinned version
+func (s *DevrigBinaryStep) resolve(env Environment) (string, configservice.BinaryInfo, string, error) {
+	system := s.system()
+	section, err := env.Configs.Binaries().ReadDevrigSection()
+	if err != nil {
+		return "", configservice.BinaryInfo{}, "", err
+	}
+
+	binary, ok := section.Binaries[system.OS()+"-"+system.Arch()]
+	if !ok {
+		return "", configservice.BinaryInfo{}, "", &devrigErrors.UnsupportedPlatformError{OS: system.OS(), Arch: system.Arch()}
+	}
+
+	return layout.ResolveDevrigBinary(env.DevrigHome, system.OS(), system.Arch(), binary.SHA512), binary, section.Version, nil
+}
+
+// downloadFromMirrors downloads the binary from the primary URL and falls back to the mirrors
+// in order when the download fails or the checksum does not match
+func downloadFromMirrors(ctx context.Context, binary configservice.BinaryInfo, target string) error {
+	logger := logging.FromContext(ctx)
+	urls := binary.URLs()
+
+	var lastErr error
+	for i, url := range urls {
+		if i > 0 {
+			logger.Warn(fmt.Sprintf("Download failed, trying mirror %d of %d: %s", i, len(urls)-1, url), "error", lastErr)
+		}
+
+		lastErr = downloadFile(ctx, url, target)
+		if lastErr == nil {
+			lastErr = verifySHA512(target, binary.SHA512)
+		}
+		if lastErr == nil {
+			return nil
+		}
+	}
+
+	if len(urls) > 1 {
+		return fmt.Errorf("failed to download from all %d locations: %w", len(urls), lastErr)
+	}
+	return lastErr
+}
+
+// downloadFile downloads the URL to the target file with progress reporting
+func downloadFile(ctx context.Context, url string, target string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", &devrigErrors.NetworkError{URL: url, Err: err})
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &devrigErrors.NetworkError{URL: url, Err: fmt.Errorf("unexpected status code: %d", resp.StatusCode)}
+	}
+
+	// Only the user can read the partial download, it becomes executable once verified
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", target, err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer out.Close()
+
+	tracker := progress.Track(ctx, "Downloading "+filepath.Base(url), resp.ContentLength, 0)
+	_, err = io.Copy(out, tracker.Reader(resp.Body))
+	tracker.Finish()
+	if err != nil {
+		return fmt.Errorf("failed to write to file %s: %w", target, err)
+	}
+
+	return out.Close()
+}
+
+// verifySHA512 checks that the file exists and matches the expected SHA-512 hash
+func verifySHA512(path string, expected string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer file.Close()
+
+	hash := sha512.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return fmt.Errorf("failed to calculate checksum of %s: %w", path, err)
+	}
+
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if !strings.EqualFold(actual, expected) {
+		return &devrigErrors.ChecksumMismatchError{Subject: path, Expected: strings.ToLower(expected), Actual: actual}
+	}
+	return nil
+}
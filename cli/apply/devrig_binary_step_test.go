@@ -0,0 +1,176 @@
+package apply
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/offline"
+)
+
+type testSystem struct{}
+
+func (testSystem) OS() string   { return "linux" }
+func (testSystem) Arch() string { return "x86_64" }
+
+func writeConfig(t *testing.T, url string, sha string) Environment {
+	t.Helper()
+	projectDir := t.TempDir()
+	configPath := filepath.Join(projectDir, "devrig.yaml")
+	config := "devrig:\n  binaries:\n    linux-x86_64:\n      url: " + url + "\n      sha512: " + sha + "\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	return NewEnvironment(configPath)
+}
+
+func TestDevrigBinaryStep_DownloadsAndVerifies(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho devrig\n")
+	hash := sha512.Sum512(binary)
+	sha := hex.EncodeToString(hash[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(binary)
+	}))
+	defer server.Close()
+
+	env := writeConfig(t, server.URL+"/devrig-linux-x86_64", sha)
+	step := &DevrigBinaryStep{System: testSystem{}}
+
+	if step.Verify(context.Background(), env) {
+		t.Fatal("Expected verification to fail before the download")
+	}
+	if err := step.Run(context.Background(), env); err != nil {
+		t.Fatalf("Failed to run step: %v", err)
+	}
+	if !step.Verify(context.Background(), env) {
+		t.Error("Expected verification to pass after the download")
+	}
+
+	target := filepath.Join(env.DevrigHome, "devrig-linux-x86_64-"+sha, "devrig")
+	if _, err := os.Stat(target); err != nil {
+		t.Errorf("Expected the binary at %s: %v", target, err)
+	}
+	metadata, err := layout.ReadDevrigBinaryMetadata(filepath.Dir(target))
+	if err != nil {
+		t.Fatalf("Failed to read metadata: %v", err)
+	}
+	if metadata.SHA512 != sha || metadata.Source != layout.DevrigBinarySourceApply || metadata.URL != server.URL+"/devrig-linux-x86_64" {
+		t.Errorf("Unexpected metadata: %+v", metadata)
+	}
+}
+
+func TestDevrigBinaryStep_ReplacesPlainFileLayout(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho devrig\n")
+	hash := sha512.Sum512(binary)
+	sha := hex.EncodeToString(hash[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(binary)
+	}))
+	defer server.Close()
+
+	// Older versions stored the binary as a plain file with the name of the folder
+	env := writeConfig(t, server.URL+"/devrig-linux-x86_64", sha)
+	if err := os.MkdirAll(env.DevrigHome, 0755); err != nil {
+		t.Fatalf("Failed to create .devrig: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(env.DevrigHome, "devrig-linux-x86_64-"+sha), binary, 0755); err != nil {
+		t.Fatalf("Failed to write the old binary: %v", err)
+	}
+
+	step := &DevrigBinaryStep{System: testSystem{}}
+	if err := step.Run(context.Background(), env); err != nil {
+		t.Fatalf("Failed to run step: %v", err)
+	}
+	if !step.Verify(context.Background(), env) {
+		t.Error("Expected verification to pass after the download")
+	}
+}
+
+func TestDevrigBinaryStep_ChecksumMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tampered"))
+	}))
+	defer server.Close()
+
+	sha := strings.Repeat("ab", 64)
+	env := writeConfig(t, server.URL+"/devrig-linux-x86_64", sha)
+	err := (&DevrigBinaryStep{System: testSystem{}}).Run(context.Background(), env)
+
+	var mismatch *devrigErrors.ChecksumMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected checksum mismatch, got: %v", err)
+	}
+
+	// The locks folder is kept for the next runs, partial downloads are not
+	entries, _ := os.ReadDir(env.DevrigHome)
+	for _, entry := range entries {
+		if entry.Name() != "locks" {
+			t.Errorf("Expected no files left in .devrig, got %s", entry.Name())
+		}
+	}
+}
+
+func TestDevrigBinaryStep_FallsBackToMirrors(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho devrig\n")
+	hash := sha512.Sum512(binary)
+	sha := hex.EncodeToString(hash[:])
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		switch r.URL.Path {
+		case "/primary":
+			http.NotFound(w, r)
+		case "/tampered":
+			_, _ = w.Write([]byte("tampered"))
+		default:
+			_, _ = w.Write(binary)
+		}
+	}))
+	defer server.Close()
+
+	env := writeConfig(t, server.URL+"/primary", sha)
+	config := "devrig:\n  binaries:\n    linux-x86_64:\n      url: " + server.URL + "/primary\n      sha512: " + sha +
+		"\n      mirrors:\n        - " + server.URL + "/tampered\n        - " + server.URL + "/mirror\n"
+	if err := os.WriteFile(env.ConfigPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+
+	step := &DevrigBinaryStep{System: testSystem{}}
+	if err := step.Run(context.Background(), env); err != nil {
+		t.Fatalf("Failed to run step: %v", err)
+	}
+	if !step.Verify(context.Background(), env) {
+		t.Error("Expected verification to pass after the download")
+	}
+	if strings.Join(requests, ",") != "/primary,/tampered,/mirror" {
+		t.Errorf("Expected the mirrors to be tried in order, got %v", requests)
+	}
+}
+
+func TestDevrigBinaryStep_Offline(t *testing.T) {
+	t.Setenv(offline.EnvOffline, "1")
+	sha := strings.Repeat("ab", 64)
+	env := writeConfig(t, "https://devrig.dev/download/devrig-linux-x86_64", sha)
+
+	err := (&DevrigBinaryStep{System: testSystem{}}).Run(context.Background(), env)
+	var offlineErr *devrigErrors.OfflineError
+	if !errors.As(err, &offlineErr) {
+		t.Fatalf("Expected OfflineError, got: %v", err)
+	}
+	target := filepath.Join(env.DevrigHome, "devrig-linux-x86_64-"+sha, "devrig")
+	if !strings.Contains(err.Error(), target) || !strings.Contains(err.Error(), sha) {
+		t.Errorf("Expected the error to explain how to pre-seed %s, got: %v", target, err)
+	}
+}
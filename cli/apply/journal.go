@@ -0,0 +1,91 @@
+package apply
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// JournalFileName is the name of the apply journal in the .devrig folder
+const JournalFileName = "apply-journal.json"
+
+// JournalEntry records a completed step
+type JournalEntry struct {
+	Key         string    `json:"key"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// Journal persists the completed steps of `devrig apply`, so an interrupted run
+// resumes from the last completed step instead of starting over
+type Journal struct {
+	path  string
+	Steps map[string]JournalEntry `json:"steps"`
+}
+
+// LoadJournal reads the journal from the .devrig folder, a missing journal is empty
+func LoadJournal(devrigHome string) (*Journal, error) {
+	journal := &Journal{
+		path:  filepath.Join(devrigHome, JournalFileName),
+		Steps: map[string]JournalEntry{},
+	}
+
+	data, err := os.ReadFile(journal.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return journal, nil
+		}
+		return nil, fmt.Errorf("failed to read apply journal %s: %w", journal.path, err)
+	}
+
+	if err := json.Unmarshal(data, journal); err != nil {
+		// A broken journal only costs a full run
+		journal.Steps = map[string]JournalEntry{}
+		return journal, nil
+	}
+	if journal.Steps == nil {
+		journal.Steps = map[string]JournalEntry{}
+	}
+	return journal, nil
+}
+
+// IsCompleted checks if the step was completed with the same key
+func (j *Journal) IsCompleted(id string, key string) bool {
+	entry, ok := j.Steps[id]
+	return ok && entry.Key == key
+}
+
+// MarkCompleted records the step as completed and saves the journal
+func (j *Journal) MarkCompleted(id string, key string) error {
+	j.Steps[id] = JournalEntry{Key: key, CompletedAt: time.Now().UTC()}
+	return j.save()
+}
+
+// Reset forgets all completed steps
+func (j *Journal) Reset() error {
+	j.Steps = map[string]JournalEntry{}
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove apply journal %s: %w", j.path, err)
+	}
+	return nil
+}
+
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize apply journal: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", j.path, err)
+	}
+
+	// Write to a temporary file first, so an interruption never leaves a truncated journal
+	if err := tempfile.WriteFile(j.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write apply journal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package apply
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Selection limits the steps executed by Run, the zero value selects all steps.
+// It lets CI pipelines split the phases, e.g. download on one machine and install on another.
+type Selection struct {
+	// Only lists the IDs of the steps to execute, all steps if empty
+	Only []string
+	// Skip lists the IDs of the steps not to execute
+	Skip []string
+}
+
+// Validate checks that all IDs refer to existing steps
+func (sel Selection) Validate(steps []Step) error {
+	var ids []string
+	for _, step := range steps {
+		ids = append(ids, step.ID())
+	}
+
+	for _, id := range append(slices.Clone(sel.Only), sel.Skip...) {
+		if !slices.Contains(ids, id) {
+			return fmt.Errorf("unknown step: %s (expected one of %s)", id, strings.Join(ids, ", "))
+		}
+	}
+	return nil
+}
+
+// excludeReason returns why the step is not selected, or an empty string if it is
+func (sel Selection) excludeReason(step Step) string {
+	if slices.Contains(sel.Skip, step.ID()) {
+		return "excluded by --skip"
+	}
+	if len(sel.Only) > 0 && !slices.Contains(sel.Only, step.ID()) {
+		return "not selected by --only"
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package apply
+
+import (
+	"context"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+// Environment gives steps access to the project being provisioned
+type Environment struct {
+	ConfigPath string
+	DevrigHome string
+	Configs    configservice.ConfigService
+}
+
+// NewEnvironment creates the Environment for the given devrig.yaml path
+func NewEnvironment(configPath string) Environment {
+	return Environment{
+		ConfigPath: configPath,
+		DevrigHome: layout.ResolveDevrigHome(configPath),
+		Configs:    configservice.NewConfigService(configPath),
+	}
+}
+
+// DefaultSteps returns the steps of `devrig apply` in the order of execution
+func DefaultSteps() []Step {
+	return []Step{
+		&ConfigStep{},
+		&DevrigBinaryStep{},
+	}
+}
+
+// Step is a single idempotent provisioning action executed by `devrig apply`
+type Step interface {
+	// ID returns a stable identifier of the step used in the journal
+	ID() string
+	// Name returns a short human-readable name of the step
+	Name() string
+	// Key returns a fingerprint of the step inputs, a completed step is executed
+	// again when its key changes, e.g. when a pinned version is updated
+	Key(env Environment) (string, error)
+	// Verify checks that the result of a previously completed step is still in place
+	Verify(ctx context.Context, env Environment) bool
+	// Run executes the step
+	Run(ctx context.Context, env Environment) error
+}
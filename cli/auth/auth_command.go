@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/configservice"
+)
+
+// NewAuthCommand creates the auth command group
+func NewAuthCommand(configs func() configservice.ConfigService) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Log in to SSO-protected artifact hosts",
+		Long: `Log in to artifact hosts protected with OAuth, as configured in the auth section of devrig.yaml:
+
+  auth:
+    providers:
+      - name: corp
+        hosts: [artifacts.example.com, "*.cdn.example.com"]
+        client_id: devrig
+        device_authorization_url: https://sso.example.com/oauth/device/code
+        token_url: https://sso.example.com/oauth/token
+        scopes: [artifacts.read]
+
+The token is cached in the OS keychain and attached to all downloads from the hosts of the provider.
+Tokens are bound to the name, client_id, token_url and hosts of the provider, log in again after changing them.
+`,
+	}
+	cmd.AddCommand(newLoginCommand(configs))
+	cmd.AddCommand(newLogoutCommand(configs))
+	cmd.AddCommand(newStatusCommand(configs))
+	return cmd
+}
+
+func newLoginCommand(configs func() configservice.ConfigService) *cobra.Command {
+	return &cobra.Command{
+		Use:   "login [provider]",
+		Short: "Log in to a provider with the device-code flow",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider, err := resolveProvider(configs(), args)
+			if err != nil {
+				return err
+			}
+
+			token, err := NewDeviceFlow(*provider, nil).Login(cmd.Context(), cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+
+			store := NewTokenStore()
+			if err := saveToken(store, *provider, token); err != nil {
+				return err
+			}
+			cmd.Printf("Logged in to %s, the token is stored in %s\n", provider.Name, store.Description())
+			return nil
+		},
+	}
+}
+
+func newLogoutCommand(configs func() configservice.ConfigService) *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout [provider]",
+		Short: "Remove the cached token of a provider",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider, err := resolveProvider(configs(), args)
+			if err != nil {
+				return err
+			}
+
+			if err := deleteToken(NewTokenStore(), *provider); err != nil {
+				return err
+			}
+			cmd.Printf("Logged out of %s\n", provider.Name)
+			return nil
+		},
+	}
+}
+
+func newStatusCommand(configs func() configservice.ConfigService) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the providers and their login state",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			section, err := configs().Auth().ReadAuth()
+			if err != nil {
+				return err
+			}
+			if len(section.Providers) == 0 {
+				cmd.Println("No auth providers are configured in devrig.yaml")
+				return nil
+			}
+
+			return printStatus(cmd, section.Providers, NewTokenStore(), time.Now())
+		},
+	}
+}
+
+// printStatus prints the login state of every provider
+func printStatus(cmd *cobra.Command, providers []configservice.AuthProvider, store TokenStore, now time.Time) error {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PROVIDER\tHOSTS\tSTATUS")
+	for _, provider := range providers {
+		token, err := loadToken(store, provider)
+		status := "not logged in"
+		switch {
+		case err != nil:
+			status = fmt.Sprintf("error: %v", err)
+		case token.Valid(now):
+			status = "logged in"
+			if !token.Expiry.IsZero() {
+				status += ", expires " + token.Expiry.Local().Format(time.RFC3339)
+			}
+		case token != nil && token.RefreshToken != "":
+			status = "expired, refreshed on the next download"
+		case token != nil:
+			status = "expired"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", provider.Name, strings.Join(provider.Hosts, ", "), status)
+	}
+	return w.Flush()
+}
+
+// resolveProvider returns the provider by name, the name can be omitted when there is only one provider
+func resolveProvider(configs configservice.ConfigService, args []string) (*configservice.AuthProvider, error) {
+	section, err := configs.Auth().ReadAuth()
+	if err != nil {
+		return nil, err
+	}
+	if len(section.Providers) == 0 {
+		return nil, fmt.Errorf("no auth providers are configured in devrig.yaml")
+	}
+
+	if len(args) == 0 {
+		if len(section.Providers) > 1 {
+			return nil, fmt.Errorf("several auth providers are configured, specify one of: %s", providerNames(section))
+		}
+		return &section.Providers[0], nil
+	}
+
+	provider := section.FindProvider(args[0])
+	if provider == nil {
+		return nil, fmt.Errorf("unknown auth provider %s, expected one of: %s", args[0], providerNames(section))
+	}
+	return provider, nil
+}
+
+func providerNames(section *configservice.AuthSection) string {
+	names := make([]string, 0, len(section.Providers))
+	for _, provider := range section.Providers {
+		names = append(names, provider.Name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,276 @@
+// Package auth implements downloads from SSO-protected artifact hosts.
+// Tokens are obtained with the OAuth 2.0 device authorization grant (RFC 8628),
+// cached in the OS keychain and attached to the requests to the hosts of the provider.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/offline"
+)
+
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// defaultPollInterval is used when the provider does not specify the polling interval
+const defaultPollInterval = 5 * time.Second
+
+// Token is an OAuth token of a provider
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+	// Provider is the fingerprint of the provider settings the token was issued for, it is not used with other ones
+	Provider string `json:"provider,omitempty"`
+}
+
+// Valid checks if the access token can be used, tokens expiring within a minute are treated as expired
+func (t *Token) Valid(now time.Time) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || now.Add(time.Minute).Before(t.Expiry)
+}
+
+// AuthorizationHeader returns the value of the Authorization header for the token
+func (t *Token) AuthorizationHeader() string {
+	tokenType := t.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + t.AccessToken
+}
+
+// DeviceCode is the response of the device authorization endpoint
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	// VerificationURL is the non-standard name of VerificationURI used by some providers
+	VerificationURL string `json:"verification_url,omitempty"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval,omitempty"`
+}
+
+// tokenResponse is the response of the token endpoint, either a token or an error
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// DeviceFlow obtains and refreshes tokens of a provider
+type DeviceFlow struct {
+	Provider configservice.AuthProvider
+	Client   *http.Client
+
+	// now and wait are replaced in tests
+	now  func() time.Time
+	wait func(ctx context.Context, d time.Duration) error
+}
+
+// NewDeviceFlow creates the device-code flow for the provider
+func NewDeviceFlow(provider configservice.AuthProvider, client *http.Client) *DeviceFlow {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &DeviceFlow{
+		Provider: provider,
+		Client:   client,
+		now:      time.Now,
+		wait:     sleep,
+	}
+}
+
+// Login runs the device-code flow: prints the code for the user to confirm in the browser
+// and polls the token endpoint until the user approves or denies the request, or the code expires
+func (f *DeviceFlow) Login(ctx context.Context, out io.Writer) (*Token, error) {
+	if err := offline.Check("a token of "+f.Provider.Name, f.Provider.DeviceAuthorizationURL, "log in on a machine with network access"); err != nil {
+		return nil, err
+	}
+
+	code, err := f.requestDeviceCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	verificationURI := code.VerificationURI
+	if verificationURI == "" {
+		verificationURI = code.VerificationURL
+	}
+	_, _ = fmt.Fprintf(out, "To log in to %s, open %s and enter the code %s\n", f.Provider.Name, verificationURI, code.UserCode)
+	if code.VerificationURIComplete != "" {
+		_, _ = fmt.Fprintf(out, "or open %s\n", code.VerificationURIComplete)
+	}
+
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	deadline := f.now().Add(time.Duration(code.ExpiresIn) * time.Second)
+
+	for {
+		if code.ExpiresIn > 0 && f.now().After(deadline) {
+			return nil, fmt.Errorf("the device code of %s expired, run the login again", f.Provider.Name)
+		}
+		if err := f.wait(ctx, interval); err != nil {
+			return nil, err
+		}
+
+		response, err := f.requestToken(ctx, url.Values{
+			"grant_type":  {deviceCodeGrantType},
+			"device_code": {code.DeviceCode},
+			"client_id":   {f.Provider.ClientID},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		switch response.Error {
+		case "":
+			return f.toToken(response), nil
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			// RFC 8628 requires to increase the interval by 5 seconds
+			interval += 5 * time.Second
+			continue
+		case "access_denied":
+			return nil, fmt.Errorf("the login to %s was denied", f.Provider.Name)
+		case "expired_token":
+			return nil, fmt.Errorf("the device code of %s expired, run the login again", f.Provider.Name)
+		default:
+			return nil, fmt.Errorf("failed to obtain a token of %s: %s", f.Provider.Name, describeError(response))
+		}
+	}
+}
+
+// Refresh obtains a new access token with the refresh token
+func (f *DeviceFlow) Refresh(ctx context.Context, token *Token) (*Token, error) {
+	if token == nil || token.RefreshToken == "" {
+		return nil, fmt.Errorf("no refresh token for %s", f.Provider.Name)
+	}
+
+	response, err := f.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+		"client_id":     {f.Provider.ClientID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("failed to refresh the token of %s: %s", f.Provider.Name, describeError(response))
+	}
+
+	refreshed := f.toToken(response)
+	// Providers may keep the refresh token and not return it again
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = token.RefreshToken
+	}
+	return refreshed, nil
+}
+
+func (f *DeviceFlow) requestDeviceCode(ctx context.Context) (*DeviceCode, error) {
+	form := url.Values{"client_id": {f.Provider.ClientID}}
+	if len(f.Provider.Scopes) > 0 {
+		form.Set("scope", strings.Join(f.Provider.Scopes, " "))
+	}
+
+	var code DeviceCode
+	status, err := f.postForm(ctx, f.Provider.DeviceAuthorizationURL, form, &code)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, &devrigErrors.NetworkError{URL: f.Provider.DeviceAuthorizationURL, Err: fmt.Errorf("unexpected status code: %d", status)}
+	}
+	if code.DeviceCode == "" || code.UserCode == "" {
+		return nil, fmt.Errorf("the device authorization response of %s has no device or user code", f.Provider.Name)
+	}
+	return &code, nil
+}
+
+func (f *DeviceFlow) requestToken(ctx context.Context, form url.Values) (*tokenResponse, error) {
+	var response tokenResponse
+	status, err := f.postForm(ctx, f.Provider.TokenURL, form, &response)
+	if err != nil {
+		return nil, err
+	}
+	// Errors of the flow come with 400 and a JSON body, anything else is a failure of the endpoint
+	if status != http.StatusOK && response.Error == "" {
+		return nil, &devrigErrors.NetworkError{URL: f.Provider.TokenURL, Err: fmt.Errorf("unexpected status code: %d", status)}
+	}
+	return &response, nil
+}
+
+// postForm posts the form and decodes the JSON response into target, returning the status code
+func (f *DeviceFlow) postForm(ctx context.Context, endpoint string, form url.Values, target interface{}) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call %s: %w", endpoint, &devrigErrors.NetworkError{URL: endpoint, Err: err})
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response of %s: %w", endpoint, err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, target); err != nil && resp.StatusCode == http.StatusOK {
+			return 0, fmt.Errorf("failed to decode response of %s: %w", endpoint, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+func (f *DeviceFlow) toToken(response *tokenResponse) *Token {
+	token := &Token{
+		AccessToken:  response.AccessToken,
+		TokenType:    response.TokenType,
+		RefreshToken: response.RefreshToken,
+	}
+	if response.ExpiresIn > 0 {
+		token.Expiry = f.now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	}
+	return token
+}
+
+func describeError(response *tokenResponse) string {
+	if response.Description != "" {
+		return response.Error + ": " + response.Description
+	}
+	return response.Error
+}
+
+// sleep waits for the duration or until the context is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+)
+
+// newTestProvider starts a fake SSO server, the token endpoint answers with the responses in order
+func newTestProvider(t *testing.T, tokenResponses ...map[string]interface{}) (configservice.AuthProvider, *[]string) {
+	var grants []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse form: %v", err)
+		}
+		if r.Form.Get("client_id") != "devrig" || r.Form.Get("scope") != "read write" {
+			t.Errorf("Unexpected device request: %v", r.Form)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":      "device-123",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": "https://sso.example.com/activate",
+			"expires_in":       600,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("Failed to parse form: %v", err)
+		}
+		grants = append(grants, r.Form.Get("grant_type"))
+		if len(tokenResponses) == 0 {
+			t.Errorf("Unexpected token request: %v", r.Form)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		response := tokenResponses[0]
+		tokenResponses = tokenResponses[1:]
+		if _, failed := response["error"]; failed {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return configservice.AuthProvider{
+		Name:                   "corp",
+		Hosts:                  []string{"artifacts.example.com"},
+		ClientID:               "devrig",
+		DeviceAuthorizationURL: server.URL + "/device",
+		TokenURL:               server.URL + "/token",
+		Scopes:                 []string{"read", "write"},
+	}, &grants
+}
+
+// newTestFlow creates a flow that does not wait between the polls
+func newTestFlow(provider configservice.AuthProvider, waits *[]time.Duration) *DeviceFlow {
+	flow := NewDeviceFlow(provider, nil)
+	flow.wait = func(ctx context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	return flow
+}
+
+func TestDeviceFlow_Login(t *testing.T) {
+	provider, grants := newTestProvider(t,
+		map[string]interface{}{"error": "authorization_pending"},
+		map[string]interface{}{"error": "slow_down"},
+		map[string]interface{}{"access_token": "secret", "token_type": "bearer", "refresh_token": "refresh", "expires_in": 3600},
+	)
+
+	var waits []time.Duration
+	var out bytes.Buffer
+	token, err := newTestFlow(provider, &waits).Login(context.Background(), &out)
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+
+	if token.AccessToken != "secret" || token.RefreshToken != "refresh" || !token.Valid(time.Now()) {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+	if token.AuthorizationHeader() != "Bearer secret" {
+		t.Errorf("Unexpected Authorization header: %s", token.AuthorizationHeader())
+	}
+	if !strings.Contains(out.String(), "ABCD-EFGH") || !strings.Contains(out.String(), "https://sso.example.com/activate") {
+		t.Errorf("Expected the user code and the verification URI in the output, got: %s", out.String())
+	}
+	if len(*grants) != 3 || (*grants)[0] != deviceCodeGrantType {
+		t.Errorf("Expected 3 device code polls, got %v", *grants)
+	}
+
+	// slow_down increases the interval by 5 seconds
+	expected := []time.Duration{time.Second, time.Second, 6 * time.Second}
+	if len(waits) != len(expected) {
+		t.Fatalf("Expected waits %v, got %v", expected, waits)
+	}
+	for i := range expected {
+		if waits[i] != expected[i] {
+			t.Errorf("Expected waits %v, got %v", expected, waits)
+		}
+	}
+}
+
+func TestDeviceFlow_LoginDenied(t *testing.T) {
+	provider, _ := newTestProvider(t, map[string]interface{}{"error": "access_denied"})
+
+	var waits []time.Duration
+	_, err := newTestFlow(provider, &waits).Login(context.Background(), &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("Expected the login to be denied, got: %v", err)
+	}
+}
+
+func TestDeviceFlow_Refresh(t *testing.T) {
+	provider, grants := newTestProvider(t,
+		map[string]interface{}{"access_token": "fresh", "expires_in": 60},
+	)
+
+	var waits []time.Duration
+	token, err := newTestFlow(provider, &waits).Refresh(context.Background(), &Token{AccessToken: "old", RefreshToken: "refresh"})
+	if err != nil {
+		t.Fatalf("Failed to refresh token: %v", err)
+	}
+	if token.AccessToken != "fresh" || token.RefreshToken != "refresh" {
+		t.Errorf("Expected the new access token with the old refresh token, got %+v", token)
+	}
+	if len(*grants) != 1 || (*grants)[0] != "refresh_token" {
+		t.Errorf("Expected a refresh_token grant, got %v", *grants)
+	}
+}
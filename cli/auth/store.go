@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// keychainService is the service name of the tokens in the OS keychain
+const keychainService = "devrig"
+
+// TokenStore keeps the tokens of the providers between runs, the store is shared by all projects of the machine.
+// Tokens are stored under the key of storeKey, use loadToken and saveToken to access them
+type TokenStore interface {
+	// Load returns the token stored under the key, or nil if there is none
+	Load(key string) (*Token, error)
+	Save(key string, token *Token) error
+	Delete(key string) error
+	// Description tells the user where the tokens are kept
+	Description() string
+}
+
+// providerFingerprint is the hex SHA-256 of the provider settings that decide where the tokens are sent.
+// Another project may declare a provider of the same name with its own hosts or token endpoint,
+// it must not receive the tokens of this one
+func providerFingerprint(provider configservice.AuthProvider) string {
+	hosts := make([]string, 0, len(provider.Hosts))
+	for _, host := range provider.Hosts {
+		hosts = append(hosts, strings.ToLower(host))
+	}
+	sort.Strings(hosts)
+
+	data, _ := json.Marshal([]interface{}{provider.Name, provider.ClientID, provider.TokenURL, hosts})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// storeKey returns the key of the tokens of the provider in the store, e.g. corp-0123456789abcdef
+func storeKey(provider configservice.AuthProvider) string {
+	return provider.Name + "-" + providerFingerprint(provider)[:16]
+}
+
+// loadToken returns the token of the provider, or nil if there is none or it was issued for other provider settings
+func loadToken(store TokenStore, provider configservice.AuthProvider) (*Token, error) {
+	token, err := store.Load(storeKey(provider))
+	if err != nil || token == nil {
+		return nil, err
+	}
+	if token.Provider != providerFingerprint(provider) {
+		return nil, nil
+	}
+	return token, nil
+}
+
+// saveToken records the provider settings in the token and saves it
+func saveToken(store TokenStore, provider configservice.AuthProvider, token *Token) error {
+	token.Provider = providerFingerprint(provider)
+	return store.Save(storeKey(provider), token)
+}
+
+// deleteToken removes the token of the provider
+func deleteToken(store TokenStore, provider configservice.AuthProvider) error {
+	return store.Delete(storeKey(provider))
+}
+
+// NewTokenStore returns the OS keychain when it is available, and a file in the user config folder otherwise
+func NewTokenStore() TokenStore {
+	if store := newKeychainStore(runtime.GOOS); store != nil {
+		return store
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return &FileStore{Dir: filepath.Join(dir, "devrig", "tokens")}
+}
+
+// FileStore keeps tokens in files readable only by the current user, for machines without a keychain
+type FileStore struct {
+	Dir string
+}
+
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.Dir, key+".json")
+}
+
+// Load returns the token stored under the key, or nil if there is none
+func (s *FileStore) Load(key string) (*Token, error) {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read token of %s: %w", key, err)
+	}
+
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token of %s: %w", key, err)
+	}
+	return &token, nil
+}
+
+// Save writes the token under the key
+func (s *FileStore) Save(key string, token *Token) error {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return fmt.Errorf("failed to create tokens directory: %w", err)
+	}
+
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+	if err := tempfile.WriteSecretFile(s.path(key), data); err != nil {
+		return fmt.Errorf("failed to write token of %s: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes the token stored under the key
+func (s *FileStore) Delete(key string) error {
+	if err := tempfile.Shred(s.path(key)); err != nil {
+		return fmt.Errorf("failed to remove token of %s: %w", key, err)
+	}
+	return nil
+}
+
+// Description tells the user where the tokens are kept
+func (s *FileStore) Description() string {
+	return s.Dir
+}
+
+// commandRunner runs the command with the stdin and returns its stdout
+type commandRunner func(stdin string, name string, args ...string) (string, error)
+
+// errNotFound is returned by the runner when the keychain has no such item
+var errNotFound = errors.New("not found in the keychain")
+
+// KeychainStore keeps tokens in the macOS Keychain or in the Secret Service on Linux
+type KeychainStore struct {
+	goos string
+	run  commandRunner
+}
+
+// newKeychainStore returns the keychain of the OS, or nil when it is not available
+func newKeychainStore(goos string) *KeychainStore {
+	switch goos {
+	case "darwin":
+		if _, err := exec.LookPath("security"); err != nil {
+			return nil
+		}
+	case "linux":
+		// The Secret Service needs a desktop session, headless machines and CI agents have none
+		if _, err := exec.LookPath("secret-tool"); err != nil || os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return &KeychainStore{goos: goos, run: runCommand}
+}
+
+// Load returns the token stored under the key, or nil if there is none
+func (s *KeychainStore) Load(key string) (*Token, error) {
+	var secret string
+	var err error
+	switch s.goos {
+	case "darwin":
+		secret, err = s.run("", "security", "find-generic-password", "-s", keychainService, "-a", key, "-w")
+	default:
+		secret, err = s.run("", "secret-tool", "lookup", "service", keychainService, "account", key)
+	}
+	if errors.Is(err, errNotFound) || (err == nil && strings.TrimSpace(secret) == "") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token of %s from the keychain: %w", key, err)
+	}
+
+	// Tokens are stored base64-encoded to avoid quoting issues in the keychain tools
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(secret))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode token of %s: %w", key, err)
+	}
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to parse token of %s: %w", key, err)
+	}
+	return &token, nil
+}
+
+// Save writes the token under the key, the secret is passed via stdin to keep it out of the process list
+func (s *KeychainStore) Save(key string, token *Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+	secret := base64.StdEncoding.EncodeToString(data)
+
+	switch s.goos {
+	case "darwin":
+		_, err = s.run(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", keychainService, key, secret), "security", "-i")
+	default:
+		_, err = s.run(secret, "secret-tool", "store", "--label=devrig "+key, "service", keychainService, "account", key)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save token of %s to the keychain: %w", key, err)
+	}
+	return nil
+}
+
+// Delete removes the token stored under the key
+func (s *KeychainStore) Delete(key string) error {
+	var err error
+	switch s.goos {
+	case "darwin":
+		_, err = s.run("", "security", "delete-generic-password", "-s", keychainService, "-a", key)
+	default:
+		_, err = s.run("", "secret-tool", "clear", "service", keychainService, "account", key)
+	}
+	if err != nil && !errors.Is(err, errNotFound) {
+		return fmt.Errorf("failed to remove token of %s from the keychain: %w", key, err)
+	}
+	return nil
+}
+
+// Description tells the user where the tokens are kept
+func (s *KeychainStore) Description() string {
+	if s.goos == "darwin" {
+		return "macOS Keychain"
+	}
+	return "Secret Service keyring"
+}
+
+func runCommand(stdin string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		// security exits with 44 for missing items, secret-tool with 1 and no output
+		if errors.As(err, &exitErr) && (exitErr.ExitCode() == 44 || (name == "secret-tool" && stdout.Len() == 0 && stderr.Len() == 0)) {
+			return "", errNotFound
+		}
+		return "", fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	store := &FileStore{Dir: filepath.Join(t.TempDir(), "tokens")}
+
+	token, err := store.Load("corp")
+	if err != nil || token != nil {
+		t.Fatalf("Expected no token, got %+v, %v", token, err)
+	}
+
+	expiry := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if err := store.Save("corp", &Token{AccessToken: "secret", RefreshToken: "refresh", Expiry: expiry}); err != nil {
+		t.Fatalf("Failed to save token: %v", err)
+	}
+
+	token, err = store.Load("corp")
+	if err != nil {
+		t.Fatalf("Failed to load token: %v", err)
+	}
+	if token.AccessToken != "secret" || token.RefreshToken != "refresh" || !token.Expiry.Equal(expiry) {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filepath.Join(store.Dir, "corp.json"))
+		if err != nil {
+			t.Fatalf("Failed to stat token file: %v", err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("Expected token file mode 0600, got %v", info.Mode().Perm())
+		}
+	}
+
+	if err := store.Delete("corp"); err != nil {
+		t.Fatalf("Failed to delete token: %v", err)
+	}
+	if token, _ := store.Load("corp"); token != nil {
+		t.Errorf("Expected the token to be removed, got %+v", token)
+	}
+}
+
+func TestKeychainStore_SecretViaStdin(t *testing.T) {
+	secrets := map[string]string{}
+	store := &KeychainStore{goos: "linux", run: func(stdin string, name string, args ...string) (string, error) {
+		if strings.Contains(strings.Join(args, " "), "secret") {
+			t.Errorf("The token must not be passed in the arguments: %v", args)
+		}
+		switch args[0] {
+		case "store":
+			secrets[args[len(args)-1]] = stdin
+		case "lookup":
+			secret, ok := secrets[args[len(args)-1]]
+			if !ok {
+				return "", errNotFound
+			}
+			return secret, nil
+		case "clear":
+			delete(secrets, args[len(args)-1])
+		}
+		return "", nil
+	}}
+
+	if err := store.Save("corp", &Token{AccessToken: "secret"}); err != nil {
+		t.Fatalf("Failed to save token: %v", err)
+	}
+	token, err := store.Load("corp")
+	if err != nil || token == nil || token.AccessToken != "secret" {
+		t.Fatalf("Expected the saved token, got %+v, %v", token, err)
+	}
+
+	if err := store.Delete("corp"); err != nil {
+		t.Fatalf("Failed to delete token: %v", err)
+	}
+	if token, err := store.Load("corp"); err != nil || token != nil {
+		t.Errorf("Expected no token after delete, got %+v, %v", token, err)
+	}
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// Transport attaches the tokens of the providers to HTTPS requests to their hosts.
+// Expired tokens are refreshed and saved back to the store.
+type Transport struct {
+	Base      http.RoundTripper
+	Providers []configservice.AuthProvider
+	Store     TokenStore
+
+	now func() time.Time
+
+	mutex  sync.Mutex
+	tokens map[string]*Token
+}
+
+// defaultBase is the transport of the standard library, captured before Install replaces it
+var defaultBase = http.DefaultTransport
+
+// Install wraps http.DefaultTransport, so all downloads of devrig authenticate to the hosts of the providers
+func Install(providers []configservice.AuthProvider, store TokenStore) {
+	if len(providers) == 0 {
+		return
+	}
+
+	base := http.DefaultTransport
+	if installed, ok := base.(*Transport); ok {
+		base = installed.Base
+	}
+	http.DefaultTransport = &Transport{Base: base, Providers: providers, Store: store}
+}
+
+// RoundTrip sends the request, with the token of the provider if the host is protected
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	provider := t.providerFor(req)
+	if provider == nil {
+		return t.base().RoundTrip(req)
+	}
+
+	token, err := t.token(req, provider)
+	if err != nil {
+		return nil, &devrigErrors.AuthRequiredError{Host: req.URL.Hostname(), Provider: provider.Name, Err: err}
+	}
+	if token == nil {
+		return nil, &devrigErrors.AuthRequiredError{Host: req.URL.Hostname(), Provider: provider.Name}
+	}
+
+	// A RoundTripper must not modify the request
+	authorized := req.Clone(req.Context())
+	authorized.Header.Set("Authorization", token.AuthorizationHeader())
+
+	resp, err := t.base().RoundTrip(authorized)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		_ = resp.Body.Close()
+		t.forget(storeKey(*provider))
+		return nil, &devrigErrors.AuthRequiredError{Host: req.URL.Hostname(), Provider: provider.Name,
+			Err: fmt.Errorf("the token was rejected with status %d", resp.StatusCode)}
+	}
+	return resp, nil
+}
+
+// providerFor returns the provider of the request host, tokens are never sent over plain HTTP
+// or when the caller set the Authorization header itself
+func (t *Transport) providerFor(req *http.Request) *configservice.AuthProvider {
+	if req.URL.Scheme != "https" || req.Header.Get("Authorization") != "" {
+		return nil
+	}
+	for i := range t.Providers {
+		if t.Providers[i].MatchesHost(req.URL.Hostname()) {
+			return &t.Providers[i]
+		}
+	}
+	return nil
+}
+
+// token returns a valid token of the provider, refreshing it when needed, or nil if the user has not logged in
+func (t *Transport) token(req *http.Request, provider *configservice.AuthProvider) (*Token, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now
+	if t.now != nil {
+		now = t.now
+	}
+
+	key := storeKey(*provider)
+	token, cached := t.tokens[key]
+	if !cached {
+		var err error
+		if token, err = loadToken(t.Store, *provider); err != nil {
+			return nil, err
+		}
+	}
+	if token.Valid(now()) {
+		t.remember(key, token)
+		return token, nil
+	}
+	if token == nil || token.RefreshToken == "" {
+		return nil, nil
+	}
+
+	// The refresh goes to the token endpoint directly, it is not a protected artifact host
+	flow := NewDeviceFlow(*provider, &http.Client{Transport: t.base(), Timeout: 30 * time.Second})
+	flow.now = now
+	refreshed, err := flow.Refresh(req.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	if err := saveToken(t.Store, *provider, refreshed); err != nil {
+		return nil, err
+	}
+	t.remember(key, refreshed)
+	return refreshed, nil
+}
+
+func (t *Transport) remember(key string, token *Token) {
+	if t.tokens == nil {
+		t.tokens = map[string]*Token{}
+	}
+	t.tokens[key] = token
+}
+
+func (t *Transport) forget(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.tokens, key)
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return defaultBase
+}
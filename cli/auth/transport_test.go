@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// memoryStore keeps tokens in memory
+type memoryStore map[string]*Token
+
+func (s memoryStore) Load(key string) (*Token, error) { return s[key], nil }
+func (s memoryStore) Save(key string, token *Token) error {
+	s[key] = token
+	return nil
+}
+func (s memoryStore) Delete(key string) error {
+	delete(s, key)
+	return nil
+}
+func (s memoryStore) Description() string { return "memory" }
+
+// newProtectedServer starts a TLS server that requires the token and returns the transport to reach it,
+// the token is saved for the provider with the host of the server unless it is nil
+func newProtectedServer(t *testing.T, token *Token, provider configservice.AuthProvider) (*httptest.Server, *Transport, memoryStore) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte("artifact"))
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %v", err)
+	}
+	provider.Hosts = []string{serverURL.Hostname()}
+
+	store := memoryStore{}
+	if token != nil {
+		if err := saveToken(store, provider, token); err != nil {
+			t.Fatalf("Failed to save token: %v", err)
+		}
+	}
+	return server, &Transport{Base: server.Client().Transport, Providers: []configservice.AuthProvider{provider}, Store: store}, store
+}
+
+func TestTransport_AttachesToken(t *testing.T) {
+	token := &Token{AccessToken: "secret", Expiry: time.Now().Add(time.Hour)}
+	server, transport, _ := newProtectedServer(t, token, configservice.AuthProvider{Name: "corp"})
+
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL + "/artifact.zip")
+	if err != nil {
+		t.Fatalf("Failed to download: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestTransport_NotLoggedIn(t *testing.T) {
+	server, transport, _ := newProtectedServer(t, nil, configservice.AuthProvider{Name: "corp"})
+
+	_, err := (&http.Client{Transport: transport}).Get(server.URL + "/artifact.zip")
+	var authErr *devrigErrors.AuthRequiredError
+	if !errors.As(err, &authErr) || authErr.Provider != "corp" {
+		t.Fatalf("Expected AuthRequiredError, got: %v", err)
+	}
+	if devrigErrors.ExitCode(err) != devrigErrors.ExitAuthRequired {
+		t.Errorf("Expected exit code %d, got %d", devrigErrors.ExitAuthRequired, devrigErrors.ExitCode(err))
+	}
+}
+
+func TestTransport_RejectedToken(t *testing.T) {
+	server, transport, _ := newProtectedServer(t, &Token{AccessToken: "revoked"}, configservice.AuthProvider{Name: "corp"})
+
+	_, err := (&http.Client{Transport: transport}).Get(server.URL + "/artifact.zip")
+	var authErr *devrigErrors.AuthRequiredError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("Expected AuthRequiredError for a rejected token, got: %v", err)
+	}
+}
+
+func TestTransport_RefreshesExpiredToken(t *testing.T) {
+	provider, grants := newTestProvider(t, map[string]interface{}{"access_token": "secret", "expires_in": 3600})
+	token := &Token{AccessToken: "expired", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Hour)}
+	server, transport, store := newProtectedServer(t, token, provider)
+
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL + "/artifact.zip")
+	if err != nil {
+		t.Fatalf("Failed to download: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	saved, _ := loadToken(store, transport.Providers[0])
+	if len(*grants) != 1 || saved == nil || saved.AccessToken != "secret" {
+		t.Errorf("Expected the refreshed token to be saved, got %+v after %v", saved, *grants)
+	}
+}
+
+func TestTransport_SkipsOtherHosts(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Expected no token for other hosts, got %s", r.Header.Get("Authorization"))
+		}
+	}))
+	defer server.Close()
+
+	transport := &Transport{
+		Base:      server.Client().Transport,
+		Providers: []configservice.AuthProvider{{Name: "corp", Hosts: []string{"artifacts.example.com"}}},
+		Store:     memoryStore{storeKey(configservice.AuthProvider{Name: "corp", Hosts: []string{"artifacts.example.com"}}): {AccessToken: "secret"}},
+	}
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to download: %v", err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestTransport_TokenOfOtherProviderSettings(t *testing.T) {
+	token := &Token{AccessToken: "secret", RefreshToken: "refresh", Expiry: time.Now().Add(time.Hour)}
+	server, transport, store := newProtectedServer(t, token, configservice.AuthProvider{Name: "corp", TokenURL: "https://sso.example.com/oauth/token"})
+
+	// Another project declares a provider of the same name with its own token endpoint
+	transport.Providers[0].TokenURL = "https://sso.attacker.example/oauth/token"
+	_, err := (&http.Client{Transport: transport}).Get(server.URL + "/artifact.zip")
+	var authErr *devrigErrors.AuthRequiredError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("Expected AuthRequiredError for other provider settings, got: %v", err)
+	}
+
+	// A token stored under the key of the provider but recorded for other settings is refused too
+	store[storeKey(transport.Providers[0])] = &Token{AccessToken: "secret", Provider: "other"}
+	if token, err := loadToken(store, transport.Providers[0]); err != nil || token != nil {
+		t.Errorf("Expected no token for other recorded settings, got %+v, %v", token, err)
+	}
+}
+
+func TestStoreKey(t *testing.T) {
+	provider := configservice.AuthProvider{Name: "corp", ClientID: "devrig", TokenURL: "https://sso.example.com/oauth/token",
+		Hosts: []string{"b.example.com", "a.example.com"}}
+	reordered := provider
+	reordered.Hosts = []string{"A.example.com", "b.example.com"}
+	if storeKey(provider) != storeKey(reordered) {
+		t.Errorf("Expected the order and the case of hosts to be ignored: %s != %s", storeKey(provider), storeKey(reordered))
+	}
+
+	for _, changed := range []configservice.AuthProvider{
+		{Name: "corp", ClientID: "other", TokenURL: provider.TokenURL, Hosts: provider.Hosts},
+		{Name: "corp", ClientID: provider.ClientID, TokenURL: "https://sso.attacker.example/oauth/token", Hosts: provider.Hosts},
+		{Name: "corp", ClientID: provider.ClientID, TokenURL: provider.TokenURL, Hosts: []string{"a.example.com", "attacker.example"}},
+	} {
+		if storeKey(changed) == storeKey(provider) {
+			t.Errorf("Expected another key for %+v", changed)
+		}
+	}
+}
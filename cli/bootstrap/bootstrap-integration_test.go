@@ -168,6 +168,41 @@ func TestParsePS1_AutoDetectLinux(t *testing.T) {
 	})
 }
 
+func TestParseSH_OtherFormats(t *testing.T) {
+	for _, config := range []string{"devrig-example.toml", "devrig-example.json"} {
+		t.Run(config, func(t *testing.T) {
+			runAndAssert(t, Run{
+				env:             Env{"devrig", "ubuntu:18.04"},
+				environmentVars: []string{"DEVRIG_DEBUG_YAML_DOWNLOAD=1", "DEVRIG_CONFIG=" + config, "DEVRIG_CPU=arm64"},
+				commandline:     []string{},
+
+				expectedExitCode: 44,
+				expectedOutput: []string{
+					"https://devrig.dev/download/v1.0.0/devrig-linux-arm64",
+					"d7a8fbb307d7809469ca9abcb0082e4f8d5651e46d3cdb762d02d0bf37c9e592",
+				},
+			})
+		})
+	}
+}
+
+func TestParsePS1_OtherFormats(t *testing.T) {
+	for _, config := range []string{"devrig-example.toml", "devrig-example.json"} {
+		t.Run(config, func(t *testing.T) {
+			runAndAssert(t, Run{
+				env:              Env{"devrig.ps1", "mcr.microsoft.com/dotnet/sdk:8.0"},
+				environmentVars:  []string{"DEVRIG_DEBUG_YAML_DOWNLOAD=1", "DEVRIG_CONFIG=" + config, "DEVRIG_OS=windows", "DEVRIG_CPU=arm64"},
+				commandline:      []string{},
+				expectedExitCode: 44,
+				expectedOutput: []string{
+					"https://devrig.dev/download/v1.0.0/devrig-windows-arm64.exe",
+					"abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890",
+				},
+			})
+		})
+	}
+}
+
 func TestHashMismatch_LocalFile(t *testing.T) {
 	// Generate config with wrong hash
 	configPath := setupTestConfig(t, "mismatch", "https://devrig.dev/", "badhash1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890")
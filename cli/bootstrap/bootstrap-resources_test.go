@@ -85,3 +85,24 @@ func TestCopyBootstrapScripts_NonExistentParent(t *testing.T) {
 		t.Errorf("devrig does not exist in nested directory")
 	}
 }
+
+func TestOutdatedScripts(t *testing.T) {
+	tempDir := t.TempDir()
+	if outdated, err := OutdatedScripts(tempDir); err != nil || len(outdated) != 3 {
+		t.Fatalf("Expected all scripts to be missing, got %v: %v", outdated, err)
+	}
+
+	if err := CopyBootstrapScripts(tempDir); err != nil {
+		t.Fatalf("CopyBootstrapScripts failed: %v", err)
+	}
+	if outdated, err := OutdatedScripts(tempDir); err != nil || len(outdated) != 0 {
+		t.Fatalf("Expected the copied scripts to be up to date, got %v: %v", outdated, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, "devrig.ps1"), []byte("# an older script\n"), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.ps1: %v", err)
+	}
+	if outdated, err := OutdatedScripts(tempDir); err != nil || len(outdated) != 1 || outdated[0] != "devrig.ps1" {
+		t.Errorf("Expected devrig.ps1 to be outdated, got %v: %v", outdated, err)
+	}
+}
@@ -1,6 +1,7 @@
 package bootstrap
 
 import (
+	"bytes"
 	_ "embed"
 	"fmt"
 	"log"
@@ -17,6 +18,17 @@ var devrigBat []byte
 //go:embed devrig.ps1
 var devrigPs1 []byte
 
+// scripts are the bootstrap scripts with their permissions
+var scripts = []struct {
+	name    string
+	content []byte
+	mode    os.FileMode
+}{
+	{"devrig", devrigScript, 0755},
+	{"devrig.bat", devrigBat, 0755},
+	{"devrig.ps1", devrigPs1, 0644},
+}
+
 // CopyBootstrapScripts copies all bootstrap scripts (devrig, devrig.bat, devrig.ps1)
 // to the specified directory with appropriate permissions.
 // Returns an error if any of the target files are symlinks.
@@ -26,16 +38,6 @@ func CopyBootstrapScripts(targetDir string) error {
 		return fmt.Errorf("failed to create target directory: %w", err)
 	}
 
-	scripts := []struct {
-		name    string
-		content []byte
-		mode    os.FileMode
-	}{
-		{"devrig", devrigScript, 0755},
-		{"devrig.bat", devrigBat, 0755},
-		{"devrig.ps1", devrigPs1, 0644},
-	}
-
 	for _, script := range scripts {
 		path := filepath.Join(targetDir, script.name)
 		log.Printf("Writing %s to %s with mode %o\n", script.name, path, script.mode)
@@ -54,3 +56,31 @@ func CopyBootstrapScripts(targetDir string) error {
 	log.Println("Bootstrap scripts created successfully!")
 	return nil
 }
+
+// OutdatedScripts returns the names of the bootstrap scripts that are missing in the directory
+// or differ from the scripts of this version. Symlinks are skipped, CopyBootstrapScripts does not replace them
+func OutdatedScripts(targetDir string) ([]string, error) {
+	var outdated []string
+	for _, script := range scripts {
+		path := filepath.Join(targetDir, script.name)
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			outdated = append(outdated, script.name)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", script.name, err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", script.name, err)
+		}
+		if !bytes.Equal(content, script.content) {
+			outdated = append(outdated, script.name)
+		}
+	}
+	return outdated, nil
+}
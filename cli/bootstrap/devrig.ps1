@@ -13,13 +13,23 @@ $ErrorActionPreference = "Stop"
 $ScriptDir = Split-Path -Parent $MyInvocation.MyCommand.Path
 
 # Configuration
-$DEVRIG_CONFIG = if ($env:DEVRIG_CONFIG) { $env:DEVRIG_CONFIG } else { Join-Path $ScriptDir "devrig.yaml" }
 $DEVRIG_HOME = if ($env:DEVRIG_HOME) { $env:DEVRIG_HOME } else { Join-Path $ScriptDir ".devrig" }
 
 # Log configuration overrides
-if ($DEVRIG_CONFIG -ne (Join-Path $ScriptDir "devrig.yaml")) {
+if ($env:DEVRIG_CONFIG) {
+    $DEVRIG_CONFIG = $env:DEVRIG_CONFIG
     Write-Host "[INFO] Using custom config location: DEVRIG_CONFIG=$DEVRIG_CONFIG"
 }
+else {
+    # devrig.yaml wins over devrig.toml and devrig.json, the same precedence as devrig uses
+    $DEVRIG_CONFIG = Join-Path $ScriptDir "devrig.yaml"
+    foreach ($name in @("devrig.yaml", "devrig.toml", "devrig.json")) {
+        if (Test-Path (Join-Path $ScriptDir $name)) {
+            $DEVRIG_CONFIG = Join-Path $ScriptDir $name
+            break
+        }
+    }
+}
 
 if ($DEVRIG_HOME -ne (Join-Path $ScriptDir ".devrig")) {
     Write-Host "[INFO] Using custom devrig home: DEVRIG_HOME=$DEVRIG_HOME"
@@ -63,7 +73,7 @@ if ($env:DEVRIG_CPU) {
     }
 }
 
-# Parse YAML to get URL and hash for current platform
+# Parse the config to get URL and hash for current platform
 $content = Get-Content $DEVRIG_CONFIG -Raw
 $lines = $content -split "`n"
 
@@ -73,6 +83,33 @@ $inPlatform = $false
 $url = ""
 $sha512 = ""
 
+if ($DEVRIG_CONFIG -match "\.json$") {
+    $platform = (ConvertFrom-Json $content).devrig.binaries."$os-$cpu"
+    if ($platform) {
+        $url = "$($platform.url)"
+        $sha512 = "$($platform.sha512)"
+    }
+    $lines = @()
+}
+elseif ($DEVRIG_CONFIG -match "\.toml$") {
+    # The binary of each platform is a [devrig.binaries.<os>-<cpu>] table with url and sha512 keys
+    foreach ($line in $lines) {
+        if ($line -match "^\s*\[") {
+            $inPlatform = $line -match "^\s*\[\s*devrig\.binaries\.[`"']?$os-$cpu[`"']?\s*\]"
+            continue
+        }
+        if ($inPlatform) {
+            if (-not $url -and $line -match "^\s*url\s*=\s*[`"']([^`"']+)[`"']") {
+                $url = $matches[1].Trim()
+            }
+            elseif (-not $sha512 -and $line -match "^\s*sha512\s*=\s*[`"']([^`"']+)[`"']") {
+                $sha512 = $matches[1].Trim()
+            }
+        }
+    }
+    $lines = @()
+}
+
 foreach ($line in $lines) {
     if ($url -and $sha512) {
         break
@@ -97,7 +134,7 @@ foreach ($line in $lines) {
         continue
     }
 
-    if ($inPlatform -and $line -match "^\s+[a-z_-]+:" -and $line -notmatch "^\s+(url|sha512):") {
+    if ($inPlatform -and $line -match "^\s+[a-z_-]+:" -and $line -notmatch "^\s+(url|sha512|mirrors):") {
         break
     }
 
@@ -128,13 +165,23 @@ if (-not (Test-Path $DEVRIG_HOME)) {
     New-Item -ItemType Directory -Path $DEVRIG_HOME -Force | Out-Null
 }
 
-# Construct binary path directly with hash (matching sh script)
-$DEVRIG_BIN = Join-Path $DEVRIG_HOME "devrig-$os-$cpu-$sha512"
+$expectedHash = $sha512.ToLower()
+
+# Construct binary path (matching sh script): .devrig/devrig-<os>-<cpu>-<sha512>/devrig with metadata.json next to it
+$DEVRIG_BIN_DIR = Join-Path $DEVRIG_HOME "devrig-$os-$cpu-$expectedHash"
+$binaryName = "devrig"
 if ($os -eq "windows") {
-    $DEVRIG_BIN = "$DEVRIG_BIN.exe"
+    $binaryName = "devrig.exe"
+}
+$DEVRIG_BIN = Join-Path $DEVRIG_BIN_DIR $binaryName
+
+# Older versions stored the binary as a plain file next to the folder, move it inside
+$legacyBin = if ($os -eq "windows") { "$DEVRIG_BIN_DIR.exe" } else { $DEVRIG_BIN_DIR }
+if (Test-Path $legacyBin -PathType Leaf) {
+    Move-Item $legacyBin "$DEVRIG_BIN_DIR-moving" -Force
+    New-Item -ItemType Directory -Path $DEVRIG_BIN_DIR -Force | Out-Null
+    Move-Item "$DEVRIG_BIN_DIR-moving" $DEVRIG_BIN -Force
 }
-
-$expectedHash = $sha512.ToLower()
 
 # Helper function to check SHA512 sum
 function Test-SHA512Sum {
@@ -161,8 +208,8 @@ function Test-SHA512Sum {
 if (-not (Test-Path $DEVRIG_BIN)) {
     Write-Host "[INFO] Devrig binary not found, downloading..."
 
-    # Create temporary file for download
-    $tempBinary = "$DEVRIG_BIN-downloading"
+    # Download next to the binary folder, same as devrig apply
+    $tempBinary = "$DEVRIG_BIN_DIR-downloading"
 
     # Download binary (no retries like sh script)
     try {
@@ -197,11 +244,22 @@ if (-not (Test-Path $DEVRIG_BIN)) {
 
     # Move to production location
     Write-Host "[INFO] Installing devrig binary..."
+    New-Item -ItemType Directory -Path $DEVRIG_BIN_DIR -Force | Out-Null
     if (Test-Path $DEVRIG_BIN) {
         Remove-Item $DEVRIG_BIN -Force
     }
     Move-Item $tempBinary $DEVRIG_BIN -Force
 
+    $metadata = [ordered]@{
+        os           = $os
+        arch         = $cpu
+        sha512       = $expectedHash
+        url          = $url
+        source       = "bootstrap"
+        installed_at = (Get-Date).ToUniversalTime().ToString("yyyy-MM-ddTHH:mm:ssZ")
+    }
+    $metadata | ConvertTo-Json | Set-Content -Path (Join-Path $DEVRIG_BIN_DIR "metadata.json") -ErrorAction SilentlyContinue
+
     Write-Host "[INFO] Devrig binary installed successfully"
 }
 
@@ -220,6 +278,31 @@ if ($env:DEVRIG_DEBUG_NO_EXEC -eq "1") {
 # Set DEVRIG_CONFIG environment variable for the tool to use
 $env:DEVRIG_CONFIG = $DEVRIG_CONFIG
 
+# Update notice, at most once per day. The binary prints the line
+#   ##devrig[update-available] current=<version> latest=<version> channel=<channel>
+# when a newer devrig is released, see `devrig update-notice`
+$noticeStamp = Join-Path $DEVRIG_HOME "update-notice.stamp"
+$noticeDue = -not (Test-Path $noticeStamp) -or ((Get-Item $noticeStamp).LastWriteTime -lt (Get-Date).AddDays(-1))
+if ($env:DEVRIG_NO_UPDATE_NOTICE -ne "1" -and $noticeDue) {
+    try {
+        Set-Content -Path $noticeStamp -Value "" -ErrorAction SilentlyContinue
+        $notice = & $DEVRIG_BIN update-notice 2>$null | Where-Object { $_.StartsWith('##devrig[update-available]') } | Select-Object -First 1
+        if ($notice) {
+            $noticeCurrent = if ($notice -match ' current=(\S+)') { $Matches[1] } else { "" }
+            $noticeLatest = if ($notice -match ' latest=(\S+)') { $Matches[1] } else { "" }
+            Write-Host ""
+            Write-Host "[UPDATE] ************************************************************" -ForegroundColor Yellow
+            Write-Host "[UPDATE] devrig $noticeLatest is available, this project uses $noticeCurrent" -ForegroundColor Yellow
+            Write-Host "[UPDATE] Run '$($MyInvocation.MyCommand.Path) upgrade' to update devrig.yaml" -ForegroundColor Yellow
+            Write-Host "[UPDATE] ************************************************************" -ForegroundColor Yellow
+            Write-Host ""
+        }
+    }
+    catch {
+        # The update notice must never break the command
+    }
+}
+
 # Execute devrig binary with all passed arguments
 Write-Host "[INFO] Executing devrig..."
 
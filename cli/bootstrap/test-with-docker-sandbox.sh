@@ -45,8 +45,9 @@ chmod +x "$BOOTSTRAP_SCRIPT"
 # For hash mismatch test, pre-create a binary with wrong content
 case "${DEVRIG_CONFIG:-}" in
   *test-config-mismatch.yaml)
-    mkdir -p .devrig
-    echo "wrong content" > ".devrig/devrig-linux-x86_64-badhash1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890"
+    binary_dir=".devrig/devrig-linux-x86_64-badhash1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890abcdef1234567890"
+    mkdir -p "$binary_dir"
+    echo "wrong content" > "$binary_dir/devrig"
     ;;
 esac
 
@@ -58,8 +59,9 @@ if [ "${DEVRIG_TEST_CREATE_LOCAL_BINARY:-}" != "" ]; then
   if [ -f "${DEVRIG_CONFIG}" ]; then
     hash=$(grep "sha512:" "${DEVRIG_CONFIG}" | sed 's/.*sha512:[[:space:]]*["'\'']*\([^"'\'']*\)["'\'']*.*/\1/')
 
-    # Create the binary path based on hash
-    binary_path=".devrig/devrig-linux-x86_64-${hash}"
+    # Create the binary path based on hash: .devrig/devrig-<os>-<cpu>-<sha512>/devrig
+    mkdir -p ".devrig/devrig-linux-x86_64-${hash}"
+    binary_path=".devrig/devrig-linux-x86_64-${hash}/devrig"
 
     # Create test binary content
     echo '#!/bin/sh' > "$binary_path"
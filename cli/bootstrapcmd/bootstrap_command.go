@@ -0,0 +1,114 @@
+package bootstrapcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/output"
+)
+
+// NewBootstrapCommand creates the bootstrap command group
+func NewBootstrapCommand(configPath func() string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bootstrap",
+		Short: "Inspect the ./devrig and devrig.ps1 wrapper scripts",
+	}
+	cmd.AddCommand(newSimulateCommand(configPath))
+	return cmd
+}
+
+func newSimulateCommand(configPath func() string) *cobra.Command {
+	var script string
+	var goos string
+	var goarch string
+
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Show what the wrapper scripts would do for devrig.yaml without running them",
+		Long: `Show what the wrapper scripts would do for devrig.yaml without running them.
+
+The command follows the steps of the scripts next to devrig.yaml: it finds the
+configuration file, detects the platform, picks the URL and the SHA-512 of the
+devrig binary and checks the binary in the .devrig folder. The DEVRIG_CONFIG,
+DEVRIG_HOME, DEVRIG_OS and DEVRIG_CPU variables are honored the same way,
+so the output matches DEVRIG_DEBUG_YAML_DOWNLOAD=1 of the scripts on any machine.
+The overrides of devrig.local.yaml and DEVRIG_SET_ variables are reported,
+the scripts do not see them.
+
+Examples:
+  devrig bootstrap simulate
+  devrig bootstrap simulate --script devrig.ps1 --os windows --cpu arm64
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			environ := os.Getenv
+			overrides := map[string]string{"DEVRIG_OS": goos, "DEVRIG_CPU": goarch}
+			lookupEnv := func(name string) string {
+				if value := overrides[name]; value != "" {
+					return value
+				}
+				return environ(name)
+			}
+
+			sim, err := Simulate(Options{
+				Script:     script,
+				ProjectDir: filepath.Dir(configPath()),
+				LookupEnv:  lookupEnv,
+				GOOS:       runtime.GOOS,
+				GOARCH:     runtime.GOARCH,
+			})
+			if err != nil {
+				return fmt.Errorf("%s would fail: %w", script, err)
+			}
+
+			if output.FormatFromContext(cmd.Context()) == output.FormatJSON {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(sim); err != nil {
+					return fmt.Errorf("failed to write the simulation: %w", err)
+				}
+				return nil
+			}
+			printSimulation(cmd.OutOrStdout(), sim)
+			return nil
+		},
+	}
+
+	defaultScript := ScriptShell
+	if runtime.GOOS == "windows" {
+		defaultScript = ScriptPowerShell
+	}
+	cmd.Flags().StringVar(&script, "script", defaultScript, "Script to simulate: devrig or devrig.ps1")
+	cmd.Flags().StringVar(&goos, "os", "", "OS the script runs on, same as DEVRIG_OS, e.g. windows")
+	cmd.Flags().StringVar(&goarch, "cpu", "", "CPU the script runs on, same as DEVRIG_CPU, e.g. arm64")
+	return cmd
+}
+
+func printSimulation(out io.Writer, sim *Simulation) {
+	fmt.Fprintf(out, "Script:      %s\n", sim.Script)
+	fmt.Fprintf(out, "Config:      %s\n", sim.ConfigPath)
+	fmt.Fprintf(out, "Devrig home: %s\n", sim.DevrigHome)
+	fmt.Fprintf(out, "Platform:    %s\n", sim.Platform())
+	for _, override := range sim.Overrides {
+		fmt.Fprintf(out, "Override:    %s\n", override)
+	}
+	fmt.Fprintf(out, "URL:         %s\n", sim.URL)
+	fmt.Fprintf(out, "SHA-512:     %s\n", sim.SHA512)
+	fmt.Fprintf(out, "Binary:      %s\n", sim.BinaryPath)
+	switch sim.Action {
+	case ActionDownload:
+		fmt.Fprintln(out, "Action:      download the binary, verify its SHA-512 and run it")
+	case ActionRun:
+		fmt.Fprintln(out, "Action:      verify the SHA-512 of the installed binary and run it")
+	case ActionFail:
+		fmt.Fprintln(out, "Action:      fail with exit code 7, the installed binary does not match the SHA-512")
+	}
+	for _, warning := range sim.Warnings {
+		fmt.Fprintf(out, "Warning:     %s\n", warning)
+	}
+}
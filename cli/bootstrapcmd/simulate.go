@@ -0,0 +1,200 @@
+// Package bootstrapcmd explains what the ./devrig and devrig.ps1 wrapper scripts do for a project
+// without running them, so a broken devrig.yaml can be debugged on any machine
+package bootstrapcmd
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/configfile"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+// Scripts of the project, named as they are written by `devrig init`
+const (
+	ScriptShell      = "devrig"
+	ScriptPowerShell = "devrig.ps1"
+)
+
+// Actions of the script once the binary is resolved
+const (
+	// ActionDownload downloads the binary, verifies its SHA-512 and runs it
+	ActionDownload = "download"
+	// ActionRun runs the installed binary after the SHA-512 check
+	ActionRun = "run"
+	// ActionFail stops with the checksum mismatch of the installed binary, exit code 7
+	ActionFail = "fail"
+)
+
+// Options are the inputs of the scripts: their folder, the environment variables and the platform of the machine
+type Options struct {
+	// Script is ScriptShell or ScriptPowerShell
+	Script string
+	// ProjectDir is the folder of the scripts
+	ProjectDir string
+	// LookupEnv returns the environment variable, e.g. os.Getenv
+	LookupEnv func(name string) string
+	// GOOS and GOARCH are the platform the scripts detect
+	GOOS   string
+	GOARCH string
+}
+
+// Simulation is what the script would do, the same values DEVRIG_DEBUG_YAML_DOWNLOAD and DEVRIG_DEBUG_NO_EXEC print
+type Simulation struct {
+	Script     string `json:"script"`
+	ConfigPath string `json:"config_path"`
+	DevrigHome string `json:"devrig_home"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	// Overrides are the DEVRIG_ variables the script reports, e.g. DEVRIG_OS=windows
+	Overrides  []string `json:"overrides,omitempty"`
+	URL        string   `json:"url"`
+	SHA512     string   `json:"sha512"`
+	BinaryPath string   `json:"binary_path"`
+	Action     string   `json:"action"`
+	// Warnings are the differences between the scripts and the devrig binary that may surprise
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Platform returns the key of devrig.binaries the script looks for
+func (s *Simulation) Platform() string {
+	return s.OS + "-" + s.Arch
+}
+
+// Simulate resolves the binary the script would run, the errors are the ones the script stops with
+func Simulate(opts Options) (*Simulation, error) {
+	if opts.Script != ScriptShell && opts.Script != ScriptPowerShell {
+		return nil, fmt.Errorf("unknown script %s, expected %s or %s", opts.Script, ScriptShell, ScriptPowerShell)
+	}
+	sim := &Simulation{Script: opts.Script}
+
+	sim.DevrigHome = filepath.Join(opts.ProjectDir, ".devrig")
+	if home := opts.LookupEnv(layout.EnvDevrigHome); home != "" {
+		sim.DevrigHome = home
+		sim.Overrides = append(sim.Overrides, layout.EnvDevrigHome+"="+home)
+	}
+	if path := opts.LookupEnv("DEVRIG_CONFIG"); path != "" {
+		sim.ConfigPath = path
+		sim.Overrides = append(sim.Overrides, "DEVRIG_CONFIG="+path)
+	} else {
+		sim.ConfigPath, _ = configfile.Find(opts.ProjectDir)
+	}
+	if _, err := os.Stat(sim.ConfigPath); err != nil {
+		return nil, fmt.Errorf("configuration file not found: %s", sim.ConfigPath)
+	}
+
+	var err error
+	if sim.OS, err = detectOS(opts, sim); err != nil {
+		return nil, err
+	}
+	if sim.Arch, err = detectArch(opts, sim); err != nil {
+		return nil, err
+	}
+
+	// The scripts read the file itself, the layers of devrig.yaml are not applied
+	data, err := os.ReadFile(sim.ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", sim.ConfigPath, err)
+	}
+	values, err := configfile.Decode(sim.ConfigPath, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", sim.ConfigPath, err)
+	}
+	binary := lookup(values, "devrig", "binaries", sim.Platform())
+	sim.URL, _ = lookup(binary, "url").(string)
+	sim.SHA512, _ = lookup(binary, "sha512").(string)
+	if sim.URL == "" || sim.SHA512 == "" {
+		return nil, fmt.Errorf("could not find devrig binary configuration for platform %s %s in %s", sim.OS, sim.Arch, sim.ConfigPath)
+	}
+	sim.Warnings = append(sim.Warnings, layerWarnings(sim)...)
+
+	sim.BinaryPath = layout.ResolveDevrigBinary(sim.DevrigHome, sim.OS, sim.Arch, sim.SHA512)
+	sim.Action = ActionDownload
+	if actual, err := fileSha512(sim.BinaryPath); err == nil {
+		sim.Action = ActionRun
+		if !strings.EqualFold(actual, sim.SHA512) {
+			sim.Action = ActionFail
+			sim.Warnings = append(sim.Warnings, fmt.Sprintf("the installed binary has SHA-512 %s, remove %s to download it again",
+				actual, filepath.Dir(sim.BinaryPath)))
+		}
+	}
+	return sim, nil
+}
+
+// detectOS mirrors the OS detection of the scripts, the shell script runs on Linux and macOS only
+func detectOS(opts Options, sim *Simulation) (string, error) {
+	if value := opts.LookupEnv("DEVRIG_OS"); value != "" {
+		sim.Overrides = append(sim.Overrides, "DEVRIG_OS="+value)
+		return value, nil
+	}
+	switch opts.GOOS {
+	case "linux", "darwin":
+		return opts.GOOS, nil
+	case "windows":
+		if opts.Script == ScriptPowerShell {
+			return opts.GOOS, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported OS %s for %s", opts.GOOS, opts.Script)
+}
+
+// detectArch mirrors the CPU detection of the scripts, x86_64 and arm64 are supported
+func detectArch(opts Options, sim *Simulation) (string, error) {
+	if value := opts.LookupEnv("DEVRIG_CPU"); value != "" {
+		sim.Overrides = append(sim.Overrides, "DEVRIG_CPU="+value)
+		return value, nil
+	}
+	switch opts.GOARCH {
+	case "amd64":
+		return "x86_64", nil
+	case "arm64":
+		return "arm64", nil
+	}
+	return "", fmt.Errorf("unsupported CPU %s for %s", opts.GOARCH, opts.Script)
+}
+
+// layerWarnings reports the binaries that devrig.local.yaml, the profiles or the DEVRIG_SET_ variables change,
+// the devrig binary sees them but the scripts do not
+func layerWarnings(sim *Simulation) []string {
+	section, err := configservice.NewConfigService(sim.ConfigPath).Binaries().ReadDevrigSection()
+	if err != nil {
+		return []string{fmt.Sprintf("devrig fails to read the devrig section: %v", err)}
+	}
+	layered, ok := section.Binaries[sim.Platform()]
+	if !ok || layered.URL != sim.URL || !strings.EqualFold(layered.SHA512, sim.SHA512) {
+		return []string{fmt.Sprintf("the overrides of %s change the binary of %s, the scripts ignore them and use the file itself",
+			filepath.Base(sim.ConfigPath), sim.Platform())}
+	}
+	return nil
+}
+
+// lookup walks the nested mappings of the decoded file, it returns nil for a missing key
+func lookup(value interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		mapping, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = mapping[key]
+	}
+	return value
+}
+
+func fileSha512(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha512.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,126 @@
+package bootstrapcmd
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+// writeProject writes devrig.yaml with the binary of linux-x86_64 and returns the project folder and the binary content
+func writeProject(t *testing.T) (string, []byte) {
+	t.Helper()
+	binary := []byte("devrig binary")
+	sum := sha512.Sum512(binary)
+	content := "devrig:\n  version: 0.79.5\n  binaries:\n    linux-x86_64:\n      url: https://example.com/devrig-linux-x86_64\n" +
+		"      sha512: " + hex.EncodeToString(sum[:]) + "\n"
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "devrig.yaml"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	return projectDir, binary
+}
+
+func options(projectDir string, env map[string]string) Options {
+	return Options{
+		Script:     ScriptShell,
+		ProjectDir: projectDir,
+		LookupEnv:  func(name string) string { return env[name] },
+		GOOS:       "linux",
+		GOARCH:     "amd64",
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	projectDir, binary := writeProject(t)
+
+	sim, err := Simulate(options(projectDir, nil))
+	if err != nil {
+		t.Fatalf("Failed to simulate: %v", err)
+	}
+	if sim.Platform() != "linux-x86_64" || sim.URL != "https://example.com/devrig-linux-x86_64" || len(sim.SHA512) != 128 {
+		t.Errorf("Unexpected binary: %+v", sim)
+	}
+	expected := layout.ResolveDevrigBinary(filepath.Join(projectDir, ".devrig"), "linux", "x86_64", sim.SHA512)
+	if sim.BinaryPath != expected || sim.Action != ActionDownload || len(sim.Warnings) != 0 {
+		t.Errorf("Expected the binary to be downloaded to %s, got %+v", expected, sim)
+	}
+
+	// The installed binary runs, a modified one stops the script
+	if err := os.MkdirAll(filepath.Dir(expected), 0755); err != nil {
+		t.Fatalf("Failed to create binary folder: %v", err)
+	}
+	if err := os.WriteFile(expected, binary, 0755); err != nil {
+		t.Fatalf("Failed to write binary: %v", err)
+	}
+	if sim, err = Simulate(options(projectDir, nil)); err != nil || sim.Action != ActionRun {
+		t.Errorf("Expected the installed binary to run, got %+v, %v", sim, err)
+	}
+	if err := os.WriteFile(expected, []byte("modified"), 0755); err != nil {
+		t.Fatalf("Failed to write binary: %v", err)
+	}
+	if sim, err = Simulate(options(projectDir, nil)); err != nil || sim.Action != ActionFail || len(sim.Warnings) != 1 {
+		t.Errorf("Expected the checksum mismatch, got %+v, %v", sim, err)
+	}
+}
+
+func TestSimulate_Overrides(t *testing.T) {
+	projectDir, _ := writeProject(t)
+	home := filepath.Join(t.TempDir(), "home")
+
+	sim, err := Simulate(options(projectDir, map[string]string{"DEVRIG_HOME": home, "DEVRIG_CPU": "arm64"}))
+	if err == nil || !strings.Contains(err.Error(), "linux arm64") {
+		t.Errorf("Expected the missing platform to fail, got %+v, %v", sim, err)
+	}
+
+	sim, err = Simulate(options(projectDir, map[string]string{"DEVRIG_HOME": home}))
+	if err != nil {
+		t.Fatalf("Failed to simulate: %v", err)
+	}
+	if !strings.HasPrefix(sim.BinaryPath, home) || len(sim.Overrides) != 1 {
+		t.Errorf("Expected the binary in DEVRIG_HOME, got %+v", sim)
+	}
+}
+
+func TestSimulate_Platforms(t *testing.T) {
+	projectDir, _ := writeProject(t)
+
+	opts := options(projectDir, nil)
+	opts.GOOS = "windows"
+	if _, err := Simulate(opts); err == nil || !strings.Contains(err.Error(), "unsupported OS") {
+		t.Errorf("Expected the shell script to reject Windows, got %v", err)
+	}
+	opts.Script = ScriptPowerShell
+	if _, err := Simulate(opts); err == nil || !strings.Contains(err.Error(), "windows x86_64") {
+		t.Errorf("Expected PowerShell to look for windows-x86_64, got %v", err)
+	}
+
+	opts = options(projectDir, nil)
+	opts.GOARCH = "386"
+	if _, err := Simulate(opts); err == nil || !strings.Contains(err.Error(), "unsupported CPU") {
+		t.Errorf("Expected 386 to be unsupported, got %v", err)
+	}
+}
+
+func TestSimulate_LocalOverrideWarning(t *testing.T) {
+	projectDir, _ := writeProject(t)
+	local := "devrig:\n  binaries:\n    linux-x86_64:\n      url: https://mirror.example.com/devrig-linux-x86_64\n"
+	if err := os.WriteFile(filepath.Join(projectDir, "devrig.local.yaml"), []byte(local), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.local.yaml: %v", err)
+	}
+
+	sim, err := Simulate(options(projectDir, nil))
+	if err != nil {
+		t.Fatalf("Failed to simulate: %v", err)
+	}
+	if sim.URL != "https://example.com/devrig-linux-x86_64" {
+		t.Errorf("Expected the scripts to ignore devrig.local.yaml, got %s", sim.URL)
+	}
+	if len(sim.Warnings) != 1 || !strings.Contains(sim.Warnings[0], "the scripts ignore them") {
+		t.Errorf("Expected the override to be reported, got %v", sim.Warnings)
+	}
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/contentstore"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/progress"
+	"jonnyzzz.com/devrig.dev/state"
+)
+
+// NewCacheCommand creates the cache command group
+func NewCacheCommand(configPath func() string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage downloaded and unpacked IDEs and devrig binaries",
+	}
+	cmd.AddCommand(newGcCommand(configPath))
+	cmd.AddCommand(newDedupeCommand(configPath))
+	return cmd
+}
+
+func newGcCommand(configPath func() string) *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove cache entries according to the retention policy",
+		Long: `Remove old IDE versions, downloads and devrig binaries according to the retention section of devrig.yaml:
+
+  retention:
+    keep_unpacked_ides: 2    # most recent unpacked versions of every IDE
+    keep_downloads_days: 30  # days to keep downloaded archives
+    keep_devrig_binaries: 1  # previous devrig binaries in .devrig next to the pinned one
+    max_age_days: 90         # remove IDEs not used for longer, 0 keeps them
+    max_total_size: 20GiB    # remove the least recently used IDEs and downloads above the size
+
+The IDE builds devrig.yaml and devrig.lock refer to are never removed. The ages
+count from the last use of an IDE recorded in .devrig/state.json.
+The same policy is applied automatically once a new IDE version or devrig binary is in place.
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := configPath()
+			configs := configservice.NewConfigService(path)
+			cacheDir := config.ResolveCacheDir(path)
+			removals, err := PlanProjectRetention(path, cacheDir)
+			if err != nil {
+				return err
+			}
+			binaries, err := PlanDevrigBinaryRetention(configs, layout.ResolveDevrigHome(path))
+			if err != nil {
+				return err
+			}
+			removals = append(removals, binaries...)
+
+			if len(removals) == 0 {
+				cmd.Printf("Nothing to remove in %s\n", cacheDir)
+				return nil
+			}
+
+			var total int64
+			for _, removal := range removals {
+				total += removal.Size
+				cmd.Printf("%s (%s): %s\n", filepath.Base(removal.Path), progress.FormatBytes(removal.Size), removal.Reason)
+			}
+
+			if dryRun {
+				cmd.Printf("Dry run: %d entries, %s would be removed\n", len(removals), progress.FormatBytes(total))
+				return nil
+			}
+
+			freed, err := ApplyRemovals(cmd.Context(), removals)
+			if err := state.Prune(cmd.Context(), layout.ResolveDevrigHome(path)); err != nil {
+				cmd.Printf("Failed to update %s: %v\n", state.FileName, err)
+			}
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Removed %d entries, freed %s\n", len(removals), progress.FormatBytes(freed))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the entries to remove")
+	return cmd
+}
+
+func newDedupeCommand(configPath func() string) *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "dedupe",
+		Short: "Link the downloaded and unpacked IDEs to the shared cache",
+		Long: `Replace the downloaded packages and unpacked IDEs of the project with hard links to the same content
+in the shared cache of the user, which is enabled in devrig.yaml:
+
+  cache:
+    shared: true
+
+New downloads use the shared cache automatically, the command frees the space of the ones made before.
+Entries the shared cache does not have yet are added to it.
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := configPath()
+			store, err := contentstore.ForProject(configservice.NewConfigService(path))
+			if err != nil {
+				return err
+			}
+			if store == nil {
+				return fmt.Errorf("the shared cache is disabled, set cache.shared: true in %s", filepath.Base(path))
+			}
+
+			deduplicated, err := Dedupe(config.ResolveCacheDir(path), store, dryRun)
+			if err != nil {
+				return err
+			}
+
+			var total int64
+			for _, entry := range deduplicated {
+				total += entry.Freed
+				cmd.Printf("%s: %s\n", filepath.Base(entry.Path), progress.FormatBytes(entry.Freed))
+			}
+			if dryRun {
+				cmd.Printf("Dry run: %s would be freed with %s\n", progress.FormatBytes(total), store.Root())
+				return nil
+			}
+			cmd.Printf("Linked %d entries to %s, freed %s\n", len(deduplicated), store.Root(), progress.FormatBytes(total))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the space to free")
+	return cmd
+}
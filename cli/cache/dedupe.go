@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"path/filepath"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/contentstore"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/unpack"
+)
+
+// Deduplicated is a downloaded package or an unpacked IDE of the cache linked to the shared cache
+type Deduplicated struct {
+	Path  string
+	Freed int64
+}
+
+// Dedupe links the downloaded packages and the unpacked IDEs of the cache to the same entries of the shared cache,
+// entries the shared cache does not have are added to it. Nothing is changed in the dry run
+func Dedupe(cacheDir string, store *contentstore.Store, dryRun bool) ([]Deduplicated, error) {
+	var result []Deduplicated
+
+	downloads, err := readEntries(layout.ResolveDownloadsDir(cacheDir))
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range downloads {
+		// Partial downloads are not verified yet
+		if !info.Mode().IsRegular() || strings.HasSuffix(info.Name(), ".part") {
+			continue
+		}
+		path := filepath.Join(layout.ResolveDownloadsDir(cacheDir), info.Name())
+		checksum, err := contentstore.FileSHA256(path)
+		if err != nil {
+			return result, err
+		}
+		freed, err := store.DedupeFile(checksum, path, dryRun)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, Deduplicated{Path: path, Freed: freed})
+	}
+
+	ides, err := readEntries(layout.ResolveUnpackedIdesDir(cacheDir))
+	if err != nil {
+		return result, err
+	}
+	for _, info := range ides {
+		if !info.IsDir() {
+			continue
+		}
+		home := filepath.Join(layout.ResolveUnpackedIdesDir(cacheDir), info.Name())
+		// Only the IDEs unpacked from archives are shared, not the ones of installers and DMGs
+		checksum, err := unpack.ArchiveChecksum(home)
+		if err != nil {
+			continue
+		}
+		freed, err := store.DedupeTree(checksum, home, dryRun)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, Deduplicated{Path: home, Freed: freed})
+	}
+	return result, nil
+}
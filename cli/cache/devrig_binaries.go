@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/progress"
+)
+
+// PlanDevrigBinaries returns the devrig binaries in the .devrig folder to remove. The binaries with the pinned
+// hashes and the protected paths are always kept, and the keep most recently installed other binaries of
+// every platform, so switching back to the previous version does not download it again
+func PlanDevrigBinaries(devrigHome string, keep int, pinned []string, protected []string) ([]Removal, error) {
+	isPinned := map[string]bool{}
+	for _, sha512 := range pinned {
+		isPinned[strings.ToLower(sha512)] = true
+	}
+	isProtected := map[string]bool{}
+	for _, path := range protected {
+		isProtected[filepath.Clean(path)] = true
+	}
+
+	entries, err := readEntries(devrigHome)
+	if err != nil {
+		return nil, err
+	}
+
+	type binary struct {
+		path        string
+		installedAt time.Time
+	}
+	platforms := map[string][]binary{}
+	for _, info := range entries {
+		osName, arch, sha512, ok := layout.ParseDevrigBinaryDir(info.Name())
+		path := filepath.Join(devrigHome, info.Name())
+		if !ok || isPinned[sha512] || isProtected[path] {
+			continue
+		}
+		installedAt := info.ModTime()
+		if metadata, err := layout.ReadDevrigBinaryMetadata(path); err == nil && !metadata.InstalledAt.IsZero() {
+			installedAt = metadata.InstalledAt
+		}
+		platform := osName + "-" + arch
+		platforms[platform] = append(platforms[platform], binary{path: path, installedAt: installedAt})
+	}
+
+	var removals []Removal
+	for platform, binaries := range platforms {
+		sort.Slice(binaries, func(i, j int) bool {
+			return binaries[i].installedAt.After(binaries[j].installedAt)
+		})
+		for _, binary := range binaries[min(keep, len(binaries)):] {
+			removals = append(removals, Removal{
+				Path:   binary.path,
+				Reason: fmt.Sprintf("not pinned in devrig.yaml, only %d previous devrig binaries for %s are kept", keep, platform),
+				Size:   dirSize(binary.path),
+			})
+		}
+	}
+
+	sort.Slice(removals, func(i, j int) bool {
+		return removals[i].Path < removals[j].Path
+	})
+	return removals, nil
+}
+
+// PlanDevrigBinaryRetention returns the devrig binaries to remove according to the retention section of devrig.yaml.
+// Nothing is removed if devrig.yaml has no valid devrig section, all binaries could be the pinned ones
+func PlanDevrigBinaryRetention(configs configservice.ConfigService, devrigHome string) ([]Removal, error) {
+	policy, err := configs.Retention().ReadRetention()
+	if err != nil {
+		return nil, err
+	}
+	section, err := configs.Binaries().ReadDevrigSection()
+	if err != nil {
+		return nil, nil
+	}
+
+	var pinned []string
+	for _, binary := range section.Binaries {
+		pinned = append(pinned, binary.SHA512)
+	}
+
+	// The running binary may be an older one, e.g. during devrig apply after an upgrade
+	var protected []string
+	if executable, err := os.Executable(); err == nil {
+		if executable, err = filepath.EvalSymlinks(executable); err == nil {
+			protected = append(protected, executable, filepath.Dir(executable))
+		}
+	}
+	return PlanDevrigBinaries(devrigHome, policy.KeepDevrigBinaries, pinned, protected)
+}
+
+// EnforceDevrigBinaryRetention removes the devrig binaries of the .devrig folder that are neither pinned
+// in devrig.yaml nor among the previous ones kept by the retention policy
+func EnforceDevrigBinaryRetention(ctx context.Context, configs configservice.ConfigService, devrigHome string) error {
+	removals, err := PlanDevrigBinaryRetention(configs, devrigHome)
+	if err != nil {
+		return err
+	}
+
+	freed, err := ApplyRemovals(ctx, removals)
+	if err != nil {
+		return err
+	}
+	if len(removals) > 0 {
+		logging.FromContext(ctx).Info(fmt.Sprintf("Removed %d old devrig binaries, freed %s", len(removals), progress.FormatBytes(freed)))
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+func installTestBinary(t *testing.T, devrigHome string, platform string, sha512 string, installedAt time.Time) string {
+	t.Helper()
+	osName, arch, _ := strings.Cut(platform, "-")
+	file := filepath.Join(devrigHome, "devrig-downloading")
+	if err := os.MkdirAll(devrigHome, 0755); err != nil {
+		t.Fatalf("Failed to create .devrig: %v", err)
+	}
+	if err := os.WriteFile(file, []byte("binary"), 0755); err != nil {
+		t.Fatalf("Failed to write binary: %v", err)
+	}
+	target, err := layout.InstallDevrigBinary(devrigHome, file, layout.DevrigBinaryMetadata{
+		OS: osName, Arch: arch, SHA512: sha512, Source: layout.DevrigBinarySourceApply, InstalledAt: installedAt,
+	})
+	if err != nil {
+		t.Fatalf("Failed to install binary: %v", err)
+	}
+	return filepath.Dir(target)
+}
+
+func TestPlanDevrigBinaries(t *testing.T) {
+	devrigHome := t.TempDir()
+	now := time.Now()
+
+	pinned := installTestBinary(t, devrigHome, "linux-x86_64", strings.Repeat("1", 128), now.Add(-72*time.Hour))
+	previous := installTestBinary(t, devrigHome, "linux-x86_64", strings.Repeat("2", 128), now.Add(-24*time.Hour))
+	oldest := installTestBinary(t, devrigHome, "linux-x86_64", strings.Repeat("3", 128), now.Add(-48*time.Hour))
+	otherPlatform := installTestBinary(t, devrigHome, "darwin-arm64", strings.Repeat("4", 128), now.Add(-96*time.Hour))
+
+	// A binary of the older layout and files that are not binaries
+	legacy := filepath.Join(devrigHome, "devrig-linux-x86_64-"+strings.Repeat("5", 128))
+	createEntry(t, legacy, false, now.Add(-120*time.Hour))
+	createEntry(t, filepath.Join(devrigHome, "devrig-linux-x86_64-"+strings.Repeat("6", 128)+"-downloading"), false, now.Add(-120*time.Hour))
+	createEntry(t, filepath.Join(devrigHome, "update-notice.stamp"), false, now.Add(-120*time.Hour))
+
+	removals, err := PlanDevrigBinaries(devrigHome, 1, []string{strings.Repeat("1", 128)}, nil)
+	if err != nil {
+		t.Fatalf("Failed to plan removals: %v", err)
+	}
+
+	var removed []string
+	for _, removal := range removals {
+		removed = append(removed, removal.Path)
+		if removal.Size == 0 {
+			t.Errorf("Expected the size of %s", removal.Path)
+		}
+	}
+	if len(removed) != 2 || removed[0] != oldest || removed[1] != legacy {
+		t.Fatalf("Expected the oldest and the legacy binary to be removed, got %v", removed)
+	}
+	for _, kept := range []string{pinned, previous, otherPlatform} {
+		if _, err := os.Stat(kept); err != nil {
+			t.Errorf("Expected %s to be kept: %v", kept, err)
+		}
+	}
+
+	// Protected binaries are kept, e.g. the running one
+	removals, err = PlanDevrigBinaries(devrigHome, 0, []string{strings.Repeat("1", 128)}, []string{oldest})
+	if err != nil {
+		t.Fatalf("Failed to plan removals: %v", err)
+	}
+	if len(removals) != 3 {
+		t.Errorf("Expected all other binaries to be removed, got %v", removals)
+	}
+	for _, removal := range removals {
+		if removal.Path == oldest || removal.Path == pinned {
+			t.Errorf("Expected %s to be kept", removal.Path)
+		}
+	}
+}
+
+func TestEnforceDevrigBinaryRetention(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "devrig.yaml")
+	devrigHome := filepath.Join(dir, ".devrig")
+	config := "devrig:\n  binaries:\n    linux-x86_64:\n      url: https://example.com/devrig\n      sha512: " + strings.Repeat("1", 128) + "\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+
+	now := time.Now()
+	pinned := installTestBinary(t, devrigHome, "linux-x86_64", strings.Repeat("1", 128), now.Add(-72*time.Hour))
+	previous := installTestBinary(t, devrigHome, "linux-x86_64", strings.Repeat("2", 128), now.Add(-24*time.Hour))
+	oldest := installTestBinary(t, devrigHome, "linux-x86_64", strings.Repeat("3", 128), now.Add(-48*time.Hour))
+
+	if err := EnforceDevrigBinaryRetention(context.Background(), configservice.NewConfigService(configPath), devrigHome); err != nil {
+		t.Fatalf("Failed to enforce retention: %v", err)
+	}
+	for _, kept := range []string{pinned, previous} {
+		if _, err := os.Stat(kept); err != nil {
+			t.Errorf("Expected %s to be kept: %v", kept, err)
+		}
+	}
+	if _, err := os.Stat(oldest); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed, got: %v", oldest, err)
+	}
+
+	// Without the devrig section nothing is known to be pinned, nothing is removed
+	if err := os.WriteFile(configPath, []byte("tools:\n  node: 20.11.0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	removals, err := PlanDevrigBinaryRetention(configservice.NewConfigService(configPath), devrigHome)
+	if err != nil || len(removals) != 0 {
+		t.Errorf("Expected nothing to be removed, got %v, %v", removals, err)
+	}
+}
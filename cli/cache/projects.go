@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// RegisterProject records that the project of devrig.yaml uses the cache, so removing the entries of a cache
+// shared by several projects can check which of them refer to an entry. The file keeps the devrig.yaml path
+func RegisterProject(cacheDir string, configPath string) error {
+	path := ProjectRegistration(cacheDir, configPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	return tempfile.WriteFile(path, []byte(projectConfigPath(configPath)+"\n"), 0644)
+}
+
+// ProjectRegistration returns the file RegisterProject writes for the project
+func ProjectRegistration(cacheDir string, configPath string) string {
+	return filepath.Join(layout.ResolveCacheProjectsDir(cacheDir), projectKey(projectConfigPath(configPath)))
+}
+
+// OtherProjects returns the devrig.yaml paths of the other projects registered in the cache, the projects
+// whose devrig.yaml is gone are skipped. The boolean is false when the project itself is not registered,
+// e.g. it used the cache before the projects were registered, then the other projects are not known either
+func OtherProjects(cacheDir string, configPath string) ([]string, bool, error) {
+	configPath = projectConfigPath(configPath)
+	dir := layout.ResolveCacheProjectsDir(cacheDir)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	var others []string
+	registered := false
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+		other := strings.TrimSpace(string(data))
+		if other == configPath {
+			registered = true
+			continue
+		}
+		if _, err := os.Stat(other); err == nil {
+			others = append(others, other)
+		}
+	}
+	return others, registered, nil
+}
+
+// projectConfigPath returns the absolute devrig.yaml path, the symlinks of the project folder are resolved
+func projectConfigPath(configPath string) string {
+	if abs, err := filepath.Abs(configPath); err == nil {
+		configPath = abs
+	}
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(configPath)); err == nil {
+		configPath = filepath.Join(dir, filepath.Base(configPath))
+	}
+	return configPath
+}
+
+func projectKey(configPath string) string {
+	hash := sha256.Sum256([]byte(configPath))
+	return fmt.Sprintf("%x", hash[:8])
+}
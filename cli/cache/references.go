@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/feed"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/lock"
+)
+
+// References returns the unpacked IDEs and downloaded packages of the cache that the IDEs of devrig.yaml refer to,
+// the retention policy never removes them. The build of an IDE comes from devrig.yaml or devrig.lock, an IDE pinned
+// by version only refers to its most recent unpacked build, which `devrig ide launch` starts
+func References(configPath string, cacheDir string) ([]string, error) {
+	ides, err := configservice.NewConfigService(configPath).IDE().ReadIdes()
+	var notFound *devrigErrors.ConfigNotFoundError
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lockfile, err := lock.Load(lock.ResolvePath(configPath))
+	if err != nil {
+		return nil, err
+	}
+
+	unpackedDir := layout.ResolveUnpackedIdesDir(cacheDir)
+	unpacked, err := readEntries(unpackedDir)
+	if err != nil {
+		return nil, err
+	}
+	downloadsDir := layout.ResolveDownloadsDir(cacheDir)
+	downloads, err := readEntries(downloadsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var references []string
+	for _, section := range ides {
+		build := section.Build
+		if locked := lockfile.Find(feed.IdeLockName, lock.Platform(runtime.GOOS, runtime.GOARCH)); build == "" && locked != nil &&
+			locked.Request == feed.IdeLockRequest(section.Request()) {
+			build = locked.Build
+		}
+
+		// The folders and packages are named <name>-<build>[.app] and <name>-<build>.<package type>
+		name := layout.UnpackedIdeName(section.Name, build)
+		if build == "" {
+			name = latestBuildOf(unpacked, strings.TrimSuffix(layout.UnpackedIdeName(section.Name, ""), "-"))
+			if name == "" {
+				continue
+			}
+		}
+		for _, info := range unpacked {
+			if info.IsDir() && strings.TrimSuffix(info.Name(), ".app") == name {
+				references = append(references, filepath.Join(unpackedDir, info.Name()))
+			}
+		}
+		for _, info := range downloads {
+			if !info.IsDir() && strings.HasPrefix(info.Name(), name+".") {
+				references = append(references, filepath.Join(downloadsDir, info.Name()))
+			}
+		}
+	}
+	return references, nil
+}
+
+// latestBuildOf returns the <name>-<build> of the most recently modified unpacked build of the IDE, or empty
+func latestBuildOf(unpacked []fs.FileInfo, product string) string {
+	var latest fs.FileInfo
+	for _, info := range unpacked {
+		if info.IsDir() && ideProduct(info.Name()) == product && (latest == nil || info.ModTime().After(latest.ModTime())) {
+			latest = info
+		}
+	}
+	if latest == nil {
+		return ""
+	}
+	return strings.TrimSuffix(latest.Name(), ".app")
+}
@@ -0,0 +1,382 @@
+// Package cache manages the IDE cache of a project: downloaded archives and unpacked IDEs
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/progress"
+	"jonnyzzz.com/devrig.dev/state"
+)
+
+// Removal is a cache entry that violates the retention policy
+type Removal struct {
+	Path   string
+	Reason string
+	Size   int64
+}
+
+// PlanRetention returns the cache entries that violate the policy, the protected paths are always kept
+func PlanRetention(cacheDir string, policy configservice.RetentionSection, now time.Time, protected []string) ([]Removal, error) {
+	return planRetention(cacheDir, policy, now, protected, nil)
+}
+
+// planRetention is PlanRetention with the last use of the entries from state.json, the modification time
+// of an entry counts when it is more recent
+func planRetention(cacheDir string, policy configservice.RetentionSection, now time.Time, protected []string, used map[string]time.Time) ([]Removal, error) {
+	isProtected := map[string]bool{}
+	for _, path := range protected {
+		isProtected[filepath.Clean(path)] = true
+	}
+
+	unpacked, err := planUnpackedIdes(layout.ResolveUnpackedIdesDir(cacheDir), policy.KeepUnpackedIdes, isProtected)
+	if err != nil {
+		return nil, err
+	}
+
+	downloads, err := planDownloads(layout.ResolveDownloadsDir(cacheDir), policy.KeepDownloadsDays, now, isProtected)
+	if err != nil {
+		return nil, err
+	}
+	removals := append(unpacked, downloads...)
+
+	// The limits of age and size apply to what the rules above keep
+	planned := map[string]bool{}
+	for _, removal := range removals {
+		planned[removal.Path] = true
+	}
+	aged, err := planMaxAge(layout.ResolveUnpackedIdesDir(cacheDir), policy.MaxAgeDays, now, isProtected, planned, used)
+	if err != nil {
+		return nil, err
+	}
+	removals = append(removals, aged...)
+
+	maxTotalSize, err := policy.MaxTotalBytes()
+	if err != nil {
+		return nil, err
+	}
+	oversized, err := planMaxTotalSize(cacheDir, maxTotalSize, isProtected, planned, used)
+	if err != nil {
+		return nil, err
+	}
+	return append(removals, oversized...), nil
+}
+
+// planMaxAge removes the unpacked IDEs that were not used for more than the given number of days, 0 keeps them
+func planMaxAge(dir string, days int, now time.Time, isProtected map[string]bool, planned map[string]bool, used map[string]time.Time) ([]Removal, error) {
+	if days <= 0 {
+		return nil, nil
+	}
+	entries, err := readEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := now.Add(-time.Duration(days) * 24 * time.Hour)
+	var removals []Removal
+	for _, info := range entries {
+		path := filepath.Join(dir, info.Name())
+		if !info.IsDir() || isProtected[path] || planned[path] || !lastUse(info, path, used).Before(cutoff) {
+			continue
+		}
+		planned[path] = true
+		removals = append(removals, Removal{
+			Path:   path,
+			Reason: fmt.Sprintf("not used for more than %d days", days),
+			Size:   dirSize(path),
+		})
+	}
+	return removals, nil
+}
+
+// planMaxTotalSize removes the least recently used unpacked IDEs and downloads until the cache fits into
+// maxTotalSize bytes, 0 does not limit the size. The protected entries count, but are never removed
+func planMaxTotalSize(cacheDir string, maxTotalSize int64, isProtected map[string]bool, planned map[string]bool, used map[string]time.Time) ([]Removal, error) {
+	if maxTotalSize <= 0 {
+		return nil, nil
+	}
+
+	type entry struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var total int64
+	var candidates []entry
+	for _, dir := range []string{layout.ResolveUnpackedIdesDir(cacheDir), layout.ResolveDownloadsDir(cacheDir)} {
+		infos, err := readEntries(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			path := filepath.Join(dir, info.Name())
+			if planned[path] {
+				continue
+			}
+			size := info.Size()
+			if info.IsDir() {
+				size = dirSize(path)
+			}
+			total += size
+			if !isProtected[path] {
+				candidates = append(candidates, entry{path: path, size: size, modTime: lastUse(info, path, used)})
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].modTime.Before(candidates[j].modTime)
+	})
+	var removals []Removal
+	for _, candidate := range candidates {
+		if total <= maxTotalSize {
+			break
+		}
+		// Manifests go with their IDE, the temporary entries of running downloads are not cache entries yet
+		if name := filepath.Base(candidate.path); strings.HasSuffix(name, layout.IdeManifestSuffix) || strings.HasPrefix(name, ".") {
+			continue
+		}
+		planned[candidate.path] = true
+		total -= candidate.size
+		removals = append(removals, Removal{
+			Path:   candidate.path,
+			Reason: fmt.Sprintf("the cache is limited to %s", progress.FormatBytes(maxTotalSize)),
+			Size:   candidate.size,
+		})
+	}
+	return removals, nil
+}
+
+// planUnpackedIdes keeps the most recently modified versions of every IDE
+func planUnpackedIdes(dir string, keep int, isProtected map[string]bool) ([]Removal, error) {
+	entries, err := readEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	products := map[string][]fs.FileInfo{}
+	for _, info := range entries {
+		if info.IsDir() {
+			product := ideProduct(info.Name())
+			products[product] = append(products[product], info)
+		}
+	}
+
+	var removals []Removal
+	for product, versions := range products {
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i].ModTime().After(versions[j].ModTime())
+		})
+		for i, info := range versions {
+			path := filepath.Join(dir, info.Name())
+			if i < keep || isProtected[path] {
+				continue
+			}
+			removals = append(removals, Removal{
+				Path:   path,
+				Reason: fmt.Sprintf("only %d most recent versions of %s are kept", keep, product),
+				Size:   dirSize(path),
+			})
+		}
+	}
+
+	sort.Slice(removals, func(i, j int) bool {
+		return removals[i].Path < removals[j].Path
+	})
+	return removals, nil
+}
+
+// planDownloads removes archives that were not modified for the given number of days
+func planDownloads(dir string, days int, now time.Time, isProtected map[string]bool) ([]Removal, error) {
+	entries, err := readEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := now.Add(-time.Duration(days) * 24 * time.Hour)
+	var removals []Removal
+	for _, info := range entries {
+		path := filepath.Join(dir, info.Name())
+		if info.IsDir() || isProtected[path] || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		removals = append(removals, Removal{
+			Path:   path,
+			Reason: fmt.Sprintf("downloaded more than %d days ago", days),
+			Size:   info.Size(),
+		})
+	}
+	return removals, nil
+}
+
+// ApplyRemovals deletes the entries and returns the number of freed bytes
+func ApplyRemovals(ctx context.Context, removals []Removal) (int64, error) {
+	logger := logging.FromContext(ctx)
+	var freed int64
+	for _, removal := range removals {
+		if err := os.RemoveAll(removal.Path); err != nil {
+			return freed, fmt.Errorf("failed to remove %s: %w", removal.Path, err)
+		}
+		// An unpacked IDE has its manifest next to the folder
+		if err := os.Remove(layout.ResolveIdeManifest(removal.Path)); err != nil && !os.IsNotExist(err) {
+			return freed, fmt.Errorf("failed to remove the manifest of %s: %w", removal.Path, err)
+		}
+		logger.Debug("removed cache entry", "path", removal.Path, "reason", removal.Reason)
+		freed += removal.Size
+	}
+	return freed, nil
+}
+
+// PlanProjectRetention returns the cache entries that violate the retention policy of devrig.yaml,
+// the entries devrig.yaml and devrig.lock refer to and the protected paths are always kept
+func PlanProjectRetention(configPath string, cacheDir string, protected ...string) ([]Removal, error) {
+	policy, err := configservice.NewConfigService(configPath).Retention().ReadRetention()
+	if err != nil {
+		return nil, err
+	}
+	references, err := References(configPath, cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := state.Load(layout.ResolveDevrigHome(configPath))
+	if err != nil {
+		return nil, err
+	}
+	return planRetention(cacheDir, *policy, time.Now(), append(references, protected...), installed.LastUsed())
+}
+
+// EnforceRetention applies the retention policy from devrig.yaml to the cache,
+// it is called after a new IDE version is in place, which is protected from removal
+func EnforceRetention(ctx context.Context, configPath string, cacheDir string, protected ...string) error {
+	removals, err := PlanProjectRetention(configPath, cacheDir, protected...)
+	if err != nil {
+		return err
+	}
+
+	freed, err := ApplyRemovals(ctx, removals)
+	if err := state.Prune(ctx, layout.ResolveDevrigHome(configPath)); err != nil {
+		logging.FromContext(ctx).Warn("failed to update "+state.FileName, "error", err)
+	}
+	if err != nil {
+		return err
+	}
+	if len(removals) > 0 {
+		logging.FromContext(ctx).Info(fmt.Sprintf("Removed %d old cache entries, freed %s", len(removals), progress.FormatBytes(freed)))
+	}
+	return nil
+}
+
+// KeptAfterInstall tells whether the unpacked IDE at home stays in the cache once the version at installed is used,
+// the installed version takes one of the keep_unpacked_ides slots of the IDE, whether it is in the cache or not
+func KeptAfterInstall(cacheDir string, policy configservice.RetentionSection, home string, installed string) (bool, error) {
+	dir := layout.ResolveUnpackedIdesDir(cacheDir)
+	entries, err := readEntries(dir)
+	if err != nil {
+		return false, err
+	}
+
+	product := ideProduct(filepath.Base(home))
+	var versions []fs.FileInfo
+	for _, info := range entries {
+		path := filepath.Join(dir, info.Name())
+		if info.IsDir() && ideProduct(info.Name()) == product && path != filepath.Clean(installed) {
+			versions = append(versions, info)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].ModTime().After(versions[j].ModTime())
+	})
+	for i, info := range versions {
+		if filepath.Join(dir, info.Name()) == filepath.Clean(home) {
+			return i < policy.KeepUnpackedIdes-1, nil
+		}
+	}
+	return false, nil
+}
+
+// LatestUnpackedIdes returns the most recently modified unpacked version of every IDE in the cache
+func LatestUnpackedIdes(cacheDir string) ([]string, error) {
+	dir := layout.ResolveUnpackedIdesDir(cacheDir)
+	entries, err := readEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	latest := map[string]fs.FileInfo{}
+	for _, info := range entries {
+		if !info.IsDir() {
+			continue
+		}
+		product := ideProduct(info.Name())
+		if current, ok := latest[product]; !ok || info.ModTime().After(current.ModTime()) {
+			latest[product] = info
+		}
+	}
+
+	var paths []string
+	for _, info := range latest {
+		paths = append(paths, filepath.Join(dir, info.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// lastUse returns the last use of the cache entry recorded in state.json, or its modification time when it is more recent
+func lastUse(info fs.FileInfo, path string, used map[string]time.Time) time.Time {
+	if recorded := used[path]; recorded.After(info.ModTime()) {
+		return recorded
+	}
+	return info.ModTime()
+}
+
+// ideProduct returns the IDE name of an unpacked IDE folder <name>-<build>[.app]
+func ideProduct(name string) string {
+	name = strings.TrimSuffix(name, ".app")
+	if i := strings.LastIndex(name, "-"); i > 0 {
+		return name[:i]
+	}
+	return name
+}
+
+func readEntries(dir string) ([]fs.FileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	var infos []fs.FileInfo
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
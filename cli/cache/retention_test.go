@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/state"
+)
+
+func createEntry(t *testing.T, path string, dir bool, modTime time.Time) {
+	t.Helper()
+	if dir {
+		if err := os.MkdirAll(filepath.Join(path, "bin"), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "bin", "idea"), []byte("ide"), 0755); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("archive"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set modification time: %v", err)
+	}
+}
+
+func TestPlanRetention(t *testing.T) {
+	cacheDir := t.TempDir()
+	now := time.Now()
+	ides := layout.ResolveUnpackedIdesDir(cacheDir)
+	downloads := layout.ResolveDownloadsDir(cacheDir)
+
+	createEntry(t, filepath.Join(ides, "IntelliJ_IDEA-251.1"), true, now.Add(-72*time.Hour))
+	createEntry(t, filepath.Join(ides, "IntelliJ_IDEA-251.2"), true, now.Add(-48*time.Hour))
+	createEntry(t, filepath.Join(ides, "IntelliJ_IDEA-251.3"), true, now.Add(-24*time.Hour))
+	createEntry(t, filepath.Join(ides, "GoLand-251.1.app"), true, now.Add(-96*time.Hour))
+	createEntry(t, filepath.Join(downloads, "IntelliJ_IDEA-251.1.dmg"), false, now.Add(-40*24*time.Hour))
+	createEntry(t, filepath.Join(downloads, "IntelliJ_IDEA-251.3.dmg"), false, now.Add(-24*time.Hour))
+
+	policy := configservice.RetentionSection{KeepUnpackedIdes: 2, KeepDownloadsDays: 30}
+	removals, err := PlanRetention(cacheDir, policy, now, nil)
+	if err != nil {
+		t.Fatalf("Failed to plan retention: %v", err)
+	}
+
+	var removed []string
+	for _, removal := range removals {
+		removed = append(removed, filepath.Base(removal.Path))
+	}
+	if len(removed) != 2 || removed[0] != "IntelliJ_IDEA-251.1" || removed[1] != "IntelliJ_IDEA-251.1.dmg" {
+		t.Fatalf("Expected the oldest IDE and the old download to be removed, got %v", removed)
+	}
+	if removals[0].Size != 3 {
+		t.Errorf("Expected the size of the unpacked IDE to be computed, got %d", removals[0].Size)
+	}
+
+	// Protected entries survive, e.g. the version that was just switched to
+	removals, err = PlanRetention(cacheDir, configservice.RetentionSection{KeepUnpackedIdes: 1, KeepDownloadsDays: 30}, now,
+		[]string{filepath.Join(ides, "IntelliJ_IDEA-251.1")})
+	if err != nil {
+		t.Fatalf("Failed to plan retention: %v", err)
+	}
+	for _, removal := range removals {
+		if filepath.Base(removal.Path) == "IntelliJ_IDEA-251.1" {
+			t.Errorf("Expected the protected entry to be kept")
+		}
+	}
+	if len(removals) != 2 {
+		t.Errorf("Expected 251.2 and the old download to be removed, got %d removals", len(removals))
+	}
+
+	freed, err := ApplyRemovals(context.Background(), removals)
+	if err != nil {
+		t.Fatalf("Failed to apply removals: %v", err)
+	}
+	if freed == 0 {
+		t.Error("Expected freed bytes to be reported")
+	}
+	if _, err := os.Stat(filepath.Join(ides, "IntelliJ_IDEA-251.2")); !os.IsNotExist(err) {
+		t.Errorf("Expected IntelliJ_IDEA-251.2 to be removed, got: %v", err)
+	}
+}
+
+func TestPlanRetention_MissingCache(t *testing.T) {
+	removals, err := PlanRetention(filepath.Join(t.TempDir(), "missing"), configservice.RetentionSection{KeepUnpackedIdes: 2, KeepDownloadsDays: 30}, time.Now(), nil)
+	if err != nil || len(removals) != 0 {
+		t.Errorf("Expected nothing to remove for a missing cache, got %v, %v", removals, err)
+	}
+}
+
+func TestPlanRetention_MaxAgeAndSize(t *testing.T) {
+	cacheDir := t.TempDir()
+	now := time.Now()
+	ides := layout.ResolveUnpackedIdesDir(cacheDir)
+	downloads := layout.ResolveDownloadsDir(cacheDir)
+
+	createEntry(t, filepath.Join(ides, "GoLand-241.1"), true, now.Add(-100*24*time.Hour))
+	createEntry(t, filepath.Join(ides, "GoLand-243.1"), true, now.Add(-50*24*time.Hour))
+	createEntry(t, filepath.Join(ides, "WebStorm-243.1"), true, now.Add(-10*24*time.Hour))
+	createEntry(t, filepath.Join(downloads, "GoLand-243.1.tar.gz"), false, now.Add(-5*24*time.Hour))
+
+	// The IDEs take 3 bytes each and the download 7 bytes, so the oldest IDE is above the limit
+	policy := configservice.RetentionSection{KeepUnpackedIdes: 5, KeepDownloadsDays: 30, MaxAgeDays: 60, MaxTotalSize: "10"}
+	removals, err := PlanRetention(cacheDir, policy, now, nil)
+	if err != nil {
+		t.Fatalf("Failed to plan retention: %v", err)
+	}
+	if len(removals) != 2 || filepath.Base(removals[0].Path) != "GoLand-241.1" || filepath.Base(removals[1].Path) != "GoLand-243.1" {
+		t.Fatalf("Expected the old IDE and the oldest IDE above the size to be removed, got %+v", removals)
+	}
+
+	// Protected entries count towards the size, the next oldest entry goes instead
+	removals, err = PlanRetention(cacheDir, policy, now, []string{filepath.Join(ides, "GoLand-243.1")})
+	if err != nil {
+		t.Fatalf("Failed to plan retention: %v", err)
+	}
+	if len(removals) != 2 || filepath.Base(removals[1].Path) != "WebStorm-243.1" {
+		t.Errorf("Expected WebStorm to be removed for the size, got %+v", removals)
+	}
+}
+
+func TestPlanProjectRetention_KeepsReferences(t *testing.T) {
+	projectDir := t.TempDir()
+	configPath := filepath.Join(projectDir, "devrig.yaml")
+	content := "ide:\n  name: GoLand\n  version: \"2024.3\"\n  build: \"243.1\"\nides:\n  - name: WebStorm\n    version: \"2024.3\"\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+
+	cacheDir := t.TempDir()
+	now := time.Now()
+	ides := layout.ResolveUnpackedIdesDir(cacheDir)
+	downloads := layout.ResolveDownloadsDir(cacheDir)
+	createEntry(t, filepath.Join(ides, "GoLand-243.0"), true, now.Add(-96*time.Hour))
+	createEntry(t, filepath.Join(ides, "GoLand-243.1"), true, now.Add(-72*time.Hour))
+	createEntry(t, filepath.Join(ides, "GoLand-243.2"), true, now.Add(-48*time.Hour))
+	createEntry(t, filepath.Join(ides, "GoLand-243.3"), true, now.Add(-24*time.Hour))
+	createEntry(t, filepath.Join(ides, "WebStorm-243.1"), true, now.Add(-24*time.Hour))
+	createEntry(t, filepath.Join(downloads, "GoLand-243.1.tar.gz"), false, now.Add(-40*24*time.Hour))
+	createEntry(t, filepath.Join(downloads, "GoLand-243.0.tar.gz"), false, now.Add(-40*24*time.Hour))
+
+	references, err := References(configPath, cacheDir)
+	if err != nil {
+		t.Fatalf("Failed to resolve references: %v", err)
+	}
+	if len(references) != 3 {
+		t.Errorf("Expected the pinned GoLand build, its package and the latest WebStorm, got %v", references)
+	}
+
+	removals, err := PlanProjectRetention(configPath, cacheDir)
+	if err != nil {
+		t.Fatalf("Failed to plan retention: %v", err)
+	}
+	var removed []string
+	for _, removal := range removals {
+		removed = append(removed, filepath.Base(removal.Path))
+	}
+	if len(removed) != 2 || removed[0] != "GoLand-243.0" || removed[1] != "GoLand-243.0.tar.gz" {
+		t.Errorf("Expected only the unreferenced old build to be removed, got %v", removed)
+	}
+}
+
+func TestPlanProjectRetention_LastUse(t *testing.T) {
+	t.Setenv(layout.EnvDevrigHome, "")
+	projectDir := t.TempDir()
+	configPath := filepath.Join(projectDir, "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte("retention:\n  max_age_days: 60\n"), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+
+	cacheDir := t.TempDir()
+	now := time.Now()
+	ides := layout.ResolveUnpackedIdesDir(cacheDir)
+	createEntry(t, filepath.Join(ides, "GoLand-241.1"), true, now.Add(-100*24*time.Hour))
+	createEntry(t, filepath.Join(ides, "WebStorm-241.1"), true, now.Add(-100*24*time.Hour))
+
+	// GoLand was unpacked long ago, but it was launched recently
+	devrigHome := layout.ResolveDevrigHome(configPath)
+	artifact := state.Artifact{Type: state.TypeIde, Name: "GoLand", Version: "241.1", Path: filepath.Join(ides, "GoLand-241.1")}
+	if err := state.Record(context.Background(), devrigHome, artifact, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to record the IDE: %v", err)
+	}
+
+	removals, err := PlanProjectRetention(configPath, cacheDir)
+	if err != nil {
+		t.Fatalf("Failed to plan retention: %v", err)
+	}
+	if len(removals) != 1 || filepath.Base(removals[0].Path) != "WebStorm-241.1" || removals[0].Reason != "not used for more than 60 days" {
+		t.Fatalf("Expected only the unused IDE to be removed, got %+v", removals)
+	}
+
+	if err := EnforceRetention(context.Background(), configPath, cacheDir); err != nil {
+		t.Fatalf("Failed to enforce retention: %v", err)
+	}
+	if err := os.RemoveAll(artifact.Path); err != nil {
+		t.Fatalf("Failed to remove the IDE: %v", err)
+	}
+	if err := EnforceRetention(context.Background(), configPath, cacheDir); err != nil {
+		t.Fatalf("Failed to enforce retention: %v", err)
+	}
+	installed, err := state.Load(devrigHome)
+	if err != nil || len(installed.Artifacts) != 0 {
+		t.Errorf("Expected the removed IDE to be forgotten, got %+v, %v", installed, err)
+	}
+}
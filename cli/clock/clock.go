@@ -0,0 +1,128 @@
+// Package clock detects the skew of the system clock from the Date headers of HTTP responses.
+// A clock that is minutes off breaks time-sensitive checks, e.g. the validity of TLS certificates,
+// signed cloud requests and the freshness of metadata, so the checks tolerate Allowance and
+// `devrig doctor` tells the user to fix the clock
+package clock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/logging"
+)
+
+const (
+	// Allowance is the skew of the system clock that time-sensitive checks tolerate
+	Allowance = 5 * time.Minute
+	// resolution is the precision of the Date header plus the rounding of the request time, smaller skews are noise
+	resolution = 2 * time.Second
+)
+
+// Observation is the skew of the system clock against a server, positive when the system clock is ahead
+type Observation struct {
+	Host string
+	Skew time.Duration
+}
+
+// Significant checks if the skew exceeds Allowance
+func (o Observation) Significant() bool {
+	return o.Skew > Allowance || o.Skew < -Allowance
+}
+
+// String describes the skew, e.g. "the system clock is 7m0s ahead of devrig.dev"
+func (o Observation) String() string {
+	skew, direction := o.Skew, "ahead of"
+	if skew < 0 {
+		skew, direction = -skew, "behind"
+	}
+	return fmt.Sprintf("the system clock is %s %s %s", skew.Round(time.Second), direction, o.Host)
+}
+
+// FromResponse measures the skew against the Date header of the response. The server time is compared to the middle
+// of sent and received, the local times of the request. It returns false when the response has no valid Date header
+func FromResponse(resp *http.Response, sent time.Time, received time.Time) (Observation, bool) {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return Observation{}, false
+	}
+	local := sent.Add(received.Sub(sent) / 2)
+	skew := local.Sub(date)
+	if skew < resolution && skew > -resolution {
+		skew = 0
+	}
+	host := ""
+	if resp.Request != nil {
+		host = resp.Request.URL.Host
+	}
+	return Observation{Host: host, Skew: skew}, true
+}
+
+var (
+	mutex    sync.Mutex
+	observed *Observation
+)
+
+// Record remembers the observation of the process, the latest one wins
+func Record(observation Observation) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	observed = &observation
+}
+
+// Observed returns the latest skew seen in the responses of this process, false before the first response
+func Observed() (Observation, bool) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if observed == nil {
+		return Observation{}, false
+	}
+	return *observed, true
+}
+
+// Now returns the time of the servers: the system time corrected by the observed skew when it is significant
+func Now() time.Time {
+	now := time.Now()
+	if observation, ok := Observed(); ok && observation.Significant() {
+		return now.Add(-observation.Skew)
+	}
+	return now
+}
+
+// Transport records the skew of the system clock from every response
+type Transport struct {
+	Base http.RoundTripper
+
+	warned sync.Once
+}
+
+// Install wraps http.DefaultTransport, so every request of devrig measures the clock for free
+func Install() {
+	if _, ok := http.DefaultTransport.(*Transport); ok {
+		return
+	}
+	http.DefaultTransport = &Transport{Base: http.DefaultTransport}
+}
+
+// RoundTrip sends the request and records the Date header of the response
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sent := time.Now()
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if observation, ok := FromResponse(resp, sent, time.Now()); ok {
+		Record(observation)
+		if observation.Significant() {
+			t.warned.Do(func() { warn(req.Context(), observation) })
+		}
+	}
+	return resp, nil
+}
+
+func warn(ctx context.Context, observation Observation) {
+	logging.FromContext(ctx).Warn(observation.String()+", time-sensitive checks may fail, fix the date and time of the machine",
+		"skew", observation.Skew.Round(time.Second).String())
+}
@@ -0,0 +1,66 @@
+package clock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFromResponse(t *testing.T) {
+	server := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	request, _ := http.NewRequest(http.MethodGet, "https://devrig.dev/download/latest.json", nil)
+	resp := &http.Response{Header: http.Header{"Date": {server.Format(http.TimeFormat)}}, Request: request}
+
+	observation, ok := FromResponse(resp, server.Add(7*time.Minute), server.Add(7*time.Minute+2*time.Second))
+	if !ok || observation.Skew != 7*time.Minute+time.Second || observation.Host != "devrig.dev" {
+		t.Fatalf("Unexpected observation: %+v, %v", observation, ok)
+	}
+	if !observation.Significant() || observation.String() != "the system clock is 7m1s ahead of devrig.dev" {
+		t.Errorf("Unexpected description: %s", observation)
+	}
+
+	observation, _ = FromResponse(resp, server.Add(-time.Hour), server.Add(-time.Hour))
+	if !observation.Significant() || !strings.Contains(observation.String(), "1h0m0s behind devrig.dev") {
+		t.Errorf("Unexpected description: %s", observation)
+	}
+
+	// The Date header has a precision of a second
+	observation, _ = FromResponse(resp, server.Add(time.Second), server.Add(time.Second))
+	if observation.Skew != 0 {
+		t.Errorf("Expected no skew within the resolution, got %s", observation.Skew)
+	}
+
+	if _, ok := FromResponse(&http.Response{Header: http.Header{}}, server, server); ok {
+		t.Errorf("Expected no observation without the Date header")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(-10*time.Minute).UTC().Format(http.TimeFormat))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Transport{Base: http.DefaultTransport}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to send the request: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	observation, ok := Observed()
+	if !ok || !observation.Significant() || observation.Skew < 9*time.Minute {
+		t.Fatalf("Expected the skew to be recorded, got: %+v", observation)
+	}
+	// The time of the servers is used once the skew is known
+	if skew := time.Since(Now()); skew < 9*time.Minute || skew > 11*time.Minute {
+		t.Errorf("Expected Now to be corrected by the skew, got %s", skew)
+	}
+
+	Record(Observation{Host: "devrig.dev"})
+	if skew := time.Since(Now()); skew > time.Second {
+		t.Errorf("Expected Now to be the system time without a skew, got %s", skew)
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultCacheSize is the number of project folders the cache of the application keeps resolved configurations for
+const DefaultCacheSize = 16
+
+// Cache keeps the configurations resolved from .idew.yaml per folder. It holds at most its capacity
+// of configurations and drops the least recently used one, so long running processes do not grow.
+// The application owns the cache in its context, tests create their own one
+type Cache struct {
+	capacity int
+
+	mutex   sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// cacheEntry is the configuration resolved from a folder
+type cacheEntry struct {
+	dir    string
+	config Config
+}
+
+// NewCache returns an empty cache of the given capacity, the default size is used for a capacity below 1
+func NewCache(capacity int) *Cache {
+	if capacity < 1 {
+		capacity = DefaultCacheSize
+	}
+	return &Cache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Resolve returns the configuration of the .idew.yaml found from the directory, the parsed file is cached per directory
+func (c *Cache) Resolve(cwd string) (Config, error) {
+	// Convert to absolute path for consistent caching
+	absCwd, err := filepath.Abs(cwd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	// The lock is held while the file is parsed, so concurrent callers parse it once
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, exists := c.entries[absCwd]; exists {
+		c.order.MoveToFront(element)
+		return element.Value.(*cacheEntry).config, nil
+	}
+
+	instance, err := loadConfig(cwd)
+	if err != nil {
+		return nil, err
+	}
+
+	c.entries[absCwd] = c.order.PushFront(&cacheEntry{dir: absCwd, config: instance})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).dir)
+	}
+	return instance, nil
+}
+
+// Len returns the number of cached configurations
+func (c *Cache) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.order.Len()
+}
+
+// Clear drops all cached configurations, the changed files are parsed again
+func (c *Cache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.order.Init()
+	clear(c.entries)
+}
+
+type cacheKey struct{}
+
+// WithCache returns a copy of ctx carrying the config cache
+func WithCache(ctx context.Context, cache *Cache) context.Context {
+	return context.WithValue(ctx, cacheKey{}, cache)
+}
+
+// CacheFromContext returns the config cache of the context, or a new cache that lives as long as the caller keeps it
+func CacheFromContext(ctx context.Context) *Cache {
+	if ctx != nil {
+		if cache, ok := ctx.Value(cacheKey{}).(*Cache); ok && cache != nil {
+			return cache
+		}
+	}
+	return NewCache(DefaultCacheSize)
+}
+
+// loadConfig finds and parses the .idew.yaml of the directory and creates its cache folder
+func loadConfig(cwd string) (Config, error) {
+	configPath, err := FindConfigFile(cwd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config: %w", err)
+	}
+
+	cacheDir := ResolveCacheDir(configPath)
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	ide, err := parseConfigFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	return &configImpl{
+		configPath: configPath,
+		cacheDir:   cacheDir,
+		ide:        ide,
+	}, nil
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvCacheDir overrides the IDE cache folder of all projects, it takes precedence over settings.cache_dir of devrig.yaml
+const EnvCacheDir = "DEVRIG_CACHE_DIR"
+
+// ReadCacheDirSetting returns settings.cache_dir of devrig.yaml with the layers, or empty when it is not set.
+// It is registered by the configservice package, which imports this one through layout
+var ReadCacheDirSetting = func(configPath string) (string, error) { return "", nil }
+
+// ResolveCacheDir returns the IDE cache folder of the project with the given config file: DEVRIG_CACHE_DIR,
+// settings.cache_dir of devrig.yaml, or .idew/cache next to the file. An invalid location falls back
+// to the default, CheckCacheDir reports it
+func ResolveCacheDir(configPath string) string {
+	dir, _, err := resolveConfiguredCacheDir(configPath)
+	if err != nil || dir == "" {
+		return defaultCacheDir(configPath)
+	}
+	return dir
+}
+
+// CheckCacheDir validates the configured cache folder, it must expand to a path that devrig can write to.
+// The default folder next to devrig.yaml is not checked, read-only checkouts are handled by the commands
+func CheckCacheDir(configPath string) error {
+	dir, source, err := resolveConfiguredCacheDir(configPath)
+	if err != nil || dir == "" {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create the cache directory %s of %s: %w", dir, source, err)
+	}
+	probe, err := os.CreateTemp(dir, ".devrig-write-probe-*")
+	if err != nil {
+		return fmt.Errorf("the cache directory %s of %s is not writable: %w", dir, source, err)
+	}
+	name := probe.Name()
+	_ = probe.Close()
+	_ = os.Remove(name)
+	return nil
+}
+
+// resolveConfiguredCacheDir returns the expanded cache folder of DEVRIG_CACHE_DIR or settings.cache_dir, and where
+// it comes from. The folder is empty when neither is set
+func resolveConfiguredCacheDir(configPath string) (string, string, error) {
+	if value := os.Getenv(EnvCacheDir); value != "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", EnvCacheDir, fmt.Errorf("failed to resolve current directory: %w", err)
+		}
+		dir, err := ExpandPath(value, cwd)
+		if err != nil {
+			return "", EnvCacheDir, fmt.Errorf("invalid %s: %w", EnvCacheDir, err)
+		}
+		return dir, EnvCacheDir, nil
+	}
+
+	const source = "settings.cache_dir"
+	value, err := ReadCacheDirSetting(configPath)
+	if err != nil || value == "" {
+		return "", source, err
+	}
+	// Relative paths are resolved next to devrig.yaml, so all checkouts of the team agree
+	projectDir, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return "", source, fmt.Errorf("failed to resolve project directory: %w", err)
+	}
+	dir, err := ExpandPath(value, projectDir)
+	if err != nil {
+		return "", source, fmt.Errorf("invalid %s in %s: %w", source, filepath.Base(configPath), err)
+	}
+	return dir, source, nil
+}
+
+func defaultCacheDir(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), ".idew", "cache")
+}
+
+// ExpandPath expands the leading ~ to the home directory and the $VAR and ${VAR} environment variables,
+// relative paths are resolved against baseDir. A variable that is not set is an error, so a typo never
+// turns the path into a folder at the root of the disk
+func ExpandPath(path string, baseDir string) (string, error) {
+	var missing []string
+	path = os.Expand(path, func(name string) string {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve home directory: %w", err)
+		}
+		path = filepath.Join(home, path[1:])
+	} else if strings.HasPrefix(path, "~") {
+		return "", fmt.Errorf("%s: only the home directory of the current user is supported", path)
+	}
+
+	if path == "" {
+		return "", errors.New("the path is empty")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	return filepath.Clean(path), nil
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExpandPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory: %v", err)
+	}
+	base := t.TempDir()
+	t.Setenv("DEVRIG_TEST_CACHE", filepath.Join(base, "ci"))
+
+	tests := map[string]string{
+		"~/.cache/devrig":            filepath.Join(home, ".cache", "devrig"),
+		"$DEVRIG_TEST_CACHE/ides":    filepath.Join(base, "ci", "ides"),
+		"${DEVRIG_TEST_CACHE}/ides/": filepath.Join(base, "ci", "ides"),
+		"build/cache":                filepath.Join(base, "build", "cache"),
+	}
+	for path, expected := range tests {
+		actual, err := ExpandPath(path, base)
+		if err != nil || actual != expected {
+			t.Errorf("ExpandPath(%q) = %q, %v; expected %q", path, actual, err, expected)
+		}
+	}
+
+	for _, path := range []string{"$DEVRIG_TEST_MISSING/cache", "~other/cache", ""} {
+		if _, err := ExpandPath(path, base); err == nil {
+			t.Errorf("Expected ExpandPath(%q) to fail", path)
+		}
+	}
+}
+
+func TestResolveCacheDir_Environment(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	t.Setenv(EnvCacheDir, "")
+	if dir := ResolveCacheDir(configPath); dir != filepath.Join(filepath.Dir(configPath), ".idew", "cache") {
+		t.Errorf("Expected the default cache folder, got %s", dir)
+	}
+
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+	t.Setenv(EnvCacheDir, cacheDir)
+	if dir := ResolveCacheDir(configPath); dir != cacheDir {
+		t.Errorf("Expected %s, got %s", cacheDir, dir)
+	}
+	if err := CheckCacheDir(configPath); err != nil {
+		t.Errorf("Expected the cache folder to be writable: %v", err)
+	}
+	if _, err := os.Stat(cacheDir); err != nil {
+		t.Errorf("Expected the cache folder to be created: %v", err)
+	}
+
+	// A broken value is reported by the check, the commands fall back to the default folder meanwhile
+	t.Setenv(EnvCacheDir, "$DEVRIG_TEST_MISSING/cache")
+	if err := CheckCacheDir(configPath); err == nil || !strings.Contains(err.Error(), EnvCacheDir) {
+		t.Errorf("Expected the variable to be reported, got %v", err)
+	}
+}
+
+func TestCheckCacheDir_ReadOnly(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Getuid() == 0 {
+		t.Skip("test needs POSIX permissions enforced for the user")
+	}
+	readOnly := t.TempDir()
+	if err := os.Chmod(readOnly, 0555); err != nil {
+		t.Fatalf("Failed to make folder read-only: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(readOnly, 0755) })
+
+	t.Setenv(EnvCacheDir, readOnly)
+	if err := CheckCacheDir(filepath.Join(t.TempDir(), "devrig.yaml")); err == nil {
+		t.Error("Expected the read-only cache folder to be rejected")
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeLegacyConfig creates a folder with .idew.yaml declaring the IDE of the given version
+func writeLegacyConfig(t *testing.T, version string) string {
+	t.Helper()
+	dir := t.TempDir()
+	content := "ide:\n  name: GoLand\n  version: " + version + "\n"
+	if err := os.WriteFile(filepath.Join(dir, LegacyConfigName), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write .idew.yaml: %v", err)
+	}
+	return dir
+}
+
+func TestCache_ReusesResolvedConfig(t *testing.T) {
+	dir := writeLegacyConfig(t, "2024.3")
+	cache := NewCache(2)
+
+	first, err := cache.Resolve(dir)
+	if err != nil {
+		t.Fatalf("Failed to resolve the config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, LegacyConfigName), []byte("ide:\n  name: GoLand\n  version: 2025.1\n"), 0644); err != nil {
+		t.Fatalf("Failed to update .idew.yaml: %v", err)
+	}
+	second, err := cache.Resolve(dir)
+	if err != nil {
+		t.Fatalf("Failed to resolve the config: %v", err)
+	}
+	if first != second || second.GetIDE().Version() != "2024.3" {
+		t.Errorf("Expected the cached config, got %s", second.GetIDE().Version())
+	}
+
+	cache.Clear()
+	third, err := cache.Resolve(dir)
+	if err != nil {
+		t.Fatalf("Failed to resolve the config: %v", err)
+	}
+	if third.GetIDE().Version() != "2025.1" {
+		t.Errorf("Expected the file to be parsed again after Clear, got %s", third.GetIDE().Version())
+	}
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	first, second, third := writeLegacyConfig(t, "2024.1"), writeLegacyConfig(t, "2024.2"), writeLegacyConfig(t, "2024.3")
+	cache := NewCache(2)
+
+	for _, dir := range []string{first, second, first, third} {
+		if _, err := cache.Resolve(dir); err != nil {
+			t.Fatalf("Failed to resolve the config of %s: %v", dir, err)
+		}
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 cached configs, got %d", cache.Len())
+	}
+	if _, ok := cache.entries[second]; ok {
+		t.Error("Expected the least recently used config to be evicted")
+	}
+	if _, ok := cache.entries[first]; !ok {
+		t.Error("Expected the recently used config to be kept")
+	}
+}
+
+func TestCacheFromContext(t *testing.T) {
+	cache := NewCache(1)
+	if CacheFromContext(WithCache(context.Background(), cache)) != cache {
+		t.Error("Expected the cache of the context")
+	}
+	if CacheFromContext(context.Background()) == CacheFromContext(context.Background()) {
+		t.Error("Expected a new cache without one in the context")
+	}
+
+	dir := writeLegacyConfig(t, "2024.3")
+	if _, err := ResolveConfigFromDirectory(WithCache(context.Background(), cache), dir); err != nil {
+		t.Fatalf("Failed to resolve the config: %v", err)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("Expected the config to be cached in the context cache, got %d", cache.Len())
+	}
+}
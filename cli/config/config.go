@@ -1,14 +1,19 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
-	"sync"
 
 	"github.com/goccy/go-yaml"
+	"jonnyzzz.com/devrig.dev/configfile"
 )
 
+// ApplyLayers merges devrig.local.yaml, the included files and the environment overrides over the parsed
+// config file. It is registered by the configservice package, which imports this one through layout
+var ApplyLayers = func(configPath string, values map[string]interface{}) error { return nil }
+
 // ideConfigImpl is the internal implementation of IDEConfig
 type ideConfigImpl struct {
 	NameV    string `yaml:"name"`
@@ -16,6 +21,16 @@ type ideConfigImpl struct {
 	BuildV   string `yaml:"build,omitempty"`
 }
 
+// NewIDEConfig returns the IDE request with the given name, version and optional build
+func NewIDEConfig(name string, version string, build string) IDEConfig {
+	return &ideConfigImpl{NameV: name, VersionV: version, BuildV: build}
+}
+
+// NewConfig returns the configuration of the IDE of the config file, the cache is resolved by ResolveCacheDir
+func NewConfig(configPath string, ide IDEConfig) Config {
+	return &configImpl{configPath: configPath, cacheDir: ResolveCacheDir(configPath), ide: ide}
+}
+
 func (i *ideConfigImpl) Name() string    { return i.NameV }
 func (i *ideConfigImpl) Version() string { return i.VersionV }
 func (i *ideConfigImpl) Build() string   { return i.BuildV }
@@ -44,90 +59,67 @@ func (c *configImpl) String() string {
 	return fmt.Sprintf("ConfigPath: %s, CacheDir: %s", c.configPath, c.cacheDir)
 }
 
-var (
-	instances = make(map[string]Config)
-	mutex     sync.RWMutex
-)
-
-func ResolveConfig() (Config, error) {
-	return ResolveConfigFromDirectory(".")
+// ResolveConfig returns the configuration of the legacy .idew.yaml found from the current directory,
+// it is cached in the config cache of the context.
+//
+// Deprecated: the IDE is declared in the ide section of devrig.yaml, use configservice.ConfigService.IDE().
+// `devrig config migrate` moves the ide section of .idew.yaml into devrig.yaml
+func ResolveConfig(ctx context.Context) (Config, error) {
+	return ResolveConfigFromDirectory(ctx, ".")
 }
 
-func ResolveConfigFromDirectory(cwd string) (Config, error) {
-	// Convert to absolute path for consistent caching
-	absCwd, err := filepath.Abs(cwd)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path: %w", err)
-	}
-
-	// Check if we already have an instance for this directory
-	mutex.RLock()
-	if instance, exists := instances[absCwd]; exists {
-		mutex.RUnlock()
-		return instance, nil
-	}
-	mutex.RUnlock()
-
-	// Create new instance
-	mutex.Lock()
-	defer mutex.Unlock()
-
-	// Double-check after acquiring write lock
-	if instance, exists := instances[absCwd]; exists {
-		return instance, nil
-	}
-
-	var instance Config
-	var configErr error
-	var configPath string
-	configPath, configErr = FindConfigFile(cwd)
-	if configErr != nil {
-		return nil, fmt.Errorf("failed to resolve config: %w", configErr)
-	}
-
-	// Create cache directory next to config file
-	cacheDir := filepath.Join(filepath.Dir(configPath), ".idew", "cache")
-
-	// Ensure cache directory exists
-	if configErr = os.MkdirAll(cacheDir, 0755); configErr != nil {
-		return nil, fmt.Errorf("failed to create cache directory: %w", configErr)
-	}
+// ResolveConfigFromDirectory returns the configuration of the legacy .idew.yaml found from the directory,
+// it is cached in the config cache of the context.
+//
+// Deprecated: use configservice.ConfigService.IDE() to read the ide section of devrig.yaml
+func ResolveConfigFromDirectory(ctx context.Context, cwd string) (Config, error) {
+	return CacheFromContext(ctx).Resolve(cwd)
+}
 
-	// Parse config file
-	ide, configErr := parseConfigFile(configPath)
-	if configErr != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", configErr)
+// ReadIDEConfig reads the ide section of the config file, it returns nil when there is no ide section.
+//
+// Deprecated: use configservice.ConfigService.IDE(), it validates the section and reads its launch settings
+func ReadIDEConfig(configPath string) (IDEConfig, error) {
+	ide, err := parseConfigFile(configPath)
+	if errors.Is(err, errMissingIDE) {
+		return nil, nil
 	}
-
-	instance = &configImpl{
-		configPath: configPath,
-		cacheDir:   cacheDir,
-		ide:        ide,
+	if err != nil {
+		return nil, err
 	}
-
-	// Cache the instance
-	instances[absCwd] = instance
-
-	return instance, nil
+	return ide, nil
 }
 
+// errMissingIDE is returned by parseConfigFile when the config file has no ide section
+var errMissingIDE = errors.New("missing ide configuration")
+
 func parseConfigFile(configPath string) (*ideConfigImpl, error) {
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	values, err := configfile.Decode(configPath, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if err := ApplyLayers(configPath, values); err != nil {
+		return nil, err
+	}
+
 	var configData struct {
 		IDE *ideConfigImpl `yaml:"ide"`
 	}
-
-	err = yaml.Unmarshal(data, &configData)
-	if err != nil {
+	// The values are encoded again to decode the ide section with the layers
+	if data, err = yaml.Marshal(values); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &configData); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
 	if configData.IDE == nil {
-		return nil, fmt.Errorf("missing ide configuration")
+		return nil, errMissingIDE
 	}
 
 	if configData.IDE.NameV == "" {
@@ -4,12 +4,39 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+
+	"jonnyzzz.com/devrig.dev/deprecation"
 )
 
+// LegacyConfigName is the configuration file of the first devrig versions, it only declared the IDE
+const LegacyConfigName = ".idew.yaml"
+
+func init() {
+	deprecation.Register(deprecation.Deprecation{
+		Kind:        deprecation.KindFile,
+		Name:        LegacyConfigName,
+		Replacement: "the ide section of devrig.yaml",
+		Hint:        "Its ide section is ignored, run `devrig config migrate` to move it into devrig.yaml",
+	})
+}
+
 // FindConfigFile searches for .idew.yaml file starting from the given directory
 // and moving up the directory tree until it finds the file or reaches the root.
+//
+// Deprecated: .idew.yaml is replaced by the ide section of devrig.yaml, use FindInParents to search for devrig.yaml
 func FindConfigFile(startDir string) (string, error) {
-	configFileName := ".idew.yaml"
+	configFileName := LegacyConfigName
+	dir, err := FindInParents(startDir, configFileName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configFileName), nil
+}
+
+// FindInParents searches for the directory containing any of the given files, starting from the given directory
+// and moving up the directory tree until it finds one or reaches the root.
+// Returns an error wrapping os.ErrNotExist if none of the directories contains the files.
+func FindInParents(startDir string, fileNames ...string) (string, error) {
 	dir := startDir
 
 	dir, err := filepath.Abs(dir)
@@ -26,20 +53,17 @@ func FindConfigFile(startDir string) (string, error) {
 			return "", fmt.Errorf("failed to resolve symlink in directory %s: %w", dir, err)
 		}
 
-		// Debug: searching for config file in directory: absDir
-
 		// Check for infinite loops due to symlinks
 		if visitedDirs[absDir] {
 			return "", fmt.Errorf("potential symlink loop detected in directory %s", absDir)
 		}
 		visitedDirs[absDir] = true
 
-		// Construct the full path to the potential config file
-		configPath := filepath.Join(absDir, configFileName)
-
-		// Check if the file exists
-		if _, err := os.Stat(configPath); err == nil {
-			return configPath, nil
+		// Check if any of the files exists in the directory
+		for _, fileName := range fileNames {
+			if _, err := os.Stat(filepath.Join(absDir, fileName)); err == nil {
+				return absDir, nil
+			}
 		}
 
 		// Get the parent directory
@@ -47,7 +71,7 @@ func FindConfigFile(startDir string) (string, error) {
 
 		// If we've reached the root directory, stop searching
 		if parent == absDir {
-			return "", fmt.Errorf("configuration file %s not found in any parent directory", configFileName)
+			return "", fmt.Errorf("configuration file %s not found in any parent directory: %w", fileNames[0], os.ErrNotExist)
 		}
 
 		// Move up to the parent directory
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -124,3 +125,27 @@ func TestFindConfigFileSymlinkLoop(t *testing.T) {
 		t.Errorf("expected an error due to symlink loop, but got none")
 	}
 }
+
+// TestFindInParentsAnyFile tests that the closest directory with any of the files is returned
+func TestFindInParentsAnyFile(t *testing.T) {
+	tempDir := t.TempDir()
+	subDir := filepath.Join(tempDir, "a", "b")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "devrig.toml"), []byte(""), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	dir, err := FindInParents(subDir, "devrig.yaml", "devrig.toml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dir != tempDir {
+		t.Errorf("expected %s, got %s", tempDir, dir)
+	}
+
+	if _, err := FindInParents(subDir, "devrig.json"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+// Package configcmd implements the `devrig config` commands for authors of devrig.yaml
+package configcmd
+
+import (
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// ReleaseFetcher downloads and verifies the signed release metadata
+type ReleaseFetcher interface {
+	FetchUpdateInfo(url string) (*updates.UpdateInfo, error)
+}
+
+// NewConfigCommand creates the config command group
+func NewConfigCommand(configPath func() string, fetcher ReleaseFetcher) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect, validate and edit devrig.yaml",
+	}
+	cmd.AddCommand(newVerifyArtifactsCommand(configPath, fetcher))
+	cmd.AddCommand(newValidateCommand(configPath))
+	cmd.AddCommand(newGetCommand(configPath))
+	cmd.AddCommand(newSetCommand(configPath))
+	cmd.AddCommand(newMigrateCommand(configPath))
+	return cmd
+}
@@ -0,0 +1,55 @@
+package configcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configservice"
+)
+
+func newMigrateCommand(configPath func() string) *cobra.Command {
+	var keep bool
+	cmd := &cobra.Command{
+		Use:   "migrate [.idew.yaml]",
+		Short: "Move the ide section of the legacy .idew.yaml into devrig.yaml",
+		Long: `Move the ide section of the legacy .idew.yaml into the ide section of devrig.yaml.
+
+.idew.yaml is deprecated, devrig.yaml declares the IDE of the project together
+with the other sections. The .idew.yaml next to devrig.yaml is migrated by default,
+it is removed after the migration unless --keep is set. An ide section that
+already exists in devrig.yaml is kept if it declares the same IDE.
+
+Examples:
+  devrig config migrate
+  devrig config migrate --keep
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := configPath()
+			legacyPath := filepath.Join(filepath.Dir(path), config.LegacyConfigName)
+			if len(args) > 0 {
+				legacyPath = args[0]
+			}
+
+			ide, err := configservice.MigrateLegacyIde(configservice.NewConfigService(path), legacyPath)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Migrated %s %s from %s to the ide section of %s\n", ide.Name, ide.Version, legacyPath, path)
+
+			if keep {
+				return nil
+			}
+			if err := os.Remove(legacyPath); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", legacyPath, err)
+			}
+			cmd.Printf("Removed %s\n", legacyPath)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&keep, "keep", false, "Keep .idew.yaml after the migration")
+	return cmd
+}
@@ -0,0 +1,56 @@
+package configcmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/output"
+)
+
+func TestConfigMigrate(t *testing.T) {
+	configPath := writeConfig(t, "https://example.com/devrig")
+	legacyPath := filepath.Join(filepath.Dir(configPath), ".idew.yaml")
+	legacy := "wrapper:\n  version: 0.0.1\nide:\n  name: GoLand\n  version: 2024.3\n  build: 243.123\n"
+	if err := os.WriteFile(legacyPath, []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write .idew.yaml: %v", err)
+	}
+
+	if _, err := runConfigCommand(t, configPath, output.FormatText, "migrate", "--keep"); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if _, err := os.Stat(legacyPath); err != nil {
+		t.Errorf("Expected .idew.yaml to be kept: %v", err)
+	}
+	ide, err := configservice.NewConfigService(configPath).IDE().ReadIde()
+	if err != nil || ide == nil || ide.Name != "GoLand" || ide.Build != "243.123" {
+		t.Fatalf("Expected the ide section in devrig.yaml, got %+v, %v", ide, err)
+	}
+
+	// The same IDE is migrated again without changes, then the file is removed
+	if _, err := runConfigCommand(t, configPath, output.FormatText, "migrate"); err != nil {
+		t.Fatalf("Failed to migrate again: %v", err)
+	}
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Errorf("Expected .idew.yaml to be removed: %v", err)
+	}
+}
+
+func TestConfigMigrate_DifferentIde(t *testing.T) {
+	configPath := writeConfig(t, "https://example.com/devrig")
+	if err := configservice.NewConfigService(configPath).IDE().UpdateIde(&configservice.IdeSection{Name: "Rider", Version: "2025.1"}); err != nil {
+		t.Fatalf("Failed to write the ide section: %v", err)
+	}
+	legacyPath := filepath.Join(t.TempDir(), ".idew.yaml")
+	if err := os.WriteFile(legacyPath, []byte("ide:\n  name: GoLand\n  version: 2024.3\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .idew.yaml: %v", err)
+	}
+
+	if _, err := runConfigCommand(t, configPath, output.FormatText, "migrate", legacyPath); err == nil {
+		t.Error("Expected a different IDE to fail the migration")
+	}
+	if _, err := os.Stat(legacyPath); err != nil {
+		t.Errorf("Expected .idew.yaml to stay after a failed migration: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package configcmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/output"
+)
+
+// validateReport is the JSON output of `devrig config validate`
+type validateReport struct {
+	Path     string                  `json:"path"`
+	Valid    bool                    `json:"valid"`
+	Problems []configservice.Problem `json:"problems"`
+}
+
+func newValidateCommand(configPath func() string) *cobra.Command {
+	var strict bool
+
+	cmd := &cobra.Command{
+		Use:   "validate [devrig.yaml]",
+		Short: "Check all sections of devrig.yaml and report every problem",
+		Long: `Check devrig.yaml against the schema of all sections it supports.
+
+All problems are reported at once with their line and column. Errors are
+wrong types, missing required keys and invalid values, unknown keys are
+warnings. The command fails on errors, use --strict to fail on warnings too.
+
+Examples:
+  devrig config validate
+  devrig config validate path/to/new/devrig.yaml --strict
+  devrig config validate --output json
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := configPath()
+			if len(args) > 0 {
+				absPath, err := filepath.Abs(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to resolve %s: %w", args[0], err)
+				}
+				path = absPath
+			}
+
+			problems, err := configservice.NewConfigService(path).Validate()
+			if err != nil {
+				return err
+			}
+
+			errorCount, warningCount := 0, 0
+			for _, problem := range problems {
+				if problem.Severity == configservice.SeverityError {
+					errorCount++
+				} else {
+					warningCount++
+				}
+			}
+			failed := errorCount > 0 || (strict && warningCount > 0)
+
+			out := cmd.OutOrStdout()
+			if output.FormatFromContext(cmd.Context()) == output.FormatJSON {
+				if problems == nil {
+					problems = []configservice.Problem{}
+				}
+				encoder := json.NewEncoder(out)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(validateReport{Path: path, Valid: !failed, Problems: problems}); err != nil {
+					return fmt.Errorf("failed to write report: %w", err)
+				}
+			} else {
+				name := filepath.Base(path)
+				for _, problem := range problems {
+					// The position follows the file name like in compiler messages, devrig.yaml:3:5: error: ...
+					separator := ": "
+					if problem.Line > 0 {
+						separator = ":"
+					}
+					_, _ = fmt.Fprintf(out, "%s%s%s\n", name, separator, problem)
+				}
+				if len(problems) == 0 {
+					_, _ = fmt.Fprintf(out, "%s is valid\n", path)
+				} else {
+					_, _ = fmt.Fprintf(out, "%d errors, %d warnings\n", errorCount, warningCount)
+				}
+			}
+
+			if failed {
+				// The problems are already printed, the usage would hide them
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%s has %d errors and %d warnings", path, errorCount, warningCount)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail on warnings, e.g. unknown keys")
+	return cmd
+}
@@ -0,0 +1,68 @@
+package configcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/output"
+)
+
+func runValidate(t *testing.T, configPath string, format output.Format, args ...string) (string, error) {
+	t.Helper()
+	cmd := NewConfigCommand(func() string { return configPath }, &mockFetcher{})
+	var out, stderr bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs(append([]string{"validate"}, args...))
+	err := cmd.ExecuteContext(output.WithFormat(t.Context(), format))
+	return out.String(), err
+}
+
+func TestValidate_Valid(t *testing.T) {
+	configPath := writeConfig(t, "https://example.com/devrig")
+
+	out, err := runValidate(t, configPath, output.FormatText)
+	if err != nil || !strings.Contains(out, "is valid") {
+		t.Errorf("Expected devrig.yaml to be valid, got: %v\n%s", err, out)
+	}
+}
+
+func TestValidate_WarningsAndStrict(t *testing.T) {
+	configPath := writeConfig(t, "https://example.com/devrig")
+	data, _ := os.ReadFile(configPath)
+	if err := os.WriteFile(configPath, append(data, []byte("future: {}\n")...), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+
+	out, err := runValidate(t, configPath, output.FormatText)
+	if err != nil || !strings.Contains(out, "devrig.yaml:7:1: warning: future: unknown section future") {
+		t.Errorf("Expected the unknown section warning, got: %v\n%s", err, out)
+	}
+	if _, err := runValidate(t, configPath, output.FormatText, "--strict"); err == nil {
+		t.Errorf("Expected --strict to fail on warnings")
+	}
+}
+
+func TestValidate_JSON(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	content := "devrig:\n  binaries:\n    linux-x86_64:\n      url: 42\n      sha512: [a]\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+
+	out, err := runValidate(t, "", output.FormatJSON, configPath)
+	if err == nil {
+		t.Errorf("Expected errors to fail the command")
+	}
+	var report validateReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("Failed to parse report: %v\n%s", err, out)
+	}
+	if report.Valid || len(report.Problems) != 1 || report.Problems[0].Path != "devrig.binaries.linux-x86_64.sha512" || report.Problems[0].Line != 5 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
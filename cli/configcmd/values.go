@@ -0,0 +1,94 @@
+package configcmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/goccy/go-yaml"
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/output"
+)
+
+// valueReport is the JSON output of `devrig config get`
+type valueReport struct {
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+func newGetCommand(configPath func() string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <path>",
+		Short: "Print a value of devrig.yaml",
+		Long: `Print the value at the path in devrig.yaml, e.g. for scripts.
+
+Keys are separated by dots, list elements are selected by index. Scalars are
+printed as they are, sections are printed as YAML. The command fails if there
+is no value at the path.
+
+Examples:
+  devrig config get ide.version
+  devrig config get devrig.binaries.linux-x86_64.url
+  devrig config get auth.providers[0].client_id
+  devrig config get tools --output json
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value, err := configservice.NewConfigService(configPath()).Values().GetValue(args[0])
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if output.FormatFromContext(cmd.Context()) == output.FormatJSON {
+				encoder := json.NewEncoder(out)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(valueReport{Path: args[0], Value: value}); err != nil {
+					return fmt.Errorf("failed to write value: %w", err)
+				}
+				return nil
+			}
+
+			switch value.(type) {
+			case map[string]interface{}, []interface{}:
+				data, err := yaml.Marshal(value)
+				if err != nil {
+					return fmt.Errorf("failed to format %s: %w", args[0], err)
+				}
+				_, _ = fmt.Fprint(out, string(data))
+			case nil:
+				_, _ = fmt.Fprintln(out)
+			default:
+				_, _ = fmt.Fprintln(out, value)
+			}
+			return nil
+		},
+	}
+}
+
+func newSetCommand(configPath func() string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <path> <value>",
+		Short: "Change a value of devrig.yaml",
+		Long: `Change the value at the path in devrig.yaml, comments and formatting are preserved.
+
+Missing keys and sections are added. The value is converted to the type the
+key expects, and the change is rejected if the key is unknown or the section
+becomes invalid, see 'devrig config validate'.
+
+Examples:
+  devrig config set devrig.channel beta
+  devrig config set ide.version 2025.2
+  devrig config set maintenance.auto_gc true
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := configPath()
+			if err := configservice.NewConfigService(path).Values().SetValue(args[0], args[1]); err != nil {
+				return err
+			}
+			cmd.Printf("Set %s to %s in %s\n", args[0], args[1], path)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,51 @@
+package configcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/output"
+)
+
+func runConfigCommand(t *testing.T, configPath string, format output.Format, args ...string) (string, error) {
+	t.Helper()
+	cmd := NewConfigCommand(func() string { return configPath }, &mockFetcher{})
+	var out, stderr bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs(args)
+	err := cmd.ExecuteContext(output.WithFormat(t.Context(), format))
+	return out.String(), err
+}
+
+func TestConfigGetSet(t *testing.T) {
+	configPath := writeConfig(t, "https://example.com/devrig")
+
+	if _, err := runConfigCommand(t, configPath, output.FormatText, "set", "devrig.channel", "beta"); err != nil {
+		t.Fatalf("Failed to set devrig.channel: %v", err)
+	}
+	out, err := runConfigCommand(t, configPath, output.FormatText, "get", "devrig.channel")
+	if err != nil || out != "beta\n" {
+		t.Errorf("Expected beta, got %q, %v", out, err)
+	}
+
+	out, err = runConfigCommand(t, configPath, output.FormatText, "get", "devrig.binaries")
+	if err != nil || !strings.Contains(out, "linux-x86_64:\n") || !strings.Contains(out, "  url: https://example.com/devrig\n") {
+		t.Errorf("Expected the binaries as YAML, got %q, %v", out, err)
+	}
+
+	out, err = runConfigCommand(t, configPath, output.FormatJSON, "get", "devrig.version")
+	var report valueReport
+	if err != nil || json.Unmarshal([]byte(out), &report) != nil || report.Value != "0.79.6" {
+		t.Errorf("Expected the version in JSON, got %q, %v", out, err)
+	}
+
+	if _, err := runConfigCommand(t, configPath, output.FormatText, "get", "ide.version"); err == nil {
+		t.Errorf("Expected a missing value to fail")
+	}
+	if _, err := runConfigCommand(t, configPath, output.FormatText, "set", "devrig.channel", "weekly"); err == nil {
+		t.Errorf("Expected an invalid channel to be rejected")
+	}
+}
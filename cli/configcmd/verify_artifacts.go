@@ -0,0 +1,215 @@
+package configcmd
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/progress"
+	"jonnyzzz.com/devrig.dev/summary"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+func newVerifyArtifactsCommand(configPath func() string, fetcher ReleaseFetcher) *cobra.Command {
+	var full bool
+
+	cmd := &cobra.Command{
+		Use:   "verify-artifacts [devrig.yaml]",
+		Short: "Check that all artifacts of devrig.yaml are available and match their hashes",
+		Long: `Verify a devrig.yaml before rolling it out, without installing anything.
+
+The pinned version is checked against the signed release metadata of devrig.dev,
+then every binary URL and mirror is requested. By default only the headers are
+downloaded, use --full to download the files and verify their SHA-512 hashes.
+
+Examples:
+  devrig config verify-artifacts
+  devrig config verify-artifacts path/to/new/devrig.yaml --full
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := configPath()
+			if len(args) > 0 {
+				absPath, err := filepath.Abs(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to resolve %s: %w", args[0], err)
+				}
+				path = absPath
+			}
+
+			service := configservice.NewConfigService(path)
+			endpoints, err := updates.ResolveEndpoints(service)
+			if err != nil {
+				return err
+			}
+			if err := offline.Check("artifacts of "+path, endpoints.BaseURL, "verify the artifacts on a machine with network access"); err != nil {
+				return err
+			}
+
+			section, err := service.Binaries().ReadDevrigSection()
+			if err != nil {
+				return err
+			}
+
+			var steps summary.Summary
+			verifier := &artifactVerifier{fetcher: fetcher, endpoints: endpoints, client: http.DefaultClient, full: full}
+			verifier.verify(cmd.Context(), section, &steps)
+			steps.Print(cmd.Context(), cmd.OutOrStdout())
+
+			failed := 0
+			for _, step := range steps.Steps {
+				if step.Status == summary.StatusFailed {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d artifact checks failed for %s", failed, len(steps.Steps), path)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&full, "full", false, "Download the files and verify their SHA-512 hashes")
+	return cmd
+}
+
+// artifactVerifier checks the devrig section against the signed release and the download hosts
+type artifactVerifier struct {
+	fetcher   ReleaseFetcher
+	endpoints updates.Endpoints
+	client    *http.Client
+	full      bool
+}
+
+// verify records a step per check, all checks are executed even if some fail
+func (v *artifactVerifier) verify(ctx context.Context, section *configservice.DevrigSection, steps *summary.Summary) {
+	platforms := make([]string, 0, len(section.Binaries))
+	for platform := range section.Binaries {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+
+	if section.Version == "" {
+		steps.Skip("Signed release metadata", "no version is pinned in devrig.yaml")
+	} else {
+		_ = steps.Run("Signed release metadata "+section.Version, true, func() error {
+			return v.verifyRelease(section, platforms)
+		})
+	}
+
+	for _, platform := range platforms {
+		binary := section.Binaries[platform]
+		for _, url := range binary.URLs() {
+			_ = steps.Run(platform+" "+url, true, func() error {
+				if v.full {
+					return v.verifyContent(ctx, url, binary.SHA512)
+				}
+				return v.verifyAvailable(ctx, url)
+			})
+		}
+	}
+}
+
+// verifyRelease checks that the hashes of devrig.yaml match the signed metadata of the pinned release
+func (v *artifactVerifier) verifyRelease(section *configservice.DevrigSection, platforms []string) error {
+	updateInfo, err := v.fetcher.FetchUpdateInfo(v.endpoints.ReleaseJSONURL(section.Version))
+	if err != nil {
+		return fmt.Errorf("failed to fetch release metadata: %w", err)
+	}
+	release := updateInfo.ToDevrigSection()
+
+	var problems []string
+	for _, platform := range platforms {
+		signed, ok := release.Binaries[platform]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s is not part of release %s", platform, section.Version))
+			continue
+		}
+		if !strings.EqualFold(signed.SHA512, section.Binaries[platform].SHA512) {
+			problems = append(problems, fmt.Sprintf("SHA-512 of %s differs from the signed release", platform))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// verifyAvailable requests the headers of the artifact
+func (v *artifactVerifier) verifyAvailable(ctx context.Context, url string) error {
+	resp, err := v.request(ctx, "HEAD", url)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+
+	// Some hosts do not support HEAD, the first byte is enough to check the availability
+	if resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+		resp, err = v.request(ctx, "GET", url)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return &devrigErrors.NetworkError{URL: url, Err: fmt.Errorf("unexpected status code: %d", resp.StatusCode)}
+	}
+	return nil
+}
+
+// verifyContent downloads the artifact and compares its SHA-512 hash
+func (v *artifactVerifier) verifyContent(ctx context.Context, url string, expected string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", &devrigErrors.NetworkError{URL: url, Err: err})
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return &devrigErrors.NetworkError{URL: url, Err: fmt.Errorf("unexpected status code: %d", resp.StatusCode)}
+	}
+
+	hasher := sha512.New()
+	tracker := progress.Track(ctx, "Verifying "+url, resp.ContentLength, 0)
+	_, err = io.Copy(hasher, tracker.Reader(resp.Body))
+	tracker.Finish()
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	if !strings.EqualFold(actual, expected) {
+		return &devrigErrors.ChecksumMismatchError{Subject: url, Expected: strings.ToLower(expected), Actual: actual}
+	}
+	return nil
+}
+
+func (v *artifactVerifier) request(ctx context.Context, method string, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if method == "GET" {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request %s: %w", url, &devrigErrors.NetworkError{URL: url, Err: err})
+	}
+	return resp, nil
+}
@@ -0,0 +1,144 @@
+package configcmd
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+var testBinary = []byte("devrig binary content")
+
+// mockFetcher returns the prepared release, or an error
+type mockFetcher struct {
+	requestedURL string
+	info         *updates.UpdateInfo
+	err          error
+}
+
+func (m *mockFetcher) FetchUpdateInfo(url string) (*updates.UpdateInfo, error) {
+	m.requestedURL = url
+	return m.info, m.err
+}
+
+func binarySHA512(data []byte) string {
+	sum := sha512.Sum512(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// newArtifactServer serves the test binary at /devrig, /broken has other content and everything else is missing
+func newArtifactServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/devrig":
+			_, _ = w.Write(testBinary)
+		case "/broken":
+			_, _ = w.Write([]byte("tampered"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func writeConfig(t *testing.T, url string, mirrors ...string) string {
+	t.Helper()
+	content := fmt.Sprintf("devrig:\n  version: 0.79.6\n  binaries:\n    linux-x86_64:\n      url: %s\n      sha512: %s\n", url, binarySHA512(testBinary))
+	if len(mirrors) > 0 {
+		content += "      mirrors: [" + strings.Join(mirrors, ", ") + "]\n"
+	}
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	return configPath
+}
+
+func signedRelease(sha string) *updates.UpdateInfo {
+	return &updates.UpdateInfo{
+		Version:  "0.79.6",
+		Binaries: []updates.BinaryInfo{{OS: "linux", Arch: "x86_64", URL: "https://example.com/devrig", SHA512: sha}},
+	}
+}
+
+func runVerify(t *testing.T, fetcher ReleaseFetcher, args ...string) (string, error) {
+	t.Helper()
+	cmd := NewConfigCommand(func() string { return filepath.Join(t.TempDir(), "missing.yaml") }, fetcher)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(append([]string{"verify-artifacts"}, args...))
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestVerifyArtifacts_OK(t *testing.T) {
+	server := newArtifactServer(t)
+	configPath := writeConfig(t, server.URL+"/devrig", server.URL+"/devrig")
+	fetcher := &mockFetcher{info: signedRelease(binarySHA512(testBinary))}
+
+	for _, args := range [][]string{{configPath}, {configPath, "--full"}} {
+		output, err := runVerify(t, fetcher, args...)
+		if err != nil {
+			t.Fatalf("Failed to verify artifacts with %v: %v\n%s", args, err, output)
+		}
+		if strings.Contains(output, "FAILED") {
+			t.Errorf("Expected all checks to pass with %v, got:\n%s", args, output)
+		}
+	}
+	if fetcher.requestedURL != "https://devrig.dev/download/v0.79.6/release.json" {
+		t.Errorf("Unexpected metadata URL: %s", fetcher.requestedURL)
+	}
+}
+
+func TestVerifyArtifacts_MissingMirror(t *testing.T) {
+	server := newArtifactServer(t)
+	configPath := writeConfig(t, server.URL+"/devrig", server.URL+"/missing")
+
+	output, err := runVerify(t, &mockFetcher{info: signedRelease(binarySHA512(testBinary))}, configPath)
+	if err == nil || !strings.Contains(err.Error(), "1 of 3") {
+		t.Fatalf("Expected one failed check, got: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "404") {
+		t.Errorf("Expected the status code in the output, got:\n%s", output)
+	}
+}
+
+func TestVerifyArtifacts_HashMismatch(t *testing.T) {
+	server := newArtifactServer(t)
+	configPath := writeConfig(t, server.URL+"/broken")
+
+	// The headers look fine, only the full download detects the tampered file
+	if output, err := runVerify(t, &mockFetcher{info: signedRelease(binarySHA512(testBinary))}, configPath); err != nil {
+		t.Fatalf("Expected the header check to pass, got: %v\n%s", err, output)
+	}
+
+	output, err := runVerify(t, &mockFetcher{info: signedRelease(binarySHA512(testBinary))}, configPath, "--full")
+	if err == nil || !strings.Contains(output, "checksum mismatch") {
+		t.Errorf("Expected checksum mismatch, got: %v\n%s", err, output)
+	}
+}
+
+func TestVerifyArtifacts_SignedReleaseMismatch(t *testing.T) {
+	server := newArtifactServer(t)
+	configPath := writeConfig(t, server.URL+"/devrig")
+
+	output, err := runVerify(t, &mockFetcher{info: signedRelease(strings.Repeat("a", 128))}, configPath)
+	if err == nil || !strings.Contains(output, "differs from the signed release") {
+		t.Errorf("Expected the signed release mismatch, got: %v\n%s", err, output)
+	}
+
+	output, err = runVerify(t, &mockFetcher{err: fmt.Errorf("invalid signature")}, configPath)
+	if err == nil || !strings.Contains(output, "invalid signature") {
+		t.Errorf("Expected the signature error, got: %v\n%s", err, output)
+	}
+}
@@ -0,0 +1,395 @@
+// Package configfile reads and edits the project configuration in the supported formats: devrig.yaml,
+// devrig.toml and devrig.json. All formats describe the same sections, the format is detected by the file extension
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Format is the syntax of a configuration file
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+	FormatJSON Format = "json"
+)
+
+// FileNames are the names of the project configuration in the order of precedence,
+// the first existing one is used when a folder has several of them
+var FileNames = []string{"devrig.yaml", "devrig.toml", "devrig.json"}
+
+// DetectFormat returns the format of the file by its extension, files with other extensions are YAML
+func DetectFormat(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return FormatTOML
+	case ".json":
+		return FormatJSON
+	default:
+		return FormatYAML
+	}
+}
+
+// Find returns the configuration file in the folder by the precedence of FileNames, or devrig.yaml if there is none.
+// The other existing configuration files are returned as shadowed, they are ignored
+func Find(dir string) (path string, shadowed []string) {
+	for _, name := range FileNames {
+		candidate := filepath.Join(dir, name)
+		if _, err := os.Stat(candidate); err != nil {
+			continue
+		}
+		if path == "" {
+			path = candidate
+		} else {
+			shadowed = append(shadowed, candidate)
+		}
+	}
+	if path == "" {
+		path = filepath.Join(dir, FileNames[0])
+	}
+	return path, shadowed
+}
+
+// Decode parses the content of the configuration file into a map, the format is detected by the path
+func Decode(path string, data []byte) (map[string]interface{}, error) {
+	var values map[string]interface{}
+	switch DetectFormat(path) {
+	case FormatTOML:
+		doc, err := parseTOML(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse TOML in %s: %w", path, err)
+		}
+		values = doc.values
+	case FormatJSON:
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON in %s: %w", path, err)
+		}
+	default:
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML in %s: %w", path, err)
+		}
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	return values, nil
+}
+
+// ToYAML converts the content of the configuration file to YAML, so it can be validated and queried by path.
+// JSON is valid YAML and is returned as is, so the positions of the problems stay the same
+func ToYAML(path string, data []byte) ([]byte, error) {
+	if DetectFormat(path) != FormatTOML {
+		return data, nil
+	}
+	values, err := Decode(path, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	converted, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s to YAML: %w", path, err)
+	}
+	return converted, nil
+}
+
+// NewFile returns the content of a new TOML or JSON configuration file with a single section.
+// The value is made of maps, lists and scalars, as YAML decodes them
+func NewFile(format Format, key string, value interface{}) ([]byte, error) {
+	switch format {
+	case FormatTOML:
+		header := "# devrig.toml - Main configuration file for devrig tool\n"
+		return replaceTOMLSection([]byte(header), key, value)
+	case FormatJSON:
+		return encodeJSON(yaml.MapSlice{{Key: key, Value: value}}), nil
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+// ReplaceSection replaces or appends the top-level section of a TOML or JSON configuration file.
+// TOML comments and formatting of all other sections are preserved, JSON keeps the order of the keys
+func ReplaceSection(format Format, data []byte, key string, value interface{}) ([]byte, error) {
+	switch format {
+	case FormatTOML:
+		return replaceTOMLSection(data, key, value)
+	case FormatJSON:
+		document, err := decodeOrderedJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		return encodeJSON(replaceKey(document, key, value)), nil
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+// SetValue replaces or adds the scalar value at the path, e.g. tools.node or auth.providers[0].client_id,
+// in a TOML or JSON configuration file. TOML values are replaced in their line when possible
+func SetValue(format Format, data []byte, path string, value interface{}) ([]byte, error) {
+	segments, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatTOML:
+		if !strings.Contains(path, "[") {
+			updated, ok, err := setTOMLValue(data, strings.Split(path, "."), value)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return updated, nil
+			}
+		}
+		// The value is in an inline table, an array or a new table, the whole section is written again
+		doc, err := parseTOML(data)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := setPath(doc.values, segments, value)
+		if err != nil {
+			return nil, err
+		}
+		key := segments[0].key
+		return replaceTOMLSection(data, key, updated.(map[string]interface{})[key])
+	case FormatJSON:
+		document, err := decodeOrderedJSON(data)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := setPath(document, segments, value)
+		if err != nil {
+			return nil, err
+		}
+		return encodeJSON(updated), nil
+	default:
+		return nil, fmt.Errorf("unsupported format %s", format)
+	}
+}
+
+// pathSegment is a key of a path with the list indexes that follow it, e.g. providers[0]
+type pathSegment struct {
+	key     string
+	indexes []int
+}
+
+func parsePath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	for _, part := range strings.Split(path, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" {
+			return nil, fmt.Errorf("invalid path %s", path)
+		}
+		segment := pathSegment{key: key}
+		for rest != "" {
+			var index string
+			index, rest, _ = strings.Cut(rest, "]")
+			rest = strings.TrimPrefix(rest, "[")
+			number, err := strconv.Atoi(index)
+			if err != nil {
+				return nil, fmt.Errorf("invalid list index in %s", path)
+			}
+			segment.indexes = append(segment.indexes, number)
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// setPath sets the value in the maps and lists of the node, missing keys are created, missing list elements are an error
+func setPath(node interface{}, segments []pathSegment, value interface{}) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+	segment := segments[0]
+
+	child := func(current interface{}) (interface{}, error) {
+		for i, index := range segment.indexes {
+			list, ok := current.([]interface{})
+			if !ok || index < 0 || index >= len(list) {
+				return nil, fmt.Errorf("list element %s[%d] does not exist", segment.key, segment.indexes[i])
+			}
+			current = list[index]
+		}
+		return current, nil
+	}
+	update := func(current interface{}) (interface{}, error) {
+		if len(segment.indexes) == 0 {
+			return setPath(current, segments[1:], value)
+		}
+		parent := current
+		for _, index := range segment.indexes[:len(segment.indexes)-1] {
+			parent = parent.([]interface{})[index]
+		}
+		list := parent.([]interface{})
+		last := segment.indexes[len(segment.indexes)-1]
+		updated, err := setPath(list[last], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		list[last] = updated
+		return current, nil
+	}
+
+	switch mapping := node.(type) {
+	case yaml.MapSlice:
+		for i, item := range mapping {
+			if fmt.Sprint(item.Key) != segment.key {
+				continue
+			}
+			if _, err := child(item.Value); err != nil {
+				return nil, err
+			}
+			updated, err := update(item.Value)
+			if err != nil {
+				return nil, err
+			}
+			mapping[i].Value = updated
+			return mapping, nil
+		}
+		if len(segment.indexes) > 0 {
+			return nil, fmt.Errorf("list element %s[%d] does not exist", segment.key, segment.indexes[0])
+		}
+		updated, err := setPath(yaml.MapSlice{}, segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		return append(mapping, yaml.MapItem{Key: segment.key, Value: updated}), nil
+	case map[string]interface{}:
+		existing, found := mapping[segment.key]
+		if !found {
+			if len(segment.indexes) > 0 {
+				return nil, fmt.Errorf("list element %s[%d] does not exist", segment.key, segment.indexes[0])
+			}
+			existing = map[string]interface{}{}
+		}
+		if _, err := child(existing); err != nil {
+			return nil, err
+		}
+		updated, err := update(existing)
+		if err != nil {
+			return nil, err
+		}
+		mapping[segment.key] = updated
+		return mapping, nil
+	case nil:
+		return setPath(map[string]interface{}{}, segments, value)
+	default:
+		return nil, fmt.Errorf("%s is not a mapping", segment.key)
+	}
+}
+
+// decodeOrderedJSON parses the JSON document keeping the order of the keys, an empty document is an empty object
+func decodeOrderedJSON(data []byte) (yaml.MapSlice, error) {
+	var document yaml.MapSlice
+	if len(bytes.TrimSpace(data)) == 0 {
+		return yaml.MapSlice{}, nil
+	}
+	if err := yaml.UnmarshalWithOptions(data, &document, yaml.UseOrderedMap()); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return document, nil
+}
+
+// replaceKey replaces the value of the key, or appends the key to the end
+func replaceKey(document yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i, item := range document {
+		if fmt.Sprint(item.Key) == key {
+			document[i].Value = value
+			return document
+		}
+	}
+	return append(document, yaml.MapItem{Key: key, Value: value})
+}
+
+// encodeJSON writes the value as JSON indented with two spaces, ordered mappings keep their order, other keys are sorted
+func encodeJSON(value interface{}) []byte {
+	var b bytes.Buffer
+	writeJSON(&b, value, "")
+	b.WriteString("\n")
+	return b.Bytes()
+}
+
+func writeJSON(b *bytes.Buffer, value interface{}, indent string) {
+	type entry struct {
+		key   string
+		value interface{}
+	}
+	var entries []entry
+	switch value := value.(type) {
+	case yaml.MapSlice:
+		for _, item := range value {
+			entries = append(entries, entry{fmt.Sprint(item.Key), item.Value})
+		}
+	case map[string]interface{}:
+		for key, item := range value {
+			entries = append(entries, entry{key, item})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].key < entries[j].key
+		})
+	case []interface{}:
+		if len(value) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		b.WriteString("[\n")
+		for i, item := range value {
+			b.WriteString(indent + "  ")
+			writeJSON(b, item, indent+"  ")
+			if i < len(value)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + "]")
+		return
+	default:
+		writeJSONScalar(b, value)
+		return
+	}
+
+	if len(entries) == 0 {
+		b.WriteString("{}")
+		return
+	}
+	b.WriteString("{\n")
+	for i, item := range entries {
+		b.WriteString(indent + "  ")
+		writeJSONScalar(b, item.key)
+		b.WriteString(": ")
+		writeJSON(b, item.value, indent+"  ")
+		if i < len(entries)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(indent + "}")
+}
+
+// writeJSONScalar writes strings, numbers, booleans and null, URLs keep their & and < characters
+func writeJSONScalar(b *bytes.Buffer, value interface{}) {
+	var scalar bytes.Buffer
+	encoder := json.NewEncoder(&scalar)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		// Values decoded from YAML or TOML are always encodable, other types are written as strings
+		scalar.Reset()
+		_ = encoder.Encode(fmt.Sprint(value))
+	}
+	b.Write(bytes.TrimSuffix(scalar.Bytes(), []byte("\n")))
+}
@@ -0,0 +1,347 @@
+package configfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+)
+
+const sampleTOML = `# Project configuration
+include = ["team.yaml"]
+
+[devrig]
+version = "0.79.6" # pinned by devrig upgrade
+channel = 'beta'
+
+[devrig.binaries.linux-x86_64]
+url = "https://devrig.dev/download/v0.79.6/devrig-linux-x86_64"
+sha512 = "abc"
+mirrors = [
+  "https://mirror.example.com/devrig", # internal
+]
+
+# Tools of the project
+[tools]
+node = "22.1.0"
+"go" = "1.25.1"
+
+[[auth.providers]]
+name = "corp"
+hosts = ["artifacts.example.com"]
+scopes = []
+
+[retention]
+keep_unpacked_ides = 2
+keep_downloads_days = 1_000
+`
+
+func TestDecode_TOML(t *testing.T) {
+	values, err := Decode("devrig.toml", []byte(sampleTOML))
+	if err != nil {
+		t.Fatalf("Failed to decode TOML: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"include": []interface{}{"team.yaml"},
+		"devrig": map[string]interface{}{
+			"version": "0.79.6",
+			"channel": "beta",
+			"binaries": map[string]interface{}{
+				"linux-x86_64": map[string]interface{}{
+					"url":     "https://devrig.dev/download/v0.79.6/devrig-linux-x86_64",
+					"sha512":  "abc",
+					"mirrors": []interface{}{"https://mirror.example.com/devrig"},
+				},
+			},
+		},
+		"tools": map[string]interface{}{"node": "22.1.0", "go": "1.25.1"},
+		"auth": map[string]interface{}{
+			"providers": []interface{}{
+				map[string]interface{}{"name": "corp", "hosts": []interface{}{"artifacts.example.com"}, "scopes": []interface{}{}},
+			},
+		},
+		"retention": map[string]interface{}{"keep_unpacked_ides": int64(2), "keep_downloads_days": int64(1000)},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Unexpected values:\n%#v", values)
+	}
+}
+
+func TestDecode_TOMLValues(t *testing.T) {
+	values, err := Decode("devrig.toml", []byte(`
+basic = "tab\tquote\" \u00e9"
+literal = 'C:\path'
+multiline = """
+first \
+  second"""
+dotted.key = true
+inline = { a = 1, b.c = -0.5 }
+hex = 0xff
+date = 1979-05-27 07:32:00Z
+nested = [[1, 2], ["a"]]
+`))
+	if err != nil {
+		t.Fatalf("Failed to decode TOML: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"basic":     "tab\tquote\" é",
+		"literal":   `C:\path`,
+		"multiline": "first second",
+		"dotted":    map[string]interface{}{"key": true},
+		"inline":    map[string]interface{}{"a": int64(1), "b": map[string]interface{}{"c": -0.5}},
+		"hex":       int64(255),
+		"date":      "1979-05-27 07:32:00Z",
+		"nested":    []interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{"a"}},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("Unexpected values:\n%#v", values)
+	}
+}
+
+func TestDecode_TOMLErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"duplicate key":   "a = 1\na = 2\n",
+		"duplicate table": "[a]\n[a]\n",
+		"missing value":   "a =\n",
+		"trailing text":   "a = 1 b\n",
+		"unterminated":    "a = \"text\n",
+		"bare value":      "a = text\n",
+	} {
+		if _, err := Decode("devrig.toml", []byte(content)); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
+
+func TestDecode_JSON(t *testing.T) {
+	values, err := Decode("devrig.json", []byte(`{"tools": {"node": "22.1.0"}}`))
+	if err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+	if values["tools"].(map[string]interface{})["node"] != "22.1.0" {
+		t.Errorf("Unexpected values: %v", values)
+	}
+}
+
+func TestFind_Precedence(t *testing.T) {
+	dir := t.TempDir()
+	if path, shadowed := Find(dir); path != filepath.Join(dir, "devrig.yaml") || len(shadowed) != 0 {
+		t.Errorf("Expected devrig.yaml without files, got %s %v", path, shadowed)
+	}
+
+	for _, name := range []string{"devrig.json", "devrig.toml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	path, shadowed := Find(dir)
+	if path != filepath.Join(dir, "devrig.toml") {
+		t.Errorf("Expected devrig.toml to win over devrig.json, got %s", path)
+	}
+	if len(shadowed) != 1 || shadowed[0] != filepath.Join(dir, "devrig.json") {
+		t.Errorf("Expected devrig.json to be shadowed, got %v", shadowed)
+	}
+}
+
+func TestToYAML_TOML(t *testing.T) {
+	converted, err := ToYAML("devrig.toml", []byte(sampleTOML))
+	if err != nil {
+		t.Fatalf("Failed to convert: %v", err)
+	}
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(converted, &values); err != nil {
+		t.Fatalf("Failed to parse converted YAML: %v\n%s", err, converted)
+	}
+	if values["tools"].(map[string]interface{})["go"] != "1.25.1" {
+		t.Errorf("Unexpected converted YAML:\n%s", converted)
+	}
+}
+
+func TestReplaceSection_TOMLKeepsOtherSections(t *testing.T) {
+	updated, err := ReplaceSection(FormatTOML, []byte(sampleTOML), "tools", map[string]interface{}{"node": "24.0.0"})
+	if err != nil {
+		t.Fatalf("Failed to replace section: %v", err)
+	}
+
+	text := string(updated)
+	if strings.Contains(text, "1.25.1") || !strings.Contains(text, "[tools]\nnode = \"24.0.0\"\n") {
+		t.Errorf("Expected the tools section to be replaced:\n%s", text)
+	}
+	for _, kept := range []string{"# Project configuration", "version = \"0.79.6\" # pinned by devrig upgrade",
+		"channel = 'beta'", "# internal", "# Tools of the project", "keep_downloads_days = 1_000"} {
+		if !strings.Contains(text, kept) {
+			t.Errorf("Expected %q to be kept:\n%s", kept, text)
+		}
+	}
+	// The section stays at its place
+	if strings.Index(text, "[tools]") > strings.Index(text, "[[auth.providers]]") {
+		t.Errorf("Expected the tools section before auth:\n%s", text)
+	}
+
+	values, err := Decode("devrig.toml", updated)
+	if err != nil {
+		t.Fatalf("Failed to decode the updated TOML: %v\n%s", err, text)
+	}
+	if !reflect.DeepEqual(values["tools"], map[string]interface{}{"node": "24.0.0"}) {
+		t.Errorf("Unexpected tools: %v", values["tools"])
+	}
+}
+
+func TestReplaceSection_TOMLNestedAndNewSections(t *testing.T) {
+	devrig := map[string]interface{}{
+		"version": "0.80.0",
+		"binaries": map[string]interface{}{
+			"linux-x86_64": map[string]interface{}{"url": "https://example.com/devrig", "sha512": "def"},
+			"windows-x86_64": map[string]interface{}{
+				"url": "https://example.com/devrig.exe", "sha512": "123", "mirrors": []interface{}{"https://mirror/devrig.exe"},
+			},
+		},
+	}
+	updated, err := ReplaceSection(FormatTOML, []byte(sampleTOML), "devrig", devrig)
+	if err != nil {
+		t.Fatalf("Failed to replace section: %v", err)
+	}
+	providers := []interface{}{
+		map[string]interface{}{"name": "corp", "hosts": []interface{}{"a.example.com"}},
+		map[string]interface{}{"name": "other", "token": map[string]interface{}{"url": "https://token"}},
+	}
+	updated, err = ReplaceSection(FormatTOML, updated, "auth", map[string]interface{}{"providers": providers})
+	if err != nil {
+		t.Fatalf("Failed to replace section: %v", err)
+	}
+	updated, err = ReplaceSection(FormatTOML, updated, "jdk", map[string]interface{}{"version": "21"})
+	if err != nil {
+		t.Fatalf("Failed to add section: %v", err)
+	}
+	updated, err = ReplaceSection(FormatTOML, updated, "include", []interface{}{"a.yaml", "b.toml"})
+	if err != nil {
+		t.Fatalf("Failed to replace include: %v", err)
+	}
+
+	values, err := Decode("devrig.toml", updated)
+	if err != nil {
+		t.Fatalf("Failed to decode the updated TOML: %v\n%s", err, updated)
+	}
+	if !reflect.DeepEqual(values["devrig"], devrig) {
+		t.Errorf("Unexpected devrig section: %v\n%s", values["devrig"], updated)
+	}
+	if !reflect.DeepEqual(values["auth"], map[string]interface{}{"providers": providers}) {
+		t.Errorf("Unexpected auth section: %v\n%s", values["auth"], updated)
+	}
+	if !reflect.DeepEqual(values["jdk"], map[string]interface{}{"version": "21"}) {
+		t.Errorf("Unexpected jdk section: %v\n%s", values["jdk"], updated)
+	}
+	if !reflect.DeepEqual(values["include"], []interface{}{"a.yaml", "b.toml"}) {
+		t.Errorf("Unexpected include: %v\n%s", values["include"], updated)
+	}
+	if !strings.HasPrefix(string(updated), "# Project configuration\ninclude = [\"a.yaml\", \"b.toml\"]\n") {
+		t.Errorf("Expected include to stay at the top:\n%s", updated)
+	}
+}
+
+func TestSetValue_TOMLInPlace(t *testing.T) {
+	updated, err := SetValue(FormatTOML, []byte(sampleTOML), "devrig.version", "0.80.0")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	expected := strings.Replace(sampleTOML, `version = "0.79.6" #`, `version = "0.80.0" #`, 1)
+	if string(updated) != expected {
+		t.Errorf("Expected only the value to change:\n%s", updated)
+	}
+
+	updated, err = SetValue(FormatTOML, []byte(sampleTOML), "retention.keep_devrig_binaries", 3)
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	expected = strings.Replace(sampleTOML, "keep_downloads_days = 1_000\n", "keep_downloads_days = 1_000\nkeep_devrig_binaries = 3\n", 1)
+	if string(updated) != expected {
+		t.Errorf("Expected the key to be added to its table:\n%s", updated)
+	}
+}
+
+func TestSetValue_TOMLRewritesSection(t *testing.T) {
+	updated, err := SetValue(FormatTOML, []byte(sampleTOML), "auth.providers[0].client_id", "devrig")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	values, err := Decode("devrig.toml", updated)
+	if err != nil {
+		t.Fatalf("Failed to decode the updated TOML: %v\n%s", err, updated)
+	}
+	provider := values["auth"].(map[string]interface{})["providers"].([]interface{})[0].(map[string]interface{})
+	if provider["client_id"] != "devrig" || provider["name"] != "corp" {
+		t.Errorf("Unexpected provider: %v\n%s", provider, updated)
+	}
+
+	updated, err = SetValue(FormatTOML, []byte(sampleTOML), "jdk.version", "21")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	if !strings.HasSuffix(string(updated), "\n[jdk]\nversion = \"21\"\n") {
+		t.Errorf("Expected a new table at the end:\n%s", updated)
+	}
+
+	if _, err := SetValue(FormatTOML, []byte(sampleTOML), "auth.providers[3].name", "x"); err == nil {
+		t.Error("Expected an error for a missing list element")
+	}
+}
+
+func TestJSON_KeepsOrder(t *testing.T) {
+	data := []byte(`{
+  "tools": {"node": "22.1.0", "go": "1.25.1"},
+  "devrig": {"channel": "beta", "binaries": {"linux-x86_64": {"url": "https://example.com/?a=1&b=2", "sha512": "abc"}}}
+}
+`)
+	updated, err := SetValue(FormatJSON, data, "devrig.channel", "stable")
+	if err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	updated, err = ReplaceSection(FormatJSON, updated, "jdk", map[string]interface{}{"version": "21"})
+	if err != nil {
+		t.Fatalf("Failed to replace section: %v", err)
+	}
+
+	expected := `{
+  "tools": {
+    "node": "22.1.0",
+    "go": "1.25.1"
+  },
+  "devrig": {
+    "channel": "stable",
+    "binaries": {
+      "linux-x86_64": {
+        "url": "https://example.com/?a=1&b=2",
+        "sha512": "abc"
+      }
+    }
+  },
+  "jdk": {
+    "version": "21"
+  }
+}
+`
+	if string(updated) != expected {
+		t.Errorf("Unexpected JSON:\n%s", updated)
+	}
+}
+
+func TestNewFile(t *testing.T) {
+	for _, format := range []Format{FormatTOML, FormatJSON} {
+		data, err := NewFile(format, "tools", map[string]interface{}{"node": "22.1.0"})
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", format, err)
+		}
+		values, err := Decode("devrig."+string(format), data)
+		if err != nil {
+			t.Fatalf("Failed to decode %s: %v\n%s", format, err, data)
+		}
+		if !reflect.DeepEqual(values, map[string]interface{}{"tools": map[string]interface{}{"node": "22.1.0"}}) {
+			t.Errorf("Unexpected %s values: %v", format, values)
+		}
+	}
+}
@@ -0,0 +1,593 @@
+package configfile
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tomlTable is a [table] or [[array.of.tables]] header of a TOML file
+type tomlTable struct {
+	path  []string
+	array bool
+	// start is the offset of the header line, end is the offset after its line break
+	start int
+	end   int
+}
+
+// tomlEntry is a key = value line of a TOML file
+type tomlEntry struct {
+	// path is the full path of the value, the path of the table followed by the dotted key
+	path []string
+	// table is the index of the table the entry belongs to, or -1 for the root table
+	table int
+	// lineStart and lineEnd are the offsets of the line, lineEnd is after the line break of the last line of the value
+	lineStart  int
+	valueStart int
+	valueEnd   int
+	lineEnd    int
+}
+
+// tomlDocument is a parsed TOML file with the positions of its tables and values, so edits keep the rest of the file
+type tomlDocument struct {
+	values  map[string]interface{}
+	tables  []tomlTable
+	entries []tomlEntry
+}
+
+// parseTOML parses a TOML document, dates and times are kept as strings
+func parseTOML(data []byte) (*tomlDocument, error) {
+	p := &tomlParser{data: data, line: 1}
+	doc := &tomlDocument{values: map[string]interface{}{}}
+	if err := p.parseDocument(doc); err != nil {
+		return nil, fmt.Errorf("line %d: %w", p.line, err)
+	}
+	return doc, nil
+}
+
+type tomlParser struct {
+	data []byte
+	pos  int
+	line int
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+func (p *tomlParser) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(string(p.data[p.pos:]), prefix)
+}
+
+func (p *tomlParser) advance(n int) {
+	for i := 0; i < n && !p.eof(); i++ {
+		if p.data[p.pos] == '\n' {
+			p.line++
+		}
+		p.pos++
+	}
+}
+
+// skipSpaces skips spaces and tabs on the current line
+func (p *tomlParser) skipSpaces() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.advance(1)
+	}
+}
+
+// skipComment skips a comment up to the line break
+func (p *tomlParser) skipComment() {
+	if p.peek() != '#' {
+		return
+	}
+	for !p.eof() && p.peek() != '\n' {
+		p.advance(1)
+	}
+}
+
+// skipBlank skips whitespace, line breaks and comments
+func (p *tomlParser) skipBlank() {
+	for {
+		p.skipSpaces()
+		p.skipComment()
+		if c := p.peek(); c == '\n' || c == '\r' {
+			p.advance(1)
+			continue
+		}
+		return
+	}
+}
+
+// endLine expects only whitespace and a comment up to the end of the line, and moves after the line break
+func (p *tomlParser) endLine() error {
+	p.skipSpaces()
+	p.skipComment()
+	if p.hasPrefix("\r\n") {
+		p.advance(2)
+		return nil
+	}
+	if p.eof() || p.peek() == '\n' {
+		p.advance(1)
+		return nil
+	}
+	return fmt.Errorf("unexpected %q after the value", p.peek())
+}
+
+func (p *tomlParser) parseDocument(doc *tomlDocument) error {
+	current := doc.values
+	currentTable := -1
+	defined := map[string]bool{}
+
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return nil
+		}
+		lineStart := p.pos
+
+		if p.peek() == '[' {
+			array := p.hasPrefix("[[")
+			if array {
+				p.advance(2)
+			} else {
+				p.advance(1)
+			}
+			p.skipSpaces()
+			path, err := p.parseKey()
+			if err != nil {
+				return err
+			}
+			p.skipSpaces()
+			closing := "]"
+			if array {
+				closing = "]]"
+			}
+			if !p.hasPrefix(closing) {
+				return fmt.Errorf("expected %s after the table name", closing)
+			}
+			p.advance(len(closing))
+			if err := p.endLine(); err != nil {
+				return err
+			}
+
+			table, err := openTable(doc.values, path, array, defined)
+			if err != nil {
+				return err
+			}
+			current = table
+			doc.tables = append(doc.tables, tomlTable{path: path, array: array, start: lineStart, end: p.pos})
+			currentTable = len(doc.tables) - 1
+			continue
+		}
+
+		key, err := p.parseKey()
+		if err != nil {
+			return err
+		}
+		p.skipSpaces()
+		if p.peek() != '=' {
+			return fmt.Errorf("expected = after the key %s", strings.Join(key, "."))
+		}
+		p.advance(1)
+		p.skipSpaces()
+		valueStart := p.pos
+		value, err := p.parseValue()
+		if err != nil {
+			return err
+		}
+		valueEnd := p.pos
+		if err := p.endLine(); err != nil {
+			return err
+		}
+		if err := setKey(current, key, value); err != nil {
+			return err
+		}
+
+		var path []string
+		if currentTable >= 0 {
+			path = append(path, doc.tables[currentTable].path...)
+		}
+		doc.entries = append(doc.entries, tomlEntry{
+			path:       append(path, key...),
+			table:      currentTable,
+			lineStart:  lineStart,
+			valueStart: valueStart,
+			valueEnd:   valueEnd,
+			lineEnd:    p.pos,
+		})
+	}
+}
+
+// openTable returns the table of a header, the intermediate tables are created, arrays of tables continue in their last element
+func openTable(root map[string]interface{}, path []string, array bool, defined map[string]bool) (map[string]interface{}, error) {
+	current := root
+	for i, key := range path {
+		last := i == len(path)-1
+		existing, found := current[key]
+
+		if last && array {
+			list, ok := existing.([]interface{})
+			if found && (!ok || !isTableArray(list)) {
+				return nil, fmt.Errorf("%s is not an array of tables", strings.Join(path, "."))
+			}
+			table := map[string]interface{}{}
+			current[key] = append(list, table)
+			return table, nil
+		}
+
+		switch value := existing.(type) {
+		case nil:
+			table := map[string]interface{}{}
+			current[key] = table
+			current = table
+		case map[string]interface{}:
+			current = value
+		case []interface{}:
+			if last || !isTableArray(value) {
+				return nil, fmt.Errorf("%s is already defined", strings.Join(path[:i+1], "."))
+			}
+			current = value[len(value)-1].(map[string]interface{})
+		default:
+			return nil, fmt.Errorf("%s is already defined", strings.Join(path[:i+1], "."))
+		}
+	}
+
+	name := strings.Join(path, "\x00")
+	if defined[name] {
+		return nil, fmt.Errorf("table %s is defined twice", strings.Join(path, "."))
+	}
+	defined[name] = true
+	return current, nil
+}
+
+// isTableArray tells if the list was created by [[array.of.tables]] headers
+func isTableArray(list []interface{}) bool {
+	if len(list) == 0 {
+		return false
+	}
+	_, ok := list[len(list)-1].(map[string]interface{})
+	return ok
+}
+
+// setKey sets the value of a dotted key in the table, the intermediate tables are created
+func setKey(table map[string]interface{}, key []string, value interface{}) error {
+	for _, part := range key[:len(key)-1] {
+		existing, found := table[part]
+		if !found {
+			next := map[string]interface{}{}
+			table[part] = next
+			table = next
+			continue
+		}
+		next, ok := existing.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s is already defined", strings.Join(key, "."))
+		}
+		table = next
+	}
+	last := key[len(key)-1]
+	if _, found := table[last]; found {
+		return fmt.Errorf("%s is defined twice", strings.Join(key, "."))
+	}
+	table[last] = value
+	return nil
+}
+
+// parseKey parses a dotted key of bare and quoted parts
+func (p *tomlParser) parseKey() ([]string, error) {
+	var key []string
+	for {
+		p.skipSpaces()
+		var part string
+		switch c := p.peek(); {
+		case c == '"':
+			value, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			part = value
+		case c == '\'':
+			value, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			part = value
+		default:
+			start := p.pos
+			for isBareKeyChar(p.peek()) {
+				p.advance(1)
+			}
+			if start == p.pos {
+				return nil, fmt.Errorf("expected a key, got %q", p.peek())
+			}
+			part = string(p.data[start:p.pos])
+		}
+		key = append(key, part)
+
+		p.skipSpaces()
+		if p.peek() != '.' {
+			return key, nil
+		}
+		p.advance(1)
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseValue() (interface{}, error) {
+	switch c := p.peek(); {
+	case p.hasPrefix(`"""`):
+		return p.parseMultilineString(`"""`, true)
+	case p.hasPrefix(`'''`):
+		return p.parseMultilineString(`'''`, false)
+	case c == '"':
+		return p.parseBasicString()
+	case c == '\'':
+		return p.parseLiteralString()
+	case c == '[':
+		return p.parseArray()
+	case c == '{':
+		return p.parseInlineTable()
+	case p.hasPrefix("true"):
+		p.advance(4)
+		return true, nil
+	case p.hasPrefix("false"):
+		p.advance(5)
+		return false, nil
+	case p.eof() || c == '\n' || c == '\r' || c == '#':
+		return nil, fmt.Errorf("expected a value")
+	default:
+		return p.parseNumberOrDate()
+	}
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.advance(1)
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", fmt.Errorf("unterminated string")
+		}
+		c := p.peek()
+		if c == '"' {
+			p.advance(1)
+			return b.String(), nil
+		}
+		if c == '\\' {
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+			continue
+		}
+		b.WriteByte(c)
+		p.advance(1)
+	}
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.advance(1)
+	start := p.pos
+	for p.peek() != '\'' {
+		if p.eof() || p.peek() == '\n' {
+			return "", fmt.Errorf("unterminated string")
+		}
+		p.advance(1)
+	}
+	value := string(p.data[start:p.pos])
+	p.advance(1)
+	return value, nil
+}
+
+// parseMultilineString parses """basic""" or ”'literal”' strings, a line break right after the opening quotes is trimmed
+func (p *tomlParser) parseMultilineString(quotes string, escapes bool) (string, error) {
+	p.advance(3)
+	if p.hasPrefix("\r\n") {
+		p.advance(2)
+	} else if p.peek() == '\n' {
+		p.advance(1)
+	}
+
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", fmt.Errorf("unterminated string")
+		}
+		if p.hasPrefix(quotes) {
+			// Up to two quotes may end the content right before the closing ones
+			for i := 0; i < 2 && strings.HasPrefix(string(p.data[p.pos+1:]), quotes); i++ {
+				b.WriteByte(p.peek())
+				p.advance(1)
+			}
+			p.advance(3)
+			return b.String(), nil
+		}
+		c := p.peek()
+		if escapes && c == '\\' {
+			// A backslash at the end of a line trims the line break and the following whitespace
+			rest := strings.TrimLeft(string(p.data[p.pos+1:]), " \t")
+			if strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n") {
+				p.advance(1)
+				for c := p.peek(); c == ' ' || c == '\t' || c == '\n' || c == '\r'; c = p.peek() {
+					p.advance(1)
+				}
+				continue
+			}
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+			continue
+		}
+		b.WriteByte(c)
+		p.advance(1)
+	}
+}
+
+func (p *tomlParser) parseEscape(b *strings.Builder) error {
+	p.advance(1)
+	c := p.peek()
+	p.advance(1)
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.data) {
+			return fmt.Errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(string(p.data[p.pos:p.pos+size]), 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return fmt.Errorf("invalid unicode escape")
+		}
+		b.WriteRune(rune(code))
+		p.advance(size)
+	default:
+		return fmt.Errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+func (p *tomlParser) parseArray() ([]interface{}, error) {
+	p.advance(1)
+	list := []interface{}{}
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			p.advance(1)
+			return list, nil
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.advance(1)
+		case ']':
+		default:
+			return nil, fmt.Errorf("expected , or ] in the array")
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (map[string]interface{}, error) {
+	p.advance(1)
+	table := map[string]interface{}{}
+	for {
+		p.skipBlank()
+		if p.peek() == '}' {
+			p.advance(1)
+			return table, nil
+		}
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if p.peek() != '=' {
+			return nil, fmt.Errorf("expected = after the key %s", strings.Join(key, "."))
+		}
+		p.advance(1)
+		p.skipSpaces()
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if err := setKey(table, key, value); err != nil {
+			return nil, err
+		}
+
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.advance(1)
+		case '}':
+		default:
+			return nil, fmt.Errorf("expected , or } in the inline table")
+		}
+	}
+}
+
+// parseNumberOrDate parses integers, floats, and dates and times which are kept as strings
+func (p *tomlParser) parseNumberOrDate() (interface{}, error) {
+	start := p.pos
+	for c := p.peek(); isBareKeyChar(c) || c == '+' || c == '.' || c == ':'; c = p.peek() {
+		p.advance(1)
+	}
+	// A date and a time may be separated by a space
+	if p.pos-start == 10 && p.data[start+4] == '-' && p.peek() == ' ' && p.pos+1 < len(p.data) &&
+		p.data[p.pos+1] >= '0' && p.data[p.pos+1] <= '9' {
+		p.advance(1)
+		for c := p.peek(); isBareKeyChar(c) || c == '+' || c == '.' || c == ':'; c = p.peek() {
+			p.advance(1)
+		}
+	}
+	token := string(p.data[start:p.pos])
+	if token == "" {
+		return nil, fmt.Errorf("unexpected %q", p.peek())
+	}
+
+	switch strings.TrimLeft(token, "+-") {
+	case "inf":
+		if strings.HasPrefix(token, "-") {
+			return math.Inf(-1), nil
+		}
+		return math.Inf(1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+
+	plain := strings.ReplaceAll(token, "_", "")
+	for _, prefix := range []struct {
+		text string
+		base int
+	}{{"0x", 16}, {"0o", 8}, {"0b", 2}} {
+		if digits, ok := strings.CutPrefix(plain, prefix.text); ok {
+			number, err := strconv.ParseInt(digits, prefix.base, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %s", token)
+			}
+			return number, nil
+		}
+	}
+	if number, err := strconv.ParseInt(plain, 10, 64); err == nil {
+		return number, nil
+	}
+	if strings.ContainsAny(plain, ".eE") && !strings.ContainsAny(plain, ":") {
+		if number, err := strconv.ParseFloat(plain, 64); err == nil {
+			return number, nil
+		}
+	}
+	if strings.ContainsAny(token, "-:") && token[0] >= '0' && token[0] <= '9' {
+		return token, nil
+	}
+	return nil, fmt.Errorf("invalid value %s", token)
+}
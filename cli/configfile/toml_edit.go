@@ -0,0 +1,148 @@
+package configfile
+
+import (
+	"slices"
+	"strings"
+)
+
+// replaceTOMLSection replaces all tables and keys of the top-level key with the value, the rest of the file is kept as is.
+// The section is written where its first table was, or at the end of the file
+func replaceTOMLSection(data []byte, key string, value interface{}) ([]byte, error) {
+	doc, err := parseTOML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	type span struct{ start, end int }
+	var removed []span
+	insertAt := -1
+	firstTable := len(data)
+	for i, table := range doc.tables {
+		firstTable = min(firstTable, table.start)
+		if table.path[0] != key {
+			continue
+		}
+		// The table ends with its last value, comments before the next header stay with it
+		end := table.end
+		for _, entry := range doc.entries {
+			if entry.table == i {
+				end = max(end, entry.lineEnd)
+			}
+		}
+		// Blank lines after the table are removed too, the separator is added again after the new section
+		for end < len(data) && (data[end] == '\n' || data[end] == '\r') {
+			end++
+		}
+		removed = append(removed, span{table.start, end})
+		if insertAt < 0 {
+			insertAt = table.start
+		}
+	}
+	rootAt := -1
+	for _, entry := range doc.entries {
+		if entry.table < 0 && entry.path[0] == key {
+			removed = append(removed, span{entry.lineStart, entry.lineEnd})
+			if rootAt < 0 {
+				rootAt = entry.lineStart
+			}
+		}
+	}
+
+	var section strings.Builder
+	if table, ok := value.(map[string]interface{}); ok {
+		if err := encodeTOMLTable(&section, []string{key}, table); err != nil {
+			return nil, err
+		}
+		if insertAt < 0 {
+			insertAt = len(data)
+		}
+	} else {
+		// Keys of the root table must come before the first header
+		encoded, err := encodeTOMLValue(value)
+		if err != nil {
+			return nil, err
+		}
+		section.WriteString(encodeTOMLKey([]string{key}) + " = " + encoded + "\n")
+		insertAt = rootAt
+		if insertAt < 0 {
+			insertAt = firstTable
+		}
+	}
+
+	// The insertion point is never inside a removed span, it is moved by the removed text before it
+	slices.SortFunc(removed, func(a, b span) int {
+		return a.start - b.start
+	})
+	var kept strings.Builder
+	last := 0
+	at := insertAt
+	for _, r := range removed {
+		kept.Write(data[last:r.start])
+		last = r.end
+		if r.end <= insertAt {
+			at -= r.end - r.start
+		}
+	}
+	kept.Write(data[last:])
+	before, after := kept.String()[:at], kept.String()[at:]
+
+	var b strings.Builder
+	b.WriteString(before)
+	if before != "" && !strings.HasSuffix(before, "\n") {
+		b.WriteString("\n")
+	}
+	if strings.HasPrefix(section.String(), "[") && before != "" && !strings.HasSuffix(b.String(), "\n\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString(section.String())
+	if after != "" && !strings.HasPrefix(after, "\n") && (strings.HasPrefix(section.String(), "[") || strings.HasPrefix(after, "[")) {
+		b.WriteString("\n")
+	}
+	b.WriteString(after)
+	return []byte(b.String()), nil
+}
+
+// setTOMLValue replaces the value at the path in its line, or adds the key to the end of its table.
+// Returns false if the path cannot be edited in place, e.g. when the value is in an inline table
+func setTOMLValue(data []byte, path []string, value interface{}) ([]byte, bool, error) {
+	doc, err := parseTOML(data)
+	if err != nil {
+		return nil, false, err
+	}
+	encoded, err := encodeTOMLValue(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, entry := range doc.entries {
+		if slices.Equal(entry.path, path) {
+			return splice(data, entry.valueStart, entry.valueEnd, encoded), true, nil
+		}
+	}
+
+	parent := path[:len(path)-1]
+	for i, table := range doc.tables {
+		if table.array || !slices.Equal(table.path, parent) {
+			continue
+		}
+		at := table.end
+		for _, entry := range doc.entries {
+			if entry.table == i {
+				at = max(at, entry.lineEnd)
+			}
+		}
+		line := encodeTOMLKey(path[len(path)-1:]) + " = " + encoded + "\n"
+		if at > 0 && data[at-1] != '\n' {
+			line = "\n" + line
+		}
+		return splice(data, at, at, line), true, nil
+	}
+	return nil, false, nil
+}
+
+func splice(data []byte, start int, end int, text string) []byte {
+	result := make([]byte, 0, len(data)-(end-start)+len(text))
+	result = append(result, data[:start]...)
+	result = append(result, text...)
+	return append(result, data[end:]...)
+}
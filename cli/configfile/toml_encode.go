@@ -0,0 +1,209 @@
+package configfile
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// encodeTOMLTable writes the table as key = value lines followed by its sub-tables, keys are sorted.
+// The [path] header is written when the table has values or nothing else, e.g. [devrig.binaries] is left out
+func encodeTOMLTable(b *strings.Builder, path []string, table map[string]interface{}) error {
+	return encodeTOMLBody(b, path, table, len(path) > 0)
+}
+
+// encodeTOMLBody writes the table, the header is left out for the elements of [[array.of.tables]]
+func encodeTOMLBody(b *strings.Builder, path []string, table map[string]interface{}, header bool) error {
+	var keys []string
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var values, tables, arrays []string
+	for _, key := range keys {
+		switch value := table[key].(type) {
+		case nil:
+			// TOML has no null, a missing key means the same
+		case map[string]interface{}:
+			tables = append(tables, key)
+		case []interface{}:
+			if isTableList(value) {
+				arrays = append(arrays, key)
+			} else {
+				values = append(values, key)
+			}
+		default:
+			values = append(values, key)
+		}
+	}
+
+	if header && (len(values) > 0 || len(tables)+len(arrays) == 0) {
+		writeSeparator(b)
+		b.WriteString("[" + encodeTOMLKey(path) + "]\n")
+	}
+	for _, key := range values {
+		value, err := encodeTOMLValue(table[key])
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", strings.Join(append(path, key), "."), err)
+		}
+		b.WriteString(encodeTOMLKey([]string{key}) + " = " + value + "\n")
+	}
+	for _, key := range tables {
+		if err := encodeTOMLTable(b, append(append([]string{}, path...), key), table[key].(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+	for _, key := range arrays {
+		childPath := append(append([]string{}, path...), key)
+		for _, item := range table[key].([]interface{}) {
+			writeSeparator(b)
+			b.WriteString("[[" + encodeTOMLKey(childPath) + "]]\n")
+			if err := encodeTOMLBody(b, childPath, item.(map[string]interface{}), false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeSeparator adds an empty line before a table header, unless it is the start of the output
+func writeSeparator(b *strings.Builder) {
+	if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n\n") {
+		b.WriteString("\n")
+	}
+}
+
+// isTableList tells if all elements of the list are tables, so it is written as [[array.of.tables]]
+func isTableList(list []interface{}) bool {
+	if len(list) == 0 {
+		return false
+	}
+	for _, item := range list {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeTOMLKey writes a dotted key, parts with other characters than letters, digits, _ and - are quoted
+func encodeTOMLKey(path []string) string {
+	parts := make([]string, len(path))
+	for i, part := range path {
+		bare := part != ""
+		for j := 0; j < len(part); j++ {
+			if !isBareKeyChar(part[j]) {
+				bare = false
+				break
+			}
+		}
+		if bare {
+			parts[i] = part
+		} else {
+			parts[i] = encodeTOMLString(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// encodeTOMLValue writes a value in a single line, tables become inline tables
+func encodeTOMLValue(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return encodeTOMLString(value), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case int:
+		return strconv.Itoa(value), nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case uint64:
+		if value > math.MaxInt64 {
+			return "", fmt.Errorf("%d is too large for TOML", value)
+		}
+		return strconv.FormatUint(value, 10), nil
+	case float64:
+		switch {
+		case math.IsNaN(value):
+			return "nan", nil
+		case math.IsInf(value, 1):
+			return "inf", nil
+		case math.IsInf(value, -1):
+			return "-inf", nil
+		}
+		text := strconv.FormatFloat(value, 'g', -1, 64)
+		if !strings.ContainsAny(text, ".eE") {
+			text += ".0"
+		}
+		return text, nil
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			encoded, err := encodeTOMLValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, encoded)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]interface{}:
+		var keys []string
+		for key := range value {
+			if value[key] != nil {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(keys))
+		for _, key := range keys {
+			encoded, err := encodeTOMLValue(value[key])
+			if err != nil {
+				return "", err
+			}
+			items = append(items, encodeTOMLKey([]string{key})+" = "+encoded)
+		}
+		if len(items) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	case nil:
+		return "", fmt.Errorf("TOML has no null values")
+	default:
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+}
+
+// encodeTOMLString writes a basic string, control characters are escaped
+func encodeTOMLString(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				_, _ = fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,139 @@
+package configservice
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// authProviderNamePattern keeps provider names safe to use as keychain accounts and file names
+var authProviderNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// AuthProvider declares an OAuth provider protecting artifact hosts, tokens are obtained with the device-code flow
+type AuthProvider struct {
+	// Name identifies the provider in `devrig auth login <name>` and in the keychain
+	Name string `yaml:"name"`
+	// Hosts receive the token of the provider, *.example.com matches all subdomains
+	Hosts                  []string `yaml:"hosts"`
+	ClientID               string   `yaml:"client_id"`
+	DeviceAuthorizationURL string   `yaml:"device_authorization_url"`
+	TokenURL               string   `yaml:"token_url"`
+	Scopes                 []string `yaml:"scopes,omitempty"`
+}
+
+// MatchesHost checks if the token of the provider is sent to the host
+func (p *AuthProvider) MatchesHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range p.Hosts {
+		pattern = strings.ToLower(pattern)
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+			if domain != "" && strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthSection declares the authentication of downloads from protected artifact hosts
+type AuthSection struct {
+	Providers []AuthProvider `yaml:"providers,omitempty"`
+}
+
+// FindProvider returns the provider by its name, or nil
+func (s *AuthSection) FindProvider(name string) *AuthProvider {
+	for i := range s.Providers {
+		if s.Providers[i].Name == name {
+			return &s.Providers[i]
+		}
+	}
+	return nil
+}
+
+// AuthService manages the auth section of devrig.yaml
+type AuthService interface {
+	// ReadAuth reads the auth section from devrig.yaml
+	// A missing section means no providers
+	ReadAuth() (*AuthSection, error)
+}
+
+// ReadAuth reads the auth section from devrig.yaml
+func (s *configServiceImpl) ReadAuth() (*AuthSection, error) {
+	var section AuthSection
+	if _, err := s.readSection("auth", &section); err != nil {
+		return nil, err
+	}
+
+	if err := validateAuthSection(&section); err != nil {
+		return nil, fmt.Errorf("validation failed for %s: %w", s.configPath, err)
+	}
+	return &section, nil
+}
+
+// validateAuthSection checks that every provider is complete and has a unique name
+func validateAuthSection(section *AuthSection) error {
+	names := map[string]bool{}
+	for i, provider := range section.Providers {
+		if provider.Name == "" {
+			return fmt.Errorf("missing name for auth provider #%d", i+1)
+		}
+		if !authProviderNamePattern.MatchString(provider.Name) {
+			return fmt.Errorf("invalid auth provider name %q: use lowercase letters, digits, '.', '_' and '-'", provider.Name)
+		}
+		if names[provider.Name] {
+			return fmt.Errorf("duplicate auth provider: %s", provider.Name)
+		}
+		names[provider.Name] = true
+
+		if len(provider.Hosts) == 0 {
+			return fmt.Errorf("no hosts configured for auth provider: %s", provider.Name)
+		}
+		for _, host := range provider.Hosts {
+			if !isAuthHost(host) {
+				return fmt.Errorf("invalid host %q for auth provider %s: expected a host name like artifacts.example.com or *.example.com", host, provider.Name)
+			}
+		}
+		if provider.ClientID == "" {
+			return fmt.Errorf("missing client_id for auth provider: %s", provider.Name)
+		}
+		if err := validateAuthURL(provider.DeviceAuthorizationURL); err != nil {
+			return fmt.Errorf("invalid device_authorization_url for auth provider %s: %w", provider.Name, err)
+		}
+		if err := validateAuthURL(provider.TokenURL); err != nil {
+			return fmt.Errorf("invalid token_url for auth provider %s: %w", provider.Name, err)
+		}
+	}
+	return nil
+}
+
+// isAuthHost checks the host is a host name, or *. followed by at least one label of a domain.
+// A bare * would send the token with every download
+func isAuthHost(host string) bool {
+	host = strings.TrimPrefix(host, "*.")
+	if host == "" || strings.ContainsAny(host, "/*") {
+		return false
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// validateAuthURL requires HTTPS, the endpoints receive the client credentials and issue tokens
+func validateAuthURL(value string) error {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if parsed.Scheme != "https" || parsed.Host == "" {
+		return fmt.Errorf("expected an https:// URL, got %q", value)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package configservice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuthService_ReadAuth(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(testFile, []byte("tools:\n  node: \"20\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	section, err := NewConfigService(testFile).Auth().ReadAuth()
+	if err != nil {
+		t.Fatalf("Failed to read auth: %v", err)
+	}
+	if len(section.Providers) != 0 {
+		t.Errorf("Expected no providers, got %+v", section.Providers)
+	}
+
+	content := `auth:
+  providers:
+    - name: corp
+      hosts: [artifacts.example.com, "*.cdn.example.com"]
+      client_id: devrig
+      device_authorization_url: https://sso.example.com/device
+      token_url: https://sso.example.com/token
+      scopes: [read]
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	section, err = NewConfigService(testFile).Auth().ReadAuth()
+	if err != nil {
+		t.Fatalf("Failed to read auth: %v", err)
+	}
+	provider := section.FindProvider("corp")
+	if provider == nil || provider.ClientID != "devrig" || len(provider.Scopes) != 1 {
+		t.Fatalf("Unexpected providers: %+v", section.Providers)
+	}
+
+	for host, expected := range map[string]bool{
+		"artifacts.example.com":    true,
+		"ARTIFACTS.example.com":    true,
+		"eu.cdn.example.com":       true,
+		"cdn.example.com":          false,
+		"artifacts.example.com.io": false,
+	} {
+		if provider.MatchesHost(host) != expected {
+			t.Errorf("Expected MatchesHost(%s) = %v", host, expected)
+		}
+	}
+}
+
+func TestAuthService_Validation(t *testing.T) {
+	valid := "      client_id: devrig\n      device_authorization_url: https://sso.example.com/device\n      token_url: https://sso.example.com/token\n"
+	cases := map[string]string{
+		"missing hosts":  "    - name: corp\n" + valid,
+		"invalid name":   "    - name: Corp/1\n      hosts: [a.example.com]\n" + valid,
+		"plain http":     "    - name: corp\n      hosts: [a.example.com]\n      client_id: devrig\n      device_authorization_url: http://sso.example.com/device\n      token_url: https://sso.example.com/token\n",
+		"duplicate name": "    - name: corp\n      hosts: [a.example.com]\n" + valid + "    - name: corp\n      hosts: [b.example.com]\n" + valid,
+		"any host":       "    - name: corp\n      hosts: [\"*\"]\n" + valid,
+		"empty wildcard": "    - name: corp\n      hosts: [\"*.\"]\n" + valid,
+		"empty label":    "    - name: corp\n      hosts: [\"*..example.com\"]\n" + valid,
+		"inner wildcard": "    - name: corp\n      hosts: [\"a.*.example.com\"]\n" + valid,
+	}
+
+	for name, providers := range cases {
+		t.Run(name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+			if err := os.WriteFile(testFile, []byte("auth:\n  providers:\n"+providers), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+			_, err := NewConfigService(testFile).Auth().ReadAuth()
+			if err == nil || !strings.Contains(err.Error(), "validation failed") {
+				t.Errorf("Expected validation error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestAuthProvider_MatchesHost_Wildcards(t *testing.T) {
+	for _, pattern := range []string{"*", "*."} {
+		provider := AuthProvider{Name: "corp", Hosts: []string{pattern}}
+		for _, host := range []string{"example.com", "devrig.dev", "localhost"} {
+			if provider.MatchesHost(host) {
+				t.Errorf("Expected %q not to match %s", pattern, host)
+			}
+		}
+	}
+
+	provider := AuthProvider{Name: "corp", Hosts: []string{"*.example.com"}}
+	if !provider.MatchesHost("eu.example.com") || provider.MatchesHost("attackerexample.com") || provider.MatchesHost("example.com") {
+		t.Errorf("Unexpected matches of *.example.com")
+	}
+}
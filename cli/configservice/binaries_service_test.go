@@ -353,3 +353,45 @@ func TestDevrigBinariesService_UpdateBinaries_MinimalSection(t *testing.T) {
 		t.Errorf("Expected empty release_date, got: %s", readSection.ReleaseDate)
 	}
 }
+
+func TestDevrigBinariesService_Mirrors(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+	configService := NewConfigService(testFile)
+
+	section := &DevrigSection{
+		Binaries: map[string]BinaryInfo{
+			"linux-x86_64": {
+				URL:     "https://example.com/devrig-linux-x86_64",
+				SHA512:  strings.Repeat("b", 128),
+				Mirrors: []string{"https://mirror.example.com/devrig-linux-x86_64"},
+			},
+		},
+	}
+	if err := configService.Binaries().UpdateBinaries(section); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	readSection, err := configService.Binaries().ReadDevrigSection()
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	urls := readSection.Binaries["linux-x86_64"].URLs()
+	if len(urls) != 2 || urls[1] != "https://mirror.example.com/devrig-linux-x86_64" {
+		t.Errorf("Expected the primary URL followed by the mirror, got: %v", urls)
+	}
+
+	// The bootstrap scripts read url and sha512 before any other key of the platform
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	content := string(data)
+	if strings.Index(content, "mirrors:") < strings.Index(content, "sha512:") {
+		t.Errorf("Expected mirrors to be written after sha512, got:\n%s", content)
+	}
+
+	section.Binaries["linux-x86_64"] = BinaryInfo{URL: "https://example.com/a", SHA512: strings.Repeat("b", 128), Mirrors: []string{""}}
+	if err := configService.Binaries().UpdateBinaries(section); err == nil {
+		t.Error("Expected an empty mirror URL to be rejected")
+	}
+}
@@ -0,0 +1,23 @@
+package configservice
+
+// CacheSection declares how the project stores downloaded and unpacked IDEs
+type CacheSection struct {
+	// Shared places the downloads and unpacked IDEs in the content-addressed store of the user, so the checkouts
+	// of projects with the same IDE share a single download and unpack
+	Shared bool `yaml:"shared,omitempty"`
+}
+
+// CacheService manages the cache section of devrig.yaml
+type CacheService interface {
+	// ReadCache reads the cache section from devrig.yaml
+	ReadCache() (*CacheSection, error)
+}
+
+// ReadCache reads the cache section from devrig.yaml
+func (s *configServiceImpl) ReadCache() (*CacheSection, error) {
+	var section CacheSection
+	if _, err := s.readSection("cache", &section); err != nil {
+		return nil, err
+	}
+	return &section, nil
+}
@@ -0,0 +1,42 @@
+package configservice
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCacheService_ReadCache(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(testFile, []byte("tools:\n  node: \"20\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	section, err := NewConfigService(testFile).Cache().ReadCache()
+	if err != nil {
+		t.Fatalf("Failed to read cache: %v", err)
+	}
+	if section.Shared {
+		t.Error("Expected the shared cache to be disabled by default")
+	}
+
+	if err := os.WriteFile(testFile, []byte("cache:\n  shared: true\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	section, err = NewConfigService(testFile).Cache().ReadCache()
+	if err != nil {
+		t.Fatalf("Failed to read cache: %v", err)
+	}
+	if !section.Shared {
+		t.Error("Expected the shared cache to be enabled")
+	}
+
+	// CI switches the shared cache without changing devrig.yaml
+	t.Setenv("DEVRIG_SET_CACHE__SHARED", "false")
+	section, err = NewConfigService(testFile).Cache().ReadCache()
+	if err != nil {
+		t.Fatalf("Failed to read cache: %v", err)
+	}
+	if section.Shared {
+		t.Error("Expected the variable to disable the shared cache")
+	}
+}
@@ -0,0 +1,49 @@
+package configservice
+
+import (
+	"os"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"jonnyzzz.com/devrig.dev/configfile"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/version"
+)
+
+// CheckCompatibility fails with ConfigTooNewError when devrig.yaml has sections or keys the running devrig does not know
+// and pins a newer devrig, so the sections of the newer version are not silently ignored. Unknown keys of a file
+// pinning this or an older version are typos, Validate reports them as warnings. Nothing is checked without devrig.yaml
+// or when a version is not a semantic version
+func CheckCompatibility(configPath string, running string) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil
+	}
+	converted, err := configfile.ToYAML(configPath, data)
+	if err != nil {
+		return nil
+	}
+
+	var pinned struct {
+		Devrig struct {
+			Version string `yaml:"version"`
+		} `yaml:"devrig"`
+	}
+	if err := yaml.Unmarshal(converted, &pinned); err != nil || pinned.Devrig.Version == "" {
+		return nil
+	}
+	if order, err := version.Compare(pinned.Devrig.Version, running); err != nil || order <= 0 {
+		return nil
+	}
+
+	var unknown []string
+	for _, problem := range ValidateBytes(converted) {
+		if strings.HasPrefix(problem.Message, "unknown key ") || strings.HasPrefix(problem.Message, "unknown section ") {
+			unknown = append(unknown, problem.Path)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	return &devrigErrors.ConfigTooNewError{Path: configPath, Keys: unknown, Required: pinned.Devrig.Version, Running: running}
+}
@@ -0,0 +1,55 @@
+package configservice
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+func writeCompatibilityConfig(t *testing.T, pinned string, extra string) string {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	content := "devrig:\n  version: " + pinned + "\n  binaries:\n    linux-x86_64:\n      url: https://devrig.dev/devrig\n      sha512: abc\n" + extra
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	return configPath
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	newer := writeCompatibilityConfig(t, "0.90.0", "services:\n  db: postgres\nide:\n  name: IU\n  version: 2024.3\n  remote: true\n")
+	err := CheckCompatibility(newer, "0.80.0")
+	var tooNew *devrigErrors.ConfigTooNewError
+	if !errors.As(err, &tooNew) {
+		t.Fatalf("Expected the config to be too new, got: %v", err)
+	}
+	if strings.Join(tooNew.Keys, ",") != "services,ide.remote" || tooNew.Required != "0.90.0" {
+		t.Errorf("Unexpected error: %+v", tooNew)
+	}
+	if !strings.Contains(err.Error(), "too old") || !strings.Contains(err.Error(), "0.90.0") {
+		t.Errorf("Expected the required version in the message, got: %v", err)
+	}
+
+	// The same binary as the pinned one reports unknown keys as typos in devrig config validate
+	if err := CheckCompatibility(newer, "0.90.0"); err != nil {
+		t.Errorf("Expected no error for the pinned version, got: %v", err)
+	}
+	if err := CheckCompatibility(newer, "1.0.0-SNAPSHOT"); err != nil {
+		t.Errorf("Expected no error for a newer version, got: %v", err)
+	}
+
+	known := writeCompatibilityConfig(t, "0.90.0", "tools:\n  go: \"1.22\"\n")
+	if err := CheckCompatibility(known, "0.80.0"); err != nil {
+		t.Errorf("Expected no error without unknown sections, got: %v", err)
+	}
+	if err := CheckCompatibility(filepath.Join(t.TempDir(), "devrig.yaml"), "0.80.0"); err != nil {
+		t.Errorf("Expected no error without devrig.yaml, got: %v", err)
+	}
+	if err := CheckCompatibility(newer, "dev"); err != nil {
+		t.Errorf("Expected no error for a development build, got: %v", err)
+	}
+}
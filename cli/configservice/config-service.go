@@ -3,8 +3,11 @@ package configservice
 import (
 	"fmt"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 
-	"github.com/goccy/go-yaml"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
 )
 
 // ConfigService provides validation of devrig.yaml configuration
@@ -13,13 +16,59 @@ type ConfigService interface {
 	// Returns detailed diagnostic errors if validation fails
 	EnsureValidConfig() error
 
+	// Validate checks all sections of devrig.yaml against the schema and returns all problems at once
+	Validate() ([]Problem, error)
+
 	// Binaries returns the DevrigBinariesService interface for managing binary configurations
 	Binaries() DevrigBinariesService
+
+	// Tools returns the ToolsService interface for managing pinned tool versions
+	Tools() ToolsService
+
+	// Retention returns the RetentionService interface for reading the cache retention policy
+	Retention() RetentionService
+
+	// Cache returns the CacheService interface for reading where the IDEs are stored
+	Cache() CacheService
+
+	// Settings returns the SettingsService interface for reading the settings of devrig, e.g. the cache folder
+	Settings() SettingsService
+
+	// Auth returns the AuthService interface for reading the providers of protected artifact hosts
+	Auth() AuthService
+
+	// Jdk returns the JdkService interface for managing the installed JDK
+	Jdk() JdkService
+
+	// IDE returns the IdeService interface for managing the IDE of the project
+	IDE() IdeService
+
+	// Maintenance returns the MaintenanceService interface for reading the recurring maintenance settings
+	Maintenance() MaintenanceService
+
+	// Doctor returns the DoctorService interface for reading the custom checks of `devrig doctor`
+	Doctor() DoctorService
+
+	// Updates returns the UpdatesService interface for reading the endpoints of devrig updates
+	Updates() UpdatesService
+
+	// Security returns the SecurityService interface for reading how the release metadata is verified
+	Security() SecurityService
+
+	// Policy returns the PolicyService interface for reading the restrictions of the layers
+	Policy() PolicyService
+
+	// Values returns the ValuesService interface for reading and writing single values by their path
+	Values() ValuesService
 }
 
 // configServiceImpl is the default implementation of ConfigService
 type configServiceImpl struct {
 	configPath string
+
+	// mu guards snapshots, the state of devrig.yaml when each section was read
+	mu        sync.Mutex
+	snapshots map[string]snapshot
 }
 
 // NewConfigService creates a new ConfigService instance with the given devrig.yaml path
@@ -34,37 +83,81 @@ func (s *configServiceImpl) Binaries() DevrigBinariesService {
 	return s
 }
 
-// ReadDevrigSection reads and parses the devrig section from devrig.yaml
-func (s *configServiceImpl) ReadDevrigSection() (*DevrigSection, error) {
-	data, err := os.ReadFile(s.configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("configuration file not found: %s", s.configPath)
-		}
-		return nil, fmt.Errorf("failed to read configuration file %s: %w", s.configPath, err)
-	}
+// Tools returns the ToolsService interface for managing pinned tool versions
+func (s *configServiceImpl) Tools() ToolsService {
+	return s
+}
 
-	// Parse into a map to extract just the devrig section
-	var yamlData map[string]interface{}
-	if err := yaml.Unmarshal(data, &yamlData); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML in %s: %w", s.configPath, err)
-	}
+// Retention returns the RetentionService interface for reading the cache retention policy
+func (s *configServiceImpl) Retention() RetentionService {
+	return s
+}
 
-	// Extract the devrig section
-	devrigData, ok := yamlData["devrig"]
-	if !ok {
-		return nil, fmt.Errorf("devrig section not found in %s", s.configPath)
-	}
+// Cache returns the CacheService interface for reading where the IDEs are stored
+func (s *configServiceImpl) Cache() CacheService {
+	return s
+}
+
+// Settings returns the SettingsService interface for reading the settings of devrig, e.g. the cache folder
+func (s *configServiceImpl) Settings() SettingsService {
+	return s
+}
+
+// Auth returns the AuthService interface for reading the providers of protected artifact hosts
+func (s *configServiceImpl) Auth() AuthService {
+	return s
+}
 
-	// Marshal the devrig section back to YAML and unmarshal into struct
-	devrigBytes, err := yaml.Marshal(devrigData)
+// Jdk returns the JdkService interface for managing the installed JDK
+func (s *configServiceImpl) Jdk() JdkService {
+	return s
+}
+
+// IDE returns the IdeService interface for managing the IDE of the project
+func (s *configServiceImpl) IDE() IdeService {
+	return s
+}
+
+// Maintenance returns the MaintenanceService interface for reading the recurring maintenance settings
+func (s *configServiceImpl) Maintenance() MaintenanceService {
+	return s
+}
+
+// Doctor returns the DoctorService interface for reading the custom checks of `devrig doctor`
+func (s *configServiceImpl) Doctor() DoctorService {
+	return s
+}
+
+// Updates returns the UpdatesService interface for reading the endpoints of devrig updates
+func (s *configServiceImpl) Updates() UpdatesService {
+	return s
+}
+
+// Security returns the SecurityService interface for reading how the release metadata is verified
+func (s *configServiceImpl) Security() SecurityService {
+	return s
+}
+
+// Policy returns the PolicyService interface for reading the restrictions of the layers
+func (s *configServiceImpl) Policy() PolicyService {
+	return s
+}
+
+// Values returns the ValuesService interface for reading and writing single values by their path
+func (s *configServiceImpl) Values() ValuesService {
+	return s
+}
+
+// ReadDevrigSection reads and parses the devrig section from devrig.yaml
+func (s *configServiceImpl) ReadDevrigSection() (*DevrigSection, error) {
+	var section DevrigSection
+	found, err := s.readSection("devrig", &section)
 	if err != nil {
-		return nil, fmt.Errorf("failed to process devrig section from %s: %w", s.configPath, err)
+		return nil, err
 	}
 
-	var section DevrigSection
-	if err := yaml.Unmarshal(devrigBytes, &section); err != nil {
-		return nil, fmt.Errorf("failed to parse devrig section from %s: %w", s.configPath, err)
+	if !found {
+		return nil, fmt.Errorf("devrig section not found in %s", s.configPath)
 	}
 
 	// Validate the section
@@ -81,7 +174,7 @@ func (s *configServiceImpl) EnsureValidConfig() error {
 	info, err := os.Stat(s.configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("devrig.yaml not found at: %s\n\nPlease run 'devrig init' to create it", s.configPath)
+			return fmt.Errorf("%w\n\nPlease run 'devrig init' to create it", &devrigErrors.ConfigNotFoundError{Path: s.configPath})
 		}
 		return fmt.Errorf("cannot access devrig.yaml at %s: %w", s.configPath, err)
 	}
@@ -99,6 +192,14 @@ func (s *configServiceImpl) EnsureValidConfig() error {
 	return nil
 }
 
+// ValidateChannel checks that the release channel is known, an empty channel means stable
+func ValidateChannel(channel string) error {
+	if channel == "" || slices.Contains(KnownChannels, channel) {
+		return nil
+	}
+	return fmt.Errorf("unknown release channel: %s (expected one of %s)", channel, strings.Join(KnownChannels, ", "))
+}
+
 // validateDevrigSection validates the devrig section structure and required fields
 func validateDevrigSection(section *DevrigSection) error {
 	if section == nil {
@@ -109,11 +210,20 @@ func validateDevrigSection(section *DevrigSection) error {
 		return fmt.Errorf("no binaries configured in devrig section")
 	}
 
+	if err := ValidateChannel(section.Channel); err != nil {
+		return err
+	}
+
 	// Validate each binary entry
 	for platform, binary := range section.Binaries {
 		if binary.URL == "" {
 			return fmt.Errorf("missing URL for platform: %s", platform)
 		}
+		for i, mirror := range binary.Mirrors {
+			if mirror == "" {
+				return fmt.Errorf("empty mirror URL #%d for platform: %s", i+1, platform)
+			}
+		}
 		if binary.SHA512 == "" {
 			return fmt.Errorf("missing SHA512 hash for platform: %s", platform)
 		}
@@ -128,6 +128,33 @@ func TestConfigService_ReadDevrigSection_InvalidHash(t *testing.T) {
 	}
 }
 
+func TestConfigService_ReadDevrigSection_Channel(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "devrig.yaml")
+
+	hash := strings.Repeat("a", 128)
+	for channel, valid := range map[string]bool{"beta": true, "nightly": true, "stable": true, "weekly": false} {
+		yamlContent := "devrig:\n  channel: " + channel + "\n  binaries:\n    linux-x86_64:\n      url: https://example.com/binary\n      sha512: " + hash + "\n"
+		if err := os.WriteFile(testFile, []byte(yamlContent), 0644); err != nil {
+			t.Fatalf("Failed to write test file: %v", err)
+		}
+
+		section, err := NewConfigService(testFile).Binaries().ReadDevrigSection()
+		if !valid {
+			if err == nil || !strings.Contains(err.Error(), "unknown release channel") {
+				t.Errorf("Expected unknown channel error for %s, got: %v", channel, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Failed to read devrig section with channel %s: %v", channel, err)
+		}
+		if section.Channel != channel {
+			t.Errorf("Expected channel %s, got: %s", channel, section.Channel)
+		}
+	}
+}
+
 func TestConfigService_ReadDevrigSection_NonHexHash(t *testing.T) {
 	tempDir := t.TempDir()
 	testFile := filepath.Join(tempDir, "devrig.yaml")
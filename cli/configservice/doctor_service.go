@@ -0,0 +1,81 @@
+package configservice
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultDoctorCheckTimeoutSeconds limits a custom check that declares no timeout
+const DefaultDoctorCheckTimeoutSeconds = 30
+
+// DoctorSection declares the checks of the team `devrig doctor` runs next to the built-in ones
+type DoctorSection struct {
+	Checks []DoctorCheck `yaml:"checks,omitempty"`
+}
+
+// DoctorCheck is a command that verifies a requirement of the environment, e.g. a VPN or a certificate
+type DoctorCheck struct {
+	Name string `yaml:"name"`
+	// Command is the executable and its arguments, it runs in the folder of devrig.yaml without a shell
+	Command []string `yaml:"command"`
+	// ExpectedExitCode is the exit code of a passing check, zero by default
+	ExpectedExitCode int `yaml:"expected_exit_code,omitempty"`
+	// ExpectedOutput is a regular expression the combined output of a passing check must match
+	ExpectedOutput string `yaml:"expected_output,omitempty"`
+	// Message explains how to fix the environment when the check fails
+	Message string `yaml:"message,omitempty"`
+	// Warning reports the check as a warning instead of a failure
+	Warning bool `yaml:"warning,omitempty"`
+	// TimeoutSeconds limits the command, DefaultDoctorCheckTimeoutSeconds is used without it
+	TimeoutSeconds int `yaml:"timeout_seconds,omitempty"`
+}
+
+// DoctorService manages the doctor section of devrig.yaml
+type DoctorService interface {
+	// ReadDoctor reads the doctor section from devrig.yaml
+	// Returns an empty section if devrig.yaml has no doctor section
+	ReadDoctor() (*DoctorSection, error)
+}
+
+// ReadDoctor reads the doctor section from devrig.yaml
+func (s *configServiceImpl) ReadDoctor() (*DoctorSection, error) {
+	var section DoctorSection
+	if _, err := s.readSection("doctor", &section); err != nil {
+		return nil, err
+	}
+
+	if err := validateDoctorSection(&section); err != nil {
+		return nil, fmt.Errorf("validation failed for %s: %w", s.configPath, err)
+	}
+	for i := range section.Checks {
+		if section.Checks[i].TimeoutSeconds == 0 {
+			section.Checks[i].TimeoutSeconds = DefaultDoctorCheckTimeoutSeconds
+		}
+	}
+	return &section, nil
+}
+
+// validateDoctorSection checks every check has a unique name, a command, a valid regular expression and timeout
+func validateDoctorSection(section *DoctorSection) error {
+	names := map[string]bool{}
+	for i, check := range section.Checks {
+		if strings.TrimSpace(check.Name) == "" {
+			return fmt.Errorf("missing name of doctor check %d", i+1)
+		}
+		if names[check.Name] {
+			return fmt.Errorf("duplicate doctor check %s", check.Name)
+		}
+		names[check.Name] = true
+		if len(check.Command) == 0 || strings.TrimSpace(check.Command[0]) == "" {
+			return fmt.Errorf("missing command of doctor check %s", check.Name)
+		}
+		if _, err := regexp.Compile(check.ExpectedOutput); err != nil {
+			return fmt.Errorf("invalid expected_output of doctor check %s: %w", check.Name, err)
+		}
+		if check.TimeoutSeconds < 0 {
+			return fmt.Errorf("timeout_seconds of doctor check %s must not be negative, got %d", check.Name, check.TimeoutSeconds)
+		}
+	}
+	return nil
+}
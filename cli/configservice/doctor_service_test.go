@@ -0,0 +1,46 @@
+package configservice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDoctorService_ReadDoctor(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+	content := "doctor:\n  checks:\n    - name: vpn\n      command: [ping, -c1, git.example.com]\n    - name: certificate\n      command: [openssl, version]\n      timeout_seconds: 5\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	section, err := NewConfigService(testFile).Doctor().ReadDoctor()
+	if err != nil {
+		t.Fatalf("Failed to read the doctor section: %v", err)
+	}
+	if len(section.Checks) != 2 || len(section.Checks[0].Command) != 3 {
+		t.Fatalf("Unexpected checks: %+v", section.Checks)
+	}
+	if section.Checks[0].TimeoutSeconds != DefaultDoctorCheckTimeoutSeconds || section.Checks[1].TimeoutSeconds != 5 {
+		t.Errorf("Expected the default timeout only without timeout_seconds, got %+v", section.Checks)
+	}
+}
+
+func TestDoctorService_ReadDoctor_Invalid(t *testing.T) {
+	for name, checks := range map[string]string{
+		"missing command":  "    - name: vpn\n      command: []\n",
+		"duplicate name":   "    - {name: vpn, command: [ping]}\n    - {name: vpn, command: [curl]}\n",
+		"invalid regexp":   "    - {name: vpn, command: [ping], expected_output: \"[\"}\n",
+		"negative timeout": "    - {name: vpn, command: [ping], timeout_seconds: -1}\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+			if err := os.WriteFile(testFile, []byte("doctor:\n  checks:\n"+checks), 0644); err != nil {
+				t.Fatalf("Failed to write file: %v", err)
+			}
+			if _, err := NewConfigService(testFile).Doctor().ReadDoctor(); err == nil || !strings.Contains(err.Error(), "validation failed") {
+				t.Errorf("Expected a validation error, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package configservice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigService_TOML(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "devrig.toml")
+	sha := strings.Repeat("a", 128)
+	content := `# Team configuration
+[devrig]
+channel = "beta" # testing the next release
+
+[devrig.binaries.linux-x86_64]
+url = "https://example.com/devrig"
+sha512 = "` + sha + `"
+
+[tools]
+node = "20.11.0"
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	service := NewConfigService(configPath)
+
+	section, err := service.Binaries().ReadDevrigSection()
+	if err != nil {
+		t.Fatalf("Failed to read devrig section: %v", err)
+	}
+	if section.Channel != "beta" || section.Binaries["linux-x86_64"].SHA512 != sha {
+		t.Errorf("Unexpected devrig section: %+v", section)
+	}
+	if err := service.EnsureValidConfig(); err != nil {
+		t.Errorf("Expected devrig.toml to be valid: %v", err)
+	}
+	problems, err := service.Validate()
+	if err != nil || len(problems) != 0 {
+		t.Errorf("Expected no problems, got %v, %v", problems, err)
+	}
+
+	if err := service.Tools().UpdateTools(ToolsSection{"node": "22.1.0", "go": "1.25.1"}); err != nil {
+		t.Fatalf("Failed to update tools: %v", err)
+	}
+	if err := service.Values().SetValue("devrig.channel", "stable"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	text := string(data)
+	for _, expected := range []string{"# Team configuration\n", `channel = "stable" # testing the next release`,
+		"[tools]\ngo = \"1.25.1\"\nnode = \"22.1.0\"\n"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in devrig.toml:\n%s", expected, text)
+		}
+	}
+
+	value, err := service.Values().GetValue("tools.go")
+	if err != nil || value != "1.25.1" {
+		t.Errorf("Expected tools.go to be 1.25.1, got %v, %v", value, err)
+	}
+	if err := service.Values().SetValue("retention.keep_unpacked_ides", "many"); err == nil {
+		t.Error("Expected a value of the wrong type to be rejected")
+	}
+}
+
+func TestConfigService_TOMLProblemsHaveNoPositions(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "devrig.toml")
+	if err := os.WriteFile(configPath, []byte("[tools]\nnode = \"22.1.0\"\n\n[unknown]\nkey = 1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	problems, err := NewConfigService(configPath).Validate()
+	if err != nil {
+		t.Fatalf("Failed to validate: %v", err)
+	}
+	found := false
+	for _, problem := range problems {
+		if problem.Line != 0 || problem.Column != 0 {
+			t.Errorf("Expected no position in the converted TOML: %v", problem)
+		}
+		found = found || problem.Path == "unknown"
+	}
+	if !found {
+		t.Errorf("Expected a problem for the unknown section, got %v", problems)
+	}
+}
+
+func TestConfigService_JSON(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "devrig.json")
+	if err := os.WriteFile(configPath, []byte(`{"tools": {"node": "20.11.0"}}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	service := NewConfigService(configPath)
+
+	if err := service.Values().SetValue("tools.go", "1.25.1"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+	tools, err := service.Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	if tools["node"] != "20.11.0" || tools["go"] != "1.25.1" {
+		t.Errorf("Unexpected tools: %v", tools)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	expected := "{\n  \"tools\": {\n    \"node\": \"20.11.0\",\n    \"go\": \"1.25.1\"\n  }\n}\n"
+	if string(data) != expected {
+		t.Errorf("Unexpected devrig.json:\n%s", data)
+	}
+}
+
+func TestConfigService_CreatesTOML(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "devrig.toml")
+	service := NewConfigService(configPath)
+	if err := service.Tools().UpdateTools(ToolsSection{"node": "22.1.0"}); err != nil {
+		t.Fatalf("Failed to write tools: %v", err)
+	}
+
+	tools, err := service.Tools().ReadTools()
+	if err != nil || tools["node"] != "22.1.0" {
+		t.Errorf("Expected node 22.1.0, got %v, %v", tools, err)
+	}
+}
@@ -0,0 +1,94 @@
+package configservice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/golden"
+)
+
+// goldenDevrigSection is the devrig section the golden files are generated with
+func goldenDevrigSection() *DevrigSection {
+	return &DevrigSection{
+		Version:     "0.80.0",
+		ReleaseDate: "2026-10-01",
+		Binaries: map[string]BinaryInfo{
+			"windows-x86_64": {URL: "https://devrig.dev/download/v0.80.0/devrig-windows-x86_64.exe", SHA512: strings.Repeat("c", 128)},
+			"darwin-arm64":   {URL: "https://devrig.dev/download/v0.80.0/devrig-darwin-arm64", SHA512: strings.Repeat("a", 128)},
+			"linux-x86_64": {
+				URL:     "https://devrig.dev/download/v0.80.0/devrig-linux-x86_64",
+				SHA512:  strings.Repeat("b", 128),
+				Mirrors: []string{"https://mirror.example.com/devrig/v0.80.0/devrig-linux-x86_64"},
+			},
+		},
+	}
+}
+
+func TestGolden_UpdateBinaries(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "*.yaml"))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("Failed to list the fixtures: %v", err)
+	}
+	for _, fixture := range fixtures {
+		name := filepath.Base(fixture)
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", fixture, err)
+			}
+			configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+			if err := os.WriteFile(configPath, data, 0644); err != nil {
+				t.Fatalf("Failed to write devrig.yaml: %v", err)
+			}
+			if err := NewConfigService(configPath).Binaries().UpdateBinaries(goldenDevrigSection()); err != nil {
+				t.Fatalf("Failed to update the binaries: %v", err)
+			}
+			assertGoldenFile(t, "update-binaries/"+name, configPath)
+		})
+	}
+}
+
+func TestGolden_NewConfigFile(t *testing.T) {
+	for _, name := range []string{"devrig.yaml", "devrig.toml", "devrig.json"} {
+		t.Run(name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), name)
+			service := NewConfigService(configPath)
+			if err := service.Binaries().UpdateBinaries(goldenDevrigSection()); err != nil {
+				t.Fatalf("Failed to write the binaries: %v", err)
+			}
+			if err := service.Tools().UpdateTools(ToolsSection{"node": "20", "go": "1.22.5"}); err != nil {
+				t.Fatalf("Failed to write the tools: %v", err)
+			}
+			assertGoldenFile(t, "new-file/"+name, configPath)
+		})
+	}
+}
+
+func TestGolden_SetValue(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "with-multiline-comments.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read the fixture: %v", err)
+	}
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	values := NewConfigService(configPath).Values()
+	for _, value := range [][2]string{{"settings.cache_dir", "~/.cache/devrig"}, {"retention.keep_unpacked_ides", "3"}} {
+		if err := values.SetValue(value[0], value[1]); err != nil {
+			t.Fatalf("Failed to set %s: %v", value[0], err)
+		}
+	}
+	assertGoldenFile(t, "set-value/devrig.yaml", configPath)
+}
+
+func assertGoldenFile(t *testing.T, name string, path string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	golden.Assert(t, name, data)
+}
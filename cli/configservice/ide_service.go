@@ -0,0 +1,221 @@
+package configservice
+
+import (
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/deprecation"
+)
+
+// IdeSection is the IDE of the project, it is downloaded from the JetBrains feed and started with `devrig ide launch`
+type IdeSection struct {
+	// Name is the product name of the feed, e.g. GoLand or IntelliJ IDEA Ultimate
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	// Build selects an exact build of the version, the latest one is used otherwise
+	Build string `yaml:"build,omitempty"`
+	// Hash is ignored, the checksum comes from the feed and devrig.lock
+	Hash string `yaml:"hash,omitempty"`
+	// PackageTypes is the preference order of the package types of the feed per OS, e.g. darwin: [zip, dmg].
+	// The first type the build is published with is downloaded, the defaults of the feed package apply to other OSes
+	PackageTypes map[string][]string `yaml:"package_types,omitempty"`
+	// Plugins are installed into the IDE from the JetBrains Marketplace
+	Plugins []IdePlugin `yaml:"plugins,omitempty"`
+	// Launch configures how `devrig ide launch` starts the IDE
+	Launch *IdeLaunchSection `yaml:"launch,omitempty"`
+}
+
+// IdePlugin is a plugin of the JetBrains Marketplace, the latest compatible version is used without a version
+type IdePlugin struct {
+	ID      string `yaml:"id"`
+	Version string `yaml:"version,omitempty"`
+	// Checksum is the hex SHA-256 of the plugin download, the installation fails if it differs
+	Checksum string `yaml:"checksum,omitempty"`
+}
+
+// IdesSection lists the additional IDEs of the project, e.g. Rider for the backend and WebStorm for the frontend.
+// The IDE of the ide section comes first and is not repeated here
+type IdesSection []IdeSection
+
+// IdeLaunchSection lists the project to open and the arguments and environment variables of the IDE process.
+// The values may use ${name} template variables, e.g. ${project_root} or ${java_home}
+type IdeLaunchSection struct {
+	// Project is the folder or the file to open, e.g. a solution, relative to the folder of devrig.yaml.
+	// The folder of devrig.yaml is opened when it is empty
+	Project string `yaml:"project,omitempty"`
+	// Args are passed to the IDE before the project
+	Args []string `yaml:"args,omitempty"`
+	// Env is added to the environment of the IDE process
+	Env map[string]string `yaml:"env,omitempty"`
+}
+
+func init() {
+	deprecation.Register(deprecation.Deprecation{
+		Kind:        deprecation.KindConfigKey,
+		Name:        "ide.hash",
+		Replacement: "devrig.lock",
+		Hint:        "The key is ignored, the checksum of the IDE comes from the feed, run `devrig lock` to pin it",
+	})
+}
+
+// Request returns the IDE to resolve in the JetBrains feed
+func (s *IdeSection) Request() config.IDEConfig {
+	return config.NewIDEConfig(s.Name, s.Version, s.Build)
+}
+
+// IdeService manages the ide section of devrig.yaml
+type IdeService interface {
+	// ReadIde reads the ide section from devrig.yaml
+	// Returns nil if devrig.yaml has no ide section
+	ReadIde() (*IdeSection, error)
+
+	// UpdateIde replaces the ide section in devrig.yaml while preserving comments and formatting
+	UpdateIde(ide *IdeSection) error
+
+	// ReadIdes reads all IDEs of devrig.yaml, the one of the ide section first and then the ides section
+	// Returns an empty list if devrig.yaml declares no IDE
+	ReadIdes() (IdesSection, error)
+
+	// UpdateIdes replaces the ides section in devrig.yaml while preserving comments and formatting,
+	// the ide section is not changed
+	UpdateIdes(ides IdesSection) error
+}
+
+// ReadIde reads the ide section from devrig.yaml
+func (s *configServiceImpl) ReadIde() (*IdeSection, error) {
+	var section IdeSection
+	found, err := s.readSection("ide", &section)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+
+	if err := validateIdeSection(&section); err != nil {
+		return nil, fmt.Errorf("validation failed for %s: %w", s.configPath, err)
+	}
+	return &section, nil
+}
+
+// UpdateIde replaces the ide section in devrig.yaml
+func (s *configServiceImpl) UpdateIde(ide *IdeSection) error {
+	if err := validateIdeSection(ide); err != nil {
+		return fmt.Errorf("invalid ide section: %w", err)
+	}
+	return s.writeSection("ide", ide)
+}
+
+// ReadIdes reads the ide and the ides sections from devrig.yaml
+func (s *configServiceImpl) ReadIdes() (IdesSection, error) {
+	primary, err := s.ReadIde()
+	if err != nil {
+		return nil, err
+	}
+	var ides IdesSection
+	if _, err := s.readSection("ides", &ides); err != nil {
+		return nil, err
+	}
+	if err := validateIdesSection(&ides); err != nil {
+		return nil, fmt.Errorf("validation failed for %s: %w", s.configPath, err)
+	}
+
+	if primary == nil {
+		return ides, nil
+	}
+	if ides.Find(primary.Name) != nil {
+		return nil, fmt.Errorf("validation failed for %s: %s is declared in both the ide and the ides sections", s.configPath, primary.Name)
+	}
+	return append(IdesSection{*primary}, ides...), nil
+}
+
+// UpdateIdes replaces the ides section in devrig.yaml
+func (s *configServiceImpl) UpdateIdes(ides IdesSection) error {
+	if err := validateIdesSection(&ides); err != nil {
+		return fmt.Errorf("invalid ides section: %w", err)
+	}
+	return s.writeSection("ides", ides)
+}
+
+// Find returns the IDE with the given name, the names are compared ignoring the case. Returns nil if it is not declared
+func (ides IdesSection) Find(name string) *IdeSection {
+	for i := range ides {
+		if strings.EqualFold(ides[i].Name, name) {
+			return &ides[i]
+		}
+	}
+	return nil
+}
+
+// validateIdesSection checks every IDE of the list and that each IDE is declared once
+func validateIdesSection(ides *IdesSection) error {
+	names := map[string]bool{}
+	for i := range *ides {
+		ide := &(*ides)[i]
+		if err := validateIdeSection(ide); err != nil {
+			return fmt.Errorf("ides[%d]: %w", i, err)
+		}
+		name := strings.ToLower(ide.Name)
+		if names[name] {
+			return fmt.Errorf("duplicate IDE %s in ides section", ide.Name)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+// isSha256 checks the value is a hex SHA-256
+func isSha256(value string) bool {
+	if len(value) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// packageTypeOSes are the keys of package_types, the OS names of Go
+var packageTypeOSes = []string{"darwin", "linux", "windows"}
+
+// validateIdeSection checks the IDE is selected, the package types are set per known OS, the plugins have IDs and SHA-256 checksums and the launch environment has variable names
+func validateIdeSection(section *IdeSection) error {
+	if strings.TrimSpace(section.Name) == "" {
+		return fmt.Errorf("missing name in ide section")
+	}
+	if strings.TrimSpace(section.Version) == "" {
+		return fmt.Errorf("missing version in ide section")
+	}
+	for goos, types := range section.PackageTypes {
+		if !slices.Contains(packageTypeOSes, goos) {
+			return fmt.Errorf("unsupported OS %s in package_types of %s, expected one of %s", goos, section.Name, strings.Join(packageTypeOSes, ", "))
+		}
+		for _, packageType := range types {
+			if strings.TrimSpace(packageType) == "" {
+				return fmt.Errorf("empty package type for %s in package_types of %s", goos, section.Name)
+			}
+		}
+	}
+	plugins := map[string]bool{}
+	for _, plugin := range section.Plugins {
+		if strings.TrimSpace(plugin.ID) == "" {
+			return fmt.Errorf("missing id of a plugin of %s", section.Name)
+		}
+		if plugins[plugin.ID] {
+			return fmt.Errorf("duplicate plugin %s of %s", plugin.ID, section.Name)
+		}
+		if plugin.Checksum != "" && !isSha256(plugin.Checksum) {
+			return fmt.Errorf("invalid checksum of plugin %s of %s, expected the hex SHA-256 of the download", plugin.ID, section.Name)
+		}
+		plugins[plugin.ID] = true
+	}
+	if section.Launch != nil {
+		for name := range section.Launch.Env {
+			if strings.TrimSpace(name) == "" || strings.Contains(name, "=") {
+				return fmt.Errorf("invalid environment variable name %q in ide launch section", name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package configservice
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIdeService_ReadIdes(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+	content := `ide:
+  name: GoLand
+  version: 2024.3
+ides:
+  - name: WebStorm
+    version: 2024.3
+    build: 243.2
+  - name: Rider
+    version: 2025.1
+    plugins:
+      - id: com.intellij.resharper.azure
+      - id: org.jetbrains.plugins.go-template
+        version: 251.1
+`
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	ides, err := NewConfigService(testFile).IDE().ReadIdes()
+	if err != nil {
+		t.Fatalf("Failed to read ides: %v", err)
+	}
+	var names []string
+	for _, ide := range ides {
+		names = append(names, ide.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"GoLand", "WebStorm", "Rider"}) {
+		t.Errorf("Unexpected IDEs: %v", names)
+	}
+
+	rider := ides.Find("rider")
+	if rider == nil {
+		t.Fatalf("Expected to find Rider ignoring the case")
+	}
+	expected := []IdePlugin{{ID: "com.intellij.resharper.azure"}, {ID: "org.jetbrains.plugins.go-template", Version: "251.1"}}
+	if !reflect.DeepEqual(rider.Plugins, expected) {
+		t.Errorf("Unexpected plugins: %+v", rider.Plugins)
+	}
+	if ides.Find("CLion") != nil {
+		t.Error("Expected no CLion")
+	}
+}
+
+func TestIdeService_ReadIdes_MissingSections(t *testing.T) {
+	ides, err := NewConfigService("testdata/basic.yaml").IDE().ReadIdes()
+	if err != nil {
+		t.Fatalf("Failed to read ides: %v", err)
+	}
+	if len(ides) != 0 {
+		t.Errorf("Expected no IDEs, got: %+v", ides)
+	}
+}
+
+func TestIdeService_ReadIdes_Duplicates(t *testing.T) {
+	for name, content := range map[string]string{
+		"in the list":      "ides:\n  - {name: Rider, version: 2025.1}\n  - {name: rider, version: 2024.3}\n",
+		"in both sections": "ide:\n  name: Rider\n  version: 2025.1\nides:\n  - {name: Rider, version: 2024.3}\n",
+		"plugins":          "ides:\n  - name: Rider\n    version: 2025.1\n    plugins: [{id: a}, {id: a}]\n",
+		"plugin checksum":  "ides:\n  - name: Rider\n    version: 2025.1\n    plugins: [{id: a, checksum: md5}]\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+			if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+			if _, err := NewConfigService(testFile).IDE().ReadIdes(); err == nil || !strings.Contains(err.Error(), "validation failed") {
+				t.Errorf("Expected a validation error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestIdeService_UpdateIdes(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+	initialContent := `# project config
+ide:
+  name: GoLand # the main IDE
+  version: 2024.3
+`
+	if err := os.WriteFile(testFile, []byte(initialContent), 0644); err != nil {
+		t.Fatalf("Failed to write initial config: %v", err)
+	}
+
+	service := NewConfigService(testFile)
+	update := IdesSection{{Name: "Rider", Version: "2025.1", Plugins: []IdePlugin{{ID: "com.intellij.resharper.azure"}}}}
+	if err := service.IDE().UpdateIdes(update); err != nil {
+		t.Fatalf("Failed to update ides: %v", err)
+	}
+
+	ides, err := service.IDE().ReadIdes()
+	if err != nil {
+		t.Fatalf("Failed to read ides: %v", err)
+	}
+	if len(ides) != 2 || ides[0].Name != "GoLand" || !reflect.DeepEqual(ides[1], update[0]) {
+		t.Errorf("Unexpected IDEs: %+v", ides)
+	}
+
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	for _, comment := range []string{"# project config", "# the main IDE"} {
+		if !strings.Contains(string(data), comment) {
+			t.Errorf("Comment %q was not preserved:\n%s", comment, data)
+		}
+	}
+	for _, problem := range ValidateBytes(data) {
+		if strings.HasPrefix(problem.Path, "ide") {
+			t.Errorf("Unexpected problem: %v", problem)
+		}
+	}
+
+	if err := service.IDE().UpdateIdes(IdesSection{{Name: "Rider"}}); err == nil || !strings.Contains(err.Error(), "missing version") {
+		t.Errorf("Expected missing version error, got: %v", err)
+	}
+}
+
+func TestIdeService_ReadIde_PackageTypes(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+	content := "ide:\n  name: GoLand\n  version: 2024.3\n  package_types:\n    darwin: [zip, dmg]\n"
+	if err := os.WriteFile(testFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	ide, err := NewConfigService(testFile).IDE().ReadIde()
+	if err != nil {
+		t.Fatalf("Failed to read ide: %v", err)
+	}
+	if !reflect.DeepEqual(ide.PackageTypes, map[string][]string{"darwin": {"zip", "dmg"}}) {
+		t.Errorf("Unexpected package types: %v", ide.PackageTypes)
+	}
+
+	err = NewConfigService(testFile).IDE().UpdateIde(&IdeSection{Name: "GoLand", Version: "2024.3", PackageTypes: map[string][]string{"macos": {"zip"}}})
+	if err == nil || !strings.Contains(err.Error(), "unsupported OS macos") {
+		t.Errorf("Expected unsupported OS error, got: %v", err)
+	}
+}
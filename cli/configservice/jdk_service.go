@@ -0,0 +1,64 @@
+package configservice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JdkSection records the JDK installed with `devrig install jdk`, for other tooling to consume
+type JdkSection struct {
+	// Vendor is the distribution of the JDK: temurin or jbr
+	Vendor  string `yaml:"vendor"`
+	Version string `yaml:"version"`
+	// Path is the Java home, relative to the folder of devrig.yaml when it is inside the project
+	Path string `yaml:"path"`
+}
+
+// JdkService manages the jdk section of devrig.yaml
+type JdkService interface {
+	// ReadJdk reads the jdk section from devrig.yaml
+	// Returns nil if devrig.yaml has no jdk section
+	ReadJdk() (*JdkSection, error)
+
+	// UpdateJdk replaces the jdk section in devrig.yaml while preserving comments and formatting
+	UpdateJdk(jdk *JdkSection) error
+}
+
+// ReadJdk reads the jdk section from devrig.yaml
+func (s *configServiceImpl) ReadJdk() (*JdkSection, error) {
+	var section JdkSection
+	found, err := s.readSection("jdk", &section)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+
+	if err := validateJdkSection(&section); err != nil {
+		return nil, fmt.Errorf("validation failed for %s: %w", s.configPath, err)
+	}
+	return &section, nil
+}
+
+// UpdateJdk replaces the jdk section in devrig.yaml
+func (s *configServiceImpl) UpdateJdk(jdk *JdkSection) error {
+	if err := validateJdkSection(jdk); err != nil {
+		return fmt.Errorf("invalid jdk section: %w", err)
+	}
+	return s.writeSection("jdk", jdk)
+}
+
+// validateJdkSection checks that all fields are set
+func validateJdkSection(section *JdkSection) error {
+	if strings.TrimSpace(section.Vendor) == "" {
+		return fmt.Errorf("missing vendor in jdk section")
+	}
+	if strings.TrimSpace(section.Version) == "" {
+		return fmt.Errorf("missing version in jdk section")
+	}
+	if strings.TrimSpace(section.Path) == "" {
+		return fmt.Errorf("missing path in jdk section")
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package configservice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJdkService_ReadJdk_MissingSection(t *testing.T) {
+	service := NewConfigService("testdata/basic.yaml")
+
+	jdk, err := service.Jdk().ReadJdk()
+	if err != nil {
+		t.Fatalf("Failed to read jdk: %v", err)
+	}
+	if jdk != nil {
+		t.Errorf("Expected no jdk section, got: %+v", jdk)
+	}
+}
+
+func TestJdkService_UpdateJdk(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+	initialContent := `# project config
+tools:
+  java: "21"
+`
+	if err := os.WriteFile(testFile, []byte(initialContent), 0644); err != nil {
+		t.Fatalf("Failed to write initial config: %v", err)
+	}
+
+	service := NewConfigService(testFile)
+	section := &JdkSection{Vendor: "temurin", Version: "21.0.5+11", Path: ".devrig/tools/jdk-temurin-21.0.5+11"}
+	if err := service.Jdk().UpdateJdk(section); err != nil {
+		t.Fatalf("Failed to update jdk: %v", err)
+	}
+
+	jdk, err := service.Jdk().ReadJdk()
+	if err != nil {
+		t.Fatalf("Failed to read jdk: %v", err)
+	}
+	if jdk == nil || *jdk != *section {
+		t.Errorf("Unexpected jdk section: %+v", jdk)
+	}
+
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if !strings.Contains(string(data), "# project config") {
+		t.Errorf("Comment was not preserved:\n%s", data)
+	}
+}
+
+func TestJdkService_UpdateJdk_Invalid(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(testFile, []byte("tools: {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write initial config: %v", err)
+	}
+
+	err := NewConfigService(testFile).Jdk().UpdateJdk(&JdkSection{Vendor: "temurin", Version: "21"})
+	if err == nil || !strings.Contains(err.Error(), "missing path") {
+		t.Errorf("Expected missing path error, got: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package configservice
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	"github.com/goccy/go-yaml"
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configfile"
+)
+
+const (
+	// LocalConfigName is the optional file next to devrig.yaml with personal overrides, it is kept out of version control
+	LocalConfigName = "devrig.local.yaml"
+	// includeKey lists more files merged over devrig.yaml, paths are relative to devrig.yaml
+	includeKey = "include"
+)
+
+func init() {
+	// The ide section is read by the config package
+	config.ApplyLayers = ApplyLayers
+}
+
+// ApplyLayers merges the layers over the values parsed from devrig.yaml at configPath, in this order: the files of the
+// include key, devrig.local.yaml, the sections of the active profile, DEVRIG_OVERRIDE and the DEVRIG_SET_ variables.
+// Mappings are deep-merged, scalars and lists of a later layer replace the earlier ones. The policy section of
+// devrig.yaml is applied to every layer
+func ApplyLayers(configPath string, values map[string]interface{}) error {
+	layers, err := readLayers(configPath, values)
+	if err != nil {
+		return err
+	}
+	mergeValues(values, layers)
+	return nil
+}
+
+// readLayers returns the values of all layers merged together, without the values of devrig.yaml
+func readLayers(configPath string, values map[string]interface{}) (map[string]interface{}, error) {
+	files, err := layerFiles(configPath, values)
+	if err != nil {
+		return nil, err
+	}
+
+	layers := map[string]interface{}{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			// Included files are usually personal and gitignored, a missing one is not an error
+			slog.Debug("devrig.yaml layer not found", "path", file)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		layer, err := configfile.Decode(file, data)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := layer[includeKey]; ok {
+			return nil, fmt.Errorf("invalid %s: %s is only supported in devrig.yaml", file, includeKey)
+		}
+		if err := checkOverrideKeys("", layer); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", file, err)
+		}
+		if err := checkPolicy(values, file, layer); err != nil {
+			return nil, err
+		}
+		mergeValues(layers, layer)
+		slog.Debug("devrig.yaml layer merged", "path", file)
+	}
+
+	// The profile is selected once all files are merged, its sections win over the top-level ones
+	profile, err := selectProfile(values, layers)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPolicy(values, "the active profile", profile); err != nil {
+		return nil, err
+	}
+	mergeValues(layers, profile)
+
+	if err := ApplyOverrides(layers); err != nil {
+		return nil, err
+	}
+	if _, ok := layers[includeKey]; ok {
+		return nil, fmt.Errorf("invalid %s: %s is only supported in devrig.yaml", EnvOverride, includeKey)
+	}
+	if err := checkPolicy(values, EnvOverride, layers); err != nil {
+		return nil, err
+	}
+	return layers, nil
+}
+
+// IncludedFiles lists the files of the include key of devrig.yaml at configPath, devrig.local.yaml is not included
+func IncludedFiles(configPath string) ([]string, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
+	values, err := configfile.Decode(configPath, data)
+	if err != nil {
+		return nil, err
+	}
+	files, err := layerFiles(configPath, values)
+	if err != nil {
+		return nil, err
+	}
+	local := filepath.Join(filepath.Dir(configPath), LocalConfigName)
+	return slices.DeleteFunc(files, func(file string) bool { return file == local }), nil
+}
+
+// layerFiles lists the files of the include key and devrig.local.yaml, each file once in the order of the merge
+func layerFiles(configPath string, values map[string]interface{}) ([]string, error) {
+	baseDir := filepath.Dir(configPath)
+	var includes []interface{}
+	switch value := values[includeKey].(type) {
+	case nil:
+	case []interface{}:
+		includes = value
+	default:
+		return nil, fmt.Errorf("invalid %s in %s: expected a list of files", includeKey, configPath)
+	}
+
+	var files []string
+	seen := map[string]bool{}
+	add := func(file string) {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(baseDir, file)
+		}
+		file = filepath.Clean(file)
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	for _, include := range includes {
+		file, ok := include.(string)
+		if !ok || file == "" {
+			return nil, fmt.Errorf("invalid %s in %s: expected a list of files", includeKey, configPath)
+		}
+		add(file)
+	}
+	add(LocalConfigName)
+	return files, nil
+}
+
+// stripLayer returns the section to write into devrig.yaml with the values that come from the layer replaced by
+// the committed ones, so personal overrides are not written into version control when a section is updated.
+// Returns false if the value is not committed and must be left out
+func stripLayer(section interface{}, layer interface{}, committed interface{}, hasCommitted bool) (interface{}, bool) {
+	layerMap, layerIsMap := layer.(map[string]interface{})
+	sectionMap, sectionIsMap := section.(map[string]interface{})
+	if layerIsMap && sectionIsMap {
+		committedMap, _ := committed.(map[string]interface{})
+		result := make(map[string]interface{}, len(sectionMap))
+		for key, value := range sectionMap {
+			layerValue, fromLayer := layerMap[key]
+			if !fromLayer {
+				result[key] = value
+				continue
+			}
+			committedValue, found := committedMap[key]
+			if kept, keep := stripLayer(value, layerValue, committedValue, found); keep {
+				result[key] = kept
+			}
+		}
+		return result, true
+	}
+
+	if reflect.DeepEqual(section, layer) {
+		return committed, hasCommitted
+	}
+	return section, true
+}
+
+// toGeneric converts the value into the maps, lists and scalars YAML decodes into, so values can be compared
+func toGeneric(value interface{}) (interface{}, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
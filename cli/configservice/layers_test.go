@@ -0,0 +1,139 @@
+package configservice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/config"
+)
+
+func writeLayersTestFile(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+func TestApplyLayers_LocalFile(t *testing.T) {
+	configPath := writeOverridesTestConfig(t)
+	writeLayersTestFile(t, filepath.Dir(configPath), LocalConfigName, `
+devrig:
+  channel: beta
+tools:
+  go: 1.22.1
+ide:
+  name: IU
+  version: "2025.2"
+`)
+	service := NewConfigService(configPath)
+
+	section, err := service.Binaries().ReadDevrigSection()
+	if err != nil {
+		t.Fatalf("Failed to read devrig section: %v", err)
+	}
+	if section.Channel != "beta" || section.Version != "0.79.6" || len(section.Binaries) != 1 {
+		t.Errorf("Expected the channel from %s and the rest kept, got %+v", LocalConfigName, section)
+	}
+
+	tools, err := service.Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	if tools["node"] != "20.11.0" || tools["go"] != "1.22.1" {
+		t.Errorf("Expected the tools to be deep-merged, got %v", tools)
+	}
+
+	ide, err := config.ReadIDEConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read the ide section: %v", err)
+	}
+	if ide == nil || ide.Name() != "IU" || ide.Version() != "2025.2" {
+		t.Errorf("Expected the ide section from %s, got %+v", LocalConfigName, ide)
+	}
+}
+
+func TestApplyLayers_Order(t *testing.T) {
+	configPath := writeOverridesTestConfig(t)
+	dir := filepath.Dir(configPath)
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read devrig.yaml: %v", err)
+	}
+	writeLayersTestFile(t, dir, "devrig.yaml", string(data)+"include: [team.yaml, personal/missing.yaml, devrig.local.yaml]\n")
+	writeLayersTestFile(t, dir, "team.yaml", "tools:\n  node: 22.1.0\n  go: 1.21.0\nretention:\n  keep_unpacked_ides: 3\n")
+	writeLayersTestFile(t, dir, LocalConfigName, "tools:\n  go: 1.22.1\n")
+	t.Setenv("DEVRIG_SET_RETENTION__KEEP_UNPACKED_IDES", "1")
+
+	service := NewConfigService(configPath)
+	tools, err := service.Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	if tools["node"] != "22.1.0" || tools["go"] != "1.22.1" {
+		t.Errorf("Expected the later layer to win, got %v", tools)
+	}
+
+	retention, err := service.Retention().ReadRetention()
+	if err != nil {
+		t.Fatalf("Failed to read retention: %v", err)
+	}
+	if retention.KeepUnpackedIdes != 1 {
+		t.Errorf("Expected the variable to win over the files, got %d", retention.KeepUnpackedIdes)
+	}
+
+	problems, err := service.Validate()
+	if err != nil {
+		t.Fatalf("Failed to validate: %v", err)
+	}
+	for _, problem := range problems {
+		if strings.HasPrefix(problem.Path, includeKey) {
+			t.Errorf("Expected the include key to be valid, got %s", problem)
+		}
+	}
+}
+
+func TestApplyLayers_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown key": "devrig:\n  chanel: beta\n",
+		"include":     "include: [other.yaml]\n",
+		"yaml":        "tools: [\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			configPath := writeOverridesTestConfig(t)
+			writeLayersTestFile(t, filepath.Dir(configPath), LocalConfigName, content)
+			_, err := NewConfigService(configPath).Tools().ReadTools()
+			if err == nil || !strings.Contains(err.Error(), LocalConfigName) {
+				t.Errorf("Expected the invalid %s to be reported, got: %v", LocalConfigName, err)
+			}
+		})
+	}
+}
+
+func TestUpdateTools_KeepsLayersOutOfDevrigYaml(t *testing.T) {
+	configPath := writeOverridesTestConfig(t)
+	writeLayersTestFile(t, filepath.Dir(configPath), LocalConfigName, "tools:\n  node: 22.1.0\n  go: 1.22.1\n")
+	service := NewConfigService(configPath)
+
+	tools, err := service.Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	tools["python"] = "3.12.1"
+	if err := service.Tools().UpdateTools(tools); err != nil {
+		t.Fatalf("Failed to update tools: %v", err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read devrig.yaml: %v", err)
+	}
+	content := string(data)
+	if !strings.Contains(content, "python: 3.12.1") || !strings.Contains(content, "node: 20.11.0") {
+		t.Errorf("Expected the new tool and the committed version in devrig.yaml, got:\n%s", content)
+	}
+	if strings.Contains(content, "22.1.0") || strings.Contains(content, "go:") {
+		t.Errorf("Expected no values of %s in devrig.yaml, got:\n%s", LocalConfigName, content)
+	}
+}
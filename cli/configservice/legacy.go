@@ -0,0 +1,60 @@
+package configservice
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/goccy/go-yaml"
+)
+
+// ReadLegacyIde reads the ide section of the legacy .idew.yaml, the wrapper section of the file is ignored.
+// Returns nil if the file has no ide section
+func ReadLegacyIde(legacyPath string) (*IdeSection, error) {
+	data, err := os.ReadFile(legacyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", legacyPath, err)
+	}
+
+	var legacy struct {
+		IDE *IdeSection `yaml:"ide"`
+	}
+	if err := yaml.Unmarshal(data, &legacy); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML in %s: %w", legacyPath, err)
+	}
+	if legacy.IDE == nil {
+		return nil, nil
+	}
+	if err := validateIdeSection(legacy.IDE); err != nil {
+		return nil, fmt.Errorf("validation failed for %s: %w", legacyPath, err)
+	}
+	return legacy.IDE, nil
+}
+
+// MigrateLegacyIde moves the ide section of the legacy .idew.yaml into the ide section of devrig.yaml.
+// The ide section of devrig.yaml is kept if it declares the same IDE, a different IDE is an error
+func MigrateLegacyIde(configs ConfigService, legacyPath string) (*IdeSection, error) {
+	legacy, err := ReadLegacyIde(legacyPath)
+	if err != nil {
+		return nil, err
+	}
+	if legacy == nil {
+		return nil, fmt.Errorf("%s has no ide section to migrate", legacyPath)
+	}
+
+	current, err := configs.IDE().ReadIde()
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		if current.Name != legacy.Name || current.Version != legacy.Version || current.Build != legacy.Build {
+			return nil, fmt.Errorf("devrig.yaml already declares %s %s, %s declares %s %s, remove one of the ide sections",
+				current.Name, current.Version, legacyPath, legacy.Name, legacy.Version)
+		}
+		return current, nil
+	}
+
+	if err := configs.IDE().UpdateIde(legacy); err != nil {
+		return nil, fmt.Errorf("failed to write the ide section: %w", err)
+	}
+	return legacy, nil
+}
@@ -0,0 +1,49 @@
+package configservice
+
+import (
+	"fmt"
+)
+
+// DefaultMaintenanceIntervalHours is the minimal interval between two runs of a maintenance task
+const DefaultMaintenanceIntervalHours = 24
+
+// MaintenanceSection enables recurring maintenance, executed by regular commands when it is due
+type MaintenanceSection struct {
+	// AutoGC applies the retention policy to the IDE cache
+	AutoGC bool `yaml:"auto_gc,omitempty"`
+	// AutoPrefetch downloads the release metadata of the channel, so it is available offline
+	AutoPrefetch bool `yaml:"auto_prefetch,omitempty"`
+	// IntervalHours is the minimal number of hours between two runs of a task
+	IntervalHours int `yaml:"interval_hours,omitempty"`
+}
+
+// MaintenanceService manages the maintenance section of devrig.yaml
+type MaintenanceService interface {
+	// ReadMaintenance reads the maintenance section from devrig.yaml
+	// Missing values are filled with the defaults, all tasks are disabled by default
+	ReadMaintenance() (*MaintenanceSection, error)
+}
+
+// ReadMaintenance reads the maintenance section from devrig.yaml
+func (s *configServiceImpl) ReadMaintenance() (*MaintenanceSection, error) {
+	var section MaintenanceSection
+	if _, err := s.readSection("maintenance", &section); err != nil {
+		return nil, err
+	}
+
+	if err := validateMaintenanceSection(&section); err != nil {
+		return nil, fmt.Errorf("validation failed for %s: %w", s.configPath, err)
+	}
+	if section.IntervalHours == 0 {
+		section.IntervalHours = DefaultMaintenanceIntervalHours
+	}
+	return &section, nil
+}
+
+// validateMaintenanceSection checks the maintenance section read from devrig.yaml
+func validateMaintenanceSection(section *MaintenanceSection) error {
+	if section.IntervalHours < 0 {
+		return fmt.Errorf("interval_hours must not be negative, got %d", section.IntervalHours)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package configservice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configfile"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// snapshot is the state of devrig.yaml when a section was read. It is the base of the optimistic locking:
+// a section is read without the lock, so another devrig process may change the file before the section is written
+type snapshot struct {
+	modTime time.Time
+	size    int64
+	hash    string
+	// section is the committed value of the section, without the layers, nil if it was missing
+	section interface{}
+}
+
+// takeSnapshot reads devrig.yaml and its state, ConfigNotFoundError is returned if the file is missing
+func (s *configServiceImpl) takeSnapshot() (snapshot, map[string]interface{}, error) {
+	info, err := os.Stat(s.configPath)
+	if os.IsNotExist(err) {
+		return snapshot{}, nil, &devrigErrors.ConfigNotFoundError{Path: s.configPath}
+	}
+	if err != nil {
+		return snapshot{}, nil, fmt.Errorf("cannot access configuration file %s: %w", s.configPath, err)
+	}
+	data, err := s.readConfigFile()
+	if err != nil {
+		return snapshot{}, nil, err
+	}
+	values, err := configfile.Decode(s.configPath, data)
+	if err != nil {
+		return snapshot{}, nil, err
+	}
+	sum := sha256.Sum256(data)
+	return snapshot{modTime: info.ModTime(), size: info.Size(), hash: hex.EncodeToString(sum[:])}, values, nil
+}
+
+// unchanged tells whether devrig.yaml is the same as in the other snapshot,
+// the modification time is checked first and the content hash only when it differs, e.g. after a touch
+func (a snapshot) unchanged(b snapshot) bool {
+	if a.modTime.Equal(b.modTime) && a.size == b.size {
+		return true
+	}
+	return a.hash == b.hash
+}
+
+// remember records the committed value of the section, so the next write of it can detect concurrent changes
+func (s *configServiceImpl) remember(key string, state snapshot, values map[string]interface{}) error {
+	section, err := toGeneric(values[key])
+	if err != nil {
+		return fmt.Errorf("failed to process %s section from %s: %w", key, s.configPath, err)
+	}
+	state.section = section
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshots == nil {
+		s.snapshots = map[string]snapshot{}
+	}
+	s.snapshots[key] = state
+	return nil
+}
+
+// reconcile checks the section against the snapshot taken when it was read. The caller holds the devrig.yaml lock.
+// If another process changed only other sections, the value is written as is, they are kept by the section update.
+// If it changed the same section, the changes are merged key by key, and ConfigConflictError is returned
+// when both processes changed the same value differently. Returns the value to write and whether there is anything to write
+func (s *configServiceImpl) reconcile(key string, value interface{}) (interface{}, bool, error) {
+	s.mu.Lock()
+	base, found := s.snapshots[key]
+	s.mu.Unlock()
+	if !found {
+		// The section was not read by this service, there is nothing to compare with
+		return value, true, nil
+	}
+
+	current, values, err := s.takeSnapshot()
+	var notFound *devrigErrors.ConfigNotFoundError
+	if errors.As(err, &notFound) {
+		// The file was removed, its sections are missing
+		values = map[string]interface{}{}
+	} else if err != nil {
+		return nil, false, err
+	}
+	if base.unchanged(current) {
+		return value, true, nil
+	}
+
+	theirs, err := toGeneric(values[key])
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to process %s section from %s: %w", key, s.configPath, err)
+	}
+	if reflect.DeepEqual(theirs, base.section) {
+		slog.Debug("devrig.yaml was changed by another process, other sections are kept", "path", s.configPath, "section", key)
+		return value, true, nil
+	}
+
+	ours, err := toGeneric(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to process %s section: %w", key, err)
+	}
+	merged, ok := mergeChanges(base.section, ours, theirs)
+	if !ok {
+		return nil, false, &devrigErrors.ConfigConflictError{Path: s.configPath, Section: key}
+	}
+	slog.Debug("merged the concurrent change of the section", "path", s.configPath, "section", key)
+	if reflect.DeepEqual(merged, theirs) {
+		// The other process already wrote the same values
+		return nil, false, nil
+	}
+	if reflect.DeepEqual(merged, ours) {
+		return value, true, nil
+	}
+	return merged, true, nil
+}
+
+// absent marks a key that is missing in one of the versions of a mapping
+var absent = &struct{}{}
+
+// mergeChanges is a three-way merge of the generic values: the changes of ours and theirs against the base are combined.
+// Mappings are merged key by key, other values conflict when both sides changed them differently
+func mergeChanges(base, ours, theirs interface{}) (interface{}, bool) {
+	switch {
+	case reflect.DeepEqual(ours, theirs), reflect.DeepEqual(base, theirs):
+		return ours, true
+	case reflect.DeepEqual(base, ours):
+		return theirs, true
+	}
+
+	oursMap, oursIsMap := ours.(map[string]interface{})
+	theirsMap, theirsIsMap := theirs.(map[string]interface{})
+	if !oursIsMap || !theirsIsMap {
+		return nil, false
+	}
+	baseMap, _ := base.(map[string]interface{})
+
+	lookup := func(mapping map[string]interface{}, key string) interface{} {
+		if value, ok := mapping[key]; ok {
+			return value
+		}
+		return absent
+	}
+	merged := map[string]interface{}{}
+	for _, mapping := range []map[string]interface{}{oursMap, theirsMap} {
+		for key := range mapping {
+			if _, done := merged[key]; done {
+				continue
+			}
+			value, ok := mergeChanges(lookup(baseMap, key), lookup(oursMap, key), lookup(theirsMap, key))
+			if !ok {
+				return nil, false
+			}
+			merged[key] = value
+		}
+	}
+	for key, value := range merged {
+		if value == absent {
+			delete(merged, key)
+		}
+	}
+	return merged, true
+}
@@ -0,0 +1,178 @@
+package configservice
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+func writeConcurrencyConfig(t *testing.T) string {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	content := "# Team configuration\ndevrig:\n  binaries:\n    linux-x86_64:\n      url: https://example.com/devrig\n      sha512: " +
+		strings.Repeat("a", 128) + "\ntools:\n  node: 20.11.0\n  go: 1.22.1\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return configPath
+}
+
+func TestWriteSection_KeepsConcurrentChangeOfOtherSection(t *testing.T) {
+	configPath := writeConcurrencyConfig(t)
+	first := NewConfigService(configPath)
+	second := NewConfigService(configPath)
+
+	section, err := first.Binaries().ReadDevrigSection()
+	if err != nil {
+		t.Fatalf("Failed to read devrig section: %v", err)
+	}
+	tools, err := second.Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	tools["python"] = "3.12.1"
+	if err := second.Tools().UpdateTools(tools); err != nil {
+		t.Fatalf("Failed to update tools: %v", err)
+	}
+
+	section.Version = "0.80.0"
+	if err := first.Binaries().UpdateBinaries(section); err != nil {
+		t.Fatalf("Failed to update binaries: %v", err)
+	}
+
+	updated := NewConfigService(configPath)
+	if tools, err := updated.Tools().ReadTools(); err != nil || tools["python"] != "3.12.1" {
+		t.Errorf("Expected the concurrent tools change to be kept, got %v, %v", tools, err)
+	}
+	if section, err := updated.Binaries().ReadDevrigSection(); err != nil || section.Version != "0.80.0" {
+		t.Errorf("Expected the devrig version to be updated, got %+v, %v", section, err)
+	}
+}
+
+func TestWriteSection_MergesConcurrentChangeOfSameSection(t *testing.T) {
+	configPath := writeConcurrencyConfig(t)
+	first := NewConfigService(configPath)
+	second := NewConfigService(configPath)
+
+	firstTools, err := first.Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	secondTools, err := second.Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+
+	secondTools["go"] = "1.25.1"
+	delete(secondTools, "node")
+	if err := second.Tools().UpdateTools(secondTools); err != nil {
+		t.Fatalf("Failed to update tools: %v", err)
+	}
+	firstTools["python"] = "3.12.1"
+	if err := first.Tools().UpdateTools(firstTools); err != nil {
+		t.Fatalf("Failed to update tools: %v", err)
+	}
+
+	tools, err := NewConfigService(configPath).Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	if !reflect.DeepEqual(tools, ToolsSection{"go": "1.25.1", "python": "3.12.1"}) {
+		t.Errorf("Expected both changes to be merged, got %v", tools)
+	}
+}
+
+func TestWriteSection_ConflictingChange(t *testing.T) {
+	configPath := writeConcurrencyConfig(t)
+	first := NewConfigService(configPath)
+	second := NewConfigService(configPath)
+
+	firstTools, err := first.Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	secondTools, err := second.Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+
+	secondTools["node"] = "22.1.0"
+	if err := second.Tools().UpdateTools(secondTools); err != nil {
+		t.Fatalf("Failed to update tools: %v", err)
+	}
+	before, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+
+	firstTools["node"] = "24.0.0"
+	err = first.Tools().UpdateTools(firstTools)
+	var conflict *devrigErrors.ConfigConflictError
+	if !errors.As(err, &conflict) || conflict.Section != "tools" {
+		t.Fatalf("Expected a conflict of the tools section, got %v", err)
+	}
+	if devrigErrors.ExitCode(err) != devrigErrors.ExitConfigConflict {
+		t.Errorf("Expected exit code %d, got %d", devrigErrors.ExitConfigConflict, devrigErrors.ExitCode(err))
+	}
+	after, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("Expected devrig.yaml to stay unchanged:\n%s", after)
+	}
+
+	// The same change from both sides is not a conflict
+	secondTools["node"] = "24.0.0"
+	if err := NewConfigService(configPath).Tools().UpdateTools(secondTools); err != nil {
+		t.Fatalf("Failed to update tools: %v", err)
+	}
+	if err := first.Tools().UpdateTools(firstTools); err != nil {
+		t.Errorf("Expected the same change to be accepted, got %v", err)
+	}
+}
+
+func TestWriteSection_TouchedFileIsNotAConflict(t *testing.T) {
+	configPath := writeConcurrencyConfig(t)
+	service := NewConfigService(configPath)
+
+	tools, err := service.Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(configPath, later, later); err != nil {
+		t.Fatalf("Failed to touch config: %v", err)
+	}
+
+	tools["node"] = "22.1.0"
+	if err := service.Tools().UpdateTools(tools); err != nil {
+		t.Errorf("Expected the touched file to be updated, got %v", err)
+	}
+}
+
+func TestMergeChanges(t *testing.T) {
+	base := map[string]interface{}{"a": "1", "b": map[string]interface{}{"c": "2", "d": "3"}, "e": []interface{}{"x"}}
+
+	merged, ok := mergeChanges(base,
+		map[string]interface{}{"a": "10", "b": map[string]interface{}{"c": "2", "d": "3"}, "e": []interface{}{"x"}},
+		map[string]interface{}{"a": "1", "b": map[string]interface{}{"c": "2", "d": "30", "f": "4"}},
+	)
+	expected := map[string]interface{}{"a": "10", "b": map[string]interface{}{"c": "2", "d": "30", "f": "4"}}
+	if !ok || !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Expected %v, got %v, %v", expected, merged, ok)
+	}
+
+	if _, ok := mergeChanges(base,
+		map[string]interface{}{"a": "1", "b": map[string]interface{}{"c": "2", "d": "3"}, "e": []interface{}{"x", "y"}},
+		map[string]interface{}{"a": "1", "b": map[string]interface{}{"c": "2", "d": "3"}, "e": []interface{}{"z"}},
+	); ok {
+		t.Error("Expected a conflict of the lists")
+	}
+}
@@ -0,0 +1,101 @@
+package configservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// EnvOverridePrefix starts the variables overriding single values of devrig.yaml, the rest of the name is
+	// the path with __ between the keys, e.g. DEVRIG_SET_DEVRIG__CHANNEL=beta or DEVRIG_SET_TOOLS__NODE=22.1.0
+	EnvOverridePrefix = "DEVRIG_SET_"
+	// EnvOverride is a JSON object deep-merged over devrig.yaml, e.g. {"retention": {"keep_unpacked_ides": 1}}.
+	// It is applied before the DEVRIG_SET_ variables, and can also set keys that are not valid variable names
+	EnvOverride = "DEVRIG_OVERRIDE"
+)
+
+// ApplyOverrides layers the values of DEVRIG_OVERRIDE and the DEVRIG_SET_ variables over the parsed devrig.yaml,
+// so CI can change the configuration without modifying the committed file. Unknown keys are rejected to catch typos
+func ApplyOverrides(values map[string]interface{}) error {
+	if blob := strings.TrimSpace(os.Getenv(EnvOverride)); blob != "" {
+		var override map[string]interface{}
+		if err := json.Unmarshal([]byte(blob), &override); err != nil {
+			return fmt.Errorf("invalid %s, expected a JSON object: %w", EnvOverride, err)
+		}
+		if err := checkOverrideKeys("", override); err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvOverride, err)
+		}
+		mergeValues(values, override)
+		slog.Debug("devrig.yaml values overridden", "variable", EnvOverride)
+	}
+
+	// Sorted, so the result does not depend on the order of the environment
+	var names []string
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		if strings.HasPrefix(name, EnvOverridePrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, EnvOverridePrefix), "__", "."))
+		target, err := lookupSchema(path)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		value, err := convertValue(path, target, os.Getenv(name))
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+
+		segments := strings.Split(path, ".")
+		current := values
+		for _, key := range segments[:len(segments)-1] {
+			next, ok := current[key].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				current[key] = next
+			}
+			current = next
+		}
+		current[segments[len(segments)-1]] = value
+		slog.Debug("devrig.yaml value overridden", "path", path, "variable", name)
+	}
+	return nil
+}
+
+// checkOverrideKeys checks that all keys of the override are known to the schema
+func checkOverrideKeys(path string, override map[string]interface{}) error {
+	for key, value := range override {
+		childPath := joinPath(path, key)
+		target, err := lookupSchema(childPath)
+		if err != nil {
+			return err
+		}
+		if nested, ok := value.(map[string]interface{}); ok && (target.kind == kindObject || target.kind == kindMap) {
+			if err := checkOverrideKeys(childPath, nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeValues deep-merges the mappings of src into dst, other values of src replace the ones of dst
+func mergeValues(dst map[string]interface{}, src map[string]interface{}) {
+	for key, value := range src {
+		nested, isMap := value.(map[string]interface{})
+		existing, existingIsMap := dst[key].(map[string]interface{})
+		if isMap && existingIsMap {
+			mergeValues(existing, nested)
+			continue
+		}
+		dst[key] = value
+	}
+}
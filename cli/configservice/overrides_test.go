@@ -0,0 +1,120 @@
+package configservice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/config"
+)
+
+func writeOverridesTestConfig(t *testing.T) string {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte(valuesTestConfig), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	return configPath
+}
+
+func newOverridesTestService(t *testing.T) ConfigService {
+	return NewConfigService(writeOverridesTestConfig(t))
+}
+
+func TestApplyOverrides_Variables(t *testing.T) {
+	configPath := writeOverridesTestConfig(t)
+	service := NewConfigService(configPath)
+	t.Setenv("DEVRIG_SET_DEVRIG__CHANNEL", "beta")
+	t.Setenv("DEVRIG_SET_TOOLS__GO", "1.22.1")
+	t.Setenv("DEVRIG_SET_RETENTION__KEEP_UNPACKED_IDES", "1")
+	t.Setenv("DEVRIG_SET_MAINTENANCE__AUTO_GC", "true")
+
+	section, err := service.Binaries().ReadDevrigSection()
+	if err != nil {
+		t.Fatalf("Failed to read devrig section: %v", err)
+	}
+	if section.Channel != "beta" || section.Version != "0.79.6" || len(section.Binaries) != 1 {
+		t.Errorf("Expected the channel to be overridden and the rest kept, got %+v", section)
+	}
+
+	tools, err := service.Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	if tools["go"] != "1.22.1" || tools["node"] != "20.11.0" {
+		t.Errorf("Expected the go tool to be added, got %v", tools)
+	}
+
+	retention, err := service.Retention().ReadRetention()
+	if err != nil {
+		t.Fatalf("Failed to read retention: %v", err)
+	}
+	if retention.KeepUnpackedIdes != 1 {
+		t.Errorf("Expected the retention to be overridden, got %+v", retention)
+	}
+
+	maintenance, err := service.Maintenance().ReadMaintenance()
+	if err != nil || !maintenance.AutoGC {
+		t.Errorf("Expected auto_gc to be enabled, got %+v, %v", maintenance, err)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil || string(data) != valuesTestConfig {
+		t.Errorf("Expected devrig.yaml to be unchanged, got: %v", err)
+	}
+}
+
+func TestApplyOverrides_IdeSection(t *testing.T) {
+	configPath := writeOverridesTestConfig(t)
+	t.Setenv("DEVRIG_SET_IDE__NAME", "IU")
+	t.Setenv("DEVRIG_SET_IDE__VERSION", "2025.2")
+
+	ide, err := config.ReadIDEConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read the ide section: %v", err)
+	}
+	if ide == nil || ide.Name() != "IU" || ide.Version() != "2025.2" {
+		t.Errorf("Expected the ide section from the overrides, got %+v", ide)
+	}
+}
+
+func TestApplyOverrides_JSON(t *testing.T) {
+	service := newOverridesTestService(t)
+	t.Setenv(EnvOverride, `{"devrig": {"channel": "nightly", "binaries": {"darwin-arm64": {"url": "https://example.com/mac", "sha512": "`+strings.Repeat("b", 128)+`"}}}}`)
+	// The variables are applied after the JSON
+	t.Setenv("DEVRIG_SET_DEVRIG__CHANNEL", "beta")
+
+	section, err := service.Binaries().ReadDevrigSection()
+	if err != nil {
+		t.Fatalf("Failed to read devrig section: %v", err)
+	}
+	if section.Channel != "beta" {
+		t.Errorf("Expected the variable to win over the JSON, got %s", section.Channel)
+	}
+	if len(section.Binaries) != 2 || section.Binaries["linux-x86_64"].URL != "https://example.com/devrig" {
+		t.Errorf("Expected the binaries to be deep-merged, got %+v", section.Binaries)
+	}
+}
+
+func TestApplyOverrides_Invalid(t *testing.T) {
+	for name, value := range map[string]string{
+		"DEVRIG_SET_DEVRIG__CHANEL":                "beta",
+		"DEVRIG_SET_RETENTION__KEEP_UNPACKED_IDES": "two",
+		"DEVRIG_SET_DEVRIG__BINARIES":              "x",
+		EnvOverride:                                `{"devrig": {"chanel": "beta"}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			_, err := newOverridesTestService(t).Tools().ReadTools()
+			if err == nil || !strings.Contains(err.Error(), name) {
+				t.Errorf("Expected the invalid %s to be reported, got: %v", name, err)
+			}
+		})
+	}
+
+	t.Setenv(EnvOverride, "[1]")
+	if _, err := newOverridesTestService(t).Tools().ReadTools(); err == nil {
+		t.Errorf("Expected a JSON array to be rejected")
+	}
+}
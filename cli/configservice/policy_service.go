@@ -0,0 +1,49 @@
+package configservice
+
+import (
+	"fmt"
+)
+
+// policyKey is the section that restricts what the layers of devrig.yaml may change
+const policyKey = "policy"
+
+// PolicySection restricts the layers of devrig.yaml, it is only read from devrig.yaml itself, so the personal files,
+// the profiles and the environment cannot weaken it
+type PolicySection struct {
+	// LockUpdateEndpoints rejects the updates section in the layers, so devrig only updates from the endpoints
+	// committed in devrig.yaml. The security section is never read from the layers, see checkPolicy
+	LockUpdateEndpoints bool `yaml:"lock_update_endpoints,omitempty"`
+}
+
+// PolicyService manages the policy section of devrig.yaml
+type PolicyService interface {
+	// ReadPolicy reads the policy section from devrig.yaml
+	ReadPolicy() (*PolicySection, error)
+}
+
+// ReadPolicy reads the policy section from devrig.yaml
+func (s *configServiceImpl) ReadPolicy() (*PolicySection, error) {
+	var section PolicySection
+	if _, err := s.readSection(policyKey, &section); err != nil {
+		return nil, err
+	}
+	return &section, nil
+}
+
+// checkPolicy rejects the keys of the layer that the policy of devrig.yaml locks, source names the layer in the error.
+// The policy and security sections are only supported in devrig.yaml, a personal file or a variable must not widen
+// the keys devrig trusts
+func checkPolicy(values map[string]interface{}, source string, layer map[string]interface{}) error {
+	for _, key := range []string{policyKey, securityKey} {
+		if _, ok := layer[key]; ok {
+			return fmt.Errorf("invalid %s: %s is only supported in devrig.yaml", source, key)
+		}
+	}
+	policy, _ := values[policyKey].(map[string]interface{})
+	if locked, _ := policy["lock_update_endpoints"].(bool); locked {
+		if _, ok := layer["updates"]; ok {
+			return fmt.Errorf("invalid %s: updates is locked by %s.lock_update_endpoints of devrig.yaml", source, policyKey)
+		}
+	}
+	return nil
+}
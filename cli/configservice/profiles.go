@@ -0,0 +1,72 @@
+package configservice
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// EnvProfile selects the profile of devrig.yaml, the --profile flag sets it too, so child processes inherit it
+	EnvProfile = "DEVRIG_PROFILE"
+	// DefaultProfile is used when no profile is selected, it is optional in devrig.yaml
+	DefaultProfile = "default"
+	// profilesKey holds the named profiles with the ide, tools and jdk sections of a role, e.g. backend or frontend
+	profilesKey = "profiles"
+)
+
+// ActiveProfile returns the selected profile, DefaultProfile unless DEVRIG_PROFILE is set
+func ActiveProfile() string {
+	if profile := strings.TrimSpace(os.Getenv(EnvProfile)); profile != "" {
+		return profile
+	}
+	return DefaultProfile
+}
+
+// selectProfile returns the sections of the active profile from the profiles of devrig.yaml and its layers.
+// A missing default profile is empty, any other profile must be defined
+func selectProfile(values map[string]interface{}, layers map[string]interface{}) (map[string]interface{}, error) {
+	name := ActiveProfile()
+	profile := map[string]interface{}{}
+	names := map[string]bool{}
+	found := false
+	for _, source := range []map[string]interface{}{values, layers} {
+		profiles, _ := source[profilesKey].(map[string]interface{})
+		for key := range profiles {
+			names[key] = true
+		}
+		if sections, ok := profiles[name].(map[string]interface{}); ok {
+			mergeValues(profile, copyValues(sections))
+			found = true
+		} else if _, ok := profiles[name]; ok {
+			// An empty profile, e.g. `backend:` without sections, is defined too
+			found = true
+		}
+	}
+
+	if !found && name != DefaultProfile {
+		available := make([]string, 0, len(names))
+		for key := range names {
+			available = append(available, key)
+		}
+		sort.Strings(available)
+		if len(available) == 0 {
+			return nil, fmt.Errorf("profile %s is not defined, devrig.yaml has no profiles section", name)
+		}
+		return nil, fmt.Errorf("profile %s is not defined, available profiles: %s", name, strings.Join(available, ", "))
+	}
+	return profile, nil
+}
+
+// copyValues returns a deep copy of the mappings, so merging into the copy does not change the parsed files
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if nested, ok := value.(map[string]interface{}); ok {
+			value = copyValues(nested)
+		}
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,136 @@
+package configservice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/config"
+)
+
+const profilesTestConfig = valuesTestConfig + `profiles:
+  default:
+    tools:
+      python: 3.12.1
+  backend:
+    ide:
+      name: IU
+      version: "2025.1"
+    tools:
+      go: 1.22.1
+  frontend:
+    tools:
+      node: 22.1.0
+`
+
+func writeProfilesTestConfig(t *testing.T) string {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte(profilesTestConfig), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	return configPath
+}
+
+func TestProfiles_Default(t *testing.T) {
+	t.Setenv(EnvProfile, "")
+	tools, err := NewConfigService(writeProfilesTestConfig(t)).Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	if len(tools) != 2 || tools["node"] != "20.11.0" || tools["python"] != "3.12.1" {
+		t.Errorf("Expected the tools of the default profile, got %v", tools)
+	}
+}
+
+func TestProfiles_Selected(t *testing.T) {
+	configPath := writeProfilesTestConfig(t)
+	t.Setenv(EnvProfile, "backend")
+
+	tools, err := NewConfigService(configPath).Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	if len(tools) != 2 || tools["node"] != "20.11.0" || tools["go"] != "1.22.1" {
+		t.Errorf("Expected the top-level and the backend tools, got %v", tools)
+	}
+
+	ide, err := config.ReadIDEConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read the ide section: %v", err)
+	}
+	if ide == nil || ide.Name() != "IU" || ide.Version() != "2025.1" {
+		t.Errorf("Expected the ide of the backend profile, got %+v", ide)
+	}
+
+	t.Setenv(EnvProfile, "frontend")
+	tools, err = NewConfigService(configPath).Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	if tools["node"] != "22.1.0" {
+		t.Errorf("Expected the profile to win over the top-level tools, got %v", tools)
+	}
+
+	// Variables are applied after the profile
+	t.Setenv("DEVRIG_SET_TOOLS__NODE", "23.0.0")
+	tools, err = NewConfigService(configPath).Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	if tools["node"] != "23.0.0" {
+		t.Errorf("Expected the variable to win over the profile, got %v", tools)
+	}
+}
+
+func TestProfiles_Unknown(t *testing.T) {
+	t.Setenv(EnvProfile, "data")
+	_, err := NewConfigService(writeProfilesTestConfig(t)).Tools().ReadTools()
+	if err == nil || !strings.Contains(err.Error(), "available profiles: backend, default, frontend") {
+		t.Errorf("Expected the unknown profile to be reported with the available ones, got: %v", err)
+	}
+
+	// Without profiles only the default profile is accepted
+	t.Setenv(EnvProfile, "backend")
+	if _, err := newOverridesTestService(t).Tools().ReadTools(); err == nil {
+		t.Errorf("Expected an error for a profile in devrig.yaml without profiles")
+	}
+}
+
+func TestProfiles_UpdateKeepsProfileOutOfTopLevel(t *testing.T) {
+	configPath := writeProfilesTestConfig(t)
+	t.Setenv(EnvProfile, "backend")
+	service := NewConfigService(configPath)
+
+	tools, err := service.Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	tools["java"] = "21"
+	if err := service.Tools().UpdateTools(tools); err != nil {
+		t.Fatalf("Failed to update tools: %v", err)
+	}
+
+	t.Setenv(EnvProfile, "")
+	tools, err = NewConfigService(configPath).Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+	if len(tools) != 3 || tools["java"] != "21" || tools["go"] != "" {
+		t.Errorf("Expected the new tool at the top level and the backend tools to stay in the profile, got %v", tools)
+	}
+}
+
+func TestProfiles_Schema(t *testing.T) {
+	problems := ValidateBytes([]byte(profilesTestConfig + "  data:\n    tols:\n      node: 20\n"))
+	var unknown []string
+	for _, problem := range problems {
+		if strings.HasPrefix(problem.Path, profilesKey) {
+			unknown = append(unknown, problem.String())
+		}
+	}
+	if len(unknown) != 1 || !strings.Contains(unknown[0], "profiles.data.tols") {
+		t.Errorf("Expected the unknown key of the profile to be reported, got %v", unknown)
+	}
+}
@@ -0,0 +1,89 @@
+package configservice
+
+import (
+	"fmt"
+
+	"jonnyzzz.com/devrig.dev/progress"
+)
+
+// Default retention of the IDE cache, used when devrig.yaml has no retention section
+const (
+	DefaultKeepUnpackedIdes   = 2
+	DefaultKeepDownloadsDays  = 30
+	DefaultKeepDevrigBinaries = 1
+)
+
+// RetentionSection declares how long devrig keeps downloaded and unpacked IDEs and devrig binaries
+type RetentionSection struct {
+	// KeepUnpackedIdes is the number of the most recent unpacked versions kept for every IDE
+	KeepUnpackedIdes int `yaml:"keep_unpacked_ides,omitempty"`
+	// KeepDownloadsDays is the number of days downloaded IDE archives are kept
+	KeepDownloadsDays int `yaml:"keep_downloads_days,omitempty"`
+	// KeepDevrigBinaries is the number of previous devrig binaries kept in .devrig for every platform,
+	// next to the ones pinned in devrig.yaml
+	KeepDevrigBinaries int `yaml:"keep_devrig_binaries,omitempty"`
+	// MaxAgeDays removes the unpacked IDEs that were unpacked more than the number of days ago, 0 keeps them
+	MaxAgeDays int `yaml:"max_age_days,omitempty"`
+	// MaxTotalSize bounds the size of the IDE cache, e.g. 20GiB, the oldest entries are removed first
+	MaxTotalSize string `yaml:"max_total_size,omitempty"`
+}
+
+// MaxTotalBytes returns MaxTotalSize in bytes, 0 when the size of the cache is not limited
+func (s *RetentionSection) MaxTotalBytes() (int64, error) {
+	if s.MaxTotalSize == "" {
+		return 0, nil
+	}
+	size, err := progress.ParseBytes(s.MaxTotalSize)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max_total_size %q, expected a size like 20GiB or 512MiB", s.MaxTotalSize)
+	}
+	return size, nil
+}
+
+// RetentionService manages the retention section of devrig.yaml
+type RetentionService interface {
+	// ReadRetention reads the retention section from devrig.yaml
+	// Missing values are filled with the defaults
+	ReadRetention() (*RetentionSection, error)
+}
+
+// ReadRetention reads the retention section from devrig.yaml
+func (s *configServiceImpl) ReadRetention() (*RetentionSection, error) {
+	var section RetentionSection
+	if _, err := s.readSection("retention", &section); err != nil {
+		return nil, err
+	}
+
+	if err := validateRetentionSection(&section); err != nil {
+		return nil, fmt.Errorf("validation failed for %s: %w", s.configPath, err)
+	}
+
+	if section.KeepUnpackedIdes == 0 {
+		section.KeepUnpackedIdes = DefaultKeepUnpackedIdes
+	}
+	if section.KeepDownloadsDays == 0 {
+		section.KeepDownloadsDays = DefaultKeepDownloadsDays
+	}
+	if section.KeepDevrigBinaries == 0 {
+		section.KeepDevrigBinaries = DefaultKeepDevrigBinaries
+	}
+	return &section, nil
+}
+
+// validateRetentionSection rejects negative limits
+func validateRetentionSection(section *RetentionSection) error {
+	if section.KeepUnpackedIdes < 0 {
+		return fmt.Errorf("keep_unpacked_ides must not be negative, got %d", section.KeepUnpackedIdes)
+	}
+	if section.KeepDownloadsDays < 0 {
+		return fmt.Errorf("keep_downloads_days must not be negative, got %d", section.KeepDownloadsDays)
+	}
+	if section.KeepDevrigBinaries < 0 {
+		return fmt.Errorf("keep_devrig_binaries must not be negative, got %d", section.KeepDevrigBinaries)
+	}
+	if section.MaxAgeDays < 0 {
+		return fmt.Errorf("max_age_days must not be negative, got %d", section.MaxAgeDays)
+	}
+	_, err := section.MaxTotalBytes()
+	return err
+}
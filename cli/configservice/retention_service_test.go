@@ -0,0 +1,59 @@
+package configservice
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRetentionService_ReadRetention(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(testFile, []byte("tools:\n  node: \"20\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	retention, err := NewConfigService(testFile).Retention().ReadRetention()
+	if err != nil {
+		t.Fatalf("Failed to read retention: %v", err)
+	}
+	if retention.KeepUnpackedIdes != DefaultKeepUnpackedIdes || retention.KeepDownloadsDays != DefaultKeepDownloadsDays ||
+		retention.KeepDevrigBinaries != DefaultKeepDevrigBinaries {
+		t.Errorf("Expected defaults, got %+v", retention)
+	}
+
+	if err := os.WriteFile(testFile, []byte("retention:\n  keep_unpacked_ides: 1\n  keep_devrig_binaries: 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	retention, err = NewConfigService(testFile).Retention().ReadRetention()
+	if err != nil {
+		t.Fatalf("Failed to read retention: %v", err)
+	}
+	if retention.KeepUnpackedIdes != 1 || retention.KeepDownloadsDays != DefaultKeepDownloadsDays || retention.KeepDevrigBinaries != 3 {
+		t.Errorf("Expected keep_unpacked_ides=1 with the default days, got %+v", retention)
+	}
+
+	if err := os.WriteFile(testFile, []byte("retention:\n  keep_downloads_days: -1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := NewConfigService(testFile).Retention().ReadRetention(); err == nil {
+		t.Error("Expected negative values to be rejected")
+	}
+
+	if err := os.WriteFile(testFile, []byte("retention:\n  max_age_days: 90\n  max_total_size: 20GiB\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	retention, err = NewConfigService(testFile).Retention().ReadRetention()
+	if err != nil {
+		t.Fatalf("Failed to read retention: %v", err)
+	}
+	if size, err := retention.MaxTotalBytes(); err != nil || size != 20<<30 || retention.MaxAgeDays != 90 {
+		t.Errorf("Expected 90 days and 20GiB, got %+v, %d, %v", retention, size, err)
+	}
+
+	if err := os.WriteFile(testFile, []byte("retention:\n  max_total_size: 20GB\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := NewConfigService(testFile).Retention().ReadRetention(); err == nil {
+		t.Error("Expected an invalid size to be rejected")
+	}
+}
@@ -0,0 +1,549 @@
+package configservice
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"jonnyzzz.com/devrig.dev/configfile"
+	"jonnyzzz.com/devrig.dev/deprecation"
+)
+
+// Severity tells if a Problem makes devrig.yaml unusable
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Problem is a finding of Validate in devrig.yaml
+type Problem struct {
+	Severity Severity `json:"severity"`
+	// Path is the location in the document, e.g. devrig.binaries.linux-x86_64.sha512
+	Path string `json:"path,omitempty"`
+	// Line and Column are 1-based, zero when the problem has no position, e.g. a missing section
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	var sb strings.Builder
+	if p.Line > 0 {
+		sb.WriteString(fmt.Sprintf("%d:%d: ", p.Line, p.Column))
+	}
+	sb.WriteString(string(p.Severity) + ": ")
+	if p.Path != "" {
+		sb.WriteString(p.Path + ": ")
+	}
+	sb.WriteString(p.Message)
+	return sb.String()
+}
+
+// missingKeyMessage starts the problems of missing required keys
+const missingKeyMessage = "missing required key "
+
+// schemaKind is the expected type of a YAML node
+type schemaKind int
+
+const (
+	// kindObject is a mapping with known keys
+	kindObject schemaKind = iota
+	// kindMap is a mapping with arbitrary keys and values of the same schema
+	kindMap
+	kindList
+	// kindString accepts numbers too, versions like 1.22 are numbers in YAML
+	kindString
+	kindInt
+	kindBool
+)
+
+var kindNames = map[schemaKind]string{
+	kindObject: "a mapping",
+	kindMap:    "a mapping",
+	kindList:   "a list",
+	kindString: "a string",
+	kindInt:    "an integer",
+	kindBool:   "true or false",
+}
+
+// schema describes a node of devrig.yaml, a small subset of JSON Schema
+type schema struct {
+	kind     schemaKind
+	fields   map[string]*schema
+	required []string
+	// items is the schema of the values of kindMap and of the elements of kindList
+	items *schema
+	// validate runs the semantic checks of a section once its structure is valid
+	validate func(node ast.Node) error
+}
+
+func stringSchema() *schema { return &schema{kind: kindString} }
+func intSchema() *schema    { return &schema{kind: kindInt} }
+func boolSchema() *schema   { return &schema{kind: kindBool} }
+func listOf(items *schema) *schema {
+	return &schema{kind: kindList, items: items}
+}
+
+// sectionValidator decodes the section and runs the validator the services use when they read it
+func sectionValidator[T any](validate func(*T) error) func(node ast.Node) error {
+	return func(node ast.Node) error {
+		var section T
+		if err := yaml.NodeToValue(node, &section); err != nil {
+			return err
+		}
+		return validate(&section)
+	}
+}
+
+// partial returns the schema without the required keys and the semantic checks, for sections merged over others
+func partial(section *schema) *schema {
+	copied := *section
+	copied.required = nil
+	copied.validate = nil
+	return &copied
+}
+
+var ideSchema = &schema{
+	kind:     kindObject,
+	required: []string{"name", "version"},
+	fields: map[string]*schema{
+		"name":          stringSchema(),
+		"version":       stringSchema(),
+		"build":         stringSchema(),
+		"hash":          stringSchema(),
+		"package_types": {kind: kindMap, items: listOf(stringSchema())},
+		"plugins": listOf(&schema{
+			kind:     kindObject,
+			required: []string{"id"},
+			fields: map[string]*schema{
+				"id":       stringSchema(),
+				"version":  stringSchema(),
+				"checksum": stringSchema(),
+			},
+		}),
+		"launch": {
+			kind: kindObject,
+			fields: map[string]*schema{
+				"project": stringSchema(),
+				"args":    listOf(stringSchema()),
+				"env":     {kind: kindMap, items: stringSchema()},
+			},
+		},
+	},
+	validate: sectionValidator(validateIdeSection),
+}
+
+var idesSchema = &schema{
+	kind:     kindList,
+	items:    ideSchema,
+	validate: sectionValidator(validateIdesSection),
+}
+
+var toolsSchema = &schema{
+	kind:     kindMap,
+	items:    stringSchema(),
+	validate: sectionValidator(func(tools *ToolsSection) error { return validateToolsSection(*tools) }),
+}
+
+var jdkSchema = &schema{
+	kind:     kindObject,
+	required: []string{"vendor", "version", "path"},
+	fields: map[string]*schema{
+		"vendor":  stringSchema(),
+		"version": stringSchema(),
+		"path":    stringSchema(),
+	},
+	validate: sectionValidator(validateJdkSection),
+}
+
+// devrigYamlSchema lists all sections of devrig.yaml, a new section must be added here to be known to Validate
+var devrigYamlSchema = &schema{
+	kind:     kindObject,
+	required: []string{"devrig"},
+	fields: map[string]*schema{
+		"devrig": {
+			kind:     kindObject,
+			required: []string{"binaries"},
+			fields: map[string]*schema{
+				"version":      stringSchema(),
+				"release_date": stringSchema(),
+				"channel":      stringSchema(),
+				"binaries": {kind: kindMap, items: &schema{
+					kind:     kindObject,
+					required: []string{"url", "sha512"},
+					fields: map[string]*schema{
+						"url":     stringSchema(),
+						"sha512":  stringSchema(),
+						"mirrors": listOf(stringSchema()),
+					},
+				}},
+			},
+			validate: sectionValidator(validateDevrigSection),
+		},
+		"ide":   ideSchema,
+		"ides":  idesSchema,
+		"tools": toolsSchema,
+		"jdk":   jdkSchema,
+		// A profile overrides the sections for a role, its values are merged over the top-level ones
+		profilesKey: {kind: kindMap, items: &schema{
+			kind: kindObject,
+			fields: map[string]*schema{
+				"ide": partial(ideSchema),
+				// The list of a profile replaces the top-level one
+				"ides":  idesSchema,
+				"tools": toolsSchema,
+				"jdk":   partial(jdkSchema),
+			},
+		}},
+		"retention": {
+			kind: kindObject,
+			fields: map[string]*schema{
+				"keep_unpacked_ides":   intSchema(),
+				"keep_downloads_days":  intSchema(),
+				"keep_devrig_binaries": intSchema(),
+				"max_age_days":         intSchema(),
+				"max_total_size":       stringSchema(),
+			},
+			validate: sectionValidator(validateRetentionSection),
+		},
+		"cache": {
+			kind: kindObject,
+			fields: map[string]*schema{
+				"shared": boolSchema(),
+			},
+		},
+		"settings": {
+			kind: kindObject,
+			fields: map[string]*schema{
+				"cache_dir":    stringSchema(),
+				"update_check": stringSchema(),
+			},
+			validate: sectionValidator(validateSettingsSection),
+		},
+		"maintenance": {
+			kind: kindObject,
+			fields: map[string]*schema{
+				"auto_gc":        boolSchema(),
+				"auto_prefetch":  boolSchema(),
+				"interval_hours": intSchema(),
+			},
+			validate: sectionValidator(validateMaintenanceSection),
+		},
+		"updates": {
+			kind: kindObject,
+			fields: map[string]*schema{
+				"base_url":   stringSchema(),
+				"latest_url": stringSchema(),
+			},
+			validate: sectionValidator(validateUpdatesSection),
+		},
+		securityKey: {
+			kind: kindObject,
+			fields: map[string]*schema{
+				"signature_scheme":     stringSchema(),
+				"trusted_keys":         listOf(stringSchema()),
+				"disable_builtin_keys": boolSchema(),
+				"sigstore": {
+					kind: kindObject,
+					fields: map[string]*schema{
+						"trusted_root": stringSchema(),
+						"identity":     stringSchema(),
+						"issuer":       stringSchema(),
+					},
+				},
+			},
+			validate: sectionValidator(validateSecuritySection),
+		},
+		policyKey: {
+			kind: kindObject,
+			fields: map[string]*schema{
+				"lock_update_endpoints": boolSchema(),
+			},
+		},
+		"doctor": {
+			kind: kindObject,
+			fields: map[string]*schema{
+				"checks": listOf(&schema{
+					kind:     kindObject,
+					required: []string{"name", "command"},
+					fields: map[string]*schema{
+						"name":               stringSchema(),
+						"command":            listOf(stringSchema()),
+						"expected_exit_code": intSchema(),
+						"expected_output":    stringSchema(),
+						"message":            stringSchema(),
+						"warning":            boolSchema(),
+						"timeout_seconds":    intSchema(),
+					},
+				}),
+			},
+			validate: sectionValidator(validateDoctorSection),
+		},
+		"auth": {
+			kind: kindObject,
+			fields: map[string]*schema{
+				"providers": listOf(&schema{
+					kind:     kindObject,
+					required: []string{"name", "hosts", "client_id", "device_authorization_url", "token_url"},
+					fields: map[string]*schema{
+						"name":                     stringSchema(),
+						"hosts":                    listOf(stringSchema()),
+						"client_id":                stringSchema(),
+						"device_authorization_url": stringSchema(),
+						"token_url":                stringSchema(),
+						"scopes":                   listOf(stringSchema()),
+					},
+				}),
+			},
+			validate: sectionValidator(validateAuthSection),
+		},
+		includeKey: listOf(stringSchema()),
+	},
+}
+
+// Validate checks the whole devrig.yaml against the schema of all sections and reports all problems at once,
+// unknown keys are warnings. The error is only returned when the file cannot be read
+func (s *configServiceImpl) Validate() ([]Problem, error) {
+	data, err := s.readConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	return validateContent(s.configPath, data), nil
+}
+
+// validateContent checks the content of devrig.yaml, devrig.toml or devrig.json. The problems of devrig.toml
+// have no positions, they are found in the converted YAML
+func validateContent(path string, data []byte) []Problem {
+	converted, err := configfile.ToYAML(path, data)
+	if err != nil {
+		return []Problem{{Severity: SeverityError, Message: err.Error()}}
+	}
+	problems := ValidateBytes(converted)
+	if configfile.DetectFormat(path) == configfile.FormatTOML {
+		for i := range problems {
+			problems[i].Line, problems[i].Column = 0, 0
+		}
+	}
+	return problems
+}
+
+// ValidateBytes checks the content of devrig.yaml, the problems are sorted by their position
+func ValidateBytes(data []byte) []Problem {
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		// The parser stops at the first syntax error, nothing else can be checked
+		problem := Problem{Severity: SeverityError, Message: err.Error()}
+		var yamlErr yaml.Error
+		if errors.As(err, &yamlErr) {
+			problem.Message = yamlErr.GetMessage()
+			if token := yamlErr.GetToken(); token != nil && token.Position != nil {
+				problem.Line, problem.Column = token.Position.Line, token.Position.Column
+			}
+		}
+		return []Problem{problem}
+	}
+
+	v := &validator{}
+	var body ast.Node
+	if len(file.Docs) > 0 {
+		body = file.Docs[0].Body
+	}
+	if len(file.Docs) > 1 {
+		v.report(SeverityWarning, file.Docs[1].Body, "", "only the first YAML document is used")
+	}
+	if body == nil {
+		v.report(SeverityError, nil, "", "devrig.yaml is empty, run 'devrig init' to create it")
+		return v.problems
+	}
+	v.walk(body, devrigYamlSchema, "")
+
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		a, b := v.problems[i], v.problems[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return v.problems
+}
+
+type validator struct {
+	problems []Problem
+}
+
+// report adds a problem at the position of the node
+func (v *validator) report(severity Severity, node ast.Node, path string, message string) {
+	problem := Problem{Severity: severity, Path: path, Message: message}
+	if node != nil {
+		if token := node.GetToken(); token != nil && token.Position != nil {
+			problem.Line, problem.Column = token.Position.Line, token.Position.Column
+		}
+	}
+	v.problems = append(v.problems, problem)
+}
+
+// walk checks the node against the schema and returns false if a structural error was found
+func (v *validator) walk(node ast.Node, s *schema, path string) bool {
+	node = unwrap(node)
+	if _, ok := node.(*ast.AliasNode); ok {
+		// Aliases repeat a node checked at its anchor
+		return true
+	}
+
+	valid := true
+	switch s.kind {
+	case kindObject, kindMap:
+		mapping, ok := mappingValues(node)
+		if !ok {
+			v.report(SeverityError, node, path, "expected "+kindNames[s.kind]+", got "+describe(node))
+			return false
+		}
+
+		seen := map[string]bool{}
+		for _, entry := range mapping {
+			if _, ok := entry.Key.(*ast.MergeKeyNode); ok {
+				continue
+			}
+			// Duplicate keys are syntax errors of the parser
+			key := entry.Key.String()
+			childPath := joinPath(path, key)
+			seen[key] = true
+
+			child := s.items
+			if s.kind == kindObject {
+				child = s.fields[key]
+				if child == nil {
+					what := "unknown key " + key
+					if path == "" {
+						what = "unknown section " + key
+					}
+					v.report(SeverityWarning, entry.Key, childPath, what)
+					continue
+				}
+			}
+			if deprecated := deprecation.Lookup(deprecation.KindConfigKey, childPath); deprecated != nil {
+				v.report(SeverityWarning, entry.Key, childPath, deprecated.Message())
+			}
+			if _, isNull := unwrap(entry.Value).(*ast.NullNode); isNull && s.kind == kindObject && !child.isSection() {
+				// An empty optional value is the same as a missing one, required ones are reported below
+				seen[key] = false
+				continue
+			}
+			if !v.walk(entry.Value, child, childPath) {
+				valid = false
+				continue
+			}
+			if child.validate != nil {
+				if err := child.validate(entry.Value); err != nil {
+					v.report(SeverityError, entry.Key, childPath, err.Error())
+				}
+			}
+		}
+
+		for _, key := range s.required {
+			if !seen[key] {
+				what := missingKeyMessage + key
+				if path == "" {
+					what = "missing required section " + key
+				}
+				v.report(SeverityError, node, path, what)
+				valid = false
+			}
+		}
+
+	case kindList:
+		sequence, ok := node.(*ast.SequenceNode)
+		if !ok {
+			v.report(SeverityError, node, path, "expected a list, got "+describe(node))
+			return false
+		}
+		for i, item := range sequence.Values {
+			if !v.walk(item, s.items, fmt.Sprintf("%s[%d]", path, i)) {
+				valid = false
+			}
+		}
+
+	case kindString:
+		switch node.(type) {
+		case *ast.StringNode, *ast.LiteralNode, *ast.IntegerNode, *ast.FloatNode:
+		default:
+			v.report(SeverityError, node, path, "expected a string, got "+describe(node))
+			valid = false
+		}
+
+	case kindInt:
+		if _, ok := node.(*ast.IntegerNode); !ok {
+			v.report(SeverityError, node, path, "expected an integer, got "+describe(node))
+			valid = false
+		}
+
+	case kindBool:
+		if _, ok := node.(*ast.BoolNode); !ok {
+			v.report(SeverityError, node, path, "expected true or false, got "+describe(node))
+			valid = false
+		}
+	}
+	return valid
+}
+
+// isSection tells if an empty value must still be checked, a section without content is a mistake
+func (s *schema) isSection() bool {
+	return s.validate != nil || len(s.required) > 0
+}
+
+// unwrap returns the value of anchors and tags
+func unwrap(node ast.Node) ast.Node {
+	for {
+		switch n := node.(type) {
+		case *ast.AnchorNode:
+			node = n.Value
+		case *ast.TagNode:
+			node = n.Value
+		default:
+			return node
+		}
+	}
+}
+
+// mappingValues returns the entries of a mapping, a mapping with a single entry is parsed as the entry itself
+func mappingValues(node ast.Node) ([]*ast.MappingValueNode, bool) {
+	switch n := node.(type) {
+	case *ast.MappingNode:
+		return n.Values, true
+	case *ast.MappingValueNode:
+		return []*ast.MappingValueNode{n}, true
+	default:
+		return nil, false
+	}
+}
+
+// describe names the type of the node for the messages
+func describe(node ast.Node) string {
+	switch node.(type) {
+	case *ast.MappingNode, *ast.MappingValueNode:
+		return "a mapping"
+	case *ast.SequenceNode:
+		return "a list"
+	case *ast.NullNode, nil:
+		return "an empty value"
+	case *ast.BoolNode:
+		return "a boolean"
+	case *ast.IntegerNode, *ast.FloatNode:
+		return "a number"
+	default:
+		return "a string"
+	}
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,143 @@
+package configservice
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+func TestValidate_AllTestFixtures(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "*.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to list fixtures: %v", err)
+	}
+
+	for _, fixture := range fixtures {
+		problems, err := NewConfigService(fixture).Validate()
+		if err != nil {
+			t.Fatalf("Failed to validate %s: %v", fixture, err)
+		}
+		for _, problem := range problems {
+			if problem.Severity == SeverityError {
+				t.Errorf("Unexpected error in %s: %s", fixture, problem)
+			}
+		}
+	}
+}
+
+func TestValidate_ReportsAllProblems(t *testing.T) {
+	content := `custom: 1
+devrig:
+  binaries:
+    linux-x86_64:
+      url: https://example.com/devrig
+      sha512: abc
+      extra: 1
+ide:
+  name: IU
+tools:
+  node: 20
+  go: [1]
+retention:
+  keep_unpacked_ides: two
+maintenance:
+  interval_hours: -1
+auth:
+  providers:
+    - name: sso
+      hosts: [example.com]
+      client_id: devrig
+      device_authorization_url: https://example.com/device
+`
+	problems := ValidateBytes([]byte(content))
+
+	expected := []Problem{
+		{Severity: SeverityWarning, Path: "custom", Line: 1, Column: 1, Message: "unknown section custom"},
+		{Severity: SeverityError, Path: "devrig", Line: 2, Column: 1, Message: "invalid SHA512 hash length"},
+		{Severity: SeverityWarning, Path: "devrig.binaries.linux-x86_64.extra", Line: 7, Column: 7, Message: "unknown key extra"},
+		{Severity: SeverityError, Path: "ide", Line: 9, Message: "missing required key version"},
+		{Severity: SeverityError, Path: "tools.go", Line: 12, Message: "expected a string, got a list"},
+		{Severity: SeverityError, Path: "retention.keep_unpacked_ides", Line: 14, Message: "expected an integer, got a string"},
+		{Severity: SeverityError, Path: "maintenance", Line: 15, Column: 1, Message: "interval_hours must not be negative"},
+		{Severity: SeverityError, Path: "auth.providers[0]", Line: 19, Message: "missing required key token_url"},
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, got %d: %v", len(expected), len(problems), problems)
+	}
+	for i, want := range expected {
+		got := problems[i]
+		if got.Severity != want.Severity || got.Path != want.Path || got.Line != want.Line ||
+			(want.Column != 0 && got.Column != want.Column) || !strings.Contains(got.Message, want.Message) {
+			t.Errorf("Problem %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func TestValidate_SyntaxError(t *testing.T) {
+	problems := ValidateBytes([]byte("devrig:\n  version: 1\n   binaries: [\n"))
+	if len(problems) != 1 || problems[0].Severity != SeverityError || problems[0].Line == 0 {
+		t.Errorf("Expected one syntax error with a position, got: %v", problems)
+	}
+}
+
+func TestValidate_MissingDevrigSection(t *testing.T) {
+	problems := ValidateBytes([]byte("tools:\n  node: 20.11.0\n"))
+	if len(problems) != 1 || !strings.Contains(problems[0].Message, "missing required section devrig") {
+		t.Errorf("Expected the missing devrig section, got: %v", problems)
+	}
+
+	problems = ValidateBytes([]byte("# only a comment\n"))
+	if len(problems) != 1 || problems[0].Severity != SeverityError {
+		t.Errorf("Expected an empty file to be an error, got: %v", problems)
+	}
+}
+
+func TestValidate_DuplicateKey(t *testing.T) {
+	content := "devrig:\n  binaries: {}\ntools:\n  node: 20\n  node: 22\n"
+	problems := ValidateBytes([]byte(content))
+	if len(problems) != 1 || problems[0].Line != 5 || !strings.Contains(problems[0].Message, "already defined") {
+		t.Errorf("Expected the duplicate key, got: %v", problems)
+	}
+}
+
+func TestValidate_DeprecatedKey(t *testing.T) {
+	content := "ide:\n  name: GoLand\n  version: 2024.3\n  hash: sha-512:abc\n"
+	problems := ValidateBytes([]byte(content))[1:]
+	if len(problems) != 1 || problems[0].Severity != SeverityWarning || problems[0].Path != "ide.hash" ||
+		!strings.Contains(problems[0].Message, "ide.hash is deprecated, use devrig.lock instead") {
+		t.Errorf("Expected the deprecated key warning, got: %v", problems)
+	}
+}
+
+func TestValidate_MissingFile(t *testing.T) {
+	_, err := NewConfigService(filepath.Join(t.TempDir(), "devrig.yaml")).Validate()
+	var notFound *devrigErrors.ConfigNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("Expected ConfigNotFoundError, got: %v", err)
+	}
+}
+
+func TestValidate_Anchors(t *testing.T) {
+	content := `devrig:
+  binaries:
+    linux-x86_64: &binary
+      url: https://example.com/devrig
+      sha512: ` + strings.Repeat("a", 128) + `
+    linux-arm64: *binary
+`
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	problems, err := NewConfigService(configPath).Validate()
+	if err != nil {
+		t.Fatalf("Failed to validate: %v", err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("Expected no problems, got: %v", problems)
+	}
+}
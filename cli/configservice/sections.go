@@ -0,0 +1,282 @@
+package configservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/parser"
+	"jonnyzzz.com/devrig.dev/configfile"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/filelock"
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+// readSection reads the top-level section with the given key from devrig.yaml into target,
+// the layers of ApplyLayers are merged over it.
+// Returns found=false without an error if the file exists but the section is missing.
+func (s *configServiceImpl) readSection(key string, target interface{}) (bool, error) {
+	// Parse into a map to extract just the requested section
+	state, yamlData, err := s.takeSnapshot()
+	if err != nil {
+		return false, err
+	}
+	if err := s.remember(key, state, yamlData); err != nil {
+		return false, err
+	}
+	if err := ApplyLayers(s.configPath, yamlData); err != nil {
+		return false, err
+	}
+
+	sectionData, ok := yamlData[key]
+	if !ok {
+		return false, nil
+	}
+
+	// Marshal the section back to YAML and unmarshal into the target
+	sectionBytes, err := yaml.Marshal(sectionData)
+	if err != nil {
+		return false, fmt.Errorf("failed to process %s section from %s: %w", key, s.configPath, err)
+	}
+
+	if err := yaml.Unmarshal(sectionBytes, target); err != nil {
+		return false, fmt.Errorf("failed to parse %s section from %s: %w", key, s.configPath, err)
+	}
+
+	return true, nil
+}
+
+// readValues parses devrig.yaml into a map without the layers
+func (s *configServiceImpl) readValues() (map[string]interface{}, error) {
+	data, err := os.ReadFile(s.configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, &devrigErrors.ConfigNotFoundError{Path: s.configPath}
+		}
+		return nil, fmt.Errorf("failed to read configuration file %s: %w", s.configPath, err)
+	}
+
+	return configfile.Decode(s.configPath, data)
+}
+
+// withoutLayers removes the values the layers added to a section that was read with readSection
+func (s *configServiceImpl) withoutLayers(key string, value interface{}) (interface{}, error) {
+	committed, err := s.readValues()
+	if err != nil {
+		var notFound *devrigErrors.ConfigNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, err
+		}
+		committed = map[string]interface{}{}
+	}
+	layers, err := readLayers(s.configPath, committed)
+	if err != nil {
+		return nil, err
+	}
+	layer, ok := layers[key]
+	if !ok {
+		return value, nil
+	}
+
+	// Layer values are compared in the form YAML decodes them, e.g. numbers from the variables
+	section, err := toGeneric(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process %s section: %w", key, err)
+	}
+	if layer, err = toGeneric(layer); err != nil {
+		return nil, fmt.Errorf("failed to process %s section: %w", key, err)
+	}
+	committedSection, found := committed[key]
+	stripped, keep := stripLayer(section, layer, committedSection, found)
+	if !keep {
+		return map[string]interface{}{}, nil
+	}
+	return stripped, nil
+}
+
+// writeSection replaces (or appends) the top-level section with the given key in devrig.yaml.
+// If the file doesn't exist, it is created with the standard header.
+// Comments and formatting of all other sections are preserved.
+func (s *configServiceImpl) writeSection(key string, value interface{}) error {
+	// Concurrent writers would lose each other's sections between the read and the write
+	lock, err := filelock.Acquire(context.Background(), layout.ResolveLockFile(layout.ResolveDevrigHome(s.configPath), "devrig.yaml"),
+		"updating the "+key+" section of devrig.yaml")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if value, err = s.withoutLayers(key, value); err != nil {
+		return err
+	}
+	// The section may have been changed by another process since it was read
+	value, changed, err := s.reconcile(key, value)
+	if err != nil || !changed {
+		return err
+	}
+	if err := s.replaceSection(key, value); err != nil {
+		return err
+	}
+
+	// The written section is the base for the next write of it
+	state, values, err := s.takeSnapshot()
+	if err != nil {
+		return err
+	}
+	return s.remember(key, state, values)
+}
+
+// replaceSection writes the section into devrig.yaml, devrig.toml or devrig.json, the file is created if it is missing
+func (s *configServiceImpl) replaceSection(key string, value interface{}) error {
+	if format := configfile.DetectFormat(s.configPath); format != configfile.FormatYAML {
+		return s.writeFormattedSection(format, key, value)
+	}
+
+	// Check if file exists
+	if _, err := os.Stat(s.configPath); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("cannot access configuration file %s: %w", s.configPath, err)
+		}
+		return s.createNewConfig(key, value)
+	}
+
+	return s.updateExistingConfig(key, value)
+}
+
+// writeFormattedSection replaces (or appends) the section in devrig.toml or devrig.json, or creates the file
+func (s *configServiceImpl) writeFormattedSection(format configfile.Format, key string, value interface{}) error {
+	generic, err := toGeneric(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal section: %w", err)
+	}
+
+	data, err := os.ReadFile(s.configPath)
+	var updated []byte
+	switch {
+	case os.IsNotExist(err):
+		if err := os.MkdirAll(filepath.Dir(s.configPath), 0755); err != nil {
+			return fmt.Errorf("failed to create .devrig directory: %w", err)
+		}
+		updated, err = configfile.NewFile(format, key, generic)
+	case err != nil:
+		return fmt.Errorf("failed to read existing configuration: %w", err)
+	default:
+		updated, err = configfile.ReplaceSection(format, data, key, generic)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update the %s section of %s: %w", key, s.configPath, err)
+	}
+
+	if err := os.WriteFile(s.configPath, updated, 0644); err != nil {
+		return fmt.Errorf("failed to write configuration file: %w", err)
+	}
+	return nil
+}
+
+// createNewConfig creates a new devrig.yaml file with a single section
+func (s *configServiceImpl) createNewConfig(key string, value interface{}) error {
+	// Marshal the section
+	yamlBytes, err := yaml.Marshal(map[string]interface{}{
+		key: value,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal section: %w", err)
+	}
+
+	// Add header comments
+	header := "# devrig.yaml - Main configuration file for devrig tool\n"
+	header += "# This file contains URLs and hash sums for devrig binaries across all supported platforms\n\n"
+	yamlBytes = []byte(header + string(yamlBytes))
+
+	devrigDir := filepath.Dir(s.configPath)
+	if err := os.MkdirAll(devrigDir, 0755); err != nil {
+		return fmt.Errorf("failed to create .devrig directory: %w", err)
+	}
+	slog.Debug("created configuration directory", "path", devrigDir)
+
+	// Write to file
+	if err := os.WriteFile(s.configPath, yamlBytes, 0644); err != nil {
+		return fmt.Errorf("failed to write configuration file: %w", err)
+	}
+	return nil
+}
+
+// updateExistingConfig updates an existing devrig.yaml file while preserving formatting
+func (s *configServiceImpl) updateExistingConfig(key string, value interface{}) error {
+	// Read the original file
+	data, err := os.ReadFile(s.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read existing configuration: %w", err)
+	}
+
+	// Parse with comments to preserve formatting
+	file, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("failed to parse existing configuration: %w", err)
+	}
+
+	// Check whether the section is already there
+	var yamlData map[string]interface{}
+	if err := yaml.Unmarshal(data, &yamlData); err != nil {
+		return fmt.Errorf("failed to parse existing configuration: %w", err)
+	}
+
+	if _, exists := yamlData[key]; !exists {
+		// Append the new section to the end of the file
+		newYaml, err := yaml.Marshal(map[string]interface{}{key: value})
+		if err != nil {
+			return fmt.Errorf("failed to marshal new section: %w", err)
+		}
+
+		content := string(data)
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			content += "\n"
+		}
+		content += "\n" + string(newYaml)
+
+		if err := os.WriteFile(s.configPath, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write configuration file: %w", err)
+		}
+		return nil
+	}
+
+	// Update the section in the AST using path-based approach
+	path, err := yaml.PathString("$." + key)
+	if err != nil {
+		return fmt.Errorf("failed to create path: %w", err)
+	}
+
+	// Marshal the new section
+	newYaml, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal new section: %w", err)
+	}
+
+	// Parse the new section to get an AST node
+	newFile, err := parser.ParseBytes(newYaml, 0)
+	if err != nil {
+		return fmt.Errorf("failed to parse new section: %w", err)
+	}
+
+	if len(newFile.Docs) == 0 || newFile.Docs[0].Body == nil {
+		return fmt.Errorf("new section has no body")
+	}
+
+	newNode := newFile.Docs[0].Body
+
+	// Replace the node at the path
+	if err := path.ReplaceWithNode(file, newNode); err != nil {
+		return fmt.Errorf("failed to replace node: %w", err)
+	}
+
+	// Write the updated AST back to file
+	if err := os.WriteFile(s.configPath, []byte(file.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write configuration file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package configservice
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// securityKey is the section that chooses how the signatures of the release metadata are verified
+const securityKey = "security"
+
+// Signature schemes of the release metadata
+const (
+	// SignatureSchemeSSH verifies the .sig SSH signatures with the keys built into devrig, the default
+	SignatureSchemeSSH = "ssh"
+	// SignatureSchemeSigstore verifies the .sigstore.json bundles of cosign against a Sigstore trusted root
+	SignatureSchemeSigstore = "sigstore"
+)
+
+// SecuritySection chooses how devrig verifies the release metadata it updates from
+type SecuritySection struct {
+	// SignatureScheme is ssh or sigstore, ssh is used when it is empty
+	SignatureScheme string `yaml:"signature_scheme,omitempty"`
+	// TrustedKeys are the SSH public keys of the organization in the authorized_keys format, the ssh scheme
+	// accepts the signatures of them in addition to the keys built into devrig
+	TrustedKeys []string `yaml:"trusted_keys,omitempty"`
+	// DisableBuiltinKeys accepts the signatures of TrustedKeys only, e.g. for metadata signed by the organization
+	DisableBuiltinKeys bool            `yaml:"disable_builtin_keys,omitempty"`
+	Sigstore           SigstoreSection `yaml:"sigstore,omitempty"`
+}
+
+// SigstoreSection is the trust policy of the sigstore scheme: the instance and the identity that signs the releases
+type SigstoreSection struct {
+	// TrustedRoot is the trusted_root.json of the Sigstore instance with the Fulcio certificates and the Rekor keys,
+	// relative paths are resolved next to devrig.yaml
+	TrustedRoot string `yaml:"trusted_root,omitempty"`
+	// Identity is the subject of the signing certificate, e.g. the URL of the release workflow or an email
+	Identity string `yaml:"identity,omitempty"`
+	// Issuer is the OIDC issuer of the signing certificate, e.g. https://token.actions.githubusercontent.com
+	Issuer string `yaml:"issuer,omitempty"`
+}
+
+// Scheme returns the signature scheme, the default is ssh
+func (s *SecuritySection) Scheme() string {
+	if s.SignatureScheme == "" {
+		return SignatureSchemeSSH
+	}
+	return s.SignatureScheme
+}
+
+// SecurityService manages the security section of devrig.yaml
+type SecurityService interface {
+	// ReadSecurity reads the security section from devrig.yaml
+	// Returns an empty section if the SSH keys built into devrig are used
+	ReadSecurity() (*SecuritySection, error)
+}
+
+// ReadSecurity reads the security section from devrig.yaml
+func (s *configServiceImpl) ReadSecurity() (*SecuritySection, error) {
+	var section SecuritySection
+	if _, err := s.readSection(securityKey, &section); err != nil {
+		return nil, err
+	}
+
+	if err := validateSecuritySection(&section); err != nil {
+		return nil, fmt.Errorf("validation failed for %s: %w", s.configPath, err)
+	}
+	if root := section.Sigstore.TrustedRoot; root != "" && !filepath.IsAbs(root) {
+		section.Sigstore.TrustedRoot = filepath.Join(filepath.Dir(s.configPath), root)
+	}
+	return &section, nil
+}
+
+// validateSecuritySection checks the scheme, the ssh scheme needs a trusted key and the sigstore scheme needs
+// the whole trust policy
+func validateSecuritySection(section *SecuritySection) error {
+	for i, key := range section.TrustedKeys {
+		if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key)); err != nil {
+			return fmt.Errorf("trusted_keys[%d] is not an SSH public key: %w", i, err)
+		}
+	}
+
+	switch section.Scheme() {
+	case SignatureSchemeSSH:
+		if section.DisableBuiltinKeys && len(section.TrustedKeys) == 0 {
+			return fmt.Errorf("disable_builtin_keys needs trusted_keys, no signature could be verified")
+		}
+		return nil
+	case SignatureSchemeSigstore:
+		if len(section.TrustedKeys) > 0 || section.DisableBuiltinKeys {
+			return fmt.Errorf("trusted_keys and disable_builtin_keys are only used by the %s signature scheme", SignatureSchemeSSH)
+		}
+		required := []struct{ key, value string }{
+			{"trusted_root", section.Sigstore.TrustedRoot},
+			{"identity", section.Sigstore.Identity},
+			{"issuer", section.Sigstore.Issuer},
+		}
+		for _, field := range required {
+			if field.value == "" {
+				return fmt.Errorf("sigstore.%s is required for the %s signature scheme", field.key, SignatureSchemeSigstore)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("signature_scheme must be %s or %s, got %q", SignatureSchemeSSH, SignatureSchemeSigstore, section.SignatureScheme)
+	}
+}
@@ -0,0 +1,105 @@
+package configservice
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecurityService_ReadSecurity(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "devrig.yaml")
+	writeLayersTestFile(t, dir, "devrig.yaml", "tools:\n  node: \"20\"\n")
+	security, err := NewConfigService(configPath).Security().ReadSecurity()
+	if err != nil {
+		t.Fatalf("Failed to read security: %v", err)
+	}
+	if security.Scheme() != SignatureSchemeSSH {
+		t.Errorf("Expected the ssh scheme by default, got %+v", security)
+	}
+
+	writeLayersTestFile(t, dir, "devrig.yaml", "security:\n  signature_scheme: sigstore\n  sigstore:\n"+
+		"    trusted_root: keys/trusted_root.json\n    identity: release@example.com\n    issuer: https://accounts.example.com\n")
+	security, err = NewConfigService(configPath).Security().ReadSecurity()
+	if err != nil {
+		t.Fatalf("Failed to read security: %v", err)
+	}
+	if security.Scheme() != SignatureSchemeSigstore || security.Sigstore.Identity != "release@example.com" ||
+		security.Sigstore.Issuer != "https://accounts.example.com" {
+		t.Errorf("Expected the sigstore scheme, got %+v", security)
+	}
+	if expected := filepath.Join(dir, "keys", "trusted_root.json"); security.Sigstore.TrustedRoot != expected {
+		t.Errorf("Expected the trusted root next to devrig.yaml %s, got %s", expected, security.Sigstore.TrustedRoot)
+	}
+}
+
+func TestSecurityService_Validation(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "devrig.yaml")
+	tests := map[string]string{
+		"security:\n  signature_scheme: gpg\n":                                                         "signature_scheme",
+		"security:\n  signature_scheme: sigstore\n":                                                    "sigstore.trusted_root",
+		"security:\n  signature_scheme: sigstore\n  sigstore:\n    trusted_root: root.json\n":          "sigstore.identity",
+		"security:\n  signature_scheme: sigstore\n  sigstore:\n    trusted_root: r\n    identity: i\n": "sigstore.issuer",
+	}
+	for content, expected := range tests {
+		writeLayersTestFile(t, dir, "devrig.yaml", content)
+		_, err := NewConfigService(configPath).Security().ReadSecurity()
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected an error with %s for %q, got %v", expected, content, err)
+		}
+	}
+}
+
+func TestPolicy_SecurityOnlyInDevrigYaml(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "devrig.yaml")
+	key := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl release@example.com"
+	writeLayersTestFile(t, dir, "devrig.yaml", "security:\n  signature_scheme: ssh\n")
+
+	// The trusted keys cannot be widened without the policy too
+	writeLayersTestFile(t, dir, LocalConfigName, "security:\n  trusted_keys:\n    - "+key+"\n")
+	_, err := NewConfigService(configPath).Security().ReadSecurity()
+	if err == nil || !strings.Contains(err.Error(), "security is only supported in devrig.yaml") {
+		t.Errorf("Expected the security section of %s to be rejected, got %v", LocalConfigName, err)
+	}
+
+	writeLayersTestFile(t, dir, LocalConfigName, "tools:\n  go: 1.22.1\n")
+	writeLayersTestFile(t, dir, "devrig.yaml", "security:\n  signature_scheme: ssh\nprofiles:\n  default:\n    security:\n      disable_builtin_keys: true\n")
+	if _, err := NewConfigService(configPath).Security().ReadSecurity(); err == nil || !strings.Contains(err.Error(), "security is only supported") {
+		t.Errorf("Expected the security section of the profile to be rejected, got %v", err)
+	}
+
+	writeLayersTestFile(t, dir, "devrig.yaml", "security:\n  signature_scheme: ssh\n")
+	t.Setenv("DEVRIG_SET_SECURITY__DISABLE_BUILTIN_KEYS", "true")
+	if _, err := NewConfigService(configPath).Security().ReadSecurity(); err == nil || !strings.Contains(err.Error(), "security is only supported") {
+		t.Errorf("Expected DEVRIG_SET_ to be rejected, got %v", err)
+	}
+}
+
+func TestSecurityService_TrustedKeys(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "devrig.yaml")
+	key := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl release@example.com"
+	writeLayersTestFile(t, dir, "devrig.yaml", "security:\n  disable_builtin_keys: true\n  trusted_keys:\n    - "+key+"\n")
+	security, err := NewConfigService(configPath).Security().ReadSecurity()
+	if err != nil {
+		t.Fatalf("Failed to read security: %v", err)
+	}
+	if !security.DisableBuiltinKeys || len(security.TrustedKeys) != 1 || security.TrustedKeys[0] != key {
+		t.Errorf("Expected the trusted key, got %+v", security)
+	}
+
+	tests := map[string]string{
+		"security:\n  trusted_keys:\n    - not a key\n":                                 "trusted_keys[0]",
+		"security:\n  disable_builtin_keys: true\n":                                     "needs trusted_keys",
+		"security:\n  signature_scheme: sigstore\n  trusted_keys:\n    - " + key + "\n": "only used by the ssh",
+	}
+	for content, expected := range tests {
+		writeLayersTestFile(t, dir, "devrig.yaml", content)
+		_, err := NewConfigService(configPath).Security().ReadSecurity()
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected an error with %s for %q, got %v", expected, content, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package configservice
+
+import (
+	"errors"
+	"fmt"
+
+	"jonnyzzz.com/devrig.dev/config"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// SettingsSection holds the settings of devrig itself for the project
+type SettingsSection struct {
+	// CacheDir is the folder of the downloaded and unpacked IDEs instead of .idew/cache next to devrig.yaml.
+	// ~ and environment variables are expanded, relative paths are resolved next to devrig.yaml
+	CacheDir string `yaml:"cache_dir,omitempty"`
+	// UpdateCheck is how often commands check for a newer devrig: never, daily or always, daily when it is empty
+	UpdateCheck string `yaml:"update_check,omitempty"`
+}
+
+// The values of settings.update_check
+const (
+	UpdateCheckNever  = "never"
+	UpdateCheckDaily  = "daily"
+	UpdateCheckAlways = "always"
+)
+
+// SettingsService manages the settings section of devrig.yaml
+type SettingsService interface {
+	// ReadSettings reads the settings section from devrig.yaml
+	ReadSettings() (*SettingsSection, error)
+}
+
+func init() {
+	// The cache folder is resolved by the config package
+	config.ReadCacheDirSetting = readCacheDirSetting
+}
+
+// ReadSettings reads the settings section from devrig.yaml
+func (s *configServiceImpl) ReadSettings() (*SettingsSection, error) {
+	var section SettingsSection
+	if _, err := s.readSection("settings", &section); err != nil {
+		return nil, err
+	}
+	if err := validateSettingsSection(&section); err != nil {
+		return nil, fmt.Errorf("validation failed for %s: %w", s.configPath, err)
+	}
+	return &section, nil
+}
+
+// validateSettingsSection checks the update check mode
+func validateSettingsSection(section *SettingsSection) error {
+	switch section.UpdateCheck {
+	case "", UpdateCheckNever, UpdateCheckDaily, UpdateCheckAlways:
+		return nil
+	default:
+		return fmt.Errorf("update_check must be one of %s, %s or %s, got %q",
+			UpdateCheckNever, UpdateCheckDaily, UpdateCheckAlways, section.UpdateCheck)
+	}
+}
+
+// readCacheDirSetting returns settings.cache_dir, a project without devrig.yaml uses the default cache folder
+func readCacheDirSetting(configPath string) (string, error) {
+	section, err := NewConfigService(configPath).Settings().ReadSettings()
+	var notFound *devrigErrors.ConfigNotFoundError
+	if errors.As(err, &notFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return section.CacheDir, nil
+}
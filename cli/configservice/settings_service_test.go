@@ -0,0 +1,65 @@
+package configservice
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/config"
+)
+
+func TestSettingsService_CacheDir(t *testing.T) {
+	t.Setenv(config.EnvCacheDir, "")
+	projectDir := t.TempDir()
+	testFile := filepath.Join(projectDir, "devrig.yaml")
+	if err := os.WriteFile(testFile, []byte("settings:\n  cache_dir: build/ide-cache\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	section, err := NewConfigService(testFile).Settings().ReadSettings()
+	if err != nil {
+		t.Fatalf("Failed to read settings: %v", err)
+	}
+	if section.CacheDir != "build/ide-cache" {
+		t.Errorf("Unexpected cache_dir: %s", section.CacheDir)
+	}
+
+	// The relative folder is next to devrig.yaml, the variable takes precedence over it
+	expected := filepath.Join(projectDir, "build", "ide-cache")
+	if dir := config.ResolveCacheDir(testFile); dir != expected {
+		t.Errorf("Expected %s, got %s", expected, dir)
+	}
+	override := filepath.Join(t.TempDir(), "cache")
+	t.Setenv(config.EnvCacheDir, override)
+	if dir := config.ResolveCacheDir(testFile); dir != override {
+		t.Errorf("Expected %s, got %s", override, dir)
+	}
+
+	// Projects without devrig.yaml use the default folder
+	t.Setenv(config.EnvCacheDir, "")
+	missing := filepath.Join(t.TempDir(), "devrig.yaml")
+	if dir := config.ResolveCacheDir(missing); dir != filepath.Join(filepath.Dir(missing), ".idew", "cache") {
+		t.Errorf("Expected the default cache folder, got %s", dir)
+	}
+}
+
+func TestSettingsService_UpdateCheck(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(testFile, []byte("settings:\n  update_check: always\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	section, err := NewConfigService(testFile).Settings().ReadSettings()
+	if err != nil || section.UpdateCheck != UpdateCheckAlways {
+		t.Fatalf("Unexpected settings %+v: %v", section, err)
+	}
+
+	if err := os.WriteFile(testFile, []byte("settings:\n  update_check: hourly\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := NewConfigService(testFile).Settings().ReadSettings(); err == nil {
+		t.Errorf("Expected an unknown update check mode to be rejected")
+	}
+	if problems := ValidateBytes([]byte("settings:\n  update_check: hourly\n")); len(problems) == 0 || problems[0].Path != "settings" {
+		t.Errorf("Expected validate to report the unknown mode, got %+v", problems)
+	}
+}
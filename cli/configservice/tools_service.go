@@ -0,0 +1,52 @@
+package configservice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ToolsService manages the tools section of devrig.yaml
+type ToolsService interface {
+	// ReadTools reads the tools section from devrig.yaml
+	// Returns an empty section if devrig.yaml has no tools section
+	ReadTools() (ToolsSection, error)
+
+	// UpdateTools replaces the tools section in devrig.yaml while preserving comments and formatting
+	UpdateTools(tools ToolsSection) error
+}
+
+// ReadTools reads the tools section from devrig.yaml
+func (s *configServiceImpl) ReadTools() (ToolsSection, error) {
+	tools := ToolsSection{}
+	if _, err := s.readSection("tools", &tools); err != nil {
+		return nil, err
+	}
+
+	if err := validateToolsSection(tools); err != nil {
+		return nil, fmt.Errorf("validation failed for %s: %w", s.configPath, err)
+	}
+
+	return tools, nil
+}
+
+// UpdateTools replaces the tools section in devrig.yaml
+func (s *configServiceImpl) UpdateTools(tools ToolsSection) error {
+	if err := validateToolsSection(tools); err != nil {
+		return fmt.Errorf("invalid tools section: %w", err)
+	}
+
+	return s.writeSection("tools", tools)
+}
+
+// validateToolsSection checks tool names and versions are not empty
+func validateToolsSection(tools ToolsSection) error {
+	for name, version := range tools {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("tool name must not be empty")
+		}
+		if strings.TrimSpace(version) == "" {
+			return fmt.Errorf("missing version for tool: %s", name)
+		}
+	}
+	return nil
+}
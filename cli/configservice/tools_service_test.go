@@ -0,0 +1,84 @@
+package configservice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestToolsService_ReadTools_MissingSection(t *testing.T) {
+	service := NewConfigService("testdata/basic.yaml")
+
+	tools, err := service.Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+
+	if len(tools) != 0 {
+		t.Errorf("Expected no tools, got: %v", tools)
+	}
+}
+
+func TestToolsService_UpdateTools_AppendsAndPreservesComments(t *testing.T) {
+	tempDir := t.TempDir()
+	testFile := filepath.Join(tempDir, "devrig.yaml")
+
+	initialContent := `# This is my custom header
+devrig:
+  binaries:
+    darwin-arm64:
+      url: https://example.com/binary # inline comment
+      sha512: ` + strings.Repeat("a", 128) + `
+`
+	if err := os.WriteFile(testFile, []byte(initialContent), 0644); err != nil {
+		t.Fatalf("Failed to write initial config: %v", err)
+	}
+
+	service := NewConfigService(testFile)
+	if err := service.Tools().UpdateTools(ToolsSection{"node": "20.11.0"}); err != nil {
+		t.Fatalf("Failed to add tools: %v", err)
+	}
+
+	if err := service.Tools().UpdateTools(ToolsSection{"node": "20.12.0", "go": "1.22.1"}); err != nil {
+		t.Fatalf("Failed to update tools: %v", err)
+	}
+
+	tools, err := service.Tools().ReadTools()
+	if err != nil {
+		t.Fatalf("Failed to read tools: %v", err)
+	}
+
+	if tools["node"] != "20.12.0" || tools["go"] != "1.22.1" {
+		t.Errorf("Unexpected tools: %v", tools)
+	}
+
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+
+	content := string(data)
+	if !strings.Contains(content, "This is my custom header") {
+		t.Error("Custom header comment was not preserved")
+	}
+	if !strings.Contains(content, "# inline comment") {
+		t.Error("Inline comment was not preserved")
+	}
+
+	if _, err := service.Binaries().ReadDevrigSection(); err != nil {
+		t.Errorf("Devrig section is broken after tools update: %v", err)
+	}
+}
+
+func TestToolsService_UpdateTools_InvalidVersion(t *testing.T) {
+	service := NewConfigService(filepath.Join(t.TempDir(), "devrig.yaml"))
+
+	err := service.Tools().UpdateTools(ToolsSection{"node": ""})
+	if err == nil {
+		t.Fatal("Expected validation error, got nil")
+	}
+	if !strings.Contains(err.Error(), "missing version") {
+		t.Errorf("Expected 'missing version' error, got: %v", err)
+	}
+}
@@ -1,14 +1,35 @@
 package configservice
 
+// Release channels of devrig, stable is used when the channel is not set
+const (
+	ChannelStable  = "stable"
+	ChannelBeta    = "beta"
+	ChannelNightly = "nightly"
+)
+
+// KnownChannels lists the supported release channels
+var KnownChannels = []string{ChannelStable, ChannelBeta, ChannelNightly}
+
 // DevrigSection contains the devrig configuration section
 type DevrigSection struct {
-	Version     string                `yaml:"version,omitempty"`
-	ReleaseDate string                `yaml:"release_date,omitempty"`
-	Binaries    map[string]BinaryInfo `yaml:"binaries"`
+	Version     string                `yaml:"version,omitempty" json:"version,omitempty"`
+	ReleaseDate string                `yaml:"release_date,omitempty" json:"release_date,omitempty"`
+	Channel     string                `yaml:"channel,omitempty" json:"channel,omitempty"`
+	Binaries    map[string]BinaryInfo `yaml:"binaries" json:"binaries"`
 }
 
 // BinaryInfo contains information about a platform-specific binary
 type BinaryInfo struct {
-	URL    string `yaml:"url"`
-	SHA512 string `yaml:"sha512"`
+	URL    string `yaml:"url" json:"url"`
+	SHA512 string `yaml:"sha512" json:"sha512"`
+	// Mirrors lists fallback URLs of the same binary, tried in order when the download from URL fails
+	Mirrors []string `yaml:"mirrors,omitempty" json:"mirrors,omitempty"`
 }
+
+// URLs returns the primary URL followed by the mirrors
+func (b BinaryInfo) URLs() []string {
+	return append([]string{b.URL}, b.Mirrors...)
+}
+
+// ToolsSection maps a tool name (e.g. node, go, java) to its pinned version
+type ToolsSection map[string]string
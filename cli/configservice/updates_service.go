@@ -0,0 +1,51 @@
+package configservice
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// UpdatesSection points the update mechanism of devrig at another server, e.g. for a fork or an internal
+// distribution. The metadata must be signed with the keys of the running devrig binary
+type UpdatesSection struct {
+	// BaseURL hosts the channel and release metadata, e.g. https://example.com/devrig/ for latest-beta.json
+	// and v0.79.6/release.json
+	BaseURL string `yaml:"base_url,omitempty"`
+	// LatestURL is the metadata of the latest stable release, latest.json under BaseURL by default.
+	// The signature is always downloaded from the same URL with the .sig suffix
+	LatestURL string `yaml:"latest_url,omitempty"`
+}
+
+// UpdatesService manages the updates section of devrig.yaml
+type UpdatesService interface {
+	// ReadUpdates reads the updates section from devrig.yaml
+	// Returns an empty section if the defaults of devrig.dev are used
+	ReadUpdates() (*UpdatesSection, error)
+}
+
+// ReadUpdates reads the updates section from devrig.yaml
+func (s *configServiceImpl) ReadUpdates() (*UpdatesSection, error) {
+	var section UpdatesSection
+	if _, err := s.readSection("updates", &section); err != nil {
+		return nil, err
+	}
+
+	if err := validateUpdatesSection(&section); err != nil {
+		return nil, fmt.Errorf("validation failed for %s: %w", s.configPath, err)
+	}
+	return &section, nil
+}
+
+// validateUpdatesSection checks that the endpoints are absolute http or https URLs
+func validateUpdatesSection(section *UpdatesSection) error {
+	for key, value := range map[string]string{"base_url": section.BaseURL, "latest_url": section.LatestURL} {
+		if value == "" {
+			continue
+		}
+		parsed, err := url.Parse(value)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return fmt.Errorf("%s must be an absolute http or https URL, got %q", key, value)
+		}
+	}
+	return nil
+}
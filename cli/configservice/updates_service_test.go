@@ -0,0 +1,87 @@
+package configservice
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUpdatesService_ReadUpdates(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(testFile, []byte("tools:\n  node: \"20\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	updates, err := NewConfigService(testFile).Updates().ReadUpdates()
+	if err != nil {
+		t.Fatalf("Failed to read updates: %v", err)
+	}
+	if *updates != (UpdatesSection{}) {
+		t.Errorf("Expected an empty section, got %+v", updates)
+	}
+
+	if err := os.WriteFile(testFile, []byte("updates:\n  base_url: https://example.com/devrig/\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	updates, err = NewConfigService(testFile).Updates().ReadUpdates()
+	if err != nil {
+		t.Fatalf("Failed to read updates: %v", err)
+	}
+	if updates.BaseURL != "https://example.com/devrig/" || updates.LatestURL != "" {
+		t.Errorf("Expected the base URL, got %+v", updates)
+	}
+
+	if err := os.WriteFile(testFile, []byte("updates:\n  latest_url: /devrig/latest.json\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := NewConfigService(testFile).Updates().ReadUpdates(); err == nil {
+		t.Error("Expected a relative URL to be rejected")
+	}
+}
+
+func TestPolicy_LockUpdateEndpoints(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "devrig.yaml")
+	writeLayersTestFile(t, dir, "devrig.yaml", "updates:\n  base_url: https://example.com/devrig/\n")
+	writeLayersTestFile(t, dir, LocalConfigName, "updates:\n  base_url: https://fork.example.com/\n")
+
+	// Without the policy the layers may move the endpoints
+	updates, err := NewConfigService(configPath).Updates().ReadUpdates()
+	if err != nil {
+		t.Fatalf("Failed to read updates: %v", err)
+	}
+	if updates.BaseURL != "https://fork.example.com/" {
+		t.Errorf("Expected the base URL of %s, got %+v", LocalConfigName, updates)
+	}
+
+	writeLayersTestFile(t, dir, "devrig.yaml", "updates:\n  base_url: https://example.com/devrig/\npolicy:\n  lock_update_endpoints: true\n")
+	_, err = NewConfigService(configPath).Updates().ReadUpdates()
+	if err == nil || !strings.Contains(err.Error(), "lock_update_endpoints") {
+		t.Errorf("Expected %s to be rejected by the policy, got %v", LocalConfigName, err)
+	}
+
+	writeLayersTestFile(t, dir, LocalConfigName, "tools:\n  go: 1.22.1\n")
+	policy, err := NewConfigService(configPath).Policy().ReadPolicy()
+	if err != nil {
+		t.Fatalf("Failed to read policy: %v", err)
+	}
+	if !policy.LockUpdateEndpoints {
+		t.Errorf("Expected the endpoints to be locked, got %+v", policy)
+	}
+
+	t.Setenv("DEVRIG_SET_UPDATES__LATEST_URL", "https://fork.example.com/latest.json")
+	if _, err := NewConfigService(configPath).Updates().ReadUpdates(); err == nil {
+		t.Error("Expected DEVRIG_SET_ to be rejected by the policy")
+	}
+}
+
+func TestPolicy_OnlyInDevrigYaml(t *testing.T) {
+	dir := t.TempDir()
+	writeLayersTestFile(t, dir, "devrig.yaml", "policy:\n  lock_update_endpoints: true\n")
+	writeLayersTestFile(t, dir, LocalConfigName, "policy:\n  lock_update_endpoints: false\n")
+
+	_, err := NewConfigService(filepath.Join(dir, "devrig.yaml")).Policy().ReadPolicy()
+	if err == nil || !strings.Contains(err.Error(), "only supported in devrig.yaml") {
+		t.Errorf("Expected the policy of %s to be rejected, got %v", LocalConfigName, err)
+	}
+}
@@ -0,0 +1,345 @@
+package configservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"jonnyzzz.com/devrig.dev/configfile"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/filelock"
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+// ErrValueNotFound is returned by GetValue when devrig.yaml has no value at the path
+var ErrValueNotFound = errors.New("value not found")
+
+// ValuesService reads and writes single values of devrig.yaml by their path, e.g. devrig.channel,
+// tools.node or auth.providers[0].client_id. Keys are separated by dots, list elements are selected by index
+type ValuesService interface {
+	// GetValue returns the value at the path, scalars are returned as string, int or bool,
+	// sections as maps and lists. Returns ErrValueNotFound if there is no value
+	GetValue(path string) (interface{}, error)
+
+	// SetValue replaces or adds the scalar value at the path, comments and formatting of devrig.yaml are preserved.
+	// The value is converted to the type the schema expects, and the change is rejected if it makes the section invalid
+	SetValue(path string, value string) error
+}
+
+// GetValue returns the value at the path in devrig.yaml
+func (s *configServiceImpl) GetValue(path string) (interface{}, error) {
+	file, err := s.parseConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	yamlPath, err := toYamlPath(path)
+	if err != nil {
+		return nil, err
+	}
+	node, err := yamlPath.FilterFile(file)
+	if err != nil {
+		if isMissing(err) {
+			return nil, fmt.Errorf("%s in %s: %w", path, s.configPath, ErrValueNotFound)
+		}
+		return nil, fmt.Errorf("failed to read %s from %s: %w", path, s.configPath, err)
+	}
+
+	var value interface{}
+	if err := yaml.NodeToValue(node, &value); err != nil {
+		return nil, fmt.Errorf("failed to read %s from %s: %w", path, s.configPath, err)
+	}
+	return value, nil
+}
+
+// SetValue replaces or adds the scalar value at the path in devrig.yaml
+func (s *configServiceImpl) SetValue(path string, value string) error {
+	target, err := lookupSchema(path)
+	if err != nil {
+		return err
+	}
+	typed, err := convertValue(path, target, value)
+	if err != nil {
+		return err
+	}
+
+	lock, err := filelock.Acquire(context.Background(), layout.ResolveLockFile(layout.ResolveDevrigHome(s.configPath), "devrig.yaml"),
+		"setting "+path+" in devrig.yaml")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	data, err := s.readConfigFile()
+	if err != nil {
+		return err
+	}
+	var updated []byte
+	if format := configfile.DetectFormat(s.configPath); format != configfile.FormatYAML {
+		if updated, err = configfile.SetValue(format, data, path, typed); err != nil {
+			return fmt.Errorf("failed to set %s in %s: %w", path, s.configPath, err)
+		}
+	} else {
+		file, err := parser.ParseBytes(data, parser.ParseComments)
+		if err != nil {
+			return fmt.Errorf("failed to parse YAML in %s: %w", s.configPath, err)
+		}
+		if err := setNode(file, path, typed); err != nil {
+			return fmt.Errorf("failed to set %s in %s: %w", path, s.configPath, err)
+		}
+		updated = []byte(file.String())
+	}
+
+	// Problems that were there before are not a reason to reject the change, neither are missing keys,
+	// a new section is created with one call per key
+	before := map[Problem]bool{}
+	for _, problem := range validateContent(s.configPath, data) {
+		before[Problem{Severity: problem.Severity, Path: problem.Path, Message: problem.Message}] = true
+	}
+	for _, problem := range validateContent(s.configPath, updated) {
+		key := Problem{Severity: problem.Severity, Path: problem.Path, Message: problem.Message}
+		if problem.Severity == SeverityError && !before[key] && !strings.HasPrefix(problem.Message, missingKeyMessage) {
+			return fmt.Errorf("cannot set %s to %q: %s", path, value, problem)
+		}
+	}
+
+	if err := os.WriteFile(s.configPath, updated, 0644); err != nil {
+		return fmt.Errorf("failed to write configuration file: %w", err)
+	}
+	return nil
+}
+
+// readConfigFile reads the content of devrig.yaml
+func (s *configServiceImpl) readConfigFile() ([]byte, error) {
+	data, err := os.ReadFile(s.configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, &devrigErrors.ConfigNotFoundError{Path: s.configPath}
+		}
+		return nil, fmt.Errorf("failed to read configuration file %s: %w", s.configPath, err)
+	}
+	return data, nil
+}
+
+// parseConfigFile parses devrig.yaml with comments, so it can be queried by path.
+// devrig.toml is converted to YAML first, JSON is valid YAML
+func (s *configServiceImpl) parseConfigFile() (*ast.File, error) {
+	data, err := s.readConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	if data, err = configfile.ToYAML(s.configPath, data); err != nil {
+		return nil, err
+	}
+	file, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse YAML in %s: %w", s.configPath, err)
+	}
+	return file, nil
+}
+
+// setNode replaces the node at the path, or merges the missing keys into the closest existing mapping
+func setNode(file *ast.File, path string, value interface{}) error {
+	segments := strings.Split(path, ".")
+	for depth := len(segments); depth >= 0; depth-- {
+		parentPath, err := toYamlPath(strings.Join(segments[:depth], "."))
+		if err != nil {
+			return err
+		}
+		existing, err := parentPath.FilterFile(file)
+		if isMissing(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if depth == len(segments) {
+			replacement, err := valueNode(value)
+			if err != nil {
+				return err
+			}
+			// The comment at the end of the line stays with the new value
+			if comment := existing.GetComment(); comment != nil {
+				_ = replacement.SetComment(comment)
+			}
+			return parentPath.ReplaceWithNode(file, replacement)
+		}
+
+		// Build the missing keys as nested mappings, e.g. channel: beta for devrig.channel
+		var missing interface{} = value
+		for i := len(segments) - 1; i >= depth; i-- {
+			if strings.Contains(segments[i], "[") {
+				return fmt.Errorf("list element %s does not exist", strings.Join(segments[:i+1], "."))
+			}
+			missing = yaml.MapSlice{{Key: segments[i], Value: missing}}
+		}
+		merged, err := valueNode(missing)
+		if err != nil {
+			return err
+		}
+		if depth > 0 {
+			// An empty section, e.g. `ide:` without values, becomes the mapping
+			if isNull(existing) {
+				return replaceNull(file, segments[:depth], missing)
+			}
+			if _, ok := mappingValues(unwrap(existing)); !ok {
+				return fmt.Errorf("%s is not a mapping", strings.Join(segments[:depth], "."))
+			}
+		}
+		return parentPath.MergeFromNode(file, merged)
+	}
+	return fmt.Errorf("devrig.yaml is empty")
+}
+
+// replaceNull replaces the empty value at the path with the value. The value is taken from a document
+// with all keys of the path and is shifted to the column of the key, so it is indented for its place in the file
+func replaceNull(file *ast.File, segments []string, value interface{}) error {
+	key := segments[len(segments)-1]
+	if strings.Contains(key, "[") {
+		return fmt.Errorf("list element %s is empty", strings.Join(segments, "."))
+	}
+	for i := len(segments) - 1; i >= 0; i-- {
+		value = yaml.MapSlice{{Key: segments[i], Value: value}}
+	}
+	document, err := valueNode(value)
+	if err != nil {
+		return err
+	}
+
+	parentPath, err := toYamlPath(strings.Join(segments[:len(segments)-1], "."))
+	if err != nil {
+		return err
+	}
+	fileParent, err := parentPath.FilterFile(file)
+	if err != nil {
+		return err
+	}
+	documentParent, err := parentPath.FilterNode(document)
+	if err != nil {
+		return err
+	}
+	fileEntry, documentEntry := findEntry(fileParent, key), findEntry(documentParent, key)
+	if fileEntry == nil || documentEntry == nil {
+		return fmt.Errorf("%s is not found", strings.Join(segments, "."))
+	}
+
+	documentEntry.Value.AddColumn(fileEntry.Key.GetToken().Position.Column - documentEntry.Key.GetToken().Position.Column)
+	fileEntry.Value = documentEntry.Value
+	return nil
+}
+
+// findEntry returns the entry of the mapping with the key
+func findEntry(node ast.Node, key string) *ast.MappingValueNode {
+	entries, _ := mappingValues(unwrap(node))
+	for _, entry := range entries {
+		if entry.Key.String() == key {
+			return entry
+		}
+	}
+	return nil
+}
+
+// valueNode marshals the value into a YAML node
+func valueNode(value interface{}) (ast.Node, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value: %w", err)
+	}
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse value: %w", err)
+	}
+	if len(file.Docs) == 0 || file.Docs[0].Body == nil {
+		return nil, fmt.Errorf("value has no body")
+	}
+	return file.Docs[0].Body, nil
+}
+
+// isMissing tells if the path query failed because a key, a list element or a parent mapping is missing
+func isMissing(err error) bool {
+	return yaml.IsNotFoundNodeError(err) || yaml.IsInvalidQueryError(err)
+}
+
+func isNull(node ast.Node) bool {
+	_, ok := unwrap(node).(*ast.NullNode)
+	return ok
+}
+
+// toYamlPath converts a path like devrig.binaries.linux-x86_64.url into the YAMLPath of goccy/go-yaml
+func toYamlPath(path string) (*yaml.Path, error) {
+	if path == "" {
+		return yaml.PathString("$")
+	}
+	yamlPath, err := yaml.PathString("$." + path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %s: %w", path, err)
+	}
+	return yamlPath, nil
+}
+
+// lookupSchema returns the schema of the value at the path, unknown keys are rejected to catch typos
+func lookupSchema(path string) (*schema, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path must not be empty, e.g. devrig.channel")
+	}
+
+	current := devrigYamlSchema
+	for _, segment := range strings.Split(path, ".") {
+		key, indexes, _ := strings.Cut(segment, "[")
+		if key == "" {
+			return nil, fmt.Errorf("invalid path %s", path)
+		}
+		switch current.kind {
+		case kindObject:
+			child, ok := current.fields[key]
+			if !ok {
+				return nil, fmt.Errorf("unknown key %s in %s", key, path)
+			}
+			current = child
+		case kindMap:
+			current = current.items
+		default:
+			return nil, fmt.Errorf("%s has no key %s", path, key)
+		}
+
+		for indexes != "" {
+			var index string
+			index, indexes, _ = strings.Cut(indexes, "]")
+			indexes = strings.TrimPrefix(indexes, "[")
+			if _, err := strconv.Atoi(index); err != nil || current.kind != kindList {
+				return nil, fmt.Errorf("invalid list index in %s", path)
+			}
+			current = current.items
+		}
+	}
+	return current, nil
+}
+
+// convertValue converts the command line value to the type of the schema
+func convertValue(path string, target *schema, value string) (interface{}, error) {
+	switch target.kind {
+	case kindString:
+		return value, nil
+	case kindInt:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects an integer, got %q", path, value)
+		}
+		return number, nil
+	case kindBool:
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects true or false, got %q", path, value)
+		}
+		return flag, nil
+	default:
+		return nil, fmt.Errorf("%s is %s, set its values one by one", path, kindNames[target.kind])
+	}
+}
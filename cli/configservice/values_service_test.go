@@ -0,0 +1,144 @@
+package configservice
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const valuesTestConfig = `# devrig.yaml - project comment
+devrig:
+  version: 0.79.6 # pinned by devrig upgrade
+  binaries:
+    linux-x86_64:
+      url: https://example.com/devrig
+      sha512: ` + "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa" + `
+
+# Pinned tools
+tools:
+  node: 20.11.0
+ide:
+auth:
+  providers:
+    - name: corp
+      hosts: [artifacts.example.com]
+      client_id: devrig
+      device_authorization_url: https://sso.example.com/device
+      token_url: https://sso.example.com/token
+`
+
+func newValuesTestService(t *testing.T) (ValuesService, string) {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte(valuesTestConfig), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	return NewConfigService(configPath).Values(), configPath
+}
+
+func TestGetValue(t *testing.T) {
+	service, _ := newValuesTestService(t)
+
+	for path, expected := range map[string]interface{}{
+		"devrig.version":                   "0.79.6",
+		"devrig.binaries.linux-x86_64.url": "https://example.com/devrig",
+		"tools.node":                       "20.11.0",
+		"auth.providers[0].client_id":      "devrig",
+	} {
+		value, err := service.GetValue(path)
+		if err != nil {
+			t.Fatalf("Failed to get %s: %v", path, err)
+		}
+		if value != expected {
+			t.Errorf("Expected %v for %s, got %v", expected, path, value)
+		}
+	}
+
+	tools, err := service.GetValue("tools")
+	if err != nil {
+		t.Fatalf("Failed to get tools: %v", err)
+	}
+	if section, ok := tools.(map[string]interface{}); !ok || section["node"] != "20.11.0" {
+		t.Errorf("Expected the tools section as a map, got %v", tools)
+	}
+
+	for _, path := range []string{"devrig.channel", "ide.version", "auth.providers[3].name", "tools.node.major"} {
+		if _, err := service.GetValue(path); !errors.Is(err, ErrValueNotFound) {
+			t.Errorf("Expected ErrValueNotFound for %s, got: %v", path, err)
+		}
+	}
+}
+
+func TestSetValue_PreservesComments(t *testing.T) {
+	service, configPath := newValuesTestService(t)
+
+	for _, change := range [][2]string{
+		{"devrig.version", "0.80.0"},
+		{"devrig.channel", "beta"},
+		{"tools.go", "1.22.1"},
+		{"ide.name", "IU"},
+		{"ide.version", "2025.2"},
+		{"maintenance.auto_gc", "true"},
+		{"maintenance.interval_hours", "12"},
+		{"auth.providers[0].client_id", "other"},
+	} {
+		if err := service.SetValue(change[0], change[1]); err != nil {
+			t.Fatalf("Failed to set %s: %v", change[0], err)
+		}
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read devrig.yaml: %v", err)
+	}
+	content := string(data)
+	for _, expected := range []string{"# devrig.yaml - project comment", "version: 0.80.0 # pinned by devrig upgrade", "# Pinned tools", "  channel: beta"} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected %q in devrig.yaml:\n%s", expected, content)
+		}
+	}
+	if problems := ValidateBytes(data); len(problems) != 0 {
+		t.Errorf("Expected devrig.yaml to stay valid, got %v:\n%s", problems, content)
+	}
+
+	for path, expected := range map[string]interface{}{
+		"ide.version":                 "2025.2",
+		"maintenance.auto_gc":         true,
+		"maintenance.interval_hours":  uint64(12),
+		"auth.providers[0].client_id": "other",
+		"tools.go":                    "1.22.1",
+	} {
+		if value, err := service.GetValue(path); err != nil || value != expected {
+			t.Errorf("Expected %v (%T) for %s, got %v (%T), %v", expected, expected, path, value, value, err)
+		}
+	}
+}
+
+func TestSetValue_Rejects(t *testing.T) {
+	service, configPath := newValuesTestService(t)
+
+	for _, change := range [][2]string{
+		{"devrig.chanel", "beta"},
+		{"devrig.channel", "unknown"},
+		{"devrig.binaries", "x"},
+		{"retention.keep_unpacked_ides", "two"},
+		{"maintenance.auto_gc", "maybe"},
+		{"maintenance.interval_hours", "-1"},
+		{"auth.providers[1].name", "corp"},
+		{"custom.key", "value"},
+	} {
+		if err := service.SetValue(change[0], change[1]); err == nil {
+			t.Errorf("Expected setting %s to %q to fail", change[0], change[1])
+		}
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read devrig.yaml: %v", err)
+	}
+	if string(data) != valuesTestConfig {
+		t.Errorf("Expected devrig.yaml to be unchanged, got:\n%s", data)
+	}
+}
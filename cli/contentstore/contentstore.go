@@ -0,0 +1,400 @@
+// Package contentstore keeps downloaded packages and unpacked IDEs and tools in a folder of the user, named by
+// the checksum of the package. Projects hard link the entries into their caches, so checkouts with the same IDE
+// share a single download and unpack. Entries are never changed once they are in place, removing the store is
+// safe: the projects keep their links, and the store is filled again by the next download
+package contentstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/extract"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// Store is the content-addressed folder: files/<xx>/<checksum> are packages, trees/<xx>/<checksum> are the
+// folders unpacked from them, xx are the first two characters of the hex SHA-256 or SHA-512 checksum
+type Store struct {
+	root string
+}
+
+// New returns the store in the folder, it is created with the first entry
+func New(root string) *Store {
+	return &Store{root: root}
+}
+
+// ForProject returns the store of the user when the cache section of devrig.yaml enables it, or nil
+func ForProject(configs configservice.ConfigService) (*Store, error) {
+	section, err := configs.Cache().ReadCache()
+	var notFound *devrigErrors.ConfigNotFoundError
+	if errors.As(err, &notFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !section.Shared {
+		return nil, nil
+	}
+	root, err := layout.ResolveContentStoreDir()
+	if err != nil {
+		return nil, err
+	}
+	return New(root), nil
+}
+
+// Root returns the folder of the store
+func (s *Store) Root() string {
+	return s.root
+}
+
+// FilePath returns the location of the package with the checksum
+func (s *Store) FilePath(checksum string) string {
+	return filepath.Join(s.root, "files", checksum[:2], checksum)
+}
+
+// TreePath returns the location of the folder unpacked from the package with the checksum
+func (s *Store) TreePath(checksum string) string {
+	return filepath.Join(s.root, "trees", checksum[:2], checksum)
+}
+
+// LinkFile places the package with the checksum at target. Returns false when the store has no such package
+func (s *Store) LinkFile(checksum string, target string) (bool, error) {
+	if err := checkChecksum(checksum); err != nil {
+		return false, err
+	}
+	source := s.FilePath(checksum)
+	if _, err := os.Stat(source); os.IsNotExist(err) {
+		return false, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return false, fmt.Errorf("failed to create parent directories for %s: %w", target, err)
+	}
+	if err := replaceWithLink(source, target); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PutFile adds the verified package to the store, it replaces an entry with the same checksum
+func (s *Store) PutFile(checksum string, source string) error {
+	if err := checkChecksum(checksum); err != nil {
+		return err
+	}
+	target := s.FilePath(checksum)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create content store %s: %w", filepath.Dir(target), err)
+	}
+	return replaceWithLink(source, target)
+}
+
+// LinkTree recreates the folder unpacked from the package with the checksum at target, which must not exist.
+// Returns false when the store has no such folder
+func (s *Store) LinkTree(checksum string, target string) (bool, error) {
+	if err := checkChecksum(checksum); err != nil {
+		return false, err
+	}
+	source := s.TreePath(checksum)
+	if _, err := os.Stat(source); os.IsNotExist(err) {
+		return false, nil
+	}
+	if err := linkTree(source, target); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PutTree adds the folder unpacked from the package with the checksum to the store, an existing entry is kept
+func (s *Store) PutTree(checksum string, source string) error {
+	if err := checkChecksum(checksum); err != nil {
+		return err
+	}
+	target := s.TreePath(checksum)
+	if _, err := os.Stat(target); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create content store %s: %w", filepath.Dir(target), err)
+	}
+
+	// The folder is complete once it is renamed into place, concurrent projects see it whole or not at all
+	tempDir, err := tempfile.Mkdir(filepath.Dir(target), ".put-*")
+	if err != nil {
+		return err
+	}
+	defer tempfile.Remove(tempDir)
+
+	tree := filepath.Join(tempDir, "tree")
+	if err := linkTree(source, tree); err != nil {
+		return err
+	}
+	if err := os.Rename(tree, target); err != nil {
+		if _, statErr := os.Stat(target); statErr == nil {
+			return nil
+		}
+		return fmt.Errorf("failed to move %s into the content store: %w", filepath.Base(target), err)
+	}
+	return nil
+}
+
+// DedupeFile replaces the package at path with a link to the same package of the store, or adds it to the store.
+// Returns the bytes freed, nothing is changed in the dry run
+func (s *Store) DedupeFile(checksum string, path string, dryRun bool) (int64, error) {
+	if err := checkChecksum(checksum); err != nil {
+		return 0, err
+	}
+	stored := s.FilePath(checksum)
+	if _, err := os.Stat(stored); os.IsNotExist(err) {
+		if dryRun {
+			return 0, nil
+		}
+		return 0, s.PutFile(checksum, path)
+	}
+	return dedupe(stored, path, dryRun)
+}
+
+// DedupeTree replaces the files of the folder unpacked from the package with links to the same files of the store,
+// or adds the folder to the store. Files that differ from the store, e.g. installed plugins, are kept.
+// Returns the bytes freed, nothing is changed in the dry run
+func (s *Store) DedupeTree(checksum string, dir string, dryRun bool) (int64, error) {
+	if err := checkChecksum(checksum); err != nil {
+		return 0, err
+	}
+	stored := s.TreePath(checksum)
+	if _, err := os.Stat(stored); os.IsNotExist(err) {
+		if dryRun {
+			return 0, nil
+		}
+		return 0, s.PutTree(checksum, dir)
+	}
+
+	var freed int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := os.Lstat(filepath.Join(stored, name))
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		saved, err := dedupe(filepath.Join(stored, name), path, dryRun)
+		freed += saved
+		return err
+	})
+	if err != nil {
+		return freed, fmt.Errorf("failed to deduplicate %s: %w", dir, err)
+	}
+	return freed, nil
+}
+
+// dedupe replaces path with a link to stored when both have the same content, returns the size of path then
+func dedupe(stored string, path string, dryRun bool) (int64, error) {
+	storedInfo, err := os.Stat(stored)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", stored, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if os.SameFile(storedInfo, info) || storedInfo.Size() != info.Size() || storedInfo.Mode() != info.Mode() {
+		return 0, nil
+	}
+	equal, err := sameContent(stored, path)
+	if err != nil || !equal {
+		return 0, err
+	}
+	if !dryRun {
+		if err := os.Link(stored, path+".dedupe"); err != nil {
+			return 0, fmt.Errorf("failed to link %s: %w", path, err)
+		}
+		if err := os.Rename(path+".dedupe", path); err != nil {
+			_ = os.Remove(path + ".dedupe")
+			return 0, fmt.Errorf("failed to replace %s: %w", path, err)
+		}
+	}
+	return info.Size(), nil
+}
+
+// sameContent compares the files byte by byte
+func sameContent(first string, second string) (bool, error) {
+	a, err := os.Open(first)
+	if err != nil {
+		return false, err
+	}
+	defer a.Close()
+	b, err := os.Open(second)
+	if err != nil {
+		return false, err
+	}
+	defer b.Close()
+
+	bufA, bufB := make([]byte, 64<<10), make([]byte, 64<<10)
+	for {
+		n, errA := io.ReadFull(a, bufA)
+		m, errB := io.ReadFull(b, bufB)
+		if n != m || string(bufA[:n]) != string(bufB[:m]) {
+			return false, nil
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return errB == io.EOF || errB == io.ErrUnexpectedEOF, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+	}
+}
+
+// replaceWithLink places a hard link of source at target, or a copy when the folders are on different filesystems
+func replaceWithLink(source string, target string) error {
+	temp := target + ".link"
+	_ = os.Remove(temp)
+	if err := os.Link(source, temp); err != nil {
+		if err := copyFile(source, temp); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(temp, target); err != nil {
+		_ = os.Remove(temp)
+		return fmt.Errorf("failed to move %s into place: %w", target, err)
+	}
+	return nil
+}
+
+func copyFile(source string, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", source, err)
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", target, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to copy %s: %w", source, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", target, err)
+	}
+	return nil
+}
+
+// linkTree recreates the folder source at target with hard links to its files, the folders and symlinks are
+// created anew. When the first link fails, e.g. across filesystems, the folder is copied instead
+func linkTree(source string, target string) error {
+	var dirs []string
+	modes := map[string]fs.FileMode{}
+	err := filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(target, name)
+
+		switch {
+		case entry.IsDir():
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(dest, 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", name, err)
+			}
+			dirs = append(dirs, dest)
+			modes[dest] = info.Mode().Perm()
+			return nil
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("failed to read symlink %s: %w", path, err)
+			}
+			return os.Symlink(link, dest)
+		default:
+			return os.Link(path, dest)
+		}
+	})
+	var linkErr *os.LinkError
+	if errors.As(err, &linkErr) && linkErr.Op == "link" {
+		// The store is on another filesystem, the copy has the same checks as a DMG application
+		if err := os.RemoveAll(target); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", target, err)
+		}
+		return extract.CopyTree(source, target)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to link %s: %w", source, err)
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i], modes[dirs[i]]); err != nil {
+			return fmt.Errorf("failed to set permissions of %s: %w", dirs[i], err)
+		}
+	}
+	return nil
+}
+
+// FileSHA256 returns the hex SHA-256 of the file, the checksum the store keeps downloads under
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// checkChecksum accepts the hex SHA-256 and SHA-512 checksums, so a checksum never escapes the store folder
+func checkChecksum(checksum string) error {
+	if _, err := hex.DecodeString(checksum); err != nil || (len(checksum) != 64 && len(checksum) != 128) {
+		return fmt.Errorf("invalid checksum for the content store: %q", checksum)
+	}
+	return nil
+}
+
+type storeKey struct{}
+
+// WithStore returns a copy of ctx carrying the store, a nil store disables it
+func WithStore(ctx context.Context, store *Store) context.Context {
+	return context.WithValue(ctx, storeKey{}, store)
+}
+
+// FromContext returns the store of the context, or nil when the project does not share its cache
+func FromContext(ctx context.Context) *Store {
+	if ctx != nil {
+		if store, ok := ctx.Value(storeKey{}).(*Store); ok {
+			return store
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package contentstore
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const testChecksum = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func writeTestFile(t *testing.T, path string, content string, mode os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+}
+
+func isSameFile(t *testing.T, first string, second string) bool {
+	t.Helper()
+	a, err := os.Stat(first)
+	if err != nil {
+		t.Fatalf("Failed to stat %s: %v", first, err)
+	}
+	b, err := os.Stat(second)
+	if err != nil {
+		t.Fatalf("Failed to stat %s: %v", second, err)
+	}
+	return os.SameFile(a, b)
+}
+
+func TestStore_Files(t *testing.T) {
+	store := New(filepath.Join(t.TempDir(), "store"))
+	target := filepath.Join(t.TempDir(), "download", "GoLand-243.1.targz")
+
+	if linked, err := store.LinkFile(testChecksum, target); err != nil || linked {
+		t.Fatalf("Expected an empty store, got %v, %v", linked, err)
+	}
+
+	source := filepath.Join(t.TempDir(), "package")
+	writeTestFile(t, source, "package", 0644)
+	if err := store.PutFile(testChecksum, source); err != nil {
+		t.Fatalf("Failed to add the package: %v", err)
+	}
+	if linked, err := store.LinkFile(testChecksum, target); err != nil || !linked {
+		t.Fatalf("Failed to link the package: %v, %v", linked, err)
+	}
+	if !isSameFile(t, source, target) {
+		t.Error("Expected the package to be hard linked")
+	}
+
+	if _, err := store.LinkFile("../../etc/passwd", target); err == nil {
+		t.Error("Expected an invalid checksum to be rejected")
+	}
+}
+
+func TestStore_Trees(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "GoLand-243.1")
+	writeTestFile(t, filepath.Join(source, "bin", "goland.sh"), "#!/bin/sh\n", 0755)
+	writeTestFile(t, filepath.Join(source, "lib", "app.jar"), "jar", 0644)
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink("bin/goland.sh", filepath.Join(source, "goland")); err != nil {
+			t.Fatalf("Failed to create symlink: %v", err)
+		}
+	}
+
+	store := New(filepath.Join(t.TempDir(), "store"))
+	if err := store.PutTree(testChecksum, source); err != nil {
+		t.Fatalf("Failed to add the tree: %v", err)
+	}
+	// The entry is complete, a second project does not replace it
+	if err := store.PutTree(testChecksum, t.TempDir()); err != nil {
+		t.Fatalf("Failed to add the tree again: %v", err)
+	}
+
+	target := filepath.Join(t.TempDir(), "home")
+	if linked, err := store.LinkTree(testChecksum, target); err != nil || !linked {
+		t.Fatalf("Failed to link the tree: %v, %v", linked, err)
+	}
+	if !isSameFile(t, filepath.Join(source, "lib", "app.jar"), filepath.Join(target, "lib", "app.jar")) {
+		t.Error("Expected the files to be hard linked")
+	}
+	if runtime.GOOS != "windows" {
+		if info, err := os.Stat(filepath.Join(target, "bin", "goland.sh")); err != nil || info.Mode().Perm()&0100 == 0 {
+			t.Errorf("Expected an executable launcher, got %v, %v", info, err)
+		}
+		if link, err := os.Readlink(filepath.Join(target, "goland")); err != nil || link != "bin/goland.sh" {
+			t.Errorf("Expected the link to be kept, got %s, %v", link, err)
+		}
+	}
+}
+
+func TestStore_Dedupe(t *testing.T) {
+	store := New(filepath.Join(t.TempDir(), "store"))
+	stored := filepath.Join(t.TempDir(), "GoLand-243.1")
+	writeTestFile(t, filepath.Join(stored, "lib", "app.jar"), strings.Repeat("jar", 100), 0644)
+	writeTestFile(t, filepath.Join(stored, "lib", "other.jar"), "other", 0644)
+	if err := store.PutTree(testChecksum, stored); err != nil {
+		t.Fatalf("Failed to add the tree: %v", err)
+	}
+
+	// The copy of another project has the same files, and a plugin the store does not have
+	home := filepath.Join(t.TempDir(), "GoLand-243.1")
+	writeTestFile(t, filepath.Join(home, "lib", "app.jar"), strings.Repeat("jar", 100), 0644)
+	writeTestFile(t, filepath.Join(home, "lib", "other.jar"), "OTHER", 0644)
+	writeTestFile(t, filepath.Join(home, "plugins", "plugin.jar"), "plugin", 0644)
+
+	freed, err := store.DedupeTree(testChecksum, home, true)
+	if err != nil || freed != 300 {
+		t.Fatalf("Expected 300 bytes to free in the dry run, got %d, %v", freed, err)
+	}
+	if isSameFile(t, filepath.Join(stored, "lib", "app.jar"), filepath.Join(home, "lib", "app.jar")) {
+		t.Fatal("Expected the dry run to keep the files")
+	}
+
+	freed, err = store.DedupeTree(testChecksum, home, false)
+	if err != nil || freed != 300 {
+		t.Fatalf("Expected 300 bytes to be freed, got %d, %v", freed, err)
+	}
+	if !isSameFile(t, filepath.Join(stored, "lib", "app.jar"), filepath.Join(home, "lib", "app.jar")) {
+		t.Error("Expected the same file to be linked")
+	}
+	if isSameFile(t, filepath.Join(stored, "lib", "other.jar"), filepath.Join(home, "lib", "other.jar")) {
+		t.Error("Expected the different file to be kept")
+	}
+
+	if freed, err := store.DedupeTree(testChecksum, home, false); err != nil || freed != 0 {
+		t.Errorf("Expected nothing to free the second time, got %d, %v", freed, err)
+	}
+}
+
+func TestFileSHA256(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	checksum, err := FileSHA256(path)
+	if err != nil {
+		t.Fatalf("Failed to hash file: %v", err)
+	}
+	if checksum != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("Unexpected checksum: %s", checksum)
+	}
+
+	if _, err := FileSHA256(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("Expected an error for a missing file")
+	}
+}
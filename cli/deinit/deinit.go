@@ -0,0 +1,202 @@
+// Package deinit removes what devrig created in a project: the wrapper scripts, the .devrig folder
+// and the cache entries of the project, so a project can stop using devrig without leftovers
+package deinit
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/cache"
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/lock"
+	"jonnyzzz.com/devrig.dev/state"
+)
+
+// Scripts are the wrapper scripts `devrig init` writes next to devrig.yaml
+var Scripts = []string{"devrig", "devrig.bat", "devrig.ps1"}
+
+// scriptMarker is in every wrapper script devrig writes, a file without it is not removed
+var scriptMarker = []byte("devrig.dev")
+
+// Options choose what is removed besides the files devrig created on its own
+type Options struct {
+	// RemoveConfig removes devrig.yaml, devrig.lock and devrig.local.yaml too
+	RemoveConfig bool
+}
+
+// Plan is the list of the paths to remove and the notes on the ones kept
+type Plan struct {
+	Removals []cache.Removal
+	// Kept describes what devrig created but deinit leaves, e.g. the shared cache entries
+	Kept []string
+}
+
+// PlanDeinit lists what devrig created for the project of devrig.yaml, nothing is removed
+func PlanDeinit(configPath string, opts Options) (*Plan, error) {
+	plan := &Plan{}
+	projectDir := filepath.Dir(configPath)
+
+	for _, name := range Scripts {
+		plan.addScript(filepath.Join(projectDir, name))
+	}
+
+	if opts.RemoveConfig {
+		for _, path := range []string{configPath, lock.ResolvePath(configPath), filepath.Join(projectDir, configservice.LocalConfigName)} {
+			plan.addFile(path, "configuration of the project")
+		}
+	}
+
+	if err := plan.addCache(configPath, projectDir); err != nil {
+		return nil, err
+	}
+
+	devrigHome := layout.ResolveDevrigHome(configPath)
+	if os.Getenv(layout.EnvDevrigHome) != "" {
+		plan.Kept = append(plan.Kept, fmt.Sprintf("%s is set by %s and may be shared, it is kept", devrigHome, layout.EnvDevrigHome))
+	} else {
+		plan.addDir(devrigHome, "devrig binaries, tools and state of the project")
+	}
+
+	plan.Kept = append(plan.Kept, "devrig installs no git hooks, there are none to remove")
+	return plan, nil
+}
+
+// addScript removes the wrapper script unless it was replaced by a file of the project with the same name
+func (p *Plan) addScript(path string) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return
+	}
+	if !info.Mode().IsRegular() {
+		p.Kept = append(p.Kept, fmt.Sprintf("%s is not a wrapper script, it is kept", path))
+		return
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !bytes.Contains(data, scriptMarker) {
+		p.Kept = append(p.Kept, fmt.Sprintf("%s is not a wrapper script written by devrig, it is kept", path))
+		return
+	}
+	p.Removals = append(p.Removals, cache.Removal{Path: path, Reason: "wrapper script", Size: info.Size()})
+}
+
+func (p *Plan) addFile(path string, reason string) {
+	if info, err := os.Lstat(path); err == nil && info.Mode().IsRegular() {
+		p.Removals = append(p.Removals, cache.Removal{Path: path, Reason: reason, Size: info.Size()})
+	}
+}
+
+func (p *Plan) addDir(path string, reason string) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		p.Removals = append(p.Removals, cache.Removal{Path: path, Reason: reason, Size: dirSize(path)})
+	}
+}
+
+// addCache removes a cache inside of the project as a whole. A cache outside of the project is shared with other
+// projects, only the IDEs the project installed and no other registered project installed or pins are removed
+// from it with their downloads. Without the registration of the project the other projects are not known,
+// the IDEs are kept then
+func (p *Plan) addCache(configPath string, projectDir string) error {
+	cacheDir := config.ResolveCacheDir(configPath)
+	if isInside(projectDir, cacheDir) {
+		p.addDir(cacheDir, "IDE cache of the project")
+		// The default cache is .idew/cache, its parent is left empty
+		if parent := filepath.Dir(cacheDir); filepath.Base(parent) == ".idew" && isInside(projectDir, parent) {
+			if entries, err := os.ReadDir(parent); err == nil && len(entries) == 1 {
+				p.Removals = append(p.Removals, cache.Removal{Path: parent, Reason: "empty folder of the IDE cache"})
+			}
+		}
+		return nil
+	}
+
+	installed, err := state.Load(layout.ResolveDevrigHome(configPath))
+	if err != nil {
+		return err
+	}
+	used, known := referencedByOthers(cacheDir, configPath)
+	unpackedDir := layout.ResolveUnpackedIdesDir(cacheDir)
+	downloadsDir := layout.ResolveDownloadsDir(cacheDir)
+	downloads, _ := os.ReadDir(downloadsDir)
+	for _, artifact := range installed.Artifacts {
+		if artifact.Type != state.TypeIde || filepath.Dir(artifact.Path) != unpackedDir {
+			continue
+		}
+		if _, err := os.Stat(artifact.Path); err != nil {
+			continue
+		}
+		if !known {
+			p.Kept = append(p.Kept, fmt.Sprintf("%s is in the shared cache and other projects may use it, it is kept", artifact.Path))
+			continue
+		}
+		if used[artifact.Path] {
+			p.Kept = append(p.Kept, fmt.Sprintf("%s is used by other projects, it is kept", artifact.Path))
+			continue
+		}
+		reason := fmt.Sprintf("%s %s installed by the project", artifact.Name, artifact.Version)
+		p.addDir(artifact.Path, reason)
+
+		// The packages are named <name>-<build>.<package type> after the folder <name>-<build>[.app]
+		base := strings.TrimSuffix(filepath.Base(artifact.Path), ".app")
+		for _, entry := range downloads {
+			path := filepath.Join(downloadsDir, entry.Name())
+			if entry.Type().IsRegular() && strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())) == base && !used[path] {
+				p.addFile(path, "package of "+reason)
+			}
+		}
+	}
+	p.addFile(cache.ProjectRegistration(cacheDir, configPath), "registration of the project in the cache")
+	p.Kept = append(p.Kept, fmt.Sprintf("%s is outside of the project, the entries other projects use are kept", cacheDir))
+	return nil
+}
+
+// referencedByOthers returns the cache entries the other projects registered in the cache installed or pin.
+// The boolean is false when the other projects cannot be known, then no entry is exclusive to the project
+func referencedByOthers(cacheDir string, configPath string) (map[string]bool, bool) {
+	others, registered, err := cache.OtherProjects(cacheDir, configPath)
+	if err != nil || !registered {
+		return nil, false
+	}
+	used := map[string]bool{}
+	for _, other := range others {
+		installed, err := state.Load(layout.ResolveDevrigHome(other))
+		if err != nil {
+			return nil, false
+		}
+		for _, artifact := range installed.Artifacts {
+			used[filepath.Clean(artifact.Path)] = true
+		}
+		references, err := cache.References(other, cacheDir)
+		if err != nil {
+			return nil, false
+		}
+		for _, path := range references {
+			used[path] = true
+		}
+	}
+	return used, true
+}
+
+// isInside checks if the path is below the dir
+func isInside(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,89 @@
+package deinit
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/cache"
+	"jonnyzzz.com/devrig.dev/progress"
+)
+
+// NewDeinitCommand creates the deinit command
+func NewDeinitCommand(configPath func() string) *cobra.Command {
+	var dryRun bool
+	var yes bool
+	var opts Options
+
+	cmd := &cobra.Command{
+		Use:   "deinit",
+		Short: "Remove everything devrig created in the project",
+		Long: `Remove everything devrig created in the project, the reverse of devrig init:
+
+  - the devrig, devrig.bat and devrig.ps1 wrapper scripts
+  - the .devrig folder with the devrig binaries, tools, logs and state
+  - the IDE cache of the project, .idew/cache by default
+
+A cache outside of the project, set by DEVRIG_CACHE_DIR or settings.cache_dir,
+is shared with other projects. Only the IDEs recorded in .devrig/state.json
+and their downloads are removed from it. A folder set by DEVRIG_HOME is kept.
+Scripts with the same names that devrig did not write are kept too.
+devrig installs no git hooks, so there are none to remove.
+
+devrig.yaml, devrig.lock and devrig.local.yaml are kept, add --remove-config
+to remove them as well. The paths are listed before anything is removed.
+
+Examples:
+  devrig deinit --dry-run
+  devrig deinit --yes --remove-config
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plan, err := PlanDeinit(configPath(), opts)
+			if err != nil {
+				return err
+			}
+
+			for _, kept := range plan.Kept {
+				cmd.Printf("Keeping %s\n", kept)
+			}
+			if len(plan.Removals) == 0 {
+				cmd.Println("Nothing to remove")
+				return nil
+			}
+
+			var total int64
+			for _, removal := range plan.Removals {
+				total += removal.Size
+				cmd.Printf("%s (%s): %s\n", removal.Path, progress.FormatBytes(removal.Size), removal.Reason)
+			}
+
+			if dryRun {
+				cmd.Printf("Dry run: %d entries, %s would be removed\n", len(plan.Removals), progress.FormatBytes(total))
+				return nil
+			}
+
+			if !yes {
+				cmd.Printf("Remove %d entries? [y/N] ", len(plan.Removals))
+				answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				answer = strings.ToLower(strings.TrimSpace(answer))
+				if answer != "y" && answer != "yes" {
+					cmd.Println("Aborted, nothing is removed")
+					return nil
+				}
+			}
+
+			freed, err := cache.ApplyRemovals(cmd.Context(), plan.Removals)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Removed %d entries, freed %s\n", len(plan.Removals), progress.FormatBytes(freed))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only list the paths to remove")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Remove without asking for confirmation")
+	cmd.Flags().BoolVar(&opts.RemoveConfig, "remove-config", false, "Remove devrig.yaml, devrig.lock and devrig.local.yaml too")
+	return cmd
+}
@@ -0,0 +1,288 @@
+package deinit
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/bootstrap"
+	"jonnyzzz.com/devrig.dev/cache"
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/state"
+)
+
+// writeProject writes devrig.yaml with the wrapper scripts, the .devrig folder and an IDE in the default cache
+func writeProject(t *testing.T) string {
+	t.Helper()
+	t.Setenv(layout.EnvDevrigHome, "")
+	t.Setenv(config.EnvCacheDir, "")
+	projectDir := t.TempDir()
+	configPath := filepath.Join(projectDir, "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte("devrig:\n  version: 0.79.5\n"), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	if err := bootstrap.CopyBootstrapScripts(projectDir); err != nil {
+		t.Fatalf("Failed to copy the wrapper scripts: %v", err)
+	}
+	writeFile(t, filepath.Join(projectDir, ".devrig", "logs", "devrig.log"), "log")
+	writeFile(t, filepath.Join(config.ResolveCacheDir(configPath), "ide", "GoLand-2024.3", "bin", "goland.sh"), "ide")
+	return configPath
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func removedPaths(plan *Plan) map[string]bool {
+	paths := map[string]bool{}
+	for _, removal := range plan.Removals {
+		paths[removal.Path] = true
+	}
+	return paths
+}
+
+func TestPlanDeinit(t *testing.T) {
+	configPath := writeProject(t)
+	projectDir := filepath.Dir(configPath)
+
+	plan, err := PlanDeinit(configPath, Options{})
+	if err != nil {
+		t.Fatalf("Failed to plan deinit: %v", err)
+	}
+	removed := removedPaths(plan)
+	for _, path := range []string{
+		filepath.Join(projectDir, "devrig"),
+		filepath.Join(projectDir, "devrig.bat"),
+		filepath.Join(projectDir, "devrig.ps1"),
+		filepath.Join(projectDir, ".devrig"),
+		filepath.Join(projectDir, ".idew", "cache"),
+		filepath.Join(projectDir, ".idew"),
+	} {
+		if !removed[path] {
+			t.Errorf("Expected %s to be removed, got %v", path, removed)
+		}
+	}
+	if removed[configPath] {
+		t.Errorf("devrig.yaml must be kept without --remove-config")
+	}
+}
+
+func TestPlanDeinit_KeepsForeignScripts(t *testing.T) {
+	configPath := writeProject(t)
+	projectDir := filepath.Dir(configPath)
+	writeFile(t, filepath.Join(projectDir, "devrig.bat"), "@echo the build script of the project\n")
+	if err := os.Remove(filepath.Join(projectDir, "devrig")); err != nil {
+		t.Fatalf("Failed to remove the script: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(projectDir, "devrig"), 0755); err != nil {
+		t.Fatalf("Failed to create the folder: %v", err)
+	}
+
+	plan, err := PlanDeinit(configPath, Options{})
+	if err != nil {
+		t.Fatalf("Failed to plan deinit: %v", err)
+	}
+	removed := removedPaths(plan)
+	if removed[filepath.Join(projectDir, "devrig.bat")] || removed[filepath.Join(projectDir, "devrig")] {
+		t.Errorf("Expected the files devrig did not write to be kept, got %v", removed)
+	}
+	if !removed[filepath.Join(projectDir, "devrig.ps1")] {
+		t.Errorf("Expected devrig.ps1 to be removed, got %v", removed)
+	}
+	if kept := strings.Join(plan.Kept, "\n"); !strings.Contains(kept, "devrig.bat") || !strings.Contains(kept, filepath.Join(projectDir, "devrig")+" ") {
+		t.Errorf("Expected the kept files to be reported, got %s", kept)
+	}
+}
+
+func TestPlanDeinit_RemoveConfig(t *testing.T) {
+	configPath := writeProject(t)
+	projectDir := filepath.Dir(configPath)
+	writeFile(t, filepath.Join(projectDir, "devrig.lock"), "{}")
+	writeFile(t, filepath.Join(projectDir, "devrig.local.yaml"), "settings: {}\n")
+
+	plan, err := PlanDeinit(configPath, Options{RemoveConfig: true})
+	if err != nil {
+		t.Fatalf("Failed to plan deinit: %v", err)
+	}
+	removed := removedPaths(plan)
+	for _, name := range []string{"devrig.yaml", "devrig.lock", "devrig.local.yaml"} {
+		if !removed[filepath.Join(projectDir, name)] {
+			t.Errorf("Expected %s to be removed, got %v", name, removed)
+		}
+	}
+}
+
+func TestPlanDeinit_SharedCache(t *testing.T) {
+	configPath := writeProject(t)
+	projectDir := filepath.Dir(configPath)
+	cacheDir := t.TempDir()
+	t.Setenv(config.EnvCacheDir, cacheDir)
+
+	own := filepath.Join(cacheDir, "ide", "GoLand-2024.3")
+	other := filepath.Join(cacheDir, "ide", "WebStorm-2024.3")
+	writeFile(t, filepath.Join(own, "bin", "goland.sh"), "ide")
+	writeFile(t, filepath.Join(other, "bin", "webstorm.sh"), "ide")
+	writeFile(t, filepath.Join(cacheDir, "download", "GoLand-2024.3.targz"), "package")
+	writeFile(t, filepath.Join(cacheDir, "download", "WebStorm-2024.3.targz"), "package")
+	if err := state.Record(context.Background(), filepath.Join(projectDir, ".devrig"),
+		state.Artifact{Type: state.TypeIde, Name: "GoLand", Version: "2024.3", Path: own}, time.Now()); err != nil {
+		t.Fatalf("Failed to record the IDE: %v", err)
+	}
+	if err := cache.RegisterProject(cacheDir, configPath); err != nil {
+		t.Fatalf("Failed to register the project: %v", err)
+	}
+
+	plan, err := PlanDeinit(configPath, Options{})
+	if err != nil {
+		t.Fatalf("Failed to plan deinit: %v", err)
+	}
+	removed := removedPaths(plan)
+	if !removed[own] || !removed[filepath.Join(cacheDir, "download", "GoLand-2024.3.targz")] {
+		t.Errorf("Expected the IDE of the project and its package to be removed, got %v", removed)
+	}
+	if removed[cacheDir] || removed[other] || removed[filepath.Join(cacheDir, "download", "WebStorm-2024.3.targz")] {
+		t.Errorf("Expected the entries of other projects to be kept, got %v", removed)
+	}
+}
+
+func TestPlanDeinit_SharedCacheUsedByOtherProjects(t *testing.T) {
+	configPath := writeProject(t)
+	otherConfigPath := writeProject(t)
+	cacheDir := t.TempDir()
+	t.Setenv(config.EnvCacheDir, cacheDir)
+
+	ide := filepath.Join(cacheDir, "ide", "GoLand-2024.3")
+	writeFile(t, filepath.Join(ide, "bin", "goland.sh"), "ide")
+	writeFile(t, filepath.Join(cacheDir, "download", "GoLand-2024.3.targz"), "package")
+	for _, path := range []string{configPath, otherConfigPath} {
+		if err := state.Record(context.Background(), filepath.Join(filepath.Dir(path), ".devrig"),
+			state.Artifact{Type: state.TypeIde, Name: "GoLand", Version: "2024.3", Path: ide}, time.Now()); err != nil {
+			t.Fatalf("Failed to record the IDE: %v", err)
+		}
+	}
+
+	// The project used the cache before the registration, the other projects are not known
+	plan, err := PlanDeinit(configPath, Options{})
+	if err != nil {
+		t.Fatalf("Failed to plan deinit: %v", err)
+	}
+	if removed := removedPaths(plan); removed[ide] || removed[filepath.Join(cacheDir, "download", "GoLand-2024.3.targz")] {
+		t.Errorf("Expected the IDE of an unregistered project to be kept, got %v", removed)
+	}
+	if kept := strings.Join(plan.Kept, "\n"); !strings.Contains(kept, ide) {
+		t.Errorf("Expected the kept IDE to be reported, got %s", kept)
+	}
+
+	for _, path := range []string{configPath, otherConfigPath} {
+		if err := cache.RegisterProject(cacheDir, path); err != nil {
+			t.Fatalf("Failed to register the project: %v", err)
+		}
+	}
+	plan, err = PlanDeinit(configPath, Options{})
+	if err != nil {
+		t.Fatalf("Failed to plan deinit: %v", err)
+	}
+	removed := removedPaths(plan)
+	if removed[ide] || removed[filepath.Join(cacheDir, "download", "GoLand-2024.3.targz")] {
+		t.Errorf("Expected the IDE another project uses to be kept, got %v", removed)
+	}
+	if !removed[cache.ProjectRegistration(cacheDir, configPath)] {
+		t.Errorf("Expected the registration of the project to be removed, got %v", removed)
+	}
+	if kept := strings.Join(plan.Kept, "\n"); !strings.Contains(kept, ide+" is used by other projects") {
+		t.Errorf("Expected the kept IDE to be reported, got %s", kept)
+	}
+
+	// Once the other project is gone, the IDE is exclusive to the project
+	if err := os.Remove(otherConfigPath); err != nil {
+		t.Fatalf("Failed to remove the other project: %v", err)
+	}
+	plan, err = PlanDeinit(configPath, Options{})
+	if err != nil {
+		t.Fatalf("Failed to plan deinit: %v", err)
+	}
+	if removed := removedPaths(plan); !removed[ide] || !removed[filepath.Join(cacheDir, "download", "GoLand-2024.3.targz")] {
+		t.Errorf("Expected the IDE and its package to be removed, got %v", removed)
+	}
+}
+
+func TestPlanDeinit_DevrigHomeIsKept(t *testing.T) {
+	configPath := writeProject(t)
+	home := t.TempDir()
+	t.Setenv(layout.EnvDevrigHome, home)
+
+	plan, err := PlanDeinit(configPath, Options{})
+	if err != nil {
+		t.Fatalf("Failed to plan deinit: %v", err)
+	}
+	if removedPaths(plan)[home] {
+		t.Errorf("Expected %s of %s to be kept", home, layout.EnvDevrigHome)
+	}
+}
+
+func TestDeinitCommand(t *testing.T) {
+	configPath := writeProject(t)
+	projectDir := filepath.Dir(configPath)
+
+	dryRun := NewDeinitCommand(func() string { return configPath })
+	var out bytes.Buffer
+	dryRun.SetOut(&out)
+	dryRun.SetErr(&out)
+	dryRun.SetArgs([]string{"--dry-run"})
+	if err := dryRun.Execute(); err != nil {
+		t.Fatalf("Failed to run deinit --dry-run: %v", err)
+	}
+	if !strings.Contains(out.String(), "Dry run:") {
+		t.Errorf("Expected the dry run summary, got %s", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, ".devrig")); err != nil {
+		t.Fatalf("The dry run must not remove .devrig: %v", err)
+	}
+
+	cmd := NewDeinitCommand(func() string { return configPath })
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"--yes"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Failed to run deinit: %v", err)
+	}
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		t.Fatalf("Failed to list the project: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "devrig.yaml" {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("Expected only devrig.yaml to be left, got %v", names)
+	}
+}
+
+func TestDeinitCommand_Aborted(t *testing.T) {
+	configPath := writeProject(t)
+
+	cmd := NewDeinitCommand(func() string { return configPath })
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetIn(strings.NewReader("n\n"))
+	cmd.SetArgs([]string{})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Failed to run deinit: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(configPath), ".devrig")); err != nil {
+		t.Fatalf("The aborted deinit must not remove .devrig: %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+// Package deprecation is the registry of the deprecated CLI flags, devrig.yaml keys and files. Every use is reported
+// once per process as a structured warning with the replacement, in the strict mode it fails the command instead,
+// so CI can catch the deprecations before they are removed
+package deprecation
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/logging"
+)
+
+// EnvStrict turns the deprecation warnings into errors when set to 1 or true.
+// The --strict-deprecations flag sets it too, so child processes inherit the mode.
+const EnvStrict = "DEVRIG_STRICT_DEPRECATIONS"
+
+// Kind is the kind of the deprecated element
+type Kind string
+
+const (
+	KindFlag      Kind = "flag"
+	KindConfigKey Kind = "config_key"
+	KindFile      Kind = "file"
+)
+
+// Deprecation describes a deprecated element and what to use instead
+type Deprecation struct {
+	Kind Kind `json:"kind"`
+	// Name is the flag with the dashes, e.g. --no-updates, the dotted path of a devrig.yaml key, e.g. ide.hash,
+	// or the name of a file, e.g. .idew.yaml
+	Name string `json:"name"`
+	// Replacement is what to use instead, empty if the element is just ignored
+	Replacement string `json:"replacement,omitempty"`
+	// Hint is the remediation step, e.g. the command that migrates the element
+	Hint string `json:"hint,omitempty"`
+}
+
+// Message returns the human-readable warning, e.g. ".idew.yaml is deprecated, use the ide section of devrig.yaml instead"
+func (d Deprecation) Message() string {
+	message := fmt.Sprintf("%s is deprecated", d.Name)
+	if d.Replacement != "" {
+		message += ", use " + d.Replacement + " instead"
+	}
+	if d.Hint != "" {
+		message += ". " + d.Hint
+	}
+	return message
+}
+
+var (
+	mutex    sync.Mutex
+	registry = map[string]Deprecation{}
+	reported = map[string]bool{}
+)
+
+func key(kind Kind, name string) string {
+	return string(kind) + ":" + name
+}
+
+// Register adds the deprecation to the registry, a later registration of the same element replaces it
+func Register(deprecation Deprecation) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	registry[key(deprecation.Kind, deprecation.Name)] = deprecation
+}
+
+// Lookup returns the deprecation of the element, or nil if it is not deprecated
+func Lookup(kind Kind, name string) *Deprecation {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if deprecation, ok := registry[key(kind, name)]; ok {
+		return &deprecation
+	}
+	return nil
+}
+
+// All returns the registered deprecations sorted by the kind and the name
+func All() []Deprecation {
+	mutex.Lock()
+	defer mutex.Unlock()
+	all := make([]Deprecation, 0, len(registry))
+	for _, deprecation := range registry {
+		all = append(all, deprecation)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Kind != all[j].Kind {
+			return all[i].Kind < all[j].Kind
+		}
+		return all[i].Name < all[j].Name
+	})
+	return all
+}
+
+// Strict checks if the deprecations fail the command
+func Strict() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(EnvStrict))) {
+	case "1", "true", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// Report records the use of the element. A deprecated element is logged once per process,
+// in the strict mode a DeprecatedError is returned instead. Elements that are not deprecated are ignored
+func Report(ctx context.Context, kind Kind, name string) error {
+	deprecation := Lookup(kind, name)
+	if deprecation == nil {
+		return nil
+	}
+	if Strict() {
+		return &devrigErrors.DeprecatedError{Kind: string(kind), Name: name, Message: deprecation.Message()}
+	}
+
+	mutex.Lock()
+	seen := reported[key(kind, name)]
+	reported[key(kind, name)] = true
+	mutex.Unlock()
+	if !seen {
+		logging.FromContext(ctx).Warn(deprecation.Message(),
+			"deprecation", string(kind), "name", name, "replacement", deprecation.Replacement)
+	}
+	return nil
+}
+
+// DeprecateFlag registers the flag of the set as deprecated and hides it from the help
+func DeprecateFlag(flags *pflag.FlagSet, name string, replacement string, hint string) {
+	Register(Deprecation{Kind: KindFlag, Name: "--" + name, Replacement: replacement, Hint: hint})
+	if flag := flags.Lookup(name); flag != nil {
+		flag.Hidden = true
+	}
+}
+
+// CheckFlags reports the deprecated flags set on the command line of the command
+func CheckFlags(ctx context.Context, cmd *cobra.Command) error {
+	var err error
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if err == nil {
+			err = Report(ctx, KindFlag, "--"+flag.Name)
+		}
+	})
+	return err
+}
+
+// CheckConfigKeys reports the deprecated keys that are set in the decoded devrig.yaml
+func CheckConfigKeys(ctx context.Context, values map[string]interface{}) error {
+	for _, deprecation := range All() {
+		if deprecation.Kind != KindConfigKey || !hasPath(values, deprecation.Name) {
+			continue
+		}
+		if err := Report(ctx, KindConfigKey, deprecation.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasPath checks if the dotted path, e.g. ide.hash, is set in the values
+func hasPath(values map[string]interface{}, path string) bool {
+	current := values
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		value, ok := current[part]
+		if !ok {
+			return false
+		}
+		if i == len(parts)-1 {
+			return value != nil
+		}
+		if current, ok = value.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return false
+}
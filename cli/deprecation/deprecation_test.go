@@ -0,0 +1,108 @@
+package deprecation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/logging"
+)
+
+// captureWarnings returns a context with a logger writing JSON records into the buffer
+func captureWarnings() (context.Context, *bytes.Buffer) {
+	var buffer bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buffer, nil))
+	return logging.WithLogger(context.Background(), logger), &buffer
+}
+
+func TestReport_WarnsOnce(t *testing.T) {
+	Register(Deprecation{Kind: KindFile, Name: "old-once.yaml", Replacement: "new.yaml", Hint: "Rename it"})
+	ctx, buffer := captureWarnings()
+
+	for i := 0; i < 3; i++ {
+		if err := Report(ctx, KindFile, "old-once.yaml"); err != nil {
+			t.Fatalf("Failed to report: %v", err)
+		}
+	}
+	if err := Report(ctx, KindFile, "current.yaml"); err != nil {
+		t.Fatalf("Failed to report: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected one warning, got:\n%s", buffer.String())
+	}
+	for _, expected := range []string{`"msg":"old-once.yaml is deprecated, use new.yaml instead. Rename it"`,
+		`"deprecation":"file"`, `"replacement":"new.yaml"`} {
+		if !strings.Contains(lines[0], expected) {
+			t.Errorf("Expected %s in the warning: %s", expected, lines[0])
+		}
+	}
+}
+
+func TestReport_Strict(t *testing.T) {
+	t.Setenv(EnvStrict, "1")
+	Register(Deprecation{Kind: KindFile, Name: "old-strict.yaml", Replacement: "new.yaml"})
+	ctx, buffer := captureWarnings()
+
+	err := Report(ctx, KindFile, "old-strict.yaml")
+	var deprecated *devrigErrors.DeprecatedError
+	if !errors.As(err, &deprecated) || deprecated.Name != "old-strict.yaml" {
+		t.Fatalf("Expected DeprecatedError, got %v", err)
+	}
+	if devrigErrors.ExitCode(err) != devrigErrors.ExitDeprecated {
+		t.Errorf("Expected exit code %d, got %d", devrigErrors.ExitDeprecated, devrigErrors.ExitCode(err))
+	}
+	if buffer.Len() != 0 {
+		t.Errorf("Expected no warning in the strict mode, got %s", buffer.String())
+	}
+}
+
+func TestCheckFlags(t *testing.T) {
+	t.Setenv(EnvStrict, "true")
+	cmd := &cobra.Command{Use: "test", Run: func(cmd *cobra.Command, args []string) {}}
+	cmd.Flags().Bool("old-flag", false, "")
+	cmd.Flags().Bool("new-flag", false, "")
+	DeprecateFlag(cmd.Flags(), "old-flag", "--new-flag", "")
+
+	if !cmd.Flags().Lookup("old-flag").Hidden {
+		t.Error("Expected the deprecated flag to be hidden")
+	}
+	if err := cmd.ParseFlags([]string{"--new-flag"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if err := CheckFlags(context.Background(), cmd); err != nil {
+		t.Errorf("Expected no error without the deprecated flag, got %v", err)
+	}
+	if err := cmd.ParseFlags([]string{"--old-flag"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+	if err := CheckFlags(context.Background(), cmd); err == nil || !strings.Contains(err.Error(), "use --new-flag instead") {
+		t.Errorf("Expected an error for the deprecated flag, got %v", err)
+	}
+}
+
+func TestCheckConfigKeys(t *testing.T) {
+	t.Setenv(EnvStrict, "1")
+	Register(Deprecation{Kind: KindConfigKey, Name: "section.old_key"})
+
+	for name, values := range map[string]map[string]interface{}{
+		"missing section": {"other": map[string]interface{}{"old_key": "x"}},
+		"missing key":     {"section": map[string]interface{}{"new_key": "x"}},
+		"scalar section":  {"section": "x"},
+	} {
+		if err := CheckConfigKeys(context.Background(), values); err != nil {
+			t.Errorf("%s: expected no error, got %v", name, err)
+		}
+	}
+
+	values := map[string]interface{}{"section": map[string]interface{}{"old_key": "x"}}
+	if err := CheckConfigKeys(context.Background(), values); err == nil {
+		t.Error("Expected an error for the deprecated key")
+	}
+}
@@ -0,0 +1,137 @@
+// Package devrig is the programmatic API of devrig for tools embedding it instead of running the CLI.
+// It resolves the project configuration, provisions the environment described in devrig.yaml
+// and checks for devrig updates, the same way the devrig commands do.
+package devrig
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"jonnyzzz.com/devrig.dev/apply"
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configfile"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/summary"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// EnvConfig overrides the location of devrig.yaml
+const EnvConfig = "DEVRIG_CONFIG"
+
+// ConfigFileName is the name of the project configuration file
+const ConfigFileName = "devrig.yaml"
+
+// ResolveConfigPath resolves the path to devrig.yaml using the following precedence:
+// 1. the given path, e.g. from the --devrig-config flag
+// 2. DEVRIG_CONFIG environment variable
+// 3. devrig.yaml, devrig.toml or devrig.json in the current directory or the closest parent directory
+// containing one of them, the first existing one in the folder
+// 4. ./devrig.yaml, if no directory has the configuration yet
+// Always returns an absolute path.
+func ResolveConfigPath(path string) string {
+	if path == "" {
+		path = os.Getenv(EnvConfig)
+	}
+	if path == "" {
+		path, _ = configfile.Find(FindProjectRoot("."))
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		// If we can't resolve, return as-is (shouldn't happen in practice)
+		return path
+	}
+	return absPath
+}
+
+// FindProjectRoot returns the directory of the project configuration, the given directory or the closest parent
+// containing devrig.yaml, devrig.toml or devrig.json. Returns the given directory if none of them has it
+func FindProjectRoot(dir string) string {
+	root, err := config.FindInParents(dir, configfile.FileNames...)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Debug("failed to search devrig.yaml in parent directories", "dir", dir, "error", err)
+		}
+		return dir
+	}
+	return root
+}
+
+// ResolveUpdateChannel returns the release channel from devrig.yaml, or stable if it is not configured
+func ResolveUpdateChannel(configs configservice.ConfigService) string {
+	section, err := configs.Binaries().ReadDevrigSection()
+	if err != nil || section.Channel == "" {
+		return configservice.ChannelStable
+	}
+	return section.Channel
+}
+
+// Project is a devrig project, the folder with devrig.yaml
+type Project struct {
+	configPath string
+	configs    configservice.ConfigService
+}
+
+// Open returns the project of the devrig.yaml, the path is resolved with ResolveConfigPath.
+// The file is read lazily, so a project can be opened before devrig.yaml is created.
+func Open(configPath string) *Project {
+	configPath = ResolveConfigPath(configPath)
+	return &Project{configPath: configPath, configs: configservice.NewConfigService(configPath)}
+}
+
+// ProjectRoot returns the absolute path of the project folder, the one containing devrig.yaml.
+// It is the repository root when devrig runs from a subdirectory of the project
+func (p *Project) ProjectRoot() string {
+	return filepath.Dir(p.configPath)
+}
+
+// ConfigPath returns the absolute path of devrig.yaml
+func (p *Project) ConfigPath() string {
+	return p.configPath
+}
+
+// Configs returns the service to read and update the sections of devrig.yaml
+func (p *Project) Configs() configservice.ConfigService {
+	return p.configs
+}
+
+// DevrigHome returns the .devrig folder of the project, or its replacement for read-only checkouts
+func (p *Project) DevrigHome() string {
+	return layout.ResolveDevrigHome(p.configPath)
+}
+
+// ApplyOptions configure Apply
+type ApplyOptions struct {
+	// Steps to execute, apply.DefaultSteps() if empty
+	Steps []apply.Step
+	// Selection limits the executed steps, the zero value selects all of them
+	Selection apply.Selection
+	// FromScratch ignores the journal of previous runs
+	FromScratch bool
+}
+
+// Apply provisions the environment described in devrig.yaml, as `devrig apply` does.
+// The summary lists the outcome of every step, it is returned together with the error of a failed step.
+func (p *Project) Apply(ctx context.Context, options ApplyOptions) (*summary.Summary, error) {
+	steps := options.Steps
+	if len(steps) == 0 {
+		steps = apply.DefaultSteps()
+	}
+
+	env := apply.Environment{ConfigPath: p.configPath, DevrigHome: p.DevrigHome(), Configs: p.configs}
+	var s summary.Summary
+	err := apply.Provision(ctx, env, steps, apply.Options{Selection: options.Selection, FromScratch: options.FromScratch}, &s)
+	return &s, err
+}
+
+// Updates returns the update service for the devrig version, it follows the release channel of devrig.yaml
+func (p *Project) Updates(currentVersion string) updates.UpdateService {
+	client := updates.NewProjectClient(func() configservice.ConfigService { return p.configs })
+	return updates.NewUpdateService(currentVersion, client, func() string {
+		return ResolveUpdateChannel(p.configs)
+	})
+}
@@ -0,0 +1,120 @@
+package devrig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/apply"
+	"jonnyzzz.com/devrig.dev/configservice"
+)
+
+func TestResolveConfigPath(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
+
+	t.Setenv(EnvConfig, "")
+	if got := ResolveConfigPath(""); got != filepath.Join(tempDir, ConfigFileName) {
+		t.Errorf("Expected devrig.yaml in the current directory, got: %s", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(tempDir, "devrig.toml"), []byte("[tools]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.toml: %v", err)
+	}
+	if got := ResolveConfigPath(""); got != filepath.Join(tempDir, "devrig.toml") {
+		t.Errorf("Expected devrig.toml without devrig.yaml, got: %s", got)
+	}
+
+	t.Setenv(EnvConfig, "from-env.yaml")
+	if got := ResolveConfigPath(""); got != filepath.Join(tempDir, "from-env.yaml") {
+		t.Errorf("Expected the path from %s, got: %s", EnvConfig, got)
+	}
+
+	if got := ResolveConfigPath("flag.yaml"); got != filepath.Join(tempDir, "flag.yaml") {
+		t.Errorf("Expected the explicit path to win, got: %s", got)
+	}
+}
+
+func TestResolveConfigPath_ParentDirectory(t *testing.T) {
+	projectDir := t.TempDir()
+	subDir := filepath.Join(projectDir, "services", "api")
+	if err := os.MkdirAll(subDir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, ConfigFileName), []byte("tools: {}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	t.Chdir(subDir)
+	t.Setenv(EnvConfig, "")
+
+	project := Open("")
+	if project.ConfigPath() != filepath.Join(projectDir, ConfigFileName) {
+		t.Errorf("Expected devrig.yaml of the parent directory, got: %s", project.ConfigPath())
+	}
+	if project.ProjectRoot() != projectDir {
+		t.Errorf("Expected the project root %s, got: %s", projectDir, project.ProjectRoot())
+	}
+
+	// The closest directory with a configuration wins
+	if err := os.WriteFile(filepath.Join(subDir, "devrig.json"), []byte("{}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.json: %v", err)
+	}
+	if got := ResolveConfigPath(""); got != filepath.Join(subDir, "devrig.json") {
+		t.Errorf("Expected devrig.json of the current directory, got: %s", got)
+	}
+}
+
+func TestResolveUpdateChannel(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), ConfigFileName)
+	if got := ResolveUpdateChannel(configservice.NewConfigService(configPath)); got != configservice.ChannelStable {
+		t.Errorf("Expected stable without devrig.yaml, got: %s", got)
+	}
+}
+
+// countingStep records its executions
+type countingStep struct {
+	runs int
+}
+
+func (s *countingStep) ID() string                                         { return "counting" }
+func (s *countingStep) Name() string                                       { return "Counting step" }
+func (s *countingStep) Key(_ apply.Environment) (string, error)            { return "1", nil }
+func (s *countingStep) Verify(_ context.Context, _ apply.Environment) bool { return true }
+
+func (s *countingStep) Run(_ context.Context, env apply.Environment) error {
+	s.runs++
+	return os.MkdirAll(env.DevrigHome, 0755)
+}
+
+func TestProject_Apply(t *testing.T) {
+	projectDir := t.TempDir()
+	project := Open(filepath.Join(projectDir, ConfigFileName))
+
+	if project.DevrigHome() != filepath.Join(projectDir, ".devrig") {
+		t.Errorf("Unexpected devrig home: %s", project.DevrigHome())
+	}
+
+	step := &countingStep{}
+	options := ApplyOptions{Steps: []apply.Step{step}}
+	for i := 0; i < 2; i++ {
+		s, err := project.Apply(context.Background(), options)
+		if err != nil {
+			t.Fatalf("Failed to apply: %v", err)
+		}
+		if s == nil {
+			t.Fatal("Expected a summary")
+		}
+	}
+	if step.runs != 1 {
+		t.Errorf("Expected the journal to skip the completed step, ran %d times", step.runs)
+	}
+
+	options.FromScratch = true
+	if _, err := project.Apply(context.Background(), options); err != nil {
+		t.Fatalf("Failed to apply from scratch: %v", err)
+	}
+	if step.runs != 2 {
+		t.Errorf("Expected the step to run again from scratch, ran %d times", step.runs)
+	}
+}
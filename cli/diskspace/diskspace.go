@@ -0,0 +1,76 @@
+// Package diskspace checks the free space of a filesystem before large downloads and unpacks, so devrig fails
+// early with the missing amount instead of leaving a half-written IDE when the disk runs full
+package diskspace
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/progress"
+)
+
+const (
+	// UnpackRatio estimates the size of an unpacked IDE from the size of its package, IDE archives expand
+	// about two and a half times
+	UnpackRatio = 3
+	// EnvSkip disables the check, e.g. for network filesystems that report no free space
+	EnvSkip = "DEVRIG_SKIP_SPACE_CHECK"
+)
+
+// InsufficientSpaceError is returned when the filesystem of Path has less free space than required
+type InsufficientSpaceError struct {
+	Path      string
+	Required  int64
+	Available int64
+}
+
+func (e *InsufficientSpaceError) Error() string {
+	return fmt.Sprintf("not enough free space in %s: %s required, %s available, free up at least %s",
+		e.Path, progress.FormatBytes(e.Required), progress.FormatBytes(e.Available), progress.FormatBytes(e.Required-e.Available))
+}
+
+func (e *InsufficientSpaceError) ExitCode() int {
+	return devrigErrors.ExitDiskFull
+}
+
+// Check fails with InsufficientSpaceError if the filesystem of path has less than required bytes free.
+// The path may not exist yet, its closest existing parent is checked. When the free space is unknown
+// the check passes, the write reports the error anyway
+func Check(ctx context.Context, path string, required int64) error {
+	if required <= 0 || os.Getenv(EnvSkip) == "1" {
+		return nil
+	}
+	available, err := Available(path)
+	if err != nil {
+		logging.FromContext(ctx).Debug("failed to check free disk space", "path", path, "error", err)
+		return nil
+	}
+	if available < required {
+		return &InsufficientSpaceError{Path: path, Required: required, Available: available}
+	}
+	return nil
+}
+
+// Available returns the number of bytes the current user may write to the filesystem of path,
+// the closest existing parent is used for a path that does not exist yet
+func Available(path string) (int64, error) {
+	existing, err := filepath.Abs(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	for {
+		if _, err := os.Stat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return 0, fmt.Errorf("no existing parent of %s", path)
+		}
+		existing = parent
+	}
+	return available(existing)
+}
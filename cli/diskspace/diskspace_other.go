@@ -0,0 +1,18 @@
+//go:build !windows
+
+package diskspace
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+func available(path string) (int64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("failed to read filesystem of %s: %w", path, err)
+	}
+	// Bavail excludes the blocks reserved for root
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
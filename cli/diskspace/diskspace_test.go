@@ -0,0 +1,55 @@
+package diskspace
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+func TestAvailable(t *testing.T) {
+	dir := t.TempDir()
+	free, err := Available(dir)
+	if err != nil {
+		t.Fatalf("Failed to read free space: %v", err)
+	}
+	if free <= 0 {
+		t.Errorf("Expected free space in %s, got %d", dir, free)
+	}
+
+	// The cache folder may not exist before the first download
+	missing, err := Available(filepath.Join(dir, "cache", "downloads"))
+	if err != nil {
+		t.Fatalf("Failed to read free space of a missing folder: %v", err)
+	}
+	if missing <= 0 {
+		t.Errorf("Expected the free space of the parent, got %d", missing)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	if err := Check(context.Background(), dir, 1); err != nil {
+		t.Errorf("Expected one byte to fit, got: %v", err)
+	}
+
+	err := Check(context.Background(), dir, 1<<60)
+	var spaceErr *InsufficientSpaceError
+	if !errors.As(err, &spaceErr) {
+		t.Fatalf("Expected InsufficientSpaceError, got: %v", err)
+	}
+	if spaceErr.Path != dir || spaceErr.Available <= 0 || !strings.Contains(err.Error(), "free up at least") {
+		t.Errorf("Expected the path and the shortfall in the error, got %+v: %v", spaceErr, err)
+	}
+	if devrigErrors.ExitCode(err) != devrigErrors.ExitDiskFull {
+		t.Errorf("Expected exit code %d, got %d", devrigErrors.ExitDiskFull, devrigErrors.ExitCode(err))
+	}
+
+	t.Setenv(EnvSkip, "1")
+	if err := Check(context.Background(), dir, 1<<60); err != nil {
+		t.Errorf("Expected the check to be skipped, got: %v", err)
+	}
+}
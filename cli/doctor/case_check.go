@@ -0,0 +1,65 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+// CaseSensitivityCheck detects folders in .devrig whose names differ only by case,
+// they collide when the project is used on a case-insensitive filesystem (macOS, Windows)
+type CaseSensitivityCheck struct{}
+
+func (c *CaseSensitivityCheck) Name() string {
+	return "Filesystem case"
+}
+
+func (c *CaseSensitivityCheck) Run(_ context.Context, env Environment) Result {
+	if _, err := os.Stat(env.DevrigHome); err != nil {
+		return Result{Status: StatusSkipped, Summary: env.DevrigHome + " does not exist yet"}
+	}
+
+	var result Result
+	caseInsensitive, err := layout.IsCaseInsensitive(env.DevrigHome)
+	if err != nil {
+		result.Details = append(result.Details, err.Error())
+	} else if caseInsensitive {
+		result.Details = append(result.Details, "the filesystem of "+env.DevrigHome+" is case-insensitive")
+	} else {
+		result.Details = append(result.Details, "the filesystem of "+env.DevrigHome+" is case-sensitive")
+	}
+
+	dirs := []string{env.DevrigHome}
+	if entries, err := os.ReadDir(env.DevrigHome); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() {
+				dirs = append(dirs, filepath.Join(env.DevrigHome, entry.Name()))
+			}
+		}
+	}
+
+	collisions := 0
+	for _, dir := range dirs {
+		for _, names := range layout.FindCaseCollisions(dir) {
+			collisions++
+			result.Details = append(result.Details, fmt.Sprintf("%s: %s differ only by case", dir, strings.Join(names, ", ")))
+			for _, name := range names[1:] {
+				result.Fixes = append(result.Fixes, "Remove "+filepath.Join(dir, name))
+			}
+		}
+	}
+
+	if collisions == 0 {
+		result.Status = StatusOK
+		result.Summary = "no names in .devrig differ only by case"
+		return result
+	}
+
+	result.Status = StatusWarning
+	result.Summary = fmt.Sprintf("%d name collisions on case-insensitive filesystems", collisions)
+	return result
+}
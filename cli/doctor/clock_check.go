@@ -0,0 +1,91 @@
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/clock"
+	"jonnyzzz.com/devrig.dev/offline"
+)
+
+// ClockCheck compares the system clock to the Date header of the devrig update server
+type ClockCheck struct {
+	// Client overrides the HTTP client, used in tests
+	Client *http.Client
+}
+
+func (c *ClockCheck) Name() string {
+	return "Clock"
+}
+
+func (c *ClockCheck) Run(ctx context.Context, env Environment) Result {
+	if offline.Enabled() {
+		return Result{Status: StatusSkipped, Summary: "the network is disabled by the offline mode"}
+	}
+
+	target := ResolveNetworkTargets(env)[0]
+	host := target.URL
+	if parsed, err := url.Parse(target.URL); err == nil {
+		host = parsed.Host
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target.URL, nil)
+	if err != nil {
+		return Result{Status: StatusSkipped, Summary: fmt.Sprintf("failed to create the request to %s: %v", host, err)}
+	}
+
+	sent := time.Now()
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return Result{Status: StatusSkipped, Summary: fmt.Sprintf("%s is not reachable, see 'devrig doctor network'", host)}
+	}
+	_ = resp.Body.Close()
+	observation, ok := clock.FromResponse(resp, sent, time.Now())
+	if !ok {
+		return Result{Status: StatusSkipped, Summary: fmt.Sprintf("%s did not send its time", host)}
+	}
+	clock.Record(observation)
+
+	if !observation.Significant() {
+		return Result{Status: StatusOK, Summary: fmt.Sprintf("the system clock matches %s", host)}
+	}
+	return Result{
+		Status:  StatusWarning,
+		Summary: observation.String(),
+		Details: []string{
+			fmt.Sprintf("devrig tolerates a skew of %s, beyond it TLS certificates and signed uploads are rejected", clock.Allowance),
+		},
+		Fixes: []string{clockFix()},
+	}
+}
+
+// client sends the request without verifying the certificate, it cannot be verified with a wrong clock.
+// Only the Date header of the response is read
+func (c *ClockCheck) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return &http.Client{
+		Timeout: defaultProbeTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+}
+
+// clockFix returns the command that synchronizes the clock of the OS
+func clockFix() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "Enable 'Set time and date automatically' in the Date & Time settings, or run: sudo sntp -sS time.apple.com"
+	case "windows":
+		return "Enable 'Set time automatically' in the Date & time settings, or run as Administrator: w32tm /resync"
+	default:
+		return "Enable the time synchronization: sudo timedatectl set-ntp true"
+	}
+}
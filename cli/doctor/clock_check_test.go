@@ -0,0 +1,45 @@
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/offline"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// dateClient returns responses with the Date header offset from the system time
+func dateClient(offset time.Duration) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		header := http.Header{"Date": {time.Now().Add(offset).UTC().Format(http.TimeFormat)}}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: http.NoBody, Request: req}, nil
+	})}
+}
+
+func TestClockCheck(t *testing.T) {
+	t.Setenv(offline.EnvOffline, "")
+
+	result := (&ClockCheck{Client: dateClient(0)}).Run(context.Background(), Environment{})
+	if result.Status != StatusOK {
+		t.Errorf("Expected the clock to match, got: %+v", result)
+	}
+
+	result = (&ClockCheck{Client: dateClient(-20 * time.Minute)}).Run(context.Background(), Environment{})
+	if result.Status != StatusWarning || !strings.Contains(result.Summary, "ahead of devrig.dev") || len(result.Fixes) != 1 {
+		t.Errorf("Expected a warning about the clock, got: %+v", result)
+	}
+
+	t.Setenv(offline.EnvOffline, "1")
+	result = (&ClockCheck{Client: dateClient(0)}).Run(context.Background(), Environment{})
+	if result.Status != StatusSkipped {
+		t.Errorf("Expected the check to be skipped offline, got: %+v", result)
+	}
+}
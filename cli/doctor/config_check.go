@@ -0,0 +1,19 @@
+package doctor
+
+import (
+	"context"
+)
+
+// ConfigCheck verifies that devrig.yaml exists and is valid
+type ConfigCheck struct{}
+
+func (c *ConfigCheck) Name() string {
+	return "devrig.yaml"
+}
+
+func (c *ConfigCheck) Run(_ context.Context, env Environment) Result {
+	if err := env.Configs.EnsureValidConfig(); err != nil {
+		return Result{Status: StatusFailed, Summary: err.Error()}
+	}
+	return Result{Status: StatusOK, Summary: env.ConfigPath + " is valid"}
+}
@@ -0,0 +1,151 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/execcmd"
+)
+
+// maxOutputDetails limits the lines of the command output shown for a failed custom check
+const maxOutputDetails = 5
+
+// CustomCheck runs a check of the doctor section of devrig.yaml, the requirements of the team
+type CustomCheck struct {
+	Check configservice.DoctorCheck
+}
+
+// WithCustomChecks returns the built-in checks followed by the checks of the doctor section of devrig.yaml.
+// A broken doctor section is reported as a failed check, so the built-in checks still run
+func WithCustomChecks(env Environment, checks []Check) []Check {
+	all := append([]Check{}, checks...)
+	section, err := env.Configs.Doctor().ReadDoctor()
+	var notFound *devrigErrors.ConfigNotFoundError
+	if errors.As(err, &notFound) {
+		// The devrig.yaml check reports the missing file
+		return all
+	}
+	if err != nil {
+		return append(all, &brokenSectionCheck{err: err})
+	}
+	for _, check := range section.Checks {
+		all = append(all, &CustomCheck{Check: check})
+	}
+	return all
+}
+
+func (c *CustomCheck) Name() string {
+	return c.Check.Name
+}
+
+func (c *CustomCheck) Run(ctx context.Context, env Environment) Result {
+	failed := StatusFailed
+	if c.Check.Warning {
+		failed = StatusWarning
+	}
+	fail := func(summary string, details ...string) Result {
+		result := Result{Status: failed, Summary: summary, Details: details}
+		if c.Check.Message != "" {
+			result.Fixes = []string{c.Check.Message}
+		}
+		return result
+	}
+
+	timeout := time.Duration(c.Check.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = configservice.DefaultDoctorCheckTimeoutSeconds * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The check sees the devrig-managed tools, like the commands of devrig exec
+	environ := os.Environ()
+	var pathDirs []string
+	if resolved, err := execcmd.ResolveEnvironment(env.ConfigPath, runtime.GOOS); err == nil {
+		environ = resolved.Apply(environ, runtime.GOOS)
+		pathDirs = resolved.PathDirs
+	}
+	command := c.Check.Command
+	child := exec.CommandContext(ctx, findCommand(command[0], pathDirs), command[1:]...)
+	child.Env = environ
+	child.Dir = filepath.Dir(env.ConfigPath)
+	var output bytes.Buffer
+	child.Stdout = &output
+	child.Stderr = &output
+
+	exitCode := 0
+	err := child.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		return fail(fmt.Sprintf("%s did not finish in %s", command[0], timeout))
+	case errors.As(err, &exitErr):
+		exitCode = exitErr.ExitCode()
+	case err != nil:
+		return fail(fmt.Sprintf("failed to run %s: %v", command[0], err))
+	}
+
+	if exitCode != c.Check.ExpectedExitCode {
+		return fail(fmt.Sprintf("%s exited with code %d, expected %d", command[0], exitCode, c.Check.ExpectedExitCode), outputDetails(output.String())...)
+	}
+	if c.Check.ExpectedOutput != "" {
+		// The pattern is validated when devrig.yaml is read
+		pattern := regexp.MustCompile(c.Check.ExpectedOutput)
+		if !pattern.MatchString(output.String()) {
+			return fail(fmt.Sprintf("the output of %s does not match %s", command[0], c.Check.ExpectedOutput), outputDetails(output.String())...)
+		}
+	}
+	return Result{Status: StatusOK, Summary: strings.Join(command, " ") + " passed"}
+}
+
+// findCommand returns the executable of the devrig-managed tools, other commands are found on PATH when they run
+func findCommand(name string, pathDirs []string) string {
+	if strings.ContainsAny(name, `/\`) {
+		return name
+	}
+	for _, dir := range pathDirs {
+		for _, candidate := range []string{name, name + ".exe", name + ".cmd"} {
+			path := filepath.Join(dir, candidate)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path
+			}
+		}
+	}
+	return name
+}
+
+// outputDetails returns the last lines of the command output
+func outputDetails(output string) []string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	if len(lines) > maxOutputDetails {
+		lines = lines[len(lines)-maxOutputDetails:]
+	}
+	return lines
+}
+
+// brokenSectionCheck reports the error of the doctor section of devrig.yaml
+type brokenSectionCheck struct {
+	err error
+}
+
+func (c *brokenSectionCheck) Name() string {
+	return "custom checks"
+}
+
+func (c *brokenSectionCheck) Run(_ context.Context, _ Environment) Result {
+	return Result{Status: StatusFailed, Summary: c.err.Error(), Fixes: []string{"Fix the doctor section of devrig.yaml"}}
+}
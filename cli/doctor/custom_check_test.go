@@ -0,0 +1,83 @@
+package doctor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// newCustomChecksEnvironment writes devrig.yaml with the doctor section
+func newCustomChecksEnvironment(t *testing.T, doctor string) Environment {
+	t.Helper()
+	projectDir := t.TempDir()
+	configPath := filepath.Join(projectDir, "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte(doctor), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	return NewEnvironment(configPath)
+}
+
+func TestCustomCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses POSIX commands")
+	}
+
+	env := newCustomChecksEnvironment(t, `doctor:
+  checks:
+    - name: vpn
+      command: ["sh", "-c", "echo connected to corp"]
+      expected_output: "connected to \\w+"
+    - name: certificate
+      command: ["sh", "-c", "echo missing; exit 3"]
+      message: Install the certificate from https://wiki.example.com/certs
+    - name: exit code
+      command: ["sh", "-c", "exit 2"]
+      expected_exit_code: 2
+    - name: output
+      command: ["sh", "-c", "echo disconnected"]
+      expected_output: "^connected"
+      warning: true
+    - name: timeout
+      command: ["sleep", "5"]
+      timeout_seconds: 1
+`)
+	checks := WithCustomChecks(env, []Check{&ConfigCheck{}})
+	if len(checks) != 6 {
+		t.Fatalf("Expected the built-in and 5 custom checks, got %d", len(checks))
+	}
+
+	expected := map[string]Status{"vpn": StatusOK, "certificate": StatusFailed, "exit code": StatusOK, "output": StatusWarning, "timeout": StatusFailed}
+	for _, check := range checks[1:] {
+		result := check.Run(context.Background(), env)
+		if result.Status != expected[check.Name()] {
+			t.Errorf("Expected %s for %s, got %+v", expected[check.Name()], check.Name(), result)
+		}
+		if check.Name() == "certificate" {
+			if !strings.Contains(result.Summary, "exited with code 3") || len(result.Details) != 1 || result.Details[0] != "missing" {
+				t.Errorf("Expected the exit code and the output, got %+v", result)
+			}
+			if len(result.Fixes) != 1 || !strings.Contains(result.Fixes[0], "wiki.example.com") {
+				t.Errorf("Expected the message as the fix, got %v", result.Fixes)
+			}
+		}
+	}
+}
+
+func TestWithCustomChecks_BrokenSection(t *testing.T) {
+	env := newCustomChecksEnvironment(t, "doctor:\n  checks:\n    - name: vpn\n      command: [ping]\n      expected_output: \"(\"\n")
+	checks := WithCustomChecks(env, nil)
+	if len(checks) != 1 {
+		t.Fatalf("Expected a single check for the broken section, got %d", len(checks))
+	}
+	if result := checks[0].Run(context.Background(), env); result.Status != StatusFailed || !strings.Contains(result.Summary, "expected_output") {
+		t.Errorf("Expected the broken section to fail, got %+v", result)
+	}
+
+	missing := NewEnvironment(filepath.Join(t.TempDir(), "devrig.yaml"))
+	if checks := WithCustomChecks(missing, []Check{&ConfigCheck{}}); len(checks) != 1 {
+		t.Errorf("Expected only the built-in checks without devrig.yaml, got %d", len(checks))
+	}
+}
@@ -0,0 +1,96 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// DefenderCheck detects Windows Defender settings that slow down or block writes to the devrig cache
+type DefenderCheck struct {
+	// Query overrides reading of the Defender preferences, used in tests
+	Query func(ctx context.Context, property string) (string, error)
+	// GOOS overrides the operating system, used in tests
+	GOOS string
+}
+
+func (c *DefenderCheck) Name() string {
+	return "Windows Defender"
+}
+
+func (c *DefenderCheck) Run(ctx context.Context, env Environment) Result {
+	goos := c.GOOS
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goos != "windows" {
+		return Result{Status: StatusSkipped, Summary: "only relevant on Windows"}
+	}
+
+	query := c.Query
+	if query == nil {
+		query = queryDefenderPreference
+	}
+
+	var result Result
+
+	// Controlled Folder Access blocks unknown applications from writing to protected folders
+	cfa, err := query(ctx, "(Get-MpPreference).EnableControlledFolderAccess")
+	if err != nil {
+		return Result{Status: StatusSkipped, Summary: fmt.Sprintf("cannot read Defender preferences: %v", err)}
+	}
+	if cfa == "1" {
+		if err := probeWrite(env.DevrigHome); err != nil {
+			exe, _ := os.Executable()
+			return Result{
+				Status:  StatusFailed,
+				Summary: "Controlled Folder Access blocks writes to " + env.DevrigHome,
+				Details: []string{err.Error()},
+				Fixes: []string{
+					fmt.Sprintf("Add-MpPreference -ControlledFolderAccessAllowedApplications \"%s\"", exe),
+				},
+			}
+		}
+		result.Details = append(result.Details, "Controlled Folder Access is enabled, writes to "+env.DevrigHome+" are allowed")
+	}
+
+	// Real-time scanning of every unpacked file slows down IDE and toolchain installation
+	realtime, err := query(ctx, "(Get-MpComputerStatus).RealTimeProtectionEnabled")
+	if err == nil && strings.EqualFold(realtime, "true") {
+		result.Status = StatusWarning
+		result.Summary = "real-time protection scans every file devrig unpacks, installations may be slow"
+		result.Fixes = append(result.Fixes,
+			fmt.Sprintf("Consider excluding the devrig cache (requires administrator): Add-MpPreference -ExclusionPath \"%s\"", env.DevrigHome))
+		return result
+	}
+
+	result.Status = StatusOK
+	result.Summary = "no Defender settings affect " + env.DevrigHome
+	return result
+}
+
+// queryDefenderPreference evaluates a PowerShell expression reading the Defender configuration
+func queryDefenderPreference(ctx context.Context, expression string) (string, error) {
+	output, err := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", expression).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// probeWrite checks that a file can be created in the directory
+func probeWrite(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	file, err := os.CreateTemp(dir, ".devrig-write-probe-*")
+	if err != nil {
+		return fmt.Errorf("failed to write to %s: %w", dir, err)
+	}
+	name := file.Name()
+	_ = file.Close()
+	return os.Remove(name)
+}
@@ -0,0 +1,40 @@
+package doctor
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefenderCheck_RealTimeProtectionWarning(t *testing.T) {
+	devrigHome := filepath.Join(t.TempDir(), ".devrig")
+	check := &DefenderCheck{
+		GOOS: "windows",
+		Query: func(_ context.Context, expression string) (string, error) {
+			if strings.Contains(expression, "EnableControlledFolderAccess") {
+				return "1", nil
+			}
+			return "True", nil
+		},
+	}
+
+	result := check.Run(context.Background(), Environment{DevrigHome: devrigHome})
+	if result.Status != StatusWarning {
+		t.Fatalf("Expected warning, got %s: %s", result.Status, result.Summary)
+	}
+	if len(result.Fixes) != 1 || !strings.Contains(result.Fixes[0], "-ExclusionPath \""+devrigHome+"\"") {
+		t.Errorf("Expected exclusion fix for the cache path, got: %v", result.Fixes)
+	}
+	if len(result.Details) != 1 || !strings.Contains(result.Details[0], "Controlled Folder Access") {
+		t.Errorf("Expected Controlled Folder Access details, got: %v", result.Details)
+	}
+}
+
+func TestDefenderCheck_SkippedOutsideWindows(t *testing.T) {
+	check := &DefenderCheck{GOOS: "linux"}
+	result := check.Run(context.Background(), Environment{})
+	if result.Status != StatusSkipped {
+		t.Errorf("Expected skip, got %s", result.Status)
+	}
+}
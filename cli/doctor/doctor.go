@@ -0,0 +1,147 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+// Status is the outcome of a single check
+type Status string
+
+const (
+	StatusOK      Status = "OK"
+	StatusWarning Status = "WARN"
+	StatusFailed  Status = "FAIL"
+	StatusSkipped Status = "SKIP"
+)
+
+// Result is the report of a single check
+type Result struct {
+	Status  Status
+	Summary string
+	// Details lists the individual observations behind the summary
+	Details []string
+	// Fixes lists the exact actions the user can take to resolve the problem
+	Fixes []string
+	// Actions resolve the problem automatically, they run with `devrig doctor --fix`
+	Actions []Action
+}
+
+// Action is a fix of a check that devrig applies itself
+type Action struct {
+	// Description tells what the action changes, e.g. pin the darwin-arm64 devrig binary in devrig.yaml
+	Description string
+	Apply       func(ctx context.Context) error
+}
+
+// Environment gives checks access to the project being diagnosed
+type Environment struct {
+	ConfigPath string
+	DevrigHome string
+	Configs    configservice.ConfigService
+}
+
+// Check is a single diagnostic executed by `devrig doctor`
+type Check interface {
+	// Name returns a short human-readable name of the check
+	Name() string
+	// Run executes the check
+	Run(ctx context.Context, env Environment) Result
+}
+
+// NewDoctorCommand creates the doctor command running the given checks
+func NewDoctorCommand(configPath func() string, checks []Check) *cobra.Command {
+	var fix bool
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose problems with the devrig environment",
+		Long: `Run diagnostics of the devrig environment and print exact steps to fix the found problems.
+
+The checks of the doctor section of devrig.yaml run after the built-in ones,
+they encode the requirements of the team, e.g. a VPN connection or a certificate.
+With --fix, the fixes devrig can apply itself are executed, e.g. the pins of devrig.yaml
+are switched to native arm64 builds on Apple Silicon Macs.
+The command exits with an error if any of the checks fails.
+Run 'devrig doctor network' to diagnose connectivity problems.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env := NewEnvironment(configPath())
+			all := WithCustomChecks(env, checks)
+			failed, fixFailed := runChecks(cmd.Context(), env, all, fix, cmd.OutOrStdout())
+			if failed > 0 {
+				return fmt.Errorf("%d of %d checks failed", failed, len(all))
+			}
+			if fixFailed > 0 {
+				return fmt.Errorf("%d fixes failed", fixFailed)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&fix, "fix", false, "Apply the fixes devrig can make itself")
+	cmd.AddCommand(newNetworkCommand(configPath))
+	return cmd
+}
+
+// NewEnvironment creates the check environment for the given devrig.yaml path
+func NewEnvironment(configPath string) Environment {
+	return Environment{
+		ConfigPath: configPath,
+		DevrigHome: layout.ResolveDevrigHome(configPath),
+		Configs:    configservice.NewConfigService(configPath),
+	}
+}
+
+// RunChecks executes the checks, prints the report to out and returns the number of failed checks
+func RunChecks(ctx context.Context, env Environment, checks []Check, out io.Writer) int {
+	failed, _ := runChecks(ctx, env, checks, false, out)
+	return failed
+}
+
+// runChecks executes the checks and applies their actions if fix is set,
+// it returns the number of failed checks and the number of failed actions
+func runChecks(ctx context.Context, env Environment, checks []Check, fix bool, out io.Writer) (int, int) {
+	failed, fixFailed := 0, 0
+	for _, check := range checks {
+		result := check.Run(ctx, env)
+		printResult(out, check.Name(), result, fix)
+		if result.Status == StatusFailed {
+			failed++
+		}
+		if !fix {
+			continue
+		}
+		for _, action := range result.Actions {
+			if err := action.Apply(ctx); err != nil {
+				fmt.Fprintf(out, "         Failed to %s: %v\n", action.Description, err)
+				fixFailed++
+				continue
+			}
+			fmt.Fprintf(out, "         Fixed: %s\n", action.Description)
+		}
+	}
+	return failed, fixFailed
+}
+
+func printResult(out io.Writer, name string, result Result, fix bool) {
+	fmt.Fprintf(out, "[%-4s] %s: %s\n", result.Status, name, result.Summary)
+	for _, detail := range result.Details {
+		fmt.Fprintf(out, "         - %s\n", detail)
+	}
+	if len(result.Fixes) > 0 || (len(result.Actions) > 0 && !fix) {
+		fmt.Fprintln(out, "         To fix:")
+		for _, fix := range result.Fixes {
+			fmt.Fprintf(out, "           %s\n", strings.ReplaceAll(fix, "\n", "\n           "))
+		}
+		if !fix {
+			for _, action := range result.Actions {
+				fmt.Fprintf(out, "           Run 'devrig doctor --fix' to %s\n", action.Description)
+			}
+		}
+	}
+}
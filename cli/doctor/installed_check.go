@@ -0,0 +1,53 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/state"
+)
+
+// InstalledCheck compares the artifacts recorded in .devrig/state.json with the disk, an IDE or a tool
+// removed by hand is installed again by the next command that needs it
+type InstalledCheck struct{}
+
+func (c *InstalledCheck) Name() string {
+	return "installed artifacts"
+}
+
+func (c *InstalledCheck) Run(_ context.Context, env Environment) Result {
+	installed, err := state.Load(env.DevrigHome)
+	if err != nil {
+		return Result{Status: StatusFailed, Summary: err.Error(), Fixes: []string{"Remove " + state.FileName + " from " + env.DevrigHome + ", it is written again on the next installation"}}
+	}
+	if len(installed.Artifacts) == 0 {
+		return Result{Status: StatusSkipped, Summary: "nothing is recorded in " + state.FileName + " yet"}
+	}
+
+	missing := installed.Missing()
+	if len(missing) == 0 {
+		return Result{Status: StatusOK, Summary: fmt.Sprintf("%d artifacts are installed", len(installed.Artifacts))}
+	}
+	result := Result{
+		Status:  StatusWarning,
+		Summary: fmt.Sprintf("%d of %d recorded artifacts are missing", len(missing), len(installed.Artifacts)),
+	}
+	for _, artifact := range missing {
+		result.Details = append(result.Details, fmt.Sprintf("%s %s %s is missing at %s", artifact.Type, artifact.Name, artifact.Version, artifact.Path))
+		result.Fixes = append(result.Fixes, reinstallCommand(artifact))
+	}
+	result.Fixes = append(result.Fixes, "devrig cache gc # or forget the missing artifacts")
+	return result
+}
+
+// reinstallCommand returns the command installing the artifact again
+func reinstallCommand(artifact state.Artifact) string {
+	if artifact.Type == state.TypeIde {
+		return fmt.Sprintf("devrig ide install %q --build %s", artifact.Name, artifact.Version)
+	}
+	if vendor, ok := strings.CutPrefix(artifact.Name, "jdk-"); ok {
+		return fmt.Sprintf("devrig install jdk --vendor %s --version %s", vendor, artifact.Version)
+	}
+	return fmt.Sprintf("devrig install %s --version %s", artifact.Name, artifact.Version)
+}
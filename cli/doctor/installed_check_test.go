@@ -0,0 +1,37 @@
+package doctor
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/state"
+)
+
+func TestInstalledCheck(t *testing.T) {
+	env := Environment{DevrigHome: t.TempDir()}
+	if result := (&InstalledCheck{}).Run(context.Background(), env); result.Status != StatusSkipped {
+		t.Errorf("Expected %s without %s, got %s: %s", StatusSkipped, state.FileName, result.Status, result.Summary)
+	}
+
+	ctx := context.Background()
+	artifacts := []state.Artifact{
+		{Type: state.TypeIde, Name: "GoLand", Version: "243.1", Path: t.TempDir()},
+		{Type: state.TypeTool, Name: "jdk-temurin", Version: "21.0.5+11", Path: filepath.Join(env.DevrigHome, "tools", "jdk-temurin-21.0.5_11")},
+	}
+	for _, artifact := range artifacts {
+		if err := state.Record(ctx, env.DevrigHome, artifact, time.Now()); err != nil {
+			t.Fatalf("Failed to record %s: %v", artifact.Name, err)
+		}
+	}
+
+	result := (&InstalledCheck{}).Run(ctx, env)
+	if result.Status != StatusWarning || len(result.Details) != 1 {
+		t.Fatalf("Expected the missing JDK to be reported, got %s: %s %v", result.Status, result.Summary, result.Details)
+	}
+	if len(result.Fixes) == 0 || !strings.Contains(result.Fixes[0], "devrig install jdk --vendor temurin --version 21.0.5+11") {
+		t.Errorf("Expected the command to install the JDK again, got %v", result.Fixes)
+	}
+}
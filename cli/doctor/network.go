@@ -0,0 +1,335 @@
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/feed"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// FailureKind tells at which stage a network probe failed
+type FailureKind string
+
+const (
+	FailureNone  FailureKind = ""
+	FailureDNS   FailureKind = "DNS"
+	FailureTCP   FailureKind = "TCP"
+	FailureTLS   FailureKind = "TLS"
+	FailureProxy FailureKind = "PROXY"
+	FailureHTTP  FailureKind = "HTTP"
+)
+
+// defaultProbeTimeout limits every stage of a probe, so a blackholed host does not hang the doctor
+const defaultProbeTimeout = 10 * time.Second
+
+// NetworkTarget is a remote endpoint devrig downloads from
+type NetworkTarget struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// ProbeResult is the outcome of probing a single target
+type ProbeResult struct {
+	Target NetworkTarget `json:"target"`
+	Host   string        `json:"host"`
+	// Proxy is the proxy used for the target, empty for direct connections
+	Proxy     string        `json:"proxy,omitempty"`
+	Addresses []string      `json:"addresses,omitempty"`
+	DNS       time.Duration `json:"dns_ns,omitempty"`
+	Connect   time.Duration `json:"connect_ns,omitempty"`
+	TLS       time.Duration `json:"tls_ns,omitempty"`
+	HTTP      time.Duration `json:"http_ns,omitempty"`
+	// Chain lists the certificates presented by the server, leaf first
+	Chain      []string    `json:"chain,omitempty"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Failure    FailureKind `json:"failure,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	// Hint explains the typical cause of the failure
+	Hint string `json:"hint,omitempty"`
+}
+
+// OK checks if the target is reachable
+func (r *ProbeResult) OK() bool {
+	return r.Failure == FailureNone
+}
+
+// NetworkProbe checks the reachability of the targets stage by stage: DNS, TCP, TLS and HTTP
+type NetworkProbe struct {
+	// Lookup resolves the host, net.DefaultResolver by default
+	Lookup func(ctx context.Context, host string) ([]string, error)
+	// Proxy returns the proxy of the request, http.ProxyFromEnvironment by default
+	Proxy func(req *http.Request) (*url.URL, error)
+	// TLSConfig is used for the TLS handshake, the system roots by default
+	TLSConfig *tls.Config
+	// Client sends the HTTP request, a client with the default transport and Timeout by default
+	Client  *http.Client
+	Timeout time.Duration
+}
+
+// ResolveNetworkTargets lists devrig.dev, the configured mirrors, GitHub and the JetBrains feed hosts,
+// one target per host
+func ResolveNetworkTargets(env Environment) []NetworkTarget {
+	latest := updates.DefaultEndpoints.LatestURL
+	if env.Configs != nil {
+		if endpoints, err := updates.ResolveEndpoints(env.Configs); err == nil {
+			latest = endpoints.LatestURL
+		}
+	}
+	targets := []NetworkTarget{{Name: "devrig updates", URL: latest}}
+
+	if env.Configs != nil {
+		if section, err := env.Configs.Binaries().ReadDevrigSection(); err == nil {
+			targets = append(targets, binaryTargets(section)...)
+		}
+	}
+
+	targets = append(targets,
+		NetworkTarget{Name: "GitHub", URL: "https://github.com/"},
+		NetworkTarget{Name: "GitHub API", URL: "https://api.github.com/"},
+	)
+	for _, feedURL := range feed.FeedURLs() {
+		targets = append(targets, NetworkTarget{Name: "JetBrains feed", URL: feedURL})
+	}
+
+	// Every host is probed once, the first target wins
+	seen := map[string]bool{}
+	var unique []NetworkTarget
+	for _, target := range targets {
+		parsed, err := url.Parse(target.URL)
+		if err != nil || seen[parsed.Host] {
+			continue
+		}
+		seen[parsed.Host] = true
+		unique = append(unique, target)
+	}
+	return unique
+}
+
+// binaryTargets returns the download URLs and mirrors of devrig binaries in a stable order
+func binaryTargets(section *configservice.DevrigSection) []NetworkTarget {
+	platforms := make([]string, 0, len(section.Binaries))
+	for platform := range section.Binaries {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+
+	var targets []NetworkTarget
+	for _, platform := range platforms {
+		binary := section.Binaries[platform]
+		for i, binaryURL := range binary.URLs() {
+			name := "devrig binaries"
+			if i > 0 {
+				name = "mirror"
+			}
+			targets = append(targets, NetworkTarget{Name: name, URL: binaryURL})
+		}
+	}
+	return targets
+}
+
+// Probe checks the target and reports the first failed stage
+func (p *NetworkProbe) Probe(ctx context.Context, target NetworkTarget) ProbeResult {
+	result := ProbeResult{Target: target}
+
+	parsed, err := url.Parse(target.URL)
+	if err != nil {
+		return p.fail(result, FailureHTTP, fmt.Errorf("invalid URL: %w", err), "")
+	}
+	result.Host = parsed.Hostname()
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", target.URL, nil)
+	if err != nil {
+		return p.fail(result, FailureHTTP, err, "")
+	}
+
+	proxy, err := p.proxy()(req)
+	if err != nil {
+		return p.fail(result, FailureProxy, err, "check the HTTPS_PROXY and NO_PROXY environment variables")
+	}
+
+	// Behind a proxy the host is resolved and connected by the proxy, only the HTTP stage is meaningful
+	if proxy != nil {
+		result.Proxy = proxy.Redacted()
+	} else {
+		if !p.probeConnection(ctx, parsed, &result) {
+			return result
+		}
+	}
+
+	p.probeHTTP(req, &result)
+	return result
+}
+
+// probeConnection runs the DNS, TCP and TLS stages, returns false on a failure
+func (p *NetworkProbe) probeConnection(ctx context.Context, parsed *url.URL, result *ProbeResult) bool {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout())
+	defer cancel()
+
+	start := time.Now()
+	addresses, err := p.lookup()(ctx, result.Host)
+	result.DNS = time.Since(start)
+	if err != nil {
+		*result = p.fail(*result, FailureDNS, err, "check the DNS servers of the machine or the VPN connection")
+		return false
+	}
+	result.Addresses = addresses
+
+	port := parsed.Port()
+	if port == "" {
+		port = "443"
+		if parsed.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	start = time.Now()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(result.Host, port))
+	result.Connect = time.Since(start)
+	if err != nil {
+		*result = p.fail(*result, FailureTCP, err, "a firewall may block outgoing connections, set HTTPS_PROXY if a proxy is required")
+		return false
+	}
+	defer func() { _ = conn.Close() }()
+
+	if parsed.Scheme != "https" {
+		return true
+	}
+
+	config := &tls.Config{}
+	if p.TLSConfig != nil {
+		config = p.TLSConfig.Clone()
+	}
+	config.ServerName = result.Host
+
+	start = time.Now()
+	tlsConn := tls.Client(conn, config)
+	_ = tlsConn.SetDeadline(time.Now().Add(p.timeout()))
+	err = tlsConn.HandshakeContext(ctx)
+	result.TLS = time.Since(start)
+
+	// The chain is reported even when it is not trusted, it shows which proxy intercepts the traffic
+	chain := tlsConn.ConnectionState().PeerCertificates
+	var verificationErr *tls.CertificateVerificationError
+	if errors.As(err, &verificationErr) {
+		chain = verificationErr.UnverifiedCertificates
+	}
+	for _, cert := range chain {
+		result.Chain = append(result.Chain, describeCertificate(cert))
+	}
+	if err != nil {
+		var unknownAuthority x509.UnknownAuthorityError
+		var invalid x509.CertificateInvalidError
+		hint := ""
+		switch {
+		case errors.As(err, &unknownAuthority):
+			hint = "a TLS-intercepting proxy may be in the way, add its CA certificate to the system trust store or set SSL_CERT_FILE"
+		case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+			hint = "the certificate is expired or not yet valid at the time of the system clock, check the date and time of the machine"
+		}
+		*result = p.fail(*result, FailureTLS, err, hint)
+		return false
+	}
+	return true
+}
+
+// probeHTTP sends the request, a HEAD rejected by the server is retried with GET
+func (p *NetworkProbe) probeHTTP(req *http.Request, result *ProbeResult) {
+	client := p.client()
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err == nil && resp.StatusCode == http.StatusMethodNotAllowed {
+		_ = resp.Body.Close()
+		get := req.Clone(req.Context())
+		get.Method = "GET"
+		get.Header.Set("Range", "bytes=0-0")
+		resp, err = client.Do(get)
+	}
+	result.HTTP = time.Since(start)
+
+	if err != nil {
+		kind := FailureHTTP
+		hint := ""
+		if result.Proxy != "" {
+			kind = FailureProxy
+			hint = "check that the proxy " + result.Proxy + " is reachable and allows the host"
+		}
+		*result = p.fail(*result, kind, err, hint)
+		return
+	}
+	_ = resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 400 {
+		hint := ""
+		switch resp.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			hint = "the host requires authentication, see `devrig auth login`"
+		case http.StatusProxyAuthRequired:
+			hint = "the proxy requires credentials, add them to HTTPS_PROXY"
+		}
+		*result = p.fail(*result, FailureHTTP, fmt.Errorf("unexpected status code: %d", resp.StatusCode), hint)
+	}
+}
+
+func (p *NetworkProbe) fail(result ProbeResult, kind FailureKind, err error, hint string) ProbeResult {
+	result.Failure = kind
+	result.Error = err.Error()
+	result.Hint = hint
+	return result
+}
+
+func (p *NetworkProbe) lookup() func(ctx context.Context, host string) ([]string, error) {
+	if p.Lookup != nil {
+		return p.Lookup
+	}
+	return net.DefaultResolver.LookupHost
+}
+
+func (p *NetworkProbe) proxy() func(req *http.Request) (*url.URL, error) {
+	if p.Proxy != nil {
+		return p.Proxy
+	}
+	return http.ProxyFromEnvironment
+}
+
+func (p *NetworkProbe) client() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return &http.Client{Timeout: p.timeout()}
+}
+
+func (p *NetworkProbe) timeout() time.Duration {
+	if p.Timeout > 0 {
+		return p.Timeout
+	}
+	return defaultProbeTimeout
+}
+
+// describeCertificate returns the subject, issuer and expiry of the certificate
+func describeCertificate(cert *x509.Certificate) string {
+	subject := cert.Subject.CommonName
+	if subject == "" && len(cert.DNSNames) > 0 {
+		subject = cert.DNSNames[0]
+	}
+	if subject == "" {
+		subject = cert.Subject.String()
+	}
+	issuer := cert.Issuer.CommonName
+	if issuer == "" {
+		issuer = strings.Join(cert.Issuer.Organization, ", ")
+	}
+	return fmt.Sprintf("%s (issued by %s, expires %s)", subject, issuer, cert.NotAfter.Format("2006-01-02"))
+}
@@ -0,0 +1,115 @@
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/output"
+)
+
+func newNetworkCommand(configPath func() string) *cobra.Command {
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "network",
+		Short: "Check connectivity to devrig.dev, mirrors, GitHub and JetBrains",
+		Long: `Probe the hosts devrig downloads from: devrig.dev, the mirrors of devrig binaries
+from devrig.yaml, GitHub and the JetBrains feed hosts.
+
+Every host is checked stage by stage (DNS, TCP, TLS, HTTP), so the table shows
+where the connection fails. Proxies from HTTPS_PROXY and NO_PROXY are respected,
+the DNS, TCP and TLS stages are done by the proxy for proxied hosts.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if offline.Enabled() {
+				cmd.Println("Offline mode is enabled, network checks are skipped")
+				return nil
+			}
+
+			probe := &NetworkProbe{Timeout: timeout}
+			var results []ProbeResult
+			for _, target := range ResolveNetworkTargets(NewEnvironment(configPath())) {
+				results = append(results, probe.Probe(cmd.Context(), target))
+			}
+
+			if output.FormatFromContext(cmd.Context()) == output.FormatJSON {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(struct {
+					Hosts []ProbeResult `json:"hosts"`
+				}{results}); err != nil {
+					return fmt.Errorf("failed to write report: %w", err)
+				}
+			} else {
+				printNetworkReport(cmd.OutOrStdout(), results)
+			}
+
+			failed := 0
+			for _, result := range results {
+				if !result.OK() {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d hosts are unreachable", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&timeout, "timeout", defaultProbeTimeout, "Timeout of every stage of a probe")
+	return cmd
+}
+
+// printNetworkReport prints the table of probes followed by the details of the failures
+func printNetworkReport(out io.Writer, results []ProbeResult) {
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "STATUS\tTARGET\tHOST\tDNS\tTCP\tTLS\tHTTP\tVIA")
+	for _, result := range results {
+		status := string(StatusOK)
+		if !result.OK() {
+			status = string(StatusFailed) + " " + string(result.Failure)
+		}
+		via := "direct"
+		if result.Proxy != "" {
+			via = result.Proxy
+		}
+		_, _ = fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", status, result.Target.Name, result.Host,
+			formatStage(result.DNS), formatStage(result.Connect), formatStage(result.TLS), formatHTTP(result), via)
+	}
+	_ = writer.Flush()
+
+	for _, result := range results {
+		if result.OK() {
+			continue
+		}
+		_, _ = fmt.Fprintf(out, "\n%s (%s): %s failure\n", result.Host, result.Target.URL, result.Failure)
+		_, _ = fmt.Fprintf(out, "  error: %s\n", result.Error)
+		for i, cert := range result.Chain {
+			_, _ = fmt.Fprintf(out, "  certificate #%d: %s\n", i, cert)
+		}
+		if result.Hint != "" {
+			_, _ = fmt.Fprintf(out, "  to fix: %s\n", result.Hint)
+		}
+	}
+}
+
+func formatStage(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
+
+func formatHTTP(result ProbeResult) string {
+	if result.StatusCode == 0 {
+		return formatStage(result.HTTP)
+	}
+	return strings.TrimSpace(fmt.Sprintf("%d %s", result.StatusCode, formatStage(result.HTTP)))
+}
@@ -0,0 +1,219 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+// toolExecutables maps devrig tool names to the executables they put on PATH
+var toolExecutables = map[string][]string{
+	"node":   {"node", "npm", "npx"},
+	"go":     {"go", "gofmt"},
+	"java":   {"java", "javac"},
+	"python": {"python3", "python"},
+	"ruby":   {"ruby", "gem"},
+	"gradle": {"gradle"},
+	"maven":  {"mvn"},
+}
+
+// versionManager describes a PATH entry pattern of another version manager
+type versionManager struct {
+	name    string
+	markers []string
+}
+
+// versionManagers lists well-known tool version managers by the PATH fragments they install
+var versionManagers = []versionManager{
+	{name: "sdkman", markers: []string{".sdkman/candidates"}},
+	{name: "nvm", markers: []string{".nvm/versions"}},
+	{name: "asdf", markers: []string{".asdf/shims", ".asdf/installs"}},
+	{name: "mise", markers: []string{"mise/shims", "mise/installs"}},
+	{name: "volta", markers: []string{".volta/bin"}},
+	{name: "pyenv", markers: []string{".pyenv/shims"}},
+	{name: "rbenv", markers: []string{".rbenv/shims"}},
+	{name: "goenv", markers: []string{".goenv/shims"}},
+	{name: "jenv", markers: []string{".jenv/shims"}},
+}
+
+// PathConflictsCheck detects system tools shadowing devrig-managed ones on PATH and vice versa
+type PathConflictsCheck struct {
+	// PathList overrides the PATH environment variable, used in tests
+	PathList string
+}
+
+func (c *PathConflictsCheck) Name() string {
+	return "PATH conflicts"
+}
+
+func (c *PathConflictsCheck) Run(_ context.Context, env Environment) Result {
+	tools, err := env.Configs.Tools().ReadTools()
+	if err != nil {
+		return Result{Status: StatusSkipped, Summary: fmt.Sprintf("cannot read tools from devrig.yaml: %v", err)}
+	}
+
+	if len(tools) == 0 {
+		return Result{Status: StatusOK, Summary: "no tools are pinned in devrig.yaml"}
+	}
+
+	pathList := c.PathList
+	if pathList == "" {
+		pathList = os.Getenv("PATH")
+	}
+	pathDirs := filepath.SplitList(pathList)
+
+	var result Result
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		toolHome := layout.ResolveToolHome(env.DevrigHome, name, tools[name])
+		if _, err := os.Stat(toolHome); err != nil {
+			// The tool is not provisioned yet, nothing can shadow it
+			continue
+		}
+
+		for _, executable := range executablesOf(name) {
+			matches := findOnPath(pathDirs, executable)
+			if len(matches) == 0 {
+				continue
+			}
+
+			managed := -1
+			for i, match := range matches {
+				if isWithin(match, toolHome) {
+					managed = i
+					break
+				}
+			}
+
+			switch {
+			case managed < 0:
+				result.Details = append(result.Details,
+					fmt.Sprintf("%s resolves to %s, the devrig-managed %s %s is not on PATH", executable, matches[0], name, tools[name]))
+				result.Fixes = append(result.Fixes, prependPathFix(filepath.Join(toolHome, "bin")))
+			case managed > 0:
+				result.Details = append(result.Details,
+					fmt.Sprintf("%s resolves to %s which shadows the devrig-managed %s", executable, matches[0], matches[managed]))
+				result.Fixes = append(result.Fixes, prependPathFix(filepath.Dir(matches[managed])))
+			}
+		}
+	}
+
+	for _, manager := range detectVersionManagers(pathDirs) {
+		result.Details = append(result.Details,
+			fmt.Sprintf("%s is on PATH (%s) and may override devrig-managed tools", manager.name, manager.entry))
+	}
+
+	result.Fixes = unique(result.Fixes)
+	switch {
+	case len(result.Fixes) > 0:
+		result.Status = StatusWarning
+		result.Summary = "some devrig-managed tools are shadowed on PATH"
+	case len(result.Details) > 0:
+		result.Status = StatusWarning
+		result.Summary = "other version managers are active"
+	default:
+		result.Status = StatusOK
+		result.Summary = fmt.Sprintf("%d pinned tools have no PATH conflicts", len(tools))
+	}
+	return result
+}
+
+type detectedVersionManager struct {
+	name  string
+	entry string
+}
+
+// detectVersionManagers returns the version managers found on PATH, in PATH order
+func detectVersionManagers(pathDirs []string) []detectedVersionManager {
+	var result []detectedVersionManager
+	seen := map[string]bool{}
+	for _, dir := range pathDirs {
+		normalized := filepath.ToSlash(dir)
+		for _, manager := range versionManagers {
+			if seen[manager.name] {
+				continue
+			}
+			for _, marker := range manager.markers {
+				if strings.Contains(normalized, marker) {
+					seen[manager.name] = true
+					result = append(result, detectedVersionManager{name: manager.name, entry: dir})
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+func executablesOf(tool string) []string {
+	if executables, ok := toolExecutables[tool]; ok {
+		return executables
+	}
+	return []string{tool}
+}
+
+// findOnPath returns all matches of the executable in PATH order
+func findOnPath(pathDirs []string, executable string) []string {
+	candidates := []string{executable}
+	if runtime.GOOS == "windows" {
+		candidates = nil
+		for _, ext := range []string{".exe", ".cmd", ".bat"} {
+			candidates = append(candidates, executable+ext)
+		}
+	}
+
+	var matches []string
+	for _, dir := range pathDirs {
+		if dir == "" {
+			continue
+		}
+		for _, candidate := range candidates {
+			path := filepath.Join(dir, candidate)
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+				continue
+			}
+			matches = append(matches, path)
+			break
+		}
+	}
+	return matches
+}
+
+func isWithin(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func prependPathFix(dir string) string {
+	if runtime.GOOS == "windows" {
+		return fmt.Sprintf(`$env:PATH = "%s;" + $env:PATH`, dir)
+	}
+	return fmt.Sprintf(`export PATH="%s:$PATH"`, dir)
+}
+
+func unique(values []string) []string {
+	seen := map[string]bool{}
+	var result []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package doctor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+func writeExecutable(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write executable: %v", err)
+	}
+}
+
+func newTestEnvironment(t *testing.T, tools configservice.ToolsSection) Environment {
+	t.Helper()
+	projectDir := t.TempDir()
+	configPath := filepath.Join(projectDir, "devrig.yaml")
+	configs := configservice.NewConfigService(configPath)
+	if err := configs.Tools().UpdateTools(tools); err != nil {
+		t.Fatalf("Failed to write tools: %v", err)
+	}
+	return Environment{
+		ConfigPath: configPath,
+		DevrigHome: filepath.Join(projectDir, ".devrig"),
+		Configs:    configs,
+	}
+}
+
+func TestPathConflictsCheck_SystemToolShadowsManaged(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses POSIX executables")
+	}
+
+	env := newTestEnvironment(t, configservice.ToolsSection{"node": "20.11.0"})
+	managedBin := filepath.Join(layout.ResolveToolHome(env.DevrigHome, "node", "20.11.0"), "bin")
+	systemBin := filepath.Join(t.TempDir(), "usr", "bin")
+	writeExecutable(t, filepath.Join(managedBin, "node"))
+	writeExecutable(t, filepath.Join(systemBin, "node"))
+
+	check := &PathConflictsCheck{PathList: strings.Join([]string{systemBin, managedBin}, string(os.PathListSeparator))}
+	result := check.Run(context.Background(), env)
+
+	if result.Status != StatusWarning {
+		t.Fatalf("Expected warning, got: %+v", result)
+	}
+	if len(result.Fixes) != 1 || !strings.Contains(result.Fixes[0], managedBin) {
+		t.Errorf("Expected fix to prepend %s, got: %v", managedBin, result.Fixes)
+	}
+}
+
+func TestPathConflictsCheck_ManagedFirst(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses POSIX executables")
+	}
+
+	env := newTestEnvironment(t, configservice.ToolsSection{"go": "1.22.1"})
+	managedBin := filepath.Join(layout.ResolveToolHome(env.DevrigHome, "go", "1.22.1"), "bin")
+	systemBin := filepath.Join(t.TempDir(), "usr", "bin")
+	writeExecutable(t, filepath.Join(managedBin, "go"))
+	writeExecutable(t, filepath.Join(systemBin, "go"))
+
+	check := &PathConflictsCheck{PathList: strings.Join([]string{managedBin, systemBin}, string(os.PathListSeparator))}
+	result := check.Run(context.Background(), env)
+
+	if result.Status != StatusOK {
+		t.Errorf("Expected OK, got: %+v", result)
+	}
+}
+
+func TestPathConflictsCheck_DetectsVersionManagers(t *testing.T) {
+	env := newTestEnvironment(t, configservice.ToolsSection{"java": "21"})
+
+	pathList := strings.Join([]string{"/home/user/.sdkman/candidates/java/current/bin", "/home/user/.nvm/versions/node/v20/bin"}, string(os.PathListSeparator))
+	result := (&PathConflictsCheck{PathList: pathList}).Run(context.Background(), env)
+
+	if result.Status != StatusWarning {
+		t.Fatalf("Expected warning, got: %+v", result)
+	}
+
+	details := strings.Join(result.Details, "\n")
+	if !strings.Contains(details, "sdkman") || !strings.Contains(details, "nvm") {
+		t.Errorf("Expected sdkman and nvm to be reported, got: %s", details)
+	}
+}
@@ -0,0 +1,258 @@
+package doctor
+
+import (
+	"context"
+	"debug/macho"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/lock"
+	"jonnyzzz.com/devrig.dev/lockcmd"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// nativePlatform is the platform of Apple Silicon Macs in devrig.yaml and devrig.lock
+const nativePlatform = "darwin-arm64"
+
+// ReleaseFetcher downloads and verifies the release metadata
+type ReleaseFetcher interface {
+	FetchUpdateInfo(url string) (*updates.UpdateInfo, error)
+}
+
+// RosettaCheck detects x86_64 binaries running under Rosetta 2 on Apple Silicon Macs
+type RosettaCheck struct {
+	// Detect overrides the host detection, used in tests
+	Detect func() (appleSilicon bool, translated bool)
+	// Fetcher downloads the release metadata with the darwin-arm64 devrig binary, there is no fix action without it
+	Fetcher ReleaseFetcher
+	// Pins overrides lockcmd.ProjectPins, used in tests
+	Pins func(configPath string) ([]lockcmd.Pin, error)
+}
+
+func (c *RosettaCheck) Name() string {
+	return "Rosetta"
+}
+
+func (c *RosettaCheck) Run(_ context.Context, env Environment) Result {
+	detect := c.Detect
+	if detect == nil {
+		if runtime.GOOS != "darwin" {
+			return Result{Status: StatusSkipped, Summary: "only relevant on macOS"}
+		}
+		detect = detectRosetta
+	}
+
+	appleSilicon, translated := detect()
+	if !appleSilicon {
+		return Result{Status: StatusOK, Summary: "not an Apple Silicon Mac"}
+	}
+
+	var result Result
+	if translated {
+		result.Details = append(result.Details, "devrig runs as x86_64 under Rosetta")
+		section, err := env.Configs.Binaries().ReadDevrigSection()
+		switch {
+		case err == nil && hasNativeDevrigBinary(section):
+			result.Fixes = append(result.Fixes,
+				"Run devrig from a native arm64 shell, `arch` must print arm64 (check the Rosetta setting of your terminal app)")
+		case err == nil && c.Fetcher != nil && section.Version != "":
+			result.Details = append(result.Details, fmt.Sprintf("devrig.yaml pins no native %s devrig binary", nativePlatform))
+			result.Actions = append(result.Actions, Action{
+				Description: fmt.Sprintf("pin the %s devrig binary of devrig %s in devrig.yaml", nativePlatform, section.Version),
+				Apply: func(context.Context) error {
+					return c.pinNativeDevrigBinary(env.Configs, section)
+				},
+			})
+		default:
+			result.Fixes = append(result.Fixes, "Run 'devrig upgrade' to pin native darwin-arm64 devrig binaries in devrig.yaml")
+		}
+
+		if names := intelOnlyLocks(env.ConfigPath); len(names) > 0 {
+			result.Details = append(result.Details, fmt.Sprintf("devrig.lock has no %s builds of %s", nativePlatform, strings.Join(names, ", ")))
+			result.Actions = append(result.Actions, Action{
+				Description: fmt.Sprintf("lock the %s builds of %s in devrig.lock", nativePlatform, strings.Join(names, ", ")),
+				Apply: func(ctx context.Context) error {
+					return c.lockNativeBuilds(ctx, env.ConfigPath, names)
+				},
+			})
+		}
+	}
+
+	intelOnly := findIntelOnlyBinaries(layout.ResolveToolsHome(env.DevrigHome))
+	for _, toolHome := range sortedKeys(intelOnly) {
+		result.Details = append(result.Details, fmt.Sprintf("x86_64 only: %s", strings.Join(intelOnly[toolHome], ", ")))
+		result.Fixes = append(result.Fixes, fmt.Sprintf("Remove %s and provision it again to get the native arm64 build", toolHome))
+	}
+
+	if len(result.Details) == 0 {
+		return Result{Status: StatusOK, Summary: "devrig and the provisioned tools run natively on arm64"}
+	}
+
+	result.Status = StatusWarning
+	result.Summary = "x86_64 binaries run under Rosetta, which is slower than native arm64 builds"
+	return result
+}
+
+// hasNativeDevrigBinary checks if devrig.yaml pins a darwin-arm64 devrig binary,
+// the x86_64 binary pinned for darwin-arm64 is not a native one
+func hasNativeDevrigBinary(section *configservice.DevrigSection) bool {
+	native, ok := section.Binaries[nativePlatform]
+	if !ok {
+		return false
+	}
+	for platform, binary := range section.Binaries {
+		if platform != nativePlatform && strings.HasPrefix(platform, "darwin-") && binary.URL == native.URL {
+			return false
+		}
+	}
+	return true
+}
+
+// pinNativeDevrigBinary rewrites the darwin-arm64 devrig binary of devrig.yaml with the one of the release metadata
+// of the pinned version, the binaries of other platforms are not changed
+func (c *RosettaCheck) pinNativeDevrigBinary(configs configservice.ConfigService, section *configservice.DevrigSection) error {
+	endpoints, err := updates.ResolveEndpoints(configs)
+	if err != nil {
+		return err
+	}
+	info, err := c.Fetcher.FetchUpdateInfo(endpoints.ReleaseJSONURL(section.Version))
+	if err != nil {
+		return fmt.Errorf("failed to fetch release metadata of devrig %s: %w", section.Version, err)
+	}
+	native, ok := info.ToDevrigSection().Binaries[nativePlatform]
+	if !ok {
+		return fmt.Errorf("devrig %s has no %s binary", section.Version, nativePlatform)
+	}
+	section.Binaries[nativePlatform] = native
+	return configs.Binaries().UpdateBinaries(section)
+}
+
+// intelOnlyLocks returns the names of the tools devrig.lock has darwin-amd64 builds for, but no darwin-arm64 ones
+func intelOnlyLocks(configPath string) []string {
+	lockfile, err := lock.Load(lock.ResolvePath(configPath))
+	if err != nil || lockfile == nil {
+		return nil
+	}
+	var names []string
+	for _, artifact := range lockfile.Artifacts {
+		if artifact.Platform == lock.Platform("darwin", "amd64") && lockfile.Find(artifact.Name, nativePlatform) == nil {
+			names = append(names, artifact.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lockNativeBuilds resolves the darwin-arm64 builds of the named pins in the feeds and adds them to devrig.lock
+func (c *RosettaCheck) lockNativeBuilds(ctx context.Context, configPath string, names []string) error {
+	pins := c.Pins
+	if pins == nil {
+		pins = lockcmd.ProjectPins
+	}
+	projectPins, err := pins(configPath)
+	if err != nil {
+		return err
+	}
+	lockPath := lock.ResolvePath(configPath)
+	lockfile, err := lock.Load(lockPath)
+	if err != nil {
+		return err
+	}
+	if lockfile == nil {
+		return fmt.Errorf("%s does not exist", lockPath)
+	}
+
+	goos, goarch, _ := lock.ParsePlatform(nativePlatform)
+	for _, pin := range projectPins {
+		intel := lockfile.Find(pin.Name, lock.Platform("darwin", "amd64"))
+		if !slices.Contains(names, pin.Name) || intel == nil || intel.Request != pin.Request {
+			continue
+		}
+		if err := offline.Check(fmt.Sprintf("%s %s for %s", pin.Name, pin.Request, nativePlatform), pin.Source, "run devrig doctor --fix on a machine with network access"); err != nil {
+			return err
+		}
+		artifact, err := pin.Resolve(ctx, goos, goarch)
+		if err != nil {
+			return fmt.Errorf("failed to lock %s %s for %s: %w", pin.Name, pin.Request, nativePlatform, err)
+		}
+		lockfile.Put(*artifact)
+	}
+	return lockfile.Save(lockPath)
+}
+
+// findIntelOnlyBinaries returns the Mach-O executables without an arm64 slice,
+// grouped by the tool home folder they belong to
+func findIntelOnlyBinaries(toolsHome string) map[string][]string {
+	result := map[string][]string{}
+
+	toolHomes, err := os.ReadDir(toolsHome)
+	if err != nil {
+		return result
+	}
+
+	for _, toolHome := range toolHomes {
+		if !toolHome.IsDir() {
+			continue
+		}
+		toolPath := filepath.Join(toolsHome, toolHome.Name())
+		for _, binDir := range []string{toolPath, filepath.Join(toolPath, "bin")} {
+			files, err := os.ReadDir(binDir)
+			if err != nil {
+				continue
+			}
+			for _, file := range files {
+				if !file.Type().IsRegular() {
+					continue
+				}
+				if isIntelOnlyMachO(filepath.Join(binDir, file.Name())) {
+					result[toolPath] = append(result[toolPath], file.Name())
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// isIntelOnlyMachO checks if the file is a Mach-O executable that has no arm64 code
+func isIntelOnlyMachO(path string) bool {
+	if fat, err := macho.OpenFat(path); err == nil {
+		//goland:noinspection GoUnhandledErrorResult
+		defer fat.Close()
+		hasIntel := false
+		for _, arch := range fat.Arches {
+			switch arch.Cpu {
+			case macho.CpuArm64:
+				return false
+			case macho.CpuAmd64:
+				hasIntel = true
+			}
+		}
+		return hasIntel
+	}
+
+	file, err := macho.Open(path)
+	if err != nil {
+		return false
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer file.Close()
+	return file.Cpu == macho.CpuAmd64
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,158 @@
+package doctor
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/lock"
+	"jonnyzzz.com/devrig.dev/lockcmd"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+type fakeReleaseFetcher struct {
+	info          *updates.UpdateInfo
+	requestedURLs []string
+}
+
+func (f *fakeReleaseFetcher) FetchUpdateInfo(url string) (*updates.UpdateInfo, error) {
+	f.requestedURLs = append(f.requestedURLs, url)
+	return f.info, nil
+}
+
+// writeMachOHeader writes a minimal 64-bit Mach-O executable header for the given CPU
+func writeMachOHeader(t *testing.T, path string, cpu uint32) {
+	t.Helper()
+	header := []uint32{0xfeedfacf, cpu, 3, 2, 0, 0, 0, 0}
+	data := make([]byte, 0, len(header)*4)
+	for _, value := range header {
+		data = binary.LittleEndian.AppendUint32(data, value)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0755); err != nil {
+		t.Fatalf("Failed to write Mach-O file: %v", err)
+	}
+}
+
+func TestRosettaCheck_IntelOnlyTool(t *testing.T) {
+	projectDir := t.TempDir()
+	configPath := filepath.Join(projectDir, "devrig.yaml")
+	devrigHome := filepath.Join(projectDir, ".devrig")
+
+	const cpuAmd64, cpuArm64 = 0x01000007, 0x0100000c
+	writeMachOHeader(t, filepath.Join(devrigHome, "tools", "node-20.11.0", "bin", "node"), cpuAmd64)
+	writeMachOHeader(t, filepath.Join(devrigHome, "tools", "go-1.22.0", "bin", "go"), cpuArm64)
+
+	env := Environment{ConfigPath: configPath, DevrigHome: devrigHome, Configs: configservice.NewConfigService(configPath)}
+	check := &RosettaCheck{Detect: func() (bool, bool) { return true, true }}
+	result := check.Run(context.Background(), env)
+
+	if result.Status != StatusWarning {
+		t.Fatalf("Expected warning, got %s: %s", result.Status, result.Summary)
+	}
+
+	details := strings.Join(result.Details, "\n")
+	if !strings.Contains(details, "under Rosetta") || !strings.Contains(details, "node") || strings.Contains(details, "go") {
+		t.Errorf("Unexpected details: %s", details)
+	}
+
+	fixes := strings.Join(result.Fixes, "\n")
+	if !strings.Contains(fixes, "devrig upgrade") || !strings.Contains(fixes, "node-20.11.0") {
+		t.Errorf("Unexpected fixes: %s", fixes)
+	}
+}
+
+func TestRosettaCheck_IntelMac(t *testing.T) {
+	check := &RosettaCheck{Detect: func() (bool, bool) { return false, false }}
+	result := check.Run(context.Background(), Environment{DevrigHome: t.TempDir()})
+
+	if result.Status != StatusOK {
+		t.Errorf("Expected OK on Intel Mac, got %s: %s", result.Status, result.Summary)
+	}
+}
+
+func TestRosettaCheck_FixesIntelPins(t *testing.T) {
+	projectDir := t.TempDir()
+	configPath := filepath.Join(projectDir, "devrig.yaml")
+	intelHash, armHash := strings.Repeat("a", 128), strings.Repeat("b", 128)
+	config := `devrig:
+  version: 0.80.0
+  binaries:
+    darwin-x86_64:
+      url: https://devrig.dev/download/devrig-darwin-x86_64
+      sha512: ` + intelHash + `
+    darwin-arm64:
+      url: https://devrig.dev/download/devrig-darwin-x86_64
+      sha512: ` + intelHash + `
+tools:
+  node: "20"
+`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	intelNode := lock.Artifact{Name: "node", Request: "20", Platform: "darwin-amd64", Version: "20.11.0", URL: "https://nodejs.org/node-v20.11.0-darwin-x64.tar.gz", Checksum: "1234"}
+	lockfile := &lock.Lockfile{Artifacts: []lock.Artifact{intelNode}}
+	if err := lockfile.Save(lock.ResolvePath(configPath)); err != nil {
+		t.Fatalf("Failed to save devrig.lock: %v", err)
+	}
+
+	fetcher := &fakeReleaseFetcher{info: &updates.UpdateInfo{Version: "0.80.0", Binaries: []updates.BinaryInfo{
+		{OS: "darwin", Arch: "x86_64", URL: "https://devrig.dev/download/devrig-darwin-x86_64", SHA512: intelHash},
+		{OS: "darwin", Arch: "arm64", URL: "https://devrig.dev/download/devrig-darwin-arm64", SHA512: armHash},
+	}}}
+	nodePin := lockcmd.Pin{Name: "node", Request: "20", Source: "https://nodejs.org", Resolve: func(_ context.Context, goos string, goarch string) (*lock.Artifact, error) {
+		return &lock.Artifact{Name: "node", Request: "20", Platform: lock.Platform(goos, goarch), Version: "20.11.0",
+			URL: "https://nodejs.org/node-v20.11.0-" + goos + "-" + goarch + ".tar.gz", Checksum: "5678"}, nil
+	}}
+
+	env := Environment{ConfigPath: configPath, DevrigHome: filepath.Join(projectDir, ".devrig"), Configs: configservice.NewConfigService(configPath)}
+	check := &RosettaCheck{
+		Detect:  func() (bool, bool) { return true, true },
+		Fetcher: fetcher,
+		Pins:    func(string) ([]lockcmd.Pin, error) { return []lockcmd.Pin{nodePin}, nil },
+	}
+	result := check.Run(context.Background(), env)
+	if result.Status != StatusWarning || len(result.Actions) != 2 {
+		t.Fatalf("Expected a warning with two actions, got %s: %+v", result.Status, result)
+	}
+	for _, action := range result.Actions {
+		if err := action.Apply(context.Background()); err != nil {
+			t.Fatalf("Failed to %s: %v", action.Description, err)
+		}
+	}
+
+	if len(fetcher.requestedURLs) != 1 || fetcher.requestedURLs[0] != updates.DefaultEndpoints.ReleaseJSONURL("0.80.0") {
+		t.Errorf("Expected the release metadata of the pinned version, got %v", fetcher.requestedURLs)
+	}
+	section, err := env.Configs.Binaries().ReadDevrigSection()
+	if err != nil {
+		t.Fatalf("Failed to read devrig.yaml: %v", err)
+	}
+	if native := section.Binaries["darwin-arm64"]; native.URL != "https://devrig.dev/download/devrig-darwin-arm64" || native.SHA512 != armHash {
+		t.Errorf("Expected the arm64 devrig binary, got %+v", native)
+	}
+	if intel := section.Binaries["darwin-x86_64"]; intel.SHA512 != intelHash {
+		t.Errorf("Expected the x86_64 devrig binary to be kept, got %+v", intel)
+	}
+
+	updated, err := lock.Load(lock.ResolvePath(configPath))
+	if err != nil {
+		t.Fatalf("Failed to load devrig.lock: %v", err)
+	}
+	if native := updated.Find("node", "darwin-arm64"); native == nil || native.URL != "https://nodejs.org/node-v20.11.0-darwin-arm64.tar.gz" {
+		t.Errorf("Expected the arm64 build of node in devrig.lock, got %+v", native)
+	}
+	if intel := updated.Find("node", "darwin-amd64"); intel == nil || *intel != intelNode {
+		t.Errorf("Expected the x86_64 build of node to be kept, got %+v", intel)
+	}
+
+	if result := check.Run(context.Background(), env); len(result.Actions) != 0 {
+		t.Errorf("Expected no actions after the fix, got %+v", result.Actions)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build darwin
+
+package doctor
+
+import "syscall"
+
+// detectRosetta reports if the machine is an Apple Silicon Mac and if the process runs under Rosetta 2
+func detectRosetta() (appleSilicon bool, translated bool) {
+	if value, err := syscall.SysctlUint32("hw.optional.arm64"); err == nil && value == 1 {
+		appleSilicon = true
+	}
+	if value, err := syscall.SysctlUint32("sysctl.proc_translated"); err == nil && value == 1 {
+		appleSilicon = true
+		translated = true
+	}
+	return appleSilicon, translated
+}
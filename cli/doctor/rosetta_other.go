@@ -0,0 +1,8 @@
+//go:build !darwin
+
+package doctor
+
+// detectRosetta reports if the machine is an Apple Silicon Mac and if the process runs under Rosetta 2
+func detectRosetta() (appleSilicon bool, translated bool) {
+	return false, false
+}
@@ -0,0 +1,165 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+const (
+	// maxOwnershipDetails limits the number of reported entries with wrong ownership
+	maxOwnershipDetails = 5
+	// maxOwnershipDepth limits the walk, the entries are usually created with wrong owners by `sudo devrig`
+	// at the top levels, while installed tools may contain millions of files
+	maxOwnershipDepth = 3
+)
+
+// SharedStoreCheck verifies ownership and permissions of the shared store on multi-user machines
+type SharedStoreCheck struct {
+	// GOOS overrides runtime.GOOS, used in tests
+	GOOS string
+}
+
+func (c *SharedStoreCheck) Name() string {
+	return "shared store"
+}
+
+func (c *SharedStoreCheck) Run(_ context.Context, _ Environment) Result {
+	goos := c.GOOS
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+
+	store, err := layout.ResolveSharedStore()
+	if err != nil {
+		return Result{Status: StatusFailed, Summary: err.Error()}
+	}
+	if store == nil {
+		return Result{Status: StatusSkipped, Summary: layout.EnvSharedStore + " is not set"}
+	}
+	if goos == "windows" {
+		return Result{Status: StatusSkipped, Summary: "ownership is controlled by ACLs on Windows"}
+	}
+
+	home, err := store.Home()
+	if err != nil {
+		return Result{Status: StatusFailed, Summary: err.Error()}
+	}
+	info, err := os.Stat(home)
+	if err != nil {
+		return Result{Status: StatusOK, Summary: home + " is not created yet"}
+	}
+
+	if store.Mode == layout.SharedStoreGroup {
+		return checkGroupStore(home, info)
+	}
+	return checkPerUserStore(home, info)
+}
+
+// checkPerUserStore verifies that the private folder belongs to the current user and is not shared
+func checkPerUserStore(home string, info os.FileInfo) Result {
+	var result Result
+	uid := os.Geteuid()
+	username := strconv.Itoa(uid)
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		result.Details = append(result.Details, fmt.Sprintf("%s is accessible by other users (mode %04o)", home, perm))
+		result.Fixes = append(result.Fixes, fmt.Sprintf("chmod 700 %q", home))
+	}
+
+	foreign := findEntries(home, func(entryUID int, _ int) bool { return entryUID != uid })
+	if len(foreign) > 0 {
+		result.Details = append(result.Details, ownershipDetails(foreign, "is not owned by "+username)...)
+		result.Fixes = append(result.Fixes, fmt.Sprintf("sudo chown -R %s %q", username, home))
+	}
+
+	if len(result.Details) == 0 {
+		return Result{Status: StatusOK, Summary: home + " is private to " + username}
+	}
+	if len(foreign) > 0 {
+		result.Status = StatusFailed
+		result.Summary = "the private folder contains files of other users"
+	} else {
+		result.Status = StatusWarning
+		result.Summary = "the private folder is accessible by other users"
+	}
+	return result
+}
+
+// checkGroupStore verifies the setgid and sticky bits and that all entries belong to the group of the store
+func checkGroupStore(home string, info os.FileInfo) Result {
+	var result Result
+	_, gid, ok := layout.FileOwner(info)
+	if !ok {
+		return Result{Status: StatusSkipped, Summary: "file ownership is not available on this system"}
+	}
+	group := strconv.Itoa(gid)
+	if g, err := user.LookupGroupId(group); err == nil {
+		group = g.Name
+	}
+
+	mode := info.Mode()
+	if mode&os.ModeSetgid == 0 || mode&os.ModeSticky == 0 || mode.Perm()&0070 != 0070 {
+		result.Details = append(result.Details, fmt.Sprintf("%s must be group-writable with the setgid and sticky bits (mode %s)", home, mode))
+		result.Fixes = append(result.Fixes, fmt.Sprintf("sudo chmod 3775 %q", home))
+	}
+
+	foreign := findEntries(home, func(_ int, entryGID int) bool { return entryGID != gid })
+	if len(foreign) > 0 {
+		result.Details = append(result.Details, ownershipDetails(foreign, "does not belong to the group "+group)...)
+		result.Fixes = append(result.Fixes, fmt.Sprintf("sudo chgrp -R %s %q", group, home))
+	}
+
+	if len(result.Details) == 0 {
+		return Result{Status: StatusOK, Summary: home + " is shared with the group " + group}
+	}
+	result.Status = StatusFailed
+	result.Summary = "other members of the group cannot use the shared store"
+	return result
+}
+
+// findEntries walks the top levels of the folder and returns the entries whose owner matches the predicate
+func findEntries(root string, wrongOwner func(uid int, gid int) bool) []string {
+	var found []string
+	_ = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if rel, err := filepath.Rel(root, path); err == nil && entry.IsDir() && rel != "." &&
+			strings.Count(rel, string(filepath.Separator)) >= maxOwnershipDepth-1 {
+			return fs.SkipDir
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		if uid, gid, ok := layout.FileOwner(info); ok && wrongOwner(uid, gid) {
+			found = append(found, path)
+		}
+		return nil
+	})
+	return found
+}
+
+func ownershipDetails(paths []string, problem string) []string {
+	var details []string
+	for i, path := range paths {
+		if i == maxOwnershipDetails {
+			details = append(details, fmt.Sprintf("and %d more", len(paths)-maxOwnershipDetails))
+			break
+		}
+		details = append(details, path+" "+problem)
+	}
+	return details
+}
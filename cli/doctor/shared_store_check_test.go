@@ -0,0 +1,74 @@
+package doctor
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+func TestSharedStoreCheck_NotConfigured(t *testing.T) {
+	t.Setenv(layout.EnvSharedStore, "")
+	result := (&SharedStoreCheck{}).Run(context.Background(), Environment{})
+	if result.Status != StatusSkipped {
+		t.Errorf("Expected %s, got %s: %s", StatusSkipped, result.Status, result.Summary)
+	}
+}
+
+func TestSharedStoreCheck_PerUser(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX permissions are not available on Windows")
+	}
+	t.Setenv(layout.EnvSharedStore, t.TempDir())
+	t.Setenv(layout.EnvSharedStoreMode, layout.SharedStorePerUser)
+
+	store, err := layout.ResolveSharedStore()
+	if err != nil {
+		t.Fatalf("Failed to resolve shared store: %v", err)
+	}
+	home, err := store.Prepare()
+	if err != nil {
+		t.Fatalf("Failed to prepare shared store: %v", err)
+	}
+
+	result := (&SharedStoreCheck{}).Run(context.Background(), Environment{})
+	if result.Status != StatusOK {
+		t.Fatalf("Expected %s, got %s: %s %v", StatusOK, result.Status, result.Summary, result.Details)
+	}
+
+	if err := os.Chmod(home, 0777); err != nil {
+		t.Fatalf("Failed to change mode: %v", err)
+	}
+	result = (&SharedStoreCheck{}).Run(context.Background(), Environment{})
+	if result.Status != StatusWarning || len(result.Fixes) != 1 {
+		t.Errorf("Expected a warning with a chmod fix, got %s: %s %v", result.Status, result.Summary, result.Fixes)
+	}
+}
+
+func TestSharedStoreCheck_GroupMissingBits(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX permissions are not available on Windows")
+	}
+	root := t.TempDir()
+	t.Setenv(layout.EnvSharedStore, root)
+	t.Setenv(layout.EnvSharedStoreMode, layout.SharedStoreGroup)
+	if err := os.Chmod(root, 0755); err != nil {
+		t.Fatalf("Failed to change mode: %v", err)
+	}
+
+	result := (&SharedStoreCheck{}).Run(context.Background(), Environment{})
+	if result.Status != StatusFailed || len(result.Fixes) == 0 {
+		t.Errorf("Expected a failure with fixes, got %s: %s", result.Status, result.Summary)
+	}
+
+	store, _ := layout.ResolveSharedStore()
+	if _, err := store.Prepare(); err != nil {
+		t.Fatalf("Failed to prepare shared store: %v", err)
+	}
+	result = (&SharedStoreCheck{}).Run(context.Background(), Environment{})
+	if result.Status != StatusOK {
+		t.Errorf("Expected %s after prepare, got %s: %s %v", StatusOK, result.Status, result.Summary, result.Details)
+	}
+}
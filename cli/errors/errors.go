@@ -0,0 +1,289 @@
+// Package errors defines the typed errors of devrig and the stable process exit codes they map to.
+// Wrapper scripts and CI pipelines can rely on the exit codes instead of parsing the error text.
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Exit codes of the devrig process. The values are part of the public contract, never renumber them.
+const (
+	ExitOK                  = 0
+	ExitGeneric             = 1
+	ExitConfigNotFound      = 3
+	ExitChecksumMismatch    = 4
+	ExitNetworkError        = 5
+	ExitSignatureInvalid    = 6
+	ExitUnsupportedPlatform = 7
+	ExitOffline             = 8
+	ExitAuthRequired        = 9
+	ExitLockOutdated        = 10
+	ExitNoCommand           = 11
+	ExitLocked              = 12
+	ExitUnsafeArchive       = 13
+	ExitConfigConflict      = 14
+	ExitDeprecated          = 15
+	ExitDiskFull            = 16
+	ExitConfigTooNew        = 17
+)
+
+// ExitCoder is implemented by errors that define their own process exit code
+type ExitCoder interface {
+	error
+	ExitCode() int
+}
+
+// ExitCode returns the process exit code for the given error.
+// The first error in the chain that implements ExitCoder wins, ExitGeneric is used otherwise.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var coder ExitCoder
+	if stderrors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+	return ExitGeneric
+}
+
+// ConfigNotFoundError is returned when devrig.yaml does not exist
+type ConfigNotFoundError struct {
+	Path string
+}
+
+func (e *ConfigNotFoundError) Error() string {
+	return fmt.Sprintf("devrig.yaml not found at: %s", e.Path)
+}
+
+func (e *ConfigNotFoundError) ExitCode() int {
+	return ExitConfigNotFound
+}
+
+// ChecksumMismatchError is returned when a downloaded file does not match the expected hash
+type ChecksumMismatchError struct {
+	Subject  string
+	Expected string
+	Actual   string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s:\n  expected: %s\n  got:      %s", e.Subject, e.Expected, e.Actual)
+}
+
+func (e *ChecksumMismatchError) ExitCode() int {
+	return ExitChecksumMismatch
+}
+
+// NetworkError is returned when a remote resource cannot be downloaded
+type NetworkError struct {
+	URL string
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("network error for %s: %v", e.URL, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+func (e *NetworkError) ExitCode() int {
+	return ExitNetworkError
+}
+
+// SignatureInvalidError is returned when a signature cannot be verified with the trusted keys
+type SignatureInvalidError struct {
+	Subject string
+	Err     error
+}
+
+func (e *SignatureInvalidError) Error() string {
+	return fmt.Sprintf("invalid signature for %s: %v", e.Subject, e.Err)
+}
+
+func (e *SignatureInvalidError) Unwrap() error {
+	return e.Err
+}
+
+func (e *SignatureInvalidError) ExitCode() int {
+	return ExitSignatureInvalid
+}
+
+// UnsupportedPlatformError is returned when there is nothing to run on the current OS or architecture
+type UnsupportedPlatformError struct {
+	OS   string
+	Arch string
+}
+
+func (e *UnsupportedPlatformError) Error() string {
+	if e.Arch == "" {
+		return fmt.Sprintf("unsupported operating system: %s", e.OS)
+	}
+	return fmt.Sprintf("unsupported platform: %s/%s", e.OS, e.Arch)
+}
+
+func (e *UnsupportedPlatformError) ExitCode() int {
+	return ExitUnsupportedPlatform
+}
+
+// OfflineError is returned in offline mode when an artifact is missing from the local caches
+type OfflineError struct {
+	// Artifact is a human-readable name of the missing artifact
+	Artifact string
+	URL      string
+	// Hint explains how to pre-seed the artifact
+	Hint string
+}
+
+func (e *OfflineError) Error() string {
+	message := fmt.Sprintf("offline mode: %s is not available locally and cannot be downloaded from %s", e.Artifact, e.URL)
+	if e.Hint != "" {
+		message += "\n  to fix: " + e.Hint
+	}
+	return message
+}
+
+func (e *OfflineError) ExitCode() int {
+	return ExitOffline
+}
+
+// AuthRequiredError is returned when a protected artifact host is accessed without a valid token
+type AuthRequiredError struct {
+	Host     string
+	Provider string
+	Err      error
+}
+
+func (e *AuthRequiredError) Error() string {
+	message := fmt.Sprintf("authentication required for %s, run `devrig auth login %s`", e.Host, e.Provider)
+	if e.Err != nil {
+		message += fmt.Sprintf(": %v", e.Err)
+	}
+	return message
+}
+
+func (e *AuthRequiredError) Unwrap() error {
+	return e.Err
+}
+
+func (e *AuthRequiredError) ExitCode() int {
+	return ExitAuthRequired
+}
+
+// LockOutdatedError is returned in frozen mode when devrig.lock does not match devrig.yaml
+type LockOutdatedError struct {
+	// Name is the locked tool, e.g. jdk or node
+	Name    string
+	Request string
+	// Reason explains what is missing or different in the lock
+	Reason string
+}
+
+func (e *LockOutdatedError) Error() string {
+	return fmt.Sprintf("devrig.lock is out of date for %s %s: %s, run `devrig lock` and commit devrig.lock", e.Name, e.Request, e.Reason)
+}
+
+func (e *LockOutdatedError) ExitCode() int {
+	return ExitLockOutdated
+}
+
+// LockedError is returned when another devrig process holds a lock and devrig must not wait or the wait timed out
+type LockedError struct {
+	Path string
+	// Holder describes the process holding the lock
+	Holder string
+	// Timeout is the time devrig waited, zero in the no-wait mode
+	Timeout time.Duration
+}
+
+func (e *LockedError) Error() string {
+	if e.Timeout > 0 {
+		return fmt.Sprintf("timed out after %s waiting for %s, held by %s", e.Timeout, e.Path, e.Holder)
+	}
+	return fmt.Sprintf("%s is locked by another devrig process (%s)", e.Path, e.Holder)
+}
+
+func (e *LockedError) ExitCode() int {
+	return ExitLocked
+}
+
+// NoCommandError is returned when devrig is started without a subcommand
+type NoCommandError struct{}
+
+func (e *NoCommandError) Error() string {
+	return "select subcommand to use devrig"
+}
+
+func (e *NoCommandError) ExitCode() int {
+	return ExitNoCommand
+}
+
+// DecompressionLimitError is returned when compressed data expands beyond the size or ratio limits,
+// the signature of a decompression bomb
+type DecompressionLimitError struct {
+	Subject string
+	Reason  string
+}
+
+func (e *DecompressionLimitError) Error() string {
+	return fmt.Sprintf("refusing to decompress %s: %s", e.Subject, e.Reason)
+}
+
+func (e *DecompressionLimitError) ExitCode() int {
+	return ExitUnsafeArchive
+}
+
+// ConfigConflictError is returned when another devrig process changed the same values of a devrig.yaml section
+// after this process read it, so writing the section would lose the other change
+type ConfigConflictError struct {
+	Path    string
+	Section string
+}
+
+func (e *ConfigConflictError) Error() string {
+	return fmt.Sprintf("the %s section of %s was changed by another process, review the change and run the command again", e.Section, e.Path)
+}
+
+func (e *ConfigConflictError) ExitCode() int {
+	return ExitConfigConflict
+}
+
+// DeprecatedError is returned for a deprecated flag, devrig.yaml key or file in the strict deprecations mode
+type DeprecatedError struct {
+	// Kind is flag, config_key or file
+	Kind    string
+	Name    string
+	Message string
+}
+
+func (e *DeprecatedError) Error() string {
+	return e.Message + " (strict deprecations mode)"
+}
+
+func (e *DeprecatedError) ExitCode() int {
+	return ExitDeprecated
+}
+
+// ConfigTooNewError is returned when devrig.yaml uses sections of a devrig version newer than the running one
+type ConfigTooNewError struct {
+	Path string
+	// Keys lists the sections and keys the running devrig does not know, e.g. services or ide.remote
+	Keys []string
+	// Required is the devrig version pinned in devrig.yaml
+	Required string
+	Running  string
+}
+
+func (e *ConfigTooNewError) Error() string {
+	return fmt.Sprintf("your devrig %s is too old for %s: it uses %s of devrig %s or newer, run the pinned devrig with ./devrig or update devrig to %s",
+		e.Running, e.Path, strings.Join(e.Keys, ", "), e.Required, e.Required)
+}
+
+func (e *ConfigTooNewError) ExitCode() int {
+	return ExitConfigTooNew
+}
@@ -0,0 +1,55 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"nil", nil, ExitOK},
+		{"generic", stderrors.New("boom"), ExitGeneric},
+		{"config", &ConfigNotFoundError{Path: "/tmp/devrig.yaml"}, ExitConfigNotFound},
+		{"checksum", &ChecksumMismatchError{Subject: "font", Expected: "a", Actual: "b"}, ExitChecksumMismatch},
+		{"network", &NetworkError{URL: "https://example.com", Err: stderrors.New("timeout")}, ExitNetworkError},
+		{"signature", &SignatureInvalidError{Subject: "latest.json", Err: stderrors.New("bad")}, ExitSignatureInvalid},
+		{"platform", &UnsupportedPlatformError{OS: "plan9"}, ExitUnsupportedPlatform},
+		{"offline", &OfflineError{Artifact: "latest.json", URL: "https://example.com"}, ExitOffline},
+		{"auth", &AuthRequiredError{Host: "artifacts.example.com", Provider: "corp"}, ExitAuthRequired},
+		{"lock", &LockOutdatedError{Name: "node", Request: "20", Reason: "not locked"}, ExitLockOutdated},
+		{"locked", &LockedError{Path: "/tmp/x.lock", Holder: "pid 1"}, ExitLocked},
+		{"decompression limit", &DecompressionLimitError{Subject: "feed.xz", Reason: "too large"}, ExitUnsafeArchive},
+		{"conflict", &ConfigConflictError{Path: "/tmp/devrig.yaml", Section: "devrig"}, ExitConfigConflict},
+		{"too new", &ConfigTooNewError{Path: "/tmp/devrig.yaml", Keys: []string{"services"}, Required: "0.90.0", Running: "0.80.0"}, ExitConfigTooNew},
+		{"deprecated", &DeprecatedError{Kind: "file", Name: ".idew.yaml", Message: ".idew.yaml is deprecated"}, ExitDeprecated},
+		{"no command", &NoCommandError{}, ExitNoCommand},
+		{"wrapped", fmt.Errorf("failed to download: %w", &NetworkError{URL: "u", Err: stderrors.New("x")}), ExitNetworkError},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if code := ExitCode(c.err); code != c.code {
+				t.Errorf("Expected exit code %d, got %d", c.code, code)
+			}
+		})
+	}
+}
+
+func TestNetworkError_Unwrap(t *testing.T) {
+	cause := stderrors.New("connection refused")
+	err := fmt.Errorf("failed: %w", &NetworkError{URL: "https://example.com", Err: cause})
+
+	if !stderrors.Is(err, cause) {
+		t.Error("Expected the cause to be reachable through the NetworkError")
+	}
+
+	var networkErr *NetworkError
+	if !stderrors.As(err, &networkErr) || networkErr.URL != "https://example.com" {
+		t.Errorf("Expected NetworkError with URL, got: %v", networkErr)
+	}
+}
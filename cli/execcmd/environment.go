@@ -0,0 +1,198 @@
+package execcmd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/cache"
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/install"
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+// Environment lists the devrig-managed installations a command runs with
+type Environment struct {
+	// PathDirs are prepended to PATH in this order
+	PathDirs []string
+	// Vars are set for the command, e.g. JAVA_HOME and GOROOT
+	Vars map[string]string
+}
+
+// ResolveEnvironment collects the installed JDK, the toolchains pinned in the tools section
+// and the unpacked IDEs of the project. Pinned tools which are not installed are skipped.
+func ResolveEnvironment(configPath string, goos string) (*Environment, error) {
+	configs := configservice.NewConfigService(configPath)
+	env := &Environment{Vars: map[string]string{}}
+
+	jdk, err := configs.Jdk().ReadJdk()
+	if err != nil {
+		return nil, err
+	}
+	if jdk != nil {
+		home := filepath.FromSlash(jdk.Path)
+		if !filepath.IsAbs(home) {
+			home = filepath.Join(filepath.Dir(configPath), home)
+		}
+		env.Vars["JAVA_HOME"] = home
+		env.PathDirs = append(env.PathDirs, filepath.Join(home, "bin"))
+	}
+
+	tools, err := configs.Tools().ReadTools()
+	if err != nil {
+		return nil, err
+	}
+	devrigHome := layout.ResolveDevrigHome(configPath)
+	for _, name := range install.ToolchainNames() {
+		pin, ok := tools[name]
+		if !ok {
+			continue
+		}
+		toolchain := install.Toolchains[name]()
+		home := findInstalled(devrigHome, toolchain, pin, goos)
+		if home == "" {
+			continue
+		}
+		env.PathDirs = append(env.PathDirs, toolchain.BinDir(home, goos))
+		if name == "go" {
+			env.Vars["GOROOT"] = home
+		}
+	}
+
+	ides, err := cache.LatestUnpackedIdes(config.ResolveCacheDir(configPath))
+	if err != nil {
+		return nil, err
+	}
+	for _, ide := range ides {
+		binDir := filepath.Join(ide, "bin")
+		if goos == "darwin" && strings.HasSuffix(ide, ".app") {
+			binDir = filepath.Join(ide, "Contents", "MacOS")
+		}
+		if info, err := os.Stat(binDir); err == nil && info.IsDir() {
+			env.PathDirs = append(env.PathDirs, binDir)
+		}
+	}
+	return env, nil
+}
+
+// findInstalled returns the installation of the pinned version, a pin like 20 selects the newest installed 20.x
+func findInstalled(devrigHome string, toolchain install.Toolchain, pin string, goos string) string {
+	exact := layout.ResolveToolHome(devrigHome, toolchain.Name(), pin)
+	if _, err := os.Stat(toolchain.Executable(exact, goos)); err == nil {
+		return exact
+	}
+
+	prefix := filepath.Base(layout.ResolveToolHome(devrigHome, toolchain.Name(), pin)) + "."
+	entries, err := os.ReadDir(layout.ResolveToolsHome(devrigHome))
+	if err != nil {
+		return ""
+	}
+	var candidates []string
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+			home := filepath.Join(layout.ResolveToolsHome(devrigHome), entry.Name())
+			if _, err := os.Stat(toolchain.Executable(home, goos)); err == nil {
+				candidates = append(candidates, home)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return compareVersions(filepath.Base(candidates[i]), filepath.Base(candidates[j])) > 0
+	})
+	return candidates[0]
+}
+
+// compareVersions compares the dot-separated parts numerically when both are numbers
+func compareVersions(a string, b string) int {
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		numA, errA := strconv.Atoi(partsA[i])
+		numB, errB := strconv.Atoi(partsB[i])
+		switch {
+		case errA == nil && errB == nil && numA != numB:
+			return numA - numB
+		case (errA != nil || errB != nil) && partsA[i] != partsB[i]:
+			return strings.Compare(partsA[i], partsB[i])
+		}
+	}
+	return len(partsA) - len(partsB)
+}
+
+// Apply returns the environment of the current process with the managed installations added
+func (e *Environment) Apply(environ []string, goos string) []string {
+	pathKey := "PATH"
+	var result []string
+	currentPath := ""
+	for _, entry := range environ {
+		key, value, _ := strings.Cut(entry, "=")
+		// Windows variable names are case-insensitive, keep the spelling of the parent process
+		if strings.EqualFold(key, "PATH") && (goos == "windows" || key == "PATH") {
+			pathKey = key
+			currentPath = value
+			continue
+		}
+		if _, overridden := e.lookupVar(key, goos); overridden {
+			continue
+		}
+		result = append(result, entry)
+	}
+
+	names := make([]string, 0, len(e.Vars))
+	for name := range e.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result = append(result, name+"="+e.Vars[name])
+	}
+
+	separator := ":"
+	if goos == "windows" {
+		separator = ";"
+	}
+	path := strings.Join(e.PathDirs, separator)
+	if currentPath != "" {
+		if path != "" {
+			path += separator
+		}
+		path += currentPath
+	}
+	return append(result, pathKey+"="+path)
+}
+
+func (e *Environment) lookupVar(key string, goos string) (string, bool) {
+	for name, value := range e.Vars {
+		if name == key || (goos == "windows" && strings.EqualFold(name, key)) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// LookPath finds the command in the managed folders first, then in the PATH of devrig
+func (e *Environment) LookPath(name string, goos string) (string, error) {
+	if strings.ContainsAny(name, `/\`) {
+		return name, nil
+	}
+
+	extensions := []string{""}
+	if goos == "windows" {
+		extensions = []string{".exe", ".cmd", ".bat", ""}
+	}
+	for _, dir := range e.PathDirs {
+		for _, ext := range extensions {
+			candidate := filepath.Join(dir, name+ext)
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() && (goos == "windows" || info.Mode().Perm()&0111 != 0) {
+				return candidate, nil
+			}
+		}
+	}
+	return lookPath(name)
+}
@@ -0,0 +1,102 @@
+// Package execcmd implements `devrig exec`, which runs commands with the devrig-managed tools on PATH
+package execcmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// lookPath finds commands which are not managed by devrig
+var lookPath = exec.LookPath
+
+// childExitError carries the exit code of the command, so devrig exits with the same code
+type childExitError struct {
+	code int
+}
+
+func (e *childExitError) Error() string {
+	return fmt.Sprintf("the command exited with code %d", e.code)
+}
+
+func (e *childExitError) ExitCode() int {
+	return e.code
+}
+
+// NewExecCommand creates the exec command
+func NewExecCommand(configPath func() string) *cobra.Command {
+	var printEnv bool
+	cmd := &cobra.Command{
+		Use:   "exec [--] <command> [args...]",
+		Short: "Run a command with the devrig-managed tools on PATH",
+		Long: `Run a command with the devrig-managed tools on PATH, like asdf exec or mise exec.
+
+The environment points at the installations of the project:
+  - the JDK of the jdk section as JAVA_HOME, with its bin folder on PATH
+  - the node and go versions pinned in the tools section and installed with devrig install
+  - the bin folders of the unpacked IDEs
+
+The command exits with the exit code of the executed command.
+
+Examples:
+  devrig exec -- java -version
+  devrig exec -- go build ./...
+  devrig exec --print-env
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env, err := ResolveEnvironment(configPath(), runtime.GOOS)
+			if err != nil {
+				return err
+			}
+
+			if printEnv {
+				for _, entry := range env.Apply(nil, runtime.GOOS) {
+					cmd.Println(entry)
+				}
+				return nil
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("command is required, e.g. devrig exec -- java -version")
+			}
+
+			path, err := env.LookPath(args[0], runtime.GOOS)
+			if err != nil {
+				return fmt.Errorf("command %s is not found in the devrig-managed tools or on PATH: %w", args[0], err)
+			}
+
+			child := exec.CommandContext(cmd.Context(), path, args[1:]...)
+			child.Env = env.Apply(os.Environ(), runtime.GOOS)
+			child.Stdin = cmd.InOrStdin()
+			child.Stdout = cmd.OutOrStdout()
+			child.Stderr = cmd.ErrOrStderr()
+
+			err = child.Run()
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				// The command has reported its failure already
+				cmd.SilenceErrors = true
+				cmd.SilenceUsage = true
+				code := exitErr.ExitCode()
+				if code < 0 {
+					// Killed by a signal
+					code = devrigErrors.ExitGeneric
+				}
+				return &childExitError{code: code}
+			}
+			if err != nil {
+				return fmt.Errorf("failed to run %s: %w", args[0], err)
+			}
+			return nil
+		},
+	}
+
+	// Flags after the command belong to the command
+	cmd.Flags().SetInterspersed(false)
+	cmd.Flags().BoolVar(&printEnv, "print-env", false, "Print the variables set for the command instead of running it")
+	return cmd
+}
@@ -0,0 +1,131 @@
+package execcmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// writeExecutable creates an executable shell script
+func writeExecutable(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// setupProject creates a project with an installed JDK and Node.js versions
+func setupProject(t *testing.T) string {
+	t.Helper()
+	t.Setenv("DEVRIG_HOME", "")
+	projectDir := t.TempDir()
+	configPath := filepath.Join(projectDir, "devrig.yaml")
+	content := `tools:
+  node: "20"
+  go: 1.22.1
+jdk:
+  vendor: temurin
+  version: 21.0.5+11
+  path: .devrig/tools/jdk-temurin-21.0.5_11
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tools := filepath.Join(projectDir, ".devrig", "tools")
+	writeExecutable(t, filepath.Join(tools, "jdk-temurin-21.0.5_11", "bin", "java"), "#!/bin/sh\necho java $JAVA_HOME\n")
+	writeExecutable(t, filepath.Join(tools, "node-20.9.0", "bin", "node"), "#!/bin/sh\necho node 20.9.0\n")
+	writeExecutable(t, filepath.Join(tools, "node-20.11.1", "bin", "node"), "#!/bin/sh\necho node 20.11.1\n")
+	writeExecutable(t, filepath.Join(tools, "node-18.19.0", "bin", "node"), "#!/bin/sh\necho node 18.19.0\n")
+	return configPath
+}
+
+func TestResolveEnvironment(t *testing.T) {
+	configPath := setupProject(t)
+	projectDir := filepath.Dir(configPath)
+
+	env, err := ResolveEnvironment(configPath, "linux")
+	if err != nil {
+		t.Fatalf("Failed to resolve environment: %v", err)
+	}
+
+	javaHome := filepath.Join(projectDir, ".devrig", "tools", "jdk-temurin-21.0.5_11")
+	if env.Vars["JAVA_HOME"] != javaHome {
+		t.Errorf("Unexpected JAVA_HOME: %s", env.Vars["JAVA_HOME"])
+	}
+	if _, ok := env.Vars["GOROOT"]; ok {
+		t.Errorf("Expected no GOROOT, Go is not installed")
+	}
+
+	expected := []string{
+		filepath.Join(javaHome, "bin"),
+		filepath.Join(projectDir, ".devrig", "tools", "node-20.11.1", "bin"),
+	}
+	if strings.Join(env.PathDirs, "|") != strings.Join(expected, "|") {
+		t.Errorf("Unexpected PATH folders: %v", env.PathDirs)
+	}
+}
+
+func TestEnvironment_Apply(t *testing.T) {
+	env := &Environment{PathDirs: []string{"/jdk/bin", "/node/bin"}, Vars: map[string]string{"JAVA_HOME": "/jdk"}}
+
+	result := env.Apply([]string{"HOME=/home/user", "JAVA_HOME=/usr/lib/jvm", "PATH=/usr/bin"}, "linux")
+	joined := strings.Join(result, "\n")
+	if !strings.Contains(joined, "PATH=/jdk/bin:/node/bin:/usr/bin") || !strings.Contains(joined, "JAVA_HOME=/jdk") {
+		t.Errorf("Unexpected environment:\n%s", joined)
+	}
+	if strings.Contains(joined, "/usr/lib/jvm") {
+		t.Errorf("Expected JAVA_HOME to be overridden:\n%s", joined)
+	}
+
+	result = env.Apply([]string{"Path=C:\\Windows", "java_home=C:\\old"}, "windows")
+	joined = strings.Join(result, "\n")
+	if !strings.Contains(joined, "Path=/jdk/bin;/node/bin;C:\\Windows") || strings.Contains(joined, "C:\\old") {
+		t.Errorf("Unexpected Windows environment:\n%s", joined)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	if compareVersions("node-20.11.1", "node-20.9.0") <= 0 {
+		t.Errorf("Expected 20.11.1 to be newer than 20.9.0")
+	}
+	if compareVersions("1.22.1", "1.22") <= 0 {
+		t.Errorf("Expected 1.22.1 to be newer than 1.22")
+	}
+}
+
+func TestExecCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test uses shell scripts")
+	}
+	configPath := setupProject(t)
+
+	var out bytes.Buffer
+	cmd := NewExecCommand(func() string { return configPath })
+	cmd.SetContext(context.Background())
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"node"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Failed to run node: %v", err)
+	}
+	if strings.TrimSpace(out.String()) != "node 20.11.1" {
+		t.Errorf("Unexpected output: %s", out.String())
+	}
+
+	out.Reset()
+	cmd.SetArgs([]string{"sh", "-c", "exit 3"})
+	err := cmd.Execute()
+	if code := devrigErrors.ExitCode(err); code != 3 {
+		t.Errorf("Expected the exit code of the command, got %d: %v", code, err)
+	}
+}
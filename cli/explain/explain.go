@@ -0,0 +1,121 @@
+// Package explain implements `devrig explain`, the extended explanations and remediation steps
+// of the exit codes, so failures in CI logs can be diagnosed without searching the web
+package explain
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/output"
+)
+
+//go:embed docs/*.md
+var docs embed.FS
+
+// Topic is the explanation of an exit code, the text is embedded from docs/<name>.md
+type Topic struct {
+	Code  int    `json:"code"`
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	Text  string `json:"text,omitempty"`
+}
+
+// topics lists all exit codes of the errors package, a new exit code needs a topic and a docs file
+var topics = []Topic{
+	{Code: devrigErrors.ExitGeneric, Name: "generic", Title: "Generic failure"},
+	{Code: devrigErrors.ExitConfigNotFound, Name: "config-not-found", Title: "devrig.yaml not found"},
+	{Code: devrigErrors.ExitChecksumMismatch, Name: "checksum-mismatch", Title: "Checksum mismatch of a downloaded file"},
+	{Code: devrigErrors.ExitNetworkError, Name: "network-error", Title: "Network error"},
+	{Code: devrigErrors.ExitSignatureInvalid, Name: "signature-invalid", Title: "Invalid signature"},
+	{Code: devrigErrors.ExitUnsupportedPlatform, Name: "unsupported-platform", Title: "Unsupported operating system or architecture"},
+	{Code: devrigErrors.ExitOffline, Name: "offline", Title: "Artifact is missing from local caches in offline mode"},
+	{Code: devrigErrors.ExitAuthRequired, Name: "auth-required", Title: "Login required for a protected artifact host"},
+	{Code: devrigErrors.ExitLockOutdated, Name: "lock-outdated", Title: "devrig.lock is out of date in --frozen mode"},
+	{Code: devrigErrors.ExitNoCommand, Name: "no-command", Title: "No subcommand given"},
+	{Code: devrigErrors.ExitLocked, Name: "locked", Title: "Another devrig process holds a lock"},
+	{Code: devrigErrors.ExitUnsafeArchive, Name: "unsafe-archive", Title: "Unsafe archive or decompression bomb"},
+	{Code: devrigErrors.ExitConfigConflict, Name: "config-conflict", Title: "devrig.yaml was changed by another process"},
+	{Code: devrigErrors.ExitDeprecated, Name: "deprecated", Title: "A deprecated flag, key or file is used in strict mode"},
+	{Code: devrigErrors.ExitDiskFull, Name: "disk-full", Title: "Not enough free disk space for a download or unpack"},
+	{Code: devrigErrors.ExitConfigTooNew, Name: "config-too-new", Title: "devrig.yaml needs a newer devrig"},
+}
+
+// Lookup finds the topic by the exit code, e.g. 4, or by the name, e.g. checksum-mismatch
+func Lookup(codeOrName string) (*Topic, error) {
+	key := strings.ToLower(strings.TrimSpace(codeOrName))
+	code, codeErr := strconv.Atoi(key)
+	for _, topic := range topics {
+		if (codeErr == nil && topic.Code == code) || topic.Name == key {
+			text, err := docs.ReadFile("docs/" + topic.Name + ".md")
+			if err != nil {
+				return nil, fmt.Errorf("failed to read the explanation of %s: %w", topic.Name, err)
+			}
+			found := topic
+			found.Text = string(text)
+			return &found, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown error code %s, run `devrig explain` to list all codes", codeOrName)
+}
+
+// Hint returns the line pointing to the explanation of the exit code, or empty if there is nothing to add
+func Hint(code int) string {
+	if code == devrigErrors.ExitOK || code == devrigErrors.ExitGeneric || code == devrigErrors.ExitNoCommand {
+		return ""
+	}
+	for _, topic := range topics {
+		if topic.Code == code {
+			return fmt.Sprintf("Run `devrig explain %s` to see how to fix it (exit code %d)", topic.Name, code)
+		}
+	}
+	return ""
+}
+
+// NewExplainCommand creates the explain command
+func NewExplainCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "explain [exit-code|name]",
+		Short: "Explain an exit code of devrig and how to fix it",
+		Long: `Print the extended explanation and remediation steps of an exit code.
+
+The code is the exit code of devrig or its name, without arguments all codes are listed.
+
+Examples:
+  devrig explain
+  devrig explain 4
+  devrig explain checksum-mismatch
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			out := cmd.OutOrStdout()
+			jsonOutput := output.FormatFromContext(cmd.Context()) == output.FormatJSON
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+
+			if len(args) == 0 {
+				if jsonOutput {
+					return encoder.Encode(topics)
+				}
+				for _, topic := range topics {
+					_, _ = fmt.Fprintf(out, "%3d  %-22s %s\n", topic.Code, topic.Name, topic.Title)
+				}
+				return nil
+			}
+
+			topic, err := Lookup(args[0])
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return encoder.Encode(topic)
+			}
+			_, _ = fmt.Fprintf(out, "Exit code %d: %s (%s)\n\n%s", topic.Code, topic.Title, topic.Name, topic.Text)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,125 @@
+package explain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/fs"
+	"strconv"
+	"strings"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/output"
+)
+
+func TestTopics_HaveDocs(t *testing.T) {
+	names := map[string]bool{}
+	for _, topic := range topics {
+		found, err := Lookup(topic.Name)
+		if err != nil {
+			t.Fatalf("Failed to look up %s: %v", topic.Name, err)
+		}
+		if !strings.Contains(found.Text, "To fix:") {
+			t.Errorf("Expected remediation steps in the explanation of %s", topic.Name)
+		}
+		names[topic.Name+".md"] = true
+	}
+
+	files, err := fs.Glob(docs, "docs/*.md")
+	if err != nil {
+		t.Fatalf("Failed to list docs: %v", err)
+	}
+	for _, file := range files {
+		if !names[strings.TrimPrefix(file, "docs/")] {
+			t.Errorf("Docs file %s has no topic", file)
+		}
+	}
+}
+
+func TestTopics_CoverAllExitCodes(t *testing.T) {
+	codes := []int{
+		devrigErrors.ExitGeneric, devrigErrors.ExitConfigNotFound, devrigErrors.ExitChecksumMismatch,
+		devrigErrors.ExitNetworkError, devrigErrors.ExitSignatureInvalid, devrigErrors.ExitUnsupportedPlatform,
+		devrigErrors.ExitOffline, devrigErrors.ExitAuthRequired, devrigErrors.ExitLockOutdated,
+		devrigErrors.ExitNoCommand, devrigErrors.ExitLocked, devrigErrors.ExitUnsafeArchive,
+		devrigErrors.ExitConfigConflict, devrigErrors.ExitDeprecated, devrigErrors.ExitDiskFull,
+		devrigErrors.ExitConfigTooNew,
+	}
+	for _, code := range codes {
+		topic, err := Lookup(strconv.Itoa(code))
+		if err != nil {
+			t.Fatalf("Failed to look up exit code %d: %v", code, err)
+		}
+		if topic.Code != code {
+			t.Errorf("Expected topic of exit code %d, got %d", code, topic.Code)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	byCode, err := Lookup("4")
+	if err != nil {
+		t.Fatalf("Failed to look up by code: %v", err)
+	}
+	byName, err := Lookup(" Checksum-Mismatch ")
+	if err != nil {
+		t.Fatalf("Failed to look up by name: %v", err)
+	}
+	if byCode.Name != "checksum-mismatch" || byName.Code != devrigErrors.ExitChecksumMismatch {
+		t.Errorf("Expected checksum-mismatch, got %s and %d", byCode.Name, byName.Code)
+	}
+
+	for _, unknown := range []string{"0", "2", "42", "policy-violation"} {
+		if _, err := Lookup(unknown); err == nil {
+			t.Errorf("Expected error for unknown code %s", unknown)
+		}
+	}
+}
+
+func TestHint(t *testing.T) {
+	for _, code := range []int{devrigErrors.ExitOK, devrigErrors.ExitGeneric, devrigErrors.ExitNoCommand, 42} {
+		if hint := Hint(code); hint != "" {
+			t.Errorf("Expected no hint for exit code %d, got %q", code, hint)
+		}
+	}
+	if hint := Hint(devrigErrors.ExitLocked); !strings.Contains(hint, "devrig explain locked") {
+		t.Errorf("Expected hint to point to devrig explain locked, got %q", hint)
+	}
+}
+
+func TestExplainCommand(t *testing.T) {
+	cmd := NewExplainCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"unsafe-archive"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Failed to run explain: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "Exit code 13: ") {
+		t.Errorf("Expected explanation of exit code 13, got %q", out.String())
+	}
+}
+
+func TestExplainCommand_ListJSON(t *testing.T) {
+	cmd := NewExplainCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{})
+	if err := cmd.ExecuteContext(output.WithFormat(context.Background(), output.FormatJSON)); err != nil {
+		t.Fatalf("Failed to run explain: %v", err)
+	}
+
+	var listed []Topic
+	if err := json.Unmarshal(out.Bytes(), &listed); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if len(listed) != len(topics) {
+		t.Errorf("Expected %d topics, got %d", len(topics), len(listed))
+	}
+	for _, topic := range listed {
+		if topic.Text != "" {
+			t.Errorf("Expected no text in the list for %s", topic.Name)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package extract
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// CopyTree copies the folder src into destDir with the same checks as the archives: files keep their permissions,
+// symlinks are copied as they are and must stay inside destDir. It replaces cp -R for the application of a mounted DMG
+func CopyTree(src, destDir string) error {
+	root, err := openRoot(destDir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	// Read-only folders are made read-only once their content is copied
+	var dirs []string
+	modes := map[string]fs.FileMode{}
+	err = filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.IsDir():
+			if err := root.MkdirAll(name, 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", name, err)
+			}
+			dirs = append(dirs, name)
+			modes[name] = info.Mode().Perm()
+			return nil
+		case entry.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("failed to read symlink %s: %w", path, err)
+			}
+			return symlink(root, name, name, target)
+		case entry.Type().IsRegular():
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			return writeFile(root, name, name, file, info.Mode().Perm(), info.Size())
+		default:
+			return &UnsafeEntryError{Entry: name, Reason: "is not a file, a directory or a symlink"}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+
+	for _, name := range slices.Backward(dirs) {
+		if err := root.Chmod(name, modes[name]); err != nil {
+			return fmt.Errorf("failed to set permissions of %s: %w", name, err)
+		}
+	}
+	return nil
+}
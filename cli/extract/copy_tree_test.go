@@ -0,0 +1,67 @@
+package extract
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCopyTree(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test checks POSIX permissions and links")
+	}
+	src := filepath.Join(t.TempDir(), "GoLand.app")
+	for _, dir := range []string{"Contents/MacOS", "Contents/Resources"} {
+		if err := os.MkdirAll(filepath.Join(src, dir), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(src, "Contents/MacOS/goland"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write launcher: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "Contents/Resources/idea.properties"), []byte("x=1\n"), 0444); err != nil {
+		t.Fatalf("Failed to write properties: %v", err)
+	}
+	if err := os.Symlink("MacOS/goland", filepath.Join(src, "Contents/goland")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Chmod(filepath.Join(src, "Contents/Resources"), 0555); err != nil {
+		t.Fatalf("Failed to make directory read-only: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(filepath.Join(src, "Contents/Resources"), 0755) })
+
+	dest := filepath.Join(t.TempDir(), "GoLand")
+	if err := CopyTree(src, dest); err != nil {
+		t.Fatalf("Failed to copy the tree: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(filepath.Join(dest, "Contents/Resources"), 0755) })
+
+	if info, err := os.Stat(filepath.Join(dest, "Contents/MacOS/goland")); err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Expected an executable launcher, got %v, %v", info, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "Contents/Resources/idea.properties")); err != nil || string(data) != "x=1\n" {
+		t.Errorf("Expected the file content to be copied, got %q, %v", data, err)
+	}
+	if info, err := os.Stat(filepath.Join(dest, "Contents/Resources")); err != nil || info.Mode().Perm() != 0555 {
+		t.Errorf("Expected the read-only directory to be kept, got %v, %v", info, err)
+	}
+	if target, err := os.Readlink(filepath.Join(dest, "Contents/goland")); err != nil || target != "MacOS/goland" {
+		t.Errorf("Expected the link to be kept, got %s, %v", target, err)
+	}
+}
+
+func TestCopyTree_RejectsEscapingLinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test creates symlinks")
+	}
+	src := t.TempDir()
+	if err := os.Symlink("../../etc/passwd", filepath.Join(src, "passwd")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	var unsafe *UnsafeEntryError
+	if err := CopyTree(src, filepath.Join(t.TempDir(), "dest")); !errors.As(err, &unsafe) {
+		t.Errorf("Expected the link to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,458 @@
+// Package extract unpacks archives without letting an entry escape the destination folder.
+// Entries with absolute paths or ../ segments, links resolving outside of the destination and oversized
+// entries are rejected. All writes go through os.Root, so links created by earlier entries cannot redirect
+// later writes, and the extracted tree is checked once more for link chains escaping the destination.
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// Limits bounds what a single archive may contain
+type Limits struct {
+	// MaxEntrySize is the largest uncompressed size of a file
+	MaxEntrySize int64
+	// MaxEntries is the largest number of entries
+	MaxEntries int
+	// MaxTotalSize is the largest uncompressed size of the whole archive
+	MaxTotalSize int64
+	// MaxRatio is the largest ratio of the uncompressed to the compressed size
+	MaxRatio int64
+}
+
+// DefaultLimits fit the largest IDE and JDK distributions with a wide margin
+var DefaultLimits = Limits{
+	MaxEntrySize: 4 << 30,
+	MaxEntries:   1 << 20,
+	MaxTotalSize: 16 << 30,
+	MaxRatio:     200,
+}
+
+// maxLinkSize bounds the content of a zip symlink entry, which is the link target
+const maxLinkSize = 4096
+
+// UnsafeEntryError is returned for an archive entry that would escape the destination or exceed the limits
+type UnsafeEntryError struct {
+	Entry  string
+	Reason string
+}
+
+func (e *UnsafeEntryError) Error() string {
+	return fmt.Sprintf("archive entry %s %s", e.Entry, e.Reason)
+}
+
+func (e *UnsafeEntryError) ExitCode() int {
+	return devrigErrors.ExitUnsafeArchive
+}
+
+// Archive unpacks a .tar.gz or .zip archive into destDir
+func Archive(archivePath string, destDir string, limits Limits) error {
+	if strings.HasSuffix(strings.ToLower(archivePath), ".zip") {
+		return Zip(archivePath, destDir, limits)
+	}
+	return TarGz(archivePath, destDir, limits)
+}
+
+// TarGz unpacks a .tar.gz archive into destDir, keeping file modes and links. The stream is decompressed
+// ahead in its own goroutine and small files are written by a pool of workers while the next entries are read
+func TarGz(archivePath string, destDir string, limits Limits) error {
+	return tarGz(archivePath, destDir, limits, defaultWorkers())
+}
+
+// tarGz unpacks the archive with the number of workers, one worker or less unpacks it sequentially
+func tarGz(archivePath string, destDir string, limits Limits, workers int) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat archive: %w", err)
+	}
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read gzip stream: %w", err)
+	}
+	defer gz.Close()
+
+	var stream io.Reader = gz
+	if workers > 1 {
+		ahead := newReadAheadReader(gz)
+		defer ahead.Close()
+		stream = ahead
+	}
+
+	root, err := openRoot(destDir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	pool := newWorkerPool(workers)
+	err = extractTar(root, tar.NewReader(NewReader(stream, filepath.Base(archivePath), info.Size(), limits)), limits, pool)
+	if closeErr := pool.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return CheckTree(destDir)
+}
+
+// extractTar reads the entries in order. Directories, links and the parents of files are created right away,
+// so the workers only write the content and the tree ends up as if the entries were written one by one
+func extractTar(root *os.Root, reader *tar.Reader, limits Limits, pool *workerPool) error {
+	written := newWrittenEntries(pool)
+	for entries := 1; ; entries++ {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+		if entries > limits.MaxEntries {
+			return &UnsafeEntryError{Entry: header.Name, Reason: fmt.Sprintf("exceeds the limit of %d entries", limits.MaxEntries)}
+		}
+
+		name, err := EntryName(header.Name)
+		if err != nil {
+			return err
+		}
+		if err := written.before(name); err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := root.MkdirAll(name, 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", header.Name, err)
+			}
+		case tar.TypeReg:
+			if header.Size > limits.MaxEntrySize {
+				return &UnsafeEntryError{Entry: header.Name, Reason: fmt.Sprintf("is larger than %d bytes", limits.MaxEntrySize)}
+			}
+			if err := mkdirParent(root, name); err != nil {
+				return err
+			}
+			mode := header.FileInfo().Mode().Perm()
+			if header.Size > bufferedEntrySize {
+				if err := writeFile(root, name, header.Name, reader, mode, limits.MaxEntrySize); err != nil {
+					return err
+				}
+				continue
+			}
+			var content bytes.Buffer
+			if _, err := Copy(&content, reader, header.Name, limits.MaxEntrySize); err != nil {
+				return err
+			}
+			entry := header.Name
+			if err := pool.Go(func() error {
+				return writeFile(root, name, entry, &content, mode, limits.MaxEntrySize)
+			}); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := symlink(root, name, header.Name, header.Linkname); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			source, err := EntryName(header.Linkname)
+			if err != nil {
+				return err
+			}
+			// The content of the source may still be written
+			if err := pool.Wait(); err != nil {
+				return err
+			}
+			if err := mkdirParent(root, name); err != nil {
+				return err
+			}
+			if err := root.Link(source, name); err != nil {
+				return fmt.Errorf("failed to create hard link %s: %w", header.Name, err)
+			}
+		default:
+			// Device files and FIFOs have no place in a tool distribution
+		}
+	}
+}
+
+// Zip unpacks a .zip archive into destDir, the entries are decompressed and written by a pool of workers
+func Zip(archivePath string, destDir string, limits Limits) error {
+	return unzip(archivePath, destDir, limits, defaultWorkers())
+}
+
+// unzip unpacks the archive with the number of workers, one worker or less unpacks it sequentially
+func unzip(archivePath string, destDir string, limits Limits, workers int) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer reader.Close()
+
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat archive: %w", err)
+	}
+
+	if len(reader.File) > limits.MaxEntries {
+		return &UnsafeEntryError{Entry: filepath.Base(archivePath), Reason: fmt.Sprintf("exceeds the limit of %d entries", limits.MaxEntries)}
+	}
+
+	root, err := openRoot(destDir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	pool := newWorkerPool(workers)
+	// The sizes of the entries are counted together, overlapping entries of a zip bomb share the compressed bytes
+	meter := newLimitedReader(nil, filepath.Base(archivePath), info.Size(), limits)
+	err = extractZip(root, reader.File, limits, meter, pool)
+	if closeErr := pool.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return CheckTree(destDir)
+}
+
+// extractZip creates the directories, links and the parents of files in the order of the archive,
+// the workers decompress and write the content of the files
+func extractZip(root *os.Root, files []*zip.File, limits Limits, meter *limitedReader, pool *workerPool) error {
+	written := newWrittenEntries(pool)
+	for _, file := range files {
+		name, err := EntryName(file.Name)
+		if err != nil {
+			return err
+		}
+		if err := written.before(name); err != nil {
+			return err
+		}
+
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			if err := root.MkdirAll(name, 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", file.Name, err)
+			}
+			continue
+		case mode&fs.ModeSymlink != 0:
+			target, err := readZipLink(file)
+			if err != nil {
+				return err
+			}
+			if err := symlink(root, name, file.Name, target); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if file.UncompressedSize64 > uint64(limits.MaxEntrySize) {
+			return &UnsafeEntryError{Entry: file.Name, Reason: fmt.Sprintf("is larger than %d bytes", limits.MaxEntrySize)}
+		}
+		if err := mkdirParent(root, name); err != nil {
+			return err
+		}
+		perm := mode.Perm()
+		if perm == 0 {
+			perm = 0644
+		}
+		if err := pool.Go(func() error {
+			rc, err := file.Open()
+			if err != nil {
+				return fmt.Errorf("failed to open file in zip: %w", err)
+			}
+			defer rc.Close()
+			return writeFile(root, name, file.Name, meter.with(rc), perm, limits.MaxEntrySize)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writtenEntries tracks the entries handed to the workers. An archive may list a path twice, the later entry
+// wins, so the writes of the earlier one are waited for first
+type writtenEntries struct {
+	pool  *workerPool
+	names map[string]bool
+}
+
+func newWrittenEntries(pool *workerPool) *writtenEntries {
+	return &writtenEntries{pool: pool, names: map[string]bool{}}
+}
+
+// before is called for every entry before it is extracted
+func (w *writtenEntries) before(name string) error {
+	if w.names[name] {
+		if err := w.pool.Wait(); err != nil {
+			return err
+		}
+		clear(w.names)
+	}
+	w.names[name] = true
+	return nil
+}
+
+// EntryName returns the entry name as a path relative to the destination,
+// rejecting absolute paths, volume names and ../ segments leading outside of it
+func EntryName(name string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(name))
+	if isAbsolute(cleaned) {
+		return "", &UnsafeEntryError{Entry: name, Reason: "points to an absolute path"}
+	}
+	if cleaned != "." && !filepath.IsLocal(cleaned) {
+		return "", &UnsafeEntryError{Entry: name, Reason: "points outside of the destination"}
+	}
+	return cleaned, nil
+}
+
+// CheckLink rejects a link of the entry whose target is absolute or leads outside of the destination.
+// The check is lexical, CheckTree resolves link chains once the links exist
+func CheckLink(name string, target string) error {
+	if isAbsolute(target) {
+		return &UnsafeEntryError{Entry: name, Reason: "is a symlink to an absolute path " + target}
+	}
+	resolved := filepath.Join(filepath.Dir(filepath.FromSlash(name)), filepath.FromSlash(target))
+	if !filepath.IsLocal(resolved) && resolved != "." {
+		return &UnsafeEntryError{Entry: name, Reason: "is a symlink pointing outside of the destination: " + target}
+	}
+	return nil
+}
+
+// CheckTree rejects symlinks under dir that resolve outside of it, including chains of links
+// that each look harmless, e.g. a -> . and a/b -> .. which makes b point to the parent of dir
+func CheckTree(dir string) error {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+
+		name, _ := filepath.Rel(root, path)
+		target, err := os.Readlink(path)
+		if err != nil {
+			return fmt.Errorf("failed to read symlink %s: %w", path, err)
+		}
+		if isAbsolute(target) {
+			return &UnsafeEntryError{Entry: name, Reason: "is a symlink to an absolute path " + target}
+		}
+
+		resolved, err := filepath.EvalSymlinks(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Dangling links are harmless as long as their target stays inside
+			return CheckLink(name, target)
+		}
+		if err != nil {
+			return &UnsafeEntryError{Entry: name, Reason: "is a symlink that cannot be resolved: " + err.Error()}
+		}
+		relative, err := filepath.Rel(root, resolved)
+		if err != nil || !filepath.IsLocal(relative) && relative != "." {
+			return &UnsafeEntryError{Entry: name, Reason: "is a symlink pointing outside of the destination: " + target}
+		}
+		return nil
+	})
+}
+
+// Copy copies at most limit bytes of the entry, failing with UnsafeEntryError when the content is larger
+func Copy(dst io.Writer, src io.Reader, entry string, limit int64) (int64, error) {
+	written, err := io.Copy(dst, io.LimitReader(src, limit+1))
+	if err != nil {
+		return written, fmt.Errorf("failed to extract %s: %w", entry, err)
+	}
+	if written > limit {
+		return written, &UnsafeEntryError{Entry: entry, Reason: fmt.Sprintf("is larger than %d bytes", limit)}
+	}
+	return written, nil
+}
+
+func openRoot(destDir string) (*os.Root, error) {
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	root, err := os.OpenRoot(destDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", destDir, err)
+	}
+	return root, nil
+}
+
+func mkdirParent(root *os.Root, name string) error {
+	if err := root.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	return nil
+}
+
+func writeFile(root *os.Root, name string, entry string, content io.Reader, mode os.FileMode, limit int64) error {
+	if err := mkdirParent(root, name); err != nil {
+		return err
+	}
+
+	out, err := root.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", entry, err)
+	}
+	if _, err := Copy(out, content, entry, limit); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func symlink(root *os.Root, name string, entry string, target string) error {
+	if err := CheckLink(name, target); err != nil {
+		return err
+	}
+	if err := mkdirParent(root, name); err != nil {
+		return err
+	}
+	if err := root.Symlink(target, name); err != nil {
+		return fmt.Errorf("failed to create symlink %s: %w", entry, err)
+	}
+	return nil
+}
+
+// readZipLink returns the target of a zip symlink entry, which is stored as the entry content
+func readZipLink(file *zip.File) (string, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open file in zip: %w", err)
+	}
+	defer rc.Close()
+
+	var target strings.Builder
+	if _, err := Copy(&target, rc, file.Name, maxLinkSize); err != nil {
+		return "", err
+	}
+	return target.String(), nil
+}
+
+// isAbsolute also catches rooted paths on Windows, e.g. /etc/passwd or \share, which filepath.IsAbs accepts there
+func isAbsolute(path string) bool {
+	return filepath.IsAbs(path) || filepath.VolumeName(path) != "" || strings.HasPrefix(filepath.ToSlash(path), "/")
+}
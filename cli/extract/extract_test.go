@@ -0,0 +1,253 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+type entry struct {
+	name     string
+	content  string
+	mode     int64
+	typeflag byte
+	linkname string
+}
+
+// buildTarGz writes a .tar.gz archive with the entries
+func buildTarGz(t *testing.T, path string, entries []entry) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		typeflag := e.typeflag
+		if typeflag == 0 {
+			typeflag = tar.TypeReg
+		}
+		mode := e.mode
+		if mode == 0 {
+			mode = 0644
+		}
+		header := &tar.Header{Name: e.name, Mode: mode, Size: int64(len(e.content)), Typeflag: typeflag, Linkname: e.linkname}
+		if typeflag != tar.TypeReg {
+			header.Size = 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write tar header: %v", err)
+		}
+		if typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.content)); err != nil {
+				t.Fatalf("Failed to write tar entry: %v", err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to close gzip: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+}
+
+// buildZip writes a .zip archive with the entries, symlinks are stored the way Info-ZIP does
+func buildZip(t *testing.T, path string, entries []entry) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		content := e.content
+		switch e.typeflag {
+		case tar.TypeSymlink:
+			header.SetMode(fs.ModeSymlink | 0777)
+			content = e.linkname
+		case tar.TypeDir:
+			header.SetMode(fs.ModeDir | 0755)
+		default:
+			header.SetMode(0644)
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatalf("Failed to create zip entry %s: %v", e.name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write zip entry %s: %v", e.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+}
+
+func TestArchive_Extracts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks require privileges on Windows")
+	}
+
+	for _, format := range []string{"tar.gz", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			tempDir := t.TempDir()
+			archivePath := filepath.Join(tempDir, "tool."+format)
+			entries := []entry{
+				{name: "node/", typeflag: tar.TypeDir},
+				{name: "node/bin/node", content: "#!/bin/sh\n"},
+				{name: "node/lib/current", typeflag: tar.TypeSymlink, linkname: "../bin/node"},
+				{name: "./node/README.md", content: "readme"},
+			}
+			if format == "zip" {
+				buildZip(t, archivePath, entries)
+			} else {
+				buildTarGz(t, archivePath, entries)
+			}
+
+			destDir := filepath.Join(tempDir, "out")
+			if err := Archive(archivePath, destDir, DefaultLimits); err != nil {
+				t.Fatalf("Failed to extract archive: %v", err)
+			}
+
+			data, err := os.ReadFile(filepath.Join(destDir, "node", "lib", "current"))
+			if err != nil {
+				t.Fatalf("Failed to read through symlink: %v", err)
+			}
+			if string(data) != "#!/bin/sh\n" {
+				t.Errorf("Unexpected symlink content: %q", data)
+			}
+			if _, err := os.Stat(filepath.Join(destDir, "node", "README.md")); err != nil {
+				t.Errorf("Expected README.md to be extracted: %v", err)
+			}
+		})
+	}
+}
+
+type adversarialArchive struct {
+	name    string
+	entries []entry
+	// symlinks marks archives that need symlink support to reach the check
+	symlinks bool
+}
+
+// adversarialArchives are the traversal tricks of zip-slip and tar path traversal reports
+var adversarialArchives = []adversarialArchive{
+	{name: "parent path", entries: []entry{{name: "../evil", content: "x"}}},
+	{name: "nested parent path", entries: []entry{{name: "node/../../evil", content: "x"}}},
+	{name: "absolute path", entries: []entry{{name: "/tmp/evil", content: "x"}}},
+	{name: "windows parent path", entries: []entry{{name: `..\evil`, content: "x"}}},
+	{name: "absolute symlink", entries: []entry{{name: "node/link", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}}},
+	{name: "escaping symlink", entries: []entry{{name: "node/link", typeflag: tar.TypeSymlink, linkname: "../../evil"}}},
+	{name: "write through escaping symlink", symlinks: true, entries: []entry{
+		{name: "node", typeflag: tar.TypeSymlink, linkname: "."},
+		{name: "node/up", typeflag: tar.TypeSymlink, linkname: ".."},
+		{name: "up/evil", content: "x"},
+	}},
+	{name: "symlink chain", symlinks: true, entries: []entry{
+		{name: "self", typeflag: tar.TypeSymlink, linkname: "."},
+		{name: "up", typeflag: tar.TypeSymlink, linkname: "self/.."},
+	}},
+}
+
+func TestTarGz_RejectsAdversarialArchives(t *testing.T) {
+	// Hard links only exist in tar archives
+	adversarial := append(slices.Clone(adversarialArchives),
+		adversarialArchive{name: "escaping hard link", entries: []entry{{name: "node/link", typeflag: tar.TypeLink, linkname: "../evil"}}})
+
+	for _, tt := range adversarial {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.symlinks && runtime.GOOS == "windows" {
+				t.Skip("symlinks require privileges on Windows")
+			}
+			if tt.name == "windows parent path" && runtime.GOOS != "windows" {
+				t.Skip("backslash is a file name character outside of Windows")
+			}
+			tempDir := t.TempDir()
+			archivePath := filepath.Join(tempDir, "evil.tar.gz")
+			buildTarGz(t, archivePath, tt.entries)
+
+			assertRejected(t, Archive(archivePath, filepath.Join(tempDir, "out"), DefaultLimits), tempDir)
+		})
+	}
+}
+
+func TestZip_RejectsAdversarialArchives(t *testing.T) {
+	for _, tt := range adversarialArchives {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.symlinks && runtime.GOOS == "windows" {
+				t.Skip("symlinks require privileges on Windows")
+			}
+			if tt.name == "windows parent path" && runtime.GOOS != "windows" {
+				t.Skip("backslash is a file name character outside of Windows")
+			}
+			tempDir := t.TempDir()
+			archivePath := filepath.Join(tempDir, "evil.zip")
+			buildZip(t, archivePath, tt.entries)
+
+			assertRejected(t, Archive(archivePath, filepath.Join(tempDir, "out"), DefaultLimits), tempDir)
+		})
+	}
+}
+
+// assertRejected checks that extraction failed for the unsafe entry and nothing was written next to the destination
+func assertRejected(t *testing.T, err error, tempDir string) {
+	t.Helper()
+
+	var unsafeErr *UnsafeEntryError
+	var pathErr *fs.PathError // os.Root refuses writes through escaping links
+	if !errors.As(err, &unsafeErr) && !errors.As(err, &pathErr) {
+		t.Errorf("Expected the archive to be rejected, got: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(tempDir, "evil")); err == nil {
+		t.Errorf("File was written outside of the destination")
+	}
+}
+
+func TestArchive_Limits(t *testing.T) {
+	tempDir := t.TempDir()
+	limits := Limits{MaxEntrySize: 4, MaxEntries: 2}
+
+	oversized := filepath.Join(tempDir, "oversized.tar.gz")
+	buildTarGz(t, oversized, []entry{{name: "big", content: "12345"}})
+	var unsafeErr *UnsafeEntryError
+	if err := Archive(oversized, filepath.Join(tempDir, "out1"), limits); !errors.As(err, &unsafeErr) {
+		t.Errorf("Expected an oversized tar entry to be rejected, got: %v", err)
+	}
+
+	oversizedZip := filepath.Join(tempDir, "oversized.zip")
+	buildZip(t, oversizedZip, []entry{{name: "big", content: "12345"}})
+	if err := Archive(oversizedZip, filepath.Join(tempDir, "out2"), limits); !errors.As(err, &unsafeErr) {
+		t.Errorf("Expected an oversized zip entry to be rejected, got: %v", err)
+	}
+
+	many := filepath.Join(tempDir, "many.tar.gz")
+	buildTarGz(t, many, []entry{{name: "a"}, {name: "b"}, {name: "c"}})
+	if err := Archive(many, filepath.Join(tempDir, "out3"), limits); !errors.As(err, &unsafeErr) {
+		t.Errorf("Expected too many entries to be rejected, got: %v", err)
+	}
+}
+
+func TestCopy_Limit(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := Copy(&out, bytes.NewReader([]byte("1234")), "exact", 4); err != nil {
+		t.Errorf("Expected content of the limit size to be copied, got: %v", err)
+	}
+
+	var unsafeErr *UnsafeEntryError
+	if _, err := Copy(&out, bytes.NewReader([]byte("12345")), "big", 4); !errors.As(err, &unsafeErr) {
+		t.Errorf("Expected UnsafeEntryError, got: %v", err)
+	}
+}
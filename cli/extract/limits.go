@@ -0,0 +1,87 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/progress"
+)
+
+const (
+	// EnvMaxUnpackedSize overrides the largest uncompressed size of an archive or feed, e.g. 32GiB
+	EnvMaxUnpackedSize = "DEVRIG_MAX_UNPACKED_SIZE"
+	// EnvMaxCompressionRatio overrides the largest ratio of the uncompressed to the compressed size, e.g. 500
+	EnvMaxCompressionRatio = "DEVRIG_MAX_COMPRESSION_RATIO"
+)
+
+// minRatioCheckSize is the uncompressed size the ratio is checked from, small files of zeros compress far better
+// than any real distribution, a bomb needs to expand much further to do harm
+const minRatioCheckSize = 16 << 20
+
+// ResolveLimits returns DefaultLimits with the overrides of DEVRIG_MAX_UNPACKED_SIZE and DEVRIG_MAX_COMPRESSION_RATIO
+func ResolveLimits() (Limits, error) {
+	limits := DefaultLimits
+
+	if value := strings.TrimSpace(os.Getenv(EnvMaxUnpackedSize)); value != "" {
+		size, err := progress.ParseBytes(value)
+		if err != nil {
+			return Limits{}, fmt.Errorf("invalid %s value %q, expected a size like 16GiB or 512MiB", EnvMaxUnpackedSize, value)
+		}
+		limits.MaxTotalSize = size
+	}
+
+	if value := strings.TrimSpace(os.Getenv(EnvMaxCompressionRatio)); value != "" {
+		ratio, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || ratio <= 0 {
+			return Limits{}, fmt.Errorf("invalid %s value %q, expected a positive number like 200", EnvMaxCompressionRatio, value)
+		}
+		limits.MaxRatio = ratio
+	}
+	return limits, nil
+}
+
+// NewReader returns a reader of the decompressed stream that fails with a DecompressionLimitError
+// once more than MaxTotalSize bytes are read, or the bytes read exceed MaxRatio times the compressed size
+func NewReader(r io.Reader, subject string, compressedSize int64, limits Limits) io.Reader {
+	return newLimitedReader(r, subject, compressedSize, limits)
+}
+
+type limitedReader struct {
+	reader         io.Reader
+	subject        string
+	compressedSize int64
+	limits         Limits
+	// read is shared by the readers of the entries of one archive, see with
+	read *atomic.Int64
+}
+
+func newLimitedReader(r io.Reader, subject string, compressedSize int64, limits Limits) *limitedReader {
+	return &limitedReader{reader: r, subject: subject, compressedSize: compressedSize, limits: limits, read: &atomic.Int64{}}
+}
+
+// with returns a reader of an entry that counts towards the limits of the archive, it is safe for concurrent use
+func (r *limitedReader) with(entry io.Reader) *limitedReader {
+	shared := *r
+	shared.reader = entry
+	return &shared
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	read := r.read.Add(int64(n))
+
+	if r.limits.MaxTotalSize > 0 && read > r.limits.MaxTotalSize {
+		return n, &devrigErrors.DecompressionLimitError{Subject: r.subject,
+			Reason: fmt.Sprintf("it expands to more than %d bytes, set %s to raise the limit", r.limits.MaxTotalSize, EnvMaxUnpackedSize)}
+	}
+	if r.limits.MaxRatio > 0 && read > minRatioCheckSize && read/max(r.compressedSize, 1) > r.limits.MaxRatio {
+		return n, &devrigErrors.DecompressionLimitError{Subject: r.subject,
+			Reason: fmt.Sprintf("it expands more than %d times, set %s to raise the limit", r.limits.MaxRatio, EnvMaxCompressionRatio)}
+	}
+	return n, err
+}
@@ -0,0 +1,116 @@
+package extract
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+func TestResolveLimits(t *testing.T) {
+	t.Setenv(EnvMaxUnpackedSize, "")
+	t.Setenv(EnvMaxCompressionRatio, "")
+	limits, err := ResolveLimits()
+	if err != nil || limits != DefaultLimits {
+		t.Errorf("Expected the default limits, got: %+v, %v", limits, err)
+	}
+
+	t.Setenv(EnvMaxUnpackedSize, "512MiB")
+	t.Setenv(EnvMaxCompressionRatio, "50")
+	limits, err = ResolveLimits()
+	if err != nil {
+		t.Fatalf("Failed to resolve limits: %v", err)
+	}
+	if limits.MaxTotalSize != 512<<20 || limits.MaxRatio != 50 {
+		t.Errorf("Expected the overrides, got: %+v", limits)
+	}
+
+	for _, value := range []string{"lots", "-1", "0GiB", "12TB"} {
+		t.Setenv(EnvMaxUnpackedSize, value)
+		if _, err := ResolveLimits(); err == nil {
+			t.Errorf("Expected an error for %s=%s", EnvMaxUnpackedSize, value)
+		}
+	}
+	t.Setenv(EnvMaxUnpackedSize, "")
+	t.Setenv(EnvMaxCompressionRatio, "0")
+	if _, err := ResolveLimits(); err == nil {
+		t.Errorf("Expected an error for %s=0", EnvMaxCompressionRatio)
+	}
+}
+
+// gzipZeros compresses the zeros about a thousand times, far beyond any real distribution
+func gzipZeros(t *testing.T, size int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(make([]byte, size)); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestNewReader_Ratio(t *testing.T) {
+	compressed := gzipZeros(t, 2*minRatioCheckSize)
+	gz, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatalf("Failed to read gzip: %v", err)
+	}
+
+	_, err = io.Copy(io.Discard, NewReader(gz, "bomb.gz", int64(len(compressed)), DefaultLimits))
+	var limitErr *devrigErrors.DecompressionLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("Expected DecompressionLimitError, got: %v", err)
+	}
+
+	// Below the ratio floor small files of zeros are fine
+	small := gzipZeros(t, 1<<20)
+	gz, err = gzip.NewReader(bytes.NewReader(small))
+	if err != nil {
+		t.Fatalf("Failed to read gzip: %v", err)
+	}
+	if _, err := io.Copy(io.Discard, NewReader(gz, "small.gz", int64(len(small)), DefaultLimits)); err != nil {
+		t.Errorf("Expected a small file to pass, got: %v", err)
+	}
+}
+
+func TestArchive_DecompressionBombs(t *testing.T) {
+	tempDir := t.TempDir()
+	zeros := string(make([]byte, 2*minRatioCheckSize))
+
+	tarBomb := filepath.Join(tempDir, "bomb.tar.gz")
+	buildTarGz(t, tarBomb, []entry{{name: "zeros", content: zeros}})
+	err := Archive(tarBomb, filepath.Join(tempDir, "out1"), DefaultLimits)
+	var limitErr *devrigErrors.DecompressionLimitError
+	if !errors.As(err, &limitErr) {
+		t.Errorf("Expected the tar.gz bomb to be rejected, got: %v", err)
+	}
+
+	zipBomb := filepath.Join(tempDir, "bomb.zip")
+	buildZip(t, zipBomb, []entry{{name: "zeros", content: zeros}})
+	err = Archive(zipBomb, filepath.Join(tempDir, "out2"), DefaultLimits)
+	if !errors.As(err, &limitErr) {
+		t.Errorf("Expected the zip bomb to be rejected, got: %v", err)
+	}
+	if devrigErrors.ExitCode(err) != devrigErrors.ExitUnsafeArchive {
+		t.Errorf("Expected exit code %d, got: %d", devrigErrors.ExitUnsafeArchive, devrigErrors.ExitCode(err))
+	}
+
+	limits := DefaultLimits
+	limits.MaxTotalSize = 1 << 10
+	sized := filepath.Join(tempDir, "sized.zip")
+	buildZip(t, sized, []entry{{name: "a", content: string(make([]byte, 800))}, {name: "b", content: string(make([]byte, 800))}})
+	if err := Archive(sized, filepath.Join(tempDir, "out3"), limits); !errors.As(err, &limitErr) {
+		t.Errorf("Expected the total size limit to apply across entries, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "out3", "a")); err != nil {
+		t.Errorf("Expected the first entry to be extracted before the limit: %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package extract
+
+import (
+	"io"
+	"runtime"
+	"sync"
+)
+
+const (
+	// bufferedEntrySize is the largest tar entry that is read into memory and written by a worker,
+	// larger entries are written while reading the stream
+	bufferedEntrySize = 1 << 20
+	// readAheadBlockSize and readAheadBlocks bound the decompressed data that is prepared in advance
+	readAheadBlockSize = 256 << 10
+	readAheadBlocks    = 16
+)
+
+// defaultWorkers is the number of goroutines writing the entries, IDE archives have tens of thousands
+// of small files, so the writes are bound by the file system calls rather than the disk throughput
+func defaultWorkers() int {
+	return min(max(runtime.GOMAXPROCS(0), 2), 16)
+}
+
+// workerPool runs the writes of the entries on a bounded number of goroutines. The first error stops the pool,
+// the jobs queued after it are skipped
+type workerPool struct {
+	jobs    chan func() error
+	workers sync.WaitGroup
+	pending sync.WaitGroup
+
+	mu  sync.Mutex
+	err error
+}
+
+// newWorkerPool starts the workers, one worker or less runs every job in the calling goroutine
+func newWorkerPool(workers int) *workerPool {
+	pool := &workerPool{}
+	if workers <= 1 {
+		return pool
+	}
+	pool.jobs = make(chan func() error, workers)
+	for range workers {
+		pool.workers.Add(1)
+		go func() {
+			defer pool.workers.Done()
+			for job := range pool.jobs {
+				if pool.Err() == nil {
+					pool.fail(job())
+				}
+				pool.pending.Done()
+			}
+		}()
+	}
+	return pool
+}
+
+// Go queues the job, it returns the first error of the pool so the caller stops reading the archive
+func (p *workerPool) Go(job func() error) error {
+	if err := p.Err(); err != nil {
+		return err
+	}
+	if p.jobs == nil {
+		p.fail(job())
+		return p.Err()
+	}
+	p.pending.Add(1)
+	p.jobs <- job
+	return nil
+}
+
+// Wait blocks until the queued jobs are done, e.g. before a hard link to a file that may be still written
+func (p *workerPool) Wait() error {
+	p.pending.Wait()
+	return p.Err()
+}
+
+// Close waits for the queued jobs and stops the workers
+func (p *workerPool) Close() error {
+	if p.jobs != nil {
+		close(p.jobs)
+		p.workers.Wait()
+	}
+	return p.Err()
+}
+
+// Err returns the first error of the jobs
+func (p *workerPool) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *workerPool) fail(err error) {
+	if err == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// readAheadReader decompresses the stream in its own goroutine, so the next blocks are ready while the entries
+// are written. At most readAheadBlocks blocks are prepared
+type readAheadReader struct {
+	blocks  chan readAheadBlock
+	done    chan struct{}
+	stopped sync.Once
+	current readAheadBlock
+	free    sync.Pool
+}
+
+type readAheadBlock struct {
+	data []byte
+	buf  *[]byte
+	err  error
+}
+
+func newReadAheadReader(r io.Reader) *readAheadReader {
+	reader := &readAheadReader{
+		blocks: make(chan readAheadBlock, readAheadBlocks),
+		done:   make(chan struct{}),
+	}
+	reader.free.New = func() any {
+		buf := make([]byte, readAheadBlockSize)
+		return &buf
+	}
+	go reader.fill(r)
+	return reader
+}
+
+func (r *readAheadReader) fill(source io.Reader) {
+	defer close(r.blocks)
+	for {
+		buf := r.free.Get().(*[]byte)
+		n, err := io.ReadFull(source, *buf)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		select {
+		case r.blocks <- readAheadBlock{data: (*buf)[:n], buf: buf, err: err}:
+		case <-r.done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (r *readAheadReader) Read(p []byte) (int, error) {
+	for len(r.current.data) == 0 {
+		if r.current.err != nil {
+			return 0, r.current.err
+		}
+		if r.current.buf != nil {
+			r.free.Put(r.current.buf)
+		}
+		block, ok := <-r.blocks
+		if !ok {
+			return 0, io.ErrClosedPipe
+		}
+		r.current = block
+	}
+	n := copy(p, r.current.data)
+	r.current.data = r.current.data[n:]
+	return n, nil
+}
+
+// Close stops the decompression goroutine, the source must not be closed before
+func (r *readAheadReader) Close() error {
+	r.stopped.Do(func() {
+		close(r.done)
+		// The goroutine returns once it sees done, drain it so it is not blocked on a send
+		for range r.blocks {
+		}
+	})
+	return nil
+}
@@ -0,0 +1,151 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestTarGz_ParallelKeepsArchiveOrder(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test checks hard links")
+	}
+	archive := filepath.Join(t.TempDir(), "order.tar.gz")
+	entries := []entry{{name: "app/", typeflag: tar.TypeDir, mode: 0755}}
+	for i := range 200 {
+		entries = append(entries, entry{name: fmt.Sprintf("app/lib/file-%03d.txt", i), content: fmt.Sprintf("content %d", i)})
+	}
+	entries = append(entries,
+		entry{name: "app/bin/tool", content: "first", mode: 0755},
+		entry{name: "app/bin/tool", content: "second", mode: 0755},
+		entry{name: "app/bin/tool-link", typeflag: tar.TypeLink, linkname: "app/bin/tool"},
+	)
+	buildTarGz(t, archive, entries)
+
+	for _, workers := range []int{1, 8} {
+		dest := filepath.Join(t.TempDir(), "dest")
+		if err := tarGz(archive, dest, DefaultLimits, workers); err != nil {
+			t.Fatalf("Failed to extract with %d workers: %v", workers, err)
+		}
+		for _, name := range []string{"app/bin/tool", "app/bin/tool-link"} {
+			if data, err := os.ReadFile(filepath.Join(dest, name)); err != nil || string(data) != "second" {
+				t.Errorf("Expected the last entry in %s with %d workers, got %q, %v", name, workers, data, err)
+			}
+		}
+		if data, err := os.ReadFile(filepath.Join(dest, "app/lib/file-199.txt")); err != nil || string(data) != "content 199" {
+			t.Errorf("Unexpected content with %d workers: %q, %v", workers, data, err)
+		}
+	}
+}
+
+func TestZip_ParallelReportsErrors(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "bomb.zip")
+	var entries []entry
+	for i := range 20 {
+		entries = append(entries, entry{name: fmt.Sprintf("file-%d.txt", i), content: string(make([]byte, 1024))})
+	}
+	buildZip(t, archive, entries)
+
+	limits := DefaultLimits
+	limits.MaxTotalSize = 10 * 1024
+	if err := unzip(archive, filepath.Join(t.TempDir(), "dest"), limits, 8); err == nil {
+		t.Error("Expected the total size limit to be enforced across the workers")
+	}
+}
+
+// benchmarkFiles is the shape of an IDE distribution: many small class and resource files, a few large jars
+func benchmarkFiles() map[string][]byte {
+	random := rand.New(rand.NewPCG(1, 2))
+	files := map[string][]byte{}
+	for i := range 2000 {
+		files[fmt.Sprintf("ide/lib/module-%d/file-%d.class", i%40, i)] = benchmarkContent(random, 4<<10+random.IntN(16<<10))
+	}
+	for i := range 4 {
+		files[fmt.Sprintf("ide/lib/library-%d.jar", i)] = benchmarkContent(random, 2<<20)
+	}
+	return files
+}
+
+// benchmarkContent is compressible about as well as class files
+func benchmarkContent(random *rand.Rand, size int) []byte {
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte('a' + random.IntN(16))
+	}
+	return content
+}
+
+func writeBenchmarkTarGz(b *testing.B, path string) {
+	file, err := os.Create(path)
+	if err != nil {
+		b.Fatalf("Failed to create archive: %v", err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for name, content := range benchmarkFiles() {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			b.Fatalf("Failed to write tar header: %v", err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			b.Fatalf("Failed to write tar entry: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		b.Fatalf("Failed to close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		b.Fatalf("Failed to close gzip: %v", err)
+	}
+}
+
+func writeBenchmarkZip(b *testing.B, path string) {
+	file, err := os.Create(path)
+	if err != nil {
+		b.Fatalf("Failed to create archive: %v", err)
+	}
+	defer file.Close()
+	zw := zip.NewWriter(file)
+	for name, content := range benchmarkFiles() {
+		w, err := zw.Create(name)
+		if err != nil {
+			b.Fatalf("Failed to create zip entry: %v", err)
+		}
+		if _, err := w.Write(content); err != nil {
+			b.Fatalf("Failed to write zip entry: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		b.Fatalf("Failed to close zip: %v", err)
+	}
+}
+
+func benchmarkExtract(b *testing.B, write func(*testing.B, string), name string, extract func(string, string, Limits, int) error) {
+	archive := filepath.Join(b.TempDir(), name)
+	write(b, archive)
+	for _, workers := range []int{1, defaultWorkers()} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; b.Loop(); i++ {
+				if err := extract(archive, filepath.Join(b.TempDir(), fmt.Sprint(i)), DefaultLimits, workers); err != nil {
+					b.Fatalf("Failed to extract: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkTarGz compares the sequential extraction with the pipeline, run it with
+// go test ./extract -run '^$' -bench .
+func BenchmarkTarGz(b *testing.B) {
+	benchmarkExtract(b, writeBenchmarkTarGz, "ide.tar.gz", tarGz)
+}
+
+func BenchmarkZip(b *testing.B) {
+	benchmarkExtract(b, writeBenchmarkZip, "ide.zip", unzip)
+}
@@ -3,9 +3,12 @@ package feed
 import (
 	"context"
 	"fmt"
+	"runtime"
 
 	"jonnyzzz.com/devrig.dev/config"
 	"jonnyzzz.com/devrig.dev/feed_api"
+	"jonnyzzz.com/devrig.dev/lock"
+	"jonnyzzz.com/devrig.dev/offline"
 )
 
 func (entry *feedEntry) Name() string {
@@ -28,16 +31,87 @@ func (entry *feedEntry) IdeType() string {
 }
 
 func ResolveRemoteIdeByConfig(ideRequest config.IDEConfig) (feed_api.RemoteIDE, error) {
-	entries, err := downloadAndProcessFeedImpl(context.Background(), getFeedUrls())
+	entries, err := downloadAndProcessFeedImpl(context.Background(), FeedURLs())
 	if err != nil {
 		return nil, err
 	}
+	return findEntry(entries, ideRequest, PackageTypesFor(runtime.GOOS, nil))
+}
+
+// ResolveRemoteIde finds the feed entry of the IDE for the current platform, packageTypes is the preference order
+// of the package types per OS from the ide section, the defaults are used without it
+func ResolveRemoteIde(ctx context.Context, ideRequest config.IDEConfig, packageTypes map[string][]string) (feed_api.RemoteIDE, error) {
+	if err := offline.Check(ideRequest.Name()+" "+ideRequest.Version(), FeedURLs()[0], "install the IDE on a machine with network access"); err != nil {
+		return nil, err
+	}
+	entries, err := downloadAndProcessFeedImpl(ctx, FeedURLs())
+	if err != nil {
+		return nil, err
+	}
+	return findEntry(entries, ideRequest, PackageTypesFor(runtime.GOOS, packageTypes))
+}
+
+// ResolveRelease finds the release ResolveRemoteIde would download on the current platform, without downloading it
+func ResolveRelease(ctx context.Context, ideRequest config.IDEConfig, packageTypes map[string][]string) (*Release, error) {
+	remote, err := ResolveRemoteIde(ctx, ideRequest, packageTypes)
+	if err != nil {
+		return nil, err
+	}
+	release := remote.(*feedEntry).release()
+	return &release, nil
+}
 
-	var result *feedEntry
-	result = nil
+// IdeLockName is the name of the IDE in devrig.lock
+const IdeLockName = "ide"
 
-	for _, p := range entries {
-		entry := p
+// IdeLockRequest returns the request the IDE is locked under, e.g. IntelliJ IDEA Ultimate 2024.3
+func IdeLockRequest(ideRequest config.IDEConfig) string {
+	request := ideRequest.Name() + " " + ideRequest.Version()
+	if build := ideRequest.Build(); build != "" {
+		request += " " + build
+	}
+	return request
+}
+
+// LockIde resolves the feed entry of the IDE on the platform for devrig.lock,
+// packageTypes is the preference order of the package types per OS from the ide section, the defaults are used without it
+func LockIde(ctx context.Context, ideRequest config.IDEConfig, packageTypes map[string][]string, goos string, goarch string) (*lock.Artifact, error) {
+	entries, err := downloadFeedEntries(ctx, FeedURLs())
+	if err != nil {
+		return nil, err
+	}
+	entries, err = filterEntriesByPlatform(entries, goos, goarch)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := findEntry(entries, ideRequest, PackageTypesFor(goos, packageTypes))
+	if err != nil {
+		return nil, err
+	}
+	checksum := entry.sha256()
+	if checksum == "" {
+		return nil, fmt.Errorf("the feed has no SHA-256 checksum for %s", entry.Package.URL)
+	}
+
+	return &lock.Artifact{
+		Name:     IdeLockName,
+		Request:  IdeLockRequest(ideRequest),
+		Platform: lock.Platform(goos, goarch),
+		Version:  entry.Version,
+		Build:    entry.BuildV,
+		URL:      entry.Package.URL,
+		Size:     entry.Package.Size,
+		Checksum: checksum,
+	}, nil
+}
+
+// findEntry returns the newest build matching the name, version and build of the request,
+// packaged as the first of the package types the build is published with
+func findEntry(entries []feedEntry, ideRequest config.IDEConfig, packageTypes []string) (*feedEntry, error) {
+	var candidates []feedEntry
+
+	for _, entry := range entries {
 		if entry.NameV != ideRequest.Name() {
 			continue
 		}
@@ -50,15 +124,29 @@ func ResolveRemoteIdeByConfig(ideRequest config.IDEConfig) (feed_api.RemoteIDE,
 			continue
 		}
 
-		if result == nil || result.OrderEntry < entry.OrderEntry {
-			result = &entry
+		switch {
+		case len(candidates) == 0 || candidates[0].OrderEntry < entry.OrderEntry:
+			candidates = []feedEntry{entry}
+		case candidates[0].OrderEntry == entry.OrderEntry && candidates[0].BuildV == entry.BuildV:
+			// The packages of one build, e.g. the .dmg and the .zip for macOS
+			candidates = append(candidates, entry)
 		}
 	}
 
-	if result != nil {
-		return result, nil
+	if len(candidates) > 0 {
+		return selectPackage(candidates, packageTypes)
 	}
 
 	return nil, fmt.Errorf("IDE not found in feed - Name: %s, Version: %s, Build: %s",
 		ideRequest.Name(), ideRequest.Version(), ideRequest.Build())
 }
+
+// sha256 returns the SHA-256 checksum of the package, or an empty string
+func (entry *feedEntry) sha256() string {
+	for _, checksum := range entry.Package.Checksums {
+		if checksum.Algorithm == "sha-256" {
+			return checksum.Value
+		}
+	}
+	return ""
+}
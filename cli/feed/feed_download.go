@@ -9,9 +9,23 @@ import (
 
 	"github.com/ulikunitz/xz"
 	"go.mozilla.org/pkcs7"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/extract"
+	"jonnyzzz.com/devrig.dev/offline"
+)
+
+const (
+	// maxSignedFeedSize limits the downloaded signed feed
+	maxSignedFeedSize = 64 << 20
+	// maxDecompressedFeedSize limits the decompressed feed, so a small xz bomb cannot exhaust the memory
+	maxDecompressedFeedSize = 512 << 20
 )
 
 func downloadAndValidateFeedUrl(ctx context.Context, url string) ([]byte, error) {
+	if err := offline.Check("JetBrains IDE feed", url, "resolving IDEs needs network access, download the IDE once online"); err != nil {
+		return nil, err
+	}
+
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w for %s", err, url)
@@ -19,26 +33,34 @@ func downloadAndValidateFeedUrl(ctx context.Context, url string) ([]byte, error)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download feed: %w for %s", err, url)
+		return nil, fmt.Errorf("failed to download feed: %w", &devrigErrors.NetworkError{URL: url, Err: err})
 	}
 	defer func(Body io.ReadCloser) {
 		_ = Body.Close()
 	}(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d for %s", resp.StatusCode, url)
+		return nil, &devrigErrors.NetworkError{URL: url, Err: fmt.Errorf("unexpected status code: %d", resp.StatusCode)}
 	}
 
 	// Read PKCS7 data
-	signedData, err := io.ReadAll(resp.Body)
+	signedData, err := io.ReadAll(io.LimitReader(resp.Body, maxSignedFeedSize+1))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read signed data: %w for %s", err, url)
 	}
+	if len(signedData) > maxSignedFeedSize {
+		return nil, fmt.Errorf("the signed feed is larger than %d bytes for %s", maxSignedFeedSize, url)
+	}
+
+	return decodeSignedFeed(signedData, url)
+}
 
+// decodeSignedFeed extracts and decompresses the content of the PKCS7 envelope of the feed
+func decodeSignedFeed(signedData []byte, url string) ([]byte, error) {
 	// Parse PKCS7
-	p7, err := pkcs7.Parse(signedData)
+	p7, err := parsePKCS7(signedData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse signed data: %w fopr %s", err, url)
+		return nil, fmt.Errorf("failed to parse signed data: %w for %s", err, url)
 	}
 
 	//TODO: implement signature verification
@@ -52,11 +74,29 @@ func downloadAndValidateFeedUrl(ctx context.Context, url string) ([]byte, error)
 		return nil, fmt.Errorf("failed to create xz reader: %w for %s", err, url)
 	}
 
+	// The feed is read into memory, so the configured size limit is capped by maxDecompressedFeedSize
+	limits, err := extract.ResolveLimits()
+	if err != nil {
+		return nil, err
+	}
+	limits.MaxTotalSize = min(limits.MaxTotalSize, maxDecompressedFeedSize)
+
 	// Read all decompressed content
-	decompressed, err := io.ReadAll(xzReader)
+	decompressed, err := io.ReadAll(extract.NewReader(xzReader, url, int64(len(content)), limits))
 	if err != nil {
 		return nil, fmt.Errorf("failed to decompress content: %w for %s", err, url)
 	}
 
 	return decompressed, nil
 }
+
+// parsePKCS7 parses the envelope, the BER decoder of the pkcs7 library panics on some malformed inputs
+func parsePKCS7(data []byte) (p7 *pkcs7.PKCS7, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p7 = nil
+			err = fmt.Errorf("malformed PKCS7 data: %v", r)
+		}
+	}()
+	return pkcs7.Parse(data)
+}
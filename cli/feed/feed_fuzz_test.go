@@ -0,0 +1,114 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+	"go.mozilla.org/pkcs7"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/extract"
+)
+
+// signFeed wraps the feed JSON the way the JetBrains feed is served: xz-compressed inside a PKCS7 envelope
+func signFeed(tb testing.TB, feedJSON string) []byte {
+	tb.Helper()
+	var compressed bytes.Buffer
+	writer, err := xz.NewWriter(&compressed)
+	if err != nil {
+		tb.Fatalf("Failed to create xz writer: %v", err)
+	}
+	if _, err := writer.Write([]byte(feedJSON)); err != nil {
+		tb.Fatalf("Failed to compress feed: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		tb.Fatalf("Failed to compress feed: %v", err)
+	}
+
+	signedData, err := pkcs7.NewSignedData(compressed.Bytes())
+	if err != nil {
+		tb.Fatalf("Failed to create signed data: %v", err)
+	}
+	envelope, err := signedData.Finish()
+	if err != nil {
+		tb.Fatalf("Failed to finish signed data: %v", err)
+	}
+	return envelope
+}
+
+func FuzzParseFeedList(f *testing.F) {
+	f.Add([]byte(`{"feeds": [{"url": "https://example.com/feed.json"}], "entries": []}`))
+	f.Add([]byte(`{"entries": [{"name": "IDE", "package": {"os": "linux", "requirements": {"cpu_arch": {"$eq": "x64"}}}}]}`))
+	f.Add([]byte(`{"entries": [{"name": "no package"}, null]}`))
+	f.Add([]byte(`[]`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		list, err := parseFeedList(data)
+		if err != nil {
+			return
+		}
+		for _, entry := range list.Entries {
+			if entry.Package == nil {
+				t.Fatalf("Expected entries without a package to be dropped")
+			}
+		}
+		if _, err := filterEntriesByPlatform(list.Entries, "linux", "amd64"); err != nil {
+			t.Fatalf("Failed to filter entries: %v", err)
+		}
+	})
+}
+
+func FuzzDecodeSignedFeed(f *testing.F) {
+	f.Add(signFeed(f, `{"entries": []}`))
+	f.Add([]byte{0x30, 0x80})
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = decodeSignedFeed(data, "https://example.com/feed")
+	})
+}
+
+func TestDownloadAndProcessFeed_NestedFeedLimit(t *testing.T) {
+	// Every feed references the next one, the chain never ends
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next := fmt.Sprintf(`{"feeds": [{"url": "%s%s/next"}]}`, server.URL, r.URL.Path)
+		_, _ = w.Write(signFeed(t, next))
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := downloadAndProcessFeedImpl(context.Background(), []string{server.URL + "/feed"})
+	if err == nil || !strings.Contains(err.Error(), "too many nested feeds") {
+		t.Fatalf("Expected a nested feed limit error, got %v", err)
+	}
+}
+
+func TestParseFeedList_DropsEntriesWithoutPackage(t *testing.T) {
+	list, err := parseFeedList([]byte(`{"entries": [{"name": "broken"}, {"name": "IDE", "package": {"os": "linux"}}]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse feed: %v", err)
+	}
+	if len(list.Entries) != 1 || list.Entries[0].NameV != "IDE" {
+		t.Errorf("Expected only the entry with a package, got %v", list.Entries)
+	}
+}
+
+func TestDecodeSignedFeed_DecompressionLimit(t *testing.T) {
+	t.Setenv(extract.EnvMaxUnpackedSize, "1MiB")
+	signed := signFeed(t, strings.Repeat(" ", 2<<20))
+
+	_, err := decodeSignedFeed(signed, "https://example.com/feed.xz")
+	var limitErr *devrigErrors.DecompressionLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("Expected DecompressionLimitError, got: %v", err)
+	}
+	if devrigErrors.ExitCode(err) != devrigErrors.ExitUnsafeArchive {
+		t.Errorf("Expected exit code %d, got: %d", devrigErrors.ExitUnsafeArchive, devrigErrors.ExitCode(err))
+	}
+}
@@ -0,0 +1,109 @@
+package feed
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/offline"
+)
+
+// Release is a package of a product build in the JetBrains feed
+type Release struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Build       string `json:"build"`
+	Quality     string `json:"quality,omitempty"`
+	Released    string `json:"released,omitempty"`
+	OS          string `json:"os"`
+	Arch        string `json:"arch"`
+	PackageType string `json:"package_type"`
+	Size        int64  `json:"size,omitempty"`
+	URL         string `json:"url"`
+	// ProductCode is the code of the IntelliJ-based products, e.g. GO, the plugins are compatible with <code>-<build>
+	ProductCode string `json:"product_code,omitempty"`
+}
+
+// ReleaseFilter selects the releases of ListReleases, empty fields match everything
+type ReleaseFilter struct {
+	// Name is a part of the product name, compared ignoring the case
+	Name string
+	// Quality is the quality of the build, e.g. release or eap
+	Quality string
+	// GOOS and GOARCH select the platform with the Go names, e.g. darwin and arm64
+	GOOS   string
+	GOARCH string
+}
+
+// ListReleases downloads the feed and returns the matching releases sorted by the product name, the newest builds first
+func ListReleases(ctx context.Context, filter ReleaseFilter) ([]Release, error) {
+	if err := offline.Check("the JetBrains feed", FeedURLs()[0], "browse the feed on a machine with network access"); err != nil {
+		return nil, err
+	}
+	entries, err := downloadFeedEntries(ctx, FeedURLs())
+	if err != nil {
+		return nil, err
+	}
+	return filterReleases(entries, filter)
+}
+
+// filterReleases converts the entries that match the filter into releases
+func filterReleases(entries []feedEntry, filter ReleaseFilter) ([]Release, error) {
+	if filter.GOOS != "" || filter.GOARCH != "" {
+		var err error
+		if entries, err = filterEntriesByPlatform(entries, filter.GOOS, filter.GOARCH); err != nil {
+			return nil, err
+		}
+	}
+
+	var matched []feedEntry
+	for _, entry := range entries {
+		if filter.Name != "" && !strings.Contains(strings.ToLower(entry.NameV), strings.ToLower(filter.Name)) {
+			continue
+		}
+		if filter.Quality != "" && !strings.EqualFold(entry.quality(), filter.Quality) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].NameV != matched[j].NameV {
+			return matched[i].NameV < matched[j].NameV
+		}
+		return matched[i].OrderEntry > matched[j].OrderEntry
+	})
+
+	releases := make([]Release, 0, len(matched))
+	for _, entry := range matched {
+		releases = append(releases, entry.release())
+	}
+	return releases, nil
+}
+
+// release converts the entry into the release of ListReleases
+func (entry *feedEntry) release() Release {
+	release := Release{
+		Name:        entry.NameV,
+		Version:     entry.Version,
+		Build:       entry.BuildV,
+		Quality:     entry.quality(),
+		Released:    entry.Released,
+		OS:          entry.Package.OS,
+		Arch:        entry.Package.Requirements.CPUArch.Equals,
+		PackageType: entry.Package.Type,
+		Size:        entry.Package.Size,
+		URL:         entry.Package.URL,
+	}
+	if entry.IntelliJ != nil {
+		release.ProductCode = entry.IntelliJ.IntelliJProductCode
+	}
+	return release
+}
+
+// quality returns the quality of the build, e.g. release or eap, or an empty string
+func (entry *feedEntry) quality() string {
+	if entry.Quality == nil {
+		return ""
+	}
+	return entry.Quality.QualityName
+}
@@ -0,0 +1,50 @@
+package feed
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFilterReleases(t *testing.T) {
+	var entries []feedEntry
+	err := json.Unmarshal([]byte(`[
+		{"name": "GoLand", "version": "2024.3", "build": "243.1", "order_value": 1, "quality": {"name": "release"},
+		 "package": {"os": "linux", "type": "targz", "requirements": {"cpu_arch": {"$eq": "x64"}}}},
+		{"name": "GoLand", "version": "2025.1", "build": "251.1", "order_value": 2, "quality": {"name": "EAP"},
+		 "package": {"os": "linux", "type": "targz", "requirements": {"cpu_arch": {"$eq": "x64"}}}},
+		{"name": "GoLand", "version": "2024.3", "build": "243.1", "order_value": 1, "quality": {"name": "release"},
+		 "package": {"os": "mac", "type": "dmg", "requirements": {"cpu_arch": {"$eq": "arm64"}}}},
+		{"name": "Rider", "version": "2024.3", "build": "243.5", "order_value": 1, "quality": {"name": "release"},
+		 "package": {"os": "linux", "type": "targz", "requirements": {"cpu_arch": {"$eq": "x64"}}}}
+	]`), &entries)
+	if err != nil {
+		t.Fatalf("Failed to parse entries: %v", err)
+	}
+
+	releases, err := filterReleases(entries, ReleaseFilter{Name: "goland", GOOS: "linux", GOARCH: "amd64"})
+	if err != nil {
+		t.Fatalf("Failed to filter releases: %v", err)
+	}
+	if len(releases) != 2 || releases[0].Build != "251.1" || releases[1].Build != "243.1" {
+		t.Errorf("Expected the GoLand builds for linux, the newest first, got %+v", releases)
+	}
+
+	releases, err = filterReleases(entries, ReleaseFilter{Quality: "eap"})
+	if err != nil {
+		t.Fatalf("Failed to filter releases: %v", err)
+	}
+	if len(releases) != 1 || releases[0].Version != "2025.1" {
+		t.Errorf("Expected the EAP build, got %+v", releases)
+	}
+
+	releases, err = filterReleases(entries, ReleaseFilter{})
+	if err != nil {
+		t.Fatalf("Failed to filter releases: %v", err)
+	}
+	if len(releases) != 4 || releases[3].Name != "Rider" {
+		t.Errorf("Expected all releases sorted by the name, got %+v", releases)
+	}
+	if releases[2].OS != "mac" || releases[2].Arch != "arm64" || releases[2].PackageType != "dmg" {
+		t.Errorf("Unexpected platform of the release: %+v", releases[2])
+	}
+}
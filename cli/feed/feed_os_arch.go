@@ -1,15 +1,15 @@
 package feed
 
 import (
-	"log"
 	"runtime"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
 )
 
-func resolveOsAndArch() (os string, arch string) {
-	// Detect OS
-	os = runtime.GOOS
-	// Detect CPU architecture
-	arch = runtime.GOARCH
+// resolveOsAndArch maps the Go platform names to the names used in the JetBrains feed
+func resolveOsAndArch(goos string, goarch string) (os string, arch string, err error) {
+	os = goos
+	arch = goarch
 
 	if os == "darwin" {
 		os = "mac"
@@ -24,28 +24,31 @@ func resolveOsAndArch() (os string, arch string) {
 	case "linux":
 	case "mac":
 	default:
-		log.Fatalln("Unknown operating system: ", os)
+		return "", "", &devrigErrors.UnsupportedPlatformError{OS: goos}
 	}
 
 	switch arch {
 	case "arm64":
 	case "x64":
 	default:
-		log.Fatalln("Unknown arch: ", arch)
+		return "", "", &devrigErrors.UnsupportedPlatformError{OS: goos, Arch: goarch}
 	}
 
-	return
+	return os, arch, nil
 }
 
-func filterEntriesByOsAndArch(slice []feedEntry) []feedEntry {
-	targetOS, targetArch := resolveOsAndArch()
-
-	var result []feedEntry
+func filterEntriesByOsAndArch(slice []feedEntry) ([]feedEntry, error) {
+	return filterEntriesByPlatform(slice, runtime.GOOS, runtime.GOARCH)
+}
 
-	if slice == nil {
-		return result
+func filterEntriesByPlatform(slice []feedEntry, goos string, goarch string) ([]feedEntry, error) {
+	targetOS, targetArch, err := resolveOsAndArch(goos, goarch)
+	if err != nil {
+		return nil, err
 	}
 
+	var result []feedEntry
+
 	for _, entry := range slice {
 		if entry.Package.OS != targetOS {
 			continue
@@ -58,5 +61,5 @@ func filterEntriesByOsAndArch(slice []feedEntry) []feedEntry {
 		result = append(result, entry) // Append items that satisfy the predicate
 	}
 
-	return result
+	return result, nil
 }
@@ -0,0 +1,50 @@
+package feed
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+func TestFilterEntriesByPlatform(t *testing.T) {
+	var entries []feedEntry
+	err := json.Unmarshal([]byte(`[
+		{"name": "mac-arm", "package": {"os": "mac", "requirements": {"cpu_arch": {"$eq": "arm64"}}}},
+		{"name": "linux-x64", "package": {"os": "linux", "requirements": {"cpu_arch": {"$eq": "x64"}}}},
+		{"name": "linux-arm", "package": {"os": "linux", "requirements": {"cpu_arch": {"$eq": "arm64"}}}}
+	]`), &entries)
+	if err != nil {
+		t.Fatalf("Failed to parse entries: %v", err)
+	}
+
+	filtered, err := filterEntriesByPlatform(entries, "linux", "amd64")
+	if err != nil {
+		t.Fatalf("Failed to filter entries: %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].NameV != "linux-x64" {
+		t.Errorf("Expected only linux-x64, got %v", filtered)
+	}
+
+	filtered, err = filterEntriesByPlatform(entries, "darwin", "arm64")
+	if err != nil {
+		t.Fatalf("Failed to filter entries: %v", err)
+	}
+	if len(filtered) != 1 || filtered[0].NameV != "mac-arm" {
+		t.Errorf("Expected only mac-arm, got %v", filtered)
+	}
+}
+
+func TestFilterEntriesByPlatform_Unsupported(t *testing.T) {
+	for _, platform := range [][2]string{{"plan9", "amd64"}, {"linux", "riscv64"}} {
+		_, err := filterEntriesByPlatform(nil, platform[0], platform[1])
+		var unsupported *devrigErrors.UnsupportedPlatformError
+		if !errors.As(err, &unsupported) {
+			t.Fatalf("Expected UnsupportedPlatformError for %s/%s, got %v", platform[0], platform[1], err)
+		}
+		if devrigErrors.ExitCode(err) != devrigErrors.ExitUnsupportedPlatform {
+			t.Errorf("Expected exit code %d, got %d", devrigErrors.ExitUnsupportedPlatform, devrigErrors.ExitCode(err))
+		}
+	}
+}
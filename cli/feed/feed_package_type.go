@@ -0,0 +1,48 @@
+package feed
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultPackageTypes is the preference order of the package types of the JetBrains feed per OS,
+// a build is downloaded as the first type of the list it is published with
+var DefaultPackageTypes = map[string][]string{
+	"linux":   {"targz"},
+	"darwin":  {"dmg", "zip"},
+	"windows": {"zip", "exe"},
+}
+
+// PackageTypesFor returns the preference order of the package types for the OS,
+// the order of the ide section of devrig.yaml replaces the default one
+func PackageTypesFor(goos string, preferences map[string][]string) []string {
+	if types, ok := preferences[goos]; ok && len(types) > 0 {
+		return types
+	}
+	return DefaultPackageTypes[goos]
+}
+
+// selectPackage returns the entry with the most preferred package type among the entries of one build.
+// The first entry is returned if there is no preference
+func selectPackage(candidates []feedEntry, packageTypes []string) (*feedEntry, error) {
+	if len(packageTypes) == 0 {
+		return &candidates[0], nil
+	}
+	for _, packageType := range packageTypes {
+		for i := range candidates {
+			if candidates[i].Package.Type == packageType {
+				return &candidates[i], nil
+			}
+		}
+	}
+
+	var available []string
+	for _, candidate := range candidates {
+		if !slices.Contains(available, candidate.Package.Type) {
+			available = append(available, candidate.Package.Type)
+		}
+	}
+	return nil, fmt.Errorf("%s %s is not published as %s, the feed has %s",
+		candidates[0].NameV, candidates[0].BuildV, strings.Join(packageTypes, ", "), strings.Join(available, ", "))
+}
@@ -0,0 +1,54 @@
+package feed
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/config"
+)
+
+func TestFindEntry_PackageTypes(t *testing.T) {
+	var entries []feedEntry
+	err := json.Unmarshal([]byte(`[
+		{"name": "GoLand", "version": "2024.3", "build": "243.1", "order_value": 1, "package": {"type": "dmg", "url": "old.dmg"}},
+		{"name": "GoLand", "version": "2024.3", "build": "243.2", "order_value": 2, "package": {"type": "dmg", "url": "new.dmg"}},
+		{"name": "GoLand", "version": "2024.3", "build": "243.2", "order_value": 2, "package": {"type": "zip", "url": "new.zip"}}
+	]`), &entries)
+	if err != nil {
+		t.Fatalf("Failed to parse entries: %v", err)
+	}
+	request := config.NewIDEConfig("GoLand", "2024.3", "")
+
+	for _, tc := range []struct {
+		packageTypes []string
+		expectedURL  string
+	}{
+		{nil, "new.dmg"},
+		{PackageTypesFor("darwin", nil), "new.dmg"},
+		{PackageTypesFor("darwin", map[string][]string{"darwin": {"zip", "dmg"}}), "new.zip"},
+		{[]string{"targz", "dmg"}, "new.dmg"},
+	} {
+		entry, err := findEntry(entries, request, tc.packageTypes)
+		if err != nil {
+			t.Fatalf("Failed to find the entry for %v: %v", tc.packageTypes, err)
+		}
+		if entry.Package.URL != tc.expectedURL {
+			t.Errorf("Expected %s for %v, got %s", tc.expectedURL, tc.packageTypes, entry.Package.URL)
+		}
+	}
+
+	_, err = findEntry(entries, request, []string{"targz"})
+	if err == nil || !strings.Contains(err.Error(), "the feed has dmg, zip") {
+		t.Errorf("Expected an error listing the package types of the build, got %v", err)
+	}
+}
+
+func TestPackageTypesFor(t *testing.T) {
+	if types := PackageTypesFor("linux", map[string][]string{"darwin": {"zip"}}); len(types) != 1 || types[0] != "targz" {
+		t.Errorf("Expected the default for linux, got %v", types)
+	}
+	if types := PackageTypesFor("plan9", nil); len(types) != 0 {
+		t.Errorf("Expected no preference for an unknown OS, got %v", types)
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 )
 
+// maxNestedFeeds limits the number of feeds processed for one request, so a malicious feed cannot reference feeds endlessly
+const maxNestedFeeds = 64
+
 type feedList struct {
 	Feeds   []nestedFeed `json:"feeds"`
 	Entries []feedEntry  `json:"entries"`
@@ -22,7 +25,7 @@ type feedEntry struct {
 	Version      string                    `json:"version"`
 	Released     string                    `json:"released"`
 	Package      *feedItemPackage          `json:"package"`
-	Quality      *feedItemQuality          `json:"feedItemQuality"`
+	Quality      *feedItemQuality          `json:"quality"`
 	OrderEntry   int64                     `json:"order_value"`
 	IntelliJ     *feedItemIntelliJMetadata `json:"intellij_platform"`
 }
@@ -63,6 +66,15 @@ type feedItemChecksum struct {
 }
 
 func downloadAndProcessFeedImpl(ctx context.Context, urlsToProcess []string) ([]feedEntry, error) {
+	entries, err := downloadFeedEntries(ctx, urlsToProcess)
+	if err != nil {
+		return []feedEntry{}, err
+	}
+	return filterEntriesByOsAndArch(entries)
+}
+
+// downloadFeedEntries downloads the feeds with the nested feeds and returns the entries of all platforms
+func downloadFeedEntries(ctx context.Context, urlsToProcess []string) ([]feedEntry, error) {
 	processed := map[string]bool{}
 	queueOfUrls := []string{}
 	entries := []feedEntry{}
@@ -78,6 +90,9 @@ func downloadAndProcessFeedImpl(ctx context.Context, urlsToProcess []string) ([]
 		}
 
 		processed[url] = true
+		if len(processed) > maxNestedFeeds {
+			return []feedEntry{}, fmt.Errorf("too many nested feeds, the limit is %d", maxNestedFeeds)
+		}
 
 		select {
 		case <-ctx.Done():
@@ -90,18 +105,38 @@ func downloadAndProcessFeedImpl(ctx context.Context, urlsToProcess []string) ([]
 			return []feedEntry{}, fmt.Errorf("failed to download feed: %w for %s", err, url)
 		}
 
-		var list feedList
-		err = json.Unmarshal(decompressed, &list)
+		list, err := parseFeedList(decompressed)
 		if err != nil {
 			return []feedEntry{}, fmt.Errorf("failed to parse nested feeds: %w for %s", err, url)
 		}
 
 		for _, nestedFeed := range list.Feeds {
+			if nestedFeed.URL == "" {
+				continue
+			}
 			queueOfUrls = append(queueOfUrls, nestedFeed.URL)
 		}
 
-		entries = append(entries, filterEntriesByOsAndArch(list.Entries)...)
+		entries = append(entries, list.Entries...)
 	}
 
 	return entries, nil
 }
+
+// parseFeedList parses the decompressed feed, entries without a package are dropped
+func parseFeedList(data []byte) (*feedList, error) {
+	var list feedList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	entries := list.Entries[:0]
+	for _, entry := range list.Entries {
+		if entry.Package == nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	list.Entries = entries
+	return &list, nil
+}
@@ -1,6 +1,7 @@
 package feed
 
-func getFeedUrls() []string {
+// FeedURLs returns the JetBrains Toolbox feeds devrig resolves IDEs from
+func FeedURLs() []string {
 	return []string{
 		"https://download.jetbrains.com/toolbox/feeds/v1/release.feed.xz.signed",
 		"https://download.jetbrains.com/toolbox/feeds/v1/public-feed-arm.feed.xz.signed",
@@ -6,13 +6,21 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
 
 	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/contentstore"
+	"jonnyzzz.com/devrig.dev/diskspace"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
 	"jonnyzzz.com/devrig.dev/feed_api"
+	"jonnyzzz.com/devrig.dev/filelock"
 	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/progress"
 )
 
 type downloadedRemoteIde struct {
@@ -38,16 +46,11 @@ func DownloadFeedEntry(ctx context.Context, entry feed_api.RemoteIDE, config con
 		log.Panicln("Failed to cast entry to feedEntry")
 	}
 
+	logger := logging.FromContext(ctx)
 	url := feedEntry.Package.URL
-	fmt.Println("Downloading ", url, " for ", feedEntry, "...")
+	logger.Info("Downloading "+url, "ide", feedEntry.NameV, "build", feedEntry.BuildV)
 
-	packageSha256 := ""
-	for _, checksum := range feedEntry.Package.Checksums {
-		if checksum.Algorithm == "sha-256" {
-			packageSha256 = checksum.Value
-			break
-		}
-	}
+	packageSha256 := feedEntry.sha256()
 
 	if len(packageSha256) == 0 {
 		log.Panicln("Failed to resolve packageSha256 checksum for ", url)
@@ -69,18 +72,74 @@ func DownloadFeedEntry(ctx context.Context, entry feed_api.RemoteIDE, config con
 		targetFile,
 	}
 
-	err := downloadIdeBinaryIfNeeded(ctx, pros)
+	// The IDE and a terminal may download the same IDE, the process waiting for the lock finds the file in place
+	lock, err := filelock.Acquire(ctx, layout.ResolveLockFile(config.CacheDir(), filepath.Base(targetFile)), "downloading "+filepath.Base(targetFile))
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	// The shared cache may have the package of another project, it is verified below like a downloaded one
+	store := contentstore.FromContext(ctx)
+	if store != nil {
+		// Projects of a workspace share the cache, e.g. with devrig foreach, the package is downloaded only once
+		storeLock, err := filelock.Acquire(ctx, layout.ResolveLockFile(store.Root(), packageSha256), "downloading "+filepath.Base(targetFile))
+		if err != nil {
+			return nil, err
+		}
+		defer storeLock.Release()
+	}
+	linkSharedPackage(ctx, store, packageSha256, targetFile)
+	existing, _ := os.Stat(targetFile)
+
+	err = downloadIdeBinaryIfNeeded(ctx, pros)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if store != nil {
+		// A new download replaces the package of the shared cache, it was missing or corrupted
+		if _, err := os.Stat(store.FilePath(packageSha256)); err != nil || !isSameFile(existing, targetFile) {
+			if err := store.PutFile(packageSha256, targetFile); err != nil {
+				logger.Warn("failed to add the package to the shared cache", "path", targetFile, "error", err)
+			}
+		}
+	}
+
 	return &downloadedRemoteIde{
 		remoteIde:  feedEntry,
 		targetFile: targetFile,
 	}, nil
 }
 
+// linkSharedPackage places the package of the shared cache at targetFile unless there is a file already
+func linkSharedPackage(ctx context.Context, store *contentstore.Store, sha256 string, targetFile string) {
+	if store == nil {
+		return
+	}
+	if _, err := os.Stat(targetFile); err == nil {
+		return
+	}
+	linked, err := store.LinkFile(sha256, targetFile)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to reuse the package of the shared cache", "path", targetFile, "error", err)
+		return
+	}
+	if linked {
+		logging.FromContext(ctx).Info(fmt.Sprintf("Reusing %s from the shared cache %s", filepath.Base(targetFile), store.Root()))
+	}
+}
+
+// isSameFile checks if path is still the file of info, it is not once the file is downloaded again
+func isSameFile(info os.FileInfo, path string) bool {
+	if info == nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	return err == nil && os.SameFile(info, current)
+}
+
 type downloadRequest struct {
 	Url    string
 	Size   int64
@@ -90,66 +149,143 @@ type downloadRequest struct {
 }
 
 func downloadIdeBinaryIfNeeded(ctx context.Context, request downloadRequest) error {
-	err := validateDownloadedFile(request)
+	err := validateDownloadedFile(ctx, request)
 	if err == nil {
-		fmt.Printf("File %s already exists for %s\n", request.TargetFile, request.Url)
+		logging.FromContext(ctx).Info(fmt.Sprintf("File %s already exists for %s", request.TargetFile, request.Url))
 		return nil
 	}
 
+	if err := offline.Check(filepath.Base(request.TargetFile), request.Url,
+		fmt.Sprintf("download %s on a machine with network access and copy it to %s", request.Url, request.TargetFile)); err != nil {
+		return err
+	}
+
+	// Partial downloads are kept next to the target file and resumed on the next run
+	partFile := request.TargetFile + ".part"
+	if err := checkDownloadSpace(ctx, request, partFile); err != nil {
+		return err
+	}
+	if err := downloadToPartFile(ctx, request, partFile); err != nil {
+		return err
+	}
+
+	// Verify the complete file before it is moved into place
+	partRequest := request
+	partRequest.TargetFile = partFile
+	if err := validateDownloadedFile(ctx, partRequest); err != nil {
+		_ = os.Remove(partFile)
+		return fmt.Errorf("downloaded file is corrupted, removed it to start over: %w", err)
+	}
+
+	if err := os.Rename(partFile, request.TargetFile); err != nil {
+		return fmt.Errorf("failed to move %s to %s: %w", partFile, request.TargetFile, err)
+	}
+
+	logging.FromContext(ctx).Info(fmt.Sprintf("Downloaded %s to %s", request.Url, request.TargetFile))
+	return nil
+}
+
+// checkDownloadSpace fails before the download when the cache has no room for the rest of the package
+// and the unpacked IDE, which is written to the same cache
+func checkDownloadSpace(ctx context.Context, request downloadRequest, partFile string) error {
+	remaining := request.Size
+	if info, err := os.Stat(partFile); err == nil && info.Size() < request.Size {
+		remaining -= info.Size()
+	}
+	return diskspace.Check(ctx, filepath.Dir(request.TargetFile), remaining+request.Size*diskspace.UnpackRatio)
+}
+
+// downloadToPartFile downloads the file to partFile, resuming from its current size
+// with an HTTP Range request when the server supports it
+func downloadToPartFile(ctx context.Context, request downloadRequest, partFile string) error {
+	logger := logging.FromContext(ctx)
+
+	var offset int64
+	if info, err := os.Stat(partFile); err == nil {
+		offset = info.Size()
+		if offset >= request.Size {
+			// Nothing to resume, the file is broken
+			_ = os.Remove(partFile)
+			offset = 0
+		}
+	}
+
 	req, err := http.NewRequestWithContext(ctx, "GET", request.Url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w for %s", err, request.Url)
 	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to download: %w for %s", err, request.Url)
+		return fmt.Errorf("failed to download: %w", &devrigErrors.NetworkError{URL: request.Url, Err: err})
 	}
 
 	defer func(Body io.ReadCloser) {
 		_ = Body.Close()
 	}(resp.Body)
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d for %s", resp.StatusCode, request.Url)
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		logger.Info(fmt.Sprintf("Resuming download of %s from %d bytes", request.Url, offset))
+	case resp.StatusCode == http.StatusOK:
+		if offset > 0 {
+			logger.Debug("server does not support resume, downloading from scratch", "url", request.Url)
+		}
+		offset = 0
+	default:
+		if resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+			_ = os.Remove(partFile)
+		}
+		return &devrigErrors.NetworkError{URL: request.Url, Err: fmt.Errorf("unexpected status code: %d", resp.StatusCode)}
 	}
 
-	err = saveResponseToFile(request.Url, request.TargetFile, resp.Body)
+	tracker := progress.Track(ctx, "Downloading "+filepath.Base(request.TargetFile), request.Size, offset)
+	err = saveResponseToFile(ctx, request.Url, partFile, offset > 0, tracker.Reader(resp.Body))
+	tracker.Finish()
 	if err != nil {
-		return fmt.Errorf("failed to save response to file %s: %w", request.TargetFile, err)
+		return fmt.Errorf("failed to save response to file %s: %w", partFile, err)
 	}
 
 	return nil
 }
 
-func saveResponseToFile(url string, targetFile string, body io.ReadCloser) error {
+func saveResponseToFile(ctx context.Context, url string, targetFile string, resume bool, body io.Reader) error {
+	logger := logging.FromContext(ctx)
+
 	// Ensure the parent directory of targetFile exists
 	if err := os.MkdirAll(filepath.Dir(targetFile), os.ModePerm); err != nil {
 		return fmt.Errorf("failed to create parent directories for %s: %w", targetFile, err)
 	}
 
-	out, err := os.Create(targetFile)
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	out, err := os.OpenFile(targetFile, flags, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to create file %s: %w for %s", targetFile, err, url)
 	}
 
 	defer func() {
 		if err := out.Close(); err != nil {
-			log.Printf("failed to close file %s: %v for %s", targetFile, err, url)
+			logger.Warn("failed to close file", "file", targetFile, "url", url, "error", err)
 		}
 	}()
 
-	//TODO: implement progress
 	// Write the response to the file
+	stopHeartbeat := progress.Heartbeat(ctx, "Downloading "+url)
+	defer stopHeartbeat()
 	if _, err := io.Copy(out, body); err != nil {
 		return fmt.Errorf("failed to write to file %s: %w", targetFile, err)
 	}
 
-	fmt.Printf("Downloaded %s to %s\n", url, targetFile)
 	return nil
 }
 
-func validateDownloadedFile(request downloadRequest) error {
+func validateDownloadedFile(ctx context.Context, request downloadRequest) error {
 	targetFileInfo, err := os.Stat(request.TargetFile)
 	if err != nil {
 		return fmt.Errorf("failed to read download file: %w for %s for %s", err, request.TargetFile, request.Url)
@@ -159,13 +295,15 @@ func validateDownloadedFile(request downloadRequest) error {
 		return fmt.Errorf("actual file size %d does not match expected size %d for %s", targetFileInfo.Size(), request.Size, request.Url)
 	}
 
+	stopHeartbeat := progress.Heartbeat(ctx, "Verifying "+request.TargetFile)
 	computedHash, err := computeSha256(request)
+	stopHeartbeat()
 	if err != nil {
 		return fmt.Errorf("failed to compute hash for %s: %w", request.TargetFile, err)
 	}
 
 	if computedHash != request.Sha256 {
-		return fmt.Errorf("computed hash %s does not match expected hash %s for %s", computedHash, request.Sha256, request.Url)
+		return &devrigErrors.ChecksumMismatchError{Subject: request.Url, Expected: request.Sha256, Actual: computedHash}
 	}
 
 	return nil
@@ -181,7 +319,7 @@ func computeSha256(request downloadRequest) (string, error) {
 	defer func() {
 		err := file.Close()
 		if err != nil {
-			log.Printf("failed to close file %s: %v for %s", request.TargetFile, err, request.Url)
+			slog.Warn("failed to close file", "file", request.TargetFile, "url", request.Url, "error", err)
 		}
 	}()
 
@@ -0,0 +1,148 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/diskspace"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/offline"
+)
+
+// newArchiveServer serves the content with Range support and records the Range headers
+func newArchiveServer(t *testing.T, content []byte) (*httptest.Server, *[]string) {
+	t.Helper()
+	var mutex sync.Mutex
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mutex.Unlock()
+		http.ServeContent(w, r, "ide.tar.gz", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+	return server, &ranges
+}
+
+func newTestRequest(url string, content []byte, targetFile string) downloadRequest {
+	return downloadRequest{
+		Url:        url,
+		Size:       int64(len(content)),
+		Sha256:     fmt.Sprintf("%x", sha256.Sum256(content)),
+		TargetFile: targetFile,
+	}
+}
+
+func TestDownloadIdeBinaryIfNeeded_ResumesPartialDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 500)
+	server, ranges := newArchiveServer(t, content)
+
+	targetFile := filepath.Join(t.TempDir(), "ide.tar.gz")
+	if err := os.WriteFile(targetFile+".part", content[:1234], 0644); err != nil {
+		t.Fatalf("Failed to write partial file: %v", err)
+	}
+
+	request := newTestRequest(server.URL, content, targetFile)
+	if err := downloadIdeBinaryIfNeeded(context.Background(), request); err != nil {
+		t.Fatalf("Failed to download: %v", err)
+	}
+
+	if len(*ranges) != 1 || (*ranges)[0] != "bytes=1234-" {
+		t.Errorf("Expected a single resumed request, got ranges: %v", *ranges)
+	}
+
+	downloaded, err := os.ReadFile(targetFile)
+	if err != nil {
+		t.Fatalf("Failed to read downloaded file: %v", err)
+	}
+	if !bytes.Equal(downloaded, content) {
+		t.Error("Downloaded file does not match the content")
+	}
+	if _, err := os.Stat(targetFile + ".part"); !os.IsNotExist(err) {
+		t.Error("Expected the .part file to be removed")
+	}
+}
+
+func TestDownloadIdeBinaryIfNeeded_CorruptedPartialDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("abcdefghij"), 500)
+	server, _ := newArchiveServer(t, content)
+
+	targetFile := filepath.Join(t.TempDir(), "ide.tar.gz")
+	if err := os.WriteFile(targetFile+".part", bytes.Repeat([]byte("x"), 100), 0644); err != nil {
+		t.Fatalf("Failed to write partial file: %v", err)
+	}
+
+	request := newTestRequest(server.URL, content, targetFile)
+	err := downloadIdeBinaryIfNeeded(context.Background(), request)
+
+	var mismatch *devrigErrors.ChecksumMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected checksum mismatch, got: %v", err)
+	}
+	if _, err := os.Stat(targetFile + ".part"); !os.IsNotExist(err) {
+		t.Error("Expected the corrupted .part file to be removed")
+	}
+	if _, err := os.Stat(targetFile); !os.IsNotExist(err) {
+		t.Error("Expected the corrupted file not to be moved into place")
+	}
+
+	// The next attempt starts over and succeeds
+	if err := downloadIdeBinaryIfNeeded(context.Background(), request); err != nil {
+		t.Fatalf("Failed to download after cleanup: %v", err)
+	}
+}
+
+func TestDownloadIdeBinaryIfNeeded_Offline(t *testing.T) {
+	t.Setenv(offline.EnvOffline, "1")
+	content := bytes.Repeat([]byte("0123456789"), 500)
+	server, ranges := newArchiveServer(t, content)
+	targetFile := filepath.Join(t.TempDir(), "ide.tar.gz")
+	request := newTestRequest(server.URL, content, targetFile)
+
+	err := downloadIdeBinaryIfNeeded(context.Background(), request)
+	var offlineErr *devrigErrors.OfflineError
+	if !errors.As(err, &offlineErr) {
+		t.Fatalf("Expected OfflineError, got: %v", err)
+	}
+	if len(*ranges) != 0 {
+		t.Errorf("Expected no requests in offline mode, got %d", len(*ranges))
+	}
+
+	// A pre-seeded file is used as is
+	if err := os.WriteFile(targetFile, content, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := downloadIdeBinaryIfNeeded(context.Background(), request); err != nil {
+		t.Errorf("Expected the pre-seeded file to be used, got: %v", err)
+	}
+}
+
+func TestDownloadIdeBinaryIfNeeded_NotEnoughSpace(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 500)
+	server, ranges := newArchiveServer(t, content)
+	request := newTestRequest(server.URL, content, filepath.Join(t.TempDir(), "ide.tar.gz"))
+	// The feed declares a package no disk can hold
+	request.Size = 1 << 60
+
+	err := downloadIdeBinaryIfNeeded(context.Background(), request)
+	var spaceErr *diskspace.InsufficientSpaceError
+	if !errors.As(err, &spaceErr) {
+		t.Fatalf("Expected InsufficientSpaceError, got: %v", err)
+	}
+	if spaceErr.Required != request.Size*(1+diskspace.UnpackRatio) {
+		t.Errorf("Expected the package and the unpacked IDE to be required, got %d", spaceErr.Required)
+	}
+	if len(*ranges) != 0 {
+		t.Errorf("Expected no requests without free space, got %d", len(*ranges))
+	}
+}
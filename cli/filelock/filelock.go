@@ -0,0 +1,170 @@
+// Package filelock implements advisory inter-process locks around downloads, unpacks and devrig.yaml writes,
+// so concurrent devrig processes, e.g. started by the IDE and from a terminal, do not corrupt shared folders.
+package filelock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/logging"
+)
+
+const (
+	// EnvNoWait makes devrig fail instead of waiting for a lock held by another process when set to 1 or true.
+	// The --no-wait flag sets it too, so child processes inherit the mode.
+	EnvNoWait = "DEVRIG_NO_WAIT"
+	// EnvTimeout overrides how long devrig waits for a lock, e.g. 30s or 5m
+	EnvTimeout = "DEVRIG_LOCK_TIMEOUT"
+)
+
+// DefaultTimeout is long enough for another process to download and unpack an IDE
+const DefaultTimeout = 10 * time.Minute
+
+// pollInterval is the delay between attempts to take a busy lock
+var pollInterval = 200 * time.Millisecond
+
+// errBusy is returned by tryLock when another process holds the lock
+var errBusy = errors.New("the lock is held by another process")
+
+// Lock is an acquired lock, release it when the guarded operation is complete
+type Lock struct {
+	file *os.File
+}
+
+// NoWait checks if devrig must fail instead of waiting for a busy lock
+func NoWait() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(EnvNoWait))) {
+	case "1", "true", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// ResolveTimeout returns the timeout of DEVRIG_LOCK_TIMEOUT, or DefaultTimeout
+func ResolveTimeout() (time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv(EnvTimeout))
+	if value == "" {
+		return DefaultTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid %s value %q, expected a duration like 30s or 5m", EnvTimeout, value)
+	}
+	return timeout, nil
+}
+
+// Acquire takes the exclusive lock of the file, the file and its folder are created when missing.
+// While another process holds the lock, Acquire waits up to the timeout of ResolveTimeout,
+// in the no-wait mode it fails right away. The purpose is shown to the processes waiting for the lock
+func Acquire(ctx context.Context, path string, purpose string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	// The lock file is never removed, removing it would let two processes lock different files of the same path
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+
+	timeout, err := ResolveTimeout()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	var deadline <-chan time.Time
+	waiting := false
+	for {
+		err := tryLock(file)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, errBusy) {
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+
+		holder := readHolder(path)
+		if NoWait() {
+			_ = file.Close()
+			return nil, &devrigErrors.LockedError{Path: path, Holder: holder}
+		}
+		if !waiting {
+			waiting = true
+			deadline = time.After(timeout)
+			logging.FromContext(ctx).Info(fmt.Sprintf("Waiting for another devrig process to release %s (%s)...", path, holder))
+		}
+
+		select {
+		case <-ctx.Done():
+			_ = file.Close()
+			return nil, ctx.Err()
+		case <-deadline:
+			_ = file.Close()
+			return nil, &devrigErrors.LockedError{Path: path, Holder: holder, Timeout: timeout}
+		case <-time.After(pollInterval):
+		}
+	}
+
+	return newLock(file, purpose), nil
+}
+
+// TryAcquire takes the exclusive lock of the file like Acquire, but fails with LockedError right away
+// when another process holds it
+func TryAcquire(path string, purpose string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file %s: %w", path, err)
+	}
+	if err := tryLock(file); err != nil {
+		_ = file.Close()
+		if errors.Is(err, errBusy) {
+			return nil, &devrigErrors.LockedError{Path: path, Holder: readHolder(path)}
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return newLock(file, purpose), nil
+}
+
+// newLock records the current process as the holder of the locked file
+func newLock(file *os.File, purpose string) *Lock {
+	// The holder is informational only, a failure to record it does not affect the lock
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(fmt.Sprintf("pid %d: %s\n", os.Getpid(), purpose)), 0)
+	}
+	return &Lock{file: file}
+}
+
+// Release unlocks and closes the lock file
+func (l *Lock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := unlock(l.file)
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
+	l.file = nil
+	return err
+}
+
+// readHolder returns the process that holds the lock as recorded in the file
+func readHolder(path string) string {
+	data, err := os.ReadFile(path)
+	holder := strings.TrimSpace(string(data))
+	if err != nil || holder == "" {
+		return "unknown process"
+	}
+	return holder
+}
@@ -0,0 +1,103 @@
+package filelock
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+func TestAcquire_NoWait(t *testing.T) {
+	t.Setenv(EnvNoWait, "")
+	path := filepath.Join(t.TempDir(), "locks", "ide.lock")
+
+	lock, err := Acquire(context.Background(), path, "downloading ide")
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	defer lock.Release()
+
+	t.Setenv(EnvNoWait, "1")
+	_, err = Acquire(context.Background(), path, "unpacking ide")
+	var lockedErr *devrigErrors.LockedError
+	if !errors.As(err, &lockedErr) {
+		t.Fatalf("Expected LockedError, got: %v", err)
+	}
+	if !strings.Contains(lockedErr.Holder, "downloading ide") {
+		t.Errorf("Expected the holder to name the purpose, got: %s", lockedErr.Holder)
+	}
+	if devrigErrors.ExitCode(err) != devrigErrors.ExitLocked {
+		t.Errorf("Expected exit code %d, got: %d", devrigErrors.ExitLocked, devrigErrors.ExitCode(err))
+	}
+}
+
+func TestAcquire_WaitsForRelease(t *testing.T) {
+	t.Setenv(EnvNoWait, "")
+	t.Setenv(EnvTimeout, "")
+	pollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { pollInterval = 200 * time.Millisecond })
+	path := filepath.Join(t.TempDir(), "devrig.yaml.lock")
+
+	first, err := Acquire(context.Background(), path, "first")
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = first.Release()
+	}()
+
+	second, err := Acquire(context.Background(), path, "second")
+	if err != nil {
+		t.Fatalf("Failed to acquire released lock: %v", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Fatalf("Failed to release lock: %v", err)
+	}
+	if holder := readHolder(path); holder != "unknown process" && !strings.Contains(holder, "second") {
+		t.Errorf("Expected the last holder to be recorded, got: %s", holder)
+	}
+}
+
+func TestAcquire_Timeout(t *testing.T) {
+	t.Setenv(EnvNoWait, "")
+	t.Setenv(EnvTimeout, "50ms")
+	pollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { pollInterval = 200 * time.Millisecond })
+	path := filepath.Join(t.TempDir(), "devrig.lock")
+
+	lock, err := Acquire(context.Background(), path, "first")
+	if err != nil {
+		t.Fatalf("Failed to acquire lock: %v", err)
+	}
+	defer lock.Release()
+
+	_, err = Acquire(context.Background(), path, "second")
+	var lockedErr *devrigErrors.LockedError
+	if !errors.As(err, &lockedErr) || lockedErr.Timeout != 50*time.Millisecond {
+		t.Fatalf("Expected LockedError after the timeout, got: %v", err)
+	}
+}
+
+func TestResolveTimeout(t *testing.T) {
+	t.Setenv(EnvTimeout, "")
+	if timeout, err := ResolveTimeout(); err != nil || timeout != DefaultTimeout {
+		t.Errorf("Expected the default timeout, got: %v, %v", timeout, err)
+	}
+
+	t.Setenv(EnvTimeout, "30s")
+	if timeout, err := ResolveTimeout(); err != nil || timeout != 30*time.Second {
+		t.Errorf("Expected 30s, got: %v, %v", timeout, err)
+	}
+
+	for _, value := range []string{"soon", "-1s"} {
+		t.Setenv(EnvTimeout, value)
+		if _, err := ResolveTimeout(); err == nil {
+			t.Errorf("Expected an error for %s=%s", EnvTimeout, value)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/bootstrap"
+	"jonnyzzz.com/devrig.dev/configfile"
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/upgrade"
+)
+
+// Cloner clones the git repository into the folder
+type Cloner func(ctx context.Context, url string, dir string) error
+
+// GitCloner clones the repository with the git command, the credentials of git are used
+func GitCloner(ctx context.Context, url string, dir string) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create the folder for %s: %w", url, err)
+	}
+	// The URL comes from the manifest, -- keeps a URL like --upload-pack=... from being read as an option
+	command := exec.CommandContext(ctx, "git", "clone", "--quiet", "--", url, dir)
+	if output, err := command.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to clone %s: %w\n%s", url, err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// Rollout brings the repositories of the manifest to the preset
+type Rollout struct {
+	Manifest *Manifest
+	// Release is the devrig section every repository pins, see Manifest.Preset
+	Release *configservice.DevrigSection
+	Cloner  Cloner
+	// DryRun only reports the changes, the repositories are still cloned to inspect them
+	DryRun bool
+}
+
+// Result is the change report of a repository
+type Result struct {
+	Repository Repository
+	Dir        string
+	// Changes are the human-readable lines describing what is changed, empty when the repository conforms
+	Changes  []string
+	Duration time.Duration
+	Err      error
+}
+
+// Apply brings the repository to the preset and reports the changes. A repository with a git URL is cloned
+// first unless the clone exists, the changes are left uncommitted for review
+func (r *Rollout) Apply(ctx context.Context, repository Repository) Result {
+	start := time.Now()
+	result := Result{Repository: repository, Dir: r.Manifest.Dir(repository)}
+	result.Err = r.apply(ctx, repository, result.Dir, &result.Changes)
+	result.Duration = time.Since(start)
+	return result
+}
+
+func (r *Rollout) apply(ctx context.Context, repository Repository, dir string, changes *[]string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) && repository.URL != "" {
+		if err := r.Cloner(ctx, repository.URL, dir); err != nil {
+			return err
+		}
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("repository folder %s is not found", dir)
+	}
+
+	if err := r.applyScripts(dir, changes); err != nil {
+		return err
+	}
+	configPath, _ := configfile.Find(dir)
+	service := configservice.NewConfigService(configPath)
+	if err := r.applyRelease(service, configPath, changes); err != nil {
+		return err
+	}
+	return r.applyValues(service, changes)
+}
+
+// applyScripts replaces the wrapper scripts that differ from the ones of this devrig
+func (r *Rollout) applyScripts(dir string, changes *[]string) error {
+	outdated, err := bootstrap.OutdatedScripts(dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range outdated {
+		*changes = append(*changes, "update wrapper script "+name)
+	}
+	if len(outdated) == 0 || r.DryRun {
+		return nil
+	}
+	if err := bootstrap.CopyBootstrapScripts(dir); err != nil {
+		return fmt.Errorf("failed to update the wrapper scripts: %w", err)
+	}
+	return nil
+}
+
+// applyRelease pins the devrig release of the preset, devrig.yaml is created when the repository has none
+func (r *Rollout) applyRelease(service configservice.ConfigService, configPath string, changes *[]string) error {
+	current := &configservice.DevrigSection{}
+	if _, err := os.Stat(configPath); err == nil {
+		if current, err = service.Binaries().ReadDevrigSection(); err != nil {
+			return err
+		}
+	} else {
+		*changes = append(*changes, "create "+filepath.Base(configPath))
+	}
+
+	diff := upgrade.DiffSections(current, r.Release)
+	*changes = append(*changes, diff...)
+	if len(diff) == 0 || r.DryRun {
+		return nil
+	}
+	if err := service.Binaries().UpdateBinaries(r.Release); err != nil {
+		return fmt.Errorf("failed to update %s: %w", configPath, err)
+	}
+	return nil
+}
+
+// applyValues sets the values of the preset that differ, the schema of devrig.yaml rejects invalid values
+func (r *Rollout) applyValues(service configservice.ConfigService, changes *[]string) error {
+	for _, path := range r.Manifest.Preset.sortedValues() {
+		expected := fmt.Sprint(r.Manifest.Preset.Values[path])
+		current := "<none>"
+		// devrig.yaml is missing only in a dry run, otherwise applyRelease creates it
+		var notFound *devrigErrors.ConfigNotFoundError
+		value, err := service.Values().GetValue(path)
+		if err == nil {
+			current = fmt.Sprint(value)
+		} else if !errors.Is(err, configservice.ErrValueNotFound) && !errors.As(err, &notFound) {
+			return err
+		}
+		if current == expected {
+			continue
+		}
+
+		*changes = append(*changes, fmt.Sprintf("%s: %s -> %s", path, current, expected))
+		if r.DryRun {
+			continue
+		}
+		if err := service.Values().SetValue(path, expected); err != nil {
+			return err
+		}
+	}
+	return nil
+}
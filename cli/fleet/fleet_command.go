@@ -0,0 +1,166 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/output"
+	"jonnyzzz.com/devrig.dev/summary"
+	"jonnyzzz.com/devrig.dev/updates"
+	"jonnyzzz.com/devrig.dev/upgrade"
+)
+
+// repositoryReport is the JSON output of `devrig fleet apply` for a repository
+type repositoryReport struct {
+	Name    string   `json:"name"`
+	Dir     string   `json:"dir"`
+	Changes []string `json:"changes"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// NewFleetCommand creates the fleet command for platform engineers who roll devrig out to many repositories
+func NewFleetCommand(configPath func() string, fetcher upgrade.ReleaseFetcher) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "fleet",
+		Short: "Roll devrig out to the repositories of an organization",
+	}
+	cmd.AddCommand(newApplyCommand(configPath, fetcher, GitCloner))
+	return cmd
+}
+
+func newApplyCommand(configPath func() string, fetcher upgrade.ReleaseFetcher, cloner Cloner) *cobra.Command {
+	var manifestPath string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "apply --manifest fleet.yaml",
+		Short: "Bring every repository of the fleet manifest to the preset of the organization",
+		Long: `Bring every repository listed in the fleet manifest to the preset of the organization:
+the wrapper scripts of this devrig, the pinned devrig release and the values of
+devrig.yaml. devrig.yaml is created in repositories that have none. Repositories
+with a git URL are cloned next to the manifest, an existing clone is reused.
+
+The changes of every repository are printed, followed by a summary table. They are
+not committed, review and commit them with the tooling of the organization.
+
+Example fleet.yaml:
+
+  clone_dir: .fleet
+  preset:
+    version: 0.80.0          # the latest release of the channel when missing
+    channel: stable
+    values:
+      maintenance.auto_gc: true
+  repositories:
+    - path: ../payments
+    - url: git@github.com:example/checkout.git
+
+Examples:
+  devrig fleet apply --manifest fleet.yaml --dry-run
+  devrig fleet apply --manifest fleet.yaml --output json
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if manifestPath == "" {
+				return fmt.Errorf("--manifest is required")
+			}
+			manifest, err := LoadManifest(manifestPath)
+			if err != nil {
+				return err
+			}
+			release, err := resolveRelease(cmd, configPath(), fetcher, manifest.Preset)
+			if err != nil {
+				return err
+			}
+			rollout := &Rollout{Manifest: manifest, Release: release, Cloner: cloner, DryRun: dryRun}
+			cmd.Printf("Applying devrig %s to %d repositories\n", release.Version, len(manifest.Repositories))
+
+			var s summary.Summary
+			var reports []repositoryReport
+			failed := 0
+			for _, repository := range manifest.Repositories {
+				result := rollout.Apply(cmd.Context(), repository)
+				s.Record(repository.Name, result.Duration, result.Err)
+				report := repositoryReport{Name: repository.Name, Dir: result.Dir, Changes: result.Changes}
+				if result.Err != nil {
+					failed++
+					report.Error = result.Err.Error()
+				}
+				reports = append(reports, report)
+				if output.FormatFromContext(cmd.Context()) != output.FormatJSON {
+					printReport(cmd, report)
+				}
+			}
+
+			if output.FormatFromContext(cmd.Context()) == output.FormatJSON {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(struct {
+					DryRun       bool               `json:"dry_run"`
+					Version      string             `json:"version"`
+					Repositories []repositoryReport `json:"repositories"`
+				}{dryRun, release.Version, reports}); err != nil {
+					return fmt.Errorf("failed to write the report: %w", err)
+				}
+			} else {
+				s.Print(cmd.Context(), cmd.OutOrStdout())
+			}
+			if dryRun {
+				cmd.Println("Dry run: the repositories are not changed")
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("failed in %d of %d repositories: %w", failed, len(manifest.Repositories), s.Err())
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&manifestPath, "manifest", "", "Fleet manifest listing the repositories and the preset")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the changes")
+	return cmd
+}
+
+// resolveRelease downloads and verifies the metadata of the devrig release of the preset. The endpoints come
+// from the devrig.yaml of the operator, like for `devrig upgrade`
+func resolveRelease(cmd *cobra.Command, configPath string, fetcher upgrade.ReleaseFetcher, preset Preset) (*configservice.DevrigSection, error) {
+	endpoints, err := updates.ResolveEndpoints(configservice.NewConfigService(configPath))
+	if err != nil {
+		return nil, err
+	}
+	channel := preset.Channel
+	if channel == configservice.ChannelStable {
+		channel = ""
+	}
+	url := endpoints.ChannelJSONURL(channel)
+	if preset.Version != "" {
+		url = endpoints.ReleaseJSONURL(preset.Version)
+	}
+	logging.FromContext(cmd.Context()).Debug("fetching release metadata", "url", url)
+
+	info, err := fetcher.FetchUpdateInfo(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch release metadata: %w", err)
+	}
+	release := info.ToDevrigSection()
+	release.Channel = channel
+	return release, nil
+}
+
+// printReport writes the changes of the repository as one block
+func printReport(cmd *cobra.Command, report repositoryReport) {
+	out := cmd.OutOrStdout()
+	_, _ = fmt.Fprintf(out, "==> %s\n", report.Name)
+	for _, change := range report.Changes {
+		_, _ = fmt.Fprintf(out, "  %s\n", change)
+	}
+	switch {
+	case report.Error != "":
+		_, _ = fmt.Fprintf(out, "  failed: %s\n", report.Error)
+	case len(report.Changes) == 0:
+		_, _ = fmt.Fprintln(out, "  up to date")
+	}
+}
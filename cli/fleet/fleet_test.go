@@ -0,0 +1,175 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+const outdatedConfig = `# devrig.yaml of the payments team
+devrig:
+  version: 0.79.5
+  binaries:
+    linux-x86_64:
+      url: https://example.com/v0.79.5/devrig-linux-x86_64
+      sha512: 11111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111
+`
+
+const testManifest = `preset:
+  version: 0.80.0
+  values:
+    maintenance.auto_gc: true
+repositories:
+  - path: payments
+  - url: git@github.com:example/checkout.git
+`
+
+// mockFetcher records the requested URL and returns the release
+type mockFetcher struct {
+	requestedURL string
+}
+
+func (m *mockFetcher) FetchUpdateInfo(url string) (*updates.UpdateInfo, error) {
+	m.requestedURL = url
+	return &updates.UpdateInfo{
+		Version: "0.80.0",
+		Binaries: []updates.BinaryInfo{
+			{OS: "linux", Arch: "x86_64", URL: "https://example.com/v0.80.0/devrig-linux-x86_64", SHA512: strings.Repeat("3", 128)},
+		},
+	}, nil
+}
+
+// newFleet writes the manifest and the payments repository with an outdated devrig.yaml
+func newFleet(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "payments"), 0755); err != nil {
+		t.Fatalf("Failed to create the repository: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "payments", "devrig.yaml"), []byte(outdatedConfig), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	manifestPath := filepath.Join(dir, "fleet.yaml")
+	if err := os.WriteFile(manifestPath, []byte(testManifest), 0644); err != nil {
+		t.Fatalf("Failed to write the manifest: %v", err)
+	}
+	return manifestPath
+}
+
+func runFleetApply(t *testing.T, manifestPath string, cloned *[]string, args ...string) (string, error) {
+	t.Helper()
+	cloner := func(ctx context.Context, url string, dir string) error {
+		*cloned = append(*cloned, url)
+		return os.MkdirAll(dir, 0755)
+	}
+	fetcher := &mockFetcher{}
+	cmd := newApplyCommand(func() string { return filepath.Join(t.TempDir(), "devrig.yaml") }, fetcher, cloner)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(append([]string{"--manifest", manifestPath}, args...))
+	err := cmd.Execute()
+	if fetcher.requestedURL != updates.DefaultEndpoints.ReleaseJSONURL("0.80.0") {
+		t.Errorf("Expected the release of the preset to be fetched, got %s", fetcher.requestedURL)
+	}
+	return out.String(), err
+}
+
+func TestLoadManifest(t *testing.T) {
+	manifest, err := LoadManifest(newFleet(t))
+	if err != nil {
+		t.Fatalf("Failed to load the manifest: %v", err)
+	}
+	if manifest.Repositories[0].Name != "payments" || manifest.Repositories[1].Name != "checkout" {
+		t.Errorf("Unexpected repository names: %+v", manifest.Repositories)
+	}
+	if dir := manifest.Dir(manifest.Repositories[1]); dir != filepath.Join(manifest.dir, ".fleet", "checkout") {
+		t.Errorf("Unexpected clone folder: %s", dir)
+	}
+
+	for name, content := range map[string]string{
+		"both":      "repositories:\n  - path: a\n    url: https://example.com/a.git\n",
+		"duplicate": "repositories:\n  - path: a\n  - url: https://example.com/a.git\n",
+		"unknown":   "repository:\n  - path: a\n",
+		"channel":   "preset:\n  channel: weekly\nrepositories:\n  - path: a\n",
+		"escape":    "repositories:\n  - url: https://example.com/a.git\n    name: ../a\n",
+		"absolute":  "repositories:\n  - url: https://example.com/a.git\n    name: /tmp/a\n",
+	} {
+		path := filepath.Join(t.TempDir(), "fleet.yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write the manifest: %v", err)
+		}
+		if _, err := LoadManifest(path); err == nil {
+			t.Errorf("Expected the %s manifest to be rejected", name)
+		}
+	}
+}
+
+func TestFleetApply(t *testing.T) {
+	manifestPath := newFleet(t)
+	fleetDir := filepath.Dir(manifestPath)
+	var cloned []string
+
+	text, err := runFleetApply(t, manifestPath, &cloned, "--dry-run")
+	if err != nil {
+		t.Fatalf("Failed to run the dry run: %v\n%s", err, text)
+	}
+	for _, expected := range []string{"==> payments", "update wrapper script devrig.ps1", "version: 0.79.5 -> 0.80.0",
+		"maintenance.auto_gc: <none> -> true", "==> checkout", "create devrig.yaml"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in the output:\n%s", expected, text)
+		}
+	}
+	if content, _ := os.ReadFile(filepath.Join(fleetDir, "payments", "devrig.yaml")); string(content) != outdatedConfig {
+		t.Errorf("Expected the dry run to keep devrig.yaml, got:\n%s", content)
+	}
+	if len(cloned) != 1 || cloned[0] != "git@github.com:example/checkout.git" {
+		t.Errorf("Expected checkout to be cloned once, got %v", cloned)
+	}
+
+	if text, err := runFleetApply(t, manifestPath, &cloned); err != nil {
+		t.Fatalf("Failed to apply the fleet: %v\n%s", err, text)
+	}
+	content, err := os.ReadFile(filepath.Join(fleetDir, "payments", "devrig.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read devrig.yaml: %v", err)
+	}
+	for _, expected := range []string{"# devrig.yaml of the payments team", "version: 0.80.0", "auto_gc: true"} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Expected %q in devrig.yaml:\n%s", expected, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(fleetDir, ".fleet", "checkout", "devrig")); err != nil {
+		t.Errorf("Expected the wrapper scripts in the clone: %v", err)
+	}
+
+	text, err = runFleetApply(t, manifestPath, &cloned)
+	if err != nil {
+		t.Fatalf("Failed to apply the fleet again: %v\n%s", err, text)
+	}
+	if strings.Count(text, "up to date") != 2 || len(cloned) != 1 {
+		t.Errorf("Expected the repositories to conform without another clone, got %v:\n%s", cloned, text)
+	}
+}
+
+func TestGitCloner_OptionURL(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	marker := filepath.Join(t.TempDir(), "marker")
+	dir := filepath.Join(t.TempDir(), "clone")
+
+	// A URL that looks like an option must not run the command of the manifest
+	if err := GitCloner(context.Background(), "--upload-pack=touch "+marker, dir); err == nil {
+		t.Error("Expected the clone of an option to fail")
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("The URL was read as a git option")
+	}
+}
@@ -0,0 +1,136 @@
+// Package fleet rolls devrig out to many repositories of an organization: every repository of the fleet
+// manifest gets the wrapper scripts of this devrig and a devrig.yaml that conforms to the preset of the organization
+package fleet
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"jonnyzzz.com/devrig.dev/configservice"
+)
+
+// defaultCloneDir is the folder next to the manifest the repositories with a git URL are cloned to
+const defaultCloneDir = ".fleet"
+
+// Manifest lists the repositories of the fleet and the preset they conform to
+type Manifest struct {
+	// CloneDir is the folder for the repositories with a git URL, relative to the manifest
+	CloneDir     string       `yaml:"clone_dir"`
+	Preset       Preset       `yaml:"preset"`
+	Repositories []Repository `yaml:"repositories"`
+
+	// dir is the folder of the manifest, the relative paths are resolved against it
+	dir string
+}
+
+// Preset is what every devrig.yaml of the organization must have
+type Preset struct {
+	// Version is the devrig release to pin, the latest release of the channel when it is empty
+	Version string `yaml:"version"`
+	// Channel is the release channel to pin, stable when it is empty
+	Channel string `yaml:"channel"`
+	// Values are the values of devrig.yaml by their path, like `devrig config set` takes them
+	Values map[string]interface{} `yaml:"values"`
+}
+
+// Repository is a local checkout or a git URL to clone
+type Repository struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+	URL  string `yaml:"url"`
+}
+
+// LoadManifest reads and validates the fleet manifest, unknown keys are rejected to catch typos
+func LoadManifest(manifestPath string) (*Manifest, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the fleet manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := yaml.UnmarshalWithOptions(data, &manifest, yaml.DisallowUnknownField()); err != nil {
+		return nil, fmt.Errorf("failed to parse the fleet manifest %s: %w", manifestPath, err)
+	}
+	absPath, err := filepath.Abs(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the fleet manifest path: %w", err)
+	}
+	manifest.dir = filepath.Dir(absPath)
+	if err := manifest.validate(); err != nil {
+		return nil, fmt.Errorf("invalid fleet manifest %s: %w", manifestPath, err)
+	}
+	return &manifest, nil
+}
+
+func (m *Manifest) validate() error {
+	if m.Preset.Channel != "" {
+		if err := configservice.ValidateChannel(m.Preset.Channel); err != nil {
+			return err
+		}
+	}
+	if len(m.Repositories) == 0 {
+		return fmt.Errorf("no repositories listed")
+	}
+	names := map[string]bool{}
+	for i := range m.Repositories {
+		repository := &m.Repositories[i]
+		if (repository.Path == "") == (repository.URL == "") {
+			return fmt.Errorf("repositories[%d]: expected either path or url", i)
+		}
+		if repository.Name == "" {
+			repository.Name = repository.defaultName()
+		}
+		// The name of a cloned repository is its folder in clone_dir, it must stay inside
+		if repository.URL != "" && !filepath.IsLocal(repository.Name) {
+			return fmt.Errorf("repositories[%d]: invalid name %s, expected a folder inside clone_dir", i, repository.Name)
+		}
+		if names[repository.Name] {
+			return fmt.Errorf("repositories[%d]: duplicate name %s, set a unique name", i, repository.Name)
+		}
+		names[repository.Name] = true
+	}
+	return nil
+}
+
+// defaultName is the path as it is written, or the last segment of the URL without .git
+func (r Repository) defaultName() string {
+	if r.Path != "" {
+		return filepath.ToSlash(r.Path)
+	}
+	name := strings.TrimRight(r.URL, "/")
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, ".git")
+}
+
+// Dir returns the folder of the repository: the path relative to the manifest, or the folder it is cloned to
+func (m *Manifest) Dir(repository Repository) string {
+	if repository.Path != "" {
+		if filepath.IsAbs(repository.Path) {
+			return repository.Path
+		}
+		return filepath.Join(m.dir, repository.Path)
+	}
+	cloneDir := m.CloneDir
+	if cloneDir == "" {
+		cloneDir = defaultCloneDir
+	}
+	if !filepath.IsAbs(cloneDir) {
+		cloneDir = filepath.Join(m.dir, cloneDir)
+	}
+	return filepath.Join(cloneDir, repository.Name)
+}
+
+// sortedValues returns the paths of the preset values in a stable order
+func (p Preset) sortedValues() []string {
+	paths := make([]string, 0, len(p.Values))
+	for valuePath := range p.Values {
+		paths = append(paths, valuePath)
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,120 @@
+// Package foreach runs a devrig command in all projects of a monorepo or workspace in parallel
+package foreach
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configfile"
+)
+
+// skippedDirs are never searched for projects, they hold dependencies and caches rather than projects
+var skippedDirs = map[string]bool{"node_modules": true, "vendor": true, "build": true, "target": true}
+
+// Project is a member of the workspace
+type Project struct {
+	// Name is the folder of the project relative to the workspace root, "." for the root itself
+	Name       string
+	ConfigPath string
+}
+
+// Discover returns the projects under the root in the order of their names: the folders with devrig.yaml,
+// devrig.toml or devrig.json. Hidden folders and the folders of dependencies are skipped
+func Discover(root string) ([]Project, error) {
+	var projects []Project
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if path != root && (strings.HasPrefix(entry.Name(), ".") || skippedDirs[entry.Name()]) {
+			return filepath.SkipDir
+		}
+		configPath, _ := configfile.Find(path)
+		if _, err := os.Stat(configPath); err != nil {
+			return nil
+		}
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		projects = append(projects, Project{Name: filepath.ToSlash(name), ConfigPath: configPath})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for projects in %s: %w", root, err)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Name < projects[j].Name })
+	return projects, nil
+}
+
+// Runner runs the devrig command with the arguments in the project and writes the output of the command
+type Runner func(ctx context.Context, project Project, args []string, output io.Writer) error
+
+// ExecRunner runs the devrig binary for the project like `devrig --devrig-config <path> <args>` from its folder
+func ExecRunner(executable string) Runner {
+	return func(ctx context.Context, project Project, args []string, output io.Writer) error {
+		command := exec.CommandContext(ctx, executable, append([]string{"--devrig-config", project.ConfigPath}, args...)...)
+		command.Dir = filepath.Dir(project.ConfigPath)
+		command.Stdout = output
+		command.Stderr = output
+		return command.Run()
+	}
+}
+
+// Result is the outcome of the command in a project
+type Result struct {
+	Project  Project
+	Output   []byte
+	Duration time.Duration
+	Err      error
+}
+
+// Run executes the command in the projects with at most parallel commands at a time. Every finished command
+// is passed to done with its output, one at a time, so the outputs of the projects are not interleaved.
+// The results are in the order of the projects
+func Run(ctx context.Context, projects []Project, args []string, parallel int, runner Runner, done func(Result)) []Result {
+	if parallel < 1 {
+		parallel = 1
+	}
+	results := make([]Result, len(projects))
+	slots := make(chan struct{}, parallel)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for i, project := range projects {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			var output bytes.Buffer
+			start := time.Now()
+			err := runner(ctx, project, args, &output)
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				err = fmt.Errorf("exit code %d", exitErr.ExitCode())
+			}
+			results[i] = Result{Project: project, Output: output.Bytes(), Duration: time.Since(start), Err: err}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			done(results[i])
+		}()
+	}
+	wg.Wait()
+	return results
+}
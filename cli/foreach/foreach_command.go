@@ -0,0 +1,92 @@
+package foreach
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/output"
+	"jonnyzzz.com/devrig.dev/summary"
+)
+
+// NewForeachCommand creates the foreach command
+func NewForeachCommand() *cobra.Command {
+	return newForeachCommand(nil)
+}
+
+func newForeachCommand(runner Runner) *cobra.Command {
+	var root string
+	var parallel int
+
+	cmd := &cobra.Command{
+		Use:   "foreach -- <command> [args...]",
+		Short: "Run a devrig command in all projects of a workspace",
+		Long: `Run a devrig command in every project of a monorepo or workspace in parallel,
+a project is a folder with devrig.yaml, devrig.toml or devrig.json. Hidden folders
+and the folders of dependencies like node_modules are not searched.
+
+The output of every project is printed as one block when it finishes, followed by
+a summary table. The command fails when it fails in any project.
+
+Set cache.shared: true in the projects to download every package once for the
+whole workspace, the projects that need the same package wait for one download.
+
+Examples:
+  devrig foreach -- apply
+  devrig foreach --parallel 2 -- tools list
+  devrig foreach --root services -- lock
+`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			projects, err := Discover(root)
+			if err != nil {
+				return err
+			}
+			if len(projects) == 0 {
+				return fmt.Errorf("no devrig projects found in %s", root)
+			}
+
+			run := runner
+			if run == nil {
+				executable, err := os.Executable()
+				if err != nil {
+					return fmt.Errorf("failed to resolve the devrig binary: %w", err)
+				}
+				run = ExecRunner(executable)
+			}
+
+			// The JSON document is the only output on stdout, the outputs of the projects go to stderr
+			out := cmd.OutOrStdout()
+			if output.FormatFromContext(cmd.Context()) == output.FormatJSON {
+				out = cmd.ErrOrStderr()
+			}
+			cmd.Printf("Running devrig %v in %d projects\n", args, len(projects))
+			results := Run(cmd.Context(), projects, args, parallel, run, func(result Result) {
+				_, _ = fmt.Fprintf(out, "==> %s\n", result.Project.Name)
+				_, _ = out.Write(result.Output)
+			})
+
+			var s summary.Summary
+			for _, result := range results {
+				s.Record(result.Project.Name, result.Duration, result.Err)
+			}
+			s.Print(cmd.Context(), cmd.OutOrStdout())
+
+			failed := 0
+			for _, result := range results {
+				if result.Err != nil {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("failed in %d of %d projects: %w", failed, len(projects), s.Err())
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&root, "root", ".", "Folder of the workspace to search for projects")
+	cmd.Flags().IntVar(&parallel, "parallel", runtime.NumCPU(), "Maximum number of projects to run at a time")
+	return cmd
+}
@@ -0,0 +1,128 @@
+package foreach
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newWorkspace creates the files relative to a new folder
+func newWorkspace(t *testing.T, files ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, file := range files {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create the folder of %s: %v", file, err)
+		}
+		if err := os.WriteFile(path, []byte("devrig:\n  version: 0.80.0\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+	return root
+}
+
+func TestDiscover(t *testing.T) {
+	root := newWorkspace(t,
+		"devrig.yaml",
+		"services/api/devrig.yaml",
+		"services/web/devrig.toml",
+		"services/web/node_modules/lib/devrig.yaml",
+		".git/devrig.yaml",
+		"docs/README.md",
+	)
+
+	projects, err := Discover(root)
+	if err != nil {
+		t.Fatalf("Failed to discover the projects: %v", err)
+	}
+	var names []string
+	for _, project := range projects {
+		names = append(names, project.Name)
+	}
+	if strings.Join(names, ",") != ".,services/api,services/web" {
+		t.Errorf("Unexpected projects: %v", names)
+	}
+	if projects[2].ConfigPath != filepath.Join(root, "services", "web", "devrig.toml") {
+		t.Errorf("Unexpected config of services/web: %s", projects[2].ConfigPath)
+	}
+}
+
+func TestRun_Parallel(t *testing.T) {
+	projects := []Project{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+	var running, maxRunning atomic.Int32
+	runner := func(ctx context.Context, project Project, args []string, output io.Writer) error {
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			seen := maxRunning.Load()
+			if current <= seen || maxRunning.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		_, _ = fmt.Fprintf(output, "%s %s\n", strings.Join(args, " "), project.Name)
+		if project.Name == "c" {
+			return fmt.Errorf("broken")
+		}
+		return nil
+	}
+
+	var finished []string
+	results := Run(context.Background(), projects, []string{"apply"}, 2, runner, func(result Result) {
+		finished = append(finished, result.Project.Name)
+	})
+
+	if maxRunning.Load() != 2 {
+		t.Errorf("Expected 2 projects at a time, got %d", maxRunning.Load())
+	}
+	if len(finished) != len(projects) {
+		t.Errorf("Expected every project to be reported, got %v", finished)
+	}
+	for i, result := range results {
+		if result.Project != projects[i] {
+			t.Errorf("Expected the results in the order of the projects, got %s at %d", result.Project.Name, i)
+		}
+		if string(result.Output) != "apply "+result.Project.Name+"\n" {
+			t.Errorf("Unexpected output of %s: %q", result.Project.Name, result.Output)
+		}
+		if (result.Err != nil) != (result.Project.Name == "c") {
+			t.Errorf("Unexpected error of %s: %v", result.Project.Name, result.Err)
+		}
+	}
+}
+
+func TestForeachCommand(t *testing.T) {
+	root := newWorkspace(t, "api/devrig.yaml", "web/devrig.yaml")
+	runner := func(ctx context.Context, project Project, args []string, output io.Writer) error {
+		_, _ = fmt.Fprintf(output, "output of %s\n", project.Name)
+		if project.Name == "web" {
+			return fmt.Errorf("no such task")
+		}
+		return nil
+	}
+
+	cmd := newForeachCommand(runner)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--root", root, "--", "run", "build"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "failed in 1 of 2 projects") {
+		t.Errorf("Expected the failure of web, got: %v", err)
+	}
+
+	text := out.String()
+	for _, expected := range []string{"==> api\noutput of api", "==> web\noutput of web", "OK      api", "FAILED  web", "no such task"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in the output:\n%s", expected, text)
+		}
+	}
+}
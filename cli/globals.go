@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/auth"
+	"jonnyzzz.com/devrig.dev/clock"
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configfile"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/contentstore"
+	"jonnyzzz.com/devrig.dev/deprecation"
+	"jonnyzzz.com/devrig.dev/devrig"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/filelock"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/maintenance"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/output"
+	"jonnyzzz.com/devrig.dev/progress"
+	"jonnyzzz.com/devrig.dev/provenance"
+	"jonnyzzz.com/devrig.dev/trial"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// globalOptions holds the persistent flags shared by all commands
+type globalOptions struct {
+	devrigConfigPath string
+	verbose          bool
+	quiet            bool
+	logJSON          bool
+	heartbeat        time.Duration
+	output           string
+	offline          bool
+	noWait           bool
+	allowDowngrade   bool
+	noUpdates        bool
+	profile          string
+	strictDeprecated bool
+
+	logCloser   io.Closer
+	configCache *config.Cache
+	// trialRun is set when the running version is on trial after `devrig upgrade --trial`
+	trialRun *trial.Run
+	// notifier announces a newer devrig after the output of the command
+	notifier *updates.Notifier
+	// updatesClient is the client of all commands, the maintenance reuses its downloads and failures
+	updatesClient *updates.Client
+}
+
+func (g *globalOptions) register(rootCmd *cobra.Command) {
+	flags := rootCmd.PersistentFlags()
+	flags.StringVar(&g.devrigConfigPath, "devrig-config", "", "Path to devrig.yaml configuration file")
+	flags.BoolVar(&g.verbose, "verbose", false, "Show debug output")
+	flags.BoolVarP(&g.quiet, "quiet", "q", false, "Show only errors")
+	flags.BoolVar(&g.logJSON, "log-json", false, "Print log messages as JSON lines")
+	flags.StringVar(&g.output, "output", string(output.FormatText), "Output format: text or json")
+	flags.BoolVar(&g.offline, "offline", false, "Never access the network, use local caches only (same as "+offline.EnvOffline+"=1)")
+	flags.BoolVar(&g.noWait, "no-wait", false, "Fail instead of waiting for another devrig process to release a lock (same as "+filelock.EnvNoWait+"=1)")
+	flags.BoolVar(&g.allowDowngrade, "allow-downgrade", false, "Accept release metadata older than the version verified before, e.g. after a withdrawn release (same as "+updates.EnvAllowDowngrade+"=1)")
+	flags.BoolVar(&g.noUpdates, "no-updates", false, "Do not check for a newer devrig (same as "+updates.EnvNoUpdateCheck+"=1)")
+	flags.StringVar(&g.profile, "profile", "", "Profile of devrig.yaml to use, e.g. backend (same as "+configservice.EnvProfile+"=<name>)")
+	flags.BoolVar(&g.strictDeprecated, "strict-deprecations", false, "Fail on deprecated flags, devrig.yaml keys and files instead of warning (same as "+deprecation.EnvStrict+"=1)")
+	flags.DurationVar(&g.heartbeat, "heartbeat-interval", progress.DefaultHeartbeatInterval,
+		"Interval of heartbeat lines during long operations when the output is not a terminal, 0 disables them")
+
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		return g.setup(cmd)
+	}
+
+	// Due maintenance runs after successful commands, so no background process is needed
+	rootCmd.PersistentPostRun = func(cmd *cobra.Command, args []string) {
+		maintenance.RunOpportunistically(cmd.Context(), g.configPath(), g.updatesClient)
+	}
+}
+
+// configPath resolves the devrig.yaml location, it is only valid once the flags are parsed
+func (g *globalOptions) configPath() string {
+	return devrig.ResolveConfigPath(g.devrigConfigPath)
+}
+
+// setup is executed before any command to apply the global flags
+func (g *globalOptions) setup(cmd *cobra.Command) error {
+	format, err := output.ParseFormat(g.output)
+	if err != nil {
+		return err
+	}
+
+	// The environment variable makes all modules and child processes see the mode
+	if g.offline {
+		if err := os.Setenv(offline.EnvOffline, "1"); err != nil {
+			return fmt.Errorf("failed to enable offline mode: %w", err)
+		}
+	}
+	if g.allowDowngrade {
+		if err := os.Setenv(updates.EnvAllowDowngrade, "1"); err != nil {
+			return fmt.Errorf("failed to allow downgrades: %w", err)
+		}
+	}
+	if g.noUpdates {
+		if err := os.Setenv(updates.EnvNoUpdateCheck, "1"); err != nil {
+			return fmt.Errorf("failed to disable update checks: %w", err)
+		}
+	}
+	if g.noWait {
+		if err := os.Setenv(filelock.EnvNoWait, "1"); err != nil {
+			return fmt.Errorf("failed to enable no-wait mode: %w", err)
+		}
+	}
+	if g.strictDeprecated {
+		if err := os.Setenv(deprecation.EnvStrict, "1"); err != nil {
+			return fmt.Errorf("failed to enable strict deprecations: %w", err)
+		}
+	}
+	if g.profile != "" {
+		if err := os.Setenv(configservice.EnvProfile, g.profile); err != nil {
+			return fmt.Errorf("failed to select profile %s: %w", g.profile, err)
+		}
+	}
+
+	logger, closer, err := logging.Setup(logging.Options{
+		Verbose: g.verbose,
+		Quiet:   g.quiet,
+		JSON:    g.logJSON,
+		LogDir:  g.resolveLogDir(),
+	}, cmd.ErrOrStderr())
+	if err != nil {
+		return err
+	}
+
+	g.logCloser = closer
+	ctx := logging.WithLogger(cmd.Context(), logger)
+	ctx = output.WithFormat(ctx, format)
+
+	// The parsed configurations live as long as the command, close drops them
+	g.configCache = config.NewCache(config.DefaultCacheSize)
+	ctx = config.WithCache(ctx, g.configCache)
+
+	// Projects with the shared cache link the IDEs and tools of the store of the user
+	store, err := contentstore.ForProject(configservice.NewConfigService(g.configPath()))
+	if err != nil {
+		logger.Warn("failed to read the cache section, the shared cache is not used", "error", err)
+	}
+	ctx = contentstore.WithStore(ctx, store)
+
+	// Progress bars are only drawn for humans, otherwise the progress is logged periodically
+	ctx = progress.WithReporter(ctx, progress.ReporterOptions{
+		Out:         cmd.ErrOrStderr(),
+		Interactive: progress.IsTerminal(cmd.ErrOrStderr()) && format == output.FormatText && !g.quiet,
+	})
+
+	// CI systems kill jobs without output for a while, interactive terminals do not need that
+	if !progress.IsTerminal(cmd.ErrOrStderr()) {
+		interval := g.heartbeat
+		if !cmd.Flags().Changed("heartbeat-interval") {
+			if interval, err = progress.ResolveHeartbeatInterval(); err != nil {
+				return err
+			}
+		}
+		ctx = progress.WithHeartbeat(ctx, progress.HeartbeatOptions{Interval: interval, Out: cmd.ErrOrStderr()})
+	}
+
+	cmd.SetContext(ctx)
+	logger.Debug("resolved devrig.yaml", "path", g.configPath(), "profile", configservice.ActiveProfile())
+
+	// Only one configuration file of the folder is used, the others are easy to edit by mistake
+	if used, shadowed := configfile.Find(filepath.Dir(g.configPath())); used == g.configPath() {
+		for _, path := range shadowed {
+			logger.Warn(fmt.Sprintf("%s is ignored, %s takes precedence. Remove one of them.", filepath.Base(path), filepath.Base(used)))
+		}
+	}
+
+	if err := g.checkDeprecations(ctx, cmd); err != nil {
+		return err
+	}
+
+	// Teams that sign devrig.yaml reject unsigned edits of the URLs and hashes before anything is downloaded,
+	// devrig sign and devrig verify report the signatures themselves
+	if !isCommand(cmd, "sign", "verify") {
+		if err := provenance.Check(g.configPath()); err != nil {
+			return err
+		}
+	}
+
+	// A crashed run of the version on trial reverts devrig.yaml before anything else runs
+	trialRun, outcome, err := trial.Begin(ctx, g.configPath(), VersionAndBuild(), time.Now())
+	if err != nil {
+		logger.Warn("failed to record the run of the devrig trial", "error", err)
+	}
+	if outcome != nil {
+		cmd.PrintErrln(outcome.String())
+	}
+	g.trialRun = trialRun
+
+	// An older devrig on PATH would ignore the sections of the newer pinned version, version and explain still work
+	// and upgrade pins a newer version
+	if !isCommand(cmd, "version", "explain", "upgrade") {
+		if err := configservice.CheckCompatibility(g.configPath(), VersionAndBuild()); err != nil {
+			return err
+		}
+	}
+
+	// Every response tells the time of the server, a skewed system clock is reported instead of failing obscurely
+	clock.Install()
+
+	// Downloads from SSO-protected hosts carry the tokens of `devrig auth login`
+	section, err := configservice.NewConfigService(g.configPath()).Auth().ReadAuth()
+	var notFound *devrigErrors.ConfigNotFoundError
+	switch {
+	case err == nil:
+		auth.Install(section.Providers, auth.NewTokenStore())
+	case !errors.As(err, &notFound):
+		logger.Warn("failed to read the auth section, downloads are not authenticated", "error", err)
+	}
+
+	// A newer devrig is announced after the output of the command, upgrade and update-notice report it themselves
+	if !isCommand(cmd, "upgrade", "update-notice") {
+		g.notifier.Start(ctx, updates.ResolveUpdateCheck(configservice.NewConfigService(g.configPath())), time.Now())
+	}
+
+	// A cache folder moved by devrig.yaml or DEVRIG_CACHE_DIR is checked once, not by every download and unpack
+	if err := config.CheckCacheDir(g.configPath()); err != nil {
+		return err
+	}
+
+	// Read-only checkouts keep working, but the state goes to another place the user should know about
+	if _, err := os.Stat(g.configPath()); err == nil && layout.IsReadOnlyCheckout(g.configPath()) {
+		logger.Info(fmt.Sprintf("The project folder %s is read-only, devrig state is stored in %s. Set %s to use another location.",
+			filepath.Dir(g.configPath()), layout.ResolveDevrigHome(g.configPath()), layout.EnvDevrigHome))
+	}
+	return nil
+}
+
+// isCommand checks the command by its path below the root command, so "upgrade" is `devrig upgrade`
+// and not `devrig ide upgrade`
+func isCommand(cmd *cobra.Command, paths ...string) bool {
+	path, ok := strings.CutPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	return ok && slices.Contains(paths, path)
+}
+
+// checkDeprecations reports the deprecated flags of the command line, keys of devrig.yaml and files next to it
+func (g *globalOptions) checkDeprecations(ctx context.Context, cmd *cobra.Command) error {
+	if err := deprecation.CheckFlags(ctx, cmd); err != nil {
+		return err
+	}
+
+	// The IDE of the first devrig versions was declared in .idew.yaml, devrig.yaml replaces it
+	legacyPath := filepath.Join(filepath.Dir(g.configPath()), config.LegacyConfigName)
+	if _, err := os.Stat(legacyPath); err == nil && !isCommand(cmd, "config migrate") {
+		if err := deprecation.Report(ctx, deprecation.KindFile, config.LegacyConfigName); err != nil {
+			return err
+		}
+	}
+
+	// A broken devrig.yaml is reported by the commands that read it
+	data, err := os.ReadFile(g.configPath())
+	if err != nil {
+		return nil
+	}
+	values, err := configfile.Decode(g.configPath(), data)
+	if err != nil {
+		return nil
+	}
+	return deprecation.CheckConfigKeys(ctx, values)
+}
+
+// resolveLogDir returns .devrig/logs for initialized projects, and empty otherwise,
+// so that running devrig outside a project never creates files there
+func (g *globalOptions) resolveLogDir() string {
+	devrigHome := layout.ResolveDevrigHome(g.configPath())
+	if info, err := os.Stat(devrigHome); err != nil || !info.IsDir() {
+		return ""
+	}
+	return filepath.Join(devrigHome, "logs")
+}
+
+// finishTrial records the result of the command for the version on trial
+func (g *globalOptions) finishTrial(cmd *cobra.Command, failure error) {
+	if g.trialRun == nil {
+		return
+	}
+	outcome, err := g.trialRun.Finish(context.Background(), failure, time.Now())
+	g.trialRun = nil
+	if err != nil {
+		cmd.PrintErrln("Failed to record the run of the devrig trial:", err)
+	}
+	if outcome != nil {
+		cmd.PrintErrln(outcome.String())
+	}
+}
+
+// cancelTrial drops the run of the version on trial, e.g. when the command is interrupted
+func (g *globalOptions) cancelTrial() {
+	g.trialRun.Cancel()
+}
+
+func (g *globalOptions) close() {
+	if g.logCloser != nil {
+		_ = g.logCloser.Close()
+	}
+	if g.configCache != nil {
+		g.configCache.Clear()
+	}
+}
@@ -11,13 +11,11 @@ require (
 
 require (
 	github.com/goccy/go-yaml v1.18.0
+	github.com/spf13/pflag v1.0.10
 	golang.org/x/crypto v0.43.0
+	golang.org/x/sys v0.37.0
 )
 
-require (
-	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.10 // indirect
-	golang.org/x/sys v0.37.0 // indirect
-)
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
 
 replace jonnyzzz.com/devrig.dev/bootstrap => ./bootstrap
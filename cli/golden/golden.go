@@ -0,0 +1,85 @@
+// Package golden compares the files generated in the tests with the expected ones in testdata/<name>.golden
+// of the package, so changes of formatting and ordering are seen in the review. Run the tests with
+// UPDATE_GOLDEN=1 to write the current output as the expected one:
+//
+//	UPDATE_GOLDEN=1 go test ./...
+package golden
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// EnvUpdate rewrites the golden files with the actual output instead of comparing them
+const EnvUpdate = "UPDATE_GOLDEN"
+
+// Path returns the golden file of the name, relative to the package of the test
+func Path(name string) string {
+	return filepath.Join("testdata", filepath.FromSlash(name)+".golden")
+}
+
+// Assert fails the test when the actual output differs from the golden file of the name,
+// the golden file is written instead when UPDATE_GOLDEN is set
+func Assert(t testing.TB, name string, actual []byte) {
+	t.Helper()
+	path := Path(name)
+	if os.Getenv(EnvUpdate) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, actual, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("Golden file %s is missing, run the test with %s=1 to create it", path, EnvUpdate)
+	}
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("The output differs from %s, run the test with %s=1 to update it if the change is expected\n%s",
+			path, EnvUpdate, Diff(string(expected), string(actual)))
+	}
+}
+
+// AssertString is Assert for a string output
+func AssertString(t testing.TB, name string, actual string) {
+	t.Helper()
+	Assert(t, name, []byte(actual))
+}
+
+// Diff describes the first difference of the texts with a few lines around it
+func Diff(expected string, actual string) string {
+	expectedLines := strings.SplitAfter(expected, "\n")
+	actualLines := strings.SplitAfter(actual, "\n")
+
+	line := 0
+	for line < len(expectedLines) && line < len(actualLines) && expectedLines[line] == actualLines[line] {
+		line++
+	}
+	if line == len(expectedLines) && line == len(actualLines) {
+		return "the texts are equal"
+	}
+
+	const context = 3
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "first difference at line %d:\n", line+1)
+	for i := max(0, line-context); i < line; i++ {
+		fmt.Fprintf(&sb, "  %q\n", expectedLines[i])
+	}
+	for i := line; i < min(len(expectedLines), line+context); i++ {
+		fmt.Fprintf(&sb, "- %q\n", expectedLines[i])
+	}
+	for i := line; i < min(len(actualLines), line+context); i++ {
+		fmt.Fprintf(&sb, "+ %q\n", actualLines[i])
+	}
+	return sb.String()
+}
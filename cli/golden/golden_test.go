@@ -0,0 +1,72 @@
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recorder keeps the failures of Assert instead of failing the test
+type recorder struct {
+	testing.TB
+	errors []string
+	fatal  string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, format)
+}
+
+// Fatalf keeps the first failure, the real one stops the test there
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	if r.fatal == "" {
+		r.fatal = format
+	}
+}
+
+func TestAssert(t *testing.T) {
+	dir := t.TempDir()
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer os.Chdir(originalDir)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change to temp directory: %v", err)
+	}
+
+	t.Setenv(EnvUpdate, "")
+	missing := &recorder{TB: t}
+	Assert(missing, "nested/output", []byte("a\n"))
+	if !strings.Contains(missing.fatal, "is missing") {
+		t.Errorf("Expected a missing golden file to fail, got %q", missing.fatal)
+	}
+
+	t.Setenv(EnvUpdate, "1")
+	Assert(t, "nested/output", []byte("a\nb\n"))
+	if data, err := os.ReadFile(filepath.Join(dir, "testdata", "nested", "output.golden")); err != nil || string(data) != "a\nb\n" {
+		t.Fatalf("Expected the golden file to be written, got %q: %v", data, err)
+	}
+
+	t.Setenv(EnvUpdate, "")
+	Assert(t, "nested/output", []byte("a\nb\n"))
+	changed := &recorder{TB: t}
+	Assert(changed, "nested/output", []byte("a\nc\n"))
+	if len(changed.errors) != 1 {
+		t.Errorf("Expected a changed output to fail, got %v", changed.errors)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	diff := Diff("a\nb\nc\n", "a\nx\nc\n")
+	if !strings.Contains(diff, "line 2") || !strings.Contains(diff, `- "b\n"`) || !strings.Contains(diff, `+ "x\n"`) {
+		t.Errorf("Unexpected diff:\n%s", diff)
+	}
+	if diff := Diff("a\n", "a\nb\n"); !strings.Contains(diff, "line 2") || !strings.Contains(diff, `+ "b\n"`) {
+		t.Errorf("Expected the added line in the diff:\n%s", diff)
+	}
+}
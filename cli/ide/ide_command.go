@@ -0,0 +1,480 @@
+package ide
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/feed"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/lock"
+	"jonnyzzz.com/devrig.dev/lockcmd"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/output"
+	"jonnyzzz.com/devrig.dev/state"
+	"jonnyzzz.com/devrig.dev/unpack"
+)
+
+// NewIdeCommand creates the ide command with the subcommands to work with the IDE of the project
+func NewIdeCommand(configPath func() string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ide",
+		Short: "Work with the IDE of the project",
+	}
+	cmd.AddCommand(newLaunchCommand(configPath))
+	cmd.AddCommand(newDownloadCommand(configPath))
+	cmd.AddCommand(newListCommand())
+	cmd.AddCommand(newInstallCommand(configPath))
+	cmd.AddCommand(newRemoveCommand(configPath))
+	cmd.AddCommand(newUpgradeCommand(configPath))
+	return cmd
+}
+
+// resolveIde returns the IDE of devrig.yaml with the name, or the one of the ide section without a name.
+// The flags override the version and the build, an IDE that is not declared needs the version.
+// Returns whether it is the IDE of the ide section as declared, it is the one locked in devrig.lock
+func resolveIde(configPath string, name string, version string, build string) (*configservice.IdeSection, bool, error) {
+	service := configservice.NewConfigService(configPath).IDE()
+	primary, err := service.ReadIde()
+	if err != nil {
+		return nil, false, err
+	}
+	ides, err := service.ReadIdes()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var section configservice.IdeSection
+	switch declared := ides.Find(name); {
+	case name == "" && primary == nil:
+		return nil, false, fmt.Errorf("%s has no ide section, pass the name and --version of the IDE", configPath)
+	case name == "":
+		section = *primary
+	case declared != nil:
+		section = *declared
+	case version == "":
+		return nil, false, fmt.Errorf("%s is not declared in %s, pass --version to install it", name, configPath)
+	default:
+		section = configservice.IdeSection{Name: name}
+	}
+
+	isPrimary := primary != nil && section.Name == primary.Name && version == "" && build == ""
+	if version != "" {
+		section.Version, section.Build = version, ""
+	}
+	if build != "" {
+		section.Build = build
+	}
+	return &section, isPrimary, nil
+}
+
+func newInstallCommand(configPath func() string) *cobra.Command {
+	var version string
+	var build string
+	var frozen bool
+	var verify bool
+	cmd := &cobra.Command{
+		Use:   "install [name]",
+		Short: "Download, verify and unpack an IDE of devrig.yaml",
+		Long: `Download, verify and unpack an IDE of devrig.yaml into the cache of the project.
+
+The IDE of the ide section is installed by default, a name selects an IDE
+of the ides section. --version and --build override the declared ones, or
+install an IDE that devrig.yaml does not declare. The package is resolved in
+the JetBrains feed, verified against its checksum and unpacked, an IDE that
+is already unpacked is reused without network access. The build locked in
+devrig.lock is installed for the ide section, --frozen fails if it is not locked.
+The plugins of the IDE are installed from the JetBrains Marketplace too.
+
+An unpacked IDE is checked against the manifest written when it was unpacked,
+the size of every file and the content of a sample of them. --verify hashes
+every file. A corrupted or tampered IDE is unpacked again.
+
+Examples:
+  devrig ide install
+  devrig ide install WebStorm
+  devrig ide install GoLand --version 2025.1
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			section, isPrimary, err := resolveIde(configPath(), name, version, build)
+			if err != nil {
+				return err
+			}
+
+			var locked *lock.Artifact
+			if isPrimary {
+				lockfile, err := lock.Load(lock.ResolvePath(configPath()))
+				if err != nil {
+					return err
+				}
+				locked, err = lockfile.Resolve(feed.IdeLockName, feed.IdeLockRequest(section.Request()), lock.Platform(runtime.GOOS, runtime.GOARCH), frozen)
+				if err != nil {
+					return err
+				}
+			}
+
+			ctx := cmd.Context()
+			if verify {
+				ctx = unpack.WithFullVerification(ctx)
+			}
+			installed, err := Install(ctx, configPath(), section, locked)
+			if err != nil {
+				return err
+			}
+			if installed.Archive == "" {
+				cmd.Printf("%s %s is already installed at %s\n", installed.Name, installed.Build, installed.Home)
+			} else {
+				cmd.Printf("Installed %s %s to %s\n", installed.Name, installed.Build, installed.Home)
+			}
+			for _, plugin := range installed.Plugins {
+				if plugin.Downloaded {
+					cmd.Printf("Installed plugin %s %s\n", plugin.ID, plugin.Version)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&version, "version", "", "Version of the IDE instead of the declared one")
+	cmd.Flags().StringVar(&build, "build", "", "Exact build of the IDE instead of the declared one")
+	cmd.Flags().BoolVar(&frozen, "frozen", false, "Fail if devrig.lock has no build of the IDE")
+	cmd.Flags().BoolVar(&verify, "verify", false, "Hash every file of an unpacked IDE instead of a sample")
+	return cmd
+}
+
+func newRemoveCommand(configPath func() string) *cobra.Command {
+	var build string
+	cmd := &cobra.Command{
+		Use:   "remove [name]",
+		Short: "Delete an unpacked IDE and its downloaded package from the cache",
+		Long: `Delete an unpacked IDE and its downloaded package from the cache of the project.
+
+The IDE of the ide section is removed by default, a name selects another one.
+All cached builds of the IDE are removed unless --build is set. devrig.yaml
+is not changed, devrig ide install brings the IDE back.
+
+Examples:
+  devrig ide remove
+  devrig ide remove WebStorm --build 251.23774.42
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			} else {
+				section, _, err := resolveIde(configPath(), "", "", "")
+				if err != nil {
+					return err
+				}
+				name = section.Name
+			}
+
+			removed, err := Remove(cmd.Context(), config.ResolveCacheDir(configPath()), name, build)
+			for _, path := range removed {
+				cmd.Printf("Removed %s\n", path)
+			}
+			if err := state.Prune(cmd.Context(), layout.ResolveDevrigHome(configPath())); err != nil {
+				cmd.Printf("Failed to update %s: %v\n", state.FileName, err)
+			}
+			if err != nil {
+				return err
+			}
+			if len(removed) == 0 {
+				cmd.Printf("%s is not in the cache\n", name)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&build, "build", "", "Remove only this build of the IDE")
+	return cmd
+}
+
+func newUpgradeCommand(configPath func() string) *cobra.Command {
+	var version string
+	var build string
+	var yes bool
+	cmd := &cobra.Command{
+		Use:   "upgrade [name]",
+		Short: "Preview and apply a new version of an IDE of devrig.yaml",
+		Long: `Preview what a new version of an IDE of devrig.yaml changes, and apply it with --yes.
+
+The IDE of the ide section is upgraded by default, a name selects an IDE of
+the ides section. The new version is resolved in the JetBrains feed and the
+preview shows the download size, whether the unpacked current version stays
+in the cache with the retention policy, the plugins without a version
+compatible with the new build and whether the IDE rebuilds its indexes, which
+happens for a new major version. Nothing is downloaded. devrig.yaml is only
+changed with --yes, the preview is printed as JSON with --output json.
+
+Examples:
+  devrig ide upgrade --version 2025.1
+  devrig ide upgrade WebStorm --version 2025.1 --yes
+`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if version == "" && build == "" {
+				return fmt.Errorf("pass --version or --build of the new IDE version")
+			}
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			current, _, err := resolveIde(configPath(), name, "", "")
+			if err != nil {
+				return err
+			}
+			target := *current
+			if version != "" {
+				target.Version, target.Build = version, ""
+			}
+			if build != "" {
+				target.Build = build
+			}
+
+			preview, err := PreviewUpgrade(cmd.Context(), configPath(), current, &target)
+			if err != nil {
+				return err
+			}
+			if output.FormatFromContext(cmd.Context()) == output.FormatJSON {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(preview); err != nil {
+					return fmt.Errorf("failed to write the preview: %w", err)
+				}
+			} else {
+				printUpgradePreview(cmd.OutOrStdout(), preview)
+			}
+
+			if !yes {
+				cmd.Println("devrig.yaml is not changed, run the command again with --yes to apply the new version")
+				return nil
+			}
+			isPrimary, err := UpdateDeclared(configPath(), &target)
+			if err != nil {
+				return err
+			}
+			cmd.Printf("Updated %s to %s in %s\n", target.Name, target.Version, configPath())
+			if isPrimary {
+				cmd.Println("Run devrig lock to update devrig.lock")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&version, "version", "", "New version of the IDE, e.g. 2025.1")
+	cmd.Flags().StringVar(&build, "build", "", "New exact build of the IDE")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Write the new version into devrig.yaml")
+	return cmd
+}
+
+func newListCommand() *cobra.Command {
+	var filter feed.ReleaseFilter
+	var allPlatforms bool
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the products, versions and builds of the JetBrains feed",
+		Long: `List the products, versions and builds of the JetBrains feed.
+
+The releases of the current platform are listed by default, the newest builds
+of every product first. --name matches a part of the product name ignoring
+the case, --quality selects e.g. release or eap builds. Use --os and --arch
+for another platform, or --all-platforms. With --output json the releases are
+printed as a JSON array, e.g. to pick a build in a script.
+
+Examples:
+  devrig ide list --name goland
+  devrig ide list --name rider --quality eap --os darwin --arch arm64
+  devrig --output json ide list --name "IntelliJ IDEA Ultimate"
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if allPlatforms {
+				filter.GOOS, filter.GOARCH = "", ""
+			}
+			releases, err := feed.ListReleases(cmd.Context(), filter)
+			if err != nil {
+				return err
+			}
+
+			if output.FormatFromContext(cmd.Context()) == output.FormatJSON {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(releases); err != nil {
+					return fmt.Errorf("failed to write releases: %w", err)
+				}
+				return nil
+			}
+
+			if len(releases) == 0 {
+				cmd.Println("No releases match the filter")
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "NAME\tVERSION\tBUILD\tQUALITY\tRELEASED\tPLATFORM\tTYPE")
+			for _, release := range releases {
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s-%s\t%s\n", release.Name, release.Version, release.Build,
+					release.Quality, release.Released, release.OS, release.Arch, release.PackageType)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&filter.Name, "name", "", "Part of the product name, e.g. goland")
+	cmd.Flags().StringVar(&filter.Quality, "quality", "", "Quality of the builds, e.g. release or eap")
+	cmd.Flags().StringVar(&filter.GOOS, "os", runtime.GOOS, "OS of the packages: darwin, linux or windows")
+	cmd.Flags().StringVar(&filter.GOARCH, "arch", runtime.GOARCH, "Architecture of the packages: amd64 or arm64")
+	cmd.Flags().BoolVar(&allPlatforms, "all-platforms", false, "List the packages of all platforms")
+	return cmd
+}
+
+func newDownloadCommand(configPath func() string) *cobra.Command {
+	var dir string
+	cmd := &cobra.Command{
+		Use:   "download",
+		Short: "Download the IDE of devrig.yaml for a platform without unpacking it",
+		Long: `Download the IDE of the ide section of devrig.yaml for a platform without unpacking it.
+
+The package is verified against the checksum of the feed and saved to
+.devrig/downloads/<os>-<arch>, or to --dir. Use --target-os and --target-arch
+to prepare the IDE for another platform, e.g. on a Linux CI agent for the
+macOS machines of the team, the package is unpacked there. The build locked
+in devrig.lock is downloaded when the lock has the platform.
+
+Examples:
+  devrig ide download
+  devrig ide download --target-os darwin --target-arch arm64 --dir out
+`,
+		Args: cobra.NoArgs,
+	}
+	target := lockcmd.AddTargetFlags(cmd)
+	cmd.Flags().StringVar(&dir, "dir", "", "Folder to save the package to, .devrig/downloads/<os>-<arch> by default")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		platform, err := target.Platform()
+		if err != nil {
+			return err
+		}
+		pins, err := lockcmd.ProjectPins(configPath())
+		if err != nil {
+			return err
+		}
+		pins = slices.DeleteFunc(pins, func(pin lockcmd.Pin) bool { return pin.Name != feed.IdeLockName })
+		if len(pins) == 0 {
+			return fmt.Errorf("%s has no ide section", configPath())
+		}
+
+		downloaded, err := lockcmd.DownloadPins(cmd.Context(), configPath(), pins, platform, dir)
+		if err != nil {
+			return err
+		}
+		lockcmd.PrintDownloaded(cmd, downloaded, platform)
+		return nil
+	}
+	return cmd
+}
+
+func newLaunchCommand(configPath func() string) *cobra.Command {
+	var wait bool
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "launch [-- extra IDE arguments]",
+		Short: "Start the IDE of devrig.yaml with the project opened",
+		Long: `Start the IDE of the ide section of devrig.yaml with the project opened.
+
+The launch subsection declares the arguments, the environment variables and the
+project to open, e.g. a solution file. The IDE runs with the devrig-managed tools,
+the same environment as devrig exec. Values may use template variables:
+  ${project_root}  the folder of devrig.yaml
+  ${devrig_home}   the .devrig folder of the project
+  ${ide_home}      the folder of the unpacked IDE
+  ${java_home}     the JDK of the jdk section
+  ${go_root}       the Go toolchain pinned in the tools section
+  ${env:NAME}      the environment variable NAME of devrig
+Use $$ for a literal $.
+
+Example of devrig.yaml:
+  ide:
+    name: Rider
+    version: 2025.1
+    launch:
+      project: backend/Backend.sln
+      args: ["-Didea.log.debug.categories=#com.example"]
+      env:
+        JAVA_HOME: ${java_home}
+
+Examples:
+  devrig ide launch
+  devrig ide launch --dry-run
+  devrig ide launch -- nosplash
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			launch, err := ResolveLaunch(configPath(), os.Environ(), runtime.GOOS, runtime.GOARCH)
+			if err != nil {
+				return err
+			}
+			// Extra arguments follow the ones of devrig.yaml, the project stays the last argument
+			project := launch.Args[len(launch.Args)-1]
+			launch.Args = append(append(launch.Args[:len(launch.Args)-1:len(launch.Args)-1], args...), project)
+
+			if dryRun {
+				names := make([]string, 0, len(launch.Vars))
+				for name := range launch.Vars {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					cmd.Printf("%s=%s\n", name, launch.Vars[name])
+				}
+				cmd.Println(launch.Path)
+				for _, arg := range launch.Args {
+					cmd.Printf("  %s\n", arg)
+				}
+				return nil
+			}
+
+			// The plugins of devrig.yaml are in place before the IDE starts for the first time
+			if _, err := InstallPlugins(cmd.Context(), config.ResolveCacheDir(configPath()), launch.Home, launch.Plugins, runtime.GOOS); err != nil {
+				return err
+			}
+
+			// The last use keeps the IDE in the cache, see the max_age_days retention
+			if err := state.Touch(cmd.Context(), layout.ResolveDevrigHome(configPath()), launch.Home, time.Now()); err != nil {
+				logging.FromContext(cmd.Context()).Warn("failed to record the use of the IDE", "error", err)
+			}
+			logging.FromContext(cmd.Context()).Debug("starting the IDE", "path", launch.Path, "args", launch.Args)
+			child := exec.Command(launch.Path, launch.Args...)
+			child.Env = launch.Env
+			child.Dir = filepath.Dir(configPath())
+			if wait {
+				child.Stdout = cmd.OutOrStdout()
+				child.Stderr = cmd.ErrOrStderr()
+				if err := child.Run(); err != nil {
+					return fmt.Errorf("the IDE failed: %w", err)
+				}
+				return nil
+			}
+			if err := child.Start(); err != nil {
+				return fmt.Errorf("failed to start the IDE %s: %w", launch.Path, err)
+			}
+			cmd.Printf("Started %s (pid %d)\n", launch.Path, child.Process.Pid)
+			// The IDE keeps running after devrig exits
+			return child.Process.Release()
+		},
+	}
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait for the IDE to exit and show its output")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the command line and the variables instead of starting the IDE")
+	return cmd
+}
@@ -0,0 +1,188 @@
+package ide
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/cache"
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/feed"
+	"jonnyzzz.com/devrig.dev/filelock"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/lock"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/plugins"
+	"jonnyzzz.com/devrig.dev/state"
+	"jonnyzzz.com/devrig.dev/unpack"
+)
+
+// Installed is an IDE unpacked into the cache of the project
+type Installed struct {
+	Name  string
+	Build string
+	Home  string
+	// Archive is the downloaded package, empty if the IDE was already unpacked
+	Archive string
+	// Plugins are the plugins of devrig.yaml in the IDE
+	Plugins []plugins.Installed
+}
+
+// Install resolves the IDE in the feed, downloads and verifies the package and unpacks it into the cache.
+// The locked artifact selects the build of devrig.lock. An unpacked IDE of the requested build is reused after
+// it is checked against its manifest, it needs no network access
+func Install(ctx context.Context, configPath string, section *configservice.IdeSection, locked *lock.Artifact) (*Installed, error) {
+	build := section.Build
+	if locked != nil {
+		build = locked.Build
+	}
+	cacheDir := config.ResolveCacheDir(configPath)
+	if build != "" {
+		pinned := *section
+		pinned.Build = build
+		home, err := FindUnpacked(cacheDir, &pinned)
+		if err == nil {
+			// A corrupted IDE is removed and unpacked again from the package below
+			if err = unpack.VerifyUnpacked(ctx, home); err != nil {
+				logging.FromContext(ctx).Warn("Unpacking the IDE again", "path", home, "reason", err)
+				if err := removeLocked(ctx, cacheDir, home); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err == nil {
+			installed, err := InstallPlugins(ctx, cacheDir, home, section.Plugins, runtime.GOOS)
+			if err != nil {
+				return nil, err
+			}
+			return recordInstalled(ctx, configPath, cacheDir, &Installed{Name: section.Name, Build: build, Home: home, Plugins: installed}), nil
+		}
+	}
+
+	request := config.NewIDEConfig(section.Name, section.Version, build)
+	remote, err := feed.ResolveRemoteIde(ctx, request, section.PackageTypes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s %s: %w", section.Name, section.Version, err)
+	}
+
+	localConfig := config.NewConfig(configPath, request)
+	downloaded, err := feed.DownloadFeedEntry(ctx, remote, localConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s %s: %w", section.Name, remote.Build(), err)
+	}
+	unpacked, err := unpack.UnpackIde(ctx, localConfig, downloaded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unpack %s %s: %w", section.Name, remote.Build(), err)
+	}
+
+	// The new build is in place, older ones can go according to the retention policy
+	if err := cache.EnforceRetention(ctx, configPath, cacheDir, unpacked.UnpackedHome(), downloaded.TargetFile()); err != nil {
+		logging.FromContext(ctx).Warn("failed to apply the retention policy", "error", err)
+	}
+
+	installed, err := InstallPlugins(ctx, cacheDir, unpacked.UnpackedHome(), section.Plugins, runtime.GOOS)
+	if err != nil {
+		return nil, err
+	}
+	return recordInstalled(ctx, configPath, cacheDir, &Installed{Name: section.Name, Build: remote.Build(), Home: unpacked.UnpackedHome(), Archive: downloaded.TargetFile(), Plugins: installed}), nil
+}
+
+// recordInstalled keeps the IDE in state.json of the project and registers the project in the cache, the IDE
+// works without the records, so a failure to write them does not fail the installation
+func recordInstalled(ctx context.Context, configPath string, cacheDir string, installed *Installed) *Installed {
+	checksum, _ := unpack.ArchiveChecksum(installed.Home)
+	artifact := state.Artifact{Type: state.TypeIde, Name: installed.Name, Version: installed.Build, Checksum: checksum, Path: installed.Home}
+	if err := state.Record(ctx, layout.ResolveDevrigHome(configPath), artifact, time.Now()); err != nil {
+		logging.FromContext(ctx).Warn("failed to record the IDE in "+state.FileName, "error", err)
+	}
+	if err := cache.RegisterProject(cacheDir, configPath); err != nil {
+		logging.FromContext(ctx).Warn("failed to register the project in the cache", "error", err)
+	}
+	return installed
+}
+
+// InstallPlugins installs the plugins of devrig.yaml into the unpacked IDE from the JetBrains Marketplace,
+// the versions without a version in devrig.yaml are compatible with the build of product-info.json.
+// Installed plugins are kept, so it needs no network access once the plugins are in place
+func InstallPlugins(ctx context.Context, cacheDir string, home string, requested []configservice.IdePlugin, goos string) ([]plugins.Installed, error) {
+	var build string
+	if len(requested) > 0 {
+		info, _, err := readProductInfo(home, goos)
+		if err != nil {
+			return nil, err
+		}
+		build = info.ProductCode + "-" + info.BuildNumber
+	}
+
+	// The plugins change the IDE folder, so they are installed under its lock
+	ideLock, err := filelock.Acquire(ctx, layout.ResolveLockFile(cacheDir, filepath.Base(home)), "installing plugins into "+filepath.Base(home))
+	if err != nil {
+		return nil, err
+	}
+	defer ideLock.Release()
+
+	installed, err := plugins.Install(ctx, plugins.NewMarketplace(), plugins.Dir(home, goos), build, requested)
+	if err != nil {
+		return nil, fmt.Errorf("failed to install the plugins of %s: %w", filepath.Base(home), err)
+	}
+	return installed, nil
+}
+
+// Remove deletes the unpacked IDE and its downloaded packages from the cache, all builds of the IDE without a build.
+// Returns the removed paths
+func Remove(ctx context.Context, cacheDir string, name string, build string) ([]string, error) {
+	var removed []string
+	for _, dir := range []string{layout.ResolveUnpackedIdesDir(cacheDir), layout.ResolveDownloadsDir(cacheDir)} {
+		archives := dir == layout.ResolveDownloadsDir(cacheDir)
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to list %s: %w", dir, err)
+		}
+		for _, entry := range entries {
+			// The manifest goes together with its IDE folder
+			if strings.HasSuffix(entry.Name(), layout.IdeManifestSuffix) || !matchesBuild(entry.Name(), archives, name, build) {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if err := removeLocked(ctx, cacheDir, path); err != nil {
+				return removed, err
+			}
+			removed = append(removed, path)
+		}
+	}
+	return removed, nil
+}
+
+// matchesBuild checks if the unpacked IDE <name>-<build>[.app] or the archive <name>-<build>.<package type> is of the IDE
+func matchesBuild(entry string, archive bool, name string, build string) bool {
+	if build != "" {
+		if archive {
+			return strings.TrimSuffix(entry, filepath.Ext(entry)) == layout.UnpackedIdeName(name, build)
+		}
+		return strings.TrimSuffix(entry, ".app") == layout.UnpackedIdeName(name, build)
+	}
+	rest, ok := strings.CutPrefix(entry, layout.UnpackedIdeName(name, ""))
+	// The build follows the name, so IntelliJ IDEA does not match IntelliJ IDEA Ultimate
+	return ok && rest != "" && rest[0] >= '0' && rest[0] <= '9'
+}
+
+// removeLocked removes the cache entry under its lock, so a concurrent download or unpack of it is not broken
+func removeLocked(ctx context.Context, cacheDir string, path string) error {
+	entryLock, err := filelock.Acquire(ctx, layout.ResolveLockFile(cacheDir, filepath.Base(path)), "removing "+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer entryLock.Release()
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	if err := os.Remove(layout.ResolveIdeManifest(path)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove the manifest of %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package ide
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/lock"
+)
+
+func TestInstall_ReusesUnpackedBuild(t *testing.T) {
+	configPath := writeProject(t, "", "243.1", "243.2")
+	section := &configservice.IdeSection{Name: "GoLand", Version: "2024.3", Build: "243.1"}
+
+	installed, err := Install(context.Background(), configPath, section, nil)
+	if err != nil {
+		t.Fatalf("Failed to install the IDE: %v", err)
+	}
+	if filepath.Base(installed.Home) != "GoLand-243.1" || installed.Build != "243.1" || installed.Archive != "" {
+		t.Errorf("Expected the unpacked build to be reused, got %+v", installed)
+	}
+
+	installed, err = Install(context.Background(), configPath, section, &lock.Artifact{Build: "243.2"})
+	if err != nil {
+		t.Fatalf("Failed to install the locked IDE: %v", err)
+	}
+	if filepath.Base(installed.Home) != "GoLand-243.2" {
+		t.Errorf("Expected the locked build, got %s", installed.Home)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	configPath := writeProject(t, "")
+	cacheDir := config.ResolveCacheDir(configPath)
+	unpackedDir := layout.ResolveUnpackedIdesDir(cacheDir)
+	downloadsDir := layout.ResolveDownloadsDir(cacheDir)
+	for _, path := range []string{
+		filepath.Join(unpackedDir, "GoLand-243.1", "bin"),
+		filepath.Join(unpackedDir, "GoLand-243.1.5", "bin"),
+		filepath.Join(unpackedDir, "IntelliJ_IDEA_Ultimate-243.1", "bin"),
+		filepath.Join(unpackedDir, "IntelliJ_IDEA-243.1", "bin"),
+		downloadsDir,
+	} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", path, err)
+		}
+	}
+	for _, name := range []string{"GoLand-243.1.dmg", "GoLand-243.1.5.dmg"} {
+		if err := os.WriteFile(filepath.Join(downloadsDir, name), []byte("package"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	removed, err := Remove(context.Background(), cacheDir, "GoLand", "243.1")
+	if err != nil {
+		t.Fatalf("Failed to remove the IDE: %v", err)
+	}
+	expected := []string{filepath.Join(unpackedDir, "GoLand-243.1"), filepath.Join(downloadsDir, "GoLand-243.1.dmg")}
+	if !slices.Equal(removed, expected) {
+		t.Errorf("Expected %v to be removed, got %v", expected, removed)
+	}
+	if _, err := os.Stat(filepath.Join(unpackedDir, "GoLand-243.1.5")); err != nil {
+		t.Errorf("Expected another build to be kept: %v", err)
+	}
+
+	removed, err = Remove(context.Background(), cacheDir, "IntelliJ IDEA", "")
+	if err != nil {
+		t.Fatalf("Failed to remove the IDE: %v", err)
+	}
+	if !slices.Equal(removed, []string{filepath.Join(unpackedDir, "IntelliJ_IDEA-243.1")}) {
+		t.Errorf("Expected only IntelliJ IDEA to be removed, got %v", removed)
+	}
+
+	removed, err = Remove(context.Background(), cacheDir, "Rider", "")
+	if err != nil || len(removed) != 0 {
+		t.Errorf("Expected nothing to remove for Rider, got %v, %v", removed, err)
+	}
+}
+
+func TestResolveIde(t *testing.T) {
+	configPath := writeProject(t, "ides:\n  - name: WebStorm\n    version: 2025.1\n")
+
+	section, primary, err := resolveIde(configPath, "", "", "")
+	if err != nil || section.Name != "GoLand" || !primary {
+		t.Errorf("Expected the ide section, got %+v, %v, %v", section, primary, err)
+	}
+	section, primary, err = resolveIde(configPath, "webstorm", "", "251.1")
+	if err != nil || section.Name != "WebStorm" || section.Build != "251.1" || primary {
+		t.Errorf("Expected WebStorm of the ides section, got %+v, %v, %v", section, primary, err)
+	}
+	section, primary, err = resolveIde(configPath, "GoLand", "2025.1", "")
+	if err != nil || section.Version != "2025.1" || primary {
+		t.Errorf("Expected the version to be overridden, got %+v, %v, %v", section, primary, err)
+	}
+	if _, _, err := resolveIde(configPath, "Rider", "", ""); err == nil {
+		t.Error("Expected an error for an undeclared IDE without a version")
+	}
+}
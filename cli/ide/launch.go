@@ -0,0 +1,220 @@
+package ide
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/execcmd"
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+// Launch is the resolved command line of the IDE
+type Launch struct {
+	// Path is the launcher of the IDE, e.g. bin/goland.sh
+	Path string
+	// Args are the arguments of the launch section followed by the project to open
+	Args []string
+	// Env is the environment of the IDE process: the devrig-managed tools and the env of the launch section
+	Env []string
+	// Vars are the variables the launch section added, for printing
+	Vars map[string]string
+	// Home is the folder of the unpacked IDE
+	Home string
+	// Plugins are the plugins of the ide section, they are installed before the IDE starts
+	Plugins []configservice.IdePlugin
+}
+
+// ResolveLaunch builds the command line of the IDE declared in devrig.yaml, the IDE must be unpacked in the cache.
+// The template variables of the launch section are replaced with the locations of the project
+func ResolveLaunch(configPath string, environ []string, goos string, goarch string) (*Launch, error) {
+	section, err := configservice.NewConfigService(configPath).IDE().ReadIde()
+	if err != nil {
+		return nil, err
+	}
+	if section == nil {
+		return nil, fmt.Errorf("%s has no ide section", configPath)
+	}
+
+	home, err := FindUnpacked(config.ResolveCacheDir(configPath), section)
+	if err != nil {
+		return nil, err
+	}
+	launcher, err := findLauncher(home, goos, goarch)
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := execcmd.ResolveEnvironment(configPath, goos)
+	if err != nil {
+		return nil, err
+	}
+	variables := map[string]string{
+		"project_root": filepath.Dir(configPath),
+		"devrig_home":  layout.ResolveDevrigHome(configPath),
+		"ide_home":     home,
+		"java_home":    env.Vars["JAVA_HOME"],
+		"go_root":      env.Vars["GOROOT"],
+	}
+	lookupEnv := func(name string) string {
+		for _, entry := range environ {
+			if key, value, _ := strings.Cut(entry, "="); key == name {
+				return value
+			}
+		}
+		return ""
+	}
+
+	launch := &Launch{Path: launcher, Vars: map[string]string{}, Home: home, Plugins: section.Plugins}
+	settings := section.Launch
+	if settings == nil {
+		settings = &configservice.IdeLaunchSection{}
+	}
+	for _, arg := range settings.Args {
+		expanded, err := expand(arg, variables, lookupEnv)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand the IDE argument %q: %w", arg, err)
+		}
+		launch.Args = append(launch.Args, expanded)
+	}
+	for name, value := range settings.Env {
+		expanded, err := expand(value, variables, lookupEnv)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand the IDE variable %s: %w", name, err)
+		}
+		env.Vars[name] = expanded
+		launch.Vars[name] = expanded
+	}
+
+	project, err := expand(settings.Project, variables, lookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand the IDE project %q: %w", settings.Project, err)
+	}
+	project = filepath.FromSlash(project)
+	if !filepath.IsAbs(project) {
+		project = filepath.Join(filepath.Dir(configPath), project)
+	}
+	launch.Args = append(launch.Args, project)
+
+	launch.Env = env.Apply(environ, goos)
+	return launch, nil
+}
+
+// expand replaces the ${name} template variables, ${env:NAME} is the environment variable of devrig and $$ is a $.
+// Unknown variables and the locations of tools that are not installed are errors
+func expand(value string, variables map[string]string, lookupEnv func(string) string) (string, error) {
+	var failure error
+	expanded := os.Expand(value, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		if env, ok := strings.CutPrefix(name, "env:"); ok {
+			return lookupEnv(env)
+		}
+		resolved, known := variables[name]
+		switch {
+		case !known:
+			failure = fmt.Errorf("unknown template variable ${%s}, expected one of %s", name, variableNames(variables))
+		case resolved == "" && failure == nil:
+			failure = fmt.Errorf("${%s} is not available, install the tool with devrig install", name)
+		}
+		return resolved
+	})
+	return expanded, failure
+}
+
+func variableNames(variables map[string]string) string {
+	names := make([]string, 0, len(variables)+1)
+	for name := range variables {
+		names = append(names, "${"+name+"}")
+	}
+	sort.Strings(names)
+	return strings.Join(append(names, "${env:NAME}"), ", ")
+}
+
+// FindUnpacked returns the most recently unpacked folder of the IDE in the cache, an exact build is required if it is set
+func FindUnpacked(cacheDir string, section *configservice.IdeSection) (string, error) {
+	dir := layout.ResolveUnpackedIdesDir(cacheDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to list unpacked IDEs: %w", err)
+	}
+
+	var latest string
+	var latestTime time.Time
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".app")
+		if !entry.IsDir() || !strings.HasPrefix(name, layout.UnpackedIdeName(section.Name, "")) {
+			continue
+		}
+		if section.Build != "" && name != layout.UnpackedIdeName(section.Name, section.Build) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if latest == "" || info.ModTime().After(latestTime) {
+			latest = filepath.Join(dir, entry.Name())
+			latestTime = info.ModTime()
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("%s %s is not unpacked in %s, download it first", section.Name, section.Version, dir)
+	}
+	return latest, nil
+}
+
+// productInfo is the product-info.json of an IDE, it lists the launchers of all platforms
+type productInfo struct {
+	Name        string `json:"name"`
+	ProductCode string `json:"productCode"`
+	BuildNumber string `json:"buildNumber"`
+	Launch      []struct {
+		OS           string `json:"os"`
+		Arch         string `json:"arch"`
+		LauncherPath string `json:"launcherPath"`
+	} `json:"launch"`
+}
+
+// readProductInfo reads the product-info.json of the unpacked IDE, it is in the resources of the bundle on macOS.
+// Returns the folder of the file too, the paths of the file are relative to it
+func readProductInfo(home string, goos string) (*productInfo, string, error) {
+	infoDir := home
+	if goos == "darwin" {
+		infoDir = filepath.Join(home, "Contents", "Resources")
+	}
+	data, err := os.ReadFile(filepath.Join(infoDir, "product-info.json"))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read the product info of %s: %w", home, err)
+	}
+	var info productInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, "", fmt.Errorf("failed to parse the product info of %s: %w", home, err)
+	}
+	return &info, infoDir, nil
+}
+
+// findLauncher returns the launcher of the IDE for the platform from its product-info.json
+func findLauncher(home string, goos string, goarch string) (string, error) {
+	info, infoDir, err := readProductInfo(home, goos)
+	if err != nil {
+		return "", err
+	}
+
+	osName := map[string]string{"linux": "Linux", "darwin": "macOS", "windows": "Windows"}[goos]
+	archName := map[string]string{"amd64": "amd64", "arm64": "aarch64"}[goarch]
+	for _, launch := range info.Launch {
+		if !strings.EqualFold(launch.OS, osName) || (launch.Arch != "" && launch.Arch != archName) {
+			continue
+		}
+		return filepath.Clean(filepath.Join(infoDir, filepath.FromSlash(launch.LauncherPath))), nil
+	}
+	return "", fmt.Errorf("%s has no launcher for %s %s", info.Name, goos, goarch)
+}
@@ -0,0 +1,178 @@
+package ide
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+const productInfoJSON = `{
+  "name": "GoLand",
+  "launch": [
+    {"os": "Linux", "arch": "amd64", "launcherPath": "bin/goland.sh"},
+    {"os": "Windows", "arch": "amd64", "launcherPath": "bin/goland64.exe"}
+  ]
+}`
+
+// writeProject creates devrig.yaml with the ide section and an unpacked IDE of the given build
+func writeProject(t *testing.T, ide string, builds ...string) string {
+	t.Helper()
+	return writeProjectIn(t, t.TempDir(), ide, builds...)
+}
+
+func writeProjectIn(t *testing.T, projectDir string, ide string, builds ...string) string {
+	t.Helper()
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	configPath := filepath.Join(projectDir, "devrig.yaml")
+	content := "ide:\n  name: GoLand\n  version: 2024.3\n" + ide +
+		"jdk:\n  vendor: temurin\n  version: 21.0.5+11\n  path: .devrig/tools/jdk-temurin-21.0.5_11\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+
+	for _, build := range builds {
+		home := filepath.Join(layout.ResolveUnpackedIdesDir(config.ResolveCacheDir(configPath)), "GoLand-"+build)
+		if err := os.MkdirAll(filepath.Join(home, "bin"), 0755); err != nil {
+			t.Fatalf("Failed to create IDE home: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(home, "product-info.json"), []byte(productInfoJSON), 0644); err != nil {
+			t.Fatalf("Failed to write product-info.json: %v", err)
+		}
+	}
+	return configPath
+}
+
+func TestResolveLaunch(t *testing.T) {
+	configPath := writeProject(t, `  launch:
+    project: backend/Backend.sln
+    args: ["-Dproject=${project_root}", "-Dprice=$$5", "-Duser=${env:USER}"]
+    env:
+      JAVA_HOME: ${java_home}
+      IDE_PLUGINS: ${ide_home}/plugins
+`, "243.1")
+	projectDir := filepath.Dir(configPath)
+
+	launch, err := ResolveLaunch(configPath, []string{"USER=dev", "PATH=/usr/bin"}, "linux", "amd64")
+	if err != nil {
+		t.Fatalf("Failed to resolve the launch: %v", err)
+	}
+
+	home := filepath.Join(layout.ResolveUnpackedIdesDir(config.ResolveCacheDir(configPath)), "GoLand-243.1")
+	if launch.Path != filepath.Join(home, "bin", "goland.sh") {
+		t.Errorf("Unexpected launcher: %s", launch.Path)
+	}
+	expectedArgs := []string{"-Dproject=" + projectDir, "-Dprice=$5", "-Duser=dev", filepath.Join(projectDir, "backend", "Backend.sln")}
+	if !reflect.DeepEqual(launch.Args, expectedArgs) {
+		t.Errorf("Expected arguments %v, got %v", expectedArgs, launch.Args)
+	}
+
+	javaHome := filepath.Join(projectDir, ".devrig", "tools", "jdk-temurin-21.0.5_11")
+	for _, expected := range []string{"JAVA_HOME=" + javaHome, "IDE_PLUGINS=" + home + "/plugins", "USER=dev"} {
+		if !slices.Contains(launch.Env, expected) {
+			t.Errorf("Expected %s in the environment: %v", expected, launch.Env)
+		}
+	}
+}
+
+func TestResolveLaunch_OpensProjectRootByDefault(t *testing.T) {
+	configPath := writeProject(t, "", "243.1")
+
+	launch, err := ResolveLaunch(configPath, nil, "linux", "amd64")
+	if err != nil {
+		t.Fatalf("Failed to resolve the launch: %v", err)
+	}
+	if !reflect.DeepEqual(launch.Args, []string{filepath.Dir(configPath)}) {
+		t.Errorf("Expected the project root to be opened, got %v", launch.Args)
+	}
+
+	if _, err := ResolveLaunch(configPath, nil, "darwin", "arm64"); err == nil {
+		t.Error("Expected an error without a launcher for the platform")
+	}
+}
+
+func TestResolveLaunch_PathWithSpacesAndUnicode(t *testing.T) {
+	projectDir := filepath.Join(t.TempDir(), "Jöhn Doe", "my project 名前")
+	configPath := writeProjectIn(t, projectDir, `  launch:
+    project: Back End/Back End.sln
+    args: ["-Dproject=${project_root}"]
+    env:
+      IDE_PLUGINS: ${ide_home}/plugins
+`, "243.1")
+
+	launch, err := ResolveLaunch(configPath, nil, "linux", "amd64")
+	if err != nil {
+		t.Fatalf("Failed to resolve the launch: %v", err)
+	}
+	// Every path is a single argument, the IDE is started without a shell
+	expectedArgs := []string{"-Dproject=" + projectDir, filepath.Join(projectDir, "Back End", "Back End.sln")}
+	if !reflect.DeepEqual(launch.Args, expectedArgs) {
+		t.Errorf("Expected arguments %v, got %v", expectedArgs, launch.Args)
+	}
+	if !strings.HasPrefix(launch.Path, projectDir) || !strings.HasPrefix(launch.Vars["IDE_PLUGINS"], projectDir) {
+		t.Errorf("Expected the IDE in the project, got %s, %v", launch.Path, launch.Vars)
+	}
+}
+
+func TestLaunchCommand_PathWithSpacesAndUnicode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test runs a shell launcher")
+	}
+	projectDir := filepath.Join(t.TempDir(), "Jöhn Doe", "my project 名前")
+	configPath := writeProjectIn(t, projectDir, "", "243.1")
+	launcher := filepath.Join(layout.ResolveUnpackedIdesDir(config.ResolveCacheDir(configPath)), "GoLand-243.1", "bin", "goland.sh")
+	if err := os.WriteFile(launcher, []byte("#!/bin/sh\nfor arg in \"$@\"; do echo \"[$arg]\"; done\n"), 0755); err != nil {
+		t.Fatalf("Failed to write the launcher: %v", err)
+	}
+
+	cmd := newLaunchCommand(func() string { return configPath })
+	var stdout bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stdout)
+	cmd.SetArgs([]string{"--wait", "--", "extra arg"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Failed to launch the IDE: %v\n%s", err, stdout.String())
+	}
+	expected := "[extra arg]\n[" + projectDir + "]\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected the launcher to get %q, got %q", expected, stdout.String())
+	}
+}
+
+func TestResolveLaunch_TemplateErrors(t *testing.T) {
+	for name, args := range map[string]string{
+		"unknown variable":  `["${workspace}"]`,
+		"missing toolchain": `["${go_root}"]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			configPath := writeProject(t, "  launch:\n    args: "+args+"\n", "243.1")
+			_, err := ResolveLaunch(configPath, nil, "linux", "amd64")
+			if err == nil || !strings.Contains(err.Error(), "failed to expand") {
+				t.Errorf("Expected an expansion error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFindUnpacked(t *testing.T) {
+	configPath := writeProject(t, "", "243.1", "243.2")
+	cacheDir := config.ResolveCacheDir(configPath)
+
+	home, err := FindUnpacked(cacheDir, &configservice.IdeSection{Name: "GoLand", Version: "2024.3", Build: "243.1"})
+	if err != nil || filepath.Base(home) != "GoLand-243.1" {
+		t.Errorf("Expected the requested build, got %s, %v", home, err)
+	}
+	if _, err := FindUnpacked(cacheDir, &configservice.IdeSection{Name: "Rider", Version: "2024.3"}); err == nil {
+		t.Error("Expected an error for an IDE that is not unpacked")
+	}
+}
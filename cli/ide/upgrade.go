@@ -0,0 +1,208 @@
+package ide
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/cache"
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/feed"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/plugins"
+	"jonnyzzz.com/devrig.dev/progress"
+)
+
+// UpgradePreview is what changing the version of an IDE in devrig.yaml does, it is shown before devrig.yaml is changed
+type UpgradePreview struct {
+	Name           string `json:"name"`
+	CurrentVersion string `json:"current_version"`
+	// CurrentBuild is the build of the unpacked current version, empty if it is not unpacked
+	CurrentBuild  string `json:"current_build,omitempty"`
+	CurrentHome   string `json:"current_home,omitempty"`
+	TargetVersion string `json:"target_version"`
+	TargetBuild   string `json:"target_build"`
+	// DownloadSize is the size of the package, zero when the package or the unpacked build is already in the cache
+	DownloadSize int64 `json:"download_size"`
+	// CurrentKept tells whether the unpacked current version stays in the cache with the retention policy
+	CurrentKept      bool `json:"current_kept"`
+	KeepUnpackedIdes int  `json:"keep_unpacked_ides"`
+	// IndexesRebuilt is set for another major version, e.g. 2024.3 to 2025.1. The IDE starts with new settings,
+	// caches and indexes folders, it imports the settings and indexes the project again
+	IndexesRebuilt bool           `json:"indexes_rebuilt"`
+	Plugins        []PluginImpact `json:"plugins,omitempty"`
+}
+
+// PluginImpact is the compatibility of a plugin of devrig.yaml with the new build
+type PluginImpact struct {
+	ID string `json:"id"`
+	// Version is the version pinned in devrig.yaml
+	Version string `json:"version,omitempty"`
+	// Compatible is the latest version of the Marketplace compatible with the new build
+	Compatible string `json:"compatible,omitempty"`
+	Warning    string `json:"warning,omitempty"`
+}
+
+// pluginChecker finds the latest plugin release compatible with a build, plugins.Marketplace in production
+type pluginChecker interface {
+	Compatible(ctx context.Context, id string, build string) (*plugins.Release, error)
+}
+
+// PreviewUpgrade resolves the new version of the IDE in the feed and checks what changes compared to the current one:
+// the download, the retention of the unpacked current version, the plugins and the indexes. Nothing is downloaded
+func PreviewUpgrade(ctx context.Context, configPath string, current *configservice.IdeSection, target *configservice.IdeSection) (*UpgradePreview, error) {
+	release, err := feed.ResolveRelease(ctx, target.Request(), target.PackageTypes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s %s: %w", target.Name, target.Version, err)
+	}
+	policy, err := configservice.NewConfigService(configPath).Retention().ReadRetention()
+	if err != nil {
+		return nil, err
+	}
+	return previewUpgrade(ctx, config.ResolveCacheDir(configPath), *policy, current, target, release, plugins.NewMarketplace())
+}
+
+func previewUpgrade(ctx context.Context, cacheDir string, policy configservice.RetentionSection, current *configservice.IdeSection,
+	target *configservice.IdeSection, release *feed.Release, checker pluginChecker) (*UpgradePreview, error) {
+	preview := &UpgradePreview{
+		Name:             current.Name,
+		CurrentVersion:   current.Version,
+		TargetVersion:    release.Version,
+		TargetBuild:      release.Build,
+		DownloadSize:     release.Size,
+		KeepUnpackedIdes: policy.KeepUnpackedIdes,
+		IndexesRebuilt:   majorVersion(current.Version) != majorVersion(release.Version),
+	}
+
+	pinned := *target
+	pinned.Build = release.Build
+	packageFile := filepath.Join(layout.ResolveDownloadsDir(cacheDir), layout.UnpackedIdeName(release.Name, release.Build)+"."+release.PackageType)
+	installed := filepath.Join(layout.ResolveUnpackedIdesDir(cacheDir), layout.UnpackedIdeName(release.Name, release.Build))
+	if home, err := FindUnpacked(cacheDir, &pinned); err == nil {
+		installed = home
+		preview.DownloadSize = 0
+	} else if _, err := os.Stat(packageFile); err == nil {
+		preview.DownloadSize = 0
+	}
+
+	if home, err := FindUnpacked(cacheDir, current); err == nil {
+		preview.CurrentHome = home
+		preview.CurrentBuild = unpackedBuild(home, current.Name)
+		kept, err := cache.KeptAfterInstall(cacheDir, policy, home, installed)
+		if err != nil {
+			return nil, err
+		}
+		preview.CurrentKept = kept
+	}
+
+	for _, plugin := range target.Plugins {
+		impact := PluginImpact{ID: plugin.ID, Version: plugin.Version}
+		switch compatible, err := checkPlugin(ctx, checker, plugin.ID, release); {
+		case err != nil:
+			impact.Warning = err.Error()
+		case plugin.Version != "" && plugin.Version != compatible.Version:
+			impact.Compatible = compatible.Version
+			impact.Warning = fmt.Sprintf("version %s is pinned, %s is the latest version compatible with %s", plugin.Version, compatible.Version, release.Build)
+		default:
+			impact.Compatible = compatible.Version
+		}
+		preview.Plugins = append(preview.Plugins, impact)
+	}
+	return preview, nil
+}
+
+// UpdateDeclared replaces the IDE with the same name in the ide or the ides section of devrig.yaml.
+// Returns whether it is the IDE of the ide section
+func UpdateDeclared(configPath string, section *configservice.IdeSection) (bool, error) {
+	service := configservice.NewConfigService(configPath).IDE()
+	primary, err := service.ReadIde()
+	if err != nil {
+		return false, err
+	}
+	if primary != nil && strings.EqualFold(primary.Name, section.Name) {
+		return true, service.UpdateIde(section)
+	}
+
+	ides, err := service.ReadIdes()
+	if err != nil {
+		return false, err
+	}
+	var updated configservice.IdesSection
+	for _, ide := range ides {
+		switch {
+		case primary != nil && ide.Name == primary.Name:
+			// ReadIdes lists the IDE of the ide section first, it is not part of the ides section
+		case strings.EqualFold(ide.Name, section.Name):
+			updated = append(updated, *section)
+		default:
+			updated = append(updated, ide)
+		}
+	}
+	return false, service.UpdateIdes(updated)
+}
+
+// printUpgradePreview writes the preview as text
+func printUpgradePreview(out io.Writer, preview *UpgradePreview) {
+	current := preview.CurrentVersion
+	if preview.CurrentBuild != "" {
+		current += " (" + preview.CurrentBuild + ")"
+	}
+	_, _ = fmt.Fprintf(out, "%s %s -> %s (%s)\n", preview.Name, current, preview.TargetVersion, preview.TargetBuild)
+
+	if preview.DownloadSize > 0 {
+		_, _ = fmt.Fprintf(out, "  Download: %s\n", progress.FormatBytes(preview.DownloadSize))
+	} else {
+		_, _ = fmt.Fprintln(out, "  Download: none, the new build is already in the cache")
+	}
+
+	switch {
+	case preview.CurrentHome == "":
+		_, _ = fmt.Fprintln(out, "  Cache:    the current version is not unpacked")
+	case preview.CurrentKept:
+		_, _ = fmt.Fprintf(out, "  Cache:    %s stays in the cache (keep_unpacked_ides: %d)\n", filepath.Base(preview.CurrentHome), preview.KeepUnpackedIdes)
+	default:
+		_, _ = fmt.Fprintf(out, "  Cache:    %s is removed from the cache (keep_unpacked_ides: %d)\n", filepath.Base(preview.CurrentHome), preview.KeepUnpackedIdes)
+	}
+
+	if preview.IndexesRebuilt {
+		_, _ = fmt.Fprintf(out, "  Indexes:  %s is a new major version, the IDE imports the settings and indexes the project again\n", majorVersion(preview.TargetVersion))
+	} else {
+		_, _ = fmt.Fprintf(out, "  Indexes:  the settings, caches and indexes of %s are reused\n", majorVersion(preview.CurrentVersion))
+	}
+
+	for _, plugin := range preview.Plugins {
+		if plugin.Warning != "" {
+			_, _ = fmt.Fprintf(out, "  Plugin:   %s: warning: %s\n", plugin.ID, plugin.Warning)
+		} else {
+			_, _ = fmt.Fprintf(out, "  Plugin:   %s %s is compatible\n", plugin.ID, plugin.Compatible)
+		}
+	}
+}
+
+// checkPlugin returns the latest release of the plugin compatible with the build of the release
+func checkPlugin(ctx context.Context, checker pluginChecker, id string, release *feed.Release) (*plugins.Release, error) {
+	if release.ProductCode == "" {
+		return nil, fmt.Errorf("the feed has no product code of %s, the compatibility is not checked", release.Name)
+	}
+	return checker.Compatible(ctx, id, release.ProductCode+"-"+release.Build)
+}
+
+// unpackedBuild returns the build of the unpacked IDE from its product-info.json or from the folder name <name>-<build>
+func unpackedBuild(home string, name string) string {
+	if info, _, err := readProductInfo(home, runtime.GOOS); err == nil && info.BuildNumber != "" {
+		return info.BuildNumber
+	}
+	return strings.TrimPrefix(strings.TrimSuffix(filepath.Base(home), ".app"), layout.UnpackedIdeName(name, ""))
+}
+
+// majorVersion returns the year and the release of the version, e.g. 2024.3 of 2024.3.1, the IDEs keep
+// their settings and indexes per major version
+func majorVersion(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	return strings.Join(parts[:min(len(parts), 2)], ".")
+}
@@ -0,0 +1,112 @@
+package ide
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/feed"
+	"jonnyzzz.com/devrig.dev/plugins"
+)
+
+// testPluginChecker returns the compatible versions of the map, other plugins have no compatible version
+type testPluginChecker map[string]string
+
+func (c testPluginChecker) Compatible(ctx context.Context, id string, build string) (*plugins.Release, error) {
+	if build != "GO-251.2" {
+		return nil, fmt.Errorf("unexpected build %s", build)
+	}
+	version, ok := c[id]
+	if !ok {
+		return nil, fmt.Errorf("plugin %s has no version compatible with %s", id, build)
+	}
+	return &plugins.Release{ID: id, Version: version}, nil
+}
+
+func TestPreviewUpgrade_NewMajorVersion(t *testing.T) {
+	configPath := writeProject(t, "", "243.1")
+	cacheDir := config.ResolveCacheDir(configPath)
+	current := &configservice.IdeSection{Name: "GoLand", Version: "2024.3", Plugins: []configservice.IdePlugin{
+		{ID: "org.latest"}, {ID: "org.pinned", Version: "1.0"}, {ID: "org.abandoned"},
+	}}
+	target := *current
+	target.Version = "2025.1"
+	release := &feed.Release{Name: "GoLand", Version: "2025.1", Build: "251.2", PackageType: "targz", Size: 1 << 30, ProductCode: "GO"}
+	checker := testPluginChecker{"org.latest": "2.0", "org.pinned": "1.1"}
+
+	preview, err := previewUpgrade(context.Background(), cacheDir, configservice.RetentionSection{KeepUnpackedIdes: 1}, current, &target, release, checker)
+	if err != nil {
+		t.Fatalf("Failed to preview the upgrade: %v", err)
+	}
+	if preview.CurrentBuild != "243.1" || preview.TargetBuild != "251.2" || preview.DownloadSize != 1<<30 {
+		t.Errorf("Expected the builds and the download size, got %+v", preview)
+	}
+	if preview.CurrentKept || !preview.IndexesRebuilt {
+		t.Errorf("Expected the current version to be removed and the indexes rebuilt, got %+v", preview)
+	}
+
+	if len(preview.Plugins) != 3 {
+		t.Fatalf("Expected all plugins, got %+v", preview.Plugins)
+	}
+	if plugin := preview.Plugins[0]; plugin.Compatible != "2.0" || plugin.Warning != "" {
+		t.Errorf("Expected a compatible plugin, got %+v", plugin)
+	}
+	if plugin := preview.Plugins[1]; plugin.Compatible != "1.1" || plugin.Warning == "" {
+		t.Errorf("Expected a warning for the pinned plugin, got %+v", plugin)
+	}
+	if plugin := preview.Plugins[2]; plugin.Compatible != "" || plugin.Warning == "" {
+		t.Errorf("Expected a warning for the incompatible plugin, got %+v", plugin)
+	}
+}
+
+func TestPreviewUpgrade_SameMajorVersion(t *testing.T) {
+	configPath := writeProject(t, "", "243.1", "243.2")
+	cacheDir := config.ResolveCacheDir(configPath)
+	current := &configservice.IdeSection{Name: "GoLand", Version: "2024.3", Build: "243.1"}
+	target := &configservice.IdeSection{Name: "GoLand", Version: "2024.3", Build: "243.2"}
+	release := &feed.Release{Name: "GoLand", Version: "2024.3", Build: "243.2", PackageType: "targz", Size: 1 << 30}
+
+	preview, err := previewUpgrade(context.Background(), cacheDir, configservice.RetentionSection{KeepUnpackedIdes: 2}, current, target, release, testPluginChecker{})
+	if err != nil {
+		t.Fatalf("Failed to preview the upgrade: %v", err)
+	}
+	if preview.DownloadSize != 0 || !preview.CurrentKept || preview.IndexesRebuilt {
+		t.Errorf("Expected the unpacked build to be reused with the indexes, got %+v", preview)
+	}
+}
+
+func TestUpdateDeclared(t *testing.T) {
+	configPath := writeProject(t, "")
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read devrig.yaml: %v", err)
+	}
+	content = append(content, "ides:\n  - name: WebStorm\n    version: 2024.3\n"...)
+	if err := os.WriteFile(configPath, content, 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+
+	isPrimary, err := UpdateDeclared(configPath, &configservice.IdeSection{Name: "WebStorm", Version: "2025.1"})
+	if err != nil || isPrimary {
+		t.Fatalf("Failed to update the ides section: %v, %v", isPrimary, err)
+	}
+	isPrimary, err = UpdateDeclared(configPath, &configservice.IdeSection{Name: "GoLand", Version: "2025.1"})
+	if err != nil || !isPrimary {
+		t.Fatalf("Failed to update the ide section: %v, %v", isPrimary, err)
+	}
+
+	ides, err := configservice.NewConfigService(configPath).IDE().ReadIdes()
+	if err != nil {
+		t.Fatalf("Failed to read the IDEs: %v", err)
+	}
+	if len(ides) != 2 || ides[0].Name != "GoLand" || ides[0].Version != "2025.1" || ides[1].Name != "WebStorm" || ides[1].Version != "2025.1" {
+		t.Errorf("Expected both IDEs on 2025.1, got %+v", ides)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(configPath), "devrig.yaml")); err != nil {
+		t.Errorf("Expected devrig.yaml to be kept: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Package identity keeps the anonymous machine ID of devrig. The ID is random, it identifies the machine
+// for rollout bucketing, telemetry and the LAN cache without revealing anything about the machine or the user.
+package identity
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// FileName is the name of the identity file in the user state folder
+const FileName = "machine-id.json"
+
+// Identity is the persisted machine ID
+type Identity struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// HostHash is a hash of the host name. A state folder copied to another machine,
+	// e.g. with a synced home folder or a container image, gets a new ID.
+	HostHash string `json:"host_hash"`
+}
+
+// Store keeps the identity in a folder of the user state
+type Store struct {
+	Dir string
+	// hostname returns the host name, os.Hostname by default
+	hostname func() (string, error)
+}
+
+// DefaultStore returns the store in the user state folder
+func DefaultStore() (*Store, error) {
+	dir, err := layout.ResolveUserStateDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Store{Dir: dir}, nil
+}
+
+// Path returns the location of the identity file
+func (s *Store) Path() string {
+	return filepath.Join(s.Dir, FileName)
+}
+
+// MachineID returns the ID of the machine, it is generated on the first call
+func (s *Store) MachineID() (string, error) {
+	identity, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	if identity != nil && identity.HostHash == s.hostHash() {
+		return identity.ID, nil
+	}
+	return s.Reset()
+}
+
+// Reset replaces the ID with a new random one, so the machine can no longer be correlated with its past reports
+func (s *Store) Reset() (string, error) {
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+
+	identity := Identity{ID: id, CreatedAt: time.Now().UTC(), HostHash: s.hostHash()}
+	data, err := json.MarshalIndent(identity, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal machine identity: %w", err)
+	}
+
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	// Concurrent processes must never see a partial file
+	if err := tempfile.WriteFile(s.Path(), data, 0600); err != nil {
+		return "", fmt.Errorf("failed to save machine identity: %w", err)
+	}
+	return id, nil
+}
+
+// load reads the identity, it returns nil for a missing or broken file
+func (s *Store) load() (*Identity, error) {
+	data, err := os.ReadFile(s.Path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read machine identity: %w", err)
+	}
+
+	var identity Identity
+	if err := json.Unmarshal(data, &identity); err != nil || identity.ID == "" {
+		return nil, nil
+	}
+	return &identity, nil
+}
+
+func (s *Store) hostHash() string {
+	hostname := os.Hostname
+	if s.hostname != nil {
+		hostname = s.hostname
+	}
+	name, err := hostname()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256([]byte("devrig-host:" + name))
+	return hex.EncodeToString(sum[:8])
+}
+
+// newID returns a random UUID v4
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate machine ID: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// Bucket maps the machine to one of 100 buckets of the rollout, a machine lands in the same bucket
+// for the same rollout, and in independent buckets for different rollouts
+func Bucket(machineID string, rollout string) int {
+	sum := sha256.Sum256([]byte(rollout + "/" + machineID))
+	return int(binary.BigEndian.Uint64(sum[:8]) % 100)
+}
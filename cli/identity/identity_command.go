@@ -0,0 +1,62 @@
+package identity
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// NewIdentityCommand creates the identity command group
+func NewIdentityCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "identity",
+		Short: "Show or reset the anonymous machine ID",
+		Long: `Show or reset the anonymous machine ID.
+
+The ID is random and stored in the devrig folder of the user config directory.
+It is used for rollout bucketing, telemetry and the LAN cache. Reset it to stop
+the machine from being correlated with its previous reports.
+`,
+	}
+	cmd.AddCommand(newShowCommand())
+	cmd.AddCommand(newResetCommand())
+	return cmd
+}
+
+func newShowCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Print the machine ID",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := DefaultStore()
+			if err != nil {
+				return err
+			}
+			id, err := store.MachineID()
+			if err != nil {
+				return err
+			}
+			cmd.Println(id)
+			return nil
+		},
+	}
+}
+
+func newResetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reset",
+		Short: "Replace the machine ID with a new random one",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := DefaultStore()
+			if err != nil {
+				return err
+			}
+			id, err := store.Reset()
+			if err != nil {
+				return err
+			}
+			cmd.Printf("The machine ID was reset, the new ID is %s\n", id)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,107 @@
+package identity
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func newTestStore(t *testing.T, host string) *Store {
+	return &Store{Dir: t.TempDir(), hostname: func() (string, error) { return host, nil }}
+}
+
+func TestMachineID_Stable(t *testing.T) {
+	store := newTestStore(t, "laptop")
+
+	first, err := store.MachineID()
+	if err != nil {
+		t.Fatalf("Failed to get machine ID: %v", err)
+	}
+	if !uuidPattern.MatchString(first) {
+		t.Errorf("Expected a UUID, got: %s", first)
+	}
+
+	second, err := (&Store{Dir: store.Dir, hostname: store.hostname}).MachineID()
+	if err != nil {
+		t.Fatalf("Failed to get machine ID: %v", err)
+	}
+	if first != second {
+		t.Errorf("Expected the ID to be stable, got %s and %s", first, second)
+	}
+}
+
+func TestMachineID_CopiedToAnotherHost(t *testing.T) {
+	store := newTestStore(t, "laptop")
+	first, err := store.MachineID()
+	if err != nil {
+		t.Fatalf("Failed to get machine ID: %v", err)
+	}
+
+	copied := &Store{Dir: store.Dir, hostname: func() (string, error) { return "ci-agent", nil }}
+	second, err := copied.MachineID()
+	if err != nil {
+		t.Fatalf("Failed to get machine ID: %v", err)
+	}
+	if first == second {
+		t.Errorf("Expected a new ID on another host")
+	}
+}
+
+func TestReset(t *testing.T) {
+	store := newTestStore(t, "laptop")
+	first, err := store.MachineID()
+	if err != nil {
+		t.Fatalf("Failed to get machine ID: %v", err)
+	}
+
+	reset, err := store.Reset()
+	if err != nil {
+		t.Fatalf("Failed to reset machine ID: %v", err)
+	}
+	current, err := store.MachineID()
+	if err != nil {
+		t.Fatalf("Failed to get machine ID: %v", err)
+	}
+	if reset == first || current != reset {
+		t.Errorf("Expected the reset ID to replace %s, got reset %s and current %s", first, reset, current)
+	}
+}
+
+func TestMachineID_BrokenFile(t *testing.T) {
+	store := newTestStore(t, "laptop")
+	if err := os.WriteFile(store.Path(), []byte("{broken"), 0600); err != nil {
+		t.Fatalf("Failed to write identity: %v", err)
+	}
+
+	id, err := store.MachineID()
+	if err != nil {
+		t.Fatalf("Failed to get machine ID: %v", err)
+	}
+	if !uuidPattern.MatchString(id) {
+		t.Errorf("Expected a new UUID, got: %s", id)
+	}
+}
+
+func TestBucket(t *testing.T) {
+	id := "3f2b8c1e-0000-4000-8000-000000000000"
+	if Bucket(id, "update-2.0") != Bucket(id, "update-2.0") {
+		t.Errorf("Expected the bucket to be stable")
+	}
+
+	counts := make([]int, 100)
+	for i := 0; i < 10000; i++ {
+		bucket := Bucket(id, fmt.Sprintf("rollout-%d", i))
+		if bucket < 0 || bucket >= 100 {
+			t.Fatalf("Bucket out of range: %d", bucket)
+		}
+		counts[bucket]++
+	}
+	for bucket, count := range counts {
+		if count == 0 {
+			t.Errorf("Bucket %d was never selected", bucket)
+		}
+	}
+}
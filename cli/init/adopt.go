@@ -0,0 +1,97 @@
+package init
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/tempfile"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// adoptCandidates returns the devrig binaries that may already be on the machine: the running binary,
+// devrig on PATH and the binaries the other projects keep in the user store
+func adoptCandidates(system updates.SystemInfo, sha512 string) []string {
+	var candidates []string
+	if execPath, err := os.Executable(); err == nil {
+		candidates = append(candidates, execPath)
+	}
+	if path, err := exec.LookPath("devrig"); err == nil {
+		candidates = append(candidates, path)
+	}
+	if projectsDir, err := layout.ResolveUserProjectsDir(); err == nil {
+		entries, _ := os.ReadDir(projectsDir)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				candidates = append(candidates, layout.ResolveDevrigBinary(filepath.Join(projectsDir, entry.Name()), system.OS(), system.Arch(), sha512))
+			}
+		}
+	}
+	return candidates
+}
+
+// findAdoptableBinary returns a devrig binary of the machine with the hash pinned for the current platform,
+// or an empty string when there is none
+func findAdoptableBinary(logger *slog.Logger, configPath string, section *configservice.DevrigSection, system updates.SystemInfo) string {
+	binary, ok := section.Binaries[system.OS()+"-"+system.Arch()]
+	if !ok || binary.SHA512 == "" {
+		return ""
+	}
+	target := layout.ResolveDevrigBinary(layout.ResolveDevrigHome(configPath), system.OS(), system.Arch(), binary.SHA512)
+	for _, candidate := range adoptCandidates(system, binary.SHA512) {
+		if resolved, err := filepath.EvalSymlinks(candidate); err == nil {
+			candidate = resolved
+		}
+		if candidate == target {
+			continue
+		}
+		if hash, err := calculateFileHash(candidate); err == nil && strings.EqualFold(hash, binary.SHA512) {
+			return candidate
+		}
+		logger.Debug("devrig binary does not match the pinned hash", "path", candidate)
+	}
+	return ""
+}
+
+// adoptBinary puts the binary from findAdoptableBinary into the .devrig folder of the project, so the bootstrap
+// scripts do not download it again. The binary is hardlinked when it belongs to the user and copied otherwise.
+// Returns the path of the adopted binary
+func adoptBinary(logger *slog.Logger, configPath string, section *configservice.DevrigSection, system updates.SystemInfo, source string) (string, error) {
+	binary := section.Binaries[system.OS()+"-"+system.Arch()]
+	devrigHome := layout.ResolveDevrigHome(configPath)
+	if err := os.MkdirAll(devrigHome, 0755); err != nil {
+		return "", fmt.Errorf("failed to create .devrig directory: %w", err)
+	}
+
+	tempFile := filepath.Dir(layout.ResolveDevrigBinary(devrigHome, system.OS(), system.Arch(), binary.SHA512)) + "-adopting"
+	tempfile.Track(tempFile)
+	//goland:noinspection GoUnhandledErrorResult
+	defer tempfile.Remove(tempFile)
+	_ = os.Remove(tempFile)
+
+	// A hardlink of a file of another user cannot be made executable by the installation, so it is copied
+	linked := false
+	if owned, _ := layout.IsOwnedByCurrentUser(source); owned {
+		linked = os.Link(source, tempFile) == nil
+	}
+	if !linked {
+		if err := copyFile(source, tempFile); err != nil {
+			return "", fmt.Errorf("failed to copy %s: %w", source, err)
+		}
+	}
+	logger.Debug("adopting devrig binary", "source", source, "hardlink", linked)
+
+	return layout.InstallDevrigBinary(devrigHome, tempFile, layout.DevrigBinaryMetadata{
+		OS:      system.OS(),
+		Arch:    system.Arch(),
+		SHA512:  binary.SHA512,
+		Version: section.Version,
+		URL:     binary.URL,
+		Source:  layout.DevrigBinarySourceAdopt,
+	})
+}
@@ -0,0 +1,141 @@
+package init
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// releaseUpdateService returns the release with the binary of the current platform
+type releaseUpdateService struct {
+	info *updates.UpdateInfo
+}
+
+func (s *releaseUpdateService) LastUpdateInfo() (*updates.UpdateInfo, error) {
+	return s.info, nil
+}
+
+func (s *releaseUpdateService) IsUpdateAvailable() (bool, error) {
+	return false, nil
+}
+
+func (s *releaseUpdateService) CompareWithLatest() (*updates.Comparison, error) {
+	return &updates.Comparison{Running: s.info.Version, Latest: s.info.Version}, nil
+}
+
+// writeStoredBinary puts a devrig binary into the user store as another project would and returns its SHA-512
+func writeStoredBinary(t *testing.T, content string) string {
+	t.Helper()
+	t.Setenv(layout.EnvSharedStore, t.TempDir())
+	t.Setenv("PATH", t.TempDir())
+
+	source := filepath.Join(t.TempDir(), "devrig")
+	if err := os.WriteFile(source, []byte(content), 0755); err != nil {
+		t.Fatalf("Failed to write binary: %v", err)
+	}
+	hash, err := calculateFileHash(source)
+	if err != nil {
+		t.Fatalf("Failed to hash binary: %v", err)
+	}
+	userHome, err := layout.ResolveUserDevrigHome(filepath.Join(t.TempDir(), "other", "devrig.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to resolve user store: %v", err)
+	}
+	system := updates.CurrentSystem{}
+	if _, err := layout.InstallDevrigBinary(userHome, source, layout.DevrigBinaryMetadata{
+		OS: system.OS(), Arch: system.Arch(), SHA512: hash, Source: layout.DevrigBinarySourceApply,
+	}); err != nil {
+		t.Fatalf("Failed to install binary: %v", err)
+	}
+	return hash
+}
+
+func newReleaseInitCommand(hash string) (*releaseUpdateService, *bytes.Buffer) {
+	system := updates.CurrentSystem{}
+	service := &releaseUpdateService{info: &updates.UpdateInfo{
+		Version: "0.79.6",
+		Binaries: []updates.BinaryInfo{
+			{OS: system.OS(), Arch: system.Arch(), SHA512: hash, URL: "https://devrig.dev/download/devrig"},
+		},
+	}}
+	return service, &bytes.Buffer{}
+}
+
+func TestInitCommand_AdoptsExistingBinary(t *testing.T) {
+	hash := writeStoredBinary(t, "devrig binary of another project")
+	service, output := newReleaseInitCommand(hash)
+	projectDir := t.TempDir()
+
+	cmd := NewInitCommand(service)
+	cmd.SetOut(output)
+	cmd.SetErr(output)
+	cmd.SetArgs([]string{projectDir})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Failed to execute init: %v", err)
+	}
+
+	system := updates.CurrentSystem{}
+	devrigHome := layout.ResolveDevrigHome(filepath.Join(projectDir, "devrig.yaml"))
+	target := layout.ResolveDevrigBinary(devrigHome, system.OS(), system.Arch(), hash)
+	data, err := os.ReadFile(target)
+	if err != nil || string(data) != "devrig binary of another project" {
+		t.Fatalf("Expected the binary to be adopted into %s, got %q, %v\n%s", target, data, err, output)
+	}
+	metadata, err := layout.ReadDevrigBinaryMetadata(filepath.Dir(target))
+	if err != nil || metadata.Source != layout.DevrigBinarySourceAdopt || metadata.Version != "0.79.6" {
+		t.Errorf("Unexpected metadata: %+v, %v", metadata, err)
+	}
+	if !strings.Contains(output.String(), "Adopted the existing devrig binary") {
+		t.Errorf("Expected the adoption to be reported, got:\n%s", output)
+	}
+}
+
+func TestInitCommand_NoAdopt(t *testing.T) {
+	hash := writeStoredBinary(t, "devrig binary of another project")
+	service, output := newReleaseInitCommand(hash)
+	projectDir := t.TempDir()
+
+	cmd := NewInitCommand(service)
+	cmd.SetOut(output)
+	cmd.SetErr(output)
+	cmd.SetArgs([]string{projectDir, "--no-adopt"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Failed to execute init: %v", err)
+	}
+
+	system := updates.CurrentSystem{}
+	devrigHome := layout.ResolveDevrigHome(filepath.Join(projectDir, "devrig.yaml"))
+	if _, err := os.Stat(layout.ResolveDevrigBinary(devrigHome, system.OS(), system.Arch(), hash)); !os.IsNotExist(err) {
+		t.Errorf("Expected no binary with --no-adopt, got: %v", err)
+	}
+}
+
+func TestFindAdoptableBinary_HashMismatch(t *testing.T) {
+	writeStoredBinary(t, "devrig binary of another project")
+
+	// A binary in the folder of the pinned hash with other content is not adopted
+	fakeHash := strings.Repeat("0", 128)
+	tampered := filepath.Join(t.TempDir(), "devrig")
+	if err := os.WriteFile(tampered, []byte("tampered"), 0755); err != nil {
+		t.Fatalf("Failed to write binary: %v", err)
+	}
+	userHome, err := layout.ResolveUserDevrigHome(filepath.Join(t.TempDir(), "tampered", "devrig.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to resolve user store: %v", err)
+	}
+	system := updates.CurrentSystem{}
+	if _, err := layout.InstallDevrigBinary(userHome, tampered, layout.DevrigBinaryMetadata{OS: system.OS(), Arch: system.Arch(), SHA512: fakeHash}); err != nil {
+		t.Fatalf("Failed to install binary: %v", err)
+	}
+
+	service, _ := newReleaseInitCommand(fakeHash)
+	if source := findAdoptableBinary(slog.Default(), filepath.Join(t.TempDir(), "devrig.yaml"), service.info.ToDevrigSection(), system); source != "" {
+		t.Errorf("Expected the tampered binary to be ignored, got %s", source)
+	}
+}
@@ -0,0 +1,111 @@
+package init
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/golden"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// goldenUpdateService returns a fixed release, so the generated devrig.yaml does not change between the runs
+type goldenUpdateService struct{}
+
+func (goldenUpdateService) LastUpdateInfo() (*updates.UpdateInfo, error) {
+	info := &updates.UpdateInfo{Version: "0.80.0", ReleaseDate: "2026-10-01"}
+	for i, platform := range [][2]string{{"darwin", "arm64"}, {"darwin", "x86_64"}, {"linux", "x86_64"}, {"linux", "arm64"}, {"windows", "x86_64"}} {
+		filename := "devrig-" + platform[0] + "-" + platform[1]
+		if platform[0] == "windows" {
+			filename += ".exe"
+		}
+		info.Binaries = append(info.Binaries, updates.BinaryInfo{
+			Filename: filename,
+			OS:       platform[0],
+			Arch:     platform[1],
+			SHA512:   strings.Repeat(fmt.Sprintf("%x", i), 128),
+			URL:      "https://devrig.dev/download/v0.80.0/" + filename,
+		})
+	}
+	return info, nil
+}
+
+func (goldenUpdateService) IsUpdateAvailable() (bool, error) {
+	return false, nil
+}
+
+func (goldenUpdateService) CompareWithLatest() (*updates.Comparison, error) {
+	return &updates.Comparison{Running: "0.80.0", Latest: "0.80.0"}, nil
+}
+
+// runGoldenInit runs devrig init --no-adopt with the fixed release in the folder
+func runGoldenInit(t *testing.T, dir string) {
+	t.Helper()
+	cmd := NewInitCommand(goldenUpdateService{})
+	var stdout bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stdout)
+	cmd.SetArgs([]string{"--no-adopt", dir})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Command failed: %v\nOutput: %s", err, stdout.String())
+	}
+}
+
+func TestGolden_InitDevrigYaml(t *testing.T) {
+	dir := t.TempDir()
+	runGoldenInit(t, dir)
+
+	data, err := os.ReadFile(filepath.Join(dir, "devrig.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read devrig.yaml: %v", err)
+	}
+	golden.Assert(t, "init/devrig.yaml", data)
+}
+
+func TestGolden_InitToolVersions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, ".tool-versions"), []byte("nodejs 20.11.0\ngolang 1.22.1 # go toolchain\njava temurin-21.0.2+13.0.LTS\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .tool-versions: %v", err)
+	}
+	runGoldenInit(t, dir)
+
+	data, err := os.ReadFile(filepath.Join(dir, "devrig.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read devrig.yaml: %v", err)
+	}
+	golden.Assert(t, "init/tool-versions.yaml", data)
+}
+
+func TestGolden_InitFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("File modes are not tracked on Windows")
+	}
+	dir := t.TempDir()
+	runGoldenInit(t, dir)
+
+	var files strings.Builder
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(&files, "%s %s\n", info.Mode(), filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to list the created files: %v", err)
+	}
+	golden.AssertString(t, "init/files", files.String())
+}
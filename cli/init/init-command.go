@@ -2,13 +2,21 @@ package init
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
 
+	"jonnyzzz.com/devrig.dev/apply"
 	"jonnyzzz.com/devrig.dev/bootstrap"
+	"jonnyzzz.com/devrig.dev/configfile"
 	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/summary"
+	"jonnyzzz.com/devrig.dev/tempfile"
+	"jonnyzzz.com/devrig.dev/tools"
+	"jonnyzzz.com/devrig.dev/toolversions"
 	"jonnyzzz.com/devrig.dev/updates"
 
 	"github.com/spf13/cobra"
@@ -18,11 +26,16 @@ type initCommandConfig struct {
 	updateService updates.UpdateService
 	scriptsOnly   bool
 	initFromLocal bool
+	noAdopt       bool
+	prefetch      bool
+	// system overrides the current OS and architecture, used in tests
+	system updates.SystemInfo
 }
 
 func NewInitCommand(updateService updates.UpdateService) *cobra.Command {
 	config := &initCommandConfig{
 		updateService: updateService,
+		system:        updates.CurrentSystem{},
 	}
 
 	cmd := &cobra.Command{
@@ -33,6 +46,8 @@ func NewInitCommand(updateService updates.UpdateService) *cobra.Command {
 	}
 	cmd.Flags().BoolVar(&config.scriptsOnly, "scripts-only", false, "Only generate bootstrap scripts")
 	cmd.Flags().BoolVar(&config.initFromLocal, "init-from-local", false, "Initialize with the current binary and generate devrig.yaml")
+	cmd.Flags().BoolVar(&config.noAdopt, "no-adopt", false, "Do not reuse a devrig binary with the pinned hash from PATH or the user store")
+	cmd.Flags().BoolVar(&config.prefetch, "prefetch", false, "Download and verify the devrig binary of the current platform, so the first run needs no network")
 
 	return cmd
 }
@@ -49,42 +64,129 @@ func (c *initCommandConfig) doTheCommand(cmd *cobra.Command, args []string) erro
 	if err != nil {
 		return fmt.Errorf("failed to resolve directory path: %w", err)
 	}
-	log.Printf("Resolved target directory to: %s\n", absPath)
+	logger := logging.FromContext(cmd.Context())
+	logger.Debug("resolved target directory", "path", absPath)
 
 	// Ensure directory exists
 	if err := os.MkdirAll(absPath, 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
+	if layout.IsReadOnlyDir(absPath) {
+		return fmt.Errorf("cannot initialize %s: the folder is read-only, run devrig init in a writable checkout and commit the generated files", absPath)
+	}
 	cmd.Printf("Initializing devrig.dev environment in: %s\n", absPath)
 
+	var steps summary.Summary
+	err = c.runSteps(cmd, logger, absPath, targetDir, &steps)
+	steps.Print(cmd.Context(), cmd.OutOrStdout())
+	return err
+}
+
+// runSteps executes the init steps and records them in the summary
+func (c *initCommandConfig) runSteps(cmd *cobra.Command, logger *slog.Logger, absPath string, targetDir string, steps *summary.Summary) error {
 	// Copy bootstrap scripts
-	if err := bootstrap.CopyBootstrapScripts(absPath); err != nil {
-		return fmt.Errorf("failed to copy bootstrap scripts: %w", err)
+	err := steps.Run("Create bootstrap scripts", true, func() error {
+		if err := bootstrap.CopyBootstrapScripts(absPath); err != nil {
+			return fmt.Errorf("failed to copy bootstrap scripts: %w", err)
+		}
+		cmd.Println("Bootstrap scripts created successfully!")
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	cmd.Println("Bootstrap scripts created successfully!")
 
 	if c.scriptsOnly {
 		cmd.Println("Scripts-only mode: Skipping additional initialization")
+		steps.Skip("Generate devrig.yaml", "--scripts-only")
 		return nil
 	}
 
-	var devrigBinaries *configservice.DevrigSection = nil
-	if c.initFromLocal {
-		cmd.Println("Initializing from local binary...")
-		if devrigBinaries, err = c.initializeFromLocalBinary(targetDir); err != nil {
-			return fmt.Errorf("failed to initialize from local binary: %w", err)
+	// An existing devrig.toml or devrig.json is updated in its format
+	configPath, _ := configfile.Find(absPath)
+	configs := configservice.NewConfigService(configPath)
+	var devrigBinaries *configservice.DevrigSection
+	err = steps.Run("Generate devrig.yaml", true, func() error {
+		var err error
+		if c.initFromLocal {
+			cmd.Println("Initializing from local binary...")
+			if devrigBinaries, err = c.initializeFromLocalBinary(logger, absPath); err != nil {
+				return fmt.Errorf("failed to initialize from local binary: %w", err)
+			}
+			cmd.Println("Local initialization completed successfully!")
+		} else {
+			if devrigBinaries, err = c.initializeFromUpdates(cmd); err != nil {
+				return fmt.Errorf("failed to initialize from local binary: %w", err)
+			}
 		}
-		cmd.Println("Local initialization completed successfully!")
-	} else {
-		if devrigBinaries, err = c.initializeFromUpdates(cmd); err != nil {
-			return fmt.Errorf("failed to initialize from local binary: %w", err)
+		return configs.Binaries().UpdateBinaries(devrigBinaries)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The local binary is already in .devrig, otherwise a binary of another project saves the download
+	if !c.initFromLocal {
+		c.adoptStep(cmd, logger, configPath, devrigBinaries, steps)
+		if c.prefetch {
+			if err := c.prefetchStep(cmd, configPath, steps); err != nil {
+				return err
+			}
 		}
 	}
-	return configservice.NewConfigService(filepath.Join(absPath, "devrig.yaml")).
-		Binaries().UpdateBinaries(
 
-		devrigBinaries,
-	)
+	// Seed the tools section from asdf/mise pins, if the project has them
+	toolVersionsPath := filepath.Join(absPath, toolversions.FileName)
+	if _, err := os.Stat(toolVersionsPath); err != nil {
+		steps.Skip("Import "+toolversions.FileName, "no "+toolversions.FileName+" file")
+		return nil
+	}
+
+	return steps.Run("Import "+toolversions.FileName, true, func() error {
+		imported, err := tools.ImportToolVersions(configs, toolVersionsPath)
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %w", toolversions.FileName, err)
+		}
+		cmd.Printf("Imported %d tools from %s\n", imported, toolversions.FileName)
+		return nil
+	})
+}
+
+// adoptStep puts a devrig binary with the pinned hash from the machine into .devrig, a failure only costs a download
+func (c *initCommandConfig) adoptStep(cmd *cobra.Command, logger *slog.Logger, configPath string, section *configservice.DevrigSection, steps *summary.Summary) {
+	const name = "Adopt existing devrig binary"
+	if c.noAdopt {
+		steps.Skip(name, "--no-adopt")
+		return
+	}
+
+	source := findAdoptableBinary(logger, configPath, section, c.system)
+	if source == "" {
+		steps.Skip(name, "no devrig binary with the pinned hash on this machine")
+		return
+	}
+
+	var adopted string
+	err := steps.Run(name, false, func() error {
+		var err error
+		adopted, err = adoptBinary(logger, configPath, section, c.system, source)
+		return err
+	})
+	if err != nil {
+		logger.Warn("Failed to adopt the existing devrig binary", "source", source, "error", err)
+		return
+	}
+	cmd.Printf("Adopted the existing devrig binary %s\n", source)
+	logger.Debug("adopted devrig binary", "path", adopted)
+}
+
+// prefetchStep downloads the devrig binary of the current platform into .devrig like `devrig apply` does, unless
+// it was adopted. The SHA-512 comes from the release metadata, which is fetched only with a valid signature
+func (c *initCommandConfig) prefetchStep(cmd *cobra.Command, configPath string, steps *summary.Summary) error {
+	step := &apply.DevrigBinaryStep{System: c.system, Source: layout.DevrigBinarySourceInit}
+	return steps.Run(step.Name(), true, func() error {
+		return step.Run(cmd.Context(), apply.NewEnvironment(configPath))
+	})
 }
 
 func (c *initCommandConfig) initializeFromUpdates(cmd *cobra.Command) (*configservice.DevrigSection, error) {
@@ -95,49 +197,37 @@ func (c *initCommandConfig) initializeFromUpdates(cmd *cobra.Command) (*configse
 	}
 
 	// Convert binaries from update info to configservice format
-	binaries := make(map[string]configservice.BinaryInfo)
-	for _, b := range updateInfo.Binaries {
-		binaries[fmt.Sprintf("%s-%s", b.OS, b.Arch)] = configservice.BinaryInfo{
-			URL:    b.URL,
-			SHA512: b.SHA512,
-		}
-	}
-
-	// Generate devrig section
-	log.Printf("Generating devrig section: version=%s, release_date=%s, binaries=%d\n", updateInfo.Version, updateInfo.ReleaseDate, len(binaries))
-	update := &configservice.DevrigSection{
-		Version:     updateInfo.Version,
-		ReleaseDate: updateInfo.ReleaseDate,
-		Binaries:    binaries,
-	}
+	update := updateInfo.ToDevrigSection()
+	logging.FromContext(cmd.Context()).Debug("generating devrig section",
+		"version", update.Version, "release_date", update.ReleaseDate, "binaries", len(update.Binaries))
 
 	return update, nil
 }
 
 // initializeFromLocalBinary creates devrig.yaml and copies the current binary to .devrig folder
-func (c *initCommandConfig) initializeFromLocalBinary(targetDir string) (*configservice.DevrigSection, error) {
-	log.Println("Initializing from local binary...")
+func (c *initCommandConfig) initializeFromLocalBinary(logger *slog.Logger, targetDir string) (*configservice.DevrigSection, error) {
+	logger.Debug("initializing from local binary")
 
 	// Get the current executable path
 	execPath, err := os.Executable()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get executable path: %w", err)
 	}
-	log.Printf("Executable path: %s\n", execPath)
+	logger.Debug("executable path", "path", execPath)
 
 	// Resolve symlinks if any
 	execPath, err = filepath.EvalSymlinks(execPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve symlinks: %w", err)
 	}
-	log.Printf("Resolved executable path: %s\n", execPath)
+	logger.Debug("resolved executable path", "path", execPath)
 
 	// Calculate hash of the current binary
 	hash, err := calculateFileHash(execPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate binary hash: %w", err)
 	}
-	log.Printf("Calculated binary hash: %s\n", hash)
+	logger.Debug("calculated binary hash", "sha512", hash)
 
 	// Determine OS and architecture
 	osName := runtime.GOOS
@@ -146,38 +236,35 @@ func (c *initCommandConfig) initializeFromLocalBinary(targetDir string) (*config
 		archName = "x86_64"
 	}
 	platform := fmt.Sprintf("%s-%s", osName, archName)
-	log.Printf("Determined platform: %s\n", platform)
+	logger.Debug("determined platform", "platform", platform)
 
-	// Create .devrig directory
-	devrigDir := filepath.Join(targetDir, ".devrig")
-	if err := os.MkdirAll(devrigDir, 0755); err != nil {
+	// The binary is copied next to the .devrig folder first, so it is moved in place with a rename
+	devrigHome := layout.ResolveDevrigHome(filepath.Join(targetDir, "devrig.yaml"))
+	if err := os.MkdirAll(devrigHome, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create .devrig directory: %w", err)
 	}
-	log.Printf("Created .devrig directory at: %s\n", devrigDir)
-
-	// Determine binary name based on the layout: .devrig/<tool-name>-<os>-<cpu-type>-<hash>/binary
-	binaryName := fmt.Sprintf("devrig-%s-%s-%s", osName, archName, hash)
-	if osName == "windows" {
-		binaryName += ".exe"
-	}
-	log.Printf("Determined binary name: %s\n", binaryName)
+	logger.Debug("created .devrig directory", "path", devrigHome)
 
-	// Copy binary to .devrig folder
-	destPath := filepath.Join(devrigDir, binaryName)
-	if err := copyFile(execPath, destPath); err != nil {
+	tempFile := filepath.Join(devrigHome, "devrig-"+platform+"-copying")
+	tempfile.Track(tempFile)
+	//goland:noinspection GoUnhandledErrorResult
+	defer tempfile.Remove(tempFile)
+	if err := copyFile(execPath, tempFile); err != nil {
 		return nil, fmt.Errorf("failed to copy binary: %w", err)
 	}
-	log.Printf("Copied binary to: %s\n", destPath)
 
-	// Set executable permissions (Unix-like systems)
-	if osName != "windows" {
-		if err := os.Chmod(destPath, 0755); err != nil {
-			return nil, fmt.Errorf("failed to set executable permissions: %w", err)
-		}
-		log.Printf("Set executable permissions for: %s\n", destPath)
+	destPath, err := layout.InstallDevrigBinary(devrigHome, tempFile, layout.DevrigBinaryMetadata{
+		OS:     osName,
+		Arch:   archName,
+		SHA512: hash,
+		Source: layout.DevrigBinarySourceInit,
+	})
+	if err != nil {
+		return nil, err
 	}
+	logger.Debug("copied binary", "path", destPath)
 
-	log.Println("Local initialization completed successfully!")
+	logger.Debug("local initialization completed")
 
 	// Generate devrig section
 	section := generateDevrigSection(platform, hash)
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"jonnyzzz.com/devrig.dev/layout"
 	"jonnyzzz.com/devrig.dev/updates"
 
 	"github.com/goccy/go-yaml"
@@ -26,6 +27,10 @@ func (t *mockUpdateService) IsUpdateAvailable() (bool, error) {
 	return false, fmt.Errorf("not implemented for tests")
 }
 
+func (t *mockUpdateService) CompareWithLatest() (*updates.Comparison, error) {
+	return nil, fmt.Errorf("not implemented for tests")
+}
+
 // newTestInitCommand creates a new init command with mock dependencies for testing
 func newTestInitCommand() *cobra.Command {
 	return NewInitCommand(&mockUpdateService{})
@@ -329,34 +334,7 @@ func TestInitCommand_InitFromLocal(t *testing.T) {
 		t.Fatalf("No directories found in .devrig folder")
 	}
 
-	// Find directory matching pattern devrig-<os>-<arch>-<hash>
-	var binaryFound bool
-	for _, entry := range entries {
-		if strings.HasPrefix(entry.Name(), "devrig-") {
-			// Check if binary exists in this directory
-			binaryPath := filepath.Join(devrigDir, entry.Name())
-			if _, err := os.Stat(binaryPath); err == nil {
-				binaryFound = true
-				t.Logf("Found binary at: %s", binaryPath)
-
-				// Verify binary is executable (Unix-like systems)
-				if runtime.GOOS != "windows" {
-					info, err := os.Stat(binaryPath)
-					if err != nil {
-						t.Fatalf("Failed to stat binary: %v", err)
-					}
-					if info.Mode().Perm()&0111 == 0 {
-						t.Errorf("BinaryInfo is not executable, mode: %v", info.Mode())
-					}
-				}
-				break
-			}
-		}
-	}
-
-	if !binaryFound {
-		t.Errorf("BinaryInfo not found in .devrig directory")
-	}
+	assertLocalBinaryInstalled(t, devrigDir)
 }
 
 func TestCalculateFileHash(t *testing.T) {
@@ -541,36 +519,7 @@ func TestInitializeFromLocalBinary(t *testing.T) {
 		t.Fatalf("No directories found in .devrig folder")
 	}
 
-	var binaryFound bool
-	for _, entry := range entries {
-		if strings.HasPrefix(entry.Name(), "devrig-") {
-			binaryName := ""
-			if runtime.GOOS == "windows" {
-				binaryName = ".exe"
-			}
-			binaryPath := filepath.Join(devrigDir, entry.Name()+binaryName)
-			if info, err := os.Stat(binaryPath); err == nil {
-				binaryFound = true
-
-				// Verify binary size is non-zero
-				if info.Size() == 0 {
-					t.Errorf("BinaryInfo has zero size")
-				}
-
-				// Verify executable permissions on Unix-like systems
-				if runtime.GOOS != "windows" {
-					if info.Mode().Perm()&0111 == 0 {
-						t.Errorf("BinaryInfo is not executable")
-					}
-				}
-				break
-			}
-		}
-	}
-
-	if !binaryFound {
-		t.Errorf("BinaryInfo was not copied to .devrig directory")
-	}
+	assertLocalBinaryInstalled(t, devrigDir)
 
 	// Read and parse devrig.yaml
 	yamlContent, err := os.ReadFile(yamlPath)
@@ -618,6 +567,39 @@ func TestInitializeFromLocalBinary(t *testing.T) {
 	}
 }
 
+func TestInitCommand_PathWithSpacesAndUnicode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need elevated permissions on Windows")
+	}
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, "Jöhn Doe"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	// The project is reached through a symlinked parent, like /tmp on macOS
+	link := filepath.Join(tempDir, "home link")
+	if err := os.Symlink(filepath.Join(tempDir, "Jöhn Doe"), link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	targetDir := filepath.Join(link, "my project 名前")
+
+	cmd := newTestInitCommand()
+	var stdout bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stdout)
+	cmd.SetArgs([]string{"--init-from-local", targetDir})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Command failed: %v\nOutput: %s", err, stdout.String())
+	}
+
+	realDir := filepath.Join(tempDir, "Jöhn Doe", "my project 名前")
+	for _, file := range []string{"devrig", "devrig.bat", "devrig.ps1", "devrig.yaml"} {
+		if _, err := os.Stat(filepath.Join(realDir, file)); err != nil {
+			t.Errorf("Expected %s in the project: %v", file, err)
+		}
+	}
+	assertLocalBinaryInstalled(t, filepath.Join(realDir, ".devrig"))
+}
+
 // TestInitCommand_DetectsSymlinks tests that init command detects and warns about symlinked bootstrap scripts
 func TestInitCommand_DetectsSymlinks(t *testing.T) {
 	tempDir := t.TempDir()
@@ -658,3 +640,85 @@ func TestInitCommand_DetectsSymlinks(t *testing.T) {
 		t.Errorf("Must still be a symlink")
 	}
 }
+
+func TestInitCommand_ImportsToolVersions(t *testing.T) {
+	tempDir := t.TempDir()
+
+	toolVersions := "nodejs 20.11.0\ngolang 1.22.1 # go toolchain\n"
+	if err := os.WriteFile(filepath.Join(tempDir, ".tool-versions"), []byte(toolVersions), 0644); err != nil {
+		t.Fatalf("Failed to write .tool-versions: %v", err)
+	}
+
+	cmd := newTestInitCommand()
+	var stdout bytes.Buffer
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stdout)
+	cmd.SetArgs([]string{"--init-from-local", tempDir})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Command failed: %v\nOutput: %s", err, stdout.String())
+	}
+
+	if !strings.Contains(stdout.String(), "Imported 2 tools from .tool-versions") {
+		t.Errorf("Expected import message in output: %s", stdout.String())
+	}
+
+	yamlContent, err := os.ReadFile(filepath.Join(tempDir, "devrig.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to read devrig.yaml: %v", err)
+	}
+
+	var config struct {
+		Tools map[string]string `yaml:"tools"`
+	}
+	if err := yaml.Unmarshal(yamlContent, &config); err != nil {
+		t.Fatalf("Failed to parse devrig.yaml: %v", err)
+	}
+
+	if config.Tools["node"] != "20.11.0" {
+		t.Errorf("Expected node 20.11.0, got: %q", config.Tools["node"])
+	}
+	if config.Tools["go"] != "1.22.1" {
+		t.Errorf("Expected go 1.22.1, got: %q", config.Tools["go"])
+	}
+}
+
+// assertLocalBinaryInstalled checks the layout of the .devrig folder: devrig-<os>-<arch>-<hash>/devrig[.exe] and metadata.json
+func assertLocalBinaryInstalled(t *testing.T, devrigDir string) {
+	t.Helper()
+	entries, err := os.ReadDir(devrigDir)
+	if err != nil {
+		t.Fatalf("Failed to read .devrig directory: %v", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), "devrig-") {
+			continue
+		}
+		binaryDir := filepath.Join(devrigDir, entry.Name())
+		metadata, err := layout.ReadDevrigBinaryMetadata(binaryDir)
+		if err != nil {
+			t.Fatalf("Failed to read binary metadata: %v", err)
+		}
+		if metadata.Source != layout.DevrigBinarySourceInit || len(metadata.SHA512) != 128 {
+			t.Errorf("Unexpected binary metadata: %+v", metadata)
+		}
+
+		binaryPath := layout.ResolveDevrigBinary(devrigDir, metadata.OS, metadata.Arch, metadata.SHA512)
+		if filepath.Dir(binaryPath) != binaryDir {
+			t.Errorf("Expected the binary in %s, got %s", binaryDir, binaryPath)
+		}
+		info, err := os.Stat(binaryPath)
+		if err != nil {
+			t.Fatalf("Binary not found: %v", err)
+		}
+		if info.Size() == 0 {
+			t.Errorf("Binary has zero size")
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm()&0111 == 0 {
+			t.Errorf("Binary is not executable, mode: %v", info.Mode())
+		}
+		return
+	}
+	t.Errorf("Binary folder not found in .devrig directory")
+}
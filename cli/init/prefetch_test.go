@@ -0,0 +1,86 @@
+package init
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// servePrefetchBinary serves the content as the devrig binary, it returns the update service of a release pinning
+// the SHA-512 of pinned
+func servePrefetchBinary(t *testing.T, content string, pinned string) *releaseUpdateService {
+	t.Helper()
+	t.Setenv(offline.EnvOffline, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+
+	source := filepath.Join(t.TempDir(), "devrig")
+	if err := os.WriteFile(source, []byte(pinned), 0644); err != nil {
+		t.Fatalf("Failed to write binary: %v", err)
+	}
+	hash, err := calculateFileHash(source)
+	if err != nil {
+		t.Fatalf("Failed to hash binary: %v", err)
+	}
+	service, _ := newReleaseInitCommand(hash)
+	service.info.Binaries[0].URL = server.URL + "/devrig"
+	return service
+}
+
+func TestInitCommand_Prefetch(t *testing.T) {
+	service := servePrefetchBinary(t, "released devrig binary", "released devrig binary")
+	var output bytes.Buffer
+	projectDir := t.TempDir()
+
+	cmd := NewInitCommand(service)
+	cmd.SetOut(&output)
+	cmd.SetErr(&output)
+	cmd.SetArgs([]string{projectDir, "--no-adopt", "--prefetch"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Failed to execute init: %v\n%s", err, output.String())
+	}
+
+	system := updates.CurrentSystem{}
+	devrigHome := layout.ResolveDevrigHome(filepath.Join(projectDir, "devrig.yaml"))
+	target := layout.ResolveDevrigBinary(devrigHome, system.OS(), system.Arch(), service.info.Binaries[0].SHA512)
+	data, err := os.ReadFile(target)
+	if err != nil || string(data) != "released devrig binary" {
+		t.Fatalf("Expected the binary to be downloaded to %s, got %q, %v", target, data, err)
+	}
+	metadata, err := layout.ReadDevrigBinaryMetadata(filepath.Dir(target))
+	if err != nil || metadata.Source != layout.DevrigBinarySourceInit || metadata.Version != "0.79.6" {
+		t.Errorf("Unexpected metadata: %+v, %v", metadata, err)
+	}
+}
+
+func TestInitCommand_PrefetchChecksumMismatch(t *testing.T) {
+	service := servePrefetchBinary(t, "tampered devrig binary", "released devrig binary")
+	var output bytes.Buffer
+	projectDir := t.TempDir()
+
+	cmd := NewInitCommand(service)
+	cmd.SetOut(&output)
+	cmd.SetErr(&output)
+	cmd.SetArgs([]string{projectDir, "--no-adopt", "--prefetch"})
+	var mismatch *devrigErrors.ChecksumMismatchError
+	if err := cmd.Execute(); !errors.As(err, &mismatch) {
+		t.Fatalf("Expected a checksum mismatch, got: %v", err)
+	}
+
+	system := updates.CurrentSystem{}
+	devrigHome := layout.ResolveDevrigHome(filepath.Join(projectDir, "devrig.yaml"))
+	if _, err := os.Stat(layout.ResolveDevrigBinary(devrigHome, system.OS(), system.Arch(), service.info.Binaries[0].SHA512)); !os.IsNotExist(err) {
+		t.Errorf("Expected no binary after the checksum mismatch, got: %v", err)
+	}
+}
@@ -0,0 +1,13 @@
+package install
+
+import "jonnyzzz.com/devrig.dev/extract"
+
+// extractArchive unpacks a .tar.gz or .zip archive into destDir.
+// Entries escaping destDir, directly or via links, oversized entries and decompression bombs are rejected.
+func extractArchive(archivePath string, destDir string) error {
+	limits, err := extract.ResolveLimits()
+	if err != nil {
+		return err
+	}
+	return extract.Archive(archivePath, destDir, limits)
+}
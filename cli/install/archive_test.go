@@ -0,0 +1,120 @@
+package install
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type tarEntry struct {
+	name     string
+	content  string
+	mode     int64
+	typeflag byte
+	linkname string
+}
+
+// buildTarGz writes a .tar.gz archive with the entries
+func buildTarGz(t *testing.T, path string, entries []tarEntry) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		typeflag := entry.typeflag
+		if typeflag == 0 {
+			typeflag = tar.TypeReg
+		}
+		mode := entry.mode
+		if mode == 0 {
+			mode = 0644
+		}
+		header := &tar.Header{Name: entry.name, Mode: mode, Size: int64(len(entry.content)), Typeflag: typeflag, Linkname: entry.linkname}
+		if typeflag != tar.TypeReg {
+			header.Size = 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write tar header: %v", err)
+		}
+		if typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(entry.content)); err != nil {
+				t.Fatalf("Failed to write tar entry: %v", err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to close gzip: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+}
+
+func TestExtractTarGz(t *testing.T) {
+	tempDir := t.TempDir()
+	archivePath := filepath.Join(tempDir, "tool.tar.gz")
+	buildTarGz(t, archivePath, []tarEntry{
+		{name: "jdk/", typeflag: tar.TypeDir, mode: 0755},
+		{name: "jdk/bin/java", content: "#!/bin/sh\n", mode: 0755},
+		{name: "jdk/release", content: "JAVA_VERSION=21"},
+		{name: "jdk/lib/current", typeflag: tar.TypeSymlink, linkname: "../release"},
+	})
+
+	destDir := filepath.Join(tempDir, "out")
+	if err := extractArchive(archivePath, destDir); err != nil {
+		t.Fatalf("Failed to extract archive: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(destDir, "jdk", "bin", "java"))
+	if err != nil {
+		t.Fatalf("Failed to stat java: %v", err)
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Expected java to be executable, got mode %v", info.Mode())
+	}
+
+	data, err := os.ReadFile(filepath.Join(destDir, "jdk", "lib", "current"))
+	if err != nil {
+		t.Fatalf("Failed to read through symlink: %v", err)
+	}
+	if string(data) != "JAVA_VERSION=21" {
+		t.Errorf("Unexpected symlink content: %q", data)
+	}
+}
+
+func TestExtractTarGz_RejectsEscapingEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry tarEntry
+	}{
+		{name: "parent path", entry: tarEntry{name: "../evil", content: "x"}},
+		{name: "nested parent path", entry: tarEntry{name: "jdk/../../evil", content: "x"}},
+		{name: "absolute symlink", entry: tarEntry{name: "jdk/link", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}},
+		{name: "escaping symlink", entry: tarEntry{name: "jdk/link", typeflag: tar.TypeSymlink, linkname: "../../evil"}},
+		{name: "escaping hard link", entry: tarEntry{name: "jdk/link", typeflag: tar.TypeLink, linkname: "../evil"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			archivePath := filepath.Join(tempDir, "evil.tar.gz")
+			buildTarGz(t, archivePath, []tarEntry{tt.entry})
+
+			err := extractArchive(archivePath, filepath.Join(tempDir, "out"))
+			if err == nil || !strings.Contains(err.Error(), "outside of the destination") && !strings.Contains(err.Error(), "absolute path") {
+				t.Errorf("Expected the entry to be rejected, got: %v", err)
+			}
+			if _, err := os.Lstat(filepath.Join(tempDir, "evil")); err == nil {
+				t.Errorf("File was written outside of the destination")
+			}
+		})
+	}
+}
@@ -1,10 +1,14 @@
 package install
 
 import (
+	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -25,7 +29,8 @@ func TestVerifyChecksum(t *testing.T) {
 
 	// Test case 1: Valid checksum
 	t.Run("ValidChecksum", func(t *testing.T) {
-		installer := &JetBrainsMonoInstaller{
+		installer := &GitHubFontInstaller{
+			spec:        JetBrainsMono,
 			fontVersion: "v9.9.9",
 		}
 
@@ -43,7 +48,8 @@ func TestVerifyChecksum(t *testing.T) {
 
 	// Test case 2: Invalid checksum
 	t.Run("InvalidChecksum", func(t *testing.T) {
-		installer := &JetBrainsMonoInstaller{
+		installer := &GitHubFontInstaller{
+			spec:        JetBrainsMono,
 			fontVersion: "v9.9.9",
 		}
 
@@ -61,7 +67,8 @@ func TestVerifyChecksum(t *testing.T) {
 
 	// Test case 3: Unknown version (should warn but not fail)
 	t.Run("UnknownVersion", func(t *testing.T) {
-		installer := &JetBrainsMonoInstaller{
+		installer := &GitHubFontInstaller{
+			spec:        JetBrainsMono,
 			fontVersion: "v99.99.99",
 		}
 
@@ -77,7 +84,8 @@ func TestVerifyChecksum(t *testing.T) {
 
 	// Test case 4: File doesn't exist (with known checksum)
 	t.Run("FileNotFound", func(t *testing.T) {
-		installer := &JetBrainsMonoInstaller{
+		installer := &GitHubFontInstaller{
+			spec:        JetBrainsMono,
 			fontVersion: "v9.9.9",
 		}
 
@@ -113,3 +121,73 @@ func TestGetKnownChecksum(t *testing.T) {
 		t.Error("Expected empty string for unknown version")
 	}
 }
+
+func TestVerifyChecksum_ReleaseAsset(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "JetBrainsMono-9.9.9.zip")
+	testContent := []byte("font archive")
+	if err := os.WriteFile(testFile, testContent, 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	sha512Sum := sha512.Sum512(testContent)
+	sha256Sum := sha256.Sum256(testContent)
+
+	assets := map[string]string{
+		"/JetBrainsMono-9.9.9.zip.sha512": hex.EncodeToString(sha512Sum[:]) + "\n",
+		"/checksums.txt":                  hex.EncodeToString(sha256Sum[:]) + "  JetBrainsMono-9.9.9.zip\n0000  other.zip\n",
+		"/wrong.sha512":                   strings.Repeat("ab", 64),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := assets[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	// The release asset wins over the known checksums
+	originalChecksums := KnownChecksums
+	KnownChecksums = map[string]string{"v9.9.9": "wrongchecksumwrongchecksumwrongchecksum"}
+	defer func() { KnownChecksums = originalChecksums }()
+
+	for _, asset := range []string{"/JetBrainsMono-9.9.9.zip.sha512", "/checksums.txt"} {
+		installer := &GitHubFontInstaller{
+			spec:        JetBrainsMono,
+			fontVersion: "v9.9.9",
+			downloadURL: server.URL + "/JetBrainsMono-9.9.9.zip",
+			checksumURL: server.URL + asset,
+		}
+		if err := installer.verifyChecksum(testFile); err != nil {
+			t.Errorf("Expected checksum from %s to pass, got error: %v", asset, err)
+		}
+	}
+
+	for _, asset := range []string{"/wrong.sha512", "/missing.sha512"} {
+		installer := &GitHubFontInstaller{
+			spec:        JetBrainsMono,
+			fontVersion: "v9.9.9",
+			downloadURL: server.URL + "/JetBrainsMono-9.9.9.zip",
+			checksumURL: server.URL + asset,
+		}
+		if err := installer.verifyChecksum(testFile); err == nil {
+			t.Errorf("Expected checksum from %s to fail", asset)
+		}
+	}
+}
+
+func TestParseChecksumAsset(t *testing.T) {
+	hash := strings.Repeat("AB", 64)
+	if checksum, err := parseChecksumAsset(hash+" *JetBrainsMono-2.304.zip\n", "JetBrainsMono-2.304.zip"); err != nil || checksum != strings.ToLower(hash) {
+		t.Errorf("Expected binary mode sha512sum output to be parsed, got %q, %v", checksum, err)
+	}
+	if _, err := parseChecksumAsset(hash+"  other.zip\n", "JetBrainsMono-2.304.zip"); err == nil {
+		t.Error("Expected an error when the file is not listed")
+	}
+	if _, err := parseChecksumAsset("not-a-checksum\n", "JetBrainsMono-2.304.zip"); err == nil {
+		t.Error("Expected an error for an invalid checksum")
+	}
+	if !isChecksumAsset("JetBrainsMono-2.304.zip.sha512") || !isChecksumAsset("checksums.txt") || isChecksumAsset("JetBrainsMono-2.304.zip") {
+		t.Error("Expected checksum assets to be detected by name")
+	}
+}
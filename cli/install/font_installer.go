@@ -0,0 +1,603 @@
+package install
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/extract"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/progress"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// GitHubFontInstaller installs a font family published as a zip asset of GitHub releases
+type GitHubFontInstaller struct {
+	spec          FontSpec
+	apiURL        string
+	devrigVersion string
+	fontVersion   string
+	downloadURL   string
+	// checksumURL is the checksum asset of the release, empty if the release has none
+	checksumURL string
+	tempDir     string
+	userAgent   string
+	logger      *slog.Logger
+
+	// AllUsers installs the fonts system-wide on Windows, requires administrator rights
+	AllUsers bool
+}
+
+// GitHubRelease represents a GitHub release response
+type GitHubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// NewFontInstaller creates an installer of the font and resolves its latest release
+func NewFontInstaller(spec FontSpec, devrigVersion string) (*GitHubFontInstaller, error) {
+	installer := &GitHubFontInstaller{
+		spec:          spec,
+		apiURL:        spec.ReleaseAPIURL(),
+		devrigVersion: devrigVersion,
+		userAgent:     fmt.Sprintf("devrig/%s", devrigVersion),
+	}
+
+	// Fetch latest release info
+	if err := installer.fetchLatestRelease(); err != nil {
+		return nil, fmt.Errorf("failed to fetch latest release: %w", err)
+	}
+
+	return installer, nil
+}
+
+// Name returns the name of the font in `devrig install font <name>`
+func (j *GitHubFontInstaller) Name() string {
+	return j.spec.Name
+}
+
+// Version returns the resolved release of the font
+func (j *GitHubFontInstaller) Version() string {
+	return j.fontVersion
+}
+
+// fetchLatestRelease fetches the latest release of the font from GitHub
+func (j *GitHubFontInstaller) fetchLatestRelease() error {
+	if err := offline.Check(j.spec.Title+" release information", j.apiURL, "fonts can only be installed with network access"); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("GET", j.apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", j.userAgent)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch release info: %w", &devrigErrors.NetworkError{URL: j.apiURL, Err: err})
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &devrigErrors.NetworkError{URL: j.apiURL, Err: fmt.Errorf("GitHub API returned status %d", resp.StatusCode)}
+	}
+
+	var release GitHubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return fmt.Errorf("failed to decode release info: %w", err)
+	}
+
+	j.fontVersion = release.TagName
+
+	// Find the zip asset
+	for _, asset := range release.Assets {
+		if j.spec.matchesAsset(asset.Name) {
+			j.downloadURL = asset.BrowserDownloadURL
+			break
+		}
+	}
+
+	if j.downloadURL == "" {
+		return fmt.Errorf("could not find font zip in release %s", j.fontVersion)
+	}
+
+	// Prefer the checksum of the zip itself over a shared checksums file
+	zipName := path.Base(j.downloadURL)
+	for _, asset := range release.Assets {
+		if !isChecksumAsset(asset.Name) {
+			continue
+		}
+		if j.checksumURL == "" || strings.HasPrefix(asset.Name, zipName) {
+			j.checksumURL = asset.BrowserDownloadURL
+		}
+	}
+
+	return nil
+}
+
+// log returns the logger of the running command, or the default logger
+func (j *GitHubFontInstaller) log() *slog.Logger {
+	if j.logger != nil {
+		return j.logger
+	}
+	return slog.Default()
+}
+
+// Install downloads, verifies, extracts and installs the font
+func (j *GitHubFontInstaller) Install(cmd *cobra.Command) error {
+	j.logger = logging.FromContext(cmd.Context())
+	if err := j.checkAllUsers(); err != nil {
+		return err
+	}
+	cmd.Printf("Downloading %s %s...\n", j.spec.Title, j.fontVersion)
+
+	// Create temp directory
+	tempDir, err := tempfile.Mkdir("", "devrig-"+j.spec.Name+"-*")
+	if err != nil {
+		return err
+	}
+	j.tempDir = tempDir
+	defer tempfile.Remove(tempDir)
+
+	// Download font
+	zipPath := filepath.Join(tempDir, "font.zip")
+	if err := j.downloadFile(cmd.Context(), zipPath); err != nil {
+		return fmt.Errorf("failed to download font: %w", err)
+	}
+
+	// Verify checksum using GitHub as source of truth
+	cmd.Println("Verifying download integrity...")
+	if err := j.verifyChecksum(zipPath); err != nil {
+		return fmt.Errorf("checksum verification failed: %w", err)
+	}
+
+	cmd.Println("Extracting fonts...")
+
+	// Extract fonts
+	fontsDir := filepath.Join(tempDir, "fonts")
+	if err := j.extractFonts(zipPath, fontsDir); err != nil {
+		return fmt.Errorf("failed to extract fonts: %w", err)
+	}
+
+	cmd.Println("Installing fonts...")
+
+	// Install fonts based on OS
+	if err := j.installFontsForOS(fontsDir); err != nil {
+		return fmt.Errorf("failed to install fonts: %w", err)
+	}
+
+	return nil
+}
+
+// downloadFile downloads a file from URL to destPath
+func (j *GitHubFontInstaller) downloadFile(ctx context.Context, destPath string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", j.downloadURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", j.userAgent)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", &devrigErrors.NetworkError{URL: j.downloadURL, Err: err})
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &devrigErrors.NetworkError{URL: j.downloadURL, Err: fmt.Errorf("download returned status %d", resp.StatusCode)}
+	}
+
+	out, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer out.Close()
+
+	tracker := progress.Track(ctx, "Downloading "+filepath.Base(destPath), resp.ContentLength, 0)
+	_, err = io.Copy(out, tracker.Reader(resp.Body))
+	tracker.Finish()
+	if err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
+	}
+
+	return nil
+}
+
+// maxFontFileSize is far above the largest TTF of a font family
+const maxFontFileSize = 64 << 20
+
+// extractFonts extracts TTF fonts from the zip archive
+func (j *GitHubFontInstaller) extractFonts(zipPath, destDir string) error {
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return fmt.Errorf("failed to create fonts directory: %w", err)
+	}
+
+	limits, err := extract.ResolveLimits()
+	if err != nil {
+		return err
+	}
+
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer r.Close()
+
+	// Extract only TTF files from the font directory of the archive
+	for _, f := range r.File {
+		if !j.spec.inFontDir(f.Name) {
+			continue
+		}
+
+		if !strings.HasSuffix(strings.ToLower(f.Name), ".ttf") || !f.Mode().IsRegular() {
+			continue
+		}
+
+		// Fonts are flattened into destDir, still an archive with escaping entries is not trusted at all
+		name, err := extract.EntryName(f.Name)
+		if err != nil {
+			return err
+		}
+		if f.UncompressedSize64 > maxFontFileSize {
+			return &extract.UnsafeEntryError{Entry: f.Name, Reason: fmt.Sprintf("is larger than %d bytes", maxFontFileSize)}
+		}
+
+		// Extract file
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open file in zip: %w", err)
+		}
+
+		fileName := filepath.Base(name)
+		destPath := filepath.Join(destDir, fileName)
+
+		outFile, err := os.Create(destPath)
+		if err != nil {
+			rc.Close()
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+
+		_, err = extract.Copy(outFile, extract.NewReader(rc, f.Name, int64(f.CompressedSize64), limits), f.Name, maxFontFileSize)
+		outFile.Close()
+		rc.Close()
+
+		if err != nil {
+			return fmt.Errorf("failed to extract file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// installFontsForOS installs fonts based on the current operating system
+func (j *GitHubFontInstaller) installFontsForOS(fontsDir string) error {
+	switch runtime.GOOS {
+	case "windows":
+		return j.installFontsWindows(fontsDir)
+	case "darwin":
+		return j.installFontsMacOS(fontsDir)
+	case "linux":
+		return j.installFontsLinux(fontsDir)
+	default:
+		return &devrigErrors.UnsupportedPlatformError{OS: runtime.GOOS}
+	}
+}
+
+// installFontsWindows installs fonts for the current user, or for all users with AllUsers,
+// registers them in the registry and notifies running applications, so no restart is needed
+func (j *GitHubFontInstaller) installFontsWindows(fontsDir string) error {
+	fontsPath := filepath.Join(os.Getenv("LOCALAPPDATA"), "Microsoft", "Windows", "Fonts")
+	if j.AllUsers {
+		fontsPath = filepath.Join(os.Getenv("WINDIR"), "Fonts")
+	}
+	if err := os.MkdirAll(fontsPath, 0755); err != nil {
+		return fmt.Errorf("failed to create fonts directory: %w", err)
+	}
+
+	files, err := os.ReadDir(fontsDir)
+	if err != nil {
+		return fmt.Errorf("failed to read fonts directory: %w", err)
+	}
+
+	for _, file := range files {
+		if !strings.HasSuffix(strings.ToLower(file.Name()), ".ttf") {
+			continue
+		}
+
+		srcPath := filepath.Join(fontsDir, file.Name())
+		destPath := filepath.Join(fontsPath, file.Name())
+
+		// Copy font file
+		if err := copyFile(srcPath, destPath); err != nil {
+			return fmt.Errorf("failed to copy font %s: %w", file.Name(), err)
+		}
+
+		// Per-user fonts are registered with the full path, system fonts with the file name in WINDIR\Fonts
+		value := destPath
+		if j.AllUsers {
+			value = file.Name()
+		}
+		if err := registerFont(j.AllUsers, fontRegistryName(destPath), value); err != nil {
+			return fmt.Errorf("failed to register font %s: %w", file.Name(), err)
+		}
+	}
+
+	if err := broadcastFontChange(); err != nil {
+		j.log().Warn("Failed to notify applications about the new fonts, restart them to see the fonts", "error", err)
+	}
+	return nil
+}
+
+// checkAllUsers fails early, before the download, if the fonts cannot be installed for all users
+func (j *GitHubFontInstaller) checkAllUsers() error {
+	if !j.AllUsers {
+		return nil
+	}
+	if runtime.GOOS != "windows" {
+		return fmt.Errorf("installing fonts for all users is only supported on Windows")
+	}
+	if !isElevated() {
+		return fmt.Errorf("installing fonts for all users requires an elevated prompt, run devrig as administrator")
+	}
+	return nil
+}
+
+// installFontsMacOS installs fonts on macOS
+func (j *GitHubFontInstaller) installFontsMacOS(fontsDir string) error {
+	// macOS user fonts directory
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	fontsPath := filepath.Join(homeDir, "Library", "Fonts")
+	if err := os.MkdirAll(fontsPath, 0755); err != nil {
+		return fmt.Errorf("failed to create fonts directory: %w", err)
+	}
+
+	files, err := os.ReadDir(fontsDir)
+	if err != nil {
+		return fmt.Errorf("failed to read fonts directory: %w", err)
+	}
+
+	for _, file := range files {
+		if !strings.HasSuffix(strings.ToLower(file.Name()), ".ttf") {
+			continue
+		}
+
+		srcPath := filepath.Join(fontsDir, file.Name())
+		destPath := filepath.Join(fontsPath, file.Name())
+
+		// Copy font file
+		if err := copyFile(srcPath, destPath); err != nil {
+			return fmt.Errorf("failed to copy font %s: %w", file.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// installFontsLinux installs fonts on Linux
+func (j *GitHubFontInstaller) installFontsLinux(fontsDir string) error {
+	// Linux user fonts directory
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	fontsPath := filepath.Join(homeDir, ".local", "share", "fonts", j.spec.InstallDir)
+	if err := os.MkdirAll(fontsPath, 0755); err != nil {
+		return fmt.Errorf("failed to create fonts directory: %w", err)
+	}
+
+	files, err := os.ReadDir(fontsDir)
+	if err != nil {
+		return fmt.Errorf("failed to read fonts directory: %w", err)
+	}
+
+	for _, file := range files {
+		if !strings.HasSuffix(strings.ToLower(file.Name()), ".ttf") {
+			continue
+		}
+
+		srcPath := filepath.Join(fontsDir, file.Name())
+		destPath := filepath.Join(fontsPath, file.Name())
+
+		// Copy font file
+		if err := copyFile(srcPath, destPath); err != nil {
+			return fmt.Errorf("failed to copy font %s: %w", file.Name(), err)
+		}
+	}
+
+	// Refresh font cache on Linux
+	j.log().Info("Refreshing font cache...")
+	// Attempts to run fc-cache -f to refresh the font cache
+	// This is not critical and won't fail if fc-cache is not installed
+	_ = refreshFontCacheLinux()
+
+	return nil
+}
+
+// copyFile copies a file from src to dst
+func copyFile(src, dst string) error {
+	sourceFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	destFile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer destFile.Close()
+
+	_, err = io.Copy(destFile, sourceFile)
+	return err
+}
+
+// verifyChecksum verifies the checksum of the downloaded file against the checksum asset of the release,
+// or against known-good checksums if the release has no checksum asset
+func (j *GitHubFontInstaller) verifyChecksum(filePath string) error {
+	knownChecksum := j.spec.knownChecksum(j.fontVersion)
+	if j.checksumURL != "" {
+		checksum, err := j.fetchReleaseChecksum()
+		if err != nil {
+			return fmt.Errorf("failed to get checksum from %s: %w", j.checksumURL, err)
+		}
+		knownChecksum = checksum
+	}
+
+	if knownChecksum == "" {
+		// If we don't have a known checksum for this version, warn but don't fail
+		// This allows installation of newer versions before we update the checksums
+		j.log().Warn(fmt.Sprintf("No known checksum for version %s. Skipping verification.", j.fontVersion))
+		j.log().Warn("Please report this at: https://github.com/jonnyzzz/devrig.dev/issues")
+		return nil
+	}
+
+	// Calculate the checksum of the downloaded file
+	calculatedChecksum, err := fileChecksum(filePath, knownChecksum)
+	if err != nil {
+		return err
+	}
+
+	// Compare checksums
+	if calculatedChecksum != knownChecksum {
+		return fmt.Errorf(
+			"%w\n\nThis could indicate a corrupted download or a security issue.\nPlease report this at: https://github.com/jonnyzzz/devrig.dev/issues",
+			&devrigErrors.ChecksumMismatchError{
+				Subject:  "version " + j.fontVersion,
+				Expected: knownChecksum,
+				Actual:   calculatedChecksum,
+			},
+		)
+	}
+
+	return nil
+}
+
+// fileChecksum calculates the checksum of the file with the algorithm of the expected checksum,
+// releases may publish SHA-256 or SHA-512 checksums
+func fileChecksum(filePath string, expected string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file for checksum: %w", err)
+	}
+	defer file.Close()
+
+	var hasher hash.Hash = sha512.New()
+	if len(expected) == sha256.Size*2 {
+		hasher = sha256.New()
+	}
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("failed to calculate checksum: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// fetchReleaseChecksum downloads the checksum asset of the release and returns the checksum of the zip
+func (j *GitHubFontInstaller) fetchReleaseChecksum() (string, error) {
+	req, err := http.NewRequest("GET", j.checksumURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", j.userAgent)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", &devrigErrors.NetworkError{URL: j.checksumURL, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &devrigErrors.NetworkError{URL: j.checksumURL, Err: fmt.Errorf("download returned status %d", resp.StatusCode)}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum asset: %w", err)
+	}
+	return parseChecksumAsset(string(data), path.Base(j.downloadURL))
+}
+
+// isChecksumAsset checks if the release asset contains checksums, e.g. JetBrainsMono-2.304.zip.sha512 or checksums.txt
+func isChecksumAsset(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasSuffix(lower, ".sha512") || strings.HasSuffix(lower, ".sha256") ||
+		strings.Contains(lower, "checksums") || strings.HasPrefix(lower, "sha512sums") || strings.HasPrefix(lower, "sha256sums")
+}
+
+// parseChecksumAsset finds the checksum of the file in the output of sha512sum/sha256sum.
+// A single checksum without a file name is accepted too, as in <file>.sha512 assets.
+func parseChecksumAsset(content string, fileName string) (string, error) {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 1 && len(lines) == 1 {
+			return validateChecksum(fields[0], fileName)
+		}
+		if len(fields) >= 2 && strings.TrimPrefix(fields[len(fields)-1], "*") == fileName {
+			return validateChecksum(fields[0], fileName)
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s in the checksum asset", fileName)
+}
+
+func validateChecksum(checksum string, fileName string) (string, error) {
+	checksum = strings.ToLower(checksum)
+	if _, err := hex.DecodeString(checksum); err != nil || (len(checksum) != sha512.Size*2 && len(checksum) != sha256.Size*2) {
+		return "", fmt.Errorf("invalid checksum for %s: %s", fileName, checksum)
+	}
+	return checksum, nil
+}
+
+// refreshFontCacheLinux refreshes the font cache on Linux
+func refreshFontCacheLinux() error {
+	// Try to run fc-cache to refresh font cache
+	// This is not critical, so we don't return errors if it fails
+	cmd := exec.Command("fc-cache", "-f")
+
+	// Run the command, but ignore any errors
+	// fc-cache might not be installed or might fail for various reasons
+	if err := cmd.Run(); err != nil {
+		// Log that we tried but failed (not critical)
+		// In a production system, you might want to use a proper logger here
+		_ = err // Ignore the error
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package install
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// fontFullNameID is the name ID of the full font name, e.g. "JetBrains Mono Bold"
+const fontFullNameID = 4
+
+// fontRegistryName returns the name of the font in the Windows registry, e.g. "JetBrains Mono Bold (TrueType)".
+// The name is read from the font file, the file name is used if the file has no readable name.
+func fontRegistryName(fontPath string) string {
+	name, err := readFontFullName(fontPath)
+	if err != nil || name == "" {
+		name = strings.TrimSuffix(filepath.Base(fontPath), filepath.Ext(fontPath))
+	}
+	return name + " (TrueType)"
+}
+
+// readFontFullName reads the full font name from the name table of a TrueType font
+func readFontFullName(fontPath string) (string, error) {
+	data, err := os.ReadFile(fontPath)
+	if err != nil {
+		return "", err
+	}
+	return parseFontFullName(data)
+}
+
+// parseFontFullName finds the full font name in the name table, preferring the
+// Windows English (US) record over the Macintosh Roman one
+func parseFontFullName(data []byte) (string, error) {
+	if len(data) < 12 {
+		return "", fmt.Errorf("font file is too short")
+	}
+
+	// The table directory follows the 12 bytes offset table
+	numTables := int(binary.BigEndian.Uint16(data[4:6]))
+	var nameOffset, nameLength int
+	for i := 0; i < numTables; i++ {
+		record := 12 + i*16
+		if record+16 > len(data) {
+			return "", fmt.Errorf("truncated table directory")
+		}
+		if string(data[record:record+4]) == "name" {
+			nameOffset = int(binary.BigEndian.Uint32(data[record+8 : record+12]))
+			nameLength = int(binary.BigEndian.Uint32(data[record+12 : record+16]))
+			break
+		}
+	}
+	if nameLength == 0 || nameOffset+nameLength > len(data) || nameLength < 6 {
+		return "", fmt.Errorf("no name table")
+	}
+
+	table := data[nameOffset : nameOffset+nameLength]
+	count := int(binary.BigEndian.Uint16(table[2:4]))
+	storage := int(binary.BigEndian.Uint16(table[4:6]))
+
+	var macName string
+	for i := 0; i < count; i++ {
+		record := 6 + i*12
+		if record+12 > len(table) {
+			break
+		}
+		platformID := binary.BigEndian.Uint16(table[record : record+2])
+		encodingID := binary.BigEndian.Uint16(table[record+2 : record+4])
+		languageID := binary.BigEndian.Uint16(table[record+4 : record+6])
+		nameID := binary.BigEndian.Uint16(table[record+6 : record+8])
+		length := int(binary.BigEndian.Uint16(table[record+8 : record+10]))
+		offset := int(binary.BigEndian.Uint16(table[record+10 : record+12]))
+		if nameID != fontFullNameID || storage+offset+length > len(table) {
+			continue
+		}
+		value := table[storage+offset : storage+offset+length]
+
+		switch {
+		case platformID == 3 && encodingID == 1 && languageID == 0x409:
+			return decodeUTF16BE(value), nil
+		case platformID == 1 && encodingID == 0 && macName == "":
+			macName = string(value)
+		}
+	}
+	if macName == "" {
+		return "", fmt.Errorf("no full font name")
+	}
+	return macName, nil
+}
+
+func decodeUTF16BE(value []byte) string {
+	units := make([]uint16, 0, len(value)/2)
+	for i := 0; i+1 < len(value); i += 2 {
+		units = append(units, binary.BigEndian.Uint16(value[i:i+2]))
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,103 @@
+package install
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// buildTestFont builds a TrueType file with only the name table holding the full font names
+func buildTestFont(macName string, windowsName string) []byte {
+	var storage []byte
+	type record struct{ platform, encoding, language, offset, length uint16 }
+	var records []record
+	if macName != "" {
+		records = append(records, record{1, 0, 0, uint16(len(storage)), uint16(len(macName))})
+		storage = append(storage, macName...)
+	}
+	if windowsName != "" {
+		var encoded []byte
+		for _, unit := range utf16.Encode([]rune(windowsName)) {
+			encoded = binary.BigEndian.AppendUint16(encoded, unit)
+		}
+		records = append(records, record{3, 1, 0x409, uint16(len(storage)), uint16(len(encoded))})
+		storage = append(storage, encoded...)
+	}
+
+	var table []byte
+	table = binary.BigEndian.AppendUint16(table, 0)
+	table = binary.BigEndian.AppendUint16(table, uint16(len(records)))
+	table = binary.BigEndian.AppendUint16(table, uint16(6+12*len(records)))
+	for _, r := range records {
+		for _, value := range []uint16{r.platform, r.encoding, r.language, fontFullNameID, r.length, r.offset} {
+			table = binary.BigEndian.AppendUint16(table, value)
+		}
+	}
+	table = append(table, storage...)
+
+	var font []byte
+	font = binary.BigEndian.AppendUint32(font, 0x00010000)
+	font = binary.BigEndian.AppendUint16(font, 1)
+	font = append(font, make([]byte, 6)...)
+	font = append(font, "name"...)
+	font = binary.BigEndian.AppendUint32(font, 0)
+	font = binary.BigEndian.AppendUint32(font, 28)
+	font = binary.BigEndian.AppendUint32(font, uint32(len(table)))
+	return append(font, table...)
+}
+
+func TestParseFontFullName(t *testing.T) {
+	name, err := parseFontFullName(buildTestFont("Mac Name", "JetBrains Mono Bold"))
+	if err != nil {
+		t.Fatalf("Failed to parse font name: %v", err)
+	}
+	if name != "JetBrains Mono Bold" {
+		t.Errorf("Expected the Windows name, got %q", name)
+	}
+
+	name, err = parseFontFullName(buildTestFont("Fira Code Regular", ""))
+	if err != nil || name != "Fira Code Regular" {
+		t.Errorf("Expected the Macintosh name as a fallback, got %q, %v", name, err)
+	}
+
+	if _, err := parseFontFullName([]byte("not a font")); err == nil {
+		t.Error("Expected error for a broken font")
+	}
+}
+
+func TestFontRegistryName(t *testing.T) {
+	dir := t.TempDir()
+	fontPath := filepath.Join(dir, "CascadiaCode-Bold.ttf")
+	if err := os.WriteFile(fontPath, buildTestFont("", "Cascadia Code Bold"), 0644); err != nil {
+		t.Fatalf("Failed to write font: %v", err)
+	}
+	if name := fontRegistryName(fontPath); name != "Cascadia Code Bold (TrueType)" {
+		t.Errorf("Unexpected registry name %q", name)
+	}
+
+	brokenPath := filepath.Join(dir, "FiraCode-Regular.ttf")
+	if err := os.WriteFile(brokenPath, []byte("mock TTF content"), 0644); err != nil {
+		t.Fatalf("Failed to write font: %v", err)
+	}
+	if name := fontRegistryName(brokenPath); name != "FiraCode-Regular (TrueType)" {
+		t.Errorf("Expected the file name for unreadable fonts, got %q", name)
+	}
+}
+
+func TestCheckAllUsers(t *testing.T) {
+	if err := (&GitHubFontInstaller{}).checkAllUsers(); err != nil {
+		t.Errorf("Expected per-user installation to pass, got: %v", err)
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("The result depends on the elevation of the test process on Windows")
+	}
+	err := (&GitHubFontInstaller{AllUsers: true}).checkAllUsers()
+	if err == nil || !strings.Contains(err.Error(), "only supported on Windows") {
+		t.Errorf("Expected --all-users to be rejected outside Windows, got: %v", err)
+	}
+}
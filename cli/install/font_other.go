@@ -0,0 +1,24 @@
+//go:build !windows
+
+package install
+
+import (
+	"runtime"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// registerFont is only needed on Windows, other systems discover fonts in the fonts folders
+func registerFont(allUsers bool, name string, value string) error {
+	return &devrigErrors.UnsupportedPlatformError{OS: runtime.GOOS}
+}
+
+// broadcastFontChange is only needed on Windows
+func broadcastFontChange() error {
+	return nil
+}
+
+// isElevated is only checked for --all-users on Windows
+func isElevated() bool {
+	return false
+}
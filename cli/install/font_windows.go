@@ -0,0 +1,94 @@
+//go:build windows
+
+package install
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	fontsRegistryKey = `Software\Microsoft\Windows NT\CurrentVersion\Fonts`
+
+	hwndBroadcast    = 0xFFFF
+	wmFontChange     = 0x001D
+	smtoAbortIfHung  = 0x0002
+	broadcastTimeout = 1000
+	tokenElevation   = 20
+)
+
+var (
+	advapi32           = syscall.NewLazyDLL("advapi32.dll")
+	procRegCreateKeyEx = advapi32.NewProc("RegCreateKeyExW")
+	procRegSetValueEx  = advapi32.NewProc("RegSetValueExW")
+
+	user32                 = syscall.NewLazyDLL("user32.dll")
+	procSendMessageTimeout = user32.NewProc("SendMessageTimeoutW")
+)
+
+// registerFont adds the font to HKCU, or to HKLM for all users, so that it is available without a restart
+func registerFont(allUsers bool, name string, value string) error {
+	root := syscall.HKEY_CURRENT_USER
+	if allUsers {
+		root = syscall.HKEY_LOCAL_MACHINE
+	}
+
+	keyName, err := syscall.UTF16PtrFromString(fontsRegistryKey)
+	if err != nil {
+		return err
+	}
+	var key syscall.Handle
+	status, _, _ := procRegCreateKeyEx.Call(uintptr(root), uintptr(unsafe.Pointer(keyName)), 0, 0, 0,
+		uintptr(syscall.KEY_SET_VALUE), 0, uintptr(unsafe.Pointer(&key)), 0)
+	if status != 0 {
+		return fmt.Errorf("failed to open registry key %s: %w", fontsRegistryKey, syscall.Errno(status))
+	}
+	defer func() { _ = syscall.RegCloseKey(key) }()
+
+	valueName, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	data, err := syscall.UTF16FromString(value)
+	if err != nil {
+		return err
+	}
+	status, _, _ = procRegSetValueEx.Call(uintptr(key), uintptr(unsafe.Pointer(valueName)), 0, uintptr(syscall.REG_SZ),
+		uintptr(unsafe.Pointer(&data[0])), uintptr(len(data)*2))
+	if status != 0 {
+		return fmt.Errorf("failed to write registry value %s: %w", name, syscall.Errno(status))
+	}
+	return nil
+}
+
+// broadcastFontChange notifies running applications that the set of fonts has changed
+func broadcastFontChange() error {
+	var result uintptr
+	ret, _, err := procSendMessageTimeout.Call(hwndBroadcast, wmFontChange, 0, 0,
+		smtoAbortIfHung, broadcastTimeout, uintptr(unsafe.Pointer(&result)))
+	if ret == 0 {
+		return fmt.Errorf("failed to broadcast WM_FONTCHANGE: %w", err)
+	}
+	return nil
+}
+
+// isElevated checks if the process runs with administrator rights
+func isElevated() bool {
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return false
+	}
+	var token syscall.Token
+	if err := syscall.OpenProcessToken(process, syscall.TOKEN_QUERY, &token); err != nil {
+		return false
+	}
+	defer func() { _ = token.Close() }()
+
+	var elevated uint32
+	var size uint32
+	if err := syscall.GetTokenInformation(token, tokenElevation, (*byte)(unsafe.Pointer(&elevated)), uint32(unsafe.Sizeof(elevated)), &size); err != nil {
+		return false
+	}
+	return elevated != 0
+}
@@ -0,0 +1,128 @@
+package install
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// FontInstaller installs a font family resolved from its latest release
+type FontInstaller interface {
+	// Name returns the name of the font in `devrig install font <name>`
+	Name() string
+	// Version returns the resolved release of the font
+	Version() string
+	// Install downloads, verifies, extracts and installs the font for the current OS
+	Install(cmd *cobra.Command) error
+}
+
+// FontSpec describes a font family published as a zip asset of GitHub releases
+type FontSpec struct {
+	// Name is the name of the font in `devrig install font <name>`
+	Name        string
+	Title       string
+	Description string
+	// Repo is the GitHub repository of the font, e.g. JetBrains/JetBrainsMono
+	Repo string
+	// AssetPattern matches the zip asset of the release
+	AssetPattern *regexp.Regexp
+	// FontDir is the path fragment of the TTF files in the zip, empty to take all TTF files
+	FontDir string
+	// InstallDir is the folder under ~/.local/share/fonts on Linux
+	InstallDir string
+	// KnownChecksum returns the SHA-512 checksum of the release maintained in devrig, if any
+	KnownChecksum func(version string) string
+}
+
+// ReleaseAPIURL returns the GitHub API URL of the latest release of the font
+func (s FontSpec) ReleaseAPIURL() string {
+	return "https://api.github.com/repos/" + s.Repo + "/releases/latest"
+}
+
+func (s FontSpec) matchesAsset(name string) bool {
+	return s.AssetPattern != nil && s.AssetPattern.MatchString(name)
+}
+
+// inFontDir checks if the zip entry is in the font directory, variable_ttf/ is not ttf/
+func (s FontSpec) inFontDir(name string) bool {
+	return s.FontDir == "" || strings.HasPrefix(name, s.FontDir) || strings.Contains(name, "/"+s.FontDir)
+}
+
+func (s FontSpec) knownChecksum(version string) string {
+	if s.KnownChecksum == nil {
+		return ""
+	}
+	return s.KnownChecksum(version)
+}
+
+const nerdFontsRepo = "ryanoasis/nerd-fonts"
+
+// Fonts is the registry of fonts installable with `devrig install font <name>`
+var Fonts = []FontSpec{
+	JetBrainsMono,
+	{
+		Name:         "fira-code",
+		Title:        "Fira Code",
+		Description:  "Monospaced font with programming ligatures",
+		Repo:         "tonsky/FiraCode",
+		AssetPattern: regexp.MustCompile(`^Fira_Code_.*\.zip$`),
+		FontDir:      "ttf/",
+		InstallDir:   "FiraCode",
+	},
+	{
+		Name:         "cascadia-code",
+		Title:        "Cascadia Code",
+		Description:  "Monospaced font by Microsoft, shipped with Windows Terminal",
+		Repo:         "microsoft/cascadia-code",
+		AssetPattern: regexp.MustCompile(`^CascadiaCode-.*\.zip$`),
+		FontDir:      "ttf/",
+		InstallDir:   "CascadiaCode",
+	},
+	{
+		Name:         "jetbrains-mono-nerd",
+		Title:        "JetBrainsMono Nerd Font",
+		Description:  "JetBrains Mono patched with Nerd Fonts icons",
+		Repo:         nerdFontsRepo,
+		AssetPattern: regexp.MustCompile(`^JetBrainsMono\.zip$`),
+		InstallDir:   "JetBrainsMonoNerdFont",
+	},
+	{
+		Name:         "fira-code-nerd",
+		Title:        "FiraCode Nerd Font",
+		Description:  "Fira Code patched with Nerd Fonts icons",
+		Repo:         nerdFontsRepo,
+		AssetPattern: regexp.MustCompile(`^FiraCode\.zip$`),
+		InstallDir:   "FiraCodeNerdFont",
+	},
+	{
+		Name:         "cascadia-code-nerd",
+		Title:        "CaskaydiaCove Nerd Font",
+		Description:  "Cascadia Code patched with Nerd Fonts icons",
+		Repo:         nerdFontsRepo,
+		AssetPattern: regexp.MustCompile(`^CascadiaCode\.zip$`),
+		InstallDir:   "CaskaydiaCoveNerdFont",
+	},
+}
+
+// FindFont returns the font from the registry by its name
+func FindFont(name string) (FontSpec, error) {
+	for _, spec := range Fonts {
+		if spec.Name == name {
+			return spec, nil
+		}
+	}
+	return FontSpec{}, fmt.Errorf("unknown font %q, run `devrig install font --list` to see available fonts", name)
+}
+
+// FontNames returns the sorted names of the fonts in the registry
+func FontNames() []string {
+	names := make([]string, 0, len(Fonts))
+	for _, spec := range Fonts {
+		names = append(names, spec.Name)
+	}
+	sort.Strings(names)
+	return names
+}
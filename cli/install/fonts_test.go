@@ -0,0 +1,171 @@
+package install
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/extract"
+)
+
+// TestFonts_Registry tests that the fonts of the registry are complete and match their release assets
+func TestFonts_Registry(t *testing.T) {
+	assets := map[string]string{
+		"jetbrains-mono":      "JetBrainsMono-2.304.zip",
+		"fira-code":           "Fira_Code_v6.2.zip",
+		"cascadia-code":       "CascadiaCode-2407.24.zip",
+		"jetbrains-mono-nerd": "JetBrainsMono.zip",
+		"fira-code-nerd":      "FiraCode.zip",
+		"cascadia-code-nerd":  "CascadiaCode.zip",
+	}
+
+	seen := map[string]bool{}
+	for _, spec := range Fonts {
+		if seen[spec.Name] {
+			t.Errorf("Duplicate font %s", spec.Name)
+		}
+		seen[spec.Name] = true
+
+		if spec.Title == "" || spec.Repo == "" || spec.InstallDir == "" {
+			t.Errorf("Font %s is incomplete: %+v", spec.Name, spec)
+		}
+
+		asset, ok := assets[spec.Name]
+		if !ok {
+			t.Errorf("No sample asset for font %s", spec.Name)
+			continue
+		}
+		if !spec.matchesAsset(asset) {
+			t.Errorf("Font %s does not match its asset %s", spec.Name, asset)
+		}
+		if spec.matchesAsset(asset + ".sha256") {
+			t.Errorf("Font %s matches the checksum asset of %s", spec.Name, asset)
+		}
+	}
+
+	// Nerd Fonts publish all families in one release, each font must pick only its own zip
+	nerd, err := FindFont("fira-code-nerd")
+	if err != nil {
+		t.Fatalf("Failed to find font: %v", err)
+	}
+	if nerd.matchesAsset("JetBrainsMono.zip") {
+		t.Error("Expected fira-code-nerd to ignore the JetBrainsMono.zip asset")
+	}
+}
+
+// TestFindFont_Unknown tests the error for a font that is not in the registry
+func TestFindFont_Unknown(t *testing.T) {
+	_, err := FindFont("comic-sans")
+	if err == nil {
+		t.Fatal("Expected error for unknown font")
+	}
+	if !strings.Contains(err.Error(), "--list") {
+		t.Errorf("Expected error to suggest --list, got: %v", err)
+	}
+}
+
+// TestFontCommand_List tests that --list prints all fonts of the registry
+func TestFontCommand_List(t *testing.T) {
+	cmd := NewFontCommand("1.0.0")
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--list"})
+
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Failed to list fonts: %v", err)
+	}
+
+	for _, spec := range Fonts {
+		if !strings.Contains(out.String(), spec.Name) || !strings.Contains(out.String(), spec.Repo) {
+			t.Errorf("Expected %s in the list, got:\n%s", spec.Name, out.String())
+		}
+	}
+}
+
+// TestFontCommand_RequiresName tests that the font command fails without a font name
+func TestFontCommand_RequiresName(t *testing.T) {
+	cmd := NewFontCommand("1.0.0")
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{})
+
+	if err := cmd.Execute(); err == nil {
+		t.Fatal("Expected error without a font name")
+	}
+}
+
+// TestExtractFonts_FontDir tests that only the TTF files of the font directory are extracted
+func TestExtractFonts_FontDir(t *testing.T) {
+	tempDir := t.TempDir()
+	zipPath := filepath.Join(tempDir, "Fira_Code_v6.2.zip")
+
+	zipFile, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatalf("Failed to create test zip: %v", err)
+	}
+	zipWriter := zip.NewWriter(zipFile)
+	for _, name := range []string{"ttf/FiraCode-Regular.ttf", "variable_ttf/FiraCode-VF.ttf", "woff2/FiraCode-Regular.woff2"} {
+		w, err := zipWriter.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create entry %s in zip: %v", name, err)
+		}
+		if _, err := w.Write([]byte("mock content")); err != nil {
+			t.Fatalf("Failed to write entry %s: %v", name, err)
+		}
+	}
+	zipWriter.Close()
+	zipFile.Close()
+
+	spec, err := FindFont("fira-code")
+	if err != nil {
+		t.Fatalf("Failed to find font: %v", err)
+	}
+	installer := &GitHubFontInstaller{spec: spec}
+	fontsDir := filepath.Join(tempDir, "fonts")
+	if err := installer.extractFonts(zipPath, fontsDir); err != nil {
+		t.Fatalf("Failed to extract fonts: %v", err)
+	}
+
+	entries, err := os.ReadDir(fontsDir)
+	if err != nil {
+		t.Fatalf("Failed to read fonts directory: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "FiraCode-Regular.ttf" {
+		t.Errorf("Expected only FiraCode-Regular.ttf to be extracted, got %v", entries)
+	}
+}
+
+// TestExtractFonts_RejectsEscapingEntries tests that an archive with a traversal entry is not trusted
+func TestExtractFonts_RejectsEscapingEntries(t *testing.T) {
+	tempDir := t.TempDir()
+	zipPath := filepath.Join(tempDir, "evil.zip")
+
+	zipFile, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatalf("Failed to create test zip: %v", err)
+	}
+	zipWriter := zip.NewWriter(zipFile)
+	w, err := zipWriter.Create("ttf/../../../evil.ttf")
+	if err != nil {
+		t.Fatalf("Failed to create entry in zip: %v", err)
+	}
+	if _, err := w.Write([]byte("mock content")); err != nil {
+		t.Fatalf("Failed to write entry: %v", err)
+	}
+	zipWriter.Close()
+	zipFile.Close()
+
+	spec, err := FindFont("fira-code")
+	if err != nil {
+		t.Fatalf("Failed to find font: %v", err)
+	}
+	installer := &GitHubFontInstaller{spec: spec}
+	var unsafeErr *extract.UnsafeEntryError
+	if err := installer.extractFonts(zipPath, filepath.Join(tempDir, "fonts")); !errors.As(err, &unsafeErr) {
+		t.Errorf("Expected UnsafeEntryError, got: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// GoToolchain resolves Go releases from go.dev
+type GoToolchain struct {
+	// APIURL lists the releases, https://go.dev/dl/?mode=json&include=all by default
+	APIURL string
+	// DownloadBaseURL hosts the archives, https://go.dev/dl by default
+	DownloadBaseURL string
+}
+
+type goRelease struct {
+	Version string `json:"version"`
+	Stable  bool   `json:"stable"`
+	Files   []struct {
+		FileName string `json:"filename"`
+		OS       string `json:"os"`
+		Arch     string `json:"arch"`
+		SHA256   string `json:"sha256"`
+		Kind     string `json:"kind"`
+	} `json:"files"`
+}
+
+func (g *GoToolchain) Name() string           { return "go" }
+func (g *GoToolchain) Title() string          { return "Go" }
+func (g *GoToolchain) DefaultVersion() string { return "latest" }
+func (g *GoToolchain) Source() string         { return g.apiURL() }
+
+// Resolve finds the release: an exact version like 1.22.1, the newest stable patch of a line like 1.22,
+// or the newest stable release with latest
+func (g *GoToolchain) Resolve(ctx context.Context, version string, goos string, goarch string) (*ToolchainRelease, error) {
+	version = strings.TrimPrefix(version, "go")
+
+	var releases []goRelease
+	if err := getJSON(ctx, nil, g.apiURL(), &releases); err != nil {
+		return nil, err
+	}
+
+	for _, release := range releases {
+		if !matchesGoVersion(release, version) {
+			continue
+		}
+		for _, file := range release.Files {
+			if file.Kind == "archive" && file.OS == goos && file.Arch == goarch {
+				return &ToolchainRelease{Version: strings.TrimPrefix(release.Version, "go"),
+					URL: g.downloadBaseURL() + "/" + file.FileName, FileName: file.FileName, Checksum: file.SHA256}, nil
+			}
+		}
+		return nil, fmt.Errorf("Go %s has no archive for %s/%s", release.Version, goos, goarch)
+	}
+	return nil, fmt.Errorf("no Go release matches %s", version)
+}
+
+// matchesGoVersion checks the release against an exact version, a line like 1.22 or latest.
+// Only an exact version selects unstable releases.
+func matchesGoVersion(release goRelease, version string) bool {
+	releaseVersion := strings.TrimPrefix(release.Version, "go")
+	if releaseVersion == version {
+		return true
+	}
+	if !release.Stable {
+		return false
+	}
+	return version == "latest" || strings.HasPrefix(releaseVersion, version+".")
+}
+
+func (g *GoToolchain) apiURL() string {
+	if g.APIURL != "" {
+		return g.APIURL
+	}
+	return "https://go.dev/dl/?mode=json&include=all"
+}
+
+func (g *GoToolchain) downloadBaseURL() string {
+	if g.DownloadBaseURL != "" {
+		return strings.TrimSuffix(g.DownloadBaseURL, "/")
+	}
+	return "https://go.dev/dl"
+}
+
+func (g *GoToolchain) BinDir(home string, goos string) string {
+	return filepath.Join(home, "bin")
+}
+
+func (g *GoToolchain) Executable(home string, goos string) string {
+	if goos == "windows" {
+		return filepath.Join(home, "bin", "go.exe")
+	}
+	return filepath.Join(home, "bin", "go")
+}
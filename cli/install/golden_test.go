@@ -0,0 +1,20 @@
+package install
+
+import (
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/golden"
+)
+
+func TestGolden_PathSnippet(t *testing.T) {
+	var snippets strings.Builder
+	for _, shell := range ShellNames {
+		snippet, err := PathSnippet(shell, "/home/user/project/.devrig/toolchains/node-20.11.0/bin")
+		if err != nil {
+			t.Fatalf("Failed to create the %s snippet: %v", shell, err)
+		}
+		snippets.WriteString("# " + shell + "\n" + snippet + "\n")
+	}
+	golden.AssertString(t, "path-snippets", snippets.String())
+}
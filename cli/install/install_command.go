@@ -2,39 +2,100 @@ package install
 
 import (
 	"fmt"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/configservice"
 )
 
+const allUsersFlagUsage = "Install the fonts for all users into %WINDIR%\\Fonts (Windows only, requires administrator rights)"
+
 // NewInstallCommand creates the install command with subcommands
-func NewInstallCommand(version string) *cobra.Command {
+func NewInstallCommand(version string, configs func() configservice.ConfigService, configPath func() string) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "install",
 		Short: "Install fonts and development tools",
 		Long: `Install various fonts and development tools.
 
 Available subcommands:
+  font           - Install a font from the registry (latest version)
   jetbrains-mono - Install JetBrains Mono font (latest version)
+  jdk            - Install a JDK (Temurin or JetBrains Runtime) into .devrig/tools
+  node           - Install Node.js into .devrig/tools
+  go             - Install Go into .devrig/tools
 
 Examples:
+  devrig install font --list
+  devrig install font fira-code
   devrig install jetbrains-mono
+  devrig install jdk --version 21
+  devrig install node --version 20 --shell bash
 `,
 		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("Please specify a package to install.")
-			fmt.Println("")
+			cmd.Println("Please specify a package to install.")
+			cmd.Println("")
 			cmd.HelpFunc()(cmd, args)
 		},
 	}
 
 	// Add subcommands
+	cmd.AddCommand(NewFontCommand(version))
 	cmd.AddCommand(NewJetBrainsMonoCommand(version))
+	cmd.AddCommand(NewJdkCommand(configs, configPath))
+	for _, name := range ToolchainNames() {
+		cmd.AddCommand(NewToolchainCommand(Toolchains[name], configs, configPath))
+	}
+
+	return cmd
+}
+
+// NewFontCommand creates the font subcommand
+func NewFontCommand(version string) *cobra.Command {
+	var list bool
+	var allUsers bool
+	cmd := &cobra.Command{
+		Use:   "font <name>",
+		Short: "Install a font from the registry",
+		Long: fmt.Sprintf(`Install a font from the registry (latest version).
+
+Fonts are downloaded from the GitHub releases of the font and verified
+against the checksums of the release before installation.
+
+Available fonts: %s
+
+Examples:
+  devrig install font --list
+  devrig install font cascadia-code
+  devrig install font jetbrains-mono-nerd
+`, strings.Join(FontNames(), ", ")),
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: FontNames(),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if list {
+				return listFonts(cmd)
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("font name is required, run `devrig install font --list` to see available fonts")
+			}
+
+			spec, err := FindFont(args[0])
+			if err != nil {
+				return err
+			}
+			return installFont(cmd, spec, version, allUsers)
+		},
+	}
 
+	cmd.Flags().BoolVar(&list, "list", false, "List fonts available for installation")
+	cmd.Flags().BoolVar(&allUsers, "all-users", false, allUsersFlagUsage)
 	return cmd
 }
 
 // NewJetBrainsMonoCommand creates the jetbrains-mono subcommand
 func NewJetBrainsMonoCommand(version string) *cobra.Command {
-	return &cobra.Command{
+	var allUsers bool
+	cmd := &cobra.Command{
 		Use:   "jetbrains-mono",
 		Short: "Install JetBrains Mono font",
 		Long: `Install JetBrains Mono font (latest version).
@@ -42,27 +103,44 @@ func NewJetBrainsMonoCommand(version string) *cobra.Command {
 JetBrains Mono is a free and open-source typeface designed for developers.
 It is downloaded from the official JetBrains GitHub repository.
 
+This is the same as devrig install font jetbrains-mono.
+
 Examples:
   devrig install jetbrains-mono
 `,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return installJetBrainsMono(cmd, args, version)
+			return installFont(cmd, JetBrainsMono, version, allUsers)
 		},
 	}
+
+	cmd.Flags().BoolVar(&allUsers, "all-users", false, allUsersFlagUsage)
+	return cmd
+}
+
+// listFonts prints the fonts of the registry
+func listFonts(cmd *cobra.Command) error {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tFONT\tSOURCE")
+	for _, name := range FontNames() {
+		spec, _ := FindFont(name)
+		_, _ = fmt.Fprintf(w, "%s\t%s\tgithub.com/%s\n", spec.Name, spec.Title, spec.Repo)
+	}
+	return w.Flush()
 }
 
-func installJetBrainsMono(cmd *cobra.Command, args []string, version string) error {
-	cmd.Println("Installing JetBrains Mono font...")
+func installFont(cmd *cobra.Command, spec FontSpec, version string, allUsers bool) error {
+	cmd.Printf("Installing %s font...\n", spec.Title)
 
-	installer, err := NewJetBrainsMonoInstaller(version)
+	installer, err := NewFontInstaller(spec, version)
 	if err != nil {
 		return fmt.Errorf("failed to create installer: %w", err)
 	}
+	installer.AllUsers = allUsers
 
 	if err := installer.Install(cmd); err != nil {
 		return fmt.Errorf("installation failed: %w", err)
 	}
 
-	cmd.Println("JetBrains Mono font installed successfully!")
+	cmd.Printf("%s font installed successfully!\n", spec.Title)
 	return nil
 }
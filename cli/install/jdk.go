@@ -0,0 +1,298 @@
+package install
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// DefaultJdkVendor is installed when no vendor is specified
+const DefaultJdkVendor = "temurin"
+
+// DefaultJdkVersion is the feature release installed when neither the command nor devrig.yaml pin a version
+const DefaultJdkVersion = "21"
+
+// JdkRelease is a JDK build resolved for the platform
+type JdkRelease struct {
+	Vendor string
+	// Version is the full version of the build, e.g. 21.0.5+11
+	Version  string
+	URL      string
+	FileName string
+	// Checksum is the SHA-256 of the archive, empty when the vendor publishes it at ChecksumURL
+	Checksum    string
+	ChecksumURL string
+}
+
+// JdkResolver finds a JDK build of a vendor.
+// The version is either a feature release (21) or an exact version of the vendor.
+type JdkResolver interface {
+	Resolve(ctx context.Context, version string, goos string, goarch string) (*JdkRelease, error)
+	// Source returns the API the builds are resolved with
+	Source() string
+}
+
+// JdkVendors lists the supported JDK distributions
+var JdkVendors = map[string]func() JdkResolver{
+	"temurin": func() JdkResolver { return &TemurinResolver{} },
+	"jbr":     func() JdkResolver { return &JbrResolver{} },
+}
+
+// JdkVendorNames returns the supported JDK vendors sorted by name
+func JdkVendorNames() []string {
+	names := make([]string, 0, len(JdkVendors))
+	for name := range JdkVendors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FindJdkResolver returns the resolver of the vendor
+func FindJdkResolver(vendor string) (JdkResolver, error) {
+	factory, ok := JdkVendors[strings.ToLower(vendor)]
+	if !ok {
+		return nil, fmt.Errorf("unknown JDK vendor %s, expected one of: %s", vendor, strings.Join(JdkVendorNames(), ", "))
+	}
+	return factory(), nil
+}
+
+// featureVersionPattern matches a plain feature release like 17 or 21
+var featureVersionPattern = regexp.MustCompile(`^[0-9]+$`)
+
+// TemurinResolver resolves Eclipse Temurin builds with the Adoptium API
+type TemurinResolver struct {
+	// BaseURL of the Adoptium API, https://api.adoptium.net by default
+	BaseURL string
+	Client  *http.Client
+}
+
+type temurinAsset struct {
+	Binary struct {
+		Package struct {
+			Name     string `json:"name"`
+			Link     string `json:"link"`
+			Checksum string `json:"checksum"`
+		} `json:"package"`
+	} `json:"binary"`
+	ReleaseName string `json:"release_name"`
+}
+
+type temurinRelease struct {
+	Binaries []struct {
+		Package struct {
+			Name     string `json:"name"`
+			Link     string `json:"link"`
+			Checksum string `json:"checksum"`
+		} `json:"package"`
+	} `json:"binaries"`
+	ReleaseName string `json:"release_name"`
+}
+
+// Resolve finds the latest build of the feature release or the exact release, e.g. 21.0.5+11
+func (r *TemurinResolver) Resolve(ctx context.Context, version string, goos string, goarch string) (*JdkRelease, error) {
+	osName, arch, err := temurinPlatform(goos, goarch)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("architecture", arch)
+	query.Set("image_type", "jdk")
+	query.Set("os", osName)
+
+	if featureVersionPattern.MatchString(version) {
+		query.Set("vendor", "eclipse")
+		apiURL := fmt.Sprintf("%s/v3/assets/latest/%s/hotspot?%s", r.baseURL(), version, query.Encode())
+
+		var assets []temurinAsset
+		if err := getJSON(ctx, r.Client, apiURL, &assets); err != nil {
+			return nil, err
+		}
+		if len(assets) == 0 || assets[0].Binary.Package.Link == "" {
+			return nil, fmt.Errorf("no Temurin %s build for %s/%s", version, goos, goarch)
+		}
+		pkg := assets[0].Binary.Package
+		return &JdkRelease{Vendor: "temurin", Version: strings.TrimPrefix(assets[0].ReleaseName, "jdk-"),
+			URL: pkg.Link, FileName: pkg.Name, Checksum: strings.ToLower(pkg.Checksum)}, nil
+	}
+
+	apiURL := fmt.Sprintf("%s/v3/assets/release_name/eclipse/%s?%s", r.baseURL(),
+		url.PathEscape("jdk-"+strings.TrimPrefix(version, "jdk-")), query.Encode())
+
+	var release temurinRelease
+	if err := getJSON(ctx, r.Client, apiURL, &release); err != nil {
+		return nil, err
+	}
+	if len(release.Binaries) == 0 || release.Binaries[0].Package.Link == "" {
+		return nil, fmt.Errorf("no Temurin %s build for %s/%s", version, goos, goarch)
+	}
+	pkg := release.Binaries[0].Package
+	return &JdkRelease{Vendor: "temurin", Version: strings.TrimPrefix(release.ReleaseName, "jdk-"),
+		URL: pkg.Link, FileName: pkg.Name, Checksum: strings.ToLower(pkg.Checksum)}, nil
+}
+
+// Source returns the API the builds are resolved with
+func (r *TemurinResolver) Source() string {
+	return r.baseURL()
+}
+
+func (r *TemurinResolver) baseURL() string {
+	if r.BaseURL != "" {
+		return strings.TrimSuffix(r.BaseURL, "/")
+	}
+	return "https://api.adoptium.net"
+}
+
+func temurinPlatform(goos string, goarch string) (string, string, error) {
+	osNames := map[string]string{"linux": "linux", "darwin": "mac", "windows": "windows"}
+	arches := map[string]string{"amd64": "x64", "arm64": "aarch64"}
+	osName, okOS := osNames[goos]
+	arch, okArch := arches[goarch]
+	if !okOS || !okArch {
+		return "", "", &devrigErrors.UnsupportedPlatformError{OS: goos, Arch: goarch}
+	}
+	return osName, arch, nil
+}
+
+// JbrResolver resolves JetBrains Runtime builds from the GitHub releases of JetBrains/JetBrainsRuntime
+type JbrResolver struct {
+	// APIURL lists the releases, the GitHub API by default
+	APIURL string
+	// DownloadBaseURL hosts the archives, the JetBrains CDN by default
+	DownloadBaseURL string
+	Client          *http.Client
+}
+
+// jbrTagPattern matches the release tags, e.g. jbr-release-21.0.5b631.8
+var jbrTagPattern = regexp.MustCompile(`^jbr-release-([0-9][0-9.]*)b([0-9][0-9.]*)$`)
+
+type jbrGitHubRelease struct {
+	TagName    string `json:"tag_name"`
+	Prerelease bool   `json:"prerelease"`
+	Draft      bool   `json:"draft"`
+}
+
+// Resolve finds the latest release of the feature version or the exact build, e.g. 21.0.5b631.8
+func (r *JbrResolver) Resolve(ctx context.Context, version string, goos string, goarch string) (*JdkRelease, error) {
+	osName, arch, err := jbrPlatform(goos, goarch)
+	if err != nil {
+		return nil, err
+	}
+
+	javaVersion, build := "", ""
+	if match := jbrTagPattern.FindStringSubmatch("jbr-release-" + version); match != nil {
+		javaVersion, build = match[1], match[2]
+	} else if featureVersionPattern.MatchString(version) {
+		if javaVersion, build, err = r.latestRelease(ctx, version); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("invalid JetBrains Runtime version %s, expected a feature release like 21 or a build like 21.0.5b631.8", version)
+	}
+
+	fileName := fmt.Sprintf("jbrsdk-%s-%s-%s-b%s.tar.gz", javaVersion, osName, arch, build)
+	downloadURL := r.downloadBaseURL() + "/" + fileName
+	return &JdkRelease{Vendor: "jbr", Version: javaVersion + "b" + build,
+		URL: downloadURL, FileName: fileName, ChecksumURL: downloadURL + ".checksum"}, nil
+}
+
+// latestRelease returns the java version and build of the newest stable release of the feature version
+func (r *JbrResolver) latestRelease(ctx context.Context, feature string) (string, string, error) {
+	var releases []jbrGitHubRelease
+	if err := getJSON(ctx, r.Client, r.apiURL(), &releases); err != nil {
+		return "", "", err
+	}
+
+	for _, release := range releases {
+		if release.Prerelease || release.Draft {
+			continue
+		}
+		match := jbrTagPattern.FindStringSubmatch(release.TagName)
+		if match == nil {
+			continue
+		}
+		if match[1] == feature || strings.HasPrefix(match[1], feature+".") {
+			return match[1], match[2], nil
+		}
+	}
+	return "", "", fmt.Errorf("no JetBrains Runtime %s release found", feature)
+}
+
+// Source returns the API the builds are resolved with
+func (r *JbrResolver) Source() string {
+	return r.apiURL()
+}
+
+func (r *JbrResolver) apiURL() string {
+	if r.APIURL != "" {
+		return r.APIURL
+	}
+	return "https://api.github.com/repos/JetBrains/JetBrainsRuntime/releases?per_page=100"
+}
+
+func (r *JbrResolver) downloadBaseURL() string {
+	if r.DownloadBaseURL != "" {
+		return strings.TrimSuffix(r.DownloadBaseURL, "/")
+	}
+	return "https://cache-redirector.jetbrains.com/intellij-jbr"
+}
+
+func jbrPlatform(goos string, goarch string) (string, string, error) {
+	osNames := map[string]string{"linux": "linux", "darwin": "osx", "windows": "windows"}
+	arches := map[string]string{"amd64": "x64", "arm64": "aarch64"}
+	osName, okOS := osNames[goos]
+	arch, okArch := arches[goarch]
+	if !okOS || !okArch {
+		return "", "", &devrigErrors.UnsupportedPlatformError{OS: goos, Arch: goarch}
+	}
+	return osName, arch, nil
+}
+
+// getJSON fetches the URL and decodes the JSON response into target
+func getJSON(ctx context.Context, client *http.Client, apiURL string, target any) error {
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return &devrigErrors.NetworkError{URL: apiURL, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("no release found at %s", apiURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &devrigErrors.NetworkError{URL: apiURL, Err: fmt.Errorf("unexpected status code: %d", resp.StatusCode)}
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(target); err != nil {
+		return fmt.Errorf("failed to parse response of %s: %w", apiURL, err)
+	}
+	return nil
+}
+
+// archiveFileName returns the file name of the download URL
+func archiveFileName(downloadURL string) string {
+	if parsed, err := url.Parse(downloadURL); err == nil {
+		return path.Base(parsed.Path)
+	}
+	return path.Base(downloadURL)
+}
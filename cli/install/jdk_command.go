@@ -0,0 +1,121 @@
+package install
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/lock"
+)
+
+// NewJdkCommand creates the jdk subcommand
+func NewJdkCommand(configs func() configservice.ConfigService, configPath func() string) *cobra.Command {
+	var vendor string
+	var version string
+	var frozen bool
+	cmd := &cobra.Command{
+		Use:   "jdk",
+		Short: "Install a JDK into the devrig tools folder",
+		Long: fmt.Sprintf(`Install a JDK into .devrig/tools and record it in the jdk section of devrig.yaml.
+
+Builds are resolved with the public API of the vendor, downloaded,
+verified against the published checksum and unpacked. Other tooling
+reads the Java home from devrig.yaml:
+
+  jdk:
+    vendor: temurin
+    version: 21.0.5+11
+    path: .devrig/tools/jdk-temurin-21.0.5_11
+
+The version is a feature release (21) or an exact build of the vendor
+(21.0.5+11 for Temurin, 21.0.5b631.8 for JetBrains Runtime). Without
+--version the java pin of the tools section is used, then the version
+of the jdk section, or %s.
+
+The build locked in devrig.lock is installed when the lock has the
+requested version, see devrig lock. With --frozen the command fails
+if the lock is missing or out of date.
+
+Available vendors: %s
+
+Examples:
+  devrig install jdk
+  devrig install jdk --version 17
+  devrig install jdk --vendor jbr --version 21
+`, DefaultJdkVersion, strings.Join(JdkVendorNames(), ", ")),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			service := configs()
+			if version == "" {
+				pinnedVendor, pinned, err := pinnedJdkVersion(service)
+				if err != nil {
+					return err
+				}
+				version = pinned
+				if pinnedVendor != "" && !cmd.Flags().Changed("vendor") {
+					vendor = pinnedVendor
+				}
+			}
+
+			installer, err := NewJdkInstaller(vendor, layout.ResolveDevrigHome(configPath()))
+			if err != nil {
+				return err
+			}
+			if installer.Lock, err = lock.Load(lock.ResolvePath(configPath())); err != nil {
+				return err
+			}
+			installer.Frozen = frozen
+
+			jdk, err := installer.Install(cmd, version)
+			if err != nil {
+				return fmt.Errorf("installation failed: %w", err)
+			}
+
+			section := &configservice.JdkSection{
+				Vendor:  jdk.Vendor,
+				Version: jdk.Version,
+				Path:    jdkConfigPath(configPath(), jdk.Home),
+			}
+			if err := service.Jdk().UpdateJdk(section); err != nil {
+				return fmt.Errorf("failed to record JDK in devrig.yaml: %w", err)
+			}
+
+			cmd.Printf("%s JDK %s installed to %s\n", jdk.Vendor, jdk.Version, jdk.Home)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&vendor, "vendor", DefaultJdkVendor, "JDK vendor: "+strings.Join(JdkVendorNames(), ", "))
+	cmd.Flags().StringVar(&version, "version", "", "Feature release or exact version of the JDK")
+	cmd.Flags().BoolVar(&frozen, "frozen", false, "Fail if devrig.lock has no build of the requested version")
+	return cmd
+}
+
+// pinnedJdkVersion returns the JDK pinned in devrig.yaml, or the default version
+func pinnedJdkVersion(service configservice.ConfigService) (string, string, error) {
+	vendor, version, err := PinnedJdk(service)
+	if err != nil || version != "" {
+		return vendor, version, err
+	}
+	return "", DefaultJdkVersion, nil
+}
+
+// jdkConfigPath returns the Java home relative to the folder of devrig.yaml, or absolute when it is outside of the project
+func jdkConfigPath(configPath string, home string) string {
+	projectDir, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return home
+	}
+	absHome, err := filepath.Abs(home)
+	if err != nil {
+		return home
+	}
+	relative, err := filepath.Rel(projectDir, absHome)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return absHome
+	}
+	return filepath.ToSlash(relative)
+}
@@ -0,0 +1,137 @@
+package install
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/lock"
+	"jonnyzzz.com/devrig.dev/offline"
+)
+
+// InstalledJdk is a JDK unpacked into the devrig tools folder
+type InstalledJdk struct {
+	Vendor  string
+	Version string
+	// Home is the Java home of the JDK, the folder with bin/java
+	Home string
+}
+
+// JdkInstaller downloads, verifies and unpacks JDK builds into .devrig/tools
+type JdkInstaller struct {
+	Vendor     string
+	Resolver   JdkResolver
+	DevrigHome string
+	GOOS       string
+	GOARCH     string
+	Client     *http.Client
+	// Lock is devrig.lock of the project, the locked build is installed instead of resolving the version
+	Lock *lock.Lockfile
+	// Frozen fails the installation when the version is not locked
+	Frozen bool
+}
+
+// NewJdkInstaller creates an installer of the vendor for the current platform
+func NewJdkInstaller(vendor string, devrigHome string) (*JdkInstaller, error) {
+	resolver, err := FindJdkResolver(vendor)
+	if err != nil {
+		return nil, err
+	}
+	return &JdkInstaller{Vendor: strings.ToLower(vendor), Resolver: resolver, DevrigHome: devrigHome, GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}, nil
+}
+
+// Install resolves the version and unpacks the JDK, an already installed build is reused
+func (j *JdkInstaller) Install(cmd *cobra.Command, version string) (*InstalledJdk, error) {
+	locked, err := j.Lock.Resolve(JdkLockName, JdkLockRequest(j.Vendor, version), lock.Platform(j.GOOS, j.GOARCH), j.Frozen)
+	if err != nil {
+		return nil, err
+	}
+	if locked != nil {
+		version = locked.Version
+	}
+
+	// An exact version is looked up locally first, so it works without network access
+	if !featureVersionPattern.MatchString(version) {
+		if installed := j.installed(j.Vendor, version); installed != nil {
+			cmd.Printf("%s JDK %s is already installed\n", installed.Vendor, installed.Version)
+			return installed, nil
+		}
+	}
+
+	release, err := j.resolve(cmd, version, locked)
+	if err != nil {
+		return nil, err
+	}
+
+	if installed := j.installed(release.Vendor, release.Version); installed != nil {
+		cmd.Printf("%s JDK %s is already installed\n", installed.Vendor, installed.Version)
+		return installed, nil
+	}
+	target := layout.ResolveToolHome(j.DevrigHome, "jdk-"+release.Vendor, release.Version)
+
+	archive := toolArchive{Title: release.Vendor + " JDK " + release.Version, URL: release.URL, FileName: release.FileName,
+		Checksum: release.Checksum, ChecksumURL: release.ChecksumURL, Name: "jdk-" + release.Vendor, Version: release.Version}
+	err = installToolArchive(cmd, j.Client, j.DevrigHome, archive, target, func(root string) error {
+		if _, err := os.Stat(javaBinary(javaHome(root, j.GOOS), j.GOOS)); err != nil {
+			return fmt.Errorf("the archive of %s has no bin/java", archive.Title)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &InstalledJdk{Vendor: release.Vendor, Version: release.Version, Home: javaHome(target, j.GOOS)}, nil
+}
+
+// resolve returns the locked build, or resolves the version with the API of the vendor
+func (j *JdkInstaller) resolve(cmd *cobra.Command, version string, locked *lock.Artifact) (*JdkRelease, error) {
+	if locked != nil {
+		if err := offline.Check(j.Vendor+" JDK "+locked.Version, locked.URL, "install the JDK on a machine with network access"); err != nil {
+			return nil, err
+		}
+		return &JdkRelease{Vendor: j.Vendor, Version: locked.Version, URL: locked.URL, FileName: locked.FileName, Checksum: locked.Checksum}, nil
+	}
+
+	if err := offline.Check(j.Vendor+" JDK "+version, j.Resolver.Source(), "install the JDK on a machine with network access"); err != nil {
+		return nil, err
+	}
+
+	cmd.Printf("Resolving %s JDK %s...\n", j.Vendor, version)
+	release, err := j.Resolver.Resolve(cmd.Context(), version, j.GOOS, j.GOARCH)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s JDK %s: %w", j.Vendor, version, err)
+	}
+	return release, nil
+}
+
+// installed returns the JDK if it is already unpacked in the tools folder, or nil
+func (j *JdkInstaller) installed(vendor string, version string) *InstalledJdk {
+	home := javaHome(layout.ResolveToolHome(j.DevrigHome, "jdk-"+vendor, strings.TrimPrefix(version, "jdk-")), j.GOOS)
+	if _, err := os.Stat(javaBinary(home, j.GOOS)); err != nil {
+		return nil
+	}
+	return &InstalledJdk{Vendor: vendor, Version: strings.TrimPrefix(version, "jdk-"), Home: home}
+}
+
+// javaHome returns the Java home inside the unpacked JDK, macOS builds are bundles with Contents/Home
+func javaHome(root string, goos string) string {
+	if goos == "darwin" {
+		bundleHome := filepath.Join(root, "Contents", "Home")
+		if info, err := os.Stat(bundleHome); err == nil && info.IsDir() {
+			return bundleHome
+		}
+	}
+	return root
+}
+
+func javaBinary(home string, goos string) string {
+	if goos == "windows" {
+		return filepath.Join(home, "bin", "java.exe")
+	}
+	return filepath.Join(home, "bin", "java")
+}
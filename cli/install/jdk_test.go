@@ -0,0 +1,310 @@
+package install
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/lock"
+	"jonnyzzz.com/devrig.dev/offline"
+)
+
+func TestTemurinResolver_FeatureVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/assets/latest/21/hotspot" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		query := r.URL.Query()
+		if query.Get("os") != "mac" || query.Get("architecture") != "aarch64" || query.Get("image_type") != "jdk" {
+			t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+		}
+		_, _ = fmt.Fprint(w, `[{"binary":{"package":{"name":"OpenJDK21U-jdk_aarch64_mac_hotspot_21.0.5_11.tar.gz",
+			"link":"https://example.com/jdk.tar.gz","checksum":"ABCDEF"}},"release_name":"jdk-21.0.5+11"}]`)
+	}))
+	defer server.Close()
+
+	release, err := (&TemurinResolver{BaseURL: server.URL}).Resolve(context.Background(), "21", "darwin", "arm64")
+	if err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+	if release.Version != "21.0.5+11" || release.URL != "https://example.com/jdk.tar.gz" || release.Checksum != "abcdef" {
+		t.Errorf("Unexpected release: %+v", release)
+	}
+}
+
+func TestTemurinResolver_ExactVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/assets/release_name/eclipse/jdk-17.0.13+11" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		_, _ = fmt.Fprint(w, `{"binaries":[{"package":{"name":"jdk.zip","link":"https://example.com/jdk.zip","checksum":"00"}}],
+			"release_name":"jdk-17.0.13+11"}`)
+	}))
+	defer server.Close()
+
+	release, err := (&TemurinResolver{BaseURL: server.URL}).Resolve(context.Background(), "17.0.13+11", "windows", "amd64")
+	if err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+	if release.Version != "17.0.13+11" || release.FileName != "jdk.zip" {
+		t.Errorf("Unexpected release: %+v", release)
+	}
+}
+
+func TestTemurinResolver_UnsupportedPlatform(t *testing.T) {
+	_, err := (&TemurinResolver{}).Resolve(context.Background(), "21", "plan9", "amd64")
+	var platformErr *devrigErrors.UnsupportedPlatformError
+	if !errors.As(err, &platformErr) {
+		t.Errorf("Expected UnsupportedPlatformError, got: %v", err)
+	}
+}
+
+func TestJbrResolver_FeatureVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[
+			{"tag_name":"jbr-release-21.0.6b800.1","prerelease":true},
+			{"tag_name":"jbr-release-17.0.12b1000.1"},
+			{"tag_name":"jbr-release-21.0.5b631.8"},
+			{"tag_name":"jbr-release-21.0.4b600.1"}
+		]`)
+	}))
+	defer server.Close()
+
+	resolver := &JbrResolver{APIURL: server.URL, DownloadBaseURL: "https://cdn.example.com/jbr/"}
+	release, err := resolver.Resolve(context.Background(), "21", "linux", "amd64")
+	if err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+
+	expectedURL := "https://cdn.example.com/jbr/jbrsdk-21.0.5-linux-x64-b631.8.tar.gz"
+	if release.Version != "21.0.5b631.8" || release.URL != expectedURL || release.ChecksumURL != expectedURL+".checksum" {
+		t.Errorf("Unexpected release: %+v", release)
+	}
+}
+
+func TestJbrResolver_ExactVersion(t *testing.T) {
+	resolver := &JbrResolver{APIURL: "http://127.0.0.1:0/unused"}
+	release, err := resolver.Resolve(context.Background(), "17.0.12b1000.1", "darwin", "arm64")
+	if err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+	if release.FileName != "jbrsdk-17.0.12-osx-aarch64-b1000.1.tar.gz" {
+		t.Errorf("Unexpected file name: %s", release.FileName)
+	}
+
+	if _, err := resolver.Resolve(context.Background(), "latest", "linux", "amd64"); err == nil {
+		t.Errorf("Expected an error for an invalid version")
+	}
+}
+
+// fakeJdkResolver returns a fixed release
+type fakeJdkResolver struct {
+	release JdkRelease
+	calls   int
+}
+
+func (r *fakeJdkResolver) Resolve(ctx context.Context, version string, goos string, goarch string) (*JdkRelease, error) {
+	r.calls++
+	release := r.release
+	return &release, nil
+}
+
+func (r *fakeJdkResolver) Source() string {
+	return "https://jdk.example.com"
+}
+
+// serveJdkArchive serves a JDK archive with bin/java and its checksum
+func serveJdkArchive(t *testing.T) (*httptest.Server, []byte) {
+	t.Helper()
+
+	archivePath := filepath.Join(t.TempDir(), "jdk.tar.gz")
+	buildTarGz(t, archivePath, []tarEntry{
+		{name: "jdk-21.0.5+11/bin/java", content: "#!/bin/sh\n", mode: 0755},
+		{name: "jdk-21.0.5+11/release", content: "JAVA_VERSION=21"},
+	})
+	archive, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jdk.tar.gz":
+			_, _ = w.Write(archive)
+		case "/jdk.tar.gz.checksum":
+			sum := sha512.Sum512(archive)
+			_, _ = fmt.Fprintf(w, "%s  jdk.tar.gz\n", hex.EncodeToString(sum[:]))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, archive
+}
+
+func newTestCommand() (*cobra.Command, *bytes.Buffer) {
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	return cmd, &out
+}
+
+func TestJdkInstaller_Install(t *testing.T) {
+	server, archive := serveJdkArchive(t)
+	sum := sha256.Sum256(archive)
+
+	devrigHome := t.TempDir()
+	resolver := &fakeJdkResolver{release: JdkRelease{Vendor: "temurin", Version: "21.0.5+11",
+		URL: server.URL + "/jdk.tar.gz", FileName: "jdk.tar.gz", Checksum: hex.EncodeToString(sum[:])}}
+	installer := &JdkInstaller{Vendor: "temurin", Resolver: resolver, DevrigHome: devrigHome, GOOS: "linux", GOARCH: "amd64"}
+
+	cmd, _ := newTestCommand()
+	jdk, err := installer.Install(cmd, "21")
+	if err != nil {
+		t.Fatalf("Failed to install JDK: %v", err)
+	}
+
+	expectedHome := filepath.Join(devrigHome, "tools", "jdk-temurin-21.0.5_11")
+	if jdk.Home != expectedHome || jdk.Version != "21.0.5+11" {
+		t.Errorf("Unexpected JDK: %+v", jdk)
+	}
+	if _, err := os.Stat(filepath.Join(expectedHome, "bin", "java")); err != nil {
+		t.Errorf("Expected bin/java to be installed: %v", err)
+	}
+
+	// The exact version is found locally without resolving it again
+	if _, err := installer.Install(cmd, "21.0.5+11"); err != nil {
+		t.Fatalf("Failed to reinstall JDK: %v", err)
+	}
+	if resolver.calls != 1 {
+		t.Errorf("Expected the installed JDK to be reused, resolved %d times", resolver.calls)
+	}
+}
+
+func TestJdkInstaller_Install_ChecksumURL(t *testing.T) {
+	server, _ := serveJdkArchive(t)
+
+	resolver := &fakeJdkResolver{release: JdkRelease{Vendor: "jbr", Version: "21.0.5b631.8",
+		URL: server.URL + "/jdk.tar.gz", FileName: "jdk.tar.gz", ChecksumURL: server.URL + "/jdk.tar.gz.checksum"}}
+	installer := &JdkInstaller{Vendor: "jbr", Resolver: resolver, DevrigHome: t.TempDir(), GOOS: "linux", GOARCH: "amd64"}
+
+	cmd, _ := newTestCommand()
+	if _, err := installer.Install(cmd, "21"); err != nil {
+		t.Fatalf("Failed to install JDK: %v", err)
+	}
+}
+
+func TestJdkInstaller_Install_ChecksumMismatch(t *testing.T) {
+	server, _ := serveJdkArchive(t)
+
+	devrigHome := t.TempDir()
+	resolver := &fakeJdkResolver{release: JdkRelease{Vendor: "temurin", Version: "21.0.5+11",
+		URL: server.URL + "/jdk.tar.gz", FileName: "jdk.tar.gz", Checksum: strings.Repeat("0", 64)}}
+	installer := &JdkInstaller{Vendor: "temurin", Resolver: resolver, DevrigHome: devrigHome, GOOS: "linux", GOARCH: "amd64"}
+
+	cmd, _ := newTestCommand()
+	_, err := installer.Install(cmd, "21")
+	var mismatch *devrigErrors.ChecksumMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected ChecksumMismatchError, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(devrigHome, "tools", "jdk-temurin-21.0.5_11")); err == nil {
+		t.Errorf("JDK must not be installed after a checksum mismatch")
+	}
+}
+
+func TestJdkInstaller_Install_Offline(t *testing.T) {
+	t.Setenv(offline.EnvOffline, "1")
+
+	resolver := &fakeJdkResolver{}
+	installer := &JdkInstaller{Vendor: "temurin", Resolver: resolver, DevrigHome: t.TempDir(), GOOS: "linux", GOARCH: "amd64"}
+
+	cmd, _ := newTestCommand()
+	_, err := installer.Install(cmd, "21")
+	var offlineErr *devrigErrors.OfflineError
+	if !errors.As(err, &offlineErr) {
+		t.Errorf("Expected OfflineError, got: %v", err)
+	}
+	if resolver.calls != 0 {
+		t.Errorf("Expected no network access in offline mode")
+	}
+}
+
+func TestJdkCommand_RecordsJdk(t *testing.T) {
+	server, archive := serveJdkArchive(t)
+	sum := sha512.Sum512(archive)
+
+	projectDir := t.TempDir()
+	configPath := filepath.Join(projectDir, "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte("tools:\n  java: temurin-21.0.5+11\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	previous := JdkVendors["temurin"]
+	JdkVendors["temurin"] = func() JdkResolver {
+		return &fakeJdkResolver{release: JdkRelease{Vendor: "temurin", Version: "21.0.5+11",
+			URL: server.URL + "/jdk.tar.gz", Checksum: hex.EncodeToString(sum[:])}}
+	}
+	defer func() { JdkVendors["temurin"] = previous }()
+
+	configs := func() configservice.ConfigService { return configservice.NewConfigService(configPath) }
+	cmd := NewJdkCommand(configs, func() string { return configPath })
+	cmd.SetContext(context.Background())
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Failed to run command: %v", err)
+	}
+
+	jdk, err := configs().Jdk().ReadJdk()
+	if err != nil {
+		t.Fatalf("Failed to read jdk section: %v", err)
+	}
+	if jdk == nil || jdk.Vendor != "temurin" || jdk.Version != "21.0.5+11" || jdk.Path != ".devrig/tools/jdk-temurin-21.0.5_11" {
+		t.Errorf("Unexpected jdk section: %+v", jdk)
+	}
+}
+
+func TestJdkInstaller_Install_Locked(t *testing.T) {
+	server, archive := serveJdkArchive(t)
+	sum := sha256.Sum256(archive)
+
+	lockfile := &lock.Lockfile{}
+	lockfile.Put(lock.Artifact{Name: JdkLockName, Request: "temurin 21", Platform: "linux-amd64", Version: "21.0.5+11",
+		URL: server.URL + "/jdk.tar.gz", FileName: "jdk.tar.gz", Checksum: hex.EncodeToString(sum[:])})
+
+	resolver := &fakeJdkResolver{}
+	installer := &JdkInstaller{Vendor: "temurin", Resolver: resolver, DevrigHome: t.TempDir(), GOOS: "linux", GOARCH: "amd64",
+		Lock: lockfile, Frozen: true}
+
+	cmd, _ := newTestCommand()
+	jdk, err := installer.Install(cmd, "21")
+	if err != nil {
+		t.Fatalf("Failed to install JDK: %v", err)
+	}
+	if jdk.Version != "21.0.5+11" || resolver.calls != 0 {
+		t.Errorf("Expected the locked build without resolving, got %+v after %d calls", jdk, resolver.calls)
+	}
+
+	// A version that is not locked fails in frozen mode
+	_, err = installer.Install(cmd, "17")
+	var outdated *devrigErrors.LockOutdatedError
+	if !errors.As(err, &outdated) {
+		t.Errorf("Expected LockOutdatedError, got: %v", err)
+	}
+}
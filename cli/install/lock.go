@@ -0,0 +1,134 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/lock"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// JdkLockName is the name of the JDK in devrig.lock
+const JdkLockName = "jdk"
+
+// PinnedJdk returns the vendor and version of the JDK pinned in devrig.yaml, the java pin of the tools section
+// or the jdk section recorded by `devrig install jdk`. The version is empty when no JDK is pinned
+func PinnedJdk(service configservice.ConfigService) (string, string, error) {
+	tools, err := service.Tools().ReadTools()
+	if err != nil {
+		return "", "", err
+	}
+
+	if pin := strings.TrimSpace(tools["java"]); pin != "" {
+		for _, vendor := range JdkVendorNames() {
+			if version, ok := strings.CutPrefix(pin, vendor+"-"); ok {
+				return vendor, version, nil
+			}
+		}
+		return DefaultJdkVendor, pin, nil
+	}
+
+	section, err := service.Jdk().ReadJdk()
+	if err != nil {
+		return "", "", err
+	}
+	if section == nil {
+		return "", "", nil
+	}
+	return section.Vendor, section.Version, nil
+}
+
+// JdkLockRequest returns the request the JDK is locked under, e.g. temurin 21
+func JdkLockRequest(vendor string, version string) string {
+	return strings.ToLower(vendor) + " " + version
+}
+
+// LockJdk resolves the JDK build for devrig.lock
+func LockJdk(ctx context.Context, resolver JdkResolver, vendor string, version string, goos string, goarch string) (*lock.Artifact, error) {
+	release, err := resolver.Resolve(ctx, version, goos, goarch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s JDK %s: %w", vendor, version, err)
+	}
+
+	archive := toolArchive{Title: release.Vendor + " JDK " + release.Version, URL: release.URL, FileName: release.FileName,
+		Checksum: release.Checksum, ChecksumURL: release.ChecksumURL}
+	return lockArchive(ctx, JdkLockName, JdkLockRequest(vendor, version), lock.Platform(goos, goarch), release.Version, archive)
+}
+
+// LockToolchain resolves the toolchain build for devrig.lock
+func LockToolchain(ctx context.Context, toolchain Toolchain, version string, goos string, goarch string) (*lock.Artifact, error) {
+	release, err := toolchain.Resolve(ctx, version, goos, goarch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s %s: %w", toolchain.Title(), version, err)
+	}
+
+	archive := toolArchive{Title: toolchain.Title() + " " + release.Version, URL: release.URL, FileName: release.FileName,
+		Checksum: release.Checksum, ChecksumURL: release.ChecksumURL}
+	return lockArchive(ctx, toolchain.Name(), version, lock.Platform(goos, goarch), release.Version, archive)
+}
+
+// lockArchive records the archive with its checksum, a checksum published separately is fetched now,
+// so installing from the lock does not depend on the checksum file
+func lockArchive(ctx context.Context, name string, request string, platform string, version string, archive toolArchive) (*lock.Artifact, error) {
+	fileName := archive.fileName()
+	checksum, err := expectedChecksum(ctx, http.DefaultClient, archive, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve the checksum of %s: %w", archive.Title, err)
+	}
+
+	return &lock.Artifact{
+		Name:     name,
+		Request:  request,
+		Platform: platform,
+		Version:  version,
+		URL:      archive.URL,
+		FileName: fileName,
+		Checksum: checksum,
+	}, nil
+}
+
+// DownloadArtifact downloads the artifact into dir and verifies its checksum. Nothing is unpacked, so the artifacts
+// of another platform can be prepared, e.g. on CI for the machines of the team. A file that is already downloaded
+// with the checksum is kept. Returns the path of the file
+func DownloadArtifact(ctx context.Context, client *http.Client, artifact *lock.Artifact, dir string) (string, error) {
+	if client == nil {
+		client = &http.Client{}
+	}
+	archive := toolArchive{Title: artifact.Name + " " + artifact.Version, URL: artifact.URL, FileName: artifact.FileName, Checksum: artifact.Checksum}
+	fileName := archive.fileName()
+	target := filepath.Join(dir, fileName)
+	if actual, err := fileChecksum(target, artifact.Checksum); err == nil && actual == artifact.Checksum {
+		return target, nil
+	}
+
+	if err := offline.Check(archive.Title, artifact.URL, "download "+archive.Title+" on a machine with network access"); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	// The temp folder is next to the target, so an interrupted download never leaves a partial file behind
+	tempDir, err := tempfile.Mkdir(dir, ".download-*")
+	if err != nil {
+		return "", err
+	}
+	defer tempfile.Remove(tempDir)
+
+	downloaded := filepath.Join(tempDir, fileName)
+	if err := downloadArchive(ctx, client, artifact.URL, downloaded); err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", archive.Title, err)
+	}
+	if _, err := verifyArchive(ctx, client, archive, downloaded, fileName); err != nil {
+		return "", fmt.Errorf("checksum verification failed: %w", err)
+	}
+	if err := os.Rename(downloaded, target); err != nil {
+		return "", fmt.Errorf("failed to move %s into place: %w", fileName, err)
+	}
+	return target, nil
+}
@@ -0,0 +1,113 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// exactNodeVersion matches a full Node.js version like 20.11.0
+var exactNodeVersion = regexp.MustCompile(`^[0-9]+\.[0-9]+\.[0-9]+$`)
+
+// NodeToolchain resolves Node.js releases from nodejs.org
+type NodeToolchain struct {
+	// BaseURL of the release distribution, https://nodejs.org/dist by default
+	BaseURL string
+}
+
+type nodeIndexEntry struct {
+	Version string `json:"version"`
+	// LTS is the codename of an LTS release, or false
+	LTS any `json:"lts"`
+}
+
+func (n *NodeToolchain) Name() string           { return "node" }
+func (n *NodeToolchain) Title() string          { return "Node.js" }
+func (n *NodeToolchain) DefaultVersion() string { return "lts" }
+func (n *NodeToolchain) Source() string         { return n.baseURL() }
+
+// Resolve finds the release: an exact version like 20.11.0, the newest release of a line like 20 or 20.11,
+// the newest LTS release with lts, or the newest release with latest
+func (n *NodeToolchain) Resolve(ctx context.Context, version string, goos string, goarch string) (*ToolchainRelease, error) {
+	osName, arch, ext, err := nodePlatform(goos, goarch)
+	if err != nil {
+		return nil, err
+	}
+
+	version = strings.TrimPrefix(version, "v")
+	if !exactNodeVersion.MatchString(version) {
+		if version, err = n.latestRelease(ctx, version); err != nil {
+			return nil, err
+		}
+	}
+
+	fileName := fmt.Sprintf("node-v%s-%s-%s.%s", version, osName, arch, ext)
+	releaseURL := fmt.Sprintf("%s/v%s", n.baseURL(), version)
+	return &ToolchainRelease{Version: version, URL: releaseURL + "/" + fileName, FileName: fileName,
+		ChecksumURL: releaseURL + "/SHASUMS256.txt"}, nil
+}
+
+// latestRelease returns the newest version matching the selector, the index lists the newest releases first
+func (n *NodeToolchain) latestRelease(ctx context.Context, selector string) (string, error) {
+	var index []nodeIndexEntry
+	if err := getJSON(ctx, nil, n.baseURL()+"/index.json", &index); err != nil {
+		return "", err
+	}
+
+	for _, entry := range index {
+		version := strings.TrimPrefix(entry.Version, "v")
+		switch selector {
+		case "latest":
+			return version, nil
+		case "lts":
+			if lts, ok := entry.LTS.(string); ok && lts != "" {
+				return version, nil
+			}
+		default:
+			if strings.HasPrefix(version, selector+".") {
+				return version, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no Node.js release matches %s", selector)
+}
+
+func (n *NodeToolchain) baseURL() string {
+	if n.BaseURL != "" {
+		return strings.TrimSuffix(n.BaseURL, "/")
+	}
+	return "https://nodejs.org/dist"
+}
+
+// BinDir returns the folder with node and npm, Windows archives keep them in the root
+func (n *NodeToolchain) BinDir(home string, goos string) string {
+	if goos == "windows" {
+		return home
+	}
+	return filepath.Join(home, "bin")
+}
+
+func (n *NodeToolchain) Executable(home string, goos string) string {
+	if goos == "windows" {
+		return filepath.Join(home, "node.exe")
+	}
+	return filepath.Join(home, "bin", "node")
+}
+
+func nodePlatform(goos string, goarch string) (string, string, string, error) {
+	osNames := map[string]string{"linux": "linux", "darwin": "darwin", "windows": "win"}
+	arches := map[string]string{"amd64": "x64", "arm64": "arm64"}
+	osName, okOS := osNames[goos]
+	arch, okArch := arches[goarch]
+	if !okOS || !okArch {
+		return "", "", "", &devrigErrors.UnsupportedPlatformError{OS: goos, Arch: goarch}
+	}
+	if goos == "windows" {
+		return osName, arch, "zip", nil
+	}
+	return osName, arch, "tar.gz", nil
+}
@@ -0,0 +1,254 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/contentstore"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/filelock"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/progress"
+	"jonnyzzz.com/devrig.dev/state"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// toolArchive is a release archive of a tool with its published checksum
+type toolArchive struct {
+	// Title names the tool in messages, e.g. temurin JDK 21.0.5+11
+	Title    string
+	URL      string
+	FileName string
+	// Checksum is the expected checksum of the archive, empty when it is published at ChecksumURL
+	Checksum    string
+	ChecksumURL string
+	// Name and Version are recorded in state.json for the installed tool, e.g. jdk-temurin 21.0.5+11
+	Name    string
+	Version string
+}
+
+// fileName returns the name of the archive, the last segment of the URL when the release does not name it
+func (a toolArchive) fileName() string {
+	if a.FileName == "" {
+		return archiveFileName(a.URL)
+	}
+	return filepath.Base(a.FileName)
+}
+
+// installToolArchive downloads and verifies the archive, then unpacks it into target.
+// The check validates the unpacked root before it is moved into place.
+func installToolArchive(cmd *cobra.Command, client *http.Client, devrigHome string, archive toolArchive, target string, check func(root string) error) error {
+	ctx := cmd.Context()
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	// Another devrig process may install the same tool, the one waiting for the lock reuses its result
+	lock, err := filelock.Acquire(ctx, layout.ResolveLockFile(devrigHome, filepath.Base(target)), "installing "+archive.Title)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	if _, err := os.Stat(target); err == nil && check(target) == nil {
+		cmd.Printf("%s is already installed\n", archive.Title)
+		return nil
+	}
+
+	toolsHome := layout.ResolveToolsHome(devrigHome)
+	if err := os.MkdirAll(toolsHome, 0755); err != nil {
+		return fmt.Errorf("failed to create tools directory: %w", err)
+	}
+
+	// The temp folder is next to the target, so the final rename never crosses filesystems
+	tempDir, err := tempfile.Mkdir(toolsHome, ".download-*")
+	if err != nil {
+		return err
+	}
+	defer tempfile.Remove(tempDir)
+
+	fileName := archive.fileName()
+	root, checksum, shared, err := unpackToolArchive(cmd, client, archive, fileName, tempDir)
+	if err != nil {
+		return err
+	}
+	if err := check(root); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(target); err != nil {
+		return fmt.Errorf("failed to remove incomplete installation: %w", err)
+	}
+	if err := os.Rename(root, target); err != nil {
+		return fmt.Errorf("failed to move %s into place: %w", archive.Title, err)
+	}
+
+	if store := contentstore.FromContext(ctx); store != nil && !shared {
+		if err := store.PutTree(checksum, target); err != nil {
+			logging.FromContext(ctx).Warn("failed to add the tool to the shared cache", "path", target, "error", err)
+		}
+	}
+
+	artifact := state.Artifact{Type: state.TypeTool, Name: archive.Name, Version: archive.Version, Checksum: checksum, Path: target}
+	if err := state.Record(ctx, devrigHome, artifact, time.Now()); err != nil {
+		logging.FromContext(ctx).Warn("failed to record the tool in "+state.FileName, "tool", archive.Title, "error", err)
+	}
+	return nil
+}
+
+// unpackToolArchive links the tool unpacked from the archive with the checksum of the release from the shared cache,
+// or downloads, verifies and extracts the archive in tempDir. Returns the unpacked root, the verified checksum
+// and whether the root is from the shared cache
+func unpackToolArchive(cmd *cobra.Command, client *http.Client, archive toolArchive, fileName string, tempDir string) (string, string, bool, error) {
+	ctx := cmd.Context()
+	// Only a checksum known upfront finds the tool, the checksums published next to the archive need network access
+	if store := contentstore.FromContext(ctx); store != nil && archive.Checksum != "" {
+		checksum, err := validateChecksum(archive.Checksum, fileName)
+		if err != nil {
+			return "", "", false, err
+		}
+		shared := filepath.Join(tempDir, "shared")
+		linked, err := store.LinkTree(checksum, shared)
+		if err != nil {
+			logging.FromContext(ctx).Warn("failed to reuse the tool of the shared cache", "tool", archive.Title, "error", err)
+		}
+		if err == nil && linked {
+			cmd.Printf("Reusing %s from the shared cache %s\n", archive.Title, store.Root())
+			return shared, checksum, true, nil
+		}
+	}
+
+	archivePath := filepath.Join(tempDir, fileName)
+	cmd.Printf("Downloading %s...\n", archive.Title)
+	if err := downloadArchive(ctx, client, archive.URL, archivePath); err != nil {
+		return "", "", false, fmt.Errorf("failed to download %s: %w", archive.Title, err)
+	}
+
+	cmd.Println("Verifying download integrity...")
+	checksum, err := verifyArchive(ctx, client, archive, archivePath, fileName)
+	if err != nil {
+		return "", "", false, fmt.Errorf("checksum verification failed: %w", err)
+	}
+
+	cmd.Printf("Extracting %s...\n", archive.Title)
+	unpacked := filepath.Join(tempDir, "unpacked")
+	if err := extractArchive(archivePath, unpacked); err != nil {
+		return "", "", false, fmt.Errorf("failed to extract %s: %w", archive.Title, err)
+	}
+
+	root, err := archiveRoot(unpacked)
+	if err != nil {
+		return "", "", false, err
+	}
+	return root, checksum, false, nil
+}
+
+// downloadArchive saves the URL to destPath
+func downloadArchive(ctx context.Context, client *http.Client, downloadURL string, destPath string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return &devrigErrors.NetworkError{URL: downloadURL, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &devrigErrors.NetworkError{URL: downloadURL, Err: fmt.Errorf("download returned status %d", resp.StatusCode)}
+	}
+
+	out, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer out.Close()
+
+	tracker := progress.Track(ctx, "Downloading "+filepath.Base(destPath), resp.ContentLength, 0)
+	_, err = io.Copy(out, tracker.Reader(resp.Body))
+	tracker.Finish()
+	if err != nil {
+		return fmt.Errorf("failed to save file: %w", err)
+	}
+	return nil
+}
+
+// verifyArchive compares the archive with its checksum, fetching it from ChecksumURL when needed.
+// Returns the verified checksum
+func verifyArchive(ctx context.Context, client *http.Client, archive toolArchive, archivePath string, fileName string) (string, error) {
+	expected, err := expectedChecksum(ctx, client, archive, fileName)
+	if err != nil {
+		return "", err
+	}
+
+	actual, err := fileChecksum(archivePath, expected)
+	if err != nil {
+		return "", err
+	}
+	if actual != expected {
+		return "", &devrigErrors.ChecksumMismatchError{Subject: fileName, Expected: expected, Actual: actual}
+	}
+	return expected, nil
+}
+
+// expectedChecksum returns the checksum of the archive, fetching it from ChecksumURL when needed
+func expectedChecksum(ctx context.Context, client *http.Client, archive toolArchive, fileName string) (string, error) {
+	expected := archive.Checksum
+	if expected == "" && archive.ChecksumURL != "" {
+		content, err := fetchText(ctx, client, archive.ChecksumURL)
+		if err != nil {
+			return "", err
+		}
+		if expected, err = parseChecksumAsset(content, fileName); err != nil {
+			return "", err
+		}
+	}
+	if expected == "" {
+		return "", fmt.Errorf("no checksum is published for %s", fileName)
+	}
+	return validateChecksum(expected, fileName)
+}
+
+func fetchText(ctx context.Context, client *http.Client, textURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", textURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", &devrigErrors.NetworkError{URL: textURL, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &devrigErrors.NetworkError{URL: textURL, Err: fmt.Errorf("download returned status %d", resp.StatusCode)}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", textURL, err)
+	}
+	return string(data), nil
+}
+
+// archiveRoot returns the single top-level folder of the unpacked archive, release archives wrap everything
+// in a folder like jdk-<version>/ or go/
+func archiveRoot(unpacked string) (string, error) {
+	entries, err := os.ReadDir(unpacked)
+	if err != nil {
+		return "", fmt.Errorf("failed to read unpacked archive: %w", err)
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(unpacked, entries[0].Name()), nil
+	}
+	return unpacked, nil
+}
@@ -0,0 +1,184 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/lock"
+	"jonnyzzz.com/devrig.dev/offline"
+)
+
+// ToolchainRelease is a toolchain build resolved for the platform
+type ToolchainRelease struct {
+	// Version is the exact version without prefixes, e.g. 20.11.0
+	Version  string
+	URL      string
+	FileName string
+	// Checksum is the SHA-256 of the archive, empty when it is published at ChecksumURL
+	Checksum    string
+	ChecksumURL string
+}
+
+// Toolchain resolves the official releases of a development tool
+type Toolchain interface {
+	// Name is the key of the tool in the tools section of devrig.yaml, e.g. node
+	Name() string
+	// Title is the human-readable name, e.g. Node.js
+	Title() string
+	// DefaultVersion is installed when neither the command nor devrig.yaml pin a version
+	DefaultVersion() string
+	// Resolve finds the build of the version, an exact version or a prefix like 20 or 1.22
+	Resolve(ctx context.Context, version string, goos string, goarch string) (*ToolchainRelease, error)
+	// Source returns the API the releases are resolved with
+	Source() string
+	// BinDir returns the folder with the executables of the installed toolchain
+	BinDir(home string, goos string) string
+	// Executable returns the main executable of the installed toolchain
+	Executable(home string, goos string) string
+}
+
+// Toolchains lists the supported toolchains by name
+var Toolchains = map[string]func() Toolchain{
+	"node": func() Toolchain { return &NodeToolchain{} },
+	"go":   func() Toolchain { return &GoToolchain{} },
+}
+
+// ToolchainNames returns the supported toolchains sorted by name
+func ToolchainNames() []string {
+	names := make([]string, 0, len(Toolchains))
+	for name := range Toolchains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InstalledToolchain is a toolchain unpacked into the devrig tools folder
+type InstalledToolchain struct {
+	Name    string
+	Version string
+	Home    string
+	// BinDir is the folder to add to PATH
+	BinDir string
+}
+
+// ToolchainInstaller downloads, verifies and unpacks toolchains into .devrig/tools/<name>-<version>
+type ToolchainInstaller struct {
+	Toolchain  Toolchain
+	DevrigHome string
+	GOOS       string
+	GOARCH     string
+	Client     *http.Client
+	// Lock is devrig.lock of the project, the locked build is installed instead of resolving the version
+	Lock *lock.Lockfile
+	// Frozen fails the installation when the version is not locked
+	Frozen bool
+}
+
+// NewToolchainInstaller creates an installer of the toolchain for the current platform
+func NewToolchainInstaller(toolchain Toolchain, devrigHome string) *ToolchainInstaller {
+	return &ToolchainInstaller{Toolchain: toolchain, DevrigHome: devrigHome, GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+}
+
+// Install resolves the version and unpacks the toolchain, an already installed version is reused
+func (t *ToolchainInstaller) Install(cmd *cobra.Command, version string) (*InstalledToolchain, error) {
+	title := t.Toolchain.Title()
+
+	locked, err := t.Lock.Resolve(t.Toolchain.Name(), version, lock.Platform(t.GOOS, t.GOARCH), t.Frozen)
+	if err != nil {
+		return nil, err
+	}
+	if locked != nil {
+		version = locked.Version
+	}
+
+	// An exact version is looked up locally first, so it works without network access
+	if installed := t.installed(version); installed != nil {
+		cmd.Printf("%s %s is already installed\n", title, installed.Version)
+		return installed, nil
+	}
+
+	release, err := t.resolve(cmd, version, locked)
+	if err != nil {
+		return nil, err
+	}
+
+	if installed := t.installed(release.Version); installed != nil {
+		cmd.Printf("%s %s is already installed\n", title, installed.Version)
+		return installed, nil
+	}
+
+	target := layout.ResolveToolHome(t.DevrigHome, t.Toolchain.Name(), release.Version)
+	archive := toolArchive{Title: title + " " + release.Version, URL: release.URL, FileName: release.FileName,
+		Checksum: release.Checksum, ChecksumURL: release.ChecksumURL, Name: t.Toolchain.Name(), Version: release.Version}
+	err = installToolArchive(cmd, t.Client, t.DevrigHome, archive, target, func(root string) error {
+		if _, err := os.Stat(t.Toolchain.Executable(root, t.GOOS)); err != nil {
+			return fmt.Errorf("the archive of %s has no %s", archive.Title, filepath.Base(t.Toolchain.Executable(root, t.GOOS)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t.describe(release.Version, target), nil
+}
+
+// resolve returns the locked build, or resolves the version with the release API of the toolchain
+func (t *ToolchainInstaller) resolve(cmd *cobra.Command, version string, locked *lock.Artifact) (*ToolchainRelease, error) {
+	title := t.Toolchain.Title()
+	if locked != nil {
+		if err := offline.Check(title+" "+locked.Version, locked.URL, "install "+title+" on a machine with network access"); err != nil {
+			return nil, err
+		}
+		return &ToolchainRelease{Version: locked.Version, URL: locked.URL, FileName: locked.FileName, Checksum: locked.Checksum}, nil
+	}
+
+	if err := offline.Check(title+" "+version, t.Toolchain.Source(), "install "+title+" on a machine with network access"); err != nil {
+		return nil, err
+	}
+
+	cmd.Printf("Resolving %s %s...\n", title, version)
+	release, err := t.Toolchain.Resolve(cmd.Context(), version, t.GOOS, t.GOARCH)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s %s: %w", title, version, err)
+	}
+	return release, nil
+}
+
+// installed returns the toolchain if the version is already unpacked in the tools folder, or nil
+func (t *ToolchainInstaller) installed(version string) *InstalledToolchain {
+	home := layout.ResolveToolHome(t.DevrigHome, t.Toolchain.Name(), version)
+	if _, err := os.Stat(t.Toolchain.Executable(home, t.GOOS)); err != nil {
+		return nil
+	}
+	return t.describe(version, home)
+}
+
+func (t *ToolchainInstaller) describe(version string, home string) *InstalledToolchain {
+	return &InstalledToolchain{Name: t.Toolchain.Name(), Version: version, Home: home, BinDir: t.Toolchain.BinDir(home, t.GOOS)}
+}
+
+// ShellNames lists the shells supported by PathSnippet
+var ShellNames = []string{"bash", "zsh", "fish", "powershell"}
+
+// PathSnippet returns the shell command prepending the folder to PATH
+func PathSnippet(shell string, dir string) (string, error) {
+	switch strings.ToLower(shell) {
+	case "bash", "zsh", "sh":
+		return fmt.Sprintf(`export PATH="%s:$PATH"`, dir), nil
+	case "fish":
+		return fmt.Sprintf(`set -gx PATH "%s" $PATH`, dir), nil
+	case "powershell", "pwsh":
+		return fmt.Sprintf(`$env:PATH = "%s;" + $env:PATH`, dir), nil
+	default:
+		return "", fmt.Errorf("unsupported shell %s, expected one of: %s", shell, strings.Join(ShellNames, ", "))
+	}
+}
@@ -0,0 +1,90 @@
+package install
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/lock"
+)
+
+// NewToolchainCommand creates the install subcommand of the toolchain, e.g. node or go
+func NewToolchainCommand(toolchain func() Toolchain, configs func() configservice.ConfigService, configPath func() string) *cobra.Command {
+	var version string
+	var shell string
+	var frozen bool
+	info := toolchain()
+	cmd := &cobra.Command{
+		Use:   info.Name(),
+		Short: fmt.Sprintf("Install %s into the devrig tools folder", info.Title()),
+		Long: fmt.Sprintf(`Install the official %[1]s release for the current platform into .devrig/tools/%[2]s-<version>.
+
+The archive is verified against the published SHA-256 checksums before it is unpacked.
+Without --version the %[2]s pin of the tools section of devrig.yaml is used, or %[3]s.
+
+The build locked in devrig.lock is installed when the lock has the
+requested version, see devrig lock. With --frozen the command fails
+if the lock is missing or out of date.
+
+Use --shell to print a snippet adding %[1]s to PATH:
+  eval "$(devrig install %[2]s --shell bash)"
+
+Examples:
+  devrig install %[2]s
+  devrig install %[2]s --version %[3]s --shell zsh
+`, info.Title(), info.Name(), info.DefaultVersion()),
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if shell != "" {
+				// Validate the shell before downloading anything
+				if _, err := PathSnippet(shell, ""); err != nil {
+					return err
+				}
+			}
+
+			toolchain := toolchain()
+			if version == "" {
+				tools, err := configs().Tools().ReadTools()
+				if err != nil {
+					return err
+				}
+				version = tools[toolchain.Name()]
+				if version == "" {
+					version = toolchain.DefaultVersion()
+				}
+			}
+
+			installer := NewToolchainInstaller(toolchain, layout.ResolveDevrigHome(configPath()))
+			lockfile, err := lock.Load(lock.ResolvePath(configPath()))
+			if err != nil {
+				return err
+			}
+			installer.Lock = lockfile
+			installer.Frozen = frozen
+
+			installed, err := installer.Install(cmd, version)
+			if err != nil {
+				return fmt.Errorf("installation failed: %w", err)
+			}
+			cmd.Printf("%s %s installed to %s\n", toolchain.Title(), installed.Version, installed.Home)
+
+			if shell == "" {
+				return nil
+			}
+			// The snippet is the only line on stdout, so it can be evaluated by the shell
+			snippet, err := PathSnippet(shell, installed.BinDir)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(cmd.OutOrStdout(), snippet)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&version, "version", "", "Exact version or release line to install")
+	cmd.Flags().BoolVar(&frozen, "frozen", false, "Fail if devrig.lock has no build of the requested version")
+	cmd.Flags().StringVar(&shell, "shell", "", "Print a snippet adding the toolchain to PATH for the shell: "+strings.Join(ShellNames, ", "))
+	return cmd
+}
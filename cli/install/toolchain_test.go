@@ -0,0 +1,171 @@
+package install
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNodeToolchain_Resolve(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/index.json" {
+			t.Errorf("Unexpected path: %s", r.URL.Path)
+		}
+		_, _ = fmt.Fprint(w, `[
+			{"version":"v22.1.0","lts":false},
+			{"version":"v20.12.0","lts":"Iron"},
+			{"version":"v20.11.1","lts":"Iron"},
+			{"version":"v18.20.0","lts":"Hydrogen"}
+		]`)
+	}))
+	defer server.Close()
+
+	node := &NodeToolchain{BaseURL: server.URL}
+	tests := []struct {
+		version  string
+		expected string
+	}{
+		{version: "latest", expected: "22.1.0"},
+		{version: "lts", expected: "20.12.0"},
+		{version: "20", expected: "20.12.0"},
+		{version: "20.11", expected: "20.11.1"},
+		{version: "v18.19.0", expected: "18.19.0"},
+	}
+	for _, tt := range tests {
+		release, err := node.Resolve(context.Background(), tt.version, "linux", "amd64")
+		if err != nil {
+			t.Fatalf("Failed to resolve %s: %v", tt.version, err)
+		}
+		if release.Version != tt.expected {
+			t.Errorf("Expected %s for %s, got: %s", tt.expected, tt.version, release.Version)
+		}
+	}
+
+	release, err := node.Resolve(context.Background(), "20.11.0", "windows", "arm64")
+	if err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+	if release.URL != server.URL+"/v20.11.0/node-v20.11.0-win-arm64.zip" || release.ChecksumURL != server.URL+"/v20.11.0/SHASUMS256.txt" {
+		t.Errorf("Unexpected release: %+v", release)
+	}
+}
+
+func TestGoToolchain_Resolve(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `[
+			{"version":"go1.23rc1","stable":false,"files":[{"filename":"go1.23rc1.linux-amd64.tar.gz","os":"linux","arch":"amd64","sha256":"01","kind":"archive"}]},
+			{"version":"go1.22.2","stable":true,"files":[
+				{"filename":"go1.22.2.src.tar.gz","os":"","arch":"","sha256":"02","kind":"source"},
+				{"filename":"go1.22.2.linux-amd64.tar.gz","os":"linux","arch":"amd64","sha256":"03","kind":"archive"}]},
+			{"version":"go1.21.9","stable":true,"files":[{"filename":"go1.21.9.linux-amd64.tar.gz","os":"linux","arch":"amd64","sha256":"04","kind":"archive"}]}
+		]`)
+	}))
+	defer server.Close()
+
+	golang := &GoToolchain{APIURL: server.URL, DownloadBaseURL: "https://dl.example.com/go/"}
+	tests := []struct {
+		version  string
+		expected string
+		checksum string
+	}{
+		{version: "latest", expected: "1.22.2", checksum: "03"},
+		{version: "1.21", expected: "1.21.9", checksum: "04"},
+		{version: "go1.23rc1", expected: "1.23rc1", checksum: "01"},
+	}
+	for _, tt := range tests {
+		release, err := golang.Resolve(context.Background(), tt.version, "linux", "amd64")
+		if err != nil {
+			t.Fatalf("Failed to resolve %s: %v", tt.version, err)
+		}
+		if release.Version != tt.expected || release.Checksum != tt.checksum {
+			t.Errorf("Unexpected release for %s: %+v", tt.version, release)
+		}
+	}
+
+	release, _ := golang.Resolve(context.Background(), "1.22", "linux", "amd64")
+	if release == nil || release.URL != "https://dl.example.com/go/go1.22.2.linux-amd64.tar.gz" {
+		t.Errorf("Unexpected release: %+v", release)
+	}
+
+	if _, err := golang.Resolve(context.Background(), "1.22", "darwin", "arm64"); err == nil {
+		t.Errorf("Expected an error for a missing platform archive")
+	}
+}
+
+func TestToolchainInstaller_Install(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "node.tar.gz")
+	buildTarGz(t, archivePath, []tarEntry{
+		{name: "node-v20.11.0-linux-x64/bin/node", content: "#!/bin/sh\n", mode: 0755},
+		{name: "node-v20.11.0-linux-x64/bin/npm", typeflag: tar.TypeSymlink, linkname: "node"},
+	})
+	archive, err := os.ReadFile(archivePath)
+	if err != nil {
+		t.Fatalf("Failed to read archive: %v", err)
+	}
+	sum := sha256.Sum256(archive)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v20.11.0/node-v20.11.0-linux-x64.tar.gz":
+			_, _ = w.Write(archive)
+		case "/v20.11.0/SHASUMS256.txt":
+			_, _ = fmt.Fprintf(w, "%s  node-v20.11.0-darwin-arm64.tar.gz\n%s  node-v20.11.0-linux-x64.tar.gz\n",
+				strings.Repeat("0", 64), hex.EncodeToString(sum[:]))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	devrigHome := t.TempDir()
+	installer := &ToolchainInstaller{Toolchain: &NodeToolchain{BaseURL: server.URL}, DevrigHome: devrigHome, GOOS: "linux", GOARCH: "amd64"}
+
+	cmd, _ := newTestCommand()
+	installed, err := installer.Install(cmd, "20.11.0")
+	if err != nil {
+		t.Fatalf("Failed to install Node.js: %v", err)
+	}
+
+	expectedHome := filepath.Join(devrigHome, "tools", "node-20.11.0")
+	if installed.Home != expectedHome || installed.BinDir != filepath.Join(expectedHome, "bin") {
+		t.Errorf("Unexpected installation: %+v", installed)
+	}
+	if _, err := os.Stat(filepath.Join(expectedHome, "bin", "npm")); err != nil {
+		t.Errorf("Expected npm to be installed: %v", err)
+	}
+
+	// The installed version is reused without network access
+	server.Close()
+	if _, err := installer.Install(cmd, "20.11.0"); err != nil {
+		t.Errorf("Expected the installed version to be reused: %v", err)
+	}
+}
+
+func TestPathSnippet(t *testing.T) {
+	tests := map[string]string{
+		"bash":       `export PATH="/tools/node/bin:$PATH"`,
+		"fish":       `set -gx PATH "/tools/node/bin" $PATH`,
+		"powershell": `$env:PATH = "/tools/node/bin;" + $env:PATH`,
+	}
+	for shell, expected := range tests {
+		snippet, err := PathSnippet(shell, "/tools/node/bin")
+		if err != nil {
+			t.Fatalf("Failed to create snippet for %s: %v", shell, err)
+		}
+		if snippet != expected {
+			t.Errorf("Unexpected snippet for %s: %s", shell, snippet)
+		}
+	}
+
+	if _, err := PathSnippet("tcsh", "/tools"); err == nil {
+		t.Errorf("Expected an error for an unsupported shell")
+	}
+}
@@ -16,7 +16,8 @@ func TestVersionInUserAgent(t *testing.T) {
 		t.Logf("Could not fetch release (expected in some environments): %v", err)
 
 		// Create a minimal installer to test
-		installer = &JetBrainsMonoInstaller{
+		installer = &GitHubFontInstaller{
+			spec:          JetBrainsMono,
 			devrigVersion: testVersion,
 			userAgent:     "devrig/" + testVersion,
 		}
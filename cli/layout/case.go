@@ -0,0 +1,74 @@
+package layout
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IsCaseInsensitive checks if the filesystem of the given existing directory ignores the case of file names,
+// which is the default on macOS and Windows
+func IsCaseInsensitive(dir string) (bool, error) {
+	probe, err := os.CreateTemp(dir, ".devrig-Case-Probe-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create probe file in %s: %w", dir, err)
+	}
+	name := probe.Name()
+	_ = probe.Close()
+	//goland:noinspection GoUnhandledErrorResult
+	defer os.Remove(name)
+
+	base := filepath.Base(name)
+	_, err = os.Stat(filepath.Join(dir, strings.ToUpper(base)))
+	return err == nil, nil
+}
+
+// CheckNoCaseCollision returns an error if the parent folder of target already contains
+// an entry whose name differs from target only by case. On a case-insensitive filesystem
+// both names resolve to the same folder, so unpacking would silently merge two installations.
+func CheckNoCaseCollision(target string) error {
+	dir, base := filepath.Split(filepath.Clean(target))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	for _, entry := range entries {
+		if entry.Name() != base && strings.EqualFold(entry.Name(), base) {
+			return fmt.Errorf("%s collides with existing %s on case-insensitive filesystems, remove one of them",
+				target, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return nil
+}
+
+// FindCaseCollisions returns the groups of entries of dir whose names differ only by case
+func FindCaseCollisions(dir string) [][]string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	groups := map[string][]string{}
+	for _, entry := range entries {
+		key := strings.ToLower(entry.Name())
+		groups[key] = append(groups[key], entry.Name())
+	}
+
+	var collisions [][]string
+	for _, names := range groups {
+		if len(names) > 1 {
+			sort.Strings(names)
+			collisions = append(collisions, names)
+		}
+	}
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i][0] < collisions[j][0]
+	})
+	return collisions
+}
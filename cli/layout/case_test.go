@@ -0,0 +1,49 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckNoCaseCollision(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "IntelliJ-IDEA-2025.1"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	if err := CheckNoCaseCollision(filepath.Join(dir, "IntelliJ-IDEA-2025.1")); err != nil {
+		t.Errorf("Expected the same name not to collide, got: %v", err)
+	}
+	if err := CheckNoCaseCollision(filepath.Join(dir, "GoLand-2025.1")); err != nil {
+		t.Errorf("Expected a different name not to collide, got: %v", err)
+	}
+
+	err := CheckNoCaseCollision(filepath.Join(dir, "intellij-idea-2025.1"))
+	if err == nil || !strings.Contains(err.Error(), "case-insensitive") {
+		t.Errorf("Expected a collision error, got: %v", err)
+	}
+}
+
+func TestFindCaseCollisions(t *testing.T) {
+	dir := t.TempDir()
+	caseInsensitive, err := IsCaseInsensitive(dir)
+	if err != nil {
+		t.Fatalf("Failed to probe the filesystem: %v", err)
+	}
+	if caseInsensitive {
+		t.Skip("the filesystem is case-insensitive, collisions cannot be created")
+	}
+
+	for _, name := range []string{"node-20", "Node-20", "go-1.22"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+	}
+
+	collisions := FindCaseCollisions(dir)
+	if len(collisions) != 1 || len(collisions[0]) != 2 || collisions[0][0] != "Node-20" {
+		t.Errorf("Unexpected collisions: %v", collisions)
+	}
+}
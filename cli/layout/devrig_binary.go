@@ -0,0 +1,122 @@
+package layout
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// DevrigBinaryMetadataFile is the name of the metadata file in the folder of a devrig binary
+const DevrigBinaryMetadataFile = "metadata.json"
+
+// Sources of devrig binaries, recorded in DevrigBinaryMetadata
+const (
+	DevrigBinarySourceBootstrap = "bootstrap"
+	DevrigBinarySourceInit      = "init"
+	DevrigBinarySourceApply     = "apply"
+	// DevrigBinarySourceAdopt is a binary of the machine with the pinned hash that init put into .devrig
+	DevrigBinarySourceAdopt = "adopt"
+)
+
+// DevrigBinaryMetadata describes a devrig binary in the .devrig folder, it is stored next to the binary
+type DevrigBinaryMetadata struct {
+	OS      string `json:"os"`
+	Arch    string `json:"arch"`
+	SHA512  string `json:"sha512"`
+	Version string `json:"version,omitempty"`
+	URL     string `json:"url,omitempty"`
+	// Source is the one of DevrigBinarySource* that installed the binary
+	Source      string    `json:"source"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+// ResolveDevrigBinaryDir returns the folder of a devrig binary in the .devrig folder,
+// the same as the bootstrap scripts use: .devrig/devrig-<os>-<arch>-<sha512>
+func ResolveDevrigBinaryDir(devrigHome string, os string, arch string, sha512 string) string {
+	return filepath.Join(devrigHome, "devrig-"+os+"-"+arch+"-"+strings.ToLower(sha512))
+}
+
+// ResolveDevrigBinary returns the location of a devrig binary in the .devrig folder,
+// the same as the bootstrap scripts use: .devrig/devrig-<os>-<arch>-<sha512>/devrig[.exe]
+func ResolveDevrigBinary(devrigHome string, os string, arch string, sha512 string) string {
+	name := "devrig"
+	if os == "windows" {
+		name += ".exe"
+	}
+	return filepath.Join(ResolveDevrigBinaryDir(devrigHome, os, arch, sha512), name)
+}
+
+// ParseDevrigBinaryDir parses the name of a folder from ResolveDevrigBinaryDir. Plain files of the older layout,
+// devrig-<os>-<arch>-<sha512>[.exe], are recognized too. Returns false for other names, e.g. temporary files
+func ParseDevrigBinaryDir(name string) (os string, arch string, sha512 string, ok bool) {
+	rest, found := strings.CutPrefix(strings.TrimSuffix(name, ".exe"), "devrig-")
+	if !found {
+		return "", "", "", false
+	}
+	parts := strings.SplitN(rest, "-", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || len(parts[2]) != 128 {
+		return "", "", "", false
+	}
+	for _, c := range parts[2] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return "", "", "", false
+		}
+	}
+	return parts[0], parts[1], strings.ToLower(parts[2]), true
+}
+
+// InstallDevrigBinary moves the verified file into the folder of the binary and writes the metadata next to it.
+// Returns the path of the installed binary
+func InstallDevrigBinary(devrigHome string, file string, metadata DevrigBinaryMetadata) (string, error) {
+	target := ResolveDevrigBinary(devrigHome, metadata.OS, metadata.Arch, metadata.SHA512)
+	dir := filepath.Dir(target)
+
+	// Older versions stored the binary as a plain file with the name of the folder
+	if info, err := os.Lstat(dir); err == nil && !info.IsDir() {
+		if err := os.Remove(dir); err != nil {
+			return "", fmt.Errorf("failed to remove the old devrig binary %s: %w", dir, err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	if err := os.Chmod(file, 0755); err != nil {
+		return "", fmt.Errorf("failed to set executable permissions: %w", err)
+	}
+	if err := os.Rename(file, target); err != nil {
+		return "", fmt.Errorf("failed to move %s to %s: %w", file, target, err)
+	}
+
+	metadata.SHA512 = strings.ToLower(metadata.SHA512)
+	if metadata.InstalledAt.IsZero() {
+		metadata.InstalledAt = time.Now()
+	}
+	metadata.InstalledAt = metadata.InstalledAt.UTC()
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal devrig binary metadata: %w", err)
+	}
+	if err := tempfile.WriteFile(filepath.Join(dir, DevrigBinaryMetadataFile), data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write devrig binary metadata: %w", err)
+	}
+	return target, nil
+}
+
+// ReadDevrigBinaryMetadata reads the metadata from the folder of a devrig binary, see ResolveDevrigBinaryDir
+func ReadDevrigBinaryMetadata(dir string) (*DevrigBinaryMetadata, error) {
+	data, err := os.ReadFile(filepath.Join(dir, DevrigBinaryMetadataFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read devrig binary metadata: %w", err)
+	}
+	var metadata DevrigBinaryMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to parse devrig binary metadata in %s: %w", dir, err)
+	}
+	return &metadata, nil
+}
@@ -0,0 +1,66 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestResolveDevrigBinary(t *testing.T) {
+	sha := strings.Repeat("AB", 64)
+	home := filepath.Join("project", ".devrig")
+
+	dir := filepath.Join(home, "devrig-linux-x86_64-"+strings.ToLower(sha))
+	if got := ResolveDevrigBinary(home, "linux", "x86_64", sha); got != filepath.Join(dir, "devrig") {
+		t.Errorf("Unexpected binary path: %s", got)
+	}
+	windows := filepath.Join(home, "devrig-windows-arm64-"+strings.ToLower(sha), "devrig.exe")
+	if got := ResolveDevrigBinary(home, "windows", "arm64", sha); got != windows {
+		t.Errorf("Unexpected Windows binary path: %s", got)
+	}
+}
+
+func TestInstallDevrigBinary(t *testing.T) {
+	home := t.TempDir()
+	sha := strings.Repeat("ab", 64)
+
+	// Older versions stored the binary as a plain file with the name of the folder
+	if err := os.WriteFile(ResolveDevrigBinaryDir(home, "linux", "x86_64", sha), []byte("old"), 0755); err != nil {
+		t.Fatalf("Failed to write the old binary: %v", err)
+	}
+	file := filepath.Join(home, "devrig-downloading")
+	if err := os.WriteFile(file, []byte("binary"), 0600); err != nil {
+		t.Fatalf("Failed to write binary: %v", err)
+	}
+
+	target, err := InstallDevrigBinary(home, file, DevrigBinaryMetadata{
+		OS: "linux", Arch: "x86_64", SHA512: strings.ToUpper(sha), Version: "0.79.6", Source: DevrigBinarySourceApply,
+	})
+	if err != nil {
+		t.Fatalf("Failed to install binary: %v", err)
+	}
+	if target != ResolveDevrigBinary(home, "linux", "x86_64", sha) {
+		t.Errorf("Unexpected target: %s", target)
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		t.Fatalf("Failed to stat binary: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0755 {
+		t.Errorf("Expected an executable binary, got %v", info.Mode())
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("Expected the file to be moved, got: %v", err)
+	}
+
+	metadata, err := ReadDevrigBinaryMetadata(filepath.Dir(target))
+	if err != nil {
+		t.Fatalf("Failed to read metadata: %v", err)
+	}
+	if metadata.SHA512 != sha || metadata.Version != "0.79.6" || metadata.Source != DevrigBinarySourceApply || metadata.InstalledAt.IsZero() {
+		t.Errorf("Unexpected metadata: %+v", metadata)
+	}
+}
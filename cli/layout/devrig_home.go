@@ -0,0 +1,158 @@
+package layout
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// EnvDevrigHome overrides the location of the .devrig folder, same as in the bootstrap scripts
+const EnvDevrigHome = "DEVRIG_HOME"
+
+// ResolveDevrigHome returns the .devrig folder for the given devrig.yaml path.
+// It is the .devrig folder next to devrig.yaml unless DEVRIG_HOME is set.
+// For read-only checkouts the state is redirected to the user-level store, see ResolveUserDevrigHome.
+func ResolveDevrigHome(configPath string) string {
+	if home := os.Getenv(EnvDevrigHome); home != "" {
+		if abs, err := filepath.Abs(home); err == nil {
+			return abs
+		}
+		return home
+	}
+	if IsReadOnlyCheckout(configPath) {
+		if home, err := ResolveUserDevrigHome(configPath); err == nil {
+			return home
+		}
+	}
+	return resolveProjectDevrigHome(configPath)
+}
+
+func resolveProjectDevrigHome(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), ".devrig")
+}
+
+// IsReadOnlyCheckout checks if devrig cannot write its .devrig folder next to devrig.yaml,
+// e.g. when the project is mounted read-only into a container. An existing writable
+// .devrig folder is used even if the project folder itself is read-only.
+func IsReadOnlyCheckout(configPath string) bool {
+	if os.Getenv(EnvDevrigHome) != "" {
+		return false
+	}
+	projectHome := resolveProjectDevrigHome(configPath)
+	if info, err := os.Stat(projectHome); err == nil && info.IsDir() {
+		return IsReadOnlyDir(projectHome)
+	}
+	return IsReadOnlyDir(filepath.Dir(configPath))
+}
+
+// IsReadOnlyDir checks if files cannot be created in the existing directory because of
+// permissions or a read-only filesystem. Missing directories are not reported as read-only.
+func IsReadOnlyDir(dir string) bool {
+	probe, err := os.CreateTemp(dir, ".devrig-write-probe-*")
+	if err != nil {
+		return errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS)
+	}
+	name := probe.Name()
+	_ = probe.Close()
+	_ = os.Remove(name)
+	return false
+}
+
+// ResolveUserDevrigHome returns the user-level .devrig folder of a project:
+// <user cache dir>/devrig/projects/<project name>-<hash of the project path>, the symlinks of the path are resolved.
+// When the shared store is configured, the folder is placed there instead, see ResolveSharedStore.
+func ResolveUserDevrigHome(configPath string) (string, error) {
+	projectsDir, err := ResolveUserProjectsDir()
+	if err != nil {
+		return "", err
+	}
+	projectDir, err := filepath.Abs(filepath.Dir(configPath))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve project directory: %w", err)
+	}
+	// A project opened through a symlinked parent, e.g. /tmp on macOS, keeps the folder of its real path
+	if resolved, err := filepath.EvalSymlinks(projectDir); err == nil {
+		projectDir = resolved
+	}
+	hash := sha256.Sum256([]byte(projectDir))
+	// The hash tells the projects apart, a name without ASCII letters, e.g. Проект, is replaced
+	name := sanitizePath(filepath.Base(projectDir))
+	if strings.Trim(name, "_") == "" {
+		name = "project"
+	}
+	return filepath.Join(projectsDir, fmt.Sprintf("%s-%x", name, hash[:6])), nil
+}
+
+// ResolveUserProjectsDir returns the folder with the user-level .devrig folders of all projects, see ResolveUserDevrigHome
+func ResolveUserProjectsDir() (string, error) {
+	storeDir, err := resolveUserStore()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(storeDir, "projects"), nil
+}
+
+// ResolveContentStoreDir returns the content-addressed store of downloads and unpacked IDEs shared by the projects
+// of the user: <user cache dir>/devrig/artifacts, or the folder of the user in the shared store
+func ResolveContentStoreDir() (string, error) {
+	storeDir, err := resolveUserStore()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(storeDir, "artifacts"), nil
+}
+
+// resolveUserStore returns the folder of the current user in the shared store, or <user cache dir>/devrig
+func resolveUserStore() (string, error) {
+	store, err := ResolveSharedStore()
+	if err != nil {
+		return "", err
+	}
+	if store != nil {
+		return store.Prepare()
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve user cache directory: %w", err)
+	}
+	return filepath.Join(cacheDir, "devrig"), nil
+}
+
+// ResolveUserStateDir returns the folder of the per-user state of devrig: <user config dir>/devrig.
+// Unlike the caches, the state is small and must survive cache cleanups.
+func ResolveUserStateDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve user config directory: %w", err)
+	}
+	return filepath.Join(configDir, "devrig"), nil
+}
+
+// ResolveToolsHome returns the folder where devrig-managed tools are installed
+func ResolveToolsHome(devrigHome string) string {
+	return filepath.Join(devrigHome, "tools")
+}
+
+// ResolveToolHome returns the installation folder of a tool: .devrig/tools/<name>-<version>.
+// Tool names are lowercased, so Node and node share the folder on every filesystem.
+func ResolveToolHome(devrigHome string, name string, version string) string {
+	return filepath.Join(ResolveToolsHome(devrigHome), sanitizePath(strings.ToLower(name)+"-"+version))
+}
+
+// ResolvePlatformDownloadsDir returns the folder of the artifacts downloaded for a platform without unpacking them:
+// .devrig/downloads/<os>-<arch>
+func ResolvePlatformDownloadsDir(devrigHome string, platform string) string {
+	return filepath.Join(devrigHome, "downloads", sanitizePath(platform))
+}
+
+// ResolveLockFile returns the lock file guarding the named entry of the folder: <dir>/locks/<name>.lock.
+// The folder is the devrig home for tools and devrig.yaml, or the IDE cache for downloads and unpacked IDEs.
+func ResolveLockFile(dir string, name string) string {
+	return filepath.Join(dir, "locks", sanitizePath(name)+".lock")
+}
@@ -0,0 +1,127 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestResolveDevrigHome_WritableCheckout(t *testing.T) {
+	t.Setenv(EnvDevrigHome, "")
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "devrig.yaml")
+
+	if IsReadOnlyCheckout(configPath) {
+		t.Fatalf("Expected %s to be writable", dir)
+	}
+	if home := ResolveDevrigHome(configPath); home != filepath.Join(dir, ".devrig") {
+		t.Errorf("Expected .devrig next to devrig.yaml, got %s", home)
+	}
+}
+
+func TestResolveDevrigHome_ReadOnlyCheckout(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		t.Skip("directory permissions are not enforced for this user")
+	}
+	t.Setenv(EnvDevrigHome, "")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	dir := filepath.Join(t.TempDir(), "project")
+	if err := os.Mkdir(dir, 0555); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	configPath := filepath.Join(dir, "devrig.yaml")
+
+	if !IsReadOnlyCheckout(configPath) {
+		t.Fatalf("Expected %s to be read-only", dir)
+	}
+	expected, err := ResolveUserDevrigHome(configPath)
+	if err != nil {
+		t.Fatalf("Failed to resolve user devrig home: %v", err)
+	}
+	if home := ResolveDevrigHome(configPath); home != expected {
+		t.Errorf("Expected the user-level store %s, got %s", expected, home)
+	}
+}
+
+func TestResolveUserDevrigHome(t *testing.T) {
+	first, err := ResolveUserDevrigHome(filepath.Join("/work", "my project", "devrig.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to resolve user devrig home: %v", err)
+	}
+	second, err := ResolveUserDevrigHome(filepath.Join("/other", "my project", "devrig.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to resolve user devrig home: %v", err)
+	}
+
+	if first == second {
+		t.Errorf("Expected different projects with the same name to get different folders, got %s", first)
+	}
+	if !strings.HasPrefix(filepath.Base(first), "my_project-") {
+		t.Errorf("Expected the folder to start with the project name, got %s", first)
+	}
+}
+
+func TestIsReadOnlyDir_Missing(t *testing.T) {
+	if IsReadOnlyDir(filepath.Join(t.TempDir(), "missing")) {
+		t.Errorf("Expected a missing directory not to be reported as read-only")
+	}
+}
+
+func TestResolveUserDevrigHome_NonASCIIName(t *testing.T) {
+	home, err := ResolveUserDevrigHome(filepath.Join(t.TempDir(), "Проект", "devrig.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to resolve user devrig home: %v", err)
+	}
+	if !strings.HasPrefix(filepath.Base(home), "project-") {
+		t.Errorf("Expected the folder to fall back to the project name, got %s", home)
+	}
+}
+
+func TestResolveUserDevrigHome_SymlinkedParent(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need elevated permissions on Windows")
+	}
+	root := t.TempDir()
+	projectDir := filepath.Join(root, "My Projects", "Café app")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	link := filepath.Join(root, "link to projects")
+	if err := os.Symlink(filepath.Join(root, "My Projects"), link); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	direct, err := ResolveUserDevrigHome(filepath.Join(projectDir, "devrig.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to resolve user devrig home: %v", err)
+	}
+	linked, err := ResolveUserDevrigHome(filepath.Join(link, "Café app", "devrig.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to resolve user devrig home: %v", err)
+	}
+	if direct != linked {
+		t.Errorf("Expected the symlinked path to share the folder %s, got %s", direct, linked)
+	}
+	if !strings.HasPrefix(filepath.Base(direct), "Caf_app-") {
+		t.Errorf("Expected the folder to start with the project name, got %s", direct)
+	}
+}
+
+func TestResolveDevrigHome_SpacesAndUnicode(t *testing.T) {
+	t.Setenv(EnvDevrigHome, "")
+	dir := filepath.Join(t.TempDir(), "Jöhn Doe", "my project 名前")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	configPath := filepath.Join(dir, "devrig.yaml")
+	if home := ResolveDevrigHome(configPath); home != filepath.Join(dir, ".devrig") {
+		t.Errorf("Expected .devrig next to devrig.yaml, got %s", home)
+	}
+	if lock := ResolveLockFile(dir, "ide GoLand"); filepath.Dir(filepath.Dir(lock)) != dir {
+		t.Errorf("Expected the lock in the folder, got %s", lock)
+	}
+}
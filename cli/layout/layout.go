@@ -1,7 +1,7 @@
 package layout
 
 import (
-	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
 
@@ -23,13 +23,43 @@ func sanitizePath(input string) string {
 
 func ResolveLocalDownloadFileName(localConfig config.Config, remoteIde feed_api.RemoteIDE) string {
 	ideDir := sanitizePath(remoteIde.Name()+"-"+remoteIde.Build()) + "." + remoteIde.PackageType()
-	return path.Join(localConfig.CacheDir(), "download", ideDir)
+	return filepath.Join(ResolveDownloadsDir(localConfig.CacheDir()), ideDir)
 }
 
 func ResolveLocalHome(localConfig config.Config, remoteIde feed_api.RemoteIDE) string {
-	ideDir := sanitizePath(remoteIde.Name() + "-" + remoteIde.Build())
+	ideDir := UnpackedIdeName(remoteIde.Name(), remoteIde.Build())
 	if remoteIde.PackageType() == "dmg" {
 		ideDir += ".app"
 	}
-	return path.Join(localConfig.CacheDir(), "ide", ideDir)
+	return filepath.Join(ResolveUnpackedIdesDir(localConfig.CacheDir()), ideDir)
+}
+
+// UnpackedIdeName returns the folder name of an unpacked IDE without the .app suffix of macOS: <name>-<build>
+func UnpackedIdeName(name string, build string) string {
+	return sanitizePath(name + "-" + build)
+}
+
+// ResolveDownloadsDir returns the folder of downloaded IDE archives: <cache>/download
+func ResolveDownloadsDir(cacheDir string) string {
+	return filepath.Join(cacheDir, "download")
+}
+
+// ResolveUnpackedIdesDir returns the folder of unpacked IDEs: <cache>/ide/<name>-<build>[.app]
+func ResolveUnpackedIdesDir(cacheDir string) string {
+	return filepath.Join(cacheDir, "ide")
+}
+
+// IdeManifestSuffix is appended to the folder of an unpacked IDE to name its manifest, see ResolveIdeManifest
+const IdeManifestSuffix = ".devrig-manifest.json"
+
+// ResolveIdeManifest returns the fingerprint of an unpacked IDE, it is kept next to the IDE folder, so it does not
+// change the signed .app bundle: <cache>/ide/<name>-<build>[.app].devrig-manifest.json
+func ResolveIdeManifest(home string) string {
+	return home + IdeManifestSuffix
+}
+
+// ResolveCacheProjectsDir returns the folder with a file for every project that installs IDEs into the cache:
+// <cache>/projects/<hash of the devrig.yaml path>
+func ResolveCacheProjectsDir(cacheDir string) string {
+	return filepath.Join(cacheDir, "projects")
 }
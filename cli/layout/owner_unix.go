@@ -0,0 +1,27 @@
+//go:build !windows
+
+package layout
+
+import (
+	"os"
+	"syscall"
+)
+
+// FileOwner returns the uid and gid of the file
+func FileOwner(info os.FileInfo) (int, int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+// IsOwnedByCurrentUser checks if the file belongs to the user running devrig
+func IsOwnedByCurrentUser(path string) (bool, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return false, err
+	}
+	uid, _, ok := FileOwner(info)
+	return !ok || uid == os.Geteuid(), nil
+}
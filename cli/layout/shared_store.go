@@ -0,0 +1,94 @@
+package layout
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+)
+
+const (
+	// EnvSharedStore points the user-level store to a folder shared by all users of a machine, e.g. on build agents
+	EnvSharedStore = "DEVRIG_SHARED_STORE"
+	// EnvSharedStoreMode selects how users share EnvSharedStore, see SharedStorePerUser and SharedStoreGroup
+	EnvSharedStoreMode = "DEVRIG_SHARED_STORE_MODE"
+)
+
+const (
+	// SharedStorePerUser gives every user a private subfolder of the shared store, nothing is shared
+	SharedStorePerUser = "per-user"
+	// SharedStoreGroup shares the store folder between the members of its group. The folder has
+	// the setgid and sticky bits set, so new entries keep the group and users cannot remove entries of others.
+	SharedStoreGroup = "group"
+)
+
+// SharedStore describes the configured shared store
+type SharedStore struct {
+	Root string
+	Mode string
+}
+
+// ResolveSharedStore returns the shared store from the environment, or nil if it is not configured
+func ResolveSharedStore() (*SharedStore, error) {
+	root := os.Getenv(EnvSharedStore)
+	if root == "" {
+		return nil, nil
+	}
+
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s=%s: %w", EnvSharedStore, root, err)
+	}
+
+	mode := os.Getenv(EnvSharedStoreMode)
+	switch mode {
+	case "":
+		mode = SharedStorePerUser
+	case SharedStorePerUser, SharedStoreGroup:
+	default:
+		return nil, fmt.Errorf("unknown %s=%s (expected %s or %s)", EnvSharedStoreMode, mode, SharedStorePerUser, SharedStoreGroup)
+	}
+	return &SharedStore{Root: abs, Mode: mode}, nil
+}
+
+// Home returns the folder of the current user in the store
+func (s *SharedStore) Home() (string, error) {
+	if s.Mode == SharedStoreGroup {
+		return s.Root, nil
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the current user: %w", err)
+	}
+	return filepath.Join(s.Root, "users", sanitizePath(current.Username)), nil
+}
+
+// Prepare creates the folder of the current user with permissions matching the mode
+func (s *SharedStore) Prepare() (string, error) {
+	home, err := s.Home()
+	if err != nil {
+		return "", err
+	}
+
+	if s.Mode == SharedStoreGroup {
+		if err := os.MkdirAll(home, 0775); err != nil {
+			return "", fmt.Errorf("failed to create shared store %s: %w", home, err)
+		}
+		// Only the owner can change the mode, other members of the group use it as is
+		if owned, _ := IsOwnedByCurrentUser(home); owned {
+			if err := os.Chmod(home, os.ModeDir|0775|os.ModeSetgid|os.ModeSticky); err != nil {
+				return "", fmt.Errorf("failed to set permissions of shared store %s: %w", home, err)
+			}
+		}
+		return home, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(home), 0755); err != nil {
+		return "", fmt.Errorf("failed to create shared store %s: %w", filepath.Dir(home), err)
+	}
+	if err := os.MkdirAll(home, 0700); err != nil {
+		return "", fmt.Errorf("failed to create shared store %s: %w", home, err)
+	}
+	return home, nil
+}
@@ -0,0 +1,81 @@
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestResolveSharedStore(t *testing.T) {
+	t.Setenv(EnvSharedStore, "")
+	store, err := ResolveSharedStore()
+	if err != nil || store != nil {
+		t.Fatalf("Expected no shared store, got %v, %v", store, err)
+	}
+
+	root := t.TempDir()
+	t.Setenv(EnvSharedStore, root)
+	t.Setenv(EnvSharedStoreMode, "")
+	store, err = ResolveSharedStore()
+	if err != nil {
+		t.Fatalf("Failed to resolve shared store: %v", err)
+	}
+	if store.Mode != SharedStorePerUser {
+		t.Errorf("Expected %s mode by default, got %s", SharedStorePerUser, store.Mode)
+	}
+
+	t.Setenv(EnvSharedStoreMode, "everyone")
+	if _, err := ResolveSharedStore(); err == nil {
+		t.Error("Expected an unknown mode to be rejected")
+	}
+}
+
+func TestSharedStore_Prepare(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX permissions are not available on Windows")
+	}
+
+	perUser := &SharedStore{Root: t.TempDir(), Mode: SharedStorePerUser}
+	home, err := perUser.Prepare()
+	if err != nil {
+		t.Fatalf("Failed to prepare per-user store: %v", err)
+	}
+	if filepath.Dir(home) != filepath.Join(perUser.Root, "users") {
+		t.Errorf("Expected the user folder under users/, got %s", home)
+	}
+	info, err := os.Stat(home)
+	if err != nil {
+		t.Fatalf("Failed to stat %s: %v", home, err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("Expected mode 0700, got %04o", info.Mode().Perm())
+	}
+
+	group := &SharedStore{Root: filepath.Join(t.TempDir(), "shared"), Mode: SharedStoreGroup}
+	home, err = group.Prepare()
+	if err != nil {
+		t.Fatalf("Failed to prepare group store: %v", err)
+	}
+	info, err = os.Stat(home)
+	if err != nil {
+		t.Fatalf("Failed to stat %s: %v", home, err)
+	}
+	if info.Mode()&os.ModeSetgid == 0 || info.Mode()&os.ModeSticky == 0 {
+		t.Errorf("Expected setgid and sticky bits, got %s", info.Mode())
+	}
+}
+
+func TestResolveUserDevrigHome_SharedStore(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(EnvSharedStore, root)
+	t.Setenv(EnvSharedStoreMode, SharedStoreGroup)
+
+	home, err := ResolveUserDevrigHome(filepath.Join(t.TempDir(), "devrig.yaml"))
+	if err != nil {
+		t.Fatalf("Failed to resolve user devrig home: %v", err)
+	}
+	if filepath.Dir(home) != filepath.Join(root, "projects") {
+		t.Errorf("Expected the project folder in the shared store, got %s", home)
+	}
+}
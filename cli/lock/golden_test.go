@@ -0,0 +1,27 @@
+package lock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/golden"
+)
+
+func TestGolden_Save(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+
+	lockfile := &Lockfile{}
+	lockfile.Put(Artifact{Name: "node", Request: "20", Platform: "linux-amd64", Version: "20.11.0", URL: "https://example.com/node.tar.gz", Checksum: "ab"})
+	lockfile.Put(Artifact{Name: "go", Request: "1.22", Platform: "linux-amd64", Version: "1.22.5", URL: "https://example.com/go.tar.gz", Checksum: "cd"})
+	lockfile.Put(Artifact{Name: "node", Request: "20", Platform: "darwin-arm64", Version: "20.11.0", URL: "https://example.com/node-mac.tar.gz", Checksum: "ef"})
+	if err := lockfile.Save(path); err != nil {
+		t.Fatalf("Failed to save lock: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read lock: %v", err)
+	}
+	golden.Assert(t, FileName, data)
+}
@@ -0,0 +1,153 @@
+// Package lock reads and writes devrig.lock, the exact artifacts resolved for the pins of devrig.yaml.
+// devrig.yaml expresses the intent, e.g. node 20, the lock records the build every machine downloads.
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// FileName is the name of the lock file next to devrig.yaml
+const FileName = "devrig.lock"
+
+// formatVersion is the version of the lock file format
+const formatVersion = 1
+
+// Artifact is a download resolved for a pin of devrig.yaml on a platform
+type Artifact struct {
+	// Name is the locked tool, e.g. jdk, node, go or ide
+	Name string `json:"name"`
+	// Request is the pin as written in devrig.yaml, e.g. 20 or temurin 21
+	Request string `json:"request"`
+	// Platform is the Go OS and architecture, e.g. linux-amd64
+	Platform string `json:"platform"`
+	Version  string `json:"version"`
+	Build    string `json:"build,omitempty"`
+	URL      string `json:"url"`
+	FileName string `json:"file_name,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	// Checksum is the hex SHA-256 or SHA-512 of the download
+	Checksum string `json:"checksum"`
+}
+
+// Lockfile is the content of devrig.lock
+type Lockfile struct {
+	Version   int        `json:"version"`
+	Artifacts []Artifact `json:"artifacts"`
+}
+
+// ResolvePath returns the path of devrig.lock next to devrig.yaml
+func ResolvePath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), FileName)
+}
+
+// Platform returns the platform key of the lock, e.g. linux-amd64
+func Platform(goos string, goarch string) string {
+	return goos + "-" + goarch
+}
+
+// ParsePlatform splits the platform key into the Go OS and architecture
+func ParsePlatform(platform string) (string, string, error) {
+	goos, goarch, ok := strings.Cut(platform, "-")
+	if !ok || goos == "" || goarch == "" {
+		return "", "", fmt.Errorf("invalid platform %s, expected <os>-<arch>, e.g. linux-amd64", platform)
+	}
+	return goos, goarch, nil
+}
+
+// Load reads the lock file, it returns nil when the file does not exist
+func Load(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var lockfile Lockfile
+	if err := json.Unmarshal(data, &lockfile); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if lockfile.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported version %d of %s, expected %d", lockfile.Version, path, formatVersion)
+	}
+	return &lockfile, nil
+}
+
+// Save writes the lock file with the artifacts sorted, so the file diffs cleanly in version control
+func (l *Lockfile) Save(path string) error {
+	l.Version = formatVersion
+	sort.Slice(l.Artifacts, func(i, j int) bool {
+		a, b := l.Artifacts[i], l.Artifacts[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Platform < b.Platform
+	})
+	if l.Artifacts == nil {
+		l.Artifacts = []Artifact{}
+	}
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", FileName, err)
+	}
+	data = append(data, '\n')
+
+	// Concurrent readers must never see a partial file, the lock is committed, so it is readable by everyone
+	if err := tempfile.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save %s: %w", FileName, err)
+	}
+	return nil
+}
+
+// Find returns the artifact of the tool on the platform, or nil
+func (l *Lockfile) Find(name string, platform string) *Artifact {
+	if l == nil {
+		return nil
+	}
+	for i := range l.Artifacts {
+		if l.Artifacts[i].Name == name && l.Artifacts[i].Platform == platform {
+			return &l.Artifacts[i]
+		}
+	}
+	return nil
+}
+
+// Put adds the artifact, replacing the artifact of the same tool and platform
+func (l *Lockfile) Put(artifact Artifact) {
+	if existing := l.Find(artifact.Name, artifact.Platform); existing != nil {
+		*existing = artifact
+		return
+	}
+	l.Artifacts = append(l.Artifacts, artifact)
+}
+
+// Resolve returns the locked artifact of the pin, nil when it is not locked or the pin has changed since.
+// In frozen mode a missing or outdated artifact is a LockOutdatedError instead
+func (l *Lockfile) Resolve(name string, request string, platform string, frozen bool) (*Artifact, error) {
+	artifact := l.Find(name, platform)
+	if artifact != nil && artifact.Request == request {
+		return artifact, nil
+	}
+	if !frozen {
+		return nil, nil
+	}
+
+	reason := fmt.Sprintf("%s is not locked for %s", name, platform)
+	switch {
+	case l == nil:
+		reason = FileName + " does not exist"
+	case artifact != nil:
+		reason = fmt.Sprintf("%s is locked for %s instead", name, artifact.Request)
+	}
+	return nil, &devrigErrors.LockOutdatedError{Name: name, Request: request, Reason: reason}
+}
@@ -0,0 +1,112 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+func TestLockfile_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+
+	lockfile := &Lockfile{}
+	lockfile.Put(Artifact{Name: "node", Request: "20", Platform: "linux-amd64", Version: "20.11.0", URL: "https://example.com/node.tar.gz", Checksum: "ab"})
+	lockfile.Put(Artifact{Name: "go", Request: "1.22", Platform: "linux-amd64", Version: "1.22.5", URL: "https://example.com/go.tar.gz", Checksum: "cd"})
+	lockfile.Put(Artifact{Name: "node", Request: "20", Platform: "darwin-arm64", Version: "20.11.0", URL: "https://example.com/node-mac.tar.gz", Checksum: "ef"})
+	if err := lockfile.Save(path); err != nil {
+		t.Fatalf("Failed to save lock: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load lock: %v", err)
+	}
+	var order []string
+	for _, artifact := range loaded.Artifacts {
+		order = append(order, artifact.Name+"/"+artifact.Platform)
+	}
+	if strings.Join(order, ",") != "go/linux-amd64,node/darwin-arm64,node/linux-amd64" {
+		t.Errorf("Expected artifacts sorted by name and platform, got %v", order)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read lock: %v", err)
+	}
+	// Empty optional fields are omitted, so the file stays readable in reviews
+	if strings.Contains(string(data), `"file_name"`) || !strings.Contains(string(data), `"checksum": "ab"`) || !strings.HasSuffix(string(data), "}\n") {
+		t.Errorf("Unexpected lock content:\n%s", data)
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	lockfile, err := Load(filepath.Join(t.TempDir(), FileName))
+	if err != nil || lockfile != nil {
+		t.Errorf("Expected nil for a missing lock, got %v, %v", lockfile, err)
+	}
+}
+
+func TestLoad_UnsupportedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(`{"version": 42, "artifacts": []}`), 0644); err != nil {
+		t.Fatalf("Failed to write lock: %v", err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Errorf("Expected an error for an unsupported version")
+	}
+}
+
+func TestLockfile_Resolve(t *testing.T) {
+	lockfile := &Lockfile{}
+	lockfile.Put(Artifact{Name: "node", Request: "20", Platform: "linux-amd64", Version: "20.11.0"})
+
+	artifact, err := lockfile.Resolve("node", "20", "linux-amd64", true)
+	if err != nil || artifact == nil || artifact.Version != "20.11.0" {
+		t.Fatalf("Expected the locked artifact, got %v, %v", artifact, err)
+	}
+
+	// A changed pin is resolved again unless the lock is frozen
+	if artifact, err := lockfile.Resolve("node", "22", "linux-amd64", false); artifact != nil || err != nil {
+		t.Errorf("Expected no artifact for a changed pin, got %v, %v", artifact, err)
+	}
+
+	cases := []struct {
+		name     string
+		lockfile *Lockfile
+		request  string
+		platform string
+		reason   string
+	}{
+		{"missing lock", nil, "20", "linux-amd64", "devrig.lock does not exist"},
+		{"changed pin", lockfile, "22", "linux-amd64", "node is locked for 20 instead"},
+		{"other platform", lockfile, "20", "windows-amd64", "node is not locked for windows-amd64"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := c.lockfile.Resolve("node", c.request, c.platform, true)
+			var outdated *devrigErrors.LockOutdatedError
+			if !errors.As(err, &outdated) {
+				t.Fatalf("Expected LockOutdatedError, got %v", err)
+			}
+			if outdated.Reason != c.reason {
+				t.Errorf("Expected reason %q, got %q", c.reason, outdated.Reason)
+			}
+		})
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	goos, goarch, err := ParsePlatform("darwin-arm64")
+	if err != nil || goos != "darwin" || goarch != "arm64" {
+		t.Errorf("Unexpected platform: %s %s %v", goos, goarch, err)
+	}
+	for _, invalid := range []string{"linux", "-amd64", "linux-"} {
+		if _, _, err := ParsePlatform(invalid); err == nil {
+			t.Errorf("Expected an error for %s", invalid)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package lockcmd
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/install"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/lock"
+)
+
+// TargetOSes and TargetArchs are the platforms the download-only commands prepare artifacts for
+var (
+	TargetOSes  = []string{"darwin", "linux", "windows"}
+	TargetArchs = []string{"amd64", "arm64"}
+)
+
+// Target is the platform of the download-only commands, the current one unless --target-os or --target-arch is set
+type Target struct {
+	OS   string
+	Arch string
+}
+
+// AddTargetFlags registers --target-os and --target-arch on the command
+func AddTargetFlags(cmd *cobra.Command) *Target {
+	target := &Target{}
+	cmd.Flags().StringVar(&target.OS, "target-os", runtime.GOOS, "OS to download for: "+strings.Join(TargetOSes, ", "))
+	cmd.Flags().StringVar(&target.Arch, "target-arch", runtime.GOARCH, "Architecture to download for: "+strings.Join(TargetArchs, ", "))
+	return target
+}
+
+// Platform returns the platform key of devrig.lock, e.g. darwin-arm64, UnsupportedPlatformError for unknown values
+func (t *Target) Platform() (string, error) {
+	if !slices.Contains(TargetOSes, t.OS) {
+		return "", &devrigErrors.UnsupportedPlatformError{OS: t.OS}
+	}
+	if !slices.Contains(TargetArchs, t.Arch) {
+		return "", &devrigErrors.UnsupportedPlatformError{OS: t.OS, Arch: t.Arch}
+	}
+	return lock.Platform(t.OS, t.Arch), nil
+}
+
+// Downloaded is an artifact downloaded for the target platform
+type Downloaded struct {
+	Artifact lock.Artifact
+	Path     string
+}
+
+// DownloadPins downloads the artifacts of the pins for the platform into dir, or into .devrig/downloads/<platform>
+// when dir is empty. The builds locked in devrig.lock are downloaded, the other pins are resolved for the platform.
+// The artifacts are verified but not unpacked, that happens on the machine of the platform
+func DownloadPins(ctx context.Context, configPath string, pins []Pin, platform string, dir string) ([]Downloaded, error) {
+	if dir == "" {
+		dir = layout.ResolvePlatformDownloadsDir(layout.ResolveDevrigHome(configPath), platform)
+	}
+	existing, err := lock.Load(lock.ResolvePath(configPath))
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := Update(ctx, existing, pins, []string{platform}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Downloaded
+	for _, artifact := range resolved.Artifacts {
+		path, err := install.DownloadArtifact(ctx, nil, &artifact, dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s %s for %s: %w", artifact.Name, artifact.Version, platform, err)
+		}
+		result = append(result, Downloaded{Artifact: artifact, Path: path})
+	}
+	return result, nil
+}
+
+// PrintDownloaded reports the downloaded artifacts
+func PrintDownloaded(cmd *cobra.Command, downloaded []Downloaded, platform string) {
+	for _, download := range downloaded {
+		version := download.Artifact.Version
+		if download.Artifact.Build != "" {
+			version += " (" + download.Artifact.Build + ")"
+		}
+		cmd.Printf("Downloaded %s %s for %s to %s\n", download.Artifact.Name, version, platform, download.Path)
+	}
+}
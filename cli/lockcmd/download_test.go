@@ -0,0 +1,82 @@
+package lockcmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/lock"
+)
+
+func TestDownloadPins_TargetPlatform(t *testing.T) {
+	content := []byte("node archive")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	var platforms []string
+	pin := Pin{Name: "node", Request: "20", Source: server.URL,
+		Resolve: func(ctx context.Context, goos string, goarch string) (*lock.Artifact, error) {
+			platforms = append(platforms, lock.Platform(goos, goarch))
+			return &lock.Artifact{Name: "node", Request: "20", Platform: lock.Platform(goos, goarch), Version: "20.11.0",
+				URL: server.URL + "/node-v20.11.0-" + goos + "-" + goarch + ".tar.gz", Checksum: fmt.Sprintf("%x", sha256.Sum256(content))}, nil
+		}}
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+
+	downloaded, err := DownloadPins(context.Background(), configPath, []Pin{pin}, "darwin-arm64", "")
+	if err != nil {
+		t.Fatalf("Failed to download: %v", err)
+	}
+	if len(platforms) != 1 || platforms[0] != "darwin-arm64" {
+		t.Errorf("Expected the pin to be resolved for darwin-arm64, got %v", platforms)
+	}
+	expected := filepath.Join(filepath.Dir(configPath), ".devrig", "downloads", "darwin-arm64", "node-v20.11.0-darwin-arm64.tar.gz")
+	if len(downloaded) != 1 || downloaded[0].Path != expected {
+		t.Fatalf("Expected %s, got %+v", expected, downloaded)
+	}
+	if data, err := os.ReadFile(expected); err != nil || string(data) != string(content) {
+		t.Errorf("Unexpected download: %q, %v", data, err)
+	}
+}
+
+func TestDownloadPins_ChecksumMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tampered"))
+	}))
+	defer server.Close()
+
+	pin := Pin{Name: "go", Request: "1.22", Source: server.URL,
+		Resolve: func(ctx context.Context, goos string, goarch string) (*lock.Artifact, error) {
+			return &lock.Artifact{Name: "go", Request: "1.22", Platform: lock.Platform(goos, goarch), Version: "1.22.5",
+				URL: server.URL + "/go1.22.5.zip", Checksum: strings.Repeat("0", 64)}, nil
+		}}
+	dir := t.TempDir()
+
+	_, err := DownloadPins(context.Background(), filepath.Join(t.TempDir(), "devrig.yaml"), []Pin{pin}, "windows-amd64", dir)
+	var mismatch *devrigErrors.ChecksumMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected ChecksumMismatchError, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go1.22.5.zip")); !os.IsNotExist(err) {
+		t.Errorf("Expected no file after a failed verification: %v", err)
+	}
+}
+
+func TestTarget_Platform(t *testing.T) {
+	if platform, err := (&Target{OS: "darwin", Arch: "arm64"}).Platform(); err != nil || platform != "darwin-arm64" {
+		t.Errorf("Expected darwin-arm64, got %s, %v", platform, err)
+	}
+	var unsupported *devrigErrors.UnsupportedPlatformError
+	if _, err := (&Target{OS: "macos", Arch: "arm64"}).Platform(); !errors.As(err, &unsupported) {
+		t.Errorf("Expected UnsupportedPlatformError, got %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package lockcmd
+
+import (
+	"fmt"
+	"runtime"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/lock"
+)
+
+// NewLockCommand creates the lock command that writes devrig.lock next to devrig.yaml
+func NewLockCommand(configPath func() string) *cobra.Command {
+	var platforms []string
+	var refresh bool
+	var check bool
+
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Resolve the pins of devrig.yaml into devrig.lock",
+		Long: `Resolve the pins of devrig.yaml into devrig.lock.
+
+devrig.yaml expresses the intent, e.g. node 20 or the IDE version, the lock
+records the exact build, URL, size and checksum, so every machine downloads
+byte-identical artifacts. Commit devrig.lock next to devrig.yaml.
+
+Locked are the JDK, the node and go pins of the tools section and the IDE.
+Artifacts are kept while their pin is unchanged, use --update to resolve
+the latest builds again. The current platform is locked by default, use
+--platform to lock other ones, e.g. --platform darwin-arm64.
+
+Use --check in CI to fail if devrig.lock is out of date, nothing is
+resolved or written then. The install commands accept --frozen to fail
+instead of resolving a version that is not locked.
+
+Examples:
+  devrig lock
+  devrig lock --platform linux-amd64 --platform darwin-arm64 --platform windows-amd64
+  devrig lock --update
+  devrig lock --check
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, platform := range platforms {
+				if _, _, err := lock.ParsePlatform(platform); err != nil {
+					return err
+				}
+			}
+
+			pins, err := ProjectPins(configPath())
+			if err != nil {
+				return err
+			}
+			lockPath := lock.ResolvePath(configPath())
+			existing, err := lock.Load(lockPath)
+			if err != nil {
+				return err
+			}
+
+			if check {
+				if err := Check(existing, pins, platforms); err != nil {
+					return err
+				}
+				cmd.Printf("%s is up to date\n", lockPath)
+				return nil
+			}
+
+			updated, err := Update(cmd.Context(), existing, pins, platforms, refresh)
+			if err != nil {
+				return err
+			}
+			if err := updated.Save(lockPath); err != nil {
+				return err
+			}
+
+			if len(updated.Artifacts) == 0 {
+				cmd.Printf("Nothing is pinned in devrig.yaml, wrote an empty %s\n", lockPath)
+				return nil
+			}
+			if err := printArtifacts(cmd, updated); err != nil {
+				return err
+			}
+			cmd.Printf("Wrote %s\n", lockPath)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&platforms, "platform", []string{lock.Platform(runtime.GOOS, runtime.GOARCH)}, "Platforms to lock as <os>-<arch>")
+	cmd.Flags().BoolVar(&refresh, "update", false, "Resolve all pins again instead of keeping the locked builds")
+	cmd.Flags().BoolVar(&check, "check", false, "Fail if devrig.lock is out of date, without writing it")
+	return cmd
+}
+
+func printArtifacts(cmd *cobra.Command, lockfile *lock.Lockfile) error {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tREQUEST\tPLATFORM\tVERSION")
+	for _, artifact := range lockfile.Artifacts {
+		version := artifact.Version
+		if artifact.Build != "" {
+			version += " (" + artifact.Build + ")"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", artifact.Name, artifact.Request, artifact.Platform, version)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,169 @@
+package lockcmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/lock"
+	"jonnyzzz.com/devrig.dev/offline"
+)
+
+// fakePin resolves to the version for every platform and counts the calls
+func fakePin(name string, request string, version string, calls *int) Pin {
+	return Pin{
+		Name:    name,
+		Request: request,
+		Source:  "https://example.com",
+		Resolve: func(ctx context.Context, goos string, goarch string) (*lock.Artifact, error) {
+			*calls++
+			return &lock.Artifact{Name: name, Request: request, Platform: lock.Platform(goos, goarch), Version: version,
+				URL: "https://example.com/" + name + "-" + version + "-" + goos + ".tar.gz", Checksum: "00"}, nil
+		},
+	}
+}
+
+func TestUpdate_KeepsLockedArtifacts(t *testing.T) {
+	calls := 0
+	pins := []Pin{fakePin("node", "20", "20.11.0", &calls)}
+
+	first, err := Update(context.Background(), nil, pins, []string{"linux-amd64"}, false)
+	if err != nil {
+		t.Fatalf("Failed to lock: %v", err)
+	}
+
+	// A newer release does not change the lock until --update
+	pins = []Pin{fakePin("node", "20", "20.12.0", &calls)}
+	second, err := Update(context.Background(), first, pins, []string{"linux-amd64"}, false)
+	if err != nil {
+		t.Fatalf("Failed to lock: %v", err)
+	}
+	if calls != 1 || second.Find("node", "linux-amd64").Version != "20.11.0" {
+		t.Errorf("Expected the locked version to be kept, resolved %d times: %+v", calls, second.Artifacts)
+	}
+
+	refreshed, err := Update(context.Background(), second, pins, []string{"linux-amd64"}, true)
+	if err != nil {
+		t.Fatalf("Failed to lock: %v", err)
+	}
+	if refreshed.Find("node", "linux-amd64").Version != "20.12.0" {
+		t.Errorf("Expected --update to resolve the pin again: %+v", refreshed.Artifacts)
+	}
+}
+
+func TestUpdate_OtherPlatformsAndRemovedPins(t *testing.T) {
+	calls := 0
+	existing := &lock.Lockfile{}
+	existing.Put(lock.Artifact{Name: "node", Request: "20", Platform: "darwin-arm64", Version: "20.11.0"})
+	existing.Put(lock.Artifact{Name: "node", Request: "18", Platform: "windows-amd64", Version: "18.19.0"})
+	existing.Put(lock.Artifact{Name: "go", Request: "1.22", Platform: "linux-amd64", Version: "1.22.5"})
+
+	pins := []Pin{fakePin("node", "20", "20.11.0", &calls)}
+	updated, err := Update(context.Background(), existing, pins, []string{"linux-amd64"}, false)
+	if err != nil {
+		t.Fatalf("Failed to lock: %v", err)
+	}
+
+	if updated.Find("node", "darwin-arm64") == nil {
+		t.Errorf("Expected the artifact of another platform to be kept")
+	}
+	if updated.Find("node", "windows-amd64") != nil {
+		t.Errorf("Expected the artifact of the old pin to be dropped")
+	}
+	if updated.Find("go", "linux-amd64") != nil {
+		t.Errorf("Expected the artifact of a removed pin to be dropped")
+	}
+	if updated.Find("node", "linux-amd64") == nil || len(updated.Artifacts) != 2 {
+		t.Errorf("Unexpected artifacts: %+v", updated.Artifacts)
+	}
+}
+
+func TestUpdate_Offline(t *testing.T) {
+	t.Setenv(offline.EnvOffline, "1")
+
+	calls := 0
+	_, err := Update(context.Background(), nil, []Pin{fakePin("node", "20", "20.11.0", &calls)}, []string{"linux-amd64"}, false)
+	var offlineErr *devrigErrors.OfflineError
+	if !errors.As(err, &offlineErr) || calls != 0 {
+		t.Errorf("Expected OfflineError without resolving, got %v after %d calls", err, calls)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	calls := 0
+	pins := []Pin{fakePin("node", "20", "20.11.0", &calls)}
+	lockfile, err := Update(context.Background(), nil, pins, []string{"linux-amd64"}, false)
+	if err != nil {
+		t.Fatalf("Failed to lock: %v", err)
+	}
+
+	if err := Check(lockfile, pins, []string{"linux-amd64"}); err != nil {
+		t.Errorf("Expected the lock to be up to date: %v", err)
+	}
+
+	changed := []Pin{fakePin("node", "22", "22.1.0", &calls)}
+	err = Check(lockfile, changed, []string{"linux-amd64"})
+	if devrigErrors.ExitCode(err) != devrigErrors.ExitLockOutdated {
+		t.Errorf("Expected LockOutdatedError for a changed pin, got %v", err)
+	}
+}
+
+func TestProjectPins(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	config := `tools:
+  java: jbr-21
+  node: "20"
+  python: "3.12"
+ide:
+  name: IntelliJ IDEA Ultimate
+  version: "2024.3"
+`
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	pins, err := ProjectPins(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read pins: %v", err)
+	}
+
+	var requests []string
+	for _, pin := range pins {
+		requests = append(requests, pin.Name+": "+pin.Request)
+	}
+	expected := []string{"jdk: jbr 21", "node: 20", "ide: IntelliJ IDEA Ultimate 2024.3"}
+	if len(requests) != len(expected) {
+		t.Fatalf("Expected pins %v, got %v", expected, requests)
+	}
+	for i := range expected {
+		if requests[i] != expected[i] {
+			t.Errorf("Expected pins %v, got %v", expected, requests)
+		}
+	}
+}
+
+func TestLockCommand_CheckWithoutLock(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte("tools:\n  node: \"20\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cmd := NewLockCommand(func() string { return configPath })
+	cmd.SetContext(context.Background())
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"--check", "--platform", "linux-amd64"})
+
+	err := cmd.Execute()
+	if devrigErrors.ExitCode(err) != devrigErrors.ExitLockOutdated {
+		t.Errorf("Expected LockOutdatedError, got %v", err)
+	}
+	if _, err := os.Stat(lock.ResolvePath(configPath)); err == nil {
+		t.Errorf("Expected --check to not write devrig.lock")
+	}
+}
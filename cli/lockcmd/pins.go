@@ -0,0 +1,136 @@
+// Package lockcmd implements `devrig lock`, it resolves the pins of devrig.yaml into devrig.lock
+package lockcmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/feed"
+	"jonnyzzz.com/devrig.dev/install"
+	"jonnyzzz.com/devrig.dev/lock"
+	"jonnyzzz.com/devrig.dev/offline"
+)
+
+// Pin is a tool pinned in devrig.yaml that is resolved into an exact artifact for devrig.lock
+type Pin struct {
+	Name    string
+	Request string
+	// Source is the API the pin is resolved with
+	Source  string
+	Resolve func(ctx context.Context, goos string, goarch string) (*lock.Artifact, error)
+}
+
+// ProjectPins returns the pins of devrig.yaml: the JDK, the toolchains of the tools section and the IDE
+func ProjectPins(configPath string) ([]Pin, error) {
+	service := configservice.NewConfigService(configPath)
+	var pins []Pin
+
+	vendor, version, err := install.PinnedJdk(service)
+	if err != nil {
+		return nil, err
+	}
+	if version != "" {
+		resolver, err := install.FindJdkResolver(vendor)
+		if err != nil {
+			return nil, err
+		}
+		pins = append(pins, Pin{
+			Name:    install.JdkLockName,
+			Request: install.JdkLockRequest(vendor, version),
+			Source:  resolver.Source(),
+			Resolve: func(ctx context.Context, goos string, goarch string) (*lock.Artifact, error) {
+				return install.LockJdk(ctx, resolver, vendor, version, goos, goarch)
+			},
+		})
+	}
+
+	tools, err := service.Tools().ReadTools()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range install.ToolchainNames() {
+		version := tools[name]
+		if version == "" {
+			continue
+		}
+		toolchain := install.Toolchains[name]()
+		pins = append(pins, Pin{
+			Name:    toolchain.Name(),
+			Request: version,
+			Source:  toolchain.Source(),
+			Resolve: func(ctx context.Context, goos string, goarch string) (*lock.Artifact, error) {
+				return install.LockToolchain(ctx, toolchain, version, goos, goarch)
+			},
+		})
+	}
+
+	section, err := service.IDE().ReadIde()
+	if err != nil {
+		return nil, err
+	}
+	if section != nil {
+		ide := section.Request()
+		pins = append(pins, Pin{
+			Name:    feed.IdeLockName,
+			Request: feed.IdeLockRequest(ide),
+			Source:  feed.FeedURLs()[0],
+			Resolve: func(ctx context.Context, goos string, goarch string) (*lock.Artifact, error) {
+				return feed.LockIde(ctx, ide, section.PackageTypes, goos, goarch)
+			},
+		})
+	}
+	return pins, nil
+}
+
+// Update returns the lock for the pins on the platforms. Artifacts of the existing lock are kept while their pin
+// is unchanged, unless refresh is set. Artifacts of other platforms are kept too, so the lock can be completed
+// on several machines, and artifacts of removed pins are dropped
+func Update(ctx context.Context, existing *lock.Lockfile, pins []Pin, platforms []string, refresh bool) (*lock.Lockfile, error) {
+	updated := &lock.Lockfile{}
+	for _, pin := range pins {
+		if existing != nil {
+			for _, artifact := range existing.Artifacts {
+				if artifact.Name == pin.Name && artifact.Request == pin.Request && !slices.Contains(platforms, artifact.Platform) {
+					updated.Put(artifact)
+				}
+			}
+		}
+
+		for _, platform := range platforms {
+			if !refresh {
+				if artifact := existing.Find(pin.Name, platform); artifact != nil && artifact.Request == pin.Request {
+					updated.Put(*artifact)
+					continue
+				}
+			}
+
+			goos, goarch, err := lock.ParsePlatform(platform)
+			if err != nil {
+				return nil, err
+			}
+			if err := offline.Check(fmt.Sprintf("%s %s for %s", pin.Name, pin.Request, platform), pin.Source, "run devrig lock on a machine with network access"); err != nil {
+				return nil, err
+			}
+			artifact, err := pin.Resolve(ctx, goos, goarch)
+			if err != nil {
+				return nil, fmt.Errorf("failed to lock %s %s for %s: %w", pin.Name, pin.Request, platform, err)
+			}
+			updated.Put(*artifact)
+		}
+	}
+	return updated, nil
+}
+
+// Check fails with a LockOutdatedError if a pin is not locked for one of the platforms
+func Check(existing *lock.Lockfile, pins []Pin, platforms []string) error {
+	for _, pin := range pins {
+		for _, platform := range platforms {
+			if _, err := existing.Resolve(pin.Name, pin.Request, platform, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// EnvLogLevel is the environment variable to set the log level (debug, info, warn, error)
+const EnvLogLevel = "DEVRIG_LOG_LEVEL"
+
+// Options configures the logging subsystem
+type Options struct {
+	// Verbose enables debug logging, wins over DEVRIG_LOG_LEVEL
+	Verbose bool
+	// Quiet shows only errors, wins over DEVRIG_LOG_LEVEL
+	Quiet bool
+	// JSON switches the console output to JSON lines
+	JSON bool
+	// LogDir is the directory to write log files to, empty disables file logging.
+	// Log files always receive debug level JSON records.
+	LogDir string
+}
+
+type contextKey struct{}
+
+// WithLogger returns a copy of ctx carrying the logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger from the context, or the default logger if there is none
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok && logger != nil {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// ResolveLevel computes the console log level from flags and the DEVRIG_LOG_LEVEL environment variable
+func ResolveLevel(opts Options) (slog.Level, error) {
+	if opts.Verbose && opts.Quiet {
+		return slog.LevelInfo, fmt.Errorf("--verbose and --quiet cannot be used together")
+	}
+	if opts.Verbose {
+		return slog.LevelDebug, nil
+	}
+	if opts.Quiet {
+		return slog.LevelError, nil
+	}
+
+	envLevel := strings.TrimSpace(os.Getenv(EnvLogLevel))
+	if envLevel == "" {
+		return slog.LevelInfo, nil
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(envLevel)); err != nil {
+		return slog.LevelInfo, fmt.Errorf("invalid %s value %q: expected debug, info, warn or error", EnvLogLevel, envLevel)
+	}
+	return level, nil
+}
+
+// Setup creates the logger for the given options and installs it as the default logger.
+// Output of the standard log package is redirected to the logger at debug level.
+// The returned closer must be called to flush and close the log file.
+func Setup(opts Options, console io.Writer) (*slog.Logger, io.Closer, error) {
+	level, err := ResolveLevel(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	handlerOptions := &slog.HandlerOptions{Level: level}
+	var consoleHandler slog.Handler
+	if opts.JSON {
+		consoleHandler = slog.NewJSONHandler(console, handlerOptions)
+	} else {
+		consoleHandler = newConsoleHandler(console, level)
+	}
+
+	handlers := []slog.Handler{consoleHandler}
+	var closer io.Closer = nopCloser{}
+
+	if opts.LogDir != "" {
+		logFile, err := openLogFile(opts.LogDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		closer = logFile
+		handlers = append(handlers, slog.NewJSONHandler(logFile, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	}
+
+	logger := slog.New(&fanoutHandler{handlers: handlers})
+	slog.SetDefault(logger)
+
+	// Route the legacy log.Printf calls through the logger as debug messages
+	log.SetFlags(0)
+	log.SetOutput(&stdLogWriter{logger: logger})
+
+	return logger, closer, nil
+}
+
+// openLogFile opens (appends to) the log file of the current day
+func openLogFile(logDir string) (*os.File, error) {
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory %s: %w", logDir, err)
+	}
+
+	name := filepath.Join(logDir, fmt.Sprintf("devrig-%s.log", time.Now().Format("2006-01-02")))
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file %s: %w", name, err)
+	}
+	return file, nil
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// stdLogWriter adapts the standard log package output to the structured logger
+type stdLogWriter struct {
+	logger *slog.Logger
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	w.logger.Debug(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
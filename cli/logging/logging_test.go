@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveLevel(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     Options
+		env      string
+		expected slog.Level
+		wantErr  bool
+	}{
+		{name: "default", expected: slog.LevelInfo},
+		{name: "verbose", opts: Options{Verbose: true}, expected: slog.LevelDebug},
+		{name: "quiet", opts: Options{Quiet: true}, expected: slog.LevelError},
+		{name: "env", env: "warn", expected: slog.LevelWarn},
+		{name: "flag wins over env", opts: Options{Verbose: true}, env: "error", expected: slog.LevelDebug},
+		{name: "invalid env", env: "loud", wantErr: true},
+		{name: "verbose and quiet", opts: Options{Verbose: true, Quiet: true}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(EnvLogLevel, tc.env)
+			level, err := ResolveLevel(tc.opts)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if level != tc.expected {
+				t.Errorf("Expected level %v, got: %v", tc.expected, level)
+			}
+		})
+	}
+}
+
+func TestSetup_ConsoleAndFile(t *testing.T) {
+	t.Setenv(EnvLogLevel, "")
+	defer slog.SetDefault(slog.Default())
+	defer log.SetOutput(os.Stderr)
+
+	logDir := filepath.Join(t.TempDir(), "logs")
+	var console bytes.Buffer
+	logger, closer, err := Setup(Options{LogDir: logDir}, &console)
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	logger.Info("visible message", "key", "value")
+	logger.Debug("hidden message")
+	log.Printf("legacy message")
+
+	if err := closer.Close(); err != nil {
+		t.Fatalf("Failed to close log file: %v", err)
+	}
+
+	output := console.String()
+	if !strings.Contains(output, "visible message key=value") {
+		t.Errorf("Expected info message on console: %q", output)
+	}
+	if strings.Contains(output, "hidden message") || strings.Contains(output, "legacy message") {
+		t.Errorf("Debug messages must not reach the console: %q", output)
+	}
+
+	files, err := os.ReadDir(logDir)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected one log file, got: %v (%v)", files, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(logDir, files[0].Name()))
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 records in log file, got %d: %s", len(lines), data)
+	}
+	for _, line := range lines {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Errorf("Log file line is not JSON: %q", line)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("Expected default logger for empty context")
+	}
+
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	ctx := WithLogger(context.Background(), logger)
+	if FromContext(ctx) != logger {
+		t.Error("Expected logger from context")
+	}
+}
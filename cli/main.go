@@ -3,113 +3,163 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
-	"path/filepath"
+	"time"
 
 	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/apply"
+	"jonnyzzz.com/devrig.dev/auth"
+	"jonnyzzz.com/devrig.dev/bootstrapcmd"
+	"jonnyzzz.com/devrig.dev/cache"
 	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configcmd"
 	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/deinit"
+	"jonnyzzz.com/devrig.dev/devrig"
+	"jonnyzzz.com/devrig.dev/doctor"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/execcmd"
+	"jonnyzzz.com/devrig.dev/explain"
 	"jonnyzzz.com/devrig.dev/feed"
+	"jonnyzzz.com/devrig.dev/fleet"
+	"jonnyzzz.com/devrig.dev/foreach"
+	"jonnyzzz.com/devrig.dev/ide"
+	"jonnyzzz.com/devrig.dev/identity"
 	initCmd "jonnyzzz.com/devrig.dev/init"
 	"jonnyzzz.com/devrig.dev/install"
+	"jonnyzzz.com/devrig.dev/lockcmd"
+	"jonnyzzz.com/devrig.dev/maintenance"
+	"jonnyzzz.com/devrig.dev/provenance"
+	"jonnyzzz.com/devrig.dev/release"
+	"jonnyzzz.com/devrig.dev/stats"
+	"jonnyzzz.com/devrig.dev/support"
+	"jonnyzzz.com/devrig.dev/tempfile"
+	"jonnyzzz.com/devrig.dev/tools"
+	"jonnyzzz.com/devrig.dev/ui"
 	"jonnyzzz.com/devrig.dev/unpack"
 	"jonnyzzz.com/devrig.dev/updates"
+	"jonnyzzz.com/devrig.dev/upgrade"
 )
 
 func main() {
-	updatesService := updates.NewUpdateService(VersionAndBuild())
+	os.Exit(run())
+}
 
-	rootCmd := newRootCommand(updatesService)
-	rootCmd.AddCommand(NewVersionCommand())
-	rootCmd.AddCommand(initCmd.NewInitCommand(updatesService))
-	rootCmd.AddCommand(install.NewInstallCommand(VersionAndBuild()))
+// run builds and executes the root command, it returns the process exit code.
+// It is the only place where errors are mapped to exit codes, the packages return errors instead of exiting.
+func run() int {
+	globals := &globalOptions{}
+
+	// Interrupted downloads and unpacks must not leave temporary files behind,
+	// and an interrupted run of a version on trial is neither a success nor a crash
+	stopCleanup := tempfile.CleanupOnSignal(func(code int) {
+		globals.cancelTrial()
+		os.Exit(code)
+	})
+	defer stopCleanup()
+
+	// The flags are parsed only when the command executes, so commands resolve the path lazily
+	configPath := globals.configPath
+	configs := func() configservice.ConfigService {
+		return configservice.NewConfigService(configPath())
+	}
 
-	var devrigConfigPath string
-	// Add global --devrig-config flag
-	rootCmd.PersistentFlags().StringVar(&devrigConfigPath, "devrig-config", "", "Path to devrig.yaml configuration file")
+	// Forks and internal distributions may move the release metadata in devrig.yaml
+	updatesClient := updates.NewProjectClient(configs)
+	updatesService := updates.NewUpdateService(VersionAndBuild(), updatesClient, func() string {
+		return devrig.ResolveUpdateChannel(configs())
+	})
 
-	configs := configservice.NewConfigService(ResolveDevrigConfigPath(devrigConfigPath))
-	configs.Binaries()
+	globals.notifier = updates.NewNotifier(updatesService)
+	globals.updatesClient = updatesClient
 
-	executeRootCommand(rootCmd)
-}
+	rootCmd := newRootCommand()
+	globals.register(rootCmd)
 
-// ResolveDevrigConfigPath resolves the path to devrig.yaml using the following precedence:
-// 1. --devrig-config flag
-// 2. DEVRIG_CONFIG environment variable
-// 3. ./devrig.yaml (current directory)
-// Always returns an absolute path.
-func ResolveDevrigConfigPath(devrigConfigPath string) string {
-	var path string
-
-	// 1. Check command-line flag
-	if devrigConfigPath != "" {
-		path = devrigConfigPath
-	} else if envPath := os.Getenv("DEVRIG_CONFIG"); envPath != "" {
-		// 2. Check environment variable
-		path = envPath
-	} else {
-		// 3. Default to current directory
-		path = filepath.Join(".", "devrig.yaml")
+	rootCmd.AddCommand(NewVersionCommand())
+	rootCmd.AddCommand(initCmd.NewInitCommand(updatesService))
+	rootCmd.AddCommand(install.NewInstallCommand(VersionAndBuild(), configs, configPath))
+	rootCmd.AddCommand(tools.NewToolsCommand(configs, configPath))
+	rootCmd.AddCommand(upgrade.NewUpgradeCommand(configPath, updatesClient))
+	rootCmd.AddCommand(upgrade.NewUpdateNoticeCommand(configs, updatesClient))
+	rootCmd.AddCommand(release.NewReleaseCommand(updatesClient))
+	rootCmd.AddCommand(cache.NewCacheCommand(configPath))
+	rootCmd.AddCommand(auth.NewAuthCommand(configs))
+	rootCmd.AddCommand(configcmd.NewConfigCommand(configPath, updatesClient))
+	rootCmd.AddCommand(identity.NewIdentityCommand())
+	rootCmd.AddCommand(explain.NewExplainCommand())
+	rootCmd.AddCommand(stats.NewStatsCommand(configPath))
+	rootCmd.AddCommand(execcmd.NewExecCommand(configPath))
+	rootCmd.AddCommand(lockcmd.NewLockCommand(configPath))
+	rootCmd.AddCommand(provenance.NewSignCommand(configPath))
+	rootCmd.AddCommand(provenance.NewVerifyCommand(configPath))
+	rootCmd.AddCommand(foreach.NewForeachCommand())
+	rootCmd.AddCommand(fleet.NewFleetCommand(configPath, updatesClient))
+	rootCmd.AddCommand(bootstrapcmd.NewBootstrapCommand(configPath))
+	rootCmd.AddCommand(deinit.NewDeinitCommand(configPath))
+	rootCmd.AddCommand(ide.NewIdeCommand(configPath))
+	rootCmd.AddCommand(maintenance.NewMaintenanceCommand(configPath, updatesClient))
+
+	rootCmd.AddCommand(apply.NewApplyCommand(configPath, apply.DefaultSteps()))
+	doctorChecks := []doctor.Check{
+		&doctor.ConfigCheck{},
+		&doctor.PathConflictsCheck{},
+		&doctor.RosettaCheck{Fetcher: updatesClient},
+		&doctor.DefenderCheck{},
+		&doctor.CaseSensitivityCheck{},
+		&doctor.SharedStoreCheck{},
+		&doctor.InstalledCheck{},
+		&doctor.ClockCheck{},
 	}
+	rootCmd.AddCommand(doctor.NewDoctorCommand(configPath, doctorChecks))
+	rootCmd.AddCommand(support.NewSupportBundleCommand(VersionAndBuild(), configPath, doctorChecks))
 
-	// Always return absolute path
-	absPath, err := filepath.Abs(path)
-	if err != nil {
-		// If we can't resolve, return as-is (shouldn't happen in practice)
-		return path
+	uiActions := []ui.Action{
+		ui.ApplyAction(configPath, apply.DefaultSteps()),
+		ui.UpgradeAction(configPath, updatesClient),
+		ui.DoctorAction(configPath, doctorChecks),
 	}
-	return absPath
+	rootCmd.AddCommand(ui.NewUiCommand(configPath, updatesService, uiActions))
+
+	return executeRootCommand(rootCmd, globals)
 }
 
-func newRootCommand(updatesService updates.UpdateService) *cobra.Command {
-	var noUpdates bool
-	rootCmd := &cobra.Command{
+func newRootCommand() *cobra.Command {
+	return &cobra.Command{
 		Use:   "devrig",
 		Short: fmt.Sprintf("Devrig v%s - Your development entry point", VersionAndBuild()),
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Println("Select subcommand to use devrig")
-			fmt.Println("")
+		RunE: func(cmd *cobra.Command, args []string) error {
 			cmd.HelpFunc()(cmd, args)
-			os.Exit(11)
-		},
-		PreRun: func(cmd *cobra.Command, args []string) {
-			if !noUpdates {
-				go func() {
-					//just fetch the update info
-					update, err := updatesService.IsUpdateAvailable()
-					if err == nil && update {
-						fmt.Print("\n\nUpdate available\n\n")
-					}
-				}()
-			}
+			// The help is already shown, only the error message is printed
+			cmd.SilenceUsage = true
+			return &devrigErrors.NoCommandError{}
 		},
 	}
-
-	rootCmd.Flags().BoolVar(&noUpdates, "no-updates", false, "Do not check for updates")
-	return rootCmd
 }
 
-func executeRootCommand(rootCmd *cobra.Command) {
+func executeRootCommand(rootCmd *cobra.Command, globals *globalOptions) int {
 	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
-	} else {
-		os.Exit(0)
+	globals.finishTrial(rootCmd, err)
+	globals.close()
+	code := devrigErrors.ExitCode(err)
+	// CI logs show the way to the remediation steps next to the error
+	if hint := explain.Hint(code); hint != "" {
+		rootCmd.PrintErrln(hint)
 	}
+	globals.notifier.Finish(rootCmd.Context(), rootCmd.ErrOrStderr(), time.Now())
+	return code
 }
 
 //goland:noinspection GoUnusedFunction
-func someOldCode(cmd *cobra.Command) {
+func someOldCode(cmd *cobra.Command) error {
 	//make it disabled
 	if cmd != nil {
-		return
+		return nil
 	}
 
-	localConfig, err := config.ResolveConfig()
+	localConfig, err := config.ResolveConfig(context.Background())
 	if err != nil {
-		log.Fatalf("Failed to resolve configuration: %v\n", err)
+		return fmt.Errorf("failed to resolve configuration: %w", err)
 	}
 
 	fmt.Printf("Configuration loaded from: %s\n", localConfig.ConfigPath())
@@ -121,22 +171,23 @@ func someOldCode(cmd *cobra.Command) {
 
 	remoteIde, err := feed.ResolveRemoteIdeByConfig(localConfig.GetIDE())
 	if err != nil {
-		log.Fatalf("Failed to resolve remote IDE: %v\n", err)
+		return fmt.Errorf("failed to resolve remote IDE: %w", err)
 	}
 
 	fmt.Printf("Found remote IDE: %v\n", remoteIde)
 
 	downloadedIde, err := feed.DownloadFeedEntry(context.Background(), remoteIde, localConfig)
 	if err != nil {
-		log.Fatalf("Failed to download IDE: %v\n", err)
+		return fmt.Errorf("failed to download IDE: %w", err)
 	}
 
 	fmt.Printf("Downloaded IDE to: %s\n", downloadedIde.TargetFile())
 
-	unpackedIde, err := unpack.UnpackIde(localConfig, downloadedIde)
+	unpackedIde, err := unpack.UnpackIde(context.Background(), localConfig, downloadedIde)
 	if err != nil {
-		log.Fatalf("Failed to unpack IDE: %v\n", err)
+		return fmt.Errorf("failed to unpack IDE: %w", err)
 	}
 
 	fmt.Printf("IDE unpacked successfully: %v\n", unpackedIde)
+	return nil
 }
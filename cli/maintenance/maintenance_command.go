@@ -0,0 +1,120 @@
+package maintenance
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// NewMaintenanceCommand creates the maintenance command group
+func NewMaintenanceCommand(configPath func() string, fetcher ChannelFetcher) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "maintenance",
+		Short: "Show and run the recurring maintenance tasks",
+		Long: `Show and run the recurring maintenance tasks enabled in devrig.yaml:
+
+  maintenance:
+    auto_gc: true         # apply the retention policy to the IDE cache
+    auto_prefetch: true   # download the release metadata for offline use
+    interval_hours: 24    # minimal interval between two runs of a task
+
+There is no background process: due tasks run at the end of regular devrig commands.
+`,
+	}
+	cmd.AddCommand(newStatusCommand(configPath, fetcher))
+	cmd.AddCommand(newRunCommand(configPath, fetcher))
+	return cmd
+}
+
+func newStatusCommand(configPath func() string, fetcher ChannelFetcher) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the last and the next run of the tasks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tasks, err := ProjectTasks(configPath(), fetcher)
+			if err != nil {
+				return err
+			}
+			if len(tasks) == 0 {
+				cmd.Println("No maintenance tasks are enabled in devrig.yaml")
+				return nil
+			}
+
+			scheduler, err := NewScheduler()
+			if err != nil {
+				return err
+			}
+			state, err := scheduler.State()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "TASK\tLAST RUN\tNEXT RUN\tLAST ERROR")
+			for _, task := range tasks {
+				last := "never"
+				if taskState, ok := state[task.Key]; ok {
+					last = taskState.LastRun.Local().Format(time.RFC3339)
+				}
+				next := "on the next command"
+				if nextRun := scheduler.NextRun(state, task); nextRun.After(time.Now()) {
+					next = nextRun.Local().Format(time.RFC3339)
+				}
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", task.Name, last, next, state[task.Key].LastError)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func newRunCommand(configPath func() string, fetcher ChannelFetcher) *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Run the due maintenance tasks now",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tasks, err := ProjectTasks(configPath(), fetcher)
+			if err != nil {
+				return err
+			}
+			if len(tasks) == 0 {
+				cmd.Println("No maintenance tasks are enabled in devrig.yaml")
+				return nil
+			}
+
+			scheduler, err := NewScheduler()
+			if err != nil {
+				return err
+			}
+			results, err := scheduler.RunDue(cmd.Context(), tasks, force)
+			if err != nil {
+				return err
+			}
+			if len(results) == 0 {
+				cmd.Println("No maintenance tasks are due, use --force to run them anyway")
+				return nil
+			}
+
+			failed := 0
+			for _, result := range results {
+				if result.Err != nil {
+					failed++
+					cmd.Printf("%s: failed: %v\n", result.Task.Name, result.Err)
+				} else {
+					cmd.Printf("%s: done\n", result.Task.Name)
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d maintenance tasks failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Run all enabled tasks even if they are not due")
+	return cmd
+}
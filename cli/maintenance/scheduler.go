@@ -0,0 +1,153 @@
+// Package maintenance runs recurring maintenance tasks, like the cache gc and the metadata prefetch,
+// opportunistically at the end of regular commands. There is no daemon: a task runs when a command
+// finishes and the task was not executed for its interval, the runs are recorded in the user state.
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// StateFileName is the name of the scheduler state in the user state folder
+const StateFileName = "maintenance.json"
+
+// defaultTaskTimeout keeps a command from being delayed for long by its maintenance
+const defaultTaskTimeout = 30 * time.Second
+
+// Task is a recurring maintenance action
+type Task struct {
+	// Key identifies the task in the state, it includes the project or channel the task is for
+	Key      string
+	Name     string
+	Interval time.Duration
+	Timeout  time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// TaskState records the last run of a task
+type TaskState struct {
+	LastRun   time.Time `json:"last_run"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// Result is the outcome of a task executed by the scheduler
+type Result struct {
+	Task Task
+	Err  error
+}
+
+// Scheduler runs the tasks which are due and records their runs in a state file
+type Scheduler struct {
+	StatePath string
+	now       func() time.Time
+}
+
+// NewScheduler creates the scheduler with the state in the user state folder
+func NewScheduler() (*Scheduler, error) {
+	dir, err := layout.ResolveUserStateDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Scheduler{StatePath: filepath.Join(dir, StateFileName)}, nil
+}
+
+// State returns the recorded runs of the tasks by key
+func (s *Scheduler) State() (map[string]TaskState, error) {
+	data, err := os.ReadFile(s.StatePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]TaskState{}, nil
+		}
+		return nil, fmt.Errorf("failed to read maintenance state: %w", err)
+	}
+
+	var state struct {
+		Tasks map[string]TaskState `json:"tasks"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil || state.Tasks == nil {
+		// A broken state only makes the tasks run once more
+		return map[string]TaskState{}, nil
+	}
+	return state.Tasks, nil
+}
+
+// NextRun returns when the task is due, a task which has never run is due immediately
+func (s *Scheduler) NextRun(state map[string]TaskState, task Task) time.Time {
+	last, ok := state[task.Key]
+	if !ok {
+		return time.Time{}
+	}
+	return last.LastRun.Add(task.Interval)
+}
+
+// RunDue executes the tasks which are due, or all tasks with force.
+// A task is recorded before it runs, so concurrent commands do not execute it twice.
+func (s *Scheduler) RunDue(ctx context.Context, tasks []Task, force bool) ([]Result, error) {
+	state, err := s.State()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock()
+	var due []Task
+	for _, task := range tasks {
+		if force || !now.Before(s.NextRun(state, task)) {
+			due = append(due, task)
+			state[task.Key] = TaskState{LastRun: now}
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	if err := s.save(state); err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, task := range due {
+		timeout := task.Timeout
+		if timeout <= 0 {
+			timeout = defaultTaskTimeout
+		}
+		taskCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := task.Run(taskCtx)
+		cancel()
+
+		results = append(results, Result{Task: task, Err: err})
+		if err != nil {
+			state[task.Key] = TaskState{LastRun: now, LastError: err.Error()}
+		}
+	}
+	return results, s.save(state)
+}
+
+func (s *Scheduler) save(state map[string]TaskState) error {
+	data, err := json.MarshalIndent(struct {
+		Tasks map[string]TaskState `json:"tasks"`
+	}{Tasks: state}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal maintenance state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.StatePath), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := tempfile.WriteFile(s.StatePath, data, 0600); err != nil {
+		return fmt.Errorf("failed to save maintenance state: %w", err)
+	}
+	return nil
+}
+
+func (s *Scheduler) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,125 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+func TestScheduler_RunDue(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	scheduler := &Scheduler{StatePath: filepath.Join(t.TempDir(), StateFileName), now: func() time.Time { return now }}
+
+	runs := 0
+	tasks := []Task{{Key: "gc:/cache", Name: "cache gc", Interval: 24 * time.Hour, Run: func(ctx context.Context) error {
+		runs++
+		return nil
+	}}}
+
+	if _, err := scheduler.RunDue(context.Background(), tasks, false); err != nil {
+		t.Fatalf("Failed to run tasks: %v", err)
+	}
+	if runs != 1 {
+		t.Fatalf("Expected a task which never ran to be due, ran %d times", runs)
+	}
+
+	now = now.Add(23 * time.Hour)
+	results, err := scheduler.RunDue(context.Background(), tasks, false)
+	if err != nil {
+		t.Fatalf("Failed to run tasks: %v", err)
+	}
+	if runs != 1 || len(results) != 0 {
+		t.Errorf("Expected the task not to be due within the interval, ran %d times", runs)
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := scheduler.RunDue(context.Background(), tasks, false); err != nil {
+		t.Fatalf("Failed to run tasks: %v", err)
+	}
+	if runs != 2 {
+		t.Errorf("Expected the task to be due after the interval, ran %d times", runs)
+	}
+
+	if _, err := scheduler.RunDue(context.Background(), tasks, true); err != nil {
+		t.Fatalf("Failed to run tasks: %v", err)
+	}
+	if runs != 3 {
+		t.Errorf("Expected --force to run the task, ran %d times", runs)
+	}
+}
+
+func TestScheduler_RecordsFailure(t *testing.T) {
+	scheduler := &Scheduler{StatePath: filepath.Join(t.TempDir(), StateFileName)}
+	tasks := []Task{{Key: "prefetch:stable", Name: "metadata prefetch", Interval: time.Hour, Run: func(ctx context.Context) error {
+		return fmt.Errorf("network is down")
+	}}}
+
+	results, err := scheduler.RunDue(context.Background(), tasks, false)
+	if err != nil {
+		t.Fatalf("Failed to run tasks: %v", err)
+	}
+	if len(results) != 1 || results[0].Err == nil {
+		t.Fatalf("Expected the failure in the results: %+v", results)
+	}
+
+	state, err := scheduler.State()
+	if err != nil {
+		t.Fatalf("Failed to read state: %v", err)
+	}
+	if state["prefetch:stable"].LastError != "network is down" {
+		t.Errorf("Expected the error to be recorded: %+v", state)
+	}
+	if scheduler.NextRun(state, tasks[0]).IsZero() {
+		t.Errorf("Expected a failed task to wait for the interval too")
+	}
+}
+
+func TestProjectTasks(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	content := `maintenance:
+  auto_gc: true
+  auto_prefetch: true
+  interval_hours: 6
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tasks, err := ProjectTasks(configPath, updates.NewClient())
+	if err != nil {
+		t.Fatalf("Failed to resolve tasks: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Name != "cache gc" || tasks[1].Key != "prefetch:stable" || tasks[0].Interval != 6*time.Hour {
+		t.Errorf("Unexpected tasks: %+v", tasks)
+	}
+
+	t.Setenv(offline.EnvOffline, "1")
+	tasks, err = ProjectTasks(configPath, updates.NewClient())
+	if err != nil {
+		t.Fatalf("Failed to resolve tasks: %v", err)
+	}
+	if len(tasks) != 1 {
+		t.Errorf("Expected no prefetch in offline mode, got: %+v", tasks)
+	}
+}
+
+func TestProjectTasks_DisabledByDefault(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte("tools:\n  node: 20.11.0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tasks, err := ProjectTasks(configPath, updates.NewClient())
+	if err != nil {
+		t.Fatalf("Failed to resolve tasks: %v", err)
+	}
+	if len(tasks) != 0 {
+		t.Errorf("Expected no tasks without the maintenance section, got: %+v", tasks)
+	}
+}
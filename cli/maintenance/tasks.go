@@ -0,0 +1,106 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/cache"
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/devrig"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// ChannelFetcher downloads and verifies the release metadata of a channel
+type ChannelFetcher interface {
+	FetchChannelUpdateInfo(channel string) (*updates.UpdateInfo, error)
+}
+
+// ProjectTasks returns the tasks enabled in the maintenance section of the devrig.yaml
+func ProjectTasks(configPath string, fetcher ChannelFetcher) ([]Task, error) {
+	configs := configservice.NewConfigService(configPath)
+	section, err := configs.Maintenance().ReadMaintenance()
+	if err != nil {
+		return nil, err
+	}
+	interval := time.Duration(section.IntervalHours) * time.Hour
+
+	var tasks []Task
+	if section.AutoGC {
+		cacheDir := config.ResolveCacheDir(configPath)
+		tasks = append(tasks, Task{
+			Key:      "gc:" + cacheDir,
+			Name:     "cache gc",
+			Interval: interval,
+			Run: func(ctx context.Context) error {
+				if err := cache.EnforceRetention(ctx, configPath, cacheDir); err != nil {
+					return err
+				}
+				return cache.EnforceDevrigBinaryRetention(ctx, configs, layout.ResolveDevrigHome(configPath))
+			},
+		})
+	}
+
+	// The metadata is fetched from the network, it can only be prefetched online
+	if section.AutoPrefetch && !offline.Enabled() {
+		channel := devrig.ResolveUpdateChannel(configs)
+		tasks = append(tasks, Task{
+			Key:      "prefetch:" + channel,
+			Name:     "metadata prefetch",
+			Interval: interval,
+			Run: func(ctx context.Context) error {
+				info, err := fetcher.FetchChannelUpdateInfo(channel)
+				if err != nil {
+					return err
+				}
+				metadata, err := updates.DefaultMetadataCache()
+				if err != nil {
+					return err
+				}
+				return metadata.Save(channel, info, time.Now())
+			},
+		})
+	}
+	return tasks, nil
+}
+
+// RunOpportunistically executes the due tasks of the project at the end of a command.
+// Maintenance never fails the command, problems are only logged.
+func RunOpportunistically(ctx context.Context, configPath string, fetcher ChannelFetcher) {
+	logger := logging.FromContext(ctx)
+
+	tasks, err := ProjectTasks(configPath, fetcher)
+	if err != nil {
+		var notFound *devrigErrors.ConfigNotFoundError
+		if !errors.As(err, &notFound) {
+			logger.Debug("failed to read the maintenance section", "error", err)
+		}
+		return
+	}
+	if len(tasks) == 0 {
+		return
+	}
+
+	scheduler, err := NewScheduler()
+	if err != nil {
+		logger.Debug("failed to create the maintenance scheduler", "error", err)
+		return
+	}
+
+	results, err := scheduler.RunDue(ctx, tasks, false)
+	if err != nil {
+		logger.Debug("failed to run maintenance", "error", err)
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			logger.Debug("maintenance task failed", "task", result.Task.Name, "error", result.Err)
+		} else {
+			logger.Debug("maintenance task completed", "task", result.Task.Name)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// Package offline implements the air-gapped mode, in which devrig never accesses the network
+// and relies on the local caches only
+package offline
+
+import (
+	"os"
+	"strings"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// EnvOffline enables the offline mode when set to 1 or true.
+// The --offline flag sets it too, so child processes inherit the mode.
+const EnvOffline = "DEVRIG_OFFLINE"
+
+// Enabled checks if the offline mode is on
+func Enabled() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(EnvOffline))) {
+	case "1", "true", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// Check returns an OfflineError for the artifact in offline mode and nil otherwise.
+// Call it right before a network request, once the local caches did not have the artifact.
+func Check(artifact string, url string, hint string) error {
+	if !Enabled() {
+		return nil
+	}
+	return &devrigErrors.OfflineError{Artifact: artifact, URL: url, Hint: hint}
+}
@@ -0,0 +1,29 @@
+package offline
+
+import (
+	"errors"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+func TestCheck(t *testing.T) {
+	t.Setenv(EnvOffline, "")
+	if err := Check("latest.json", "https://devrig.dev/download/latest.json", ""); err != nil {
+		t.Fatalf("Expected no error when online, got: %v", err)
+	}
+
+	for _, value := range []string{"1", "true", "TRUE"} {
+		t.Setenv(EnvOffline, value)
+		err := Check("latest.json", "https://devrig.dev/download/latest.json", "copy it")
+		var offlineErr *devrigErrors.OfflineError
+		if !errors.As(err, &offlineErr) || offlineErr.Artifact != "latest.json" {
+			t.Errorf("Expected OfflineError for %s=%s, got: %v", EnvOffline, value, err)
+		}
+	}
+
+	t.Setenv(EnvOffline, "0")
+	if Enabled() {
+		t.Errorf("Expected %s=0 to keep the network enabled", EnvOffline)
+	}
+}
@@ -0,0 +1,42 @@
+// Package output holds the output format selected with the global --output flag
+package output
+
+import (
+	"context"
+	"fmt"
+)
+
+// Format is the format of the command output
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat validates the value of the --output flag
+func ParseFormat(value string) (Format, error) {
+	switch Format(value) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported output format: %s (expected text or json)", value)
+	}
+}
+
+type formatKey struct{}
+
+// WithFormat returns a context with the selected output format
+func WithFormat(ctx context.Context, format Format) context.Context {
+	return context.WithValue(ctx, formatKey{}, format)
+}
+
+// FormatFromContext returns the selected output format, text by default
+func FormatFromContext(ctx context.Context) Format {
+	if format, ok := ctx.Value(formatKey{}).(Format); ok {
+		return format
+	}
+	return FormatText
+}
@@ -0,0 +1,145 @@
+// Package plugins installs the plugins of the ide section of devrig.yaml from the JetBrains Marketplace
+// into the unpacked IDE, so the IDE starts with them the first time it is launched
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/progress"
+)
+
+// DefaultMarketplaceURL is the JetBrains Marketplace
+const DefaultMarketplaceURL = "https://plugins.jetbrains.com"
+
+// EnvMarketplaceURL is the environment variable to use a mirror of the JetBrains Marketplace
+const EnvMarketplaceURL = "DEVRIG_PLUGINS_URL"
+
+// Release is a version of a plugin to download
+type Release struct {
+	ID      string
+	Version string
+	URL     string
+}
+
+// Marketplace resolves and downloads plugins with the API of the JetBrains Marketplace
+type Marketplace struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewMarketplace returns the client of the JetBrains Marketplace or of the mirror of DEVRIG_PLUGINS_URL
+func NewMarketplace() *Marketplace {
+	baseURL := strings.TrimSpace(os.Getenv(EnvMarketplaceURL))
+	if baseURL == "" {
+		baseURL = DefaultMarketplaceURL
+	}
+	return &Marketplace{BaseURL: strings.TrimSuffix(baseURL, "/"), Client: &http.Client{}}
+}
+
+// compatibleUpdate is an entry of the compatible updates search of the Marketplace API
+type compatibleUpdate struct {
+	ID          int    `json:"id"`
+	PluginXMLID string `json:"pluginXmlId"`
+	Version     string `json:"version"`
+}
+
+// Resolve returns the release of the plugin to download, the version of devrig.yaml or the latest one
+// compatible with the build of the IDE, e.g. GO-243.21565.193
+func (m *Marketplace) Resolve(ctx context.Context, plugin configservice.IdePlugin, build string) (*Release, error) {
+	if plugin.Version != "" {
+		query := url.Values{"pluginId": {plugin.ID}, "version": {plugin.Version}}
+		return &Release{ID: plugin.ID, Version: plugin.Version, URL: m.BaseURL + "/plugin/download?" + query.Encode()}, nil
+	}
+	return m.Compatible(ctx, plugin.ID, build)
+}
+
+// Compatible returns the latest release of the plugin compatible with the build of the IDE, e.g. GO-243.21565.193
+func (m *Marketplace) Compatible(ctx context.Context, id string, build string) (*Release, error) {
+	searchURL := m.BaseURL + "/api/search/compatibleUpdates"
+	if err := offline.Check("plugin "+id, searchURL, "set the version of the plugin and install it on a machine with network access"); err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(map[string]any{"build": build, "pluginXMLIds": []string{id}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the search request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", searchURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return nil, &devrigErrors.NetworkError{URL: searchURL, Err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &devrigErrors.NetworkError{URL: searchURL, Err: fmt.Errorf("search returned status %d", resp.StatusCode)}
+	}
+
+	var updates []compatibleUpdate
+	if err := json.NewDecoder(resp.Body).Decode(&updates); err != nil {
+		return nil, fmt.Errorf("failed to parse the compatible updates of %s: %w", id, err)
+	}
+	for _, update := range updates {
+		if update.PluginXMLID == id {
+			query := url.Values{"updateId": {strconv.Itoa(update.ID)}}
+			return &Release{ID: id, Version: update.Version, URL: m.BaseURL + "/plugin/download?" + query.Encode()}, nil
+		}
+	}
+	return nil, fmt.Errorf("plugin %s has no version compatible with %s in %s", id, build, m.BaseURL)
+}
+
+// Download saves the release into destPath, it returns the file name the Marketplace published the plugin with
+func (m *Marketplace) Download(ctx context.Context, release *Release, destPath string) (string, error) {
+	if err := offline.Check("plugin "+release.ID+" "+release.Version, release.URL, "install the plugin on a machine with network access"); err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", release.URL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return "", &devrigErrors.NetworkError{URL: release.URL, Err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", &devrigErrors.NetworkError{URL: release.URL, Err: fmt.Errorf("download returned status %d", resp.StatusCode)}
+	}
+
+	out, err := os.Create(destPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer out.Close()
+
+	tracker := progress.Track(ctx, "Downloading "+release.ID, resp.ContentLength, 0)
+	_, err = io.Copy(out, tracker.Reader(resp.Body))
+	tracker.Finish()
+	if err != nil {
+		return "", fmt.Errorf("failed to save file: %w", err)
+	}
+
+	// The download redirects to the file, its name tells a .jar from a .zip
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		return path.Base(params["filename"]), nil
+	}
+	return path.Base(resp.Request.URL.Path), nil
+}
@@ -0,0 +1,236 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/contentstore"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/extract"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// stateFileName records the plugins devrig installed into the plugins folder of the IDE
+const stateFileName = ".devrig-plugins.json"
+
+// Installed is a plugin in the plugins folder of the IDE
+type Installed struct {
+	ID      string `json:"id"`
+	Version string `json:"version"`
+	// Request is the version of devrig.yaml, empty for the latest compatible one
+	Request string `json:"request,omitempty"`
+	// Path is the plugin folder or the .jar file, relative to the plugins folder
+	Path string `json:"path"`
+	// Checksum is the hex SHA-256 of the download
+	Checksum string `json:"checksum"`
+	// Downloaded is set when the plugin was installed now, it is not recorded
+	Downloaded bool `json:"-"`
+}
+
+// state is the content of .devrig-plugins.json
+type state struct {
+	Plugins []Installed `json:"plugins"`
+}
+
+// Dir returns the plugins folder of the unpacked IDE, it is inside the bundle on macOS
+func Dir(home string, goos string) string {
+	if goos == "darwin" {
+		return filepath.Join(home, "Contents", "plugins")
+	}
+	return filepath.Join(home, "plugins")
+}
+
+// Install brings the plugins folder in line with the plugins of devrig.yaml. Plugins that are installed with the
+// requested version are kept, the others are downloaded for the build of the IDE, e.g. GO-243.21565.193, verified
+// against the checksum of devrig.yaml and unpacked. Plugins devrig installed before that are no longer requested
+// are removed, the bundled plugins of the IDE are never touched. The caller holds the lock of the IDE
+func Install(ctx context.Context, marketplace *Marketplace, pluginsDir string, build string, requested []configservice.IdePlugin) ([]Installed, error) {
+	current, err := readState(pluginsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var installed []Installed
+	for _, plugin := range requested {
+		existing := current.find(plugin.ID)
+		if existing != nil && existing.Request == plugin.Version && (plugin.Checksum == "" || strings.EqualFold(plugin.Checksum, existing.Checksum)) {
+			if _, err := os.Stat(filepath.Join(pluginsDir, existing.Path)); err == nil {
+				installed = append(installed, *existing)
+				continue
+			}
+		}
+
+		result, err := installPlugin(ctx, marketplace, pluginsDir, build, plugin)
+		if err != nil {
+			return nil, err
+		}
+		// A new version may come with another folder or file name, the previous one must not be loaded too
+		if existing != nil && existing.Path != result.Path {
+			if err := os.RemoveAll(filepath.Join(pluginsDir, existing.Path)); err != nil {
+				return nil, fmt.Errorf("failed to remove the previous version of plugin %s: %w", plugin.ID, err)
+			}
+		}
+		installed = append(installed, *result)
+		if err := writeState(pluginsDir, merge(current, installed)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, previous := range current.Plugins {
+		if (&state{Plugins: installed}).find(previous.ID) != nil {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(pluginsDir, previous.Path)); err != nil {
+			return nil, fmt.Errorf("failed to remove plugin %s: %w", previous.ID, err)
+		}
+		logging.FromContext(ctx).Info("removed plugin that is no longer in devrig.yaml", "plugin", previous.ID)
+	}
+	if err := writeState(pluginsDir, &state{Plugins: installed}); err != nil {
+		return nil, err
+	}
+	return installed, nil
+}
+
+// installPlugin downloads, verifies and unpacks the plugin into the plugins folder
+func installPlugin(ctx context.Context, marketplace *Marketplace, pluginsDir string, build string, plugin configservice.IdePlugin) (*Installed, error) {
+	release, err := marketplace.Resolve(ctx, plugin, build)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve plugin %s: %w", plugin.ID, err)
+	}
+
+	if err := os.MkdirAll(pluginsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", pluginsDir, err)
+	}
+	// The temp folder is next to the target, so the final rename never crosses filesystems
+	tempDir, err := tempfile.Mkdir(pluginsDir, ".download-*")
+	if err != nil {
+		return nil, err
+	}
+	defer tempfile.Remove(tempDir)
+
+	archivePath := filepath.Join(tempDir, "plugin")
+	fileName, err := marketplace.Download(ctx, release, archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download plugin %s %s: %w", release.ID, release.Version, err)
+	}
+
+	actual, err := contentstore.FileSHA256(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	if plugin.Checksum == "" {
+		// The Marketplace publishes no checksums, devrig.yaml pins the one of the reviewed download
+		logging.FromContext(ctx).Info("the plugin has no checksum in devrig.yaml, add it to pin the download",
+			"plugin", release.ID, "version", release.Version, "checksum", actual)
+	} else if !strings.EqualFold(plugin.Checksum, actual) {
+		return nil, &devrigErrors.ChecksumMismatchError{Subject: fileName, Expected: plugin.Checksum, Actual: actual}
+	}
+
+	// A plugin is a single .jar or a .zip with the plugin folder
+	var root string
+	if strings.EqualFold(filepath.Ext(fileName), ".jar") {
+		root = filepath.Join(tempDir, filepath.Base(fileName))
+		if err := os.Rename(archivePath, root); err != nil {
+			return nil, fmt.Errorf("failed to rename %s: %w", fileName, err)
+		}
+	} else {
+		limits, err := extract.ResolveLimits()
+		if err != nil {
+			return nil, err
+		}
+		unpacked := filepath.Join(tempDir, "unpacked")
+		if err := extract.Zip(archivePath, unpacked, limits); err != nil {
+			return nil, fmt.Errorf("failed to extract plugin %s: %w", release.ID, err)
+		}
+		if root, err = pluginRoot(unpacked); err != nil {
+			return nil, fmt.Errorf("failed to install plugin %s: %w", release.ID, err)
+		}
+	}
+
+	target := filepath.Join(pluginsDir, filepath.Base(root))
+	if err := os.RemoveAll(target); err != nil {
+		return nil, fmt.Errorf("failed to remove incomplete installation: %w", err)
+	}
+	if err := os.Rename(root, target); err != nil {
+		return nil, fmt.Errorf("failed to move plugin %s into place: %w", release.ID, err)
+	}
+	return &Installed{ID: release.ID, Version: release.Version, Request: plugin.Version, Path: filepath.Base(root), Checksum: actual, Downloaded: true}, nil
+}
+
+// pluginRoot returns the single folder of the unpacked plugin archive
+func pluginRoot(unpacked string) (string, error) {
+	entries, err := os.ReadDir(unpacked)
+	if err != nil {
+		return "", fmt.Errorf("failed to list the plugin archive: %w", err)
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return "", fmt.Errorf("expected a single folder in the plugin archive, found %d entries", len(entries))
+	}
+	return filepath.Join(unpacked, entries[0].Name()), nil
+}
+
+// find returns the recorded plugin with the ID, or nil
+func (s *state) find(id string) *Installed {
+	for i := range s.Plugins {
+		if s.Plugins[i].ID == id {
+			return &s.Plugins[i]
+		}
+	}
+	return nil
+}
+
+// merge returns the recorded plugins with the installed ones replacing theirs, so an interrupted run keeps track
+// of the plugins installed so far
+func merge(current *state, installed []Installed) *state {
+	merged := &state{Plugins: installed}
+	for _, previous := range current.Plugins {
+		if merged.find(previous.ID) == nil {
+			merged.Plugins = append(merged.Plugins, previous)
+		}
+	}
+	return merged
+}
+
+// readState reads .devrig-plugins.json of the plugins folder, it is empty when devrig installed no plugins yet
+func readState(pluginsDir string) (*state, error) {
+	path := filepath.Join(pluginsDir, stateFileName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &state{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var result state
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &result, nil
+}
+
+// writeState records the installed plugins in .devrig-plugins.json
+func writeState(pluginsDir string, s *state) error {
+	if len(s.Plugins) == 0 {
+		if err := os.Remove(filepath.Join(pluginsDir, stateFileName)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", stateFileName, err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", stateFileName, err)
+	}
+	if err := os.MkdirAll(pluginsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", pluginsDir, err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginsDir, stateFileName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", stateFileName, err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package plugins
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/extract"
+)
+
+// pluginZip returns a plugin archive with the plugin folder
+func pluginZip(t *testing.T, folder string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	file, err := writer.Create(folder + "/lib/" + folder + ".jar")
+	if err != nil {
+		t.Fatalf("Failed to create zip entry: %v", err)
+	}
+	if _, err := file.Write([]byte("jar")); err != nil {
+		t.Fatalf("Failed to write zip entry: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+// fakeMarketplace serves the compatible updates search and the downloads, it counts the downloads
+func fakeMarketplace(t *testing.T, downloads *int) *Marketplace {
+	t.Helper()
+	archive := pluginZip(t, "kotest")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/search/compatibleUpdates":
+			var request struct {
+				Build string   `json:"build"`
+				IDs   []string `json:"pluginXMLIds"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Build != "GO-243.1" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode([]compatibleUpdate{{ID: 42, PluginXMLID: "kotest-plugin-intellij", Version: "1.3.0"}})
+		case "/plugin/download":
+			*downloads++
+			if r.URL.Query().Get("pluginId") == "ideavim" {
+				w.Header().Set("Content-Disposition", `attachment; filename="IdeaVim-2.10.0.jar"`)
+				_, _ = w.Write([]byte("jar"))
+				return
+			}
+			w.Header().Set("Content-Disposition", `attachment; filename="kotest-1.3.0.zip"`)
+			_, _ = w.Write(archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return &Marketplace{BaseURL: server.URL, Client: server.Client()}
+}
+
+func TestInstall(t *testing.T) {
+	var downloads int
+	marketplace := fakeMarketplace(t, &downloads)
+	pluginsDir := filepath.Join(t.TempDir(), "plugins")
+	requested := []configservice.IdePlugin{{ID: "kotest-plugin-intellij"}, {ID: "ideavim", Version: "2.10.0"}}
+
+	installed, err := Install(context.Background(), marketplace, pluginsDir, "GO-243.1", requested)
+	if err != nil {
+		t.Fatalf("Failed to install the plugins: %v", err)
+	}
+	if len(installed) != 2 || installed[0].Version != "1.3.0" || installed[0].Path != "kotest" || installed[1].Path != "IdeaVim-2.10.0.jar" {
+		t.Fatalf("Unexpected plugins: %+v", installed)
+	}
+	for _, path := range []string{"kotest/lib/kotest.jar", "IdeaVim-2.10.0.jar"} {
+		if _, err := os.Stat(filepath.Join(pluginsDir, path)); err != nil {
+			t.Errorf("Expected %s in the plugins folder: %v", path, err)
+		}
+	}
+
+	// The installed plugins are kept, the dropped one is removed
+	installed, err = Install(context.Background(), marketplace, pluginsDir, "GO-243.1", requested[:1])
+	if err != nil {
+		t.Fatalf("Failed to install the plugins again: %v", err)
+	}
+	if downloads != 2 || len(installed) != 1 || installed[0].Downloaded {
+		t.Errorf("Expected the installed plugin to be reused, got %d downloads and %+v", downloads, installed)
+	}
+	if _, err := os.Stat(filepath.Join(pluginsDir, "IdeaVim-2.10.0.jar")); !os.IsNotExist(err) {
+		t.Errorf("Expected the dropped plugin to be removed: %v", err)
+	}
+}
+
+func TestInstall_ExtractionLimits(t *testing.T) {
+	var downloads int
+	marketplace := fakeMarketplace(t, &downloads)
+	t.Setenv(extract.EnvMaxUnpackedSize, "2")
+
+	_, err := Install(context.Background(), marketplace, filepath.Join(t.TempDir(), "plugins"), "GO-243.1",
+		[]configservice.IdePlugin{{ID: "kotest-plugin-intellij"}})
+	var limit *devrigErrors.DecompressionLimitError
+	if !errors.As(err, &limit) {
+		t.Errorf("Expected %s to limit the plugin, got %v", extract.EnvMaxUnpackedSize, err)
+	}
+}
+
+func TestInstall_VerifiesChecksum(t *testing.T) {
+	var downloads int
+	marketplace := fakeMarketplace(t, &downloads)
+	pluginsDir := filepath.Join(t.TempDir(), "plugins")
+
+	wrong := hex.EncodeToString(make([]byte, sha256.Size))
+	_, err := Install(context.Background(), marketplace, pluginsDir, "GO-243.1", []configservice.IdePlugin{{ID: "ideavim", Version: "2.10.0", Checksum: wrong}})
+	var mismatch *devrigErrors.ChecksumMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected a checksum mismatch, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(pluginsDir, "IdeaVim-2.10.0.jar")); !os.IsNotExist(err) {
+		t.Errorf("Expected no plugin to be installed: %v", err)
+	}
+
+	sum := sha256.Sum256([]byte("jar"))
+	if _, err := Install(context.Background(), marketplace, pluginsDir, "GO-243.1",
+		[]configservice.IdePlugin{{ID: "ideavim", Version: "2.10.0", Checksum: hex.EncodeToString(sum[:])}}); err != nil {
+		t.Errorf("Expected the plugin with the checksum to install: %v", err)
+	}
+}
+
+func TestMarketplace_ResolveIncompatible(t *testing.T) {
+	var downloads int
+	marketplace := fakeMarketplace(t, &downloads)
+	if _, err := marketplace.Resolve(context.Background(), configservice.IdePlugin{ID: "org.rust.lang"}, "GO-243.1"); err == nil {
+		t.Error("Expected an error for a plugin without a compatible version")
+	}
+}
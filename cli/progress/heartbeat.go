@@ -0,0 +1,94 @@
+package progress
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// EnvHeartbeatInterval overrides the heartbeat interval, e.g. 1m, 0 disables heartbeats
+	EnvHeartbeatInterval = "DEVRIG_HEARTBEAT_INTERVAL"
+	// DefaultHeartbeatInterval is below the output inactivity timeouts of common CI systems
+	DefaultHeartbeatInterval = 30 * time.Second
+)
+
+// HeartbeatOptions configures the heartbeat lines printed during long silent operations
+type HeartbeatOptions struct {
+	// Interval between heartbeat lines, zero disables heartbeats
+	Interval time.Duration
+	Out      io.Writer
+}
+
+type heartbeatKey struct{}
+
+// WithHeartbeat returns a context that enables heartbeats for long operations
+func WithHeartbeat(ctx context.Context, opts HeartbeatOptions) context.Context {
+	return context.WithValue(ctx, heartbeatKey{}, opts)
+}
+
+// ResolveHeartbeatInterval returns the heartbeat interval from DEVRIG_HEARTBEAT_INTERVAL,
+// or DefaultHeartbeatInterval if it is not set
+func ResolveHeartbeatInterval() (time.Duration, error) {
+	value := os.Getenv(EnvHeartbeatInterval)
+	if value == "" {
+		return DefaultHeartbeatInterval, nil
+	}
+	if value == "0" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s=%s: %w", EnvHeartbeatInterval, value, err)
+	}
+	return interval, nil
+}
+
+// IsTerminal checks if the writer is an interactive terminal
+func IsTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Heartbeat periodically prints that the named phase is still running, until the returned function is called.
+// It does nothing unless heartbeats are enabled in the context, which is the case when the output is not a terminal.
+func Heartbeat(ctx context.Context, phase string) (stop func()) {
+	opts, ok := ctx.Value(heartbeatKey{}).(HeartbeatOptions)
+	if !ok || opts.Interval <= 0 || opts.Out == nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+	start := time.Now()
+
+	go func() {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				elapsed := time.Since(start).Round(time.Second)
+				_, _ = fmt.Fprintf(opts.Out, "[heartbeat] %s is still running (%s)\n", phase, elapsed)
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
@@ -0,0 +1,75 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes from the heartbeat goroutine
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
+func TestHeartbeat_PrintsUntilStopped(t *testing.T) {
+	var out syncBuffer
+	ctx := WithHeartbeat(context.Background(), HeartbeatOptions{Interval: 10 * time.Millisecond, Out: &out})
+
+	stop := Heartbeat(ctx, "Unpacking IDE")
+	time.Sleep(55 * time.Millisecond)
+	stop()
+	stop()
+
+	printed := out.String()
+	if strings.Count(printed, "[heartbeat] Unpacking IDE is still running") < 2 {
+		t.Fatalf("Expected several heartbeat lines, got:\n%s", printed)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if out.String() != printed {
+		t.Error("Expected no heartbeats after stop")
+	}
+}
+
+func TestHeartbeat_DisabledByDefault(t *testing.T) {
+	stop := Heartbeat(context.Background(), "Hashing")
+	stop()
+}
+
+func TestResolveHeartbeatInterval(t *testing.T) {
+	t.Setenv(EnvHeartbeatInterval, "")
+	if interval, err := ResolveHeartbeatInterval(); err != nil || interval != DefaultHeartbeatInterval {
+		t.Errorf("Expected default interval, got %v, %v", interval, err)
+	}
+
+	t.Setenv(EnvHeartbeatInterval, "2m")
+	if interval, err := ResolveHeartbeatInterval(); err != nil || interval != 2*time.Minute {
+		t.Errorf("Expected 2m, got %v, %v", interval, err)
+	}
+
+	t.Setenv(EnvHeartbeatInterval, "0")
+	if interval, err := ResolveHeartbeatInterval(); err != nil || interval != 0 {
+		t.Errorf("Expected heartbeats to be disabled, got %v, %v", interval, err)
+	}
+
+	t.Setenv(EnvHeartbeatInterval, "often")
+	if _, err := ResolveHeartbeatInterval(); err == nil {
+		t.Error("Expected error for invalid interval")
+	}
+}
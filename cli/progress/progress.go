@@ -0,0 +1,207 @@
+package progress
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/logging"
+)
+
+const (
+	// DefaultLogInterval is the interval of progress log lines when progress bars are not shown
+	DefaultLogInterval = 10 * time.Second
+	// barRefreshInterval limits the redraws of the progress bar
+	barRefreshInterval = 200 * time.Millisecond
+	barWidth           = 30
+)
+
+// ReporterOptions configures how the progress of long transfers is shown
+type ReporterOptions struct {
+	// Out receives the progress bar
+	Out io.Writer
+	// Interactive shows a progress bar, otherwise progress is reported with periodic log lines
+	Interactive bool
+	// LogInterval is the interval between the log lines in the non-interactive mode
+	LogInterval time.Duration
+}
+
+type reporterKey struct{}
+
+// WithReporter returns a context with the progress reporting options
+func WithReporter(ctx context.Context, opts ReporterOptions) context.Context {
+	return context.WithValue(ctx, reporterKey{}, opts)
+}
+
+// Tracker reports the progress of a single transfer, e.g. a download
+type Tracker struct {
+	ctx   context.Context
+	opts  ReporterOptions
+	name  string
+	total int64
+	// initial is the amount of bytes done before the tracking started, e.g. a resumed download
+	initial int64
+
+	mutex      sync.Mutex
+	done       int64
+	start      time.Time
+	lastReport time.Time
+	finished   bool
+}
+
+// Track starts tracking of a transfer of total bytes, where initial bytes are already done.
+// The total can be unknown (zero or negative), then neither the percentage nor the ETA are shown.
+func Track(ctx context.Context, name string, total int64, initial int64) *Tracker {
+	opts, ok := ctx.Value(reporterKey{}).(ReporterOptions)
+	if !ok {
+		opts = ReporterOptions{}
+	}
+	if opts.LogInterval <= 0 {
+		opts.LogInterval = DefaultLogInterval
+	}
+	if opts.Out == nil {
+		opts.Interactive = false
+	}
+
+	now := time.Now()
+	return &Tracker{
+		ctx:        ctx,
+		opts:       opts,
+		name:       name,
+		total:      total,
+		initial:    initial,
+		done:       initial,
+		start:      now,
+		lastReport: now,
+	}
+}
+
+// Reader wraps the reader to track the bytes read from it
+func (t *Tracker) Reader(r io.Reader) io.Reader {
+	return &trackingReader{reader: r, tracker: t}
+}
+
+// Add records n more bytes transferred
+func (t *Tracker) Add(n int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.done += n
+
+	now := time.Now()
+	interval := t.opts.LogInterval
+	if t.opts.Interactive {
+		interval = barRefreshInterval
+	}
+	if now.Sub(t.lastReport) < interval {
+		return
+	}
+	t.lastReport = now
+	t.report(now)
+}
+
+// Finish prints the final state of the transfer
+func (t *Tracker) Finish() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.finished {
+		return
+	}
+	t.finished = true
+
+	if t.opts.Interactive {
+		t.report(time.Now())
+		_, _ = fmt.Fprintln(t.opts.Out)
+	}
+}
+
+func (t *Tracker) report(now time.Time) {
+	line := t.name + " " + t.stats(now)
+	if t.opts.Interactive {
+		_, _ = fmt.Fprintf(t.opts.Out, "\r%-100s", line)
+		return
+	}
+	logging.FromContext(t.ctx).Info(line)
+}
+
+// stats formats the percentage, the amount, the speed and the ETA of the transfer
+func (t *Tracker) stats(now time.Time) string {
+	var parts []string
+
+	elapsed := now.Sub(t.start).Seconds()
+	speed := 0.0
+	if elapsed > 0 {
+		speed = float64(t.done-t.initial) / elapsed
+	}
+
+	if t.total > 0 {
+		ratio := float64(t.done) / float64(t.total)
+		if ratio > 1 {
+			ratio = 1
+		}
+		if t.opts.Interactive {
+			filled := int(ratio * barWidth)
+			parts = append(parts, "["+strings.Repeat("=", filled)+strings.Repeat(" ", barWidth-filled)+"]")
+		}
+		parts = append(parts, fmt.Sprintf("%3.0f%%", ratio*100))
+		parts = append(parts, FormatBytes(t.done)+"/"+FormatBytes(t.total))
+	} else {
+		parts = append(parts, FormatBytes(t.done))
+	}
+
+	parts = append(parts, FormatBytes(int64(speed))+"/s")
+
+	if t.total > 0 && speed > 0 && t.done < t.total {
+		eta := time.Duration(float64(t.total-t.done) / speed * float64(time.Second))
+		parts = append(parts, "ETA "+eta.Round(time.Second).String())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// FormatBytes formats the size in a human-readable way
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// ParseBytes parses a byte count with an optional KiB, MiB, GiB or TiB suffix, the opposite of FormatBytes
+func ParseBytes(value string) (int64, error) {
+	multiplier := int64(1)
+	for suffix, factor := range map[string]int64{"KiB": 1 << 10, "MiB": 1 << 20, "GiB": 1 << 30, "TiB": 1 << 40} {
+		if number, ok := strings.CutSuffix(value, suffix); ok {
+			value, multiplier = strings.TrimSpace(number), factor
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size <= 0 || size > (1<<62)/multiplier {
+		return 0, fmt.Errorf("invalid size %s", value)
+	}
+	return size * multiplier, nil
+}
+
+type trackingReader struct {
+	reader  io.Reader
+	tracker *Tracker
+}
+
+func (r *trackingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.tracker.Add(int64(n))
+	}
+	return n, err
+}
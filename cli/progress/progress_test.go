@@ -0,0 +1,72 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracker_Stats(t *testing.T) {
+	tracker := Track(context.Background(), "Downloading", 100*1024*1024, 20*1024*1024)
+	tracker.done = 60 * 1024 * 1024
+
+	stats := tracker.stats(tracker.start.Add(10 * time.Second))
+	for _, expected := range []string{"60%", "60.0 MiB/100.0 MiB", "4.0 MiB/s", "ETA 10s"} {
+		if !strings.Contains(stats, expected) {
+			t.Errorf("Expected %q in %q", expected, stats)
+		}
+	}
+}
+
+func TestTracker_InteractiveBar(t *testing.T) {
+	var out bytes.Buffer
+	ctx := WithReporter(context.Background(), ReporterOptions{Out: &out, Interactive: true})
+
+	content := strings.Repeat("x", 4096)
+	tracker := Track(ctx, "font.zip", int64(len(content)), 0)
+	if _, err := io.Copy(io.Discard, tracker.Reader(strings.NewReader(content))); err != nil {
+		t.Fatalf("Failed to read: %v", err)
+	}
+	tracker.Finish()
+	tracker.Finish()
+
+	printed := out.String()
+	if !strings.Contains(printed, "font.zip ["+strings.Repeat("=", barWidth)+"] 100%") {
+		t.Errorf("Expected a complete progress bar, got: %q", printed)
+	}
+	if strings.Count(printed, "\n") != 1 {
+		t.Errorf("Expected a single final line break, got: %q", printed)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	cases := map[int64]string{
+		0:                  "0 B",
+		1023:               "1023 B",
+		1024:               "1.0 KiB",
+		5 * 1024 * 1024:    "5.0 MiB",
+		3 * 1024 * 1 << 30: "3.0 TiB",
+	}
+	for size, expected := range cases {
+		if actual := FormatBytes(size); actual != expected {
+			t.Errorf("FormatBytes(%d) = %s, expected %s", size, actual, expected)
+		}
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := map[string]int64{"1024": 1024, "4KiB": 4 << 10, "16 MiB": 16 << 20, "2GiB": 2 << 30, "1TiB": 1 << 40}
+	for value, expected := range tests {
+		if size, err := ParseBytes(value); err != nil || size != expected {
+			t.Errorf("Expected %s to be %d, got: %d, %v", value, expected, size, err)
+		}
+	}
+	for _, value := range []string{"", "0", "-1GiB", "2GB"} {
+		if _, err := ParseBytes(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Package provenance signs devrig.yaml, its included files and devrig.lock with a team key, so an edit of the binary
+// URLs and hashes that is not signed by the team is rejected before anything is downloaded
+package provenance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"jonnyzzz.com/devrig.dev/configfile"
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/lock"
+	"jonnyzzz.com/devrig.dev/tempfile"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+const (
+	// Namespace of the SSH signatures, `ssh-keygen -Y sign -n devrig` makes the same signatures
+	Namespace = "devrig"
+	// SignatureSuffix is appended to the name of a signed file
+	SignatureSuffix = ".sig"
+	// EnvTrustedSigners is the authorized_keys file with the keys of the team. When it is set,
+	// every command checks the signatures of the project files before it runs
+	EnvTrustedSigners = "DEVRIG_TRUSTED_SIGNERS"
+	// EnvKeyPassphrase is the passphrase of the signing key, for keys protected with one
+	EnvKeyPassphrase = "DEVRIG_KEY_PASSPHRASE"
+)
+
+// unsignedLocalKeys are the sections devrig.local.yaml cannot set in a signed project, it is not signed and
+// would replace the devrig binaries, the update endpoints, the trusted keys or the IDE of the signed files
+var unsignedLocalKeys = []string{"devrig", "updates", "security", "ide", "profiles"}
+
+// Files returns the files that are signed: devrig.yaml, the existing files of its include key and devrig.lock
+// if it exists. devrig.local.yaml is personal and is never signed, see checkLocalConfig
+func Files(configPath string) ([]string, error) {
+	includes, err := configservice.IncludedFiles(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{configPath}
+	for _, path := range append(includes, lock.ResolvePath(configPath)) {
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// LoadSigner reads the private SSH key, the passphrase is taken from DEVRIG_KEY_PASSPHRASE
+func LoadSigner(path string) (ssh.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the signing key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		passphrase := os.Getenv(EnvKeyPassphrase)
+		if passphrase == "" {
+			return nil, fmt.Errorf("the signing key %s is protected with a passphrase, set %s", path, EnvKeyPassphrase)
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(data, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the signing key %s: %w", path, err)
+	}
+	return signer, nil
+}
+
+// LoadTrustedKeys reads the keys of the team from a file in the authorized_keys format, comments and empty lines are skipped
+func LoadTrustedKeys(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the trusted signers: %w", err)
+	}
+
+	var keys []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line)); err != nil {
+			return nil, fmt.Errorf("invalid key at %s:%d: %w", path, i+1, err)
+		}
+		keys = append(keys, line)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys in %s", path)
+	}
+	return keys, nil
+}
+
+// Sign writes the signature of every file next to it and returns the signed files
+func Sign(configPath string, signer ssh.Signer) ([]string, error) {
+	files, err := Files(configPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		signature, err := updates.SignSSH(signer, data, Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign %s: %w", file, err)
+		}
+		if err := tempfile.WriteFile(file+SignatureSuffix, signature, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write the signature of %s: %w", file, err)
+		}
+	}
+	return files, nil
+}
+
+// Verify checks the signatures of the files with the trusted keys and returns the verified files,
+// a missing signature is an error
+func Verify(configPath string, keys []string) ([]string, error) {
+	files, err := Files(configPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		signature, err := os.ReadFile(file + SignatureSuffix)
+		if os.IsNotExist(err) {
+			return nil, &devrigErrors.SignatureInvalidError{Subject: file,
+				Err: fmt.Errorf("%s is missing, run devrig sign", filepath.Base(file)+SignatureSuffix)}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the signature of %s: %w", file, err)
+		}
+		if err := updates.VerifySignatureInNamespace(data, signature, keys, Namespace); err != nil {
+			return nil, &devrigErrors.SignatureInvalidError{Subject: file, Err: err}
+		}
+	}
+	if err := checkLocalConfig(configPath); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// checkLocalConfig rejects devrig.local.yaml when it sets a section that must come from the signed files
+func checkLocalConfig(configPath string) error {
+	path := filepath.Join(filepath.Dir(configPath), configservice.LocalConfigName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	values, err := configfile.Decode(path, data)
+	if err != nil {
+		return err
+	}
+	for _, key := range unsignedLocalKeys {
+		if _, ok := values[key]; ok {
+			return &devrigErrors.SignatureInvalidError{Subject: path,
+				Err: fmt.Errorf("%s is not signed and cannot set the %s section, move it to devrig.yaml", configservice.LocalConfigName, key)}
+		}
+	}
+	return nil
+}
+
+// Check verifies the project files with the keys of DEVRIG_TRUSTED_SIGNERS, it does nothing when the variable
+// is not set or the project has no devrig.yaml
+func Check(configPath string) error {
+	path := os.Getenv(EnvTrustedSigners)
+	if path == "" {
+		return nil
+	}
+	if _, err := os.Stat(configPath); err != nil {
+		return nil
+	}
+	keys, err := LoadTrustedKeys(path)
+	if err != nil {
+		return err
+	}
+	_, err = Verify(configPath, keys)
+	return err
+}
@@ -0,0 +1,89 @@
+package provenance
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// NewSignCommand creates the sign command
+func NewSignCommand(configPath func() string) *cobra.Command {
+	var keyPath string
+
+	cmd := &cobra.Command{
+		Use:   "sign",
+		Short: "Sign devrig.yaml and devrig.lock with the key of the team",
+		Long: `Sign devrig.yaml, the files of its include key and devrig.lock with an SSH key of the team,
+an Ed25519 key is recommended. The signatures are written next to the files with the .sig suffix
+and are committed with them.
+
+Set ` + EnvTrustedSigners + ` to the authorized_keys file with the keys of the team, so every
+devrig command checks the signatures before it downloads anything.
+
+Examples:
+  devrig sign --key ~/.ssh/devrig_team_ed25519
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if keyPath == "" {
+				return fmt.Errorf("--key is required")
+			}
+			signer, err := LoadSigner(keyPath)
+			if err != nil {
+				return err
+			}
+			files, err := Sign(configPath(), signer)
+			if err != nil {
+				return err
+			}
+			for _, file := range files {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Signed %s\n", file)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&keyPath, "key", "", "Path to the private SSH key of the team")
+	return cmd
+}
+
+// NewVerifyCommand creates the verify command
+func NewVerifyCommand(configPath func() string) *cobra.Command {
+	var keysPath string
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verify the signatures of devrig.yaml and devrig.lock",
+		Long: `Verify the signatures of devrig.yaml, the files of its include key and devrig.lock
+with the keys of the team from an authorized_keys file, ` + EnvTrustedSigners + ` by default.
+A missing or invalid signature fails with exit code 6.
+
+Examples:
+  devrig verify --keys .ci/devrig-signers.pub
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if keysPath == "" {
+				keysPath = os.Getenv(EnvTrustedSigners)
+			}
+			if keysPath == "" {
+				return fmt.Errorf("--keys or %s is required", EnvTrustedSigners)
+			}
+			keys, err := LoadTrustedKeys(keysPath)
+			if err != nil {
+				return err
+			}
+			files, err := Verify(configPath(), keys)
+			if err != nil {
+				return err
+			}
+			for _, file := range files {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Verified %s\n", filepath.Base(file))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&keysPath, "keys", "", "Path to the authorized_keys file with the keys of the team")
+	return cmd
+}
@@ -0,0 +1,243 @@
+package provenance
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/lock"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// newTeamKey writes a new private Ed25519 key to the folder, it returns the path and the authorized_keys line
+func newTeamKey(t *testing.T, dir string, passphrase string) (string, string) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate a key: %v", err)
+	}
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(private, "team")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(private, "team", []byte(passphrase))
+	}
+	if err != nil {
+		t.Fatalf("Failed to marshal the key: %v", err)
+	}
+	path := filepath.Join(dir, "team_ed25519")
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatalf("Failed to write the key: %v", err)
+	}
+	publicKey, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatalf("Failed to convert the public key: %v", err)
+	}
+	return path, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey))) + " team@example.com"
+}
+
+// newProject creates devrig.yaml with an included file, a missing personal include, devrig.lock and devrig.local.yaml
+func newProject(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "devrig.yaml")
+	files := map[string]string{
+		"devrig.yaml":       "include:\n  - team.yaml\n  - personal.yaml\ndevrig:\n  version: 0.80.0\n",
+		"team.yaml":         "tools:\n  node: \"20\"\n",
+		lock.FileName:       "{\"version\": 1}\n",
+		"devrig.local.yaml": "tools:\n  go: \"1.22\"\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return configPath
+}
+
+func TestSignAndVerify(t *testing.T) {
+	configPath := newProject(t)
+	dir := filepath.Dir(configPath)
+	keyPath, authorizedKey := newTeamKey(t, t.TempDir(), "")
+
+	signer, err := LoadSigner(keyPath)
+	if err != nil {
+		t.Fatalf("Failed to load the key: %v", err)
+	}
+	signed, err := Sign(configPath, signer)
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	var names []string
+	for _, file := range signed {
+		names = append(names, filepath.Base(file))
+	}
+	if strings.Join(names, ",") != "devrig.yaml,team.yaml,devrig.lock" {
+		t.Errorf("Unexpected signed files: %v", names)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "devrig.local.yaml.sig")); !os.IsNotExist(err) {
+		t.Errorf("Expected devrig.local.yaml not to be signed")
+	}
+
+	if _, err := Verify(configPath, []string{authorizedKey}); err != nil {
+		t.Fatalf("Failed to verify: %v", err)
+	}
+
+	// devrig.local.yaml is personal, changing it keeps the signatures valid
+	if err := os.WriteFile(filepath.Join(dir, "devrig.local.yaml"), []byte("tools:\n  go: \"1.23\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.local.yaml: %v", err)
+	}
+	if _, err := Verify(configPath, []string{authorizedKey}); err != nil {
+		t.Errorf("Expected devrig.local.yaml to be ignored: %v", err)
+	}
+
+	_, otherKey := newTeamKey(t, t.TempDir(), "")
+	var invalid *devrigErrors.SignatureInvalidError
+	if _, err := Verify(configPath, []string{otherKey}); !errors.As(err, &invalid) {
+		t.Errorf("Expected the signatures of another key to be rejected, got: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "team.yaml"), []byte("tools:\n  node: \"18\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write team.yaml: %v", err)
+	}
+	if _, err := Verify(configPath, []string{authorizedKey}); !errors.As(err, &invalid) || invalid.Subject != filepath.Join(dir, "team.yaml") {
+		t.Errorf("Expected the edited include to be rejected, got: %v", err)
+	}
+}
+
+func TestVerify_MissingSignature(t *testing.T) {
+	configPath := newProject(t)
+	_, authorizedKey := newTeamKey(t, t.TempDir(), "")
+
+	var invalid *devrigErrors.SignatureInvalidError
+	if _, err := Verify(configPath, []string{authorizedKey}); !errors.As(err, &invalid) || !strings.Contains(err.Error(), "devrig sign") {
+		t.Errorf("Expected a missing signature to be rejected, got: %v", err)
+	}
+}
+
+func TestVerify_OtherNamespace(t *testing.T) {
+	configPath := newProject(t)
+	keyPath, authorizedKey := newTeamKey(t, t.TempDir(), "")
+	signer, err := LoadSigner(keyPath)
+	if err != nil {
+		t.Fatalf("Failed to load the key: %v", err)
+	}
+	if _, err := Sign(configPath, signer); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+
+	// A signature of the same key for another purpose is not a signature of devrig.yaml
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read devrig.yaml: %v", err)
+	}
+	signature, err := updates.SignSSH(signer, data, "git")
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if err := os.WriteFile(configPath+SignatureSuffix, signature, 0644); err != nil {
+		t.Fatalf("Failed to write the signature: %v", err)
+	}
+	var invalid *devrigErrors.SignatureInvalidError
+	if _, err := Verify(configPath, []string{authorizedKey}); !errors.As(err, &invalid) || !strings.Contains(err.Error(), "namespace") {
+		t.Errorf("Expected the signature of another namespace to be rejected, got: %v", err)
+	}
+}
+
+func TestLoadSigner_Passphrase(t *testing.T) {
+	keyPath, _ := newTeamKey(t, t.TempDir(), "secret")
+
+	t.Setenv(EnvKeyPassphrase, "")
+	if _, err := LoadSigner(keyPath); err == nil || !strings.Contains(err.Error(), EnvKeyPassphrase) {
+		t.Errorf("Expected an error about the passphrase, got: %v", err)
+	}
+	t.Setenv(EnvKeyPassphrase, "secret")
+	if _, err := LoadSigner(keyPath); err != nil {
+		t.Errorf("Failed to load the key with the passphrase: %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	configPath := newProject(t)
+	keyPath, authorizedKey := newTeamKey(t, t.TempDir(), "")
+	signersPath := filepath.Join(t.TempDir(), "signers.pub")
+	if err := os.WriteFile(signersPath, []byte("# devrig signers of the team\n\n"+authorizedKey+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write the signers: %v", err)
+	}
+
+	t.Setenv(EnvTrustedSigners, "")
+	if err := Check(configPath); err != nil {
+		t.Errorf("Expected no check without %s: %v", EnvTrustedSigners, err)
+	}
+
+	t.Setenv(EnvTrustedSigners, signersPath)
+	var invalid *devrigErrors.SignatureInvalidError
+	if err := Check(configPath); !errors.As(err, &invalid) {
+		t.Errorf("Expected the unsigned project to be rejected, got: %v", err)
+	}
+	if err := Check(filepath.Join(t.TempDir(), "devrig.yaml")); err != nil {
+		t.Errorf("Expected no check without devrig.yaml: %v", err)
+	}
+
+	signer, err := LoadSigner(keyPath)
+	if err != nil {
+		t.Fatalf("Failed to load the key: %v", err)
+	}
+	if _, err := Sign(configPath, signer); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	if err := Check(configPath); err != nil {
+		t.Errorf("Expected the signed project to pass: %v", err)
+	}
+}
+
+func TestCheck_LocalConfigSections(t *testing.T) {
+	configPath := newProject(t)
+	keyPath, authorizedKey := newTeamKey(t, t.TempDir(), "")
+	signersPath := filepath.Join(t.TempDir(), "signers.pub")
+	if err := os.WriteFile(signersPath, []byte(authorizedKey+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write the signers: %v", err)
+	}
+	signer, err := LoadSigner(keyPath)
+	if err != nil {
+		t.Fatalf("Failed to load the key: %v", err)
+	}
+	if _, err := Sign(configPath, signer); err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	t.Setenv(EnvTrustedSigners, signersPath)
+
+	// A dropped-in devrig.local.yaml must not replace the signed devrig binaries
+	localPath := filepath.Join(filepath.Dir(configPath), "devrig.local.yaml")
+	local := "devrig:\n  version: 0.80.0\n  binaries:\n    linux-x86_64:\n      url: https://evil.example.com/devrig\n      sha512: 00\n"
+	if err := os.WriteFile(localPath, []byte(local), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.local.yaml: %v", err)
+	}
+	var invalid *devrigErrors.SignatureInvalidError
+	if err := Check(configPath); !errors.As(err, &invalid) || !strings.Contains(err.Error(), "devrig section") {
+		t.Errorf("Expected devrig.local.yaml with the devrig section to be rejected, got: %v", err)
+	}
+
+	for _, key := range []string{"updates", "security", "ide"} {
+		if err := os.WriteFile(localPath, []byte(key+": {}\n"), 0644); err != nil {
+			t.Fatalf("Failed to write devrig.local.yaml: %v", err)
+		}
+		if err := Check(configPath); !errors.As(err, &invalid) {
+			t.Errorf("Expected devrig.local.yaml with the %s section to be rejected, got: %v", key, err)
+		}
+	}
+
+	if err := os.WriteFile(localPath, []byte("tools:\n  go: \"1.22\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.local.yaml: %v", err)
+	}
+	if err := Check(configPath); err != nil {
+		t.Errorf("Expected the personal tools to pass: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package release
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// brewPlatform is a binary of the formula, Homebrew selects it with the on_<os> and on_<arch> blocks
+type brewPlatform struct {
+	Arch   string
+	URL    string
+	SHA256 string
+}
+
+var brewTemplate = template.Must(template.New("devrig.rb").Parse(`# Generated by devrig release package from the signed release metadata, do not edit
+class Devrig < Formula
+  desc "{{.Description}}"
+  homepage "{{.Homepage}}"
+  version "{{.Version}}"
+  license "Apache-2.0"
+{{range $os := .OSes}}
+  on_{{$os.Block}} do
+{{- range $os.Platforms}}
+    on_{{.Arch}} do
+      url "{{.URL}}"
+      sha256 "{{.SHA256}}"
+    end
+{{- end}}
+  end
+{{end}}
+  def install
+    bin.install Dir["*"].first => "devrig"
+  end
+
+  test do
+    system bin/"devrig", "version"
+  end
+end
+`))
+
+// brewOS groups the binaries of an OS in the formula
+type brewOS struct {
+	Block     string
+	Platforms []brewPlatform
+}
+
+// brewOSBlocks maps the OS of latest.json to the block of the formula
+var brewOSBlocks = []struct{ os, block string }{{"darwin", "macos"}, {"linux", "linux"}}
+
+// brewArchBlocks maps the architecture of latest.json to the block of the formula
+var brewArchBlocks = map[string]string{"arm64": "arm", "aarch64": "arm", "x86_64": "intel", "amd64": "intel"}
+
+// BrewFormula returns the Homebrew formula of the release. Homebrew verifies SHA-256, latest.json publishes SHA-512,
+// so every binary is downloaded, checked against its SHA-512 and hashed with SHA-256
+func BrewFormula(ctx context.Context, client *http.Client, info *updates.UpdateInfo) (string, error) {
+	data := struct {
+		Description string
+		Homepage    string
+		Version     string
+		OSes        []brewOS
+	}{Description: description, Homepage: homepage, Version: info.Version}
+
+	for _, osBlock := range brewOSBlocks {
+		group := brewOS{Block: osBlock.block}
+		for _, binary := range info.Binaries {
+			arch, ok := brewArchBlocks[binary.Arch]
+			if binary.OS != osBlock.os || !ok {
+				continue
+			}
+			sum, err := binarySha256(ctx, client, binary)
+			if err != nil {
+				return "", err
+			}
+			group.Platforms = append(group.Platforms, brewPlatform{Arch: arch, URL: binary.URL, SHA256: sum})
+		}
+		if len(group.Platforms) > 0 {
+			data.OSes = append(data.OSes, group)
+		}
+	}
+	if len(data.OSes) == 0 {
+		return "", fmt.Errorf("release %s has no binaries for macOS or Linux", info.Version)
+	}
+
+	var formula strings.Builder
+	if err := brewTemplate.Execute(&formula, data); err != nil {
+		return "", fmt.Errorf("failed to render the Homebrew formula: %w", err)
+	}
+	return formula.String(), nil
+}
+
+// binarySha256 downloads the binary, verifies its SHA-512 from latest.json and returns its SHA-256
+func binarySha256(ctx context.Context, client *http.Client, binary updates.BinaryInfo) (string, error) {
+	if err := offline.Check(binary.Filename, binary.URL, "run the command with network access, Homebrew needs the SHA-256 of the binaries"); err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", binary.URL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", &devrigErrors.NetworkError{URL: binary.URL, Err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", &devrigErrors.NetworkError{URL: binary.URL, Err: fmt.Errorf("download returned status %d", resp.StatusCode)}
+	}
+
+	sum256, sum512 := sha256.New(), sha512.New()
+	if _, err := io.Copy(io.MultiWriter(sum256, sum512), resp.Body); err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", binary.Filename, err)
+	}
+	if actual := hex.EncodeToString(sum512.Sum(nil)); !strings.EqualFold(actual, binary.SHA512) {
+		return "", &devrigErrors.ChecksumMismatchError{Subject: binary.Filename, Expected: binary.SHA512, Actual: actual}
+	}
+	return hex.EncodeToString(sum256.Sum(nil)), nil
+}
@@ -0,0 +1,45 @@
+package release
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/golden"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// goldenHost replaces the random address of the test server in the golden files
+const goldenHost = "https://devrig.example.com"
+
+// withGoldenHost replaces the address of the test server of testRelease with goldenHost
+func withGoldenHost(t *testing.T, text string, info *updates.UpdateInfo) string {
+	t.Helper()
+	binary := info.Binaries[0]
+	server := strings.TrimSuffix(binary.URL, "/"+binary.Filename)
+	if server == binary.URL {
+		t.Fatalf("Unexpected binary URL %s", binary.URL)
+	}
+	return strings.ReplaceAll(text, server, goldenHost)
+}
+
+func TestGolden_BrewFormula(t *testing.T) {
+	info, _ := testRelease(t)
+
+	formula, err := BrewFormula(context.Background(), http.DefaultClient, info)
+	if err != nil {
+		t.Fatalf("Failed to generate the formula: %v", err)
+	}
+	golden.AssertString(t, "devrig.rb", withGoldenHost(t, formula, info))
+}
+
+func TestGolden_ScoopManifest(t *testing.T) {
+	info, _ := testRelease(t)
+
+	data, err := ScoopManifest(info)
+	if err != nil {
+		t.Fatalf("Failed to generate the manifest: %v", err)
+	}
+	golden.AssertString(t, "devrig.json", withGoldenHost(t, string(data), info))
+}
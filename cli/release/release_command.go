@@ -0,0 +1,118 @@
+package release
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/updates"
+	"jonnyzzz.com/devrig.dev/upgrade"
+)
+
+const (
+	description = "Pins and bootstraps the developer tools and IDEs of a project"
+	homepage    = "https://devrig.dev"
+
+	// BrewFormulaFile and ScoopManifestFile are the files written to the output directory
+	BrewFormulaFile   = "devrig.rb"
+	ScoopManifestFile = "devrig.json"
+)
+
+// NewReleaseCommand creates the release command with the tools for publishing devrig
+func NewReleaseCommand(fetcher upgrade.ReleaseFetcher) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "release",
+		Short: "Tools for publishing devrig releases",
+	}
+	cmd.AddCommand(newPackageCommand(fetcher, &http.Client{Timeout: 10 * time.Minute}))
+	return cmd
+}
+
+func newPackageCommand(fetcher upgrade.ReleaseFetcher, client *http.Client) *cobra.Command {
+	var brew bool
+	var scoop bool
+	var version string
+	var channel string
+	var outputDir string
+
+	cmd := &cobra.Command{
+		Use:   "package",
+		Short: "Generate the Homebrew formula and the Scoop manifest of a release",
+		Long: `Generate the Homebrew formula (devrig.rb) and the Scoop manifest (devrig.json)
+from the signed release metadata.
+
+The signature of latest.json is verified first. Scoop checks the SHA-512 of
+latest.json, Homebrew only supports SHA-256, so the macOS and Linux binaries
+are downloaded and verified against latest.json to compute it.
+
+Examples:
+  devrig release package --brew --scoop
+  devrig release package --scoop --version 0.79.6 --output-dir dist
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !brew && !scoop {
+				return fmt.Errorf("nothing to generate, use --brew and/or --scoop")
+			}
+			if version != "" && channel != "" {
+				return fmt.Errorf("--version and --channel cannot be used together")
+			}
+
+			url := updates.LatestJSONURL
+			if version != "" {
+				url = updates.ReleaseJSONURL(version)
+			} else if channel != "" {
+				if err := configservice.ValidateChannel(channel); err != nil {
+					return err
+				}
+				url = updates.ChannelJSONURL(channel)
+			}
+			info, err := fetcher.FetchUpdateInfo(url)
+			if err != nil {
+				return fmt.Errorf("failed to fetch release metadata: %w", err)
+			}
+
+			if err := os.MkdirAll(outputDir, 0755); err != nil {
+				return fmt.Errorf("failed to create output directory: %w", err)
+			}
+			if brew {
+				formula, err := BrewFormula(cmd.Context(), client, info)
+				if err != nil {
+					return err
+				}
+				if err := writeOutput(cmd, filepath.Join(outputDir, BrewFormulaFile), []byte(formula)); err != nil {
+					return err
+				}
+			}
+			if scoop {
+				manifest, err := ScoopManifest(info)
+				if err != nil {
+					return err
+				}
+				if err := writeOutput(cmd, filepath.Join(outputDir, ScoopManifestFile), manifest); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&brew, "brew", false, "Generate the Homebrew formula")
+	cmd.Flags().BoolVar(&scoop, "scoop", false, "Generate the Scoop manifest")
+	cmd.Flags().StringVar(&version, "version", "", "Release to package, e.g. 0.79.6 (default is the latest release)")
+	cmd.Flags().StringVar(&channel, "channel", "", "Release channel to package: stable, beta or nightly")
+	cmd.Flags().StringVar(&outputDir, "output-dir", ".", "Directory for the generated files")
+	return cmd
+}
+
+func writeOutput(cmd *cobra.Command, path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	cmd.Printf("Written %s\n", path)
+	return nil
+}
@@ -0,0 +1,105 @@
+package release
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// testRelease serves a binary per platform and returns latest.json describing them
+func testRelease(t *testing.T) (*updates.UpdateInfo, map[string][]byte) {
+	t.Helper()
+	content := map[string][]byte{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := content[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	info := &updates.UpdateInfo{Version: "0.79.6"}
+	for _, platform := range [][2]string{{"darwin", "arm64"}, {"darwin", "x86_64"}, {"linux", "x86_64"}, {"windows", "x86_64"}, {"windows", "arm64"}} {
+		filename := "devrig-" + platform[0] + "-" + platform[1]
+		content[filename] = []byte("binary " + filename)
+		sum := sha512.Sum512(content[filename])
+		info.Binaries = append(info.Binaries, updates.BinaryInfo{
+			Filename: filename,
+			OS:       platform[0],
+			Arch:     platform[1],
+			SHA512:   hex.EncodeToString(sum[:]),
+			URL:      server.URL + "/" + filename,
+		})
+	}
+	return info, content
+}
+
+func TestBrewFormula(t *testing.T) {
+	info, content := testRelease(t)
+
+	formula, err := BrewFormula(context.Background(), http.DefaultClient, info)
+	if err != nil {
+		t.Fatalf("Failed to generate the formula: %v", err)
+	}
+	sum := sha256.Sum256(content["devrig-darwin-arm64"])
+	for _, expected := range []string{
+		`version "0.79.6"`,
+		"on_macos do\n    on_arm do\n      url \"" + info.Binaries[0].URL + "\"\n      sha256 \"" + hex.EncodeToString(sum[:]) + "\"",
+		"on_linux do\n    on_intel do",
+		`bin.install Dir["*"].first => "devrig"`,
+	} {
+		if !strings.Contains(formula, expected) {
+			t.Errorf("Expected %q in the formula:\n%s", expected, formula)
+		}
+	}
+	if strings.Contains(formula, "windows") {
+		t.Errorf("Expected no Windows binaries in the formula:\n%s", formula)
+	}
+}
+
+func TestBrewFormula_ChecksumMismatch(t *testing.T) {
+	info, content := testRelease(t)
+	content["devrig-linux-x86_64"] = []byte("tampered")
+
+	_, err := BrewFormula(context.Background(), http.DefaultClient, info)
+	var mismatch *devrigErrors.ChecksumMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Subject != "devrig-linux-x86_64" {
+		t.Errorf("Expected a checksum mismatch, got: %v", err)
+	}
+}
+
+func TestScoopManifest(t *testing.T) {
+	info, _ := testRelease(t)
+
+	data, err := ScoopManifest(info)
+	if err != nil {
+		t.Fatalf("Failed to generate the manifest: %v", err)
+	}
+	var manifest scoopManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Failed to parse the manifest: %v", err)
+	}
+	if manifest.Version != "0.79.6" || manifest.Bin != "devrig.exe" || len(manifest.Architecture) != 2 {
+		t.Fatalf("Unexpected manifest: %s", data)
+	}
+	x64 := manifest.Architecture["64bit"]
+	if x64.URL != info.Binaries[3].URL+"#/devrig.exe" || x64.Hash != "sha512:"+info.Binaries[3].SHA512 {
+		t.Errorf("Unexpected 64bit entry: %+v", x64)
+	}
+
+	if _, err := ScoopManifest(&updates.UpdateInfo{Version: "0.79.6", Binaries: info.Binaries[:3]}); err == nil {
+		t.Error("Expected an error for a release without Windows binaries")
+	}
+}
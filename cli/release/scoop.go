@@ -0,0 +1,63 @@
+package release
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// scoopManifest is the Scoop manifest of devrig, the field names are defined by Scoop
+type scoopManifest struct {
+	Version      string                       `json:"version"`
+	Description  string                       `json:"description"`
+	Homepage     string                       `json:"homepage"`
+	License      string                       `json:"license"`
+	Architecture map[string]scoopArchitecture `json:"architecture"`
+	Bin          string                       `json:"bin"`
+	Checkver     scoopCheckver                `json:"checkver"`
+}
+
+type scoopArchitecture struct {
+	// URL ends with #/devrig.exe, so Scoop saves the binary under the name of bin
+	URL  string `json:"url"`
+	Hash string `json:"hash"`
+}
+
+type scoopCheckver struct {
+	URL      string `json:"url"`
+	JSONPath string `json:"jsonpath"`
+}
+
+// scoopArchitectures maps the architecture of latest.json to the one of Scoop
+var scoopArchitectures = map[string]string{"x86_64": "64bit", "amd64": "64bit", "arm64": "arm64", "aarch64": "arm64"}
+
+// ScoopManifest returns the Scoop manifest of the Windows binaries of the release,
+// Scoop verifies the SHA-512 of latest.json, so nothing is downloaded
+func ScoopManifest(info *updates.UpdateInfo) ([]byte, error) {
+	manifest := scoopManifest{
+		Version:      info.Version,
+		Description:  description,
+		Homepage:     homepage,
+		License:      "Apache-2.0",
+		Architecture: map[string]scoopArchitecture{},
+		Bin:          "devrig.exe",
+		Checkver:     scoopCheckver{URL: updates.LatestJSONURL, JSONPath: "$.version"},
+	}
+	for _, binary := range info.Binaries {
+		arch, ok := scoopArchitectures[binary.Arch]
+		if binary.OS != "windows" || !ok {
+			continue
+		}
+		manifest.Architecture[arch] = scoopArchitecture{URL: binary.URL + "#/devrig.exe", Hash: "sha512:" + binary.SHA512}
+	}
+	if len(manifest.Architecture) == 0 {
+		return nil, fmt.Errorf("release %s has no binaries for Windows", info.Version)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the Scoop manifest: %w", err)
+	}
+	return append(data, '\n'), nil
+}
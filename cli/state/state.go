@@ -0,0 +1,184 @@
+// Package state keeps .devrig/state.json, the record of the artifacts devrig installed for the project:
+// the unpacked IDEs and the tools with their versions, checksums and the times they were installed and last used.
+// The commands read it instead of guessing the artifacts from the names of the folders. The devrig binaries
+// are described by the metadata.json next to them, the bootstrap scripts install them without devrig
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/filelock"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// FileName is the name of the file in the .devrig folder
+const FileName = "state.json"
+
+// Types of the installed artifacts
+const (
+	// TypeIde is an IDE unpacked into the cache of the project
+	TypeIde = "ide"
+	// TypeTool is a tool or a JDK in .devrig/tools
+	TypeTool = "tool"
+)
+
+// Artifact is an installed artifact
+type Artifact struct {
+	Type string `json:"type"`
+	// Name is the IDE or the tool, e.g. GoLand, node or jdk-temurin
+	Name string `json:"name"`
+	// Version is the build of the IDE or the version of the tool
+	Version string `json:"version"`
+	// Checksum is the hex SHA-256 or SHA-512 of the package the artifact was installed from
+	Checksum string `json:"checksum,omitempty"`
+	// Path is the folder of the artifact
+	Path        string    `json:"path"`
+	InstalledAt time.Time `json:"installed_at"`
+	LastUsedAt  time.Time `json:"last_used_at"`
+}
+
+// State is the content of state.json
+type State struct {
+	Artifacts []Artifact `json:"artifacts"`
+}
+
+// Find returns the artifact installed at the path, or nil
+func (s *State) Find(path string) *Artifact {
+	path = filepath.Clean(path)
+	for i := range s.Artifacts {
+		if s.Artifacts[i].Path == path {
+			return &s.Artifacts[i]
+		}
+	}
+	return nil
+}
+
+// Missing returns the recorded artifacts whose folders are gone, e.g. removed by hand
+func (s *State) Missing() []Artifact {
+	var missing []Artifact
+	for _, artifact := range s.Artifacts {
+		if _, err := os.Stat(artifact.Path); os.IsNotExist(err) {
+			missing = append(missing, artifact)
+		}
+	}
+	return missing
+}
+
+// LastUsed returns the last use of the artifacts by their paths
+func (s *State) LastUsed() map[string]time.Time {
+	used := map[string]time.Time{}
+	for _, artifact := range s.Artifacts {
+		used[artifact.Path] = artifact.LastUsedAt
+	}
+	return used
+}
+
+// Load reads state.json of the .devrig folder, it is empty when the file is missing
+func Load(devrigHome string) (*State, error) {
+	data, err := os.ReadFile(filepath.Join(devrigHome, FileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", FileName, err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", FileName, err)
+	}
+	return &state, nil
+}
+
+// Update changes state.json under its lock, so concurrent devrig processes do not lose each other's records
+func Update(ctx context.Context, devrigHome string, update func(state *State)) error {
+	lock, err := filelock.Acquire(ctx, layout.ResolveLockFile(devrigHome, FileName), "updating "+FileName)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	state, err := Load(devrigHome)
+	if err != nil {
+		return err
+	}
+	update(state)
+	if state.Artifacts == nil {
+		state.Artifacts = []Artifact{}
+	}
+	sort.Slice(state.Artifacts, func(i, j int) bool {
+		return state.Artifacts[i].Path < state.Artifacts[j].Path
+	})
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", FileName, err)
+	}
+	if err := os.MkdirAll(devrigHome, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", devrigHome, err)
+	}
+	return tempfile.WriteFile(filepath.Join(devrigHome, FileName), data, 0644)
+}
+
+// Record adds the installed artifact or replaces the one at its path. The install time of an artifact
+// installed from the same package is kept, the artifact counts as used at the time it is recorded
+func Record(ctx context.Context, devrigHome string, artifact Artifact, now time.Time) error {
+	artifact.Path = filepath.Clean(artifact.Path)
+	artifact.InstalledAt = now.UTC()
+	artifact.LastUsedAt = now.UTC()
+	return Update(ctx, devrigHome, func(state *State) {
+		if existing := state.Find(artifact.Path); existing != nil {
+			if existing.Checksum == artifact.Checksum && !existing.InstalledAt.IsZero() {
+				artifact.InstalledAt = existing.InstalledAt
+			}
+			*existing = artifact
+			return
+		}
+		state.Artifacts = append(state.Artifacts, artifact)
+	})
+}
+
+// Touch records the use of the artifact at the path, unknown paths are ignored
+func Touch(ctx context.Context, devrigHome string, path string, now time.Time) error {
+	if !exists(devrigHome) {
+		return nil
+	}
+	return Update(ctx, devrigHome, func(state *State) {
+		if artifact := state.Find(path); artifact != nil {
+			artifact.LastUsedAt = now.UTC()
+		}
+	})
+}
+
+// Prune drops the records of the artifacts whose folders are gone, e.g. removed by the retention policy or by hand
+func Prune(ctx context.Context, devrigHome string) error {
+	if !exists(devrigHome) {
+		return nil
+	}
+	return Update(ctx, devrigHome, func(state *State) {
+		missing := map[string]bool{}
+		for _, artifact := range state.Missing() {
+			missing[artifact.Path] = true
+		}
+		var kept []Artifact
+		for _, artifact := range state.Artifacts {
+			if !missing[artifact.Path] {
+				kept = append(kept, artifact)
+			}
+		}
+		state.Artifacts = kept
+	})
+}
+
+// exists checks if state.json is there, the records of a project without it are not created by reads
+func exists(devrigHome string) bool {
+	_, err := os.Stat(filepath.Join(devrigHome, FileName))
+	return err == nil
+}
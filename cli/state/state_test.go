@@ -0,0 +1,98 @@
+package state
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	ctx := context.Background()
+	devrigHome := t.TempDir()
+	installedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tool := Artifact{Type: TypeTool, Name: "node", Version: "20.11.0", Checksum: "abc", Path: filepath.Join(devrigHome, "tools", "node-20.11.0")}
+	if err := Record(ctx, devrigHome, tool, installedAt); err != nil {
+		t.Fatalf("Failed to record the tool: %v", err)
+	}
+
+	// The same package recorded again keeps its install time
+	usedAt := installedAt.Add(time.Hour)
+	if err := Record(ctx, devrigHome, tool, usedAt); err != nil {
+		t.Fatalf("Failed to record the tool again: %v", err)
+	}
+	state, err := Load(devrigHome)
+	if err != nil {
+		t.Fatalf("Failed to load the state: %v", err)
+	}
+	if len(state.Artifacts) != 1 {
+		t.Fatalf("Expected one artifact, got %+v", state.Artifacts)
+	}
+	recorded := state.Find(tool.Path)
+	if recorded == nil || !recorded.InstalledAt.Equal(installedAt) || !recorded.LastUsedAt.Equal(usedAt) {
+		t.Errorf("Expected the install time to be kept and the use to be updated, got %+v", recorded)
+	}
+
+	// Another package at the same path is a new installation
+	tool.Checksum = "def"
+	reinstalledAt := usedAt.Add(time.Hour)
+	if err := Record(ctx, devrigHome, tool, reinstalledAt); err != nil {
+		t.Fatalf("Failed to record the new package: %v", err)
+	}
+	if state, err = Load(devrigHome); err != nil {
+		t.Fatalf("Failed to load the state: %v", err)
+	}
+	if recorded := state.Find(tool.Path); recorded == nil || !recorded.InstalledAt.Equal(reinstalledAt) || recorded.Checksum != "def" {
+		t.Errorf("Expected the new package to replace the record, got %+v", recorded)
+	}
+}
+
+func TestTouchAndPrune(t *testing.T) {
+	ctx := context.Background()
+	devrigHome := t.TempDir()
+
+	// Nothing is written for a project without the file
+	if err := Touch(ctx, devrigHome, filepath.Join(devrigHome, "missing"), time.Now()); err != nil {
+		t.Fatalf("Failed to touch: %v", err)
+	}
+	if err := Prune(ctx, devrigHome); err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(devrigHome, FileName)); !os.IsNotExist(err) {
+		t.Fatalf("Expected no %s, got %v", FileName, err)
+	}
+
+	now := time.Now().UTC()
+	kept := Artifact{Type: TypeIde, Name: "GoLand", Version: "243.1", Path: t.TempDir()}
+	gone := Artifact{Type: TypeIde, Name: "GoLand", Version: "243.0", Path: filepath.Join(devrigHome, "gone")}
+	for _, artifact := range []Artifact{kept, gone} {
+		if err := Record(ctx, devrigHome, artifact, now.Add(-time.Hour)); err != nil {
+			t.Fatalf("Failed to record %s: %v", artifact.Path, err)
+		}
+	}
+	if err := Touch(ctx, devrigHome, kept.Path, now); err != nil {
+		t.Fatalf("Failed to touch: %v", err)
+	}
+
+	state, err := Load(devrigHome)
+	if err != nil {
+		t.Fatalf("Failed to load the state: %v", err)
+	}
+	if missing := state.Missing(); len(missing) != 1 || missing[0].Path != gone.Path {
+		t.Errorf("Expected the removed IDE to be missing, got %+v", missing)
+	}
+	if used := state.LastUsed(); !used[kept.Path].Equal(now) {
+		t.Errorf("Expected the use to be recorded, got %v", used)
+	}
+
+	if err := Prune(ctx, devrigHome); err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if state, err = Load(devrigHome); err != nil {
+		t.Fatalf("Failed to load the state: %v", err)
+	}
+	if len(state.Artifacts) != 1 || state.Artifacts[0].Path != kept.Path {
+		t.Errorf("Expected only the present IDE to be kept, got %+v", state.Artifacts)
+	}
+}
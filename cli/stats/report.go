@@ -0,0 +1,213 @@
+package stats
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/summary"
+)
+
+// Durations summarizes a set of durations
+type Durations struct {
+	Count int           `json:"count"`
+	Mean  time.Duration `json:"mean_ns"`
+	P50   time.Duration `json:"p50_ns"`
+	P90   time.Duration `json:"p90_ns"`
+	Max   time.Duration `json:"max_ns"`
+}
+
+// StepReport aggregates the runs of a single step
+type StepReport struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Executed int       `json:"executed"`
+	Failed   int       `json:"failed"`
+	Reused   int       `json:"reused"`
+	Duration Durations `json:"duration"`
+}
+
+// ArtifactReport aggregates the uses of a single artifact
+type ArtifactReport struct {
+	Name            string `json:"name"`
+	Uses            int    `json:"uses"`
+	CacheHits       int    `json:"cache_hits"`
+	Size            int64  `json:"size"`
+	DownloadedBytes int64  `json:"downloaded_bytes"`
+}
+
+// Report is the aggregate of the recorded runs, see Aggregate
+type Report struct {
+	Since      *time.Time `json:"since,omitempty"`
+	Runs       int        `json:"runs"`
+	FailedRuns int        `json:"failed_runs"`
+	// TimeToProvision is the duration of the successful runs
+	TimeToProvision Durations `json:"time_to_provision"`
+	CacheHits       int       `json:"cache_hits"`
+	CacheMisses     int       `json:"cache_misses"`
+	// CacheHitRate is the share of the artifacts that were in place, 0 when no artifacts were recorded
+	CacheHitRate    float64          `json:"cache_hit_rate"`
+	DownloadedBytes int64            `json:"downloaded_bytes"`
+	CachedBytes     int64            `json:"cached_bytes"`
+	Steps           []StepReport     `json:"steps"`
+	Artifacts       []ArtifactReport `json:"artifacts"`
+}
+
+// Aggregate computes the report of the runs. Steps skipped as already done count as reused,
+// artifacts count as cache hits when they were in place and as misses when they were downloaded
+func Aggregate(runs []Run, since time.Time) Report {
+	report := Report{Runs: len(runs), Steps: []StepReport{}, Artifacts: []ArtifactReport{}}
+	if !since.IsZero() {
+		report.Since = &since
+	}
+
+	var provision []time.Duration
+	stepDurations := map[string][]time.Duration{}
+	steps := map[string]*StepReport{}
+	artifacts := map[string]*ArtifactReport{}
+
+	for _, run := range runs {
+		if run.Success {
+			provision = append(provision, run.Duration)
+		} else {
+			report.FailedRuns++
+		}
+
+		for _, step := range run.Steps {
+			entry, ok := steps[step.ID]
+			if !ok {
+				entry = &StepReport{ID: step.ID, Name: step.Name}
+				steps[step.ID] = entry
+			}
+			switch {
+			case step.Status == summary.StatusSkipped && step.Message == "already done":
+				entry.Reused++
+			case step.Status == summary.StatusOK:
+				entry.Executed++
+				stepDurations[step.ID] = append(stepDurations[step.ID], step.Duration)
+			case step.Status == summary.StatusFailed:
+				entry.Executed++
+				entry.Failed++
+			}
+		}
+
+		for _, artifact := range run.Artifacts {
+			entry, ok := artifacts[artifact.Name]
+			if !ok {
+				entry = &ArtifactReport{Name: artifact.Name}
+				artifacts[artifact.Name] = entry
+			}
+			entry.Uses++
+			entry.Size = artifact.Size
+			if artifact.Cached {
+				entry.CacheHits++
+				report.CacheHits++
+				report.CachedBytes += artifact.Size
+			} else {
+				entry.DownloadedBytes += artifact.Size
+				report.CacheMisses++
+				report.DownloadedBytes += artifact.Size
+			}
+		}
+	}
+
+	report.TimeToProvision = summarize(provision)
+	if total := report.CacheHits + report.CacheMisses; total > 0 {
+		report.CacheHitRate = float64(report.CacheHits) / float64(total)
+	}
+	for id, entry := range steps {
+		entry.Duration = summarize(stepDurations[id])
+		report.Steps = append(report.Steps, *entry)
+	}
+	sort.Slice(report.Steps, func(i, j int) bool {
+		return report.Steps[i].ID < report.Steps[j].ID
+	})
+	for _, entry := range artifacts {
+		report.Artifacts = append(report.Artifacts, *entry)
+	}
+	sort.Slice(report.Artifacts, func(i, j int) bool {
+		return report.Artifacts[i].Name < report.Artifacts[j].Name
+	})
+	return report
+}
+
+// summarize computes the mean and the nearest-rank percentiles of the durations
+func summarize(durations []time.Duration) Durations {
+	if len(durations) == 0 {
+		return Durations{}
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	percentile := func(p int) time.Duration {
+		rank := (p*len(sorted) + 99) / 100
+		return sorted[max(rank, 1)-1]
+	}
+	return Durations{
+		Count: len(sorted),
+		Mean:  total / time.Duration(len(sorted)),
+		P50:   percentile(50),
+		P90:   percentile(90),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// WriteCSV writes the report as metric,value rows, durations are in milliseconds,
+// the rows of steps and artifacts are prefixed with step.<id>. and artifact.<name>.
+func (r Report) WriteCSV(w io.Writer) error {
+	rows := [][]string{{"metric", "value"}}
+	add := func(metric string, value string) {
+		rows = append(rows, []string{metric, value})
+	}
+	addInt := func(metric string, value int64) {
+		add(metric, strconv.FormatInt(value, 10))
+	}
+	addDurations := func(prefix string, d Durations) {
+		addInt(prefix+"count", int64(d.Count))
+		addInt(prefix+"mean_ms", d.Mean.Milliseconds())
+		addInt(prefix+"p50_ms", d.P50.Milliseconds())
+		addInt(prefix+"p90_ms", d.P90.Milliseconds())
+		addInt(prefix+"max_ms", d.Max.Milliseconds())
+	}
+
+	if r.Since != nil {
+		add("since", r.Since.UTC().Format(time.RFC3339))
+	}
+	addInt("runs", int64(r.Runs))
+	addInt("failed_runs", int64(r.FailedRuns))
+	addDurations("time_to_provision.", r.TimeToProvision)
+	addInt("cache_hits", int64(r.CacheHits))
+	addInt("cache_misses", int64(r.CacheMisses))
+	add("cache_hit_rate", strconv.FormatFloat(r.CacheHitRate, 'f', 4, 64))
+	addInt("downloaded_bytes", r.DownloadedBytes)
+	addInt("cached_bytes", r.CachedBytes)
+	for _, step := range r.Steps {
+		prefix := "step." + step.ID + "."
+		addInt(prefix+"executed", int64(step.Executed))
+		addInt(prefix+"failed", int64(step.Failed))
+		addInt(prefix+"reused", int64(step.Reused))
+		addDurations(prefix+"duration.", step.Duration)
+	}
+	for _, artifact := range r.Artifacts {
+		prefix := "artifact." + artifact.Name + "."
+		addInt(prefix+"uses", int64(artifact.Uses))
+		addInt(prefix+"cache_hits", int64(artifact.CacheHits))
+		addInt(prefix+"size", artifact.Size)
+		addInt(prefix+"downloaded_bytes", artifact.DownloadedBytes)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
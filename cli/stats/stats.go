@@ -0,0 +1,159 @@
+// Package stats records the provisioning runs of devrig and exports aggregate metrics about them,
+// e.g. the time to provision and the cache hit rate, for platform teams measuring the developer experience
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/summary"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// HistoryFileName is the name of the file in the .devrig folder with one JSON line per recorded run
+const HistoryFileName = "stats.jsonl"
+
+// MaxRecords is the number of most recent runs kept in the history
+const MaxRecords = 1000
+
+// Artifact is a file a run needed, either found in place or downloaded
+type Artifact struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+	// Cached tells the artifact was already in place, so nothing was downloaded
+	Cached bool `json:"cached"`
+}
+
+// Step is the outcome of a step of a run, see summary.Step
+type Step struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	Status   summary.Status `json:"status"`
+	Duration time.Duration  `json:"duration_ns"`
+	Message  string         `json:"message,omitempty"`
+}
+
+// Run is the record of a single provisioning run
+type Run struct {
+	Command   string        `json:"command"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration_ns"`
+	Success   bool          `json:"success"`
+	Steps     []Step        `json:"steps,omitempty"`
+	Artifacts []Artifact    `json:"artifacts,omitempty"`
+}
+
+// Recorder collects the artifacts of a run, the download code reports them through the context
+type Recorder struct {
+	mu        sync.Mutex
+	artifacts []Artifact
+}
+
+type recorderKey struct{}
+
+// WithRecorder returns the context with a new recorder for the artifacts of a run
+func WithRecorder(ctx context.Context) (context.Context, *Recorder) {
+	recorder := &Recorder{}
+	return context.WithValue(ctx, recorderKey{}, recorder), recorder
+}
+
+// RecordArtifact adds the artifact to the recorder of the context, it does nothing if no run is recorded
+func RecordArtifact(ctx context.Context, artifact Artifact) {
+	recorder, ok := ctx.Value(recorderKey{}).(*Recorder)
+	if !ok {
+		return
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.artifacts = append(recorder.artifacts, artifact)
+}
+
+// Artifacts returns the recorded artifacts
+func (r *Recorder) Artifacts() []Artifact {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Artifact(nil), r.artifacts...)
+}
+
+// Append adds the run to the history in the .devrig folder, only the MaxRecords most recent runs are kept
+func Append(devrigHome string, run Run) error {
+	line, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to marshal run statistics: %w", err)
+	}
+	if err := os.MkdirAll(devrigHome, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", devrigHome, err)
+	}
+
+	path := filepath.Join(devrigHome, HistoryFileName)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	// A single write per line, so concurrent runs do not interleave
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return trim(path)
+}
+
+// trim rewrites the history with the MaxRecords most recent lines once it has twice as many
+func trim(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	lines := bytes.SplitAfter(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	if len(lines) <= 2*MaxRecords {
+		return nil
+	}
+	lines[len(lines)-1] = append(lines[len(lines)-1], '\n')
+	if err := tempfile.WriteFile(path, bytes.Join(lines[len(lines)-MaxRecords:], nil), 0644); err != nil {
+		return fmt.Errorf("failed to trim %s: %w", path, err)
+	}
+	return nil
+}
+
+// Load reads the runs started at or after since from the history, the oldest first.
+// A missing history is empty, broken lines, e.g. of an interrupted write, are ignored
+func Load(devrigHome string, since time.Time) ([]Run, error) {
+	path := filepath.Join(devrigHome, HistoryFileName)
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer file.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			continue
+		}
+		if run.StartedAt.Before(since) {
+			continue
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return runs, nil
+}
@@ -0,0 +1,111 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/storage"
+)
+
+// NewStatsCommand creates the stats command group
+func NewStatsCommand(configPath func() string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Report metrics about the provisioning runs of devrig",
+	}
+	cmd.AddCommand(newExportCommand(configPath))
+	return cmd
+}
+
+func newExportCommand(configPath func() string) *cobra.Command {
+	var since string
+	var format string
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the aggregate metrics of the recorded runs as JSON or CSV",
+		Long: `Export the aggregate metrics of the recent runs of devrig apply, so platform teams
+can measure the developer experience: the time to provision, the cache hit rate
+and the sizes of the downloaded artifacts.
+
+Every run of devrig apply is recorded in .devrig/` + HistoryFileName + `, the most recent
+` + strconv.Itoa(MaxRecords) + ` runs are kept. The CSV has metric,value rows, durations are in milliseconds.
+
+Examples:
+  devrig stats export
+  devrig stats export --since 7d --format csv --file stats.csv
+  devrig stats export --file gs://ci-metrics/devrig/stats.json
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "json" && format != "csv" {
+				return fmt.Errorf("unsupported format %q, expected json or csv", format)
+			}
+			var from time.Time
+			if since != "" {
+				age, err := parseAge(since)
+				if err != nil {
+					return err
+				}
+				from = time.Now().Add(-age)
+			}
+
+			runs, err := Load(layout.ResolveDevrigHome(configPath()), from)
+			if err != nil {
+				return err
+			}
+			report := Aggregate(runs, from)
+
+			if file == "" {
+				return writeReport(cmd.OutOrStdout(), report, format)
+			}
+			var data bytes.Buffer
+			if err := writeReport(&data, report, format); err != nil {
+				return err
+			}
+			if err := storage.Write(cmd.Context(), file, data.Bytes(), 0644); err != nil {
+				return err
+			}
+			cmd.Printf("Exported %d runs to %s\n", report.Runs, file)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "", "Only include the runs of the given period, e.g. 7d or 12h")
+	cmd.Flags().StringVar(&format, "format", "json", "Format of the report: json or csv")
+	cmd.Flags().StringVar(&file, "file", "", "Write the report to the file, or upload it to an s3://, gs:// or https:// URL, instead of stdout")
+	return cmd
+}
+
+func writeReport(w io.Writer, report Report, format string) error {
+	if format == "csv" {
+		return report.WriteCSV(w)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// parseAge parses a period like 30d, 12h or 90m, days are not supported by time.ParseDuration
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		count, err := strconv.Atoi(days)
+		if err != nil || count < 0 {
+			return 0, fmt.Errorf("invalid period %q, expected e.g. 7d or 12h", value)
+		}
+		return time.Duration(count) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid period %q, expected e.g. 7d or 12h", value)
+	}
+	return age, nil
+}
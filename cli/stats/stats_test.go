@@ -0,0 +1,193 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/summary"
+)
+
+func TestAppendAndLoad(t *testing.T) {
+	devrigHome := filepath.Join(t.TempDir(), ".devrig")
+	now := time.Now().UTC()
+
+	old := Run{Command: "apply", StartedAt: now.Add(-48 * time.Hour), Duration: time.Second, Success: true}
+	recent := Run{Command: "apply", StartedAt: now, Duration: 2 * time.Second, Success: true,
+		Artifacts: []Artifact{{Name: "devrig-linux-x86_64", Size: 42}}}
+	for _, run := range []Run{old, recent} {
+		if err := Append(devrigHome, run); err != nil {
+			t.Fatalf("Failed to append run: %v", err)
+		}
+	}
+
+	// An interrupted write leaves a broken line
+	file, err := os.OpenFile(filepath.Join(devrigHome, HistoryFileName), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open history: %v", err)
+	}
+	_, _ = file.WriteString("{\"command\": \"ap")
+	_ = file.Close()
+
+	all, err := Load(devrigHome, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to load runs: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("Expected 2 runs, got %d", len(all))
+	}
+
+	runs, err := Load(devrigHome, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to load runs: %v", err)
+	}
+	if len(runs) != 1 || runs[0].Duration != 2*time.Second || runs[0].Artifacts[0].Size != 42 {
+		t.Errorf("Expected only the recent run, got %+v", runs)
+	}
+}
+
+func TestLoad_MissingHistory(t *testing.T) {
+	runs, err := Load(t.TempDir(), time.Time{})
+	if err != nil || len(runs) != 0 {
+		t.Errorf("Expected no runs, got %v, %v", runs, err)
+	}
+}
+
+func TestAppend_KeepsMostRecentRuns(t *testing.T) {
+	devrigHome := t.TempDir()
+	start := time.Now().UTC()
+	for i := 0; i <= 2*MaxRecords; i++ {
+		if err := Append(devrigHome, Run{Command: "apply", StartedAt: start.Add(time.Duration(i) * time.Second)}); err != nil {
+			t.Fatalf("Failed to append run: %v", err)
+		}
+	}
+
+	runs, err := Load(devrigHome, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to load runs: %v", err)
+	}
+	if len(runs) != MaxRecords {
+		t.Fatalf("Expected %d runs, got %d", MaxRecords, len(runs))
+	}
+	if !runs[len(runs)-1].StartedAt.Equal(start.Add(2 * MaxRecords * time.Second)) {
+		t.Errorf("Expected the most recent run to be kept, got %v", runs[len(runs)-1].StartedAt)
+	}
+}
+
+func TestRecordArtifact(t *testing.T) {
+	// Without a recorder the artifact is dropped
+	RecordArtifact(context.Background(), Artifact{Name: "ignored"})
+
+	ctx, recorder := WithRecorder(context.Background())
+	RecordArtifact(ctx, Artifact{Name: "binary", Size: 10, Cached: true})
+	artifacts := recorder.Artifacts()
+	if len(artifacts) != 1 || artifacts[0].Name != "binary" || !artifacts[0].Cached {
+		t.Errorf("Unexpected artifacts: %+v", artifacts)
+	}
+}
+
+func testRuns() []Run {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	var runs []Run
+	for i := 1; i <= 10; i++ {
+		runs = append(runs, Run{
+			Command:   "apply",
+			StartedAt: start.Add(time.Duration(i) * time.Hour),
+			Duration:  time.Duration(i) * time.Second,
+			Success:   true,
+			Steps: []Step{
+				{ID: "config", Name: "Validate config", Status: summary.StatusSkipped, Message: "already done"},
+				{ID: "devrig-binary", Name: "Download devrig binary", Status: summary.StatusOK, Duration: time.Duration(i) * time.Millisecond},
+			},
+			Artifacts: []Artifact{{Name: "devrig-linux-x86_64", Size: 100, Cached: i > 1}},
+		})
+	}
+	runs = append(runs, Run{
+		Command:   "apply",
+		StartedAt: start.Add(11 * time.Hour),
+		Duration:  time.Hour,
+		Steps:     []Step{{ID: "devrig-binary", Name: "Download devrig binary", Status: summary.StatusFailed}},
+	})
+	return runs
+}
+
+func TestAggregate(t *testing.T) {
+	report := Aggregate(testRuns(), time.Time{})
+
+	if report.Runs != 11 || report.FailedRuns != 1 {
+		t.Errorf("Expected 11 runs with 1 failure, got %d and %d", report.Runs, report.FailedRuns)
+	}
+	provision := report.TimeToProvision
+	if provision.Count != 10 || provision.P50 != 5*time.Second || provision.P90 != 9*time.Second ||
+		provision.Max != 10*time.Second || provision.Mean != 5500*time.Millisecond {
+		t.Errorf("Unexpected time to provision, the failed run must not count: %+v", provision)
+	}
+	if report.CacheHits != 9 || report.CacheMisses != 1 || report.CacheHitRate != 0.9 {
+		t.Errorf("Expected the cache hit rate 0.9, got %d/%d %v", report.CacheHits, report.CacheMisses, report.CacheHitRate)
+	}
+	if report.DownloadedBytes != 100 || report.CachedBytes != 900 {
+		t.Errorf("Unexpected sizes: %d downloaded, %d cached", report.DownloadedBytes, report.CachedBytes)
+	}
+
+	if len(report.Steps) != 2 {
+		t.Fatalf("Expected 2 steps, got %+v", report.Steps)
+	}
+	if report.Steps[0].ID != "config" || report.Steps[0].Reused != 10 || report.Steps[0].Executed != 0 {
+		t.Errorf("Expected the config step to be reused: %+v", report.Steps[0])
+	}
+	if binary := report.Steps[1]; binary.Executed != 11 || binary.Failed != 1 || binary.Duration.Max != 10*time.Millisecond {
+		t.Errorf("Unexpected devrig binary step: %+v", binary)
+	}
+	if len(report.Artifacts) != 1 || report.Artifacts[0].Uses != 10 || report.Artifacts[0].CacheHits != 9 {
+		t.Errorf("Unexpected artifacts: %+v", report.Artifacts)
+	}
+}
+
+func TestAggregate_NoRuns(t *testing.T) {
+	report := Aggregate(nil, time.Time{})
+	if report.Runs != 0 || report.CacheHitRate != 0 || report.Steps == nil || report.Artifacts == nil {
+		t.Errorf("Expected an empty report with empty lists: %+v", report)
+	}
+}
+
+func TestReport_WriteCSV(t *testing.T) {
+	var out bytes.Buffer
+	if err := Aggregate(testRuns(), time.Time{}).WriteCSV(&out); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	for _, row := range []string{
+		"metric,value\n",
+		"runs,11\n",
+		"time_to_provision.p90_ms,9000\n",
+		"cache_hit_rate,0.9000\n",
+		"step.config.reused,10\n",
+		"artifact.devrig-linux-x86_64.downloaded_bytes,100\n",
+	} {
+		if !strings.Contains(out.String(), row) {
+			t.Errorf("Expected the row %q in:\n%s", row, out.String())
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	for value, expected := range map[string]time.Duration{
+		"7d":  7 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+		"90m": 90 * time.Minute,
+	} {
+		age, err := parseAge(value)
+		if err != nil || age != expected {
+			t.Errorf("parseAge(%q) = %v, %v, expected %v", value, age, err, expected)
+		}
+	}
+	for _, value := range []string{"", "d", "-1d", "week"} {
+		if _, err := parseAge(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
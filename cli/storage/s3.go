@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/clock"
+)
+
+// S3 credentials and settings, the same variables as of the AWS CLI
+const (
+	EnvAWSAccessKeyID     = "AWS_ACCESS_KEY_ID"
+	EnvAWSSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+	EnvAWSSessionToken    = "AWS_SESSION_TOKEN"
+	EnvAWSRegion          = "AWS_REGION"
+	EnvAWSDefaultRegion   = "AWS_DEFAULT_REGION"
+	// EnvAWSEndpointURL points to an S3-compatible server, e.g. MinIO, the bucket is in the path then
+	EnvAWSEndpointURL = "AWS_ENDPOINT_URL"
+)
+
+// S3Credentials sign the requests to S3
+type S3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3Backend uploads the data to an S3 bucket with a request signed with AWS Signature Version 4
+type S3Backend struct {
+	Bucket      string
+	Key         string
+	Region      string
+	Endpoint    string
+	Credentials S3Credentials
+	// Client sends the request, http.DefaultClient when nil
+	Client *http.Client
+
+	now func() time.Time
+}
+
+// NewS3Backend returns the backend of the object with the credentials and the region of the environment
+func NewS3Backend(bucket string, key string) (*S3Backend, error) {
+	credentials := S3Credentials{
+		AccessKeyID:     os.Getenv(EnvAWSAccessKeyID),
+		SecretAccessKey: os.Getenv(EnvAWSSecretAccessKey),
+		SessionToken:    os.Getenv(EnvAWSSessionToken),
+	}
+	if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+		return nil, fmt.Errorf("failed to upload to s3://%s/%s: set %s and %s", bucket, key, EnvAWSAccessKeyID, EnvAWSSecretAccessKey)
+	}
+
+	region := os.Getenv(EnvAWSRegion)
+	if region == "" {
+		region = os.Getenv(EnvAWSDefaultRegion)
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3Backend{Bucket: bucket, Key: key, Region: region, Endpoint: os.Getenv(EnvAWSEndpointURL), Credentials: credentials}, nil
+}
+
+// URL returns the URL of the object, virtual-hosted on AWS and path-style on a custom endpoint
+func (b *S3Backend) URL() string {
+	if b.Endpoint != "" {
+		return strings.TrimSuffix(b.Endpoint, "/") + "/" + b.Bucket + "/" + escapePath(b.Key)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", b.Bucket, b.Region, escapePath(b.Key))
+}
+
+// Put uploads the data
+func (b *S3Backend) Put(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, b.URL(), bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create the upload request: %w", err)
+	}
+	// S3 rejects requests signed more than 15 minutes off its time, the time of the servers is used when the system clock is skewed
+	now := clock.Now
+	if b.now != nil {
+		now = b.now
+	}
+	if err := signV4(req, data, b.Region, b.Credentials, now()); err != nil {
+		return err
+	}
+	return send(b.Client, req)
+}
+
+// signV4 adds the AWS Signature Version 4 of the S3 service to the request, all its headers are signed
+func signV4(req *http.Request, payload []byte, region string, credentials S3Credentials, now time.Time) error {
+	if req.URL.RawQuery != "" {
+		return errors.New("failed to sign the request: query parameters are not supported")
+	}
+	payloadHash := sha256.Sum256(payload)
+	amzDate := now.UTC().Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, "", canonicalHeaders.String(), signedHeaders, hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	day := amzDate[:8]
+	scope := day + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := []byte("AWS4" + credentials.SecretAccessKey)
+	for _, part := range []string{day, region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,149 @@
+// Package storage writes the exported files of devrig to local files or straight to object storage,
+// so CI and air-gap flows need no extra tooling to upload them
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// GCSBaseURL is the XML API of Google Cloud Storage, gs://bucket/object is uploaded to GCSBaseURL/bucket/object
+const GCSBaseURL = "https://storage.googleapis.com/"
+
+// Backend uploads an exported file to its location
+type Backend interface {
+	Put(ctx context.Context, data []byte) error
+}
+
+// IsRemote checks if the location is a URL of a storage backend rather than a local path
+func IsRemote(location string) bool {
+	for _, scheme := range []string{"s3://", "gs://", "https://", "http://"} {
+		if strings.HasPrefix(location, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Open returns the backend of the location: s3://bucket/key, gs://bucket/object, an http(s) URL that accepts PUT,
+// or a local path that is written with the permissions
+func Open(location string, perm os.FileMode) (Backend, error) {
+	if !IsRemote(location) {
+		return &FileBackend{Path: location, Perm: perm}, nil
+	}
+
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", location, err)
+	}
+	switch parsed.Scheme {
+	case "s3", "gs":
+		bucket, key := parsed.Host, strings.TrimPrefix(parsed.Path, "/")
+		if bucket == "" || key == "" || strings.HasSuffix(key, "/") {
+			return nil, fmt.Errorf("invalid location %s, expected %s://bucket/path/to/file", location, parsed.Scheme)
+		}
+		if parsed.Scheme == "gs" {
+			return &HTTPBackend{URL: GCSBaseURL + bucket + "/" + escapePath(key)}, nil
+		}
+		return NewS3Backend(bucket, key)
+	default:
+		return &HTTPBackend{URL: location}, nil
+	}
+}
+
+// Write uploads the data to the location, see Open
+func Write(ctx context.Context, location string, data []byte, perm os.FileMode) error {
+	backend, err := Open(location, perm)
+	if err != nil {
+		return err
+	}
+	return backend.Put(ctx, data)
+}
+
+// FileBackend writes the data to a local file
+type FileBackend struct {
+	Path string
+	Perm os.FileMode
+}
+
+// Put replaces the file with the data
+func (b *FileBackend) Put(ctx context.Context, data []byte) error {
+	if err := tempfile.WriteFile(b.Path, data, b.Perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", b.Path, err)
+	}
+	return nil
+}
+
+// HTTPBackend uploads the data with a PUT request. The auth providers of devrig.yaml add the tokens
+// from the secret store for their hosts, e.g. for storage.googleapis.com
+type HTTPBackend struct {
+	URL string
+	// Client sends the request, http.DefaultClient with the auth transport when nil
+	Client *http.Client
+}
+
+// Put uploads the data
+func (b *HTTPBackend) Put(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, b.URL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create the upload request: %w", err)
+	}
+	return send(b.Client, req)
+}
+
+// send uploads the request, a status other than 2xx is an error
+func send(client *http.Client, req *http.Request) error {
+	location := req.URL.Redacted()
+	if err := offline.Check(location, location, "uploads need network access, write to a local file instead"); err != nil {
+		return err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload to %s: %w", location, &devrigErrors.NetworkError{URL: location, Err: err})
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to upload to %s: %w", location, &devrigErrors.NetworkError{URL: location,
+			Err: fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))})
+	}
+	return nil
+}
+
+// escapePath escapes the segments of an object key, the slashes are kept
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// uriEncode escapes all characters except the unreserved ones of RFC 3986, as the S3 signature requires
+func uriEncode(value string) string {
+	var sb strings.Builder
+	for _, b := range []byte(value) {
+		if ('A' <= b && b <= 'Z') || ('a' <= b && b <= 'z') || ('0' <= b && b <= '9') || b == '-' || b == '_' || b == '.' || b == '~' {
+			sb.WriteByte(b)
+		} else {
+			_, _ = fmt.Fprintf(&sb, "%%%02X", b)
+		}
+	}
+	return sb.String()
+}
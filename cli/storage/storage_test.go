@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/offline"
+)
+
+func TestOpen(t *testing.T) {
+	for location, expected := range map[string]string{
+		"stats.json":                     "",
+		"gs://bucket/ci/a b.zip":         GCSBaseURL + "bucket/ci/a%20b.zip",
+		"https://cache.example.com/a.js": "https://cache.example.com/a.js",
+	} {
+		backend, err := Open(location, 0644)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", location, err)
+		}
+		switch b := backend.(type) {
+		case *FileBackend:
+			if expected != "" || b.Path != location {
+				t.Errorf("Unexpected file backend for %s: %+v", location, b)
+			}
+		case *HTTPBackend:
+			if b.URL != expected {
+				t.Errorf("Expected %s for %s, got %s", expected, location, b.URL)
+			}
+		default:
+			t.Errorf("Unexpected backend for %s: %T", location, backend)
+		}
+	}
+
+	for _, location := range []string{"gs://bucket", "s3://bucket/", "gs:///file.zip", "s3://bucket/folder/"} {
+		if _, err := Open(location, 0644); err == nil {
+			t.Errorf("Expected an error for %s", location)
+		}
+	}
+}
+
+func TestWrite_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	if err := Write(context.Background(), path, []byte("zip"), 0600); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat %s: %v", path, err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestWrite_HTTP(t *testing.T) {
+	var method, body string
+	status := http.StatusCreated
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		method, body = r.Method, string(data)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("denied"))
+	}))
+	defer server.Close()
+
+	if err := Write(context.Background(), server.URL+"/stats.json", []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to upload: %v", err)
+	}
+	if method != http.MethodPut || body != "{}" {
+		t.Errorf("Unexpected upload: %s %q", method, body)
+	}
+
+	status = http.StatusForbidden
+	var network *devrigErrors.NetworkError
+	if err := Write(context.Background(), server.URL+"/stats.json", []byte("{}"), 0644); !errors.As(err, &network) {
+		t.Errorf("Expected a network error for status 403, got: %v", err)
+	}
+
+	t.Setenv(offline.EnvOffline, "1")
+	var offlineErr *devrigErrors.OfflineError
+	if err := Write(context.Background(), server.URL+"/stats.json", []byte("{}"), 0644); !errors.As(err, &offlineErr) {
+		t.Errorf("Expected an offline error, got: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+// Package summary collects the status of the steps of multi-step commands
+// and prints them as a table at the end, so long outputs are easy to scan
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/output"
+)
+
+// Status is the outcome of a step
+type Status string
+
+const (
+	StatusOK      Status = "OK"
+	StatusSkipped Status = "SKIPPED"
+	StatusFailed  Status = "FAILED"
+)
+
+// Step is the recorded outcome of a single step
+type Step struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Duration time.Duration `json:"duration_ns"`
+	Required bool          `json:"required"`
+	Message  string        `json:"message,omitempty"`
+
+	err error
+}
+
+// Summary records the steps of a command
+type Summary struct {
+	Steps []Step
+}
+
+// Run executes the step and records its status and duration, the error of fn is returned as is
+func (s *Summary) Run(name string, required bool, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	step := Step{Name: name, Status: StatusOK, Duration: time.Since(start), Required: required}
+	if err != nil {
+		step.Status = StatusFailed
+		step.Message = err.Error()
+		step.err = err
+	}
+	s.Steps = append(s.Steps, step)
+	return err
+}
+
+// Record adds a required step that was executed elsewhere, e.g. in parallel with others
+func (s *Summary) Record(name string, duration time.Duration, err error) {
+	step := Step{Name: name, Status: StatusOK, Duration: duration, Required: true}
+	if err != nil {
+		step.Status = StatusFailed
+		step.Message = err.Error()
+		step.err = err
+	}
+	s.Steps = append(s.Steps, step)
+}
+
+// Skip records a step that was not executed
+func (s *Summary) Skip(name string, reason string) {
+	s.Steps = append(s.Steps, Step{Name: name, Status: StatusSkipped, Message: reason})
+}
+
+// Err returns the error of the first failed required step, or nil
+func (s *Summary) Err() error {
+	for _, step := range s.Steps {
+		if step.Status == StatusFailed && step.Required {
+			return fmt.Errorf("%s failed: %w", step.Name, step.err)
+		}
+	}
+	return nil
+}
+
+// Print writes the table of steps, or a JSON document when --output json is set
+func (s *Summary) Print(ctx context.Context, out io.Writer) {
+	if len(s.Steps) == 0 {
+		return
+	}
+
+	if output.FormatFromContext(ctx) == output.FormatJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(struct {
+			Steps []Step `json:"steps"`
+		}{s.Steps})
+		return
+	}
+
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, "Summary:")
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, step := range s.Steps {
+		duration := ""
+		if step.Status != StatusSkipped {
+			duration = formatDuration(step.Duration)
+		}
+		message := step.Message
+		if step.Status == StatusFailed && !step.Required {
+			message = "optional: " + message
+		}
+		_, _ = fmt.Fprintf(writer, "  %s\t%s\t%s\t%s\n", step.Status, step.Name, duration, firstLine(message))
+	}
+	_ = writer.Flush()
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
+
+func firstLine(text string) string {
+	for i, c := range text {
+		if c == '\n' {
+			return text[:i]
+		}
+	}
+	return text
+}
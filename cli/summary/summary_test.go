@@ -0,0 +1,66 @@
+package summary
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/output"
+)
+
+func TestSummary_PrintTable(t *testing.T) {
+	var s Summary
+	_ = s.Run("Create bootstrap scripts", true, func() error { return nil })
+	s.Skip("Import .tool-versions", "no .tool-versions file")
+	_ = s.Run("Install fonts", false, func() error { return fmt.Errorf("network is down\nmore details") })
+
+	if err := s.Err(); err != nil {
+		t.Errorf("Expected optional failures to be ignored, got: %v", err)
+	}
+
+	var out bytes.Buffer
+	s.Print(context.Background(), &out)
+	text := out.String()
+	for _, expected := range []string{"OK       Create bootstrap scripts", "SKIPPED  Import .tool-versions", "FAILED   Install fonts", "optional: network is down"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in the summary:\n%s", expected, text)
+		}
+	}
+	if strings.Contains(text, "more details") {
+		t.Errorf("Expected only the first line of errors:\n%s", text)
+	}
+}
+
+func TestSummary_RequiredFailure(t *testing.T) {
+	var s Summary
+	cause := fmt.Errorf("checksum mismatch")
+	if err := s.Run("Download devrig binary", true, func() error { return cause }); err != cause {
+		t.Errorf("Expected the step error to be returned as is, got: %v", err)
+	}
+
+	err := s.Err()
+	if err == nil || !strings.Contains(err.Error(), "Download devrig binary failed") {
+		t.Errorf("Expected required failure, got: %v", err)
+	}
+}
+
+func TestSummary_PrintJSON(t *testing.T) {
+	var s Summary
+	_ = s.Run("Validate devrig.yaml", true, func() error { return nil })
+
+	var out bytes.Buffer
+	s.Print(output.WithFormat(context.Background(), output.FormatJSON), &out)
+
+	var parsed struct {
+		Steps []Step `json:"steps"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &parsed); err != nil {
+		t.Fatalf("Failed to parse JSON summary: %v\n%s", err, out.String())
+	}
+	if len(parsed.Steps) != 1 || parsed.Steps[0].Status != StatusOK {
+		t.Errorf("Unexpected JSON summary: %s", out.String())
+	}
+}
@@ -0,0 +1,172 @@
+package support
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/doctor"
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+// maxLogBytes limits how much of each log file is included, the tail is kept
+const maxLogBytes = 1024 * 1024
+
+// Entry is a single file in the support bundle
+type Entry struct {
+	Name    string
+	Content []byte
+}
+
+// Bundle is the collected support information
+type Bundle struct {
+	Entries []Entry
+}
+
+// Options configures what is collected into the bundle
+type Options struct {
+	ConfigPath    string
+	DevrigVersion string
+	Checks        []doctor.Check
+}
+
+// Collect gathers redacted diagnostics of the project into a bundle
+func Collect(ctx context.Context, opts Options) (*Bundle, error) {
+	bundle := &Bundle{}
+
+	bundle.add("versions.txt", []byte(describeVersions(opts.DevrigVersion)))
+	bundle.add("environment.txt", []byte(RedactText(describeEnvironment())))
+
+	if data, err := os.ReadFile(opts.ConfigPath); err == nil {
+		bundle.add(filepath.Base(opts.ConfigPath), []byte(RedactText(string(data))))
+	} else {
+		bundle.add(filepath.Base(opts.ConfigPath)+".missing", []byte(err.Error()+"\n"))
+	}
+
+	var doctorOutput bytes.Buffer
+	doctorEnv := doctor.NewEnvironment(opts.ConfigPath)
+	doctor.RunChecks(ctx, doctorEnv, doctor.WithCustomChecks(doctorEnv, opts.Checks), &doctorOutput)
+	bundle.add("doctor.txt", []byte(RedactText(doctorOutput.String())))
+
+	logDir := filepath.Join(layout.ResolveDevrigHome(opts.ConfigPath), "logs")
+	logs, err := os.ReadDir(logDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list logs in %s: %w", logDir, err)
+	}
+	for _, log := range logs {
+		if log.IsDir() {
+			continue
+		}
+		data, err := readTail(filepath.Join(logDir, log.Name()), maxLogBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read log %s: %w", log.Name(), err)
+		}
+		bundle.add("logs/"+log.Name(), []byte(RedactText(string(data))))
+	}
+
+	return bundle, nil
+}
+
+func (b *Bundle) add(name string, content []byte) {
+	b.Entries = append(b.Entries, Entry{Name: name, Content: content})
+}
+
+// Preview prints the list of files in the bundle with their sizes
+func (b *Bundle) Preview(out io.Writer) {
+	total := 0
+	for _, entry := range b.Entries {
+		fmt.Fprintf(out, "  %-40s %8d bytes\n", entry.Name, len(entry.Content))
+		total += len(entry.Content)
+	}
+	fmt.Fprintf(out, "  %d files, %d bytes in total\n", len(b.Entries), total)
+}
+
+// Zip returns the bundle as a zip archive
+func (b *Bundle) Zip() ([]byte, error) {
+	var data bytes.Buffer
+	writer := zip.NewWriter(&data)
+	for _, entry := range b.Entries {
+		w, err := writer.Create(entry.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to the archive: %w", entry.Name, err)
+		}
+		if _, err := w.Write(entry.Content); err != nil {
+			return nil, fmt.Errorf("failed to write %s to the archive: %w", entry.Name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish the archive: %w", err)
+	}
+	return data.Bytes(), nil
+}
+
+// WriteZip writes the bundle to a zip archive
+func (b *Bundle) WriteZip(path string) error {
+	data, err := b.Zip()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	return nil
+}
+
+func describeVersions(devrigVersion string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "devrig: %s\n", devrigVersion)
+	fmt.Fprintf(&sb, "go: %s\n", runtime.Version())
+	fmt.Fprintf(&sb, "os: %s\n", runtime.GOOS)
+	fmt.Fprintf(&sb, "arch: %s\n", runtime.GOARCH)
+	fmt.Fprintf(&sb, "cpus: %d\n", runtime.NumCPU())
+	fmt.Fprintf(&sb, "collected: %s\n", time.Now().UTC().Format(time.RFC3339))
+	return sb.String()
+}
+
+// describeEnvironment lists devrig-related environment variables and proxy settings
+func describeEnvironment() string {
+	var lines []string
+	for _, kv := range os.Environ() {
+		name := strings.SplitN(kv, "=", 2)[0]
+		upper := strings.ToUpper(name)
+		if strings.HasPrefix(upper, "DEVRIG_") || strings.HasSuffix(upper, "_PROXY") {
+			lines = append(lines, kv)
+		}
+	}
+	sort.Strings(lines)
+	if len(lines) == 0 {
+		return "no DEVRIG_* or proxy environment variables are set\n"
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// readTail reads at most limit bytes from the end of the file
+func readTail(path string, limit int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() > limit {
+		if _, err := file.Seek(info.Size()-limit, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return io.ReadAll(file)
+}
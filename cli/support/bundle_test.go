@@ -0,0 +1,65 @@
+package support
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/doctor"
+)
+
+func TestCollectAndWriteZip(t *testing.T) {
+	projectDir := t.TempDir()
+	configPath := filepath.Join(projectDir, "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte("custom:\n  password: secret-value\n"), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+
+	logDir := filepath.Join(projectDir, ".devrig", "logs")
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		t.Fatalf("Failed to create log dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(logDir, "devrig-2025-01-15.log"), []byte(`{"msg":"hello"}`), 0644); err != nil {
+		t.Fatalf("Failed to write log: %v", err)
+	}
+
+	bundle, err := Collect(context.Background(), Options{
+		ConfigPath:    configPath,
+		DevrigVersion: "1.2.3",
+		Checks:        []doctor.Check{&doctor.ConfigCheck{}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to collect bundle: %v", err)
+	}
+
+	archive := filepath.Join(t.TempDir(), "bundle.zip")
+	if err := bundle.WriteZip(archive); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatalf("Failed to open zip: %v", err)
+	}
+	//goland:noinspection GoUnhandledErrorResult
+	defer reader.Close()
+
+	names := map[string]bool{}
+	for _, f := range reader.File {
+		names[f.Name] = true
+	}
+	for _, expected := range []string{"versions.txt", "environment.txt", "devrig.yaml", "doctor.txt", "logs/devrig-2025-01-15.log"} {
+		if !names[expected] {
+			t.Errorf("Expected %s in bundle, got: %v", expected, names)
+		}
+	}
+
+	for _, entry := range bundle.Entries {
+		if strings.Contains(string(entry.Content), "secret-value") {
+			t.Errorf("Secret leaked into %s", entry.Name)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package support
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces removed secret values
+const Redacted = "<redacted>"
+
+var (
+	// secretKeyPattern matches YAML/JSON keys that usually hold credentials
+	secretKeyPattern = regexp.MustCompile(`(?i)^(\s*-?\s*"?[a-z0-9_.-]*(token|password|passwd|secret|api[_-]?key|credential|private[_-]?key|auth)[a-z0-9_.-]*"?\s*:\s*)(.+)$`)
+	// jsonSecretPattern matches string values of secret-looking keys anywhere in a line, e.g. in the JSON logs
+	jsonSecretPattern = regexp.MustCompile(`(?i)("[a-z0-9_.-]*(token|password|passwd|secret|api[_-]?key|credential|private[_-]?key|auth)[a-z0-9_.-]*"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	// slogSecretPattern matches key=value pairs of secret-looking keys in the slog text format
+	slogSecretPattern = regexp.MustCompile(`(?i)(\b[a-z0-9_.-]*(token|password|passwd|secret|api[_-]?key|credential|private[_-]?key|auth)[a-z0-9_.-]*=)("(?:[^"\\]|\\.)*"|[^\s&"']+)`)
+	// urlUserInfoPattern matches user:password@ in URLs
+	urlUserInfoPattern = regexp.MustCompile(`(://)[^/\s:@]+(:[^/\s@]*)?@`)
+	// urlSecretQueryPattern matches query parameters that usually hold credentials
+	urlSecretQueryPattern = regexp.MustCompile(`(?i)([?&](token|access_token|sig|signature|key|x-amz-signature|x-goog-signature)=)[^&\s"']+`)
+	// envSecretPattern matches NAME=value pairs of secret-looking environment variables
+	envSecretPattern = regexp.MustCompile(`\b([A-Z0-9_]*(TOKEN|PASSWORD|SECRET|API_KEY|CREDENTIALS)[A-Z0-9_]*=)[^\s&"']+`)
+)
+
+// RedactText strips secrets from configuration files and logs line by line,
+// keeping the structure and comments intact so the result stays useful for diagnostics
+func RedactText(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if match := secretKeyPattern.FindStringSubmatch(line); match != nil {
+			value := strings.TrimSpace(match[3])
+			// keep nested YAML mappings (key:) and empty values as is
+			if value != "" && value != "|" && value != ">" {
+				line = match[1] + Redacted
+			}
+		}
+		line = jsonSecretPattern.ReplaceAllString(line, `${1}"`+Redacted+`"`)
+		line = slogSecretPattern.ReplaceAllString(line, "${1}"+Redacted)
+		line = urlUserInfoPattern.ReplaceAllString(line, "${1}"+Redacted+"@")
+		line = urlSecretQueryPattern.ReplaceAllString(line, "${1}"+Redacted)
+		line = envSecretPattern.ReplaceAllString(line, "${1}"+Redacted)
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
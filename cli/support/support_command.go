@@ -0,0 +1,92 @@
+package support
+
+import (
+	"bufio"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/doctor"
+	"jonnyzzz.com/devrig.dev/storage"
+)
+
+// NewSupportBundleCommand creates the support-bundle command
+func NewSupportBundleCommand(devrigVersion string, configPath func() string, checks []doctor.Check) *cobra.Command {
+	var output string
+	var yes bool
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "support-bundle",
+		Short: "Collect a redacted diagnostics archive to attach to bug reports",
+		Long: `Collect a zip archive with diagnostics to attach to bug reports.
+
+The archive contains devrig.yaml with secrets stripped, the doctor report,
+the devrig logs, versions and OS information. The contents are listed
+before the archive is written.
+
+Examples:
+  devrig support-bundle
+  devrig support-bundle --dry-run
+  devrig support-bundle --yes --file devrig-support.zip
+  devrig support-bundle --yes --file s3://ci-artifacts/devrig/support.zip
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bundle, err := Collect(cmd.Context(), Options{
+				ConfigPath:    configPath(),
+				DevrigVersion: devrigVersion,
+				Checks:        checks,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to collect support bundle: %w", err)
+			}
+
+			if output == "" {
+				output = fmt.Sprintf("devrig-support-%s.zip", time.Now().Format("20060102-150405"))
+			}
+			if !storage.IsRemote(output) {
+				output, err = filepath.Abs(output)
+				if err != nil {
+					return fmt.Errorf("failed to resolve output path: %w", err)
+				}
+			}
+
+			cmd.Println("The support bundle will contain:")
+			bundle.Preview(cmd.OutOrStdout())
+
+			if dryRun {
+				cmd.Println("Dry run: nothing is written")
+				return nil
+			}
+
+			if !yes {
+				cmd.Printf("Write it to %s? [y/N] ", output)
+				answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				answer = strings.ToLower(strings.TrimSpace(answer))
+				if answer != "y" && answer != "yes" {
+					cmd.Println("Aborted, nothing is written")
+					return nil
+				}
+			}
+
+			data, err := bundle.Zip()
+			if err != nil {
+				return err
+			}
+			if err := storage.Write(cmd.Context(), output, data, 0600); err != nil {
+				return err
+			}
+
+			cmd.Printf("Support bundle written to %s\n", output)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&output, "file", "", "Path to the zip archive to write, or an s3://, gs:// or https:// URL to upload it to")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Write the archive without asking for confirmation")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only list the contents of the archive")
+	return cmd
+}
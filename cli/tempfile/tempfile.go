@@ -0,0 +1,174 @@
+// Package tempfile creates the temporary files and folders of devrig readable only by the current user
+// and removes them reliably, also when devrig is interrupted, so partial downloads, tokens and signatures
+// do not linger on disk. Files with secrets are overwritten before they are removed.
+package tempfile
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+)
+
+var (
+	mu sync.Mutex
+	// tracked are the paths to remove on interrupt, the value tells to shred the file first
+	tracked = map[string]bool{}
+)
+
+// Create creates a temporary file in dir with mode 0600. The file is removed on interrupt until Remove is called
+func Create(dir string, pattern string) (*os.File, error) {
+	file, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	track(file.Name(), false)
+	return file, nil
+}
+
+// Mkdir creates a temporary folder in dir with mode 0700. The folder is removed on interrupt until Remove is called
+func Mkdir(dir string, pattern string) (string, error) {
+	path, err := os.MkdirTemp(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	track(path, false)
+	return path, nil
+}
+
+// Track registers a file created elsewhere, e.g. a download written next to its target, for removal on interrupt
+func Track(path string) {
+	track(path, false)
+}
+
+// TrackSecret registers a file with secrets, it is overwritten before removal on interrupt
+func TrackSecret(path string) {
+	track(path, true)
+}
+
+func track(path string, secret bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	tracked[path] = secret
+}
+
+// Remove removes the file or folder and stops tracking it, a missing path is not an error
+func Remove(path string) error {
+	mu.Lock()
+	secret := tracked[path]
+	delete(tracked, path)
+	mu.Unlock()
+
+	if secret {
+		return Shred(path)
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
+
+// Shred overwrites the file with zeros before removing it, for tokens and other secrets.
+// Journaling filesystems and SSDs may keep copies, still the content is gone from the file itself
+func Shred(path string) error {
+	if file, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+		if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+			_, _ = io.CopyN(file, zeroReader{}, info.Size())
+			_ = file.Sync()
+		}
+		_ = file.Close()
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
+
+// WriteFile replaces the file atomically: the data goes to a 0600 temporary file next to it, which gets
+// the mode and is renamed over the file, so concurrent readers never see a partial file and an interrupted
+// write leaves no temporary file behind
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	return writeFile(path, data, perm, false)
+}
+
+// WriteSecretFile replaces the file with secrets atomically with mode 0600, a failed write shreds the temporary file
+func WriteSecretFile(path string, data []byte) error {
+	return writeFile(path, data, 0600, true)
+}
+
+func writeFile(path string, data []byte, perm os.FileMode, secret bool) error {
+	temp, err := Create(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	track(temp.Name(), secret)
+	defer Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(temp.Name(), perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	return nil
+}
+
+// Cleanup removes all tracked files and folders
+func Cleanup() {
+	mu.Lock()
+	paths := tracked
+	tracked = map[string]bool{}
+	mu.Unlock()
+
+	for path, secret := range paths {
+		if secret {
+			_ = Shred(path)
+		} else {
+			_ = os.RemoveAll(path)
+		}
+	}
+}
+
+// CleanupOnSignal removes the tracked files when devrig is interrupted or terminated, and then calls exit
+// with the conventional 128+signal code. The process decides how to exit, so library code never calls os.Exit.
+// The returned function stops the handling
+func CleanupOnSignal(exit func(code int)) func() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			Cleanup()
+			code := 128 + int(syscall.SIGINT)
+			if s, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(s)
+			}
+			exit(code)
+		case <-done:
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
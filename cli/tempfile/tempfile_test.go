@@ -0,0 +1,138 @@
+package tempfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "devrig.lock")
+
+	if err := WriteFile(path, []byte("first"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := WriteFile(path, []byte("second"), 0644); err != nil {
+		t.Fatalf("Failed to replace file: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "second" {
+		t.Errorf("Expected the replaced content, got: %q, %v", data, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files left, got %d entries", len(entries))
+	}
+	if len(tracked) != 0 {
+		t.Errorf("Expected no tracked files left, got %v", tracked)
+	}
+}
+
+func TestWriteSecretFile_Mode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no Unix file modes")
+	}
+	path := filepath.Join(t.TempDir(), "token.json")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if err := WriteSecretFile(path, []byte("secret")); err != nil {
+		t.Fatalf("Failed to write secret: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat secret: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600 even for a file created before, got %v", info.Mode().Perm())
+	}
+}
+
+func TestShred(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	if err := os.WriteFile(path, []byte("secret"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if err := Shred(path); err != nil {
+		t.Fatalf("Failed to shred: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the file to be removed, got: %v", err)
+	}
+	if err := Shred(path); err != nil {
+		t.Errorf("Expected a missing file to be no error, got: %v", err)
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	dir := t.TempDir()
+	folder, err := Mkdir(dir, ".download-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	file, err := Create(folder, "archive-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	_ = file.Close()
+	secret := filepath.Join(dir, "token.json.tmp")
+	if err := os.WriteFile(secret, []byte("secret"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	TrackSecret(secret)
+	kept := filepath.Join(dir, "kept")
+	Track(kept)
+	if err := Remove(kept); err != nil {
+		t.Fatalf("Failed to remove untracked file: %v", err)
+	}
+
+	Cleanup()
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Errorf("Expected the tracked files to be removed, got %d entries", len(entries))
+	}
+	if len(tracked) != 0 {
+		t.Errorf("Expected no tracked files left, got %v", tracked)
+	}
+}
+
+func TestCleanupOnSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals cannot be sent to the own process on Windows")
+	}
+	folder, err := Mkdir(t.TempDir(), ".download-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+
+	codes := make(chan int, 1)
+	stop := CleanupOnSignal(func(code int) { codes <- code })
+	defer stop()
+
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("Failed to find own process: %v", err)
+	}
+	if err := process.Signal(syscall.SIGTERM); err != nil {
+		t.Fatalf("Failed to send signal: %v", err)
+	}
+	select {
+	case code := <-codes:
+		if code != 128+int(syscall.SIGTERM) {
+			t.Errorf("Expected exit code %d, got %d", 128+int(syscall.SIGTERM), code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected exit to be called on the signal")
+	}
+	if _, err := os.Stat(folder); !os.IsNotExist(err) {
+		t.Errorf("Expected the temp directory to be removed on the signal, got: %v", err)
+	}
+}
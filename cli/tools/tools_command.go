@@ -0,0 +1,210 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/feed"
+	"jonnyzzz.com/devrig.dev/lockcmd"
+	"jonnyzzz.com/devrig.dev/toolversions"
+)
+
+// NewToolsCommand creates the tools command with subcommands
+func NewToolsCommand(configs func() configservice.ConfigService, configPath func() string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tools",
+		Short: "Manage tool versions pinned in devrig.yaml",
+		Long: `Manage tool versions pinned in the tools section of devrig.yaml.
+
+The tools section is compatible with the asdf/mise .tool-versions file,
+which can be imported into devrig.yaml and kept in sync with it.
+
+Examples:
+  devrig tools list
+  devrig tools import
+  devrig tools export
+  devrig tools download --target-os darwin --target-arch arm64
+`,
+	}
+
+	cmd.AddCommand(newListCommand(configs))
+	cmd.AddCommand(newImportCommand(configs, configPath))
+	cmd.AddCommand(newExportCommand(configs, configPath))
+	cmd.AddCommand(newDownloadCommand(configPath))
+
+	return cmd
+}
+
+func newListCommand(configs func() configservice.ConfigService) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List tools pinned in devrig.yaml",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tools, err := configs().Tools().ReadTools()
+			if err != nil {
+				return err
+			}
+
+			if len(tools) == 0 {
+				cmd.Println("No tools are pinned in devrig.yaml")
+				return nil
+			}
+
+			for _, name := range sortedNames(tools) {
+				cmd.Printf("%s %s\n", name, tools[name])
+			}
+			return nil
+		},
+	}
+}
+
+func newImportCommand(configs func() configservice.ConfigService, configPath func() string) *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import tool versions from .tool-versions into devrig.yaml",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				file = filepath.Join(filepath.Dir(configPath()), toolversions.FileName)
+			}
+
+			imported, err := ImportToolVersions(configs(), file)
+			if err != nil {
+				return err
+			}
+
+			cmd.Printf("Imported %d tools from %s\n", imported, file)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&file, "file", "", "Path to the .tool-versions file (defaults to the one next to devrig.yaml)")
+	return cmd
+}
+
+func newExportCommand(configs func() configservice.ConfigService, configPath func() string) *cobra.Command {
+	var file string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write tool versions from devrig.yaml into .tool-versions",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				file = filepath.Join(filepath.Dir(configPath()), toolversions.FileName)
+			}
+
+			tools, err := configs().Tools().ReadTools()
+			if err != nil {
+				return err
+			}
+
+			if err := toolversions.SyncFile(file, tools); err != nil {
+				return err
+			}
+
+			cmd.Printf("Exported %d tools to %s\n", len(tools), file)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&file, "file", "", "Path to the .tool-versions file (defaults to the one next to devrig.yaml)")
+	return cmd
+}
+
+// ImportToolVersions merges the tools from the given .tool-versions file into devrig.yaml.
+// Versions from the file win over the ones already pinned in devrig.yaml.
+// Returns the number of imported tools.
+func ImportToolVersions(configs configservice.ConfigService, file string) (int, error) {
+	entries, err := toolversions.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, fmt.Errorf("%s not found", file)
+		}
+		return 0, err
+	}
+
+	tools, err := configs.Tools().ReadTools()
+	if err != nil {
+		return 0, err
+	}
+
+	imported := toolversions.ToTools(entries)
+	for name, version := range imported {
+		tools[name] = version
+	}
+
+	if err := configs.Tools().UpdateTools(tools); err != nil {
+		return 0, fmt.Errorf("failed to update tools section: %w", err)
+	}
+
+	return len(imported), nil
+}
+
+func sortedNames(tools configservice.ToolsSection) []string {
+	names := make([]string, 0, len(tools))
+	for name := range tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newDownloadCommand(configPath func() string) *cobra.Command {
+	var dir string
+	cmd := &cobra.Command{
+		Use:   "download [name...]",
+		Short: "Download the pinned tools for a platform without installing them",
+		Long: `Download the JDK and the toolchains pinned in devrig.yaml for a platform without installing them.
+
+The archives are verified against the published checksums and saved to
+.devrig/downloads/<os>-<arch>, or to --dir. Use --target-os and --target-arch
+to prepare the tools for another platform, e.g. on a Linux CI agent for the
+macOS machines of the team, the archives are unpacked there. The builds
+locked in devrig.lock are downloaded when the lock has the platform.
+All pinned tools are downloaded unless names like jdk or node are given.
+
+Examples:
+  devrig tools download
+  devrig tools download node --target-os windows --target-arch amd64 --dir out
+`,
+	}
+	target := lockcmd.AddTargetFlags(cmd)
+	cmd.Flags().StringVar(&dir, "dir", "", "Folder to save the archives to, .devrig/downloads/<os>-<arch> by default")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		platform, err := target.Platform()
+		if err != nil {
+			return err
+		}
+		pins, err := lockcmd.ProjectPins(configPath())
+		if err != nil {
+			return err
+		}
+		// The IDE is downloaded with devrig ide download
+		pins = slices.DeleteFunc(pins, func(pin lockcmd.Pin) bool { return pin.Name == feed.IdeLockName })
+		if len(args) > 0 {
+			for _, name := range args {
+				if !slices.ContainsFunc(pins, func(pin lockcmd.Pin) bool { return pin.Name == name }) {
+					return fmt.Errorf("%s is not pinned in devrig.yaml", name)
+				}
+			}
+			pins = slices.DeleteFunc(pins, func(pin lockcmd.Pin) bool { return !slices.Contains(args, pin.Name) })
+		}
+		if len(pins) == 0 {
+			cmd.Println("No tools are pinned in devrig.yaml")
+			return nil
+		}
+
+		downloaded, err := lockcmd.DownloadPins(cmd.Context(), configPath(), pins, platform, dir)
+		if err != nil {
+			return err
+		}
+		lockcmd.PrintDownloaded(cmd, downloaded, platform)
+		return nil
+	}
+	return cmd
+}
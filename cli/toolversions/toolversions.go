@@ -0,0 +1,169 @@
+package toolversions
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// FileName is the name of the asdf/mise version pin file
+const FileName = ".tool-versions"
+
+// asdfAliases maps asdf plugin names to the tool names used in devrig.yaml.
+// mise accepts both spellings, so devrig uses the shorter ones.
+var asdfAliases = map[string]string{
+	"nodejs": "node",
+	"golang": "go",
+}
+
+// Entry is a single tool pin from a .tool-versions file
+type Entry struct {
+	// Name is the tool name exactly as written in the file
+	Name string
+	// Versions lists the pinned versions, the first one is the preferred one
+	Versions []string
+}
+
+// CanonicalName returns the devrig tool name for the entry
+func (e Entry) CanonicalName() string {
+	return CanonicalToolName(e.Name)
+}
+
+// CanonicalToolName converts an asdf plugin name into the devrig tool name
+func CanonicalToolName(name string) string {
+	if alias, ok := asdfAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// Parse parses the content of a .tool-versions file.
+// Comments (starting with #) and blank lines are ignored.
+func Parse(data []byte) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(stripComment(scanner.Text()))
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: missing version for tool %s", lineNo, fields[0])
+		}
+
+		entries = append(entries, Entry{Name: fields[0], Versions: fields[1:]})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tool versions: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ReadFile reads and parses a .tool-versions file
+func ReadFile(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// ToTools converts entries to a tool-name to version map using canonical names
+// and the preferred (first) version of each tool
+func ToTools(entries []Entry) map[string]string {
+	tools := make(map[string]string, len(entries))
+	for _, e := range entries {
+		tools[e.CanonicalName()] = e.Versions[0]
+	}
+	return tools
+}
+
+// Sync rewrites the content of a .tool-versions file so it pins exactly the given tools.
+// Existing lines keep their original tool spelling, fallback versions and comments, lines for tools that are
+// no longer pinned are removed, and new tools are appended in alphabetical order.
+func Sync(data []byte, tools map[string]string) []byte {
+	var out bytes.Buffer
+	seen := map[string]bool{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		content := stripComment(line)
+		fields := strings.Fields(content)
+		if len(fields) == 0 {
+			out.WriteString(line + "\n")
+			continue
+		}
+
+		name := CanonicalToolName(fields[0])
+		version, ok := tools[name]
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if len(fields) > 1 && fields[1] == version {
+			out.WriteString(line + "\n")
+			continue
+		}
+
+		// asdf falls back to the versions after the first one, they are kept unless one of them is pinned now
+		pinned := []string{fields[0], version}
+		for _, fallback := range fields[min(len(fields), 2):] {
+			if fallback != version {
+				pinned = append(pinned, fallback)
+			}
+		}
+		// keep the inline comment together with the whitespace before it
+		comment := strings.TrimPrefix(line, strings.TrimRight(content, " \t"))
+		out.WriteString(strings.Join(pinned, " ") + comment + "\n")
+	}
+
+	var missing []string
+	for name := range tools {
+		if !seen[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+
+	for _, name := range missing {
+		out.WriteString(name + " " + tools[name] + "\n")
+	}
+
+	return out.Bytes()
+}
+
+// SyncFile updates (or creates) the .tool-versions file at path, see Sync
+func SyncFile(path string, tools map[string]string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, Sync(data, tools), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// stripComment removes a trailing # comment, keeping the leading part of the line
+func stripComment(line string) string {
+	if idx := strings.Index(line, "#"); idx >= 0 {
+		return line[:idx]
+	}
+	return line
+}
@@ -0,0 +1,112 @@
+package toolversions
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	content := `# project pins
+nodejs 20.11.0 18.19.0
+golang 1.22.1   # inline comment
+
+java temurin-21.0.2+13.0.LTS
+`
+	entries, err := Parse([]byte(content))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got: %d", len(entries))
+	}
+
+	if entries[0].Name != "nodejs" || len(entries[0].Versions) != 2 {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+
+	tools := ToTools(entries)
+	if tools["node"] != "20.11.0" {
+		t.Errorf("Expected node 20.11.0, got: %s", tools["node"])
+	}
+	if tools["go"] != "1.22.1" {
+		t.Errorf("Expected go 1.22.1, got: %s", tools["go"])
+	}
+	if tools["java"] != "temurin-21.0.2+13.0.LTS" {
+		t.Errorf("Unexpected java version: %s", tools["java"])
+	}
+}
+
+func TestParse_MissingVersion(t *testing.T) {
+	_, err := Parse([]byte("nodejs\n"))
+	if err == nil {
+		t.Fatal("Expected error for missing version, got nil")
+	}
+	if !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected line number in error, got: %v", err)
+	}
+}
+
+func TestSync_PreservesCommentsAndNames(t *testing.T) {
+	content := `# project pins
+nodejs 20.11.0  # LTS
+python 3.12.1
+golang 1.21.0
+`
+	updated := string(Sync([]byte(content), map[string]string{
+		"node": "20.12.0",
+		"go":   "1.22.1",
+		"java": "21",
+	}))
+
+	expected := `# project pins
+nodejs 20.12.0  # LTS
+golang 1.22.1
+java 21
+`
+	if updated != expected {
+		t.Errorf("Unexpected sync result:\n%s\nexpected:\n%s", updated, expected)
+	}
+}
+
+func TestSync_KeepsFallbackVersions(t *testing.T) {
+	content := `nodejs 20.1.0 18.0.0
+golang  1.21.0   system # fallback to the system go
+python 3.12.1 3.11.0
+`
+	updated := string(Sync([]byte(content), map[string]string{
+		"node":   "20.1.0",
+		"go":     "1.22.1",
+		"python": "3.11.0",
+	}))
+
+	expected := `nodejs 20.1.0 18.0.0
+golang 1.22.1 system # fallback to the system go
+python 3.11.0
+`
+	if updated != expected {
+		t.Errorf("Unexpected sync result:\n%s\nexpected:\n%s", updated, expected)
+	}
+}
+
+func TestSyncFile_CreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+
+	if err := SyncFile(path, map[string]string{"node": "20", "go": "1.22"}); err != nil {
+		t.Fatalf("Failed to sync file: %v", err)
+	}
+
+	entries, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read synced file: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 entries, got: %d", len(entries))
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected file to exist: %v", err)
+	}
+}
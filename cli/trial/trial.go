@@ -0,0 +1,300 @@
+// Package trial runs a devrig version pinned by `devrig upgrade --trial` on probation. The runs of the new
+// binary are recorded in the .devrig folder, and the previous pin of devrig.yaml is restored when all of its first
+// runs fail or crash, so a bad release does not reach the team through the wrapper
+package trial
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/filelock"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// FileName is the name of the file in the .devrig folder with the state of the trial
+const FileName = "trial.json"
+
+// Duration bounds the trial in time, a version that is rarely run is accepted once it passes
+const Duration = 7 * 24 * time.Hour
+
+// State is the content of trial.json
+type State struct {
+	// Version is the devrig version on trial
+	Version string `json:"version"`
+	// Previous is the devrig section of devrig.yaml before the upgrade, it is restored on revert
+	Previous  *configservice.DevrigSection `json:"previous"`
+	StartedAt time.Time                    `json:"started_at"`
+	ExpiresAt time.Time                    `json:"expires_at"`
+	// Runs is the number of runs of the trial
+	Runs      int `json:"runs"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	// Pending are the runs in progress, a run that ends without being recorded has crashed
+	Pending []string `json:"pending,omitempty"`
+	// Failures describe the failed and crashed runs
+	Failures []string `json:"failures,omitempty"`
+}
+
+// Done checks if the trial is over, by the number of its runs or by its time
+func (s *State) Done(now time.Time) bool {
+	return s.Succeeded+s.Failed >= s.Runs || now.After(s.ExpiresAt)
+}
+
+// verdict returns how the trial ends after a recorded run, false while it goes on. The trial is reverted when
+// all of its runs failed or crashed without a single success
+func (s *State) verdict(now time.Time) (Verdict, bool) {
+	switch {
+	case s.Failed >= s.Runs && s.Succeeded == 0:
+		return Reverted, true
+	case s.Done(now):
+		return Passed, true
+	}
+	return Passed, false
+}
+
+// Verdict tells how the trial ended
+type Verdict int
+
+const (
+	// Passed ends the trial, the version stays pinned
+	Passed Verdict = iota
+	// Reverted ends the trial, the previous pin of devrig.yaml is restored
+	Reverted
+	// Abandoned ends the trial without a change, devrig.yaml pins another version by now
+	Abandoned
+)
+
+// Outcome is the end of the trial
+type Outcome struct {
+	Verdict Verdict
+	State   *State
+}
+
+// String describes the outcome for the user
+func (o *Outcome) String() string {
+	switch o.Verdict {
+	case Reverted:
+		reason := fmt.Sprintf("all %d runs failed", o.State.Runs)
+		if len(o.State.Failures) > 0 {
+			reason = o.State.Failures[len(o.State.Failures)-1]
+		}
+		return fmt.Sprintf("devrig %s failed its trial (%s), devrig.yaml is reverted to devrig %s",
+			o.State.Version, reason, o.State.Previous.Version)
+	case Abandoned:
+		return fmt.Sprintf("The trial of devrig %s ended, devrig.yaml pins another version", o.State.Version)
+	default:
+		return fmt.Sprintf("devrig %s passed its trial after %d successful runs", o.State.Version, o.State.Succeeded)
+	}
+}
+
+// Start puts the version on trial for the given number of runs, previous is restored when it fails
+func Start(ctx context.Context, configPath string, version string, previous *configservice.DevrigSection, runs int, now time.Time) error {
+	if runs < 1 {
+		return fmt.Errorf("the trial needs at least 1 run, got %d", runs)
+	}
+	home := layout.ResolveDevrigHome(configPath)
+	lock, err := filelock.Acquire(ctx, lockFile(home), "starting the trial of devrig "+version)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	return write(home, &State{Version: version, Previous: previous, StartedAt: now, ExpiresAt: now.Add(Duration), Runs: runs})
+}
+
+// Load returns the trial of the project, or nil when there is none
+func Load(configPath string) (*State, error) {
+	return read(layout.ResolveDevrigHome(configPath))
+}
+
+// Run is a run of the version on trial, Finish must be called once the command is done
+type Run struct {
+	configPath string
+	id         string
+	lock       *filelock.Lock
+}
+
+// Begin records a run of the running version when it is on trial and returns it, or nil otherwise.
+// The runs that ended without being recorded are counted as failed runs first, a closed terminal or a killed
+// process ends a run the same way. The outcome is set when the trial ends
+func Begin(ctx context.Context, configPath string, version string, now time.Time) (*Run, *Outcome, error) {
+	home := layout.ResolveDevrigHome(configPath)
+	if _, err := os.Stat(filepath.Join(home, FileName)); err != nil {
+		return nil, nil, nil
+	}
+
+	stateLock, err := filelock.Acquire(ctx, lockFile(home), "recording a run of the devrig trial")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer stateLock.Release()
+
+	state, err := read(home)
+	if err != nil || state == nil || state.Version != version {
+		return nil, nil, err
+	}
+
+	// The lock of a run is held as long as its process lives, a free lock is left by a crashed run
+	var pending []string
+	for _, id := range state.Pending {
+		runLock, err := filelock.TryAcquire(runLockFile(home, id), "checking a run of the devrig trial")
+		if err != nil {
+			pending = append(pending, id)
+			continue
+		}
+		_ = runLock.Release()
+		_ = os.Remove(runLockFile(home, id))
+		state.Failed++
+		state.Failures = append(state.Failures, fmt.Sprintf("run %s crashed", id))
+	}
+	state.Pending = pending
+
+	if verdict, done := state.verdict(now); done {
+		outcome, err := conclude(configPath, home, state, verdict)
+		return nil, outcome, err
+	}
+
+	run := &Run{configPath: configPath, id: fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano())}
+	if run.lock, err = filelock.TryAcquire(runLockFile(home, run.id), "running devrig "+version+" on trial"); err != nil {
+		return nil, nil, err
+	}
+	state.Pending = append(state.Pending, run.id)
+	if err := write(home, state); err != nil {
+		_ = run.lock.Release()
+		return nil, nil, err
+	}
+	return run, nil, nil
+}
+
+// Finish records the result of the run, failure is nil for a successful run. The previous pin is restored
+// when all runs of the trial failed, the outcome is set when the trial ends
+func (r *Run) Finish(ctx context.Context, failure error, now time.Time) (*Outcome, error) {
+	if r == nil {
+		return nil, nil
+	}
+	home := layout.ResolveDevrigHome(r.configPath)
+	defer func() {
+		_ = r.lock.Release()
+		_ = os.Remove(runLockFile(home, r.id))
+	}()
+
+	stateLock, err := filelock.Acquire(ctx, lockFile(home), "recording a run of the devrig trial")
+	if err != nil {
+		return nil, err
+	}
+	defer stateLock.Release()
+
+	state, err := read(home)
+	if err != nil || state == nil {
+		return nil, err
+	}
+	state.Pending = removeRun(state.Pending, r.id)
+	if failure == nil {
+		state.Succeeded++
+	} else {
+		state.Failed++
+		state.Failures = append(state.Failures, fmt.Sprintf("run %s failed: %v", r.id, failure))
+	}
+
+	if verdict, done := state.verdict(now); done {
+		return conclude(r.configPath, home, state, verdict)
+	}
+	return nil, write(home, state)
+}
+
+// Cancel drops the run without a result, e.g. when it is interrupted by the user
+func (r *Run) Cancel() {
+	if r == nil {
+		return
+	}
+	home := layout.ResolveDevrigHome(r.configPath)
+	defer func() {
+		_ = r.lock.Release()
+		_ = os.Remove(runLockFile(home, r.id))
+	}()
+
+	stateLock, err := filelock.TryAcquire(lockFile(home), "cancelling a run of the devrig trial")
+	if err != nil {
+		return
+	}
+	defer stateLock.Release()
+	if state, err := read(home); err == nil && state != nil {
+		state.Pending = removeRun(state.Pending, r.id)
+		_ = write(home, state)
+	}
+}
+
+// conclude ends the trial, the revert writes the previous devrig section back to devrig.yaml
+func conclude(configPath string, home string, state *State, verdict Verdict) (*Outcome, error) {
+	if verdict == Reverted {
+		if state.Previous == nil {
+			return nil, fmt.Errorf("the trial of devrig %s has no previous version to restore", state.Version)
+		}
+		// devrig.yaml may pin another version by now, e.g. after a pull, it is not touched then
+		binaries := configservice.NewConfigService(configPath).Binaries()
+		current, err := binaries.ReadDevrigSection()
+		if err != nil {
+			return nil, err
+		}
+		if current.Version != state.Version {
+			verdict = Abandoned
+		} else if err := binaries.UpdateBinaries(state.Previous); err != nil {
+			return nil, fmt.Errorf("failed to restore devrig %s in devrig.yaml: %w", state.Previous.Version, err)
+		}
+	}
+	outcome := &Outcome{Verdict: verdict, State: state}
+	if err := os.Remove(filepath.Join(home, FileName)); err != nil && !os.IsNotExist(err) {
+		return outcome, fmt.Errorf("failed to remove %s: %w", FileName, err)
+	}
+	return outcome, nil
+}
+
+func removeRun(runs []string, id string) []string {
+	var rest []string
+	for _, run := range runs {
+		if run != id {
+			rest = append(rest, run)
+		}
+	}
+	return rest
+}
+
+func read(home string) (*State, error) {
+	data, err := os.ReadFile(filepath.Join(home, FileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", FileName, err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", FileName, err)
+	}
+	return &state, nil
+}
+
+func write(home string, state *State) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", FileName, err)
+	}
+	if err := os.MkdirAll(home, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", home, err)
+	}
+	return tempfile.WriteFile(filepath.Join(home, FileName), data, 0644)
+}
+
+func lockFile(home string) string {
+	return layout.ResolveLockFile(home, FileName)
+}
+
+func runLockFile(home string, id string) string {
+	return layout.ResolveLockFile(home, "trial-run-"+id)
+}
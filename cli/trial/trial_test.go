@@ -0,0 +1,206 @@
+package trial
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+)
+
+const testConfig = `devrig:
+  version: 0.79.6
+  binaries:
+    linux-x86_64:
+      url: https://example.com/v0.79.6/devrig-linux-x86_64
+      sha512: 22222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222
+`
+
+var previous = &configservice.DevrigSection{
+	Version: "0.79.5",
+	Binaries: map[string]configservice.BinaryInfo{
+		"linux-x86_64": {URL: "https://example.com/v0.79.5/devrig-linux-x86_64", SHA512: strings.Repeat("1", 128)},
+	},
+}
+
+// startTrial writes devrig.yaml pinning 0.79.6 and puts it on trial
+func startTrial(t *testing.T, runs int, now time.Time) string {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte(testConfig), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	if err := Start(context.Background(), configPath, "0.79.6", previous, runs, now); err != nil {
+		t.Fatalf("Failed to start the trial: %v", err)
+	}
+	return configPath
+}
+
+func pinnedVersion(t *testing.T, configPath string) string {
+	t.Helper()
+	section, err := configservice.NewConfigService(configPath).Binaries().ReadDevrigSection()
+	if err != nil {
+		t.Fatalf("Failed to read devrig.yaml: %v", err)
+	}
+	return section.Version
+}
+
+func begin(t *testing.T, configPath string, now time.Time) *Run {
+	t.Helper()
+	run, outcome, err := Begin(context.Background(), configPath, "0.79.6", now)
+	if err != nil || outcome != nil || run == nil {
+		t.Fatalf("Expected the run to be recorded, got %v, %v, %v", run, outcome, err)
+	}
+	return run
+}
+
+func TestTrial_RevertsWhenAllRunsFail(t *testing.T) {
+	now := time.Now()
+	configPath := startTrial(t, 2, now)
+
+	if outcome, err := begin(t, configPath, now).Finish(context.Background(), errors.New("exit 1"), now); err != nil || outcome != nil {
+		t.Fatalf("Expected the trial to continue, got %v, %v", outcome, err)
+	}
+	outcome, err := begin(t, configPath, now).Finish(context.Background(), errors.New("exit 2"), now)
+	if err != nil || outcome == nil || outcome.Verdict != Reverted {
+		t.Fatalf("Expected the trial to be reverted, got %v, %v", outcome, err)
+	}
+	if !strings.Contains(outcome.String(), "exit 2") || !strings.Contains(outcome.String(), "0.79.5") {
+		t.Errorf("Expected the failure and the restored version in %q", outcome.String())
+	}
+	if version := pinnedVersion(t, configPath); version != "0.79.5" {
+		t.Errorf("Expected devrig.yaml to pin 0.79.5, got %s", version)
+	}
+	if state, err := Load(configPath); err != nil || state != nil {
+		t.Errorf("Expected the trial to be removed, got %v, %v", state, err)
+	}
+}
+
+func TestTrial_PassesWithSuccessfulRun(t *testing.T) {
+	now := time.Now()
+	configPath := startTrial(t, 2, now)
+
+	if _, err := begin(t, configPath, now).Finish(context.Background(), errors.New("exit 1"), now); err != nil {
+		t.Fatalf("Failed to finish the run: %v", err)
+	}
+	outcome, err := begin(t, configPath, now).Finish(context.Background(), nil, now)
+	if err != nil || outcome == nil || outcome.Verdict != Passed {
+		t.Fatalf("Expected the trial to pass, got %v, %v", outcome, err)
+	}
+	if version := pinnedVersion(t, configPath); version != "0.79.6" {
+		t.Errorf("Expected devrig.yaml to keep 0.79.6, got %s", version)
+	}
+}
+
+func TestTrial_PassesAfterDuration(t *testing.T) {
+	now := time.Now()
+	configPath := startTrial(t, 5, now)
+
+	run, outcome, err := Begin(context.Background(), configPath, "0.79.6", now.Add(Duration+time.Hour))
+	if err != nil || run != nil || outcome == nil || outcome.Verdict != Passed {
+		t.Fatalf("Expected the expired trial to pass, got %v, %v, %v", run, outcome, err)
+	}
+}
+
+// crash ends the run without Finish, like a process that crashed, and its lock is released by the OS
+func crash(t *testing.T, run *Run) {
+	t.Helper()
+	if err := run.lock.Release(); err != nil {
+		t.Fatalf("Failed to release the run lock: %v", err)
+	}
+}
+
+func TestTrial_RevertsAfterCrashes(t *testing.T) {
+	now := time.Now()
+	configPath := startTrial(t, 2, now)
+
+	// A single crash, e.g. a closed terminal, counts as a failed run
+	crash(t, begin(t, configPath, now))
+	run := begin(t, configPath, now)
+	if state, err := Load(configPath); err != nil || state.Failed != 1 || len(state.Pending) != 1 {
+		t.Fatalf("Expected the crash to count as a failed run, got %+v, %v", state, err)
+	}
+	crash(t, run)
+
+	_, outcome, err := Begin(context.Background(), configPath, "0.79.6", now)
+	if err != nil || outcome == nil || outcome.Verdict != Reverted {
+		t.Fatalf("Expected the crashes to revert the trial, got %v, %v", outcome, err)
+	}
+	if !strings.Contains(outcome.String(), "crashed") {
+		t.Errorf("Expected the crash in %q", outcome.String())
+	}
+	if version := pinnedVersion(t, configPath); version != "0.79.5" {
+		t.Errorf("Expected devrig.yaml to pin 0.79.5, got %s", version)
+	}
+}
+
+func TestTrial_CrashAfterSuccess(t *testing.T) {
+	now := time.Now()
+	configPath := startTrial(t, 2, now)
+
+	if _, err := begin(t, configPath, now).Finish(context.Background(), nil, now); err != nil {
+		t.Fatalf("Failed to finish the run: %v", err)
+	}
+	crash(t, begin(t, configPath, now))
+
+	_, outcome, err := Begin(context.Background(), configPath, "0.79.6", now)
+	if err != nil || outcome == nil || outcome.Verdict != Passed {
+		t.Fatalf("Expected the trial with a successful run to pass, got %v, %v", outcome, err)
+	}
+	if version := pinnedVersion(t, configPath); version != "0.79.6" {
+		t.Errorf("Expected devrig.yaml to keep 0.79.6, got %s", version)
+	}
+}
+
+func TestTrial_KeepsConcurrentRuns(t *testing.T) {
+	now := time.Now()
+	configPath := startTrial(t, 5, now)
+
+	first := begin(t, configPath, now)
+	defer first.Cancel()
+	second := begin(t, configPath, now)
+	second.Cancel()
+
+	state, err := Load(configPath)
+	if err != nil || state == nil || len(state.Pending) != 1 || state.Pending[0] != first.id {
+		t.Errorf("Expected only the running run to be pending, got %+v, %v", state, err)
+	}
+}
+
+func TestTrial_AbandonedWhenVersionChanged(t *testing.T) {
+	now := time.Now()
+	configPath := startTrial(t, 1, now)
+	run := begin(t, configPath, now)
+
+	// Another version is pinned meanwhile, e.g. after a pull
+	other := *previous
+	other.Version = "0.80.0"
+	if err := configservice.NewConfigService(configPath).Binaries().UpdateBinaries(&other); err != nil {
+		t.Fatalf("Failed to update devrig.yaml: %v", err)
+	}
+
+	outcome, err := run.Finish(context.Background(), errors.New("exit 1"), now)
+	if err != nil || outcome == nil || outcome.Verdict != Abandoned {
+		t.Fatalf("Expected the trial to be abandoned, got %v, %v", outcome, err)
+	}
+	if version := pinnedVersion(t, configPath); version != "0.80.0" {
+		t.Errorf("Expected devrig.yaml to keep 0.80.0, got %s", version)
+	}
+}
+
+func TestBegin_NoTrial(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	run, outcome, err := Begin(context.Background(), configPath, "0.79.6", time.Now())
+	if err != nil || run != nil || outcome != nil {
+		t.Errorf("Expected no run without a trial, got %v, %v, %v", run, outcome, err)
+	}
+	// Finish and Cancel of a missing run do nothing
+	if _, err := run.Finish(context.Background(), nil, time.Now()); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	run.Cancel()
+}
@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/apply"
+	"jonnyzzz.com/devrig.dev/doctor"
+	"jonnyzzz.com/devrig.dev/summary"
+	"jonnyzzz.com/devrig.dev/upgrade"
+)
+
+// DoctorAction runs the checks of devrig doctor
+func DoctorAction(configPath func() string, checks []doctor.Check) Action {
+	return Action{Key: "d", Title: "Doctor", Run: func(cmd *cobra.Command) error {
+		env := doctor.NewEnvironment(configPath())
+		doctor.RunChecks(cmd.Context(), env, doctor.WithCustomChecks(env, checks), cmd.OutOrStdout())
+		return nil
+	}}
+}
+
+// ApplyAction provisions the environment like devrig apply, the journal skips the completed steps
+func ApplyAction(configPath func() string, steps []apply.Step) Action {
+	return Action{Key: "a", Title: "Apply", Run: func(cmd *cobra.Command) error {
+		var s summary.Summary
+		err := apply.Provision(cmd.Context(), apply.NewEnvironment(configPath()), steps, apply.Options{}, &s)
+		s.Print(cmd.Context(), cmd.OutOrStdout())
+		return err
+	}}
+}
+
+// UpgradeAction pins the latest devrig release like devrig upgrade, the changes are confirmed first
+func UpgradeAction(configPath func() string, fetcher upgrade.ReleaseFetcher) Action {
+	return Action{Key: "u", Title: "Upgrade", Run: func(cmd *cobra.Command) error {
+		return upgrade.UpgradeConfirmed(cmd, configPath(), fetcher)
+	}}
+}
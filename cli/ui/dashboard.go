@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/clock"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/progress"
+)
+
+// ToolStatus describes a tool pinned in devrig.yaml
+type ToolStatus struct {
+	Name      string
+	Version   string
+	Installed bool
+}
+
+// Snapshot is the state shown on the dashboard
+type Snapshot struct {
+	ConfigPath  string
+	ConfigError error
+	DevrigHome  string
+	CacheBytes  int64
+	Tools       []ToolStatus
+	Update      string
+	// Clock describes a significant skew of the system clock seen in the responses of the servers, empty if there is none
+	Clock string
+}
+
+// Tasks returns the pending provisioning steps for the snapshot
+func (s *Snapshot) Tasks() []string {
+	var tasks []string
+	if s.ConfigError != nil {
+		tasks = append(tasks, "fix devrig.yaml (see 'devrig doctor')")
+	}
+	if s.Clock != "" {
+		tasks = append(tasks, "fix the date and time of the machine (see 'devrig doctor')")
+	}
+	for _, tool := range s.Tools {
+		if !tool.Installed {
+			tasks = append(tasks, fmt.Sprintf("install %s %s", tool.Name, tool.Version))
+		}
+	}
+	return tasks
+}
+
+// CollectSnapshot reads the provisioning status and cache usage for the given devrig.yaml
+func CollectSnapshot(configPath string, update string) *Snapshot {
+	devrigHome := layout.ResolveDevrigHome(configPath)
+	snapshot := &Snapshot{
+		ConfigPath: configPath,
+		DevrigHome: devrigHome,
+		CacheBytes: directorySize(devrigHome),
+		Update:     update,
+	}
+	if observation, ok := clock.Observed(); ok && observation.Significant() {
+		snapshot.Clock = observation.String()
+	}
+
+	configs := configservice.NewConfigService(configPath)
+	snapshot.ConfigError = configs.EnsureValidConfig()
+
+	tools, err := configs.Tools().ReadTools()
+	if err == nil {
+		for name, version := range tools {
+			_, statErr := os.Stat(layout.ResolveToolHome(devrigHome, name, version))
+			snapshot.Tools = append(snapshot.Tools, ToolStatus{
+				Name:      name,
+				Version:   version,
+				Installed: statErr == nil,
+			})
+		}
+		sort.Slice(snapshot.Tools, func(i, j int) bool {
+			return snapshot.Tools[i].Name < snapshot.Tools[j].Name
+		})
+	}
+
+	return snapshot
+}
+
+// Render prints the dashboard
+func Render(out io.Writer, snapshot *Snapshot) {
+	_, _ = fmt.Fprintln(out, "devrig dashboard")
+	_, _ = fmt.Fprintln(out, strings.Repeat("=", 60))
+
+	_, _ = fmt.Fprintf(out, "Config:  %s\n", snapshot.ConfigPath)
+	if snapshot.ConfigError != nil {
+		firstLine, _, _ := strings.Cut(snapshot.ConfigError.Error(), "\n")
+		_, _ = fmt.Fprintf(out, "Status:  problem: %s\n", firstLine)
+	} else {
+		_, _ = fmt.Fprintln(out, "Status:  ok")
+	}
+	_, _ = fmt.Fprintf(out, "Cache:   %s in %s\n", progress.FormatBytes(snapshot.CacheBytes), snapshot.DevrigHome)
+	_, _ = fmt.Fprintf(out, "Updates: %s\n", snapshot.Update)
+	if snapshot.Clock != "" {
+		_, _ = fmt.Fprintf(out, "Clock:   %s\n", snapshot.Clock)
+	}
+
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, "Tools:")
+	if len(snapshot.Tools) == 0 {
+		_, _ = fmt.Fprintln(out, "  no tools are pinned in devrig.yaml")
+	}
+	for _, tool := range snapshot.Tools {
+		state := "missing"
+		if tool.Installed {
+			state = "installed"
+		}
+		_, _ = fmt.Fprintf(out, "  %-20s %-15s %s\n", tool.Name, tool.Version, state)
+	}
+
+	_, _ = fmt.Fprintln(out)
+	_, _ = fmt.Fprintln(out, "Tasks:")
+	tasks := snapshot.Tasks()
+	if len(tasks) == 0 {
+		_, _ = fmt.Fprintln(out, "  nothing to do")
+	}
+	for _, task := range tasks {
+		_, _ = fmt.Fprintf(out, "  - %s\n", task)
+	}
+}
+
+// directorySize returns the total size of files under dir, or 0 if it does not exist
+func directorySize(dir string) int64 {
+	var total int64
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCollectSnapshot(t *testing.T) {
+	projectDir := t.TempDir()
+	configPath := filepath.Join(projectDir, "devrig.yaml")
+	config := "tools:\n  go: 1.22.0\n  node: 20.11.0\n"
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+
+	goHome := filepath.Join(projectDir, ".devrig", "tools", "go-1.22.0")
+	if err := os.MkdirAll(goHome, 0755); err != nil {
+		t.Fatalf("Failed to create tool home: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(goHome, "go"), make([]byte, 2048), 0755); err != nil {
+		t.Fatalf("Failed to write tool binary: %v", err)
+	}
+
+	snapshot := CollectSnapshot(configPath, "up to date")
+
+	if len(snapshot.Tools) != 2 {
+		t.Fatalf("Expected 2 tools, got %d", len(snapshot.Tools))
+	}
+	if !snapshot.Tools[0].Installed || snapshot.Tools[1].Installed {
+		t.Errorf("Expected only go to be installed, got: %+v", snapshot.Tools)
+	}
+	if snapshot.CacheBytes != 2048 {
+		t.Errorf("Expected cache size 2048, got %d", snapshot.CacheBytes)
+	}
+
+	var out bytes.Buffer
+	Render(&out, snapshot)
+	text := out.String()
+	for _, expected := range []string{"2.0 KiB", "up to date", "install node 20.11.0", "fix devrig.yaml"} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in the dashboard:\n%s", expected, text)
+		}
+	}
+}
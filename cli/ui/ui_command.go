@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/updates"
+	"jonnyzzz.com/devrig.dev/version"
+)
+
+// Action is a keyboard action available on the dashboard
+type Action struct {
+	Key   string
+	Title string
+	Run   func(cmd *cobra.Command) error
+}
+
+// NewUiCommand creates the ui command that shows an interactive dashboard
+func NewUiCommand(configPath func() string, updatesService updates.UpdateService, actions []Action) *cobra.Command {
+	return &cobra.Command{
+		Use:   "ui",
+		Short: "Show an interactive dashboard of the project environment",
+		Long: `Show an interactive terminal dashboard with the provisioning status,
+cache usage, available updates and pending tasks.
+
+Type the key of an action and press Enter to run it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			update := newUpdateStatus(updatesService)
+			input := bufio.NewReader(cmd.InOrStdin())
+			out := cmd.OutOrStdout()
+
+			for {
+				clearScreen(cmd)
+				Render(out, CollectSnapshot(configPath(), update.String()))
+
+				_, _ = fmt.Fprintln(out)
+				for _, action := range actions {
+					_, _ = fmt.Fprintf(out, "[%s] %s  ", action.Key, action.Title)
+				}
+				_, _ = fmt.Fprint(out, "[r] Refresh  [q] Quit\n> ")
+
+				line, err := input.ReadString('\n')
+				key := strings.ToLower(strings.TrimSpace(line))
+				if key == "q" || (err != nil && key == "") {
+					return nil
+				}
+				if key == "r" || key == "" {
+					continue
+				}
+
+				action := findAction(actions, key)
+				if action == nil {
+					_, _ = fmt.Fprintf(out, "Unknown action: %s\n", key)
+					waitForEnter(cmd, input)
+					continue
+				}
+
+				// The questions of the action read the same input as the dashboard
+				cmd.SetIn(input)
+				if err := action.Run(cmd); err != nil {
+					cmd.PrintErrf("%s failed: %v\n", action.Title, err)
+				}
+				waitForEnter(cmd, input)
+			}
+		},
+	}
+}
+
+func findAction(actions []Action, key string) *Action {
+	for i := range actions {
+		if strings.ToLower(actions[i].Key) == key {
+			return &actions[i]
+		}
+	}
+	return nil
+}
+
+func waitForEnter(cmd *cobra.Command, input *bufio.Reader) {
+	_, _ = fmt.Fprint(cmd.OutOrStdout(), "Press Enter to continue...")
+	_, _ = input.ReadString('\n')
+}
+
+// clearScreen clears the terminal, it does nothing if the output is redirected
+func clearScreen(cmd *cobra.Command) {
+	out, ok := cmd.OutOrStdout().(*os.File)
+	if !ok {
+		return
+	}
+	info, err := out.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return
+	}
+	_, _ = fmt.Fprint(out, "\033[H\033[2J")
+}
+
+// updateStatus checks for updates in the background, so the dashboard is shown immediately
+type updateStatus struct {
+	mutex  sync.Mutex
+	status string
+}
+
+func newUpdateStatus(updatesService updates.UpdateService) *updateStatus {
+	u := &updateStatus{status: "checking..."}
+	go func() {
+		comparison, err := updatesService.CompareWithLatest()
+		u.mutex.Lock()
+		defer u.mutex.Unlock()
+		switch {
+		case err != nil:
+			u.status = fmt.Sprintf("failed to check: %v", err)
+		case comparison.Order == version.Older:
+			u.status = fmt.Sprintf("devrig %s is available, run 'devrig upgrade'", comparison.Latest)
+		default:
+			u.status = fmt.Sprintf("up to date, latest devrig is %s", comparison.Latest)
+		}
+	}()
+	return u
+}
+
+func (u *updateStatus) String() string {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.status
+}
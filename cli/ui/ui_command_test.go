@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/apply"
+	"jonnyzzz.com/devrig.dev/updates"
+	"jonnyzzz.com/devrig.dev/version"
+)
+
+const testConfig = `devrig:
+  version: 0.79.5
+  binaries:
+    linux-x86_64:
+      url: https://example.com/v0.79.5/devrig-linux-x86_64
+      sha512: 11111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111
+`
+
+// fakeUpdates reports the running devrig as the latest release
+type fakeUpdates struct{}
+
+func (fakeUpdates) LastUpdateInfo() (*updates.UpdateInfo, error) { return &updates.UpdateInfo{}, nil }
+func (fakeUpdates) IsUpdateAvailable() (bool, error)             { return false, nil }
+func (fakeUpdates) CompareWithLatest() (*updates.Comparison, error) {
+	return &updates.Comparison{Latest: "0.79.5", Order: version.Equal}, nil
+}
+
+// fakeFetcher returns the release 0.79.6 for every URL
+type fakeFetcher struct{}
+
+func (fakeFetcher) FetchUpdateInfo(string) (*updates.UpdateInfo, error) {
+	return &updates.UpdateInfo{Version: "0.79.6", Binaries: []updates.BinaryInfo{
+		{OS: "linux", Arch: "x86_64", URL: "https://example.com/v0.79.6/devrig-linux-x86_64", SHA512: strings.Repeat("2", 128)},
+	}}, nil
+}
+
+// recordingStep counts its runs
+type recordingStep struct {
+	runs int
+}
+
+func (s *recordingStep) ID() string                                     { return "recording" }
+func (s *recordingStep) Name() string                                   { return "Recording step" }
+func (s *recordingStep) Key(apply.Environment) (string, error)          { return "", nil }
+func (s *recordingStep) Verify(context.Context, apply.Environment) bool { return false }
+func (s *recordingStep) Run(context.Context, apply.Environment) error   { s.runs++; return nil }
+
+func runDashboard(t *testing.T, configPath string, step *recordingStep, input string) string {
+	t.Helper()
+	actions := []Action{
+		ApplyAction(func() string { return configPath }, []apply.Step{step}),
+		UpgradeAction(func() string { return configPath }, fakeFetcher{}),
+	}
+	cmd := NewUiCommand(func() string { return configPath }, fakeUpdates{}, actions)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetIn(strings.NewReader(input))
+	cmd.SetArgs([]string{})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Failed to run the dashboard: %v", err)
+	}
+	return out.String()
+}
+
+func TestUiCommand_Actions(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte(testConfig), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	step := &recordingStep{}
+
+	// The upgrade is declined first, then confirmed
+	output := runDashboard(t, configPath, step, "a\n\nu\nn\n\nu\ny\n\nq\n")
+	if step.runs != 1 {
+		t.Errorf("Expected the apply action to run the steps once, got %d", step.runs)
+	}
+	for _, expected := range []string{"[a] Apply", "[u] Upgrade", "version: 0.79.5 -> 0.79.6", "Aborted", "Upgraded devrig.yaml to devrig 0.79.6"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in the output:\n%s", expected, output)
+		}
+	}
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read devrig.yaml: %v", err)
+	}
+	if !strings.Contains(string(content), "version: 0.79.6") {
+		t.Errorf("Expected the confirmed upgrade in devrig.yaml:\n%s", content)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package unpack
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// installerCommand is only supported on Windows, the exe installer does not run anywhere else
+func installerCommand(ctx context.Context, installer string, args ...string) (*exec.Cmd, error) {
+	return nil, &devrigErrors.UnsupportedPlatformError{OS: runtime.GOOS}
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package unpack
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// installerCommand runs the installer with the arguments passed as is, Go would quote /D=C:\Path With Spaces
+// and the NSIS installer only accepts the path unquoted
+func installerCommand(ctx context.Context, installer string, args ...string) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, installer)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CmdLine: syscall.EscapeArg(installer) + " " + strings.Join(args, " "),
+	}
+	return cmd, nil
+}
@@ -0,0 +1,174 @@
+package unpack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/contentstore"
+	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// manifestSampleSize is the number of files whose content is hashed by the sampled verification,
+// the size of every file is checked anyway
+const manifestSampleSize = 64
+
+// Manifest is the fingerprint of an unpacked IDE, it is written right after unpacking
+type Manifest struct {
+	CreatedAt time.Time      `json:"created_at"`
+	Files     []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a file or a symbolic link of the IDE
+type ManifestFile struct {
+	// Path is relative to the IDE home and slash-separated
+	Path   string `json:"path"`
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+	// Link is the target of a symbolic link
+	Link string `json:"link,omitempty"`
+}
+
+type fullVerificationKey struct{}
+
+// WithFullVerification makes UnpackIde hash every file of an unpacked IDE before reusing it, not only a sample
+func WithFullVerification(ctx context.Context) context.Context {
+	return context.WithValue(ctx, fullVerificationKey{}, true)
+}
+
+// FullVerificationFromContext checks if the context asks to hash every file, see WithFullVerification
+func FullVerificationFromContext(ctx context.Context) bool {
+	full, _ := ctx.Value(fullVerificationKey{}).(bool)
+	return full
+}
+
+// ComputeManifest lists the files and links of the IDE with their SHA-256. The unpack marker is skipped,
+// it is rewritten when the IDE is unpacked again
+func ComputeManifest(home string) (*Manifest, error) {
+	manifest := &Manifest{CreatedAt: time.Now().UTC()}
+	err := filepath.WalkDir(home, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(home, path)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || rel == markerFileName {
+			return nil
+		}
+
+		file := ManifestFile{Path: filepath.ToSlash(rel)}
+		if entry.Type()&fs.ModeSymlink != 0 {
+			if file.Link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		} else {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			file.Size = info.Size()
+			if file.SHA256, err = contentstore.FileSHA256(path); err != nil {
+				return err
+			}
+		}
+		manifest.Files = append(manifest.Files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fingerprint %s: %w", home, err)
+	}
+	return manifest, nil
+}
+
+// WriteManifest fingerprints the IDE and stores the manifest next to it, see layout.ResolveIdeManifest
+func WriteManifest(home string) error {
+	manifest, err := ComputeManifest(home)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to encode the manifest of %s: %w", home, err)
+	}
+	if err := tempfile.WriteFile(layout.ResolveIdeManifest(home), data, 0644); err != nil {
+		return fmt.Errorf("failed to write the manifest of %s: %w", home, err)
+	}
+	return nil
+}
+
+// ReadManifest reads the manifest of the IDE, it returns an error satisfying os.IsNotExist when there is none
+func ReadManifest(home string) (*Manifest, error) {
+	data, err := os.ReadFile(layout.ResolveIdeManifest(home))
+	if err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse the manifest of %s: %w", home, err)
+	}
+	return &manifest, nil
+}
+
+// VerifyManifest checks the IDE against its manifest. Every file must exist with the recorded size and every
+// link with the recorded target. The content is hashed for all files when full is set and for a random sample
+// otherwise. Files that are not in the manifest, e.g. installed plugins, are ignored
+func VerifyManifest(home string, manifest *Manifest, full bool) error {
+	var sample []ManifestFile
+	for _, file := range manifest.Files {
+		path := filepath.Join(home, filepath.FromSlash(file.Path))
+		if file.Link != "" {
+			if target, err := os.Readlink(path); err != nil || target != file.Link {
+				return fmt.Errorf("link %s was changed", file.Path)
+			}
+			continue
+		}
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			return fmt.Errorf("file %s is missing", file.Path)
+		}
+		if info.Size() != file.Size {
+			return fmt.Errorf("file %s has %d bytes, expected %d", file.Path, info.Size(), file.Size)
+		}
+		sample = append(sample, file)
+	}
+
+	if !full && len(sample) > manifestSampleSize {
+		rand.Shuffle(len(sample), func(i, j int) { sample[i], sample[j] = sample[j], sample[i] })
+		sample = sample[:manifestSampleSize]
+	}
+	for _, file := range sample {
+		sum, err := contentstore.FileSHA256(filepath.Join(home, filepath.FromSlash(file.Path)))
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(sum, file.SHA256) {
+			return fmt.Errorf("file %s was modified", file.Path)
+		}
+	}
+	return nil
+}
+
+// VerifyUnpacked checks the unpacked IDE against its manifest, the full check is selected by
+// WithFullVerification. An IDE without a manifest, e.g. unpacked by an older devrig, is not checked
+func VerifyUnpacked(ctx context.Context, home string) error {
+	manifest, err := ReadManifest(home)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := VerifyManifest(home, manifest, FullVerificationFromContext(ctx)); err != nil {
+		return fmt.Errorf("%s is corrupted or was tampered with: %w", filepath.Base(home), err)
+	}
+	return nil
+}
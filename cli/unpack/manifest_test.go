@@ -0,0 +1,102 @@
+package unpack
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+func writeTestIde(t *testing.T) string {
+	t.Helper()
+	home := filepath.Join(t.TempDir(), "GoLand-243.1")
+	for name, content := range map[string]string{
+		"bin/goland.sh":     "#!/bin/sh\n",
+		"lib/app.jar":       "jar content",
+		"product-info.json": "{}",
+		markerFileName:      "{}",
+	} {
+		path := filepath.Join(home, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	return home
+}
+
+func TestManifest_Verify(t *testing.T) {
+	home := writeTestIde(t)
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink("bin/goland.sh", filepath.Join(home, "goland")); err != nil {
+			t.Fatalf("Failed to create link: %v", err)
+		}
+	}
+	if err := WriteManifest(home); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	manifest, err := ReadManifest(home)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	for _, file := range manifest.Files {
+		if file.Path == markerFileName {
+			t.Errorf("Expected the unpack marker to be skipped")
+		}
+	}
+	if err := VerifyManifest(home, manifest, true); err != nil {
+		t.Fatalf("Expected the IDE to pass verification: %v", err)
+	}
+
+	// Files that are not in the manifest, e.g. plugins, are ignored
+	if err := os.WriteFile(filepath.Join(home, "lib", "plugin.jar"), []byte("plugin"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := VerifyManifest(home, manifest, false); err != nil {
+		t.Errorf("Expected new files to be ignored: %v", err)
+	}
+
+	// Same size, other content
+	if err := os.WriteFile(filepath.Join(home, "lib", "app.jar"), []byte("jar CONTENT"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := VerifyManifest(home, manifest, true); err == nil || !strings.Contains(err.Error(), "lib/app.jar") {
+		t.Errorf("Expected the modified file to be reported, got: %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(home, "product-info.json")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if err := VerifyManifest(home, manifest, false); err == nil || !strings.Contains(err.Error(), "product-info.json is missing") {
+		t.Errorf("Expected the missing file to be reported, got: %v", err)
+	}
+}
+
+func TestVerifyUnpacked(t *testing.T) {
+	home := writeTestIde(t)
+
+	// An IDE unpacked before manifests existed is not checked
+	if err := VerifyUnpacked(context.Background(), home); err != nil {
+		t.Errorf("Expected no verification without a manifest: %v", err)
+	}
+
+	if err := WriteManifest(home); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	if _, err := os.Stat(layout.ResolveIdeManifest(home)); err != nil {
+		t.Fatalf("Expected the manifest next to the IDE: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "bin", "goland.sh"), []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	err := VerifyUnpacked(WithFullVerification(context.Background()), home)
+	if err == nil || !strings.Contains(err.Error(), "tampered") {
+		t.Errorf("Expected the tampered IDE to be reported, got: %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build darwin
+
+package unpack
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// quarantineAttribute is set by macOS on downloaded files, Gatekeeper asks to confirm every binary that has it
+const quarantineAttribute = "com.apple.quarantine"
+
+// removeQuarantine removes the quarantine attribute from every file of the application, links are not followed
+func removeQuarantine(dir string) error {
+	return filepath.WalkDir(dir, func(path string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := unix.Lremovexattr(path, quarantineAttribute); err != nil && !errors.Is(err, unix.ENOATTR) {
+			return &os.PathError{Op: "removexattr " + quarantineAttribute, Path: path, Err: err}
+		}
+		return nil
+	})
+}
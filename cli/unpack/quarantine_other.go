@@ -0,0 +1,8 @@
+//go:build !darwin
+
+package unpack
+
+// removeQuarantine does nothing, only macOS marks downloaded files with the quarantine attribute
+func removeQuarantine(dir string) error {
+	return nil
+}
@@ -0,0 +1,56 @@
+package unpack
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/logging"
+)
+
+const (
+	// slowUnpackBytesPerSecond is the throughput below which the unpack is considered slow.
+	// Real-time antivirus scanning on Windows typically drops it by an order of magnitude.
+	slowUnpackBytesPerSecond = 10 * 1024 * 1024
+	// slowUnpackMinDuration avoids warnings for small packages, where the rate is noisy
+	slowUnpackMinDuration = 20 * time.Second
+)
+
+// isSlowUnpack checks if unpacking of the given amount of bytes took unusually long
+func isSlowUnpack(bytes int64, elapsed time.Duration) bool {
+	if elapsed < slowUnpackMinDuration {
+		return false
+	}
+	return float64(bytes)/elapsed.Seconds() < slowUnpackBytesPerSecond
+}
+
+// reportUnpackThroughput warns on Windows when unpacking was slow, which usually means
+// that every file was scanned by Windows Defender or another antivirus
+func reportUnpackThroughput(ctx context.Context, targetDir string, cacheDir string, elapsed time.Duration) {
+	if runtime.GOOS != "windows" {
+		return
+	}
+
+	var size int64
+	_ = filepath.WalkDir(targetDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+
+	logger := logging.FromContext(ctx)
+	logger.Debug("unpack throughput", "bytes", size, "elapsed", elapsed.String())
+	if !isSlowUnpack(size, elapsed) {
+		return
+	}
+
+	logger.Warn(fmt.Sprintf("Unpacking %s took %s, real-time antivirus scanning is the likely cause.", targetDir, elapsed.Round(time.Second)))
+	logger.Warn(fmt.Sprintf("Consider excluding the devrig cache from scanning: Add-MpPreference -ExclusionPath \"%s\"", cacheDir))
+	logger.Warn("Run 'devrig doctor' for details")
+}
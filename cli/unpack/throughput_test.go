@@ -0,0 +1,20 @@
+package unpack
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsSlowUnpack(t *testing.T) {
+	const mib = 1024 * 1024
+
+	if isSlowUnpack(10*mib, 5*time.Second) {
+		t.Error("Short unpacks must not be reported")
+	}
+	if !isSlowUnpack(500*mib, 60*time.Second) {
+		t.Error("Expected 500 MiB in 60s to be slow")
+	}
+	if isSlowUnpack(1500*mib, 30*time.Second) {
+		t.Error("Expected 1500 MiB in 30s to be fast enough")
+	}
+}
@@ -1,36 +1,89 @@
 package unpack
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/diskspace"
 	"jonnyzzz.com/devrig.dev/feed_api"
+	"jonnyzzz.com/devrig.dev/filelock"
 	"jonnyzzz.com/devrig.dev/layout"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/progress"
 	"jonnyzzz.com/devrig.dev/unpack_api"
 )
 
-func UnpackIde(localConfig config.Config, request feed_api.DownloadedRemoteIde) (unpack_api.UnpackedDownloadedRemoteIde, error) {
+func UnpackIde(ctx context.Context, localConfig config.Config, request feed_api.DownloadedRemoteIde) (unpack_api.UnpackedDownloadedRemoteIde, error) {
+	logger := logging.FromContext(ctx)
 	targetDir := layout.ResolveLocalHome(localConfig, request.RemoteIde())
-	fmt.Println("Unpacking ", request.TargetFile(), " to ", targetDir, "...")
+	logger.Info(fmt.Sprintf("Unpacking %s to %s...", request.TargetFile(), targetDir))
 
-	if request.RemoteIde().PackageType() == "dmg" {
-		if !strings.HasSuffix(targetDir, ".app") {
-			log.Fatalln("Target directory must end with .app: ", targetDir)
+	// Refuse to merge into a folder that differs only by case, it is the same folder on macOS and Windows
+	if err := layout.CheckNoCaseCollision(targetDir); err != nil {
+		return nil, err
+	}
+
+	// A concurrent unpack into the same folder would corrupt it
+	lock, err := filelock.Acquire(ctx, layout.ResolveLockFile(localConfig.CacheDir(), filepath.Base(targetDir)), "unpacking "+request.TargetFile())
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	// A corrupted or tampered IDE is unpacked again from the package
+	if err := VerifyUnpacked(ctx, targetDir); err != nil {
+		logger.Warn("Unpacking the IDE again", "path", targetDir, "reason", err)
+		if err := os.RemoveAll(targetDir); err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %w", targetDir, err)
 		}
+	}
 
-		targetApp, err := unpackDmg(localConfig, request, targetDir)
-		if err != nil {
+	var unpacked unpack_api.UnpackedDownloadedRemoteIde
+	var fresh bool
+	start := time.Now()
+	stopHeartbeat := progress.Heartbeat(ctx, "Unpacking "+request.TargetFile())
+	switch packageType := request.RemoteIde().PackageType(); packageType {
+	case "dmg":
+		if !strings.HasSuffix(targetDir, ".app") {
+			stopHeartbeat()
+			return nil, fmt.Errorf("target directory must end with .app: %s", targetDir)
+		}
+		unpacked, fresh, err = unpackDmg(ctx, localConfig, request, targetDir)
+	case "targz", "zip":
+		unpacked, fresh, err = unpackArchive(ctx, localConfig, request, targetDir)
+	case "exe":
+		unpacked, fresh, err = unpackExe(ctx, localConfig, request, targetDir)
+	default:
+		err = fmt.Errorf("unsupported package type: %s", packageType)
+	}
+	stopHeartbeat()
+	if err != nil {
+		return nil, err
+	}
+	if fresh {
+		if err := WriteManifest(unpacked.UnpackedHome()); err != nil {
 			return nil, err
 		}
-
-		fmt.Println("Unpacked ", request.TargetFile(), " to ", targetApp, "...")
-		return targetApp, nil
 	}
+	reportUnpackThroughput(ctx, unpacked.UnpackedHome(), localConfig.CacheDir(), time.Since(start))
 
-	return nil, fmt.Errorf("unsupported package type: %s", request.RemoteIde().PackageType())
+	logger.Info(fmt.Sprintf("Unpacked %s to %s", request.TargetFile(), unpacked.UnpackedHome()))
+	return unpacked, nil
+}
+
+// checkUnpackSpace fails before unpacking when the cache has no room for the unpacked IDE,
+// which is estimated from the size of the package
+func checkUnpackSpace(ctx context.Context, localConfig config.Config, request feed_api.DownloadedRemoteIde) error {
+	info, err := os.Stat(request.TargetFile())
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", request.TargetFile(), err)
+	}
+	return diskspace.Check(ctx, localConfig.CacheDir(), info.Size()*diskspace.UnpackRatio)
 }
 
 func isDirectoryExistsAndNotEmpty(path string) (bool, error) {
@@ -0,0 +1,196 @@
+package unpack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/contentstore"
+	"jonnyzzz.com/devrig.dev/extract"
+	"jonnyzzz.com/devrig.dev/feed_api"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/tempfile"
+	"jonnyzzz.com/devrig.dev/unpack_api"
+)
+
+// markerFileName records the package an IDE folder was unpacked from
+const markerFileName = ".devrig-unpacked.json"
+
+// unpackMarker is the content of .devrig-unpacked.json in the unpacked IDE
+type unpackMarker struct {
+	PackageType string `json:"package_type"`
+	Archive     string `json:"archive"`
+	// Checksum is the hex SHA-256 of the package
+	Checksum string `json:"checksum"`
+}
+
+type unpackedDownloadedRemoteIdeArchive struct {
+	unpack_api.UnpackedDownloadedRemoteIde
+
+	home      string
+	remoteIde feed_api.RemoteIDE
+}
+
+func (u *unpackedDownloadedRemoteIdeArchive) RemoteIde() feed_api.RemoteIDE {
+	return u.remoteIde
+}
+
+func (u *unpackedDownloadedRemoteIdeArchive) UnpackedHome() string {
+	return u.home
+}
+
+func (u *unpackedDownloadedRemoteIdeArchive) String() string {
+	return fmt.Sprintf("UnpackedDownloadedRemoteIdeArchive{home: %s, remoteIde: %s}", u.home, u.remoteIde)
+}
+
+// unpackArchive unpacks the targz or zip package into targetDir, the single root folder of the package is
+// the IDE home. A folder unpacked from the same package is reused, anything else in targetDir is replaced.
+// Returns whether the package was unpacked
+func unpackArchive(ctx context.Context, localConfig config.Config, request feed_api.DownloadedRemoteIde, targetDir string) (*unpackedDownloadedRemoteIdeArchive, bool, error) {
+	packageType := request.RemoteIde().PackageType()
+	checksum, err := contentstore.FileSHA256(request.TargetFile())
+	if err != nil {
+		return nil, false, err
+	}
+
+	result := &unpackedDownloadedRemoteIdeArchive{remoteIde: request.RemoteIde(), home: targetDir}
+	if marker, err := readMarker(targetDir); err == nil && marker.Checksum == checksum {
+		return result, false, nil
+	}
+
+	if err := checkUnpackSpace(ctx, localConfig, request); err != nil {
+		return nil, false, err
+	}
+
+	// The temp folder is in the cache, so the final rename never crosses filesystems
+	tempDir, err := tempfile.Mkdir(localConfig.CacheDir(), ".unpack-*")
+	if err != nil {
+		return nil, false, err
+	}
+	defer tempfile.Remove(tempDir)
+
+	// The shared cache keeps the IDE homes unpacked by other projects with the marker of the same package
+	store := contentstore.FromContext(ctx)
+	root, shared := linkSharedTree(ctx, store, checksum, filepath.Join(tempDir, "shared"))
+	if !shared {
+		limits, err := extract.ResolveLimits()
+		if err != nil {
+			return nil, false, err
+		}
+		unpacked := filepath.Join(tempDir, "unpacked")
+		switch packageType {
+		case "targz":
+			err = extract.TarGz(request.TargetFile(), unpacked, limits)
+		case "zip":
+			err = extract.Zip(request.TargetFile(), unpacked, limits)
+		default:
+			err = fmt.Errorf("unsupported package type: %s", packageType)
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to extract %s: %w", request.TargetFile(), err)
+		}
+
+		root, err = packageRoot(unpacked)
+		if err != nil {
+			return nil, false, err
+		}
+		marker := unpackMarker{PackageType: packageType, Archive: filepath.Base(request.TargetFile()), Checksum: checksum}
+		if err := writeMarker(root, marker); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(targetDir), 0755); err != nil {
+		return nil, false, fmt.Errorf("failed to create parent directories for %s: %w", targetDir, err)
+	}
+	if err := os.RemoveAll(targetDir); err != nil {
+		return nil, false, fmt.Errorf("failed to remove incomplete installation %s: %w", targetDir, err)
+	}
+	if err := os.Rename(root, targetDir); err != nil {
+		return nil, false, fmt.Errorf("failed to move %s into place: %w", filepath.Base(targetDir), err)
+	}
+	// The extracted root may be the temp folder, which is private to the user
+	if err := os.Chmod(targetDir, 0755); err != nil {
+		logging.FromContext(ctx).Warn("failed to make the IDE folder readable", "path", targetDir, "error", err)
+	}
+	if store != nil && !shared {
+		if err := store.PutTree(checksum, targetDir); err != nil {
+			logging.FromContext(ctx).Warn("failed to add the IDE to the shared cache", "path", targetDir, "error", err)
+		}
+	}
+	return result, true, nil
+}
+
+// linkSharedTree links the IDE home unpacked from the package with the checksum from the shared cache into target,
+// returns false when there is none
+func linkSharedTree(ctx context.Context, store *contentstore.Store, checksum string, target string) (string, bool) {
+	if store == nil {
+		return "", false
+	}
+	linked, err := store.LinkTree(checksum, target)
+	if err != nil {
+		logging.FromContext(ctx).Warn("failed to reuse the IDE of the shared cache", "checksum", checksum, "error", err)
+		return "", false
+	}
+	if linked {
+		logging.FromContext(ctx).Info("Reusing the unpacked IDE from the shared cache " + store.Root())
+	}
+	return target, linked
+}
+
+// packageRoot returns the single top-level folder of the package, e.g. GoLand-2024.3, or the unpacked folder itself
+// when the package has several entries at the top, like the Windows zip
+func packageRoot(unpacked string) (string, error) {
+	entries, err := os.ReadDir(unpacked)
+	if err != nil {
+		return "", fmt.Errorf("failed to list unpacked package: %w", err)
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("the package is empty")
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(unpacked, entries[0].Name()), nil
+	}
+	return unpacked, nil
+}
+
+// ArchiveChecksum returns the hex SHA-256 of the targz or zip package the IDE at home was unpacked from,
+// the folders of installers are not shared
+func ArchiveChecksum(home string) (string, error) {
+	marker, err := readMarker(home)
+	if err != nil {
+		return "", err
+	}
+	if marker.PackageType != "targz" && marker.PackageType != "zip" {
+		return "", fmt.Errorf("%s is installed from a %s package", filepath.Base(home), marker.PackageType)
+	}
+	return marker.Checksum, nil
+}
+
+// readMarker reads .devrig-unpacked.json of the unpacked IDE
+func readMarker(home string) (*unpackMarker, error) {
+	data, err := os.ReadFile(filepath.Join(home, markerFileName))
+	if err != nil {
+		return nil, err
+	}
+	var marker unpackMarker
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", markerFileName, err)
+	}
+	return &marker, nil
+}
+
+// writeMarker records the package in .devrig-unpacked.json of the unpacked IDE
+func writeMarker(home string, marker unpackMarker) error {
+	data, err := json.MarshalIndent(marker, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", markerFileName, err)
+	}
+	if err := os.WriteFile(filepath.Join(home, markerFileName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", markerFileName, err)
+	}
+	return nil
+}
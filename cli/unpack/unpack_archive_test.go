@@ -0,0 +1,293 @@
+package unpack
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/contentstore"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/extract"
+	"jonnyzzz.com/devrig.dev/feed_api"
+	"jonnyzzz.com/devrig.dev/layout"
+)
+
+type testRemoteIde struct {
+	packageType string
+}
+
+func (r *testRemoteIde) String() string      { return "GoLand 243.1" }
+func (r *testRemoteIde) Name() string        { return "GoLand" }
+func (r *testRemoteIde) Build() string       { return "243.1" }
+func (r *testRemoteIde) PackageType() string { return r.packageType }
+func (r *testRemoteIde) IdeType() string     { return "intellij" }
+
+type testDownloadedIde struct {
+	file   string
+	remote feed_api.RemoteIDE
+}
+
+func (d *testDownloadedIde) String() string                { return d.file }
+func (d *testDownloadedIde) TargetFile() string            { return d.file }
+func (d *testDownloadedIde) RemoteIde() feed_api.RemoteIDE { return d.remote }
+
+// writeTarGz creates a package with the GoLand-2024.3 root folder, an executable launcher and a link to it
+func writeTarGz(t *testing.T, path string, launcher string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	headers := []*tar.Header{
+		{Name: "GoLand-2024.3/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "GoLand-2024.3/bin/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "GoLand-2024.3/bin/goland.sh", Typeflag: tar.TypeReg, Mode: 0755, Size: int64(len(launcher))},
+		{Name: "GoLand-2024.3/goland", Typeflag: tar.TypeSymlink, Linkname: "bin/goland.sh"},
+	}
+	for _, header := range headers {
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write tar header: %v", err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(launcher)); err != nil {
+				t.Fatalf("Failed to write tar entry: %v", err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to close gzip: %v", err)
+	}
+}
+
+func TestUnpackIde_TarGz(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test checks POSIX permissions and links")
+	}
+	projectDir := t.TempDir()
+	localConfig := config.NewConfig(filepath.Join(projectDir, "devrig.yaml"), config.NewIDEConfig("GoLand", "2024.3", ""))
+	archive := filepath.Join(t.TempDir(), "goland-2024.3.tar.gz")
+	writeTarGz(t, archive, "#!/bin/sh\n")
+	request := &testDownloadedIde{file: archive, remote: &testRemoteIde{packageType: "targz"}}
+
+	unpacked, err := UnpackIde(context.Background(), localConfig, request)
+	if err != nil {
+		t.Fatalf("Failed to unpack the IDE: %v", err)
+	}
+	home := unpacked.UnpackedHome()
+	if home != filepath.Join(layout.ResolveUnpackedIdesDir(localConfig.CacheDir()), "GoLand-243.1") {
+		t.Errorf("Unexpected IDE home: %s", home)
+	}
+	info, err := os.Stat(filepath.Join(home, "bin", "goland.sh"))
+	if err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Errorf("Expected an executable launcher, got %v, %v", info, err)
+	}
+	if target, err := os.Readlink(filepath.Join(home, "goland")); err != nil || target != "bin/goland.sh" {
+		t.Errorf("Expected the link to be kept, got %s, %v", target, err)
+	}
+	marker, err := readMarker(home)
+	if err != nil || marker.PackageType != "targz" || len(marker.Checksum) != 64 {
+		t.Errorf("Expected the package to be recorded, got %+v, %v", marker, err)
+	}
+
+	// The same package is not unpacked again, another one replaces the folder
+	if err := os.WriteFile(filepath.Join(home, "bin", "extra.txt"), []byte("extra"), 0644); err != nil {
+		t.Fatalf("Failed to add a file: %v", err)
+	}
+	if _, err := UnpackIde(context.Background(), localConfig, request); err != nil {
+		t.Fatalf("Failed to unpack the IDE again: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, "bin", "extra.txt")); err != nil {
+		t.Errorf("Expected the unpacked IDE to be reused: %v", err)
+	}
+
+	// A modified file of the package is detected by the manifest and the IDE is unpacked again
+	if err := os.WriteFile(filepath.Join(home, "bin", "goland.sh"), []byte("changed"), 0755); err != nil {
+		t.Fatalf("Failed to change the launcher: %v", err)
+	}
+	if _, err := UnpackIde(context.Background(), localConfig, request); err != nil {
+		t.Fatalf("Failed to unpack the modified IDE: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(home, "bin", "goland.sh")); string(data) != "#!/bin/sh\n" {
+		t.Errorf("Expected the modified IDE to be unpacked again, got %q", data)
+	}
+
+	writeTarGz(t, archive, "#!/bin/sh\nexit 0\n")
+	if _, err := UnpackIde(context.Background(), localConfig, request); err != nil {
+		t.Fatalf("Failed to unpack the new package: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(home, "bin", "goland.sh")); string(data) != "#!/bin/sh\nexit 0\n" {
+		t.Errorf("Expected the new package to replace the IDE, got %q", data)
+	}
+}
+
+func TestUnpackIde_Zip(t *testing.T) {
+	projectDir := t.TempDir()
+	localConfig := config.NewConfig(filepath.Join(projectDir, "devrig.yaml"), config.NewIDEConfig("GoLand", "2024.3", ""))
+	archive := filepath.Join(t.TempDir(), "goland-2024.3.win.zip")
+	file, err := os.Create(archive)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	writer := zip.NewWriter(file)
+	for _, name := range []string{"bin/goland64.exe", "product-info.json"} {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create zip entry: %v", err)
+		}
+		if _, err := entry.Write([]byte(name)); err != nil {
+			t.Fatalf("Failed to write zip entry: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	file.Close()
+
+	unpacked, err := UnpackIde(context.Background(), localConfig, &testDownloadedIde{file: archive, remote: &testRemoteIde{packageType: "zip"}})
+	if err != nil {
+		t.Fatalf("Failed to unpack the IDE: %v", err)
+	}
+	// The Windows zip has no root folder, its entries are the IDE home
+	for _, name := range []string{"bin/goland64.exe", "product-info.json", markerFileName} {
+		if _, err := os.Stat(filepath.Join(unpacked.UnpackedHome(), filepath.FromSlash(name))); err != nil {
+			t.Errorf("Expected %s in the IDE home: %v", name, err)
+		}
+	}
+
+	// The limits of the environment apply to the IDE packages too
+	t.Setenv(extract.EnvMaxUnpackedSize, "16")
+	otherConfig := config.NewConfig(filepath.Join(t.TempDir(), "devrig.yaml"), config.NewIDEConfig("GoLand", "2024.3", ""))
+	var limitErr *devrigErrors.DecompressionLimitError
+	if _, err := UnpackIde(context.Background(), otherConfig, &testDownloadedIde{file: archive, remote: &testRemoteIde{packageType: "zip"}}); !errors.As(err, &limitErr) {
+		t.Errorf("Expected %s to limit the unpacked size, got %v", extract.EnvMaxUnpackedSize, err)
+	}
+}
+
+func TestUnpackIde_Exe(t *testing.T) {
+	localConfig := config.NewConfig(filepath.Join(t.TempDir(), "devrig.yaml"), config.NewIDEConfig("GoLand", "2024.3", ""))
+	installer := filepath.Join(t.TempDir(), "goland-2024.3.exe")
+	if err := os.WriteFile(installer, []byte("installer"), 0755); err != nil {
+		t.Fatalf("Failed to write installer: %v", err)
+	}
+	request := &testDownloadedIde{file: installer, remote: &testRemoteIde{packageType: "exe"}}
+
+	if runtime.GOOS != "windows" {
+		var unsupported *devrigErrors.UnsupportedPlatformError
+		if _, err := UnpackIde(context.Background(), localConfig, request); !errors.As(err, &unsupported) {
+			t.Errorf("Expected the installer to be unsupported, got: %v", err)
+		}
+	}
+
+	// A folder installed from the same installer is reused without running it
+	home := layout.ResolveLocalHome(localConfig, request.RemoteIde())
+	if err := os.MkdirAll(home, 0755); err != nil {
+		t.Fatalf("Failed to create IDE home: %v", err)
+	}
+	checksum, err := contentstore.FileSHA256(installer)
+	if err != nil {
+		t.Fatalf("Failed to hash installer: %v", err)
+	}
+	if err := writeMarker(home, unpackMarker{PackageType: "exe", Archive: "goland-2024.3.exe", Checksum: checksum}); err != nil {
+		t.Fatalf("Failed to write marker: %v", err)
+	}
+	unpacked, err := UnpackIde(context.Background(), localConfig, request)
+	if err != nil {
+		t.Fatalf("Failed to reuse the installed IDE: %v", err)
+	}
+	if unpacked.UnpackedHome() != home {
+		t.Errorf("Unexpected IDE home: %s", unpacked.UnpackedHome())
+	}
+}
+
+func TestUnpackIde_UnsupportedPackageType(t *testing.T) {
+	localConfig := config.NewConfig(filepath.Join(t.TempDir(), "devrig.yaml"), config.NewIDEConfig("GoLand", "2024.3", ""))
+	request := &testDownloadedIde{file: filepath.Join(t.TempDir(), "goland.sit"), remote: &testRemoteIde{packageType: "sit"}}
+	if _, err := UnpackIde(context.Background(), localConfig, request); err == nil {
+		t.Error("Expected an error for the sit package")
+	}
+}
+
+func TestUnpackIde_SharedCache(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test checks POSIX links")
+	}
+	store := contentstore.New(filepath.Join(t.TempDir(), "store"))
+	ctx := contentstore.WithStore(context.Background(), store)
+	archive := filepath.Join(t.TempDir(), "goland-2024.3.tar.gz")
+	writeTarGz(t, archive, "#!/bin/sh\n")
+	request := &testDownloadedIde{file: archive, remote: &testRemoteIde{packageType: "targz"}}
+
+	var homes []string
+	for range 2 {
+		localConfig := config.NewConfig(filepath.Join(t.TempDir(), "devrig.yaml"), config.NewIDEConfig("GoLand", "2024.3", ""))
+		unpacked, err := UnpackIde(ctx, localConfig, request)
+		if err != nil {
+			t.Fatalf("Failed to unpack the IDE: %v", err)
+		}
+		homes = append(homes, unpacked.UnpackedHome())
+	}
+
+	// The second project links the files unpacked by the first one
+	first, err := os.Stat(filepath.Join(homes[0], "bin", "goland.sh"))
+	if err != nil {
+		t.Fatalf("Failed to stat the launcher: %v", err)
+	}
+	second, err := os.Stat(filepath.Join(homes[1], "bin", "goland.sh"))
+	if err != nil || !os.SameFile(first, second) || second.Mode().Perm()&0100 == 0 {
+		t.Errorf("Expected the executable launcher to be shared, got %v, %v", second, err)
+	}
+	if target, err := os.Readlink(filepath.Join(homes[1], "goland")); err != nil || target != "bin/goland.sh" {
+		t.Errorf("Expected the link to be kept, got %s, %v", target, err)
+	}
+	if checksum, err := ArchiveChecksum(homes[1]); err != nil || len(checksum) != 64 {
+		t.Errorf("Expected the package to be recorded, got %s, %v", checksum, err)
+	}
+	if err := VerifyUnpacked(ctx, homes[1]); err != nil {
+		t.Errorf("Expected the linked IDE to match its manifest: %v", err)
+	}
+}
+
+func TestUnpackIde_PathWithSpacesAndUnicode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test checks POSIX links")
+	}
+	projectDir := filepath.Join(t.TempDir(), "Jöhn Doe", "Проект 1")
+	localConfig := config.NewConfig(filepath.Join(projectDir, "devrig.yaml"), config.NewIDEConfig("GoLand", "2024.3", ""))
+	archive := filepath.Join(t.TempDir(), "Downloads ü", "goland 2024.3.tar.gz")
+	if err := os.MkdirAll(filepath.Dir(archive), 0755); err != nil {
+		t.Fatalf("Failed to create the downloads folder: %v", err)
+	}
+	writeTarGz(t, archive, "#!/bin/sh\n")
+
+	unpacked, err := UnpackIde(context.Background(), localConfig, &testDownloadedIde{file: archive, remote: &testRemoteIde{packageType: "targz"}})
+	if err != nil {
+		t.Fatalf("Failed to unpack the IDE: %v", err)
+	}
+	home := unpacked.UnpackedHome()
+	if !strings.HasPrefix(home, projectDir+string(filepath.Separator)) {
+		t.Errorf("Expected the IDE in the project %s, got %s", projectDir, home)
+	}
+	if target, err := os.Readlink(filepath.Join(home, "goland")); err != nil || target != "bin/goland.sh" {
+		t.Errorf("Expected the link to be kept, got %s, %v", target, err)
+	}
+	if marker, err := readMarker(home); err != nil || marker.Archive != "goland 2024.3.tar.gz" {
+		t.Errorf("Expected the package to be recorded, got %+v, %v", marker, err)
+	}
+	if err := VerifyUnpacked(context.Background(), home); err != nil {
+		t.Errorf("Expected the IDE to match its manifest: %v", err)
+	}
+}
@@ -1,14 +1,18 @@
 package unpack
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/extract"
 	"jonnyzzz.com/devrig.dev/feed_api"
+	"jonnyzzz.com/devrig.dev/logging"
 	"jonnyzzz.com/devrig.dev/unpack_api"
 )
 
@@ -31,74 +35,97 @@ func (u *unpackedDownloadedRemoteIdeDmg) String() string {
 	return fmt.Sprintf("UnpackedDownloadedRemoteIdeDmg{appHome: %s, remoteIde: %s}", u.appHome, u.remoteIde)
 }
 
-func unpackDmg(localConfig config.Config, request feed_api.DownloadedRemoteIde, targetDir string) (*unpackedDownloadedRemoteIdeDmg, error) {
+func unpackDmg(ctx context.Context, localConfig config.Config, request feed_api.DownloadedRemoteIde, targetDir string) (*unpackedDownloadedRemoteIdeDmg, bool, error) {
+	logger := logging.FromContext(ctx)
 	if runtime.GOOS != "darwin" {
-		return nil, fmt.Errorf("unpacking DMG is only supported on macOS")
+		return nil, false, fmt.Errorf("unpacking DMG is only supported on macOS")
 	}
 
 	exists, err := isDirectoryExistsAndNotEmpty(targetDir)
 	if err == nil && exists {
-		//TODO: implement checksum validation here
+		// UnpackIde checks the application against its manifest first
 		//TODO: list files and resolve the only .app there
-		return &unpackedDownloadedRemoteIdeDmg{remoteIde: request.RemoteIde(), appHome: targetDir}, nil
+		return &unpackedDownloadedRemoteIdeDmg{remoteIde: request.RemoteIde(), appHome: targetDir}, false, nil
+	}
+
+	if err := checkUnpackSpace(ctx, localConfig, request); err != nil {
+		return nil, false, err
 	}
 
 	// Ensure the parent directory of targetFile exists
 	if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
-		return nil, fmt.Errorf("failed to create parent directories for %s: %w", targetDir, err)
+		return nil, false, fmt.Errorf("failed to create parent directories for %s: %w", targetDir, err)
 	}
 
 	_ = os.RemoveAll(targetDir)
 	// Create a temporary mount point
 	mountPoint, err := os.MkdirTemp(localConfig.CacheDir(), "jbcli-dmg-*")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		return nil, false, fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
 	defer os.RemoveAll(mountPoint)
 
-	// Mount the DMG
-	attachCmd := exec.Command("hdiutil", "attach", "-nobrowse", "-mountpoint", mountPoint, request.TargetFile())
-	if err := attachCmd.Run(); err != nil {
-		return nil, fmt.Errorf("failed to mount DMG: %w", err)
+	// Mount the DMG, hdiutil is only used for the mount, the copy is done natively
+	if output, err := exec.Command("hdiutil", hdiutilAttachArgs(mountPoint, request.TargetFile())...).CombinedOutput(); err != nil {
+		return nil, false, fmt.Errorf("failed to mount DMG %s: %w: %s", request.TargetFile(), err, strings.TrimSpace(string(output)))
 	}
-	defer exec.Command("hdiutil", "detach", mountPoint, "-force").Run()
+	defer func() {
+		if output, err := exec.Command("hdiutil", hdiutilDetachArgs(mountPoint)...).CombinedOutput(); err != nil {
+			logger.Warn("failed to unmount DMG", "path", mountPoint, "error", err, "output", strings.TrimSpace(string(output)))
+		}
+	}()
 
 	// Find and copy the .app directory
 	entries, err := os.ReadDir(mountPoint)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read mount directory: %w for %s", err, request.TargetFile())
+		return nil, false, fmt.Errorf("failed to read mount directory: %w for %s", err, request.TargetFile())
 	}
 
 	dstPath := ""
 	for _, entry := range entries {
 		if filepath.Ext(entry.Name()) != ".app" {
-			fmt.Printf("Skipping %s from %s\n", entry.Name(), request.TargetFile())
+			logger.Debug("skipping DMG entry", "entry", entry.Name(), "file", request.TargetFile())
 			continue
 		}
 
 		if dstPath != "" {
-			return nil, fmt.Errorf("multiple .app directories found in DMG file %s", request.TargetFile())
+			return nil, false, fmt.Errorf("multiple .app directories found in DMG file %s", request.TargetFile())
 		}
 
 		srcPath := filepath.Join(mountPoint, entry.Name())
 		dstPath = filepath.Join(targetDir)
 
-		cpCmd := exec.Command("cp", "-Rv", srcPath+"/", dstPath+"/")
-		if err := cpCmd.Run(); err != nil {
-			return nil, fmt.Errorf("failed to copy application: %w to %s for %s", err, targetDir, request.TargetFile())
+		if err := extract.CopyTree(srcPath, dstPath); err != nil {
+			_ = os.RemoveAll(dstPath)
+			return nil, false, fmt.Errorf("failed to copy application to %s for %s: %w", targetDir, request.TargetFile(), err)
+		}
+
+		// Links are copied as they are, a crafted DMG could ship a chain of links leading out of the application
+		if err := extract.CheckTree(dstPath); err != nil {
+			_ = os.RemoveAll(dstPath)
+			return nil, false, fmt.Errorf("unsafe application in DMG file %s: %w", request.TargetFile(), err)
 		}
 
-		// Remove quarantine attributes
-		xattrCmd := exec.Command("xattr", "-rd", "com.apple.quarantine", dstPath)
-		if err := xattrCmd.Run(); err != nil {
-			fmt.Printf("failed to remove quarantine attributes: %s\n", err.Error())
+		if err := removeQuarantine(dstPath); err != nil {
+			logger.Warn("failed to remove quarantine attributes", "path", dstPath, "error", err)
 		}
 	}
 
 	if dstPath == "" {
-		return nil, fmt.Errorf("no .app directories found in DMG file %s", request.TargetFile())
+		return nil, false, fmt.Errorf("no .app directories found in DMG file %s", request.TargetFile())
 	}
 
-	return &unpackedDownloadedRemoteIdeDmg{remoteIde: request.RemoteIde(), appHome: targetDir}, nil
+	return &unpackedDownloadedRemoteIdeDmg{remoteIde: request.RemoteIde(), appHome: targetDir}, true, nil
+}
+
+// hdiutilAttachArgs returns the arguments to mount the DMG read-only at mountPoint. hdiutil runs without a shell,
+// so every path is a single argument even with spaces or non-ASCII characters, e.g. /Users/Jöhn Doe
+func hdiutilAttachArgs(mountPoint string, dmg string) []string {
+	return []string{"attach", "-nobrowse", "-readonly", "-mountpoint", mountPoint, dmg}
+}
+
+// hdiutilDetachArgs returns the arguments to unmount the DMG at mountPoint
+func hdiutilDetachArgs(mountPoint string) []string {
+	return []string{"detach", mountPoint, "-force"}
 }
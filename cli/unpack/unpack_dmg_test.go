@@ -0,0 +1,21 @@
+package unpack
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHdiutilArgs_PathWithSpacesAndUnicode(t *testing.T) {
+	cacheDir := filepath.Join("/Users", "Jöhn Doe", "my project 名前", ".idew", "cache")
+	mountPoint := filepath.Join(cacheDir, "jbcli-dmg-1")
+	dmg := filepath.Join(cacheDir, "download", "GoLand-243.1.dmg")
+
+	expected := []string{"attach", "-nobrowse", "-readonly", "-mountpoint", mountPoint, dmg}
+	if args := hdiutilAttachArgs(mountPoint, dmg); !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected %q, got %q", expected, args)
+	}
+	if args := hdiutilDetachArgs(mountPoint); !reflect.DeepEqual(args, []string{"detach", mountPoint, "-force"}) {
+		t.Errorf("Expected the mount point as a single argument, got %q", args)
+	}
+}
@@ -0,0 +1,81 @@
+package unpack
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"jonnyzzz.com/devrig.dev/config"
+	"jonnyzzz.com/devrig.dev/contentstore"
+	"jonnyzzz.com/devrig.dev/feed_api"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// silentConfig answers the questions of the JetBrains installer: a per-user installation without shortcuts,
+// PATH changes and file associations, the IDE is started by devrig
+const silentConfig = `mode=user
+launcher32=0
+launcher64=0
+updatePATH=0
+updateContextMenu=0
+jre32=0
+regenerationSharedArchive=1
+`
+
+// unpackExe runs the exe installer silently into targetDir. The portable zip is preferred by the package types of
+// the feed, the installer is used for builds that are only published as exe. The folder of the same installer is
+// reused, an interrupted installation has no marker and is installed again. Returns whether the installer ran
+func unpackExe(ctx context.Context, localConfig config.Config, request feed_api.DownloadedRemoteIde, targetDir string) (*unpackedDownloadedRemoteIdeArchive, bool, error) {
+	checksum, err := contentstore.FileSHA256(request.TargetFile())
+	if err != nil {
+		return nil, false, err
+	}
+
+	result := &unpackedDownloadedRemoteIdeArchive{remoteIde: request.RemoteIde(), home: targetDir}
+	if marker, err := readMarker(targetDir); err == nil && marker.Checksum == checksum {
+		return result, false, nil
+	}
+
+	if err := checkUnpackSpace(ctx, localConfig, request); err != nil {
+		return nil, false, err
+	}
+
+	tempDir, err := tempfile.Mkdir(localConfig.CacheDir(), ".installer-*")
+	if err != nil {
+		return nil, false, err
+	}
+	defer tempfile.Remove(tempDir)
+
+	configFile := filepath.Join(tempDir, "silent.config")
+	if err := os.WriteFile(configFile, []byte(silentConfig), 0644); err != nil {
+		return nil, false, fmt.Errorf("failed to write the installer config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(targetDir), 0755); err != nil {
+		return nil, false, fmt.Errorf("failed to create parent directories for %s: %w", targetDir, err)
+	}
+	if err := os.RemoveAll(targetDir); err != nil {
+		return nil, false, fmt.Errorf("failed to remove incomplete installation %s: %w", targetDir, err)
+	}
+
+	// The installer takes the folder verbatim from the last argument, so /D= must not be quoted
+	cmd, err := installerCommand(ctx, request.TargetFile(), "/S", "/CONFIG="+configFile, "/D="+targetDir)
+	if err != nil {
+		return nil, false, err
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		logging.FromContext(ctx).Debug("installer output", "installer", request.TargetFile(), "output", string(output))
+		return nil, false, fmt.Errorf("failed to run the installer %s: %w", filepath.Base(request.TargetFile()), err)
+	}
+	if _, err := os.Stat(filepath.Join(targetDir, "bin")); err != nil {
+		return nil, false, fmt.Errorf("the installer %s did not install into %s: %w", filepath.Base(request.TargetFile()), targetDir, err)
+	}
+
+	marker := unpackMarker{PackageType: "exe", Archive: filepath.Base(request.TargetFile()), Checksum: checksum}
+	if err := writeMarker(targetDir, marker); err != nil {
+		return nil, false, err
+	}
+	return result, true, nil
+}
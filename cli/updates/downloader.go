@@ -5,13 +5,37 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/offline"
 )
 
+// maxMetadataSize limits the downloaded release metadata and signatures
+const maxMetadataSize = 1 << 20
+
 const (
-	LatestJSONURL    = "https://devrig.dev/download/latest.json"
-	LatestJSONSigURL = "https://devrig.dev/download/latest.json.sig"
+	DownloadBaseURL  = "https://devrig.dev/download/"
+	LatestJSONURL    = DownloadBaseURL + "latest.json"
+	LatestJSONSigURL = LatestJSONURL + ".sig"
 )
 
+// ChannelJSONURL returns the URL of the signed metadata of the latest release in the channel of devrig.dev, e.g. latest-beta.json
+func ChannelJSONURL(channel string) string {
+	return DefaultEndpoints.ChannelJSONURL(channel)
+}
+
+// ReleaseJSONURL returns the URL of the signed metadata of the given release of devrig.dev, e.g. 0.79.6
+func ReleaseJSONURL(version string) string {
+	return DefaultEndpoints.ReleaseJSONURL(version)
+}
+
+// statusError is the unexpected HTTP status of a response
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("status %d", int(e))
+}
+
 // Downloader handles downloading update information
 type Downloader struct {
 	HTTPClient *http.Client
@@ -28,21 +52,28 @@ func NewDownloader() *Downloader {
 
 // download is a helper method that performs the actual HTTP download
 func (d *Downloader) download(url, name string) ([]byte, error) {
+	if err := offline.Check(name, url, "release metadata is never cached, run the command with network access"); err != nil {
+		return nil, err
+	}
+
 	resp, err := d.HTTPClient.Get(url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+		return nil, fmt.Errorf("failed to download %s: %w", name, &devrigErrors.NetworkError{URL: url, Err: err})
 	}
 	//goland:noinspection GoUnhandledErrorResult
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to download %s: status %d", name, resp.StatusCode)
+		return nil, fmt.Errorf("failed to download %s: %w", name, &devrigErrors.NetworkError{URL: url, Err: statusError(resp.StatusCode)})
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize+1))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read %s: %w", name, err)
 	}
+	if len(data) > maxMetadataSize {
+		return nil, fmt.Errorf("failed to read %s: the response is larger than %d bytes", name, maxMetadataSize)
+	}
 
 	return data, nil
 }
@@ -0,0 +1,57 @@
+package updates
+
+import (
+	"errors"
+	"strings"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// Endpoints are the URLs of the signed release metadata, the updates section of devrig.yaml replaces them,
+// e.g. for a fork or an internal distribution
+type Endpoints struct {
+	// BaseURL hosts the channel and release metadata, it ends with a slash
+	BaseURL string
+	// LatestURL is the metadata of the latest stable release, the signature is at the same URL with the .sig suffix
+	LatestURL string
+}
+
+// DefaultEndpoints are the endpoints of devrig.dev
+var DefaultEndpoints = Endpoints{BaseURL: DownloadBaseURL, LatestURL: LatestJSONURL}
+
+// ChannelJSONURL returns the URL of the signed metadata of the latest release in the channel, e.g. latest-beta.json
+func (e Endpoints) ChannelJSONURL(channel string) string {
+	if channel == "" || channel == configservice.ChannelStable {
+		return e.LatestURL
+	}
+	return e.BaseURL + "latest-" + channel + ".json"
+}
+
+// ReleaseJSONURL returns the URL of the signed metadata of the given release, e.g. 0.79.6
+func (e Endpoints) ReleaseJSONURL(version string) string {
+	return e.BaseURL + "v" + strings.TrimPrefix(version, "v") + "/release.json"
+}
+
+// ResolveEndpoints returns the endpoints of the updates section of devrig.yaml, the ones of devrig.dev are used
+// when devrig.yaml or the section is missing. A base_url alone moves latest.json too
+func ResolveEndpoints(configs configservice.ConfigService) (Endpoints, error) {
+	section, err := configs.Updates().ReadUpdates()
+	var notFound *devrigErrors.ConfigNotFoundError
+	if errors.As(err, &notFound) {
+		return DefaultEndpoints, nil
+	}
+	if err != nil {
+		return Endpoints{}, err
+	}
+
+	endpoints := DefaultEndpoints
+	if section.BaseURL != "" {
+		endpoints.BaseURL = strings.TrimSuffix(section.BaseURL, "/") + "/"
+		endpoints.LatestURL = endpoints.BaseURL + "latest.json"
+	}
+	if section.LatestURL != "" {
+		endpoints.LatestURL = section.LatestURL
+	}
+	return endpoints, nil
+}
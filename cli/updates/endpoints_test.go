@@ -0,0 +1,63 @@
+package updates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+)
+
+func TestEndpoints_Defaults(t *testing.T) {
+	if url := ChannelJSONURL("stable"); url != LatestJSONURL {
+		t.Errorf("Expected latest.json for stable, got %s", url)
+	}
+	if url := ChannelJSONURL("beta"); url != "https://devrig.dev/download/latest-beta.json" {
+		t.Errorf("Unexpected beta URL: %s", url)
+	}
+	if url := ReleaseJSONURL("v0.79.6"); url != "https://devrig.dev/download/v0.79.6/release.json" {
+		t.Errorf("Unexpected release URL: %s", url)
+	}
+}
+
+func TestResolveEndpoints(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	resolve := func(content string) Endpoints {
+		t.Helper()
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write devrig.yaml: %v", err)
+		}
+		endpoints, err := ResolveEndpoints(configservice.NewConfigService(configPath))
+		if err != nil {
+			t.Fatalf("Failed to resolve endpoints: %v", err)
+		}
+		return endpoints
+	}
+
+	missing, err := ResolveEndpoints(configservice.NewConfigService(filepath.Join(t.TempDir(), "devrig.yaml")))
+	if err != nil || missing != DefaultEndpoints {
+		t.Errorf("Expected the defaults without devrig.yaml, got %+v, %v", missing, err)
+	}
+	if endpoints := resolve("tools:\n  go: 1.22.1\n"); endpoints != DefaultEndpoints {
+		t.Errorf("Expected the defaults without the updates section, got %+v", endpoints)
+	}
+
+	endpoints := resolve("updates:\n  base_url: https://example.com/devrig\n")
+	if endpoints.LatestURL != "https://example.com/devrig/latest.json" ||
+		endpoints.ChannelJSONURL("beta") != "https://example.com/devrig/latest-beta.json" ||
+		endpoints.ReleaseJSONURL("0.79.6") != "https://example.com/devrig/v0.79.6/release.json" {
+		t.Errorf("Expected all URLs under the base URL, got %+v", endpoints)
+	}
+
+	endpoints = resolve("updates:\n  latest_url: https://example.com/stable.json\n")
+	if endpoints.LatestURL != "https://example.com/stable.json" || endpoints.BaseURL != DownloadBaseURL {
+		t.Errorf("Expected only latest.json to move, got %+v", endpoints)
+	}
+
+	if err := os.WriteFile(configPath, []byte("updates:\n  base_url: ftp://example.com/\n"), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	if _, err := ResolveEndpoints(configservice.NewConfigService(configPath)); err == nil {
+		t.Error("Expected an ftp URL to be rejected")
+	}
+}
@@ -0,0 +1,125 @@
+package updates
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_FetchUpdateInfo_SingleFlight(t *testing.T) {
+	signer, key := newOrganizationKey(t)
+	metadata := []byte(`{"version": "0.80.0", "binaries": [{"os": "linux", "arch": "x86_64"}]}`)
+	signature := signSSH(t, signer, metadata)
+
+	var requests sync.Map
+	signatureRequested := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count, _ := requests.LoadOrStore(r.URL.Path, new(atomic.Int32))
+		count.(*atomic.Int32).Add(1)
+		switch r.URL.Path {
+		case "/latest.json":
+			// The signature is requested while the metadata is still downloading
+			select {
+			case <-signatureRequested:
+			case <-time.After(5 * time.Second):
+				t.Error("Expected the signature to be downloaded concurrently with the metadata")
+			}
+			_, _ = w.Write(metadata)
+		case "/latest.json.sig":
+			close(signatureRequested)
+			_, _ = w.Write(signature)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{
+		downloader: NewDownloader(),
+		endpoints:  func() (Endpoints, error) { return DefaultEndpoints, nil },
+		verifier:   func() (Verifier, error) { return SSHVerifier{Keys: []string{key}}, nil },
+	}
+	requested := func(path string) int32 {
+		count, ok := requests.Load(path)
+		if !ok {
+			return 0
+		}
+		return count.(*atomic.Int32).Load()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info, err := client.FetchUpdateInfo(server.URL + "/latest.json")
+			if err != nil || info.Version != "0.80.0" {
+				t.Errorf("Failed to fetch the metadata: %+v, %v", info, err)
+				return
+			}
+			// Every caller gets its own copy
+			info.Binaries[0].OS = "changed"
+		}()
+	}
+	wg.Wait()
+	if requested("/latest.json") != 1 || requested("/latest.json.sig") != 1 {
+		t.Errorf("Expected one download of the metadata and the signature, got %d and %d",
+			requested("/latest.json"), requested("/latest.json.sig"))
+	}
+	if info, _ := client.FetchUpdateInfo(server.URL + "/latest.json"); info.Binaries[0].OS != "linux" {
+		t.Errorf("Expected the shared result to stay unchanged, got %+v", info.Binaries[0])
+	}
+
+	// A failure is not retried within the invocation
+	for i := 0; i < 3; i++ {
+		if _, err := client.FetchUpdateInfo(server.URL + "/missing.json"); err == nil {
+			t.Fatalf("Expected the missing metadata to fail")
+		}
+	}
+	if requested("/missing.json") != 1 {
+		t.Errorf("Expected the failure to be remembered, got %d requests", requested("/missing.json"))
+	}
+}
+
+func TestClient_FetchUpdateInfo_SignatureWhileRotatingKeys(t *testing.T) {
+	signer, key := newOrganizationKey(t)
+	metadata := []byte(`{"version": "0.80.0", "binaries": [{"os": "linux", "arch": "x86_64"}]}`)
+	signature := signSSH(t, signer, metadata)
+
+	signatureRequested := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest.json":
+			_, _ = w.Write(metadata)
+		case "/latest.json.sig":
+			close(signatureRequested)
+			_, _ = w.Write(signature)
+		case "/" + KeysFileName:
+			// The signature is requested before the key manifest is known
+			select {
+			case <-signatureRequested:
+			case <-time.After(5 * time.Second):
+				t.Error("Expected the signature to be downloaded concurrently with the key manifest")
+			}
+			http.NotFound(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{
+		downloader: NewDownloader(),
+		endpoints: func() (Endpoints, error) {
+			return Endpoints{BaseURL: server.URL + "/", LatestURL: server.URL + "/latest.json"}, nil
+		},
+		verifier: func() (Verifier, error) { return SSHVerifier{Keys: []string{key}}, nil },
+		keys:     &KeyStore{Dir: t.TempDir()},
+	}
+	if info, err := client.FetchLatestUpdateInfo(); err != nil || info.Version != "0.80.0" {
+		t.Fatalf("Failed to fetch the metadata: %+v, %v", info, err)
+	}
+}
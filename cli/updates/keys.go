@@ -0,0 +1,199 @@
+package updates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"golang.org/x/crypto/ssh"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// KeysFileName is the key manifest next to latest.json, the signature is at the same URL with the .sig suffix
+const KeysFileName = "keys.json"
+
+// KeyManifest introduces new signing keys and revokes old ones, it is signed by one of the keys trusted before it.
+// The version grows with every change, an older manifest than the accepted one is rejected
+type KeyManifest struct {
+	Version int      `json:"version"`
+	Keys    []string `json:"keys"`
+	Revoked []string `json:"revoked,omitempty"`
+}
+
+// ParseKeyManifest parses and validates keys.json, the keys are in the authorized_keys format
+func ParseKeyManifest(data []byte) (*KeyManifest, error) {
+	var manifest KeyManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", KeysFileName, err)
+	}
+	if manifest.Version < 1 {
+		return nil, fmt.Errorf("invalid %s: version must be positive, got %d", KeysFileName, manifest.Version)
+	}
+	if len(manifest.Keys) == 0 {
+		return nil, fmt.Errorf("invalid %s: no keys", KeysFileName)
+	}
+	for _, key := range append(slices.Clone(manifest.Keys), manifest.Revoked...) {
+		if _, err := keyID(key); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", KeysFileName, err)
+		}
+	}
+	return &manifest, nil
+}
+
+// Accepted returns the keys and the keys of the manifest without the revoked ones
+func (m *KeyManifest) Accepted(keys []string) []string {
+	revoked := map[string]bool{}
+	for _, key := range m.Revoked {
+		if id, err := keyID(key); err == nil {
+			revoked[id] = true
+		}
+	}
+
+	var accepted []string
+	seen := map[string]bool{}
+	for _, key := range append(slices.Clone(keys), m.Keys...) {
+		id, err := keyID(key)
+		if err != nil || revoked[id] || seen[id] {
+			continue
+		}
+		seen[id] = true
+		accepted = append(accepted, key)
+	}
+	return accepted
+}
+
+// keyID identifies the key of an authorized_keys line, the comment is ignored
+func keyID(key string) (string, error) {
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse key %q: %w", key, err)
+	}
+	return string(publicKey.Marshal()), nil
+}
+
+// KeyStore keeps the last accepted key manifest of every endpoint, so a revoked key stays revoked
+// and an older manifest cannot replace it
+type KeyStore struct {
+	Dir string
+}
+
+// DefaultKeyStore returns the store in the devrig folder of the user cache directory
+func DefaultKeyStore() (*KeyStore, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve user cache directory: %w", err)
+	}
+	return &KeyStore{Dir: filepath.Join(cacheDir, "devrig", "keys")}, nil
+}
+
+func (s *KeyStore) path(baseURL string) string {
+	hash := sha256.Sum256([]byte(baseURL))
+	return filepath.Join(s.Dir, hex.EncodeToString(hash[:8])+".json")
+}
+
+// Load returns the accepted manifest of the endpoint, or nil if there is none
+func (s *KeyStore) Load(baseURL string) (*KeyManifest, error) {
+	data, err := os.ReadFile(s.path(baseURL))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read the accepted keys: %w", err)
+	}
+	manifest, err := ParseKeyManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the accepted keys from %s: %w", s.path(baseURL), err)
+	}
+	return manifest, nil
+}
+
+// Save stores the manifest of the endpoint, it must be verified before
+func (s *KeyStore) Save(baseURL string, manifest *KeyManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the accepted keys: %w", err)
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create the key store: %w", err)
+	}
+	if err := tempfile.WriteFile(s.path(baseURL), data, 0644); err != nil {
+		return fmt.Errorf("failed to save the accepted keys: %w", err)
+	}
+	return nil
+}
+
+// rotateKeys applies keys.json of the endpoints to the SSH verifier. The manifest must be signed by a key
+// trusted before it and must not be older than the accepted one, its revocations add to the accepted ones.
+// When keys.json or its signature cannot be downloaded, e.g. the server has none or is unreachable,
+// the accepted keys stay as they are, so the release metadata is still verified
+func (c *Client) rotateKeys(verifier Verifier) (Verifier, error) {
+	sshVerifier, ok := verifier.(SSHVerifier)
+	if !ok || c.keys == nil {
+		return verifier, nil
+	}
+	endpoints, err := c.endpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	initial := sshVerifier.Keys
+	if len(initial) == 0 {
+		initial = TrustedPublicKeys
+	}
+	trusted := initial
+	accepted, err := c.keys.Load(endpoints.BaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if accepted != nil {
+		trusted = accepted.Accepted(initial)
+	}
+
+	url := endpoints.BaseURL + KeysFileName
+	data, err := c.downloader.download(url, KeysFileName)
+	if err != nil {
+		slog.Debug("using the accepted keys, the key manifest is not available", "url", url, "error", err)
+		return SSHVerifier{Keys: trusted}, nil
+	}
+	signature, err := c.downloader.download(url+".sig", KeysFileName+".sig")
+	if err != nil {
+		slog.Debug("using the accepted keys, the key manifest signature is not available", "url", url+".sig", "error", err)
+		return SSHVerifier{Keys: trusted}, nil
+	}
+	if err := VerifySignatureWithKeys(data, signature, trusted); err != nil {
+		return nil, &devrigErrors.SignatureInvalidError{Subject: url, Err: err}
+	}
+
+	manifest, err := ParseKeyManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	if accepted != nil {
+		if manifest.Version < accepted.Version {
+			return nil, &devrigErrors.SignatureInvalidError{Subject: url,
+				Err: fmt.Errorf("version %d is older than the accepted version %d", manifest.Version, accepted.Version)}
+		}
+		// A revoked key is never trusted again, even if a newer manifest forgets it
+		for _, key := range accepted.Revoked {
+			if !slices.Contains(manifest.Revoked, key) {
+				manifest.Revoked = append(manifest.Revoked, key)
+			}
+		}
+	}
+
+	keys := manifest.Accepted(initial)
+	if len(keys) == 0 {
+		return nil, &devrigErrors.SignatureInvalidError{Subject: url, Err: errors.New("all keys are revoked")}
+	}
+	if err := c.keys.Save(endpoints.BaseURL, manifest); err != nil {
+		return nil, err
+	}
+	return SSHVerifier{Keys: keys}, nil
+}
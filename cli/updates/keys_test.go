@@ -0,0 +1,190 @@
+package updates
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// keysServer serves keys.json and its signature, nothing is served while the manifest is nil
+type keysServer struct {
+	manifest  []byte
+	signature []byte
+}
+
+func newKeysClient(t *testing.T, server *keysServer) (*Client, *KeyStore) {
+	t.Helper()
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case server.manifest != nil && r.URL.Path == "/"+KeysFileName:
+			_, _ = w.Write(server.manifest)
+		case server.manifest != nil && r.URL.Path == "/"+KeysFileName+".sig":
+			_, _ = w.Write(server.signature)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(httpServer.Close)
+
+	store := &KeyStore{Dir: t.TempDir()}
+	return &Client{
+		downloader: NewDownloader(),
+		endpoints: func() (Endpoints, error) {
+			return Endpoints{BaseURL: httpServer.URL + "/", LatestURL: httpServer.URL + "/latest.json"}, nil
+		},
+		keys: store,
+	}, store
+}
+
+// publish signs the manifest with the signer and serves it
+func (s *keysServer) publish(t *testing.T, signer ssh.Signer, manifest KeyManifest) {
+	t.Helper()
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("Failed to marshal the manifest: %v", err)
+	}
+	s.manifest = data
+	s.signature = signSSH(t, signer, data)
+}
+
+func TestRotateKeys(t *testing.T) {
+	oldSigner, oldKey := newOrganizationKey(t)
+	newSigner, newKey := newOrganizationKey(t)
+	data := []byte(testPayload)
+	server := &keysServer{}
+	client, store := newKeysClient(t, server)
+
+	// Without keys.json the configured keys are used
+	verifier, err := client.rotateKeys(SSHVerifier{Keys: []string{oldKey}})
+	if err != nil {
+		t.Fatalf("Failed to rotate the keys: %v", err)
+	}
+	if err := verifier.Verify(data, signSSH(t, oldSigner, data)); err != nil {
+		t.Errorf("Expected the configured key to be accepted: %v", err)
+	}
+
+	server.publish(t, oldSigner, KeyManifest{Version: 1, Keys: []string{newKey}, Revoked: []string{oldKey}})
+	verifier, err = client.rotateKeys(SSHVerifier{Keys: []string{oldKey}})
+	if err != nil {
+		t.Fatalf("Failed to rotate the keys: %v", err)
+	}
+	if err := verifier.Verify(data, signSSH(t, newSigner, data)); err != nil {
+		t.Errorf("Expected the new key to be accepted: %v", err)
+	}
+	if err := verifier.Verify(data, signSSH(t, oldSigner, data)); err == nil {
+		t.Error("Expected the revoked key to be rejected")
+	}
+	if accepted, err := store.Load(mustEndpoints(t, client).BaseURL); err != nil || accepted == nil || accepted.Version != 1 {
+		t.Fatalf("Expected the manifest to be stored, got %+v: %v", accepted, err)
+	}
+
+	// The revoked key cannot sign the next manifest, and stays revoked when a newer manifest forgets it
+	server.publish(t, oldSigner, KeyManifest{Version: 2, Keys: []string{oldKey, newKey}})
+	var invalid *devrigErrors.SignatureInvalidError
+	if _, err := client.rotateKeys(SSHVerifier{Keys: []string{oldKey}}); !errors.As(err, &invalid) {
+		t.Errorf("Expected a manifest of the revoked key to be rejected, got: %v", err)
+	}
+	server.publish(t, newSigner, KeyManifest{Version: 2, Keys: []string{oldKey, newKey}})
+	verifier, err = client.rotateKeys(SSHVerifier{Keys: []string{oldKey}})
+	if err != nil {
+		t.Fatalf("Failed to rotate the keys: %v", err)
+	}
+	if err := verifier.Verify(data, signSSH(t, oldSigner, data)); err == nil {
+		t.Error("Expected the revoked key to stay revoked")
+	}
+
+	// An older manifest is a rollback, even with a valid signature
+	server.publish(t, newSigner, KeyManifest{Version: 1, Keys: []string{newKey}})
+	if _, err := client.rotateKeys(SSHVerifier{Keys: []string{oldKey}}); !errors.As(err, &invalid) {
+		t.Errorf("Expected an older manifest to be rejected, got: %v", err)
+	}
+
+	// Without keys.json the accepted keys stay
+	server.manifest = nil
+	verifier, err = client.rotateKeys(SSHVerifier{Keys: []string{oldKey}})
+	if err != nil {
+		t.Fatalf("Failed to rotate the keys: %v", err)
+	}
+	if err := verifier.Verify(data, signSSH(t, oldSigner, data)); err == nil {
+		t.Error("Expected the revoked key to be rejected without keys.json")
+	}
+}
+
+func TestRotateKeys_UntrustedSigner(t *testing.T) {
+	signer, key := newOrganizationKey(t)
+	server := &keysServer{}
+	client, store := newKeysClient(t, server)
+
+	server.publish(t, signer, KeyManifest{Version: 1, Keys: []string{key}})
+	var invalid *devrigErrors.SignatureInvalidError
+	if _, err := client.rotateKeys(SSHVerifier{}); !errors.As(err, &invalid) {
+		t.Fatalf("Expected a manifest of an untrusted key to be rejected, got: %v", err)
+	}
+	if accepted, _ := store.Load(mustEndpoints(t, client).BaseURL); accepted != nil {
+		t.Errorf("Expected the rejected manifest not to be stored, got %+v", accepted)
+	}
+}
+
+func TestRotateKeys_ServerFailures(t *testing.T) {
+	signer, key := newOrganizationKey(t)
+	data := []byte(testPayload)
+	status := http.StatusServiceUnavailable
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer httpServer.Close()
+
+	client := &Client{
+		downloader: NewDownloader(),
+		endpoints: func() (Endpoints, error) {
+			return Endpoints{BaseURL: httpServer.URL + "/", LatestURL: httpServer.URL + "/latest.json"}, nil
+		},
+		keys: &KeyStore{Dir: t.TempDir()},
+	}
+	check := func(name string) {
+		t.Helper()
+		verifier, err := client.rotateKeys(SSHVerifier{Keys: []string{key}})
+		if err != nil {
+			t.Fatalf("Expected the configured keys with %s, got: %v", name, err)
+		}
+		if err := verifier.Verify(data, signSSH(t, signer, data)); err != nil {
+			t.Errorf("Expected the configured key to be accepted with %s: %v", name, err)
+		}
+	}
+
+	check("a server error")
+	status = http.StatusForbidden
+	check("a missing file of an object store")
+
+	// The server is gone, e.g. a timeout or a refused connection
+	httpServer.Close()
+	check("an unreachable server")
+}
+
+func TestParseKeyManifest(t *testing.T) {
+	_, key := newOrganizationKey(t)
+	for _, content := range []string{
+		`{"version": 0, "keys": ["` + key + `"]}`,
+		`{"version": 1, "keys": []}`,
+		`{"version": 1, "keys": ["not a key"]}`,
+		`{"version": 1, "keys": ["` + key + `"], "revoked": ["not a key"]}`,
+	} {
+		if _, err := ParseKeyManifest([]byte(content)); err == nil {
+			t.Errorf("Expected an error for %s", content)
+		}
+	}
+}
+
+func mustEndpoints(t *testing.T, client *Client) Endpoints {
+	t.Helper()
+	endpoints, err := client.Endpoints()
+	if err != nil {
+		t.Fatalf("Failed to resolve the endpoints: %v", err)
+	}
+	return endpoints
+}
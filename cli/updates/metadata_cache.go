@@ -0,0 +1,79 @@
+package updates
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// MetadataCache keeps the verified release metadata of the channels, so it is available without network access
+type MetadataCache struct {
+	Dir string
+}
+
+// cachedUpdateInfo is the file format of the cache
+type cachedUpdateInfo struct {
+	FetchedAt time.Time   `json:"fetched_at"`
+	Info      *UpdateInfo `json:"info"`
+}
+
+// DefaultMetadataCache returns the cache in the devrig folder of the user cache directory
+func DefaultMetadataCache() (*MetadataCache, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve user cache directory: %w", err)
+	}
+	return &MetadataCache{Dir: filepath.Join(cacheDir, "devrig", "metadata")}, nil
+}
+
+func (c *MetadataCache) path(channel string) string {
+	if channel == "" {
+		channel = configservice.ChannelStable
+	}
+	return filepath.Join(c.Dir, "latest-"+channel+".json")
+}
+
+// Save stores the metadata of the channel, it must be verified by the Client before
+func (c *MetadataCache) Save(channel string, info *UpdateInfo, fetchedAt time.Time) error {
+	if err := configservice.ValidateChannel(channel); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cachedUpdateInfo{FetchedAt: fetchedAt.UTC(), Info: info}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal release metadata: %w", err)
+	}
+
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create metadata cache: %w", err)
+	}
+	if err := tempfile.WriteFile(c.path(channel), data, 0644); err != nil {
+		return fmt.Errorf("failed to save release metadata: %w", err)
+	}
+	return nil
+}
+
+// Load returns the cached metadata of the channel and the time it was fetched, or nil if there is none
+func (c *MetadataCache) Load(channel string) (*UpdateInfo, time.Time, error) {
+	if err := configservice.ValidateChannel(channel); err != nil {
+		return nil, time.Time{}, err
+	}
+	data, err := os.ReadFile(c.path(channel))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, time.Time{}, nil
+		}
+		return nil, time.Time{}, fmt.Errorf("failed to read release metadata: %w", err)
+	}
+
+	var cached cachedUpdateInfo
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Info == nil {
+		// A broken cache is the same as no cache
+		return nil, time.Time{}, nil
+	}
+	return cached.Info, cached.FetchedAt, nil
+}
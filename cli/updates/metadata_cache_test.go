@@ -0,0 +1,35 @@
+package updates
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMetadataCache(t *testing.T) {
+	cache := &MetadataCache{Dir: t.TempDir()}
+
+	info, _, err := cache.Load("beta")
+	if err != nil {
+		t.Fatalf("Failed to load empty cache: %v", err)
+	}
+	if info != nil {
+		t.Errorf("Expected no metadata, got: %+v", info)
+	}
+
+	fetchedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := cache.Save("beta", &UpdateInfo{Version: "1.2.3", Channel: "beta"}, fetchedAt); err != nil {
+		t.Fatalf("Failed to save metadata: %v", err)
+	}
+
+	info, loadedAt, err := cache.Load("beta")
+	if err != nil {
+		t.Fatalf("Failed to load metadata: %v", err)
+	}
+	if info == nil || info.Version != "1.2.3" || !loadedAt.Equal(fetchedAt) {
+		t.Errorf("Unexpected metadata: %+v at %v", info, loadedAt)
+	}
+
+	if err := cache.Save("../evil", &UpdateInfo{}, fetchedAt); err == nil {
+		t.Errorf("Expected an unknown channel to be rejected")
+	}
+}
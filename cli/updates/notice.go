@@ -0,0 +1,49 @@
+package updates
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NoticePrefix starts the machine-readable update notice line of `devrig update-notice`.
+// The bootstrap scripts look for it, the format is part of the contract with them and must stay stable:
+//
+//	##devrig[update-available] current=0.79.6 latest=0.80.0 channel=stable
+const NoticePrefix = "##devrig[update-available]"
+
+// Notice tells that a newer devrig than the version pinned in devrig.yaml is released
+type Notice struct {
+	Current string
+	Latest  string
+	Channel string
+}
+
+// String formats the notice line the bootstrap scripts recognize
+func (n Notice) String() string {
+	return fmt.Sprintf("%s current=%s latest=%s channel=%s", NoticePrefix, n.Current, n.Latest, n.Channel)
+}
+
+// ParseNotice parses a notice line, unknown fields are ignored so the line can be extended
+func ParseNotice(line string) (*Notice, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), NoticePrefix)
+	if !ok {
+		return nil, false
+	}
+
+	var notice Notice
+	for _, field := range strings.Fields(rest) {
+		key, value, _ := strings.Cut(field, "=")
+		switch key {
+		case "current":
+			notice.Current = value
+		case "latest":
+			notice.Latest = value
+		case "channel":
+			notice.Channel = value
+		}
+	}
+	if notice.Latest == "" {
+		return nil, false
+	}
+	return &notice, true
+}
@@ -0,0 +1,24 @@
+package updates
+
+import "testing"
+
+func TestNotice_RoundTrip(t *testing.T) {
+	notice := Notice{Current: "0.79.5", Latest: "0.80.0", Channel: "beta"}
+	line := notice.String()
+	if line != "##devrig[update-available] current=0.79.5 latest=0.80.0 channel=beta" {
+		t.Errorf("Unexpected notice line: %s", line)
+	}
+
+	parsed, ok := ParseNotice(line + " future=1\n")
+	if !ok || *parsed != notice {
+		t.Errorf("Expected %+v, got %+v", notice, parsed)
+	}
+}
+
+func TestParseNotice_Rejects(t *testing.T) {
+	for _, line := range []string{"", "Update available", "##devrig[update-available]", "x ##devrig[update-available] latest=1"} {
+		if _, ok := ParseNotice(line); ok {
+			t.Errorf("Expected %q to be no notice", line)
+		}
+	}
+}
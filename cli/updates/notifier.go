@@ -0,0 +1,146 @@
+package updates
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/tempfile"
+)
+
+// EnvNoUpdateCheck disables the update notifications of commands when set to 1 or true.
+// The --no-updates flag sets it too
+const EnvNoUpdateCheck = "DEVRIG_NO_UPDATE_CHECK"
+
+// checkInterval is the time between the checks of the daily mode
+const checkInterval = 24 * time.Hour
+
+// ResolveUpdateCheck returns how often commands check for a newer devrig: never with DEVRIG_NO_UPDATE_CHECK
+// or in offline mode, otherwise settings.update_check of devrig.yaml, daily when it is not set
+func ResolveUpdateCheck(configs configservice.ConfigService) string {
+	if envEnabled(EnvNoUpdateCheck) || offline.Enabled() {
+		return configservice.UpdateCheckNever
+	}
+	section, err := configs.Settings().ReadSettings()
+	var notFound *devrigErrors.ConfigNotFoundError
+	if err != nil && !errors.As(err, &notFound) {
+		// A broken devrig.yaml is reported by the commands that read it, a notification is not worth more noise
+		return configservice.UpdateCheckNever
+	}
+	if err != nil || section.UpdateCheck == "" {
+		return configservice.UpdateCheckDaily
+	}
+	return section.UpdateCheck
+}
+
+// Notifier checks for a newer devrig in the background while a command runs and announces it after the output of
+// the command. It never delays the command: a check that has not finished by then is dropped and repeated next time
+type Notifier struct {
+	Service UpdateService
+	// StatePath keeps the time of the last check for the daily mode, every command checks when it is empty
+	StatePath string
+
+	done       chan struct{}
+	comparison *Comparison
+}
+
+// notifierState is the file format of the state
+type notifierState struct {
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// NewNotifier creates the notifier with the state in the devrig folder of the user cache directory
+func NewNotifier(service UpdateService) *Notifier {
+	notifier := &Notifier{Service: service}
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		notifier.StatePath = filepath.Join(cacheDir, "devrig", "update-check.json")
+	}
+	return notifier
+}
+
+// Start checks for a newer devrig in the background, unless the mode is never or the daily check is already done
+func (n *Notifier) Start(ctx context.Context, mode string, now time.Time) {
+	logger := logging.FromContext(ctx)
+	if mode == configservice.UpdateCheckNever {
+		return
+	}
+	if mode != configservice.UpdateCheckAlways && now.Sub(n.lastCheck()) < checkInterval {
+		logger.Debug("skipping the update check, devrig checked for updates in the last day")
+		return
+	}
+
+	done := make(chan struct{})
+	n.done = done
+	go func() {
+		defer close(done)
+		comparison, err := n.Service.CompareWithLatest()
+		if err != nil {
+			logger.Debug("failed to check for updates", "error", err)
+		}
+		n.comparison = comparison
+		// A newer release is recorded once it is announced, a failure is not retried until the next day either
+		if comparison == nil || comparison.Banner() == "" {
+			n.record(ctx, now)
+		}
+	}()
+}
+
+// Finish prints the announcement of a newer devrig if the check has finished, it never waits for the check
+func (n *Notifier) Finish(ctx context.Context, out io.Writer, now time.Time) {
+	if n.done == nil {
+		return
+	}
+	select {
+	case <-n.done:
+	default:
+		logging.FromContext(ctx).Debug("the update check has not finished, it is repeated by the next command")
+		return
+	}
+	if n.comparison == nil || n.comparison.Banner() == "" {
+		return
+	}
+	_, _ = fmt.Fprintf(out, "\n%s\n", n.comparison.Banner())
+	n.record(ctx, now)
+}
+
+// lastCheck returns the time of the last check, zero if there is no state
+func (n *Notifier) lastCheck() time.Time {
+	if n.StatePath == "" {
+		return time.Time{}
+	}
+	data, err := os.ReadFile(n.StatePath)
+	if err != nil {
+		return time.Time{}
+	}
+	var state notifierState
+	if err := json.Unmarshal(data, &state); err != nil {
+		// A broken state is the same as no state
+		return time.Time{}
+	}
+	return state.CheckedAt
+}
+
+func (n *Notifier) record(ctx context.Context, now time.Time) {
+	if n.StatePath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(notifierState{CheckedAt: now.UTC()}, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(n.StatePath), 0755)
+	}
+	if err == nil {
+		err = tempfile.WriteFile(n.StatePath, data, 0644)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Debug("failed to save the time of the update check", "error", err)
+	}
+}
@@ -0,0 +1,124 @@
+package updates
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/offline"
+)
+
+// countingUpdateService counts the checks, a check waits for release when it is set
+type countingUpdateService struct {
+	UpdateService
+	checks  int
+	release chan struct{}
+}
+
+func (s *countingUpdateService) CompareWithLatest() (*Comparison, error) {
+	s.checks++
+	if s.release != nil {
+		<-s.release
+	}
+	return s.UpdateService.CompareWithLatest()
+}
+
+func newTestNotifier(t *testing.T, running string) (*Notifier, *countingUpdateService) {
+	t.Helper()
+	service := &countingUpdateService{UpdateService: newTestUpdateService(running, &UpdateInfo{Version: "0.80.0"})}
+	return &Notifier{Service: service, StatePath: filepath.Join(t.TempDir(), "update-check.json")}, service
+}
+
+// finish waits for the background check and returns the announcement
+func finish(notifier *Notifier, now time.Time) string {
+	if notifier.done != nil {
+		<-notifier.done
+	}
+	var out bytes.Buffer
+	notifier.Finish(context.Background(), &out, now)
+	return out.String()
+}
+
+func TestNotifier_Daily(t *testing.T) {
+	notifier, service := newTestNotifier(t, "0.79.6")
+	now := time.Now()
+
+	notifier.Start(context.Background(), configservice.UpdateCheckDaily, now)
+	if text := finish(notifier, now); !strings.Contains(text, "Update available: devrig 0.80.0") {
+		t.Errorf("Expected the announcement, got %q", text)
+	}
+
+	// The next commands of the day do not check again
+	next := &Notifier{Service: service, StatePath: notifier.StatePath}
+	next.Start(context.Background(), configservice.UpdateCheckDaily, now.Add(time.Hour))
+	if text := finish(next, now.Add(time.Hour)); text != "" || service.checks != 1 {
+		t.Errorf("Expected no check within a day, got %d checks: %q", service.checks, text)
+	}
+
+	tomorrow := &Notifier{Service: service, StatePath: notifier.StatePath}
+	tomorrow.Start(context.Background(), configservice.UpdateCheckDaily, now.Add(25*time.Hour))
+	if text := finish(tomorrow, now.Add(25*time.Hour)); text == "" || service.checks != 2 {
+		t.Errorf("Expected the check on the next day, got %d checks: %q", service.checks, text)
+	}
+
+	always := &Notifier{Service: service, StatePath: notifier.StatePath}
+	always.Start(context.Background(), configservice.UpdateCheckAlways, now.Add(25*time.Hour))
+	never := &Notifier{Service: service, StatePath: t.TempDir()}
+	never.Start(context.Background(), configservice.UpdateCheckNever, now)
+	if finish(always, now) == "" || finish(never, now) != "" || service.checks != 3 {
+		t.Errorf("Expected only the always mode to check, got %d checks", service.checks)
+	}
+}
+
+func TestNotifier_NeverWaits(t *testing.T) {
+	notifier, service := newTestNotifier(t, "0.79.6")
+	service.release = make(chan struct{})
+
+	notifier.Start(context.Background(), configservice.UpdateCheckAlways, time.Now())
+	finished := make(chan string)
+	go func() {
+		var out bytes.Buffer
+		notifier.Finish(context.Background(), &out, time.Now())
+		finished <- out.String()
+	}()
+	select {
+	case text := <-finished:
+		if text != "" {
+			t.Errorf("Expected no announcement of the unfinished check, got %q", text)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected Finish not to wait for the check")
+	}
+	if _, err := os.Stat(notifier.StatePath); !os.IsNotExist(err) {
+		t.Errorf("Expected the unfinished check to be repeated by the next command: %v", err)
+	}
+	close(service.release)
+	<-notifier.done
+}
+
+func TestResolveUpdateCheck(t *testing.T) {
+	t.Setenv(EnvNoUpdateCheck, "")
+	t.Setenv(offline.EnvOffline, "")
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	configs := configservice.NewConfigService(configPath)
+	if mode := ResolveUpdateCheck(configs); mode != configservice.UpdateCheckDaily {
+		t.Errorf("Expected the daily check without devrig.yaml, got %s", mode)
+	}
+
+	if err := os.WriteFile(configPath, []byte("settings:\n  update_check: always\n"), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	if mode := ResolveUpdateCheck(configs); mode != configservice.UpdateCheckAlways {
+		t.Errorf("Expected the mode of devrig.yaml, got %s", mode)
+	}
+
+	t.Setenv(EnvNoUpdateCheck, "1")
+	if mode := ResolveUpdateCheck(configs); mode != configservice.UpdateCheckNever {
+		t.Errorf("Expected %s to disable the check, got %s", EnvNoUpdateCheck, mode)
+	}
+}
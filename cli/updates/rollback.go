@@ -0,0 +1,137 @@
+package updates
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+	"jonnyzzz.com/devrig.dev/tempfile"
+	"jonnyzzz.com/devrig.dev/version"
+)
+
+// EnvAllowDowngrade accepts release metadata older than the one verified before when set to 1 or true.
+// The --allow-downgrade flag sets it too
+const EnvAllowDowngrade = "DEVRIG_ALLOW_DOWNGRADE"
+
+// allowDowngrade checks if DEVRIG_ALLOW_DOWNGRADE is set
+func allowDowngrade() bool {
+	return envEnabled(EnvAllowDowngrade)
+}
+
+// envEnabled checks if the variable is set to 1, true or yes
+func envEnabled(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv(name))) {
+	case "1", "true", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+// VersionStore keeps the highest version of the release metadata verified from every URL, so a replayed
+// older latest.json with a valid signature cannot roll devrig back to a version with known vulnerabilities
+type VersionStore struct {
+	Path string
+
+	mutex sync.Mutex
+}
+
+// DefaultVersionStore returns the store in the devrig folder of the user cache directory
+func DefaultVersionStore() (*VersionStore, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve user cache directory: %w", err)
+	}
+	return &VersionStore{Path: filepath.Join(cacheDir, "devrig", "versions.json")}, nil
+}
+
+// defaultVersionStore returns the version store of the user, versions are not recorded without the user cache directory
+func defaultVersionStore() *VersionStore {
+	store, err := DefaultVersionStore()
+	if err != nil {
+		return nil
+	}
+	return store
+}
+
+func (s *VersionStore) load() (map[string]string, error) {
+	versions := map[string]string{}
+	data, err := os.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return versions, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the verified versions: %w", err)
+	}
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, fmt.Errorf("failed to parse the verified versions %s: %w", s.Path, err)
+	}
+	return versions, nil
+}
+
+// Highest returns the highest version verified from the URL, or an empty string if there is none
+func (s *VersionStore) Highest(url string) (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	versions, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	return versions[url], nil
+}
+
+// Record stores the verified version of the URL, it replaces a higher one only when downgrade is set
+func (s *VersionStore) Record(url string, verified string, downgrade bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	versions, err := s.load()
+	if err != nil {
+		return err
+	}
+	if current, ok := versions[url]; ok && !downgrade {
+		if order, err := version.Compare(verified, current); err != nil || order <= 0 {
+			return nil
+		}
+	}
+	versions[url] = verified
+
+	data, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the verified versions: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create the folder of the verified versions: %w", err)
+	}
+	if err := tempfile.WriteFile(s.Path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save the verified versions: %w", err)
+	}
+	return nil
+}
+
+// checkRollback rejects the verified metadata of the URL when its version is lower than the one verified before,
+// unless DEVRIG_ALLOW_DOWNGRADE is set. The version of accepted metadata is recorded
+func (c *Client) checkRollback(url string, info *UpdateInfo) error {
+	if c.versions == nil {
+		return nil
+	}
+	highest, err := c.versions.Highest(url)
+	if err != nil {
+		return err
+	}
+	downgrade := false
+	if highest != "" {
+		if order, err := version.Compare(info.Version, highest); err == nil && order < 0 {
+			if !allowDowngrade() {
+				return &devrigErrors.SignatureInvalidError{Subject: url, Err: fmt.Errorf(
+					"version %s is older than the version %s verified before, the release metadata may be replayed by an attacker; "+
+						"run with --allow-downgrade if the release was withdrawn", info.Version, highest)}
+			}
+			downgrade = true
+		}
+	}
+	return c.versions.Record(url, info.Version, downgrade)
+}
@@ -0,0 +1,68 @@
+package updates
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+func TestClient_FetchUpdateInfo_Rollback(t *testing.T) {
+	t.Setenv(EnvAllowDowngrade, "")
+	signer, key := newOrganizationKey(t)
+	var metadata []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest.json.sig" {
+			_, _ = w.Write(signSSH(t, signer, metadata))
+			return
+		}
+		_, _ = w.Write(metadata)
+	}))
+	defer server.Close()
+
+	store := &VersionStore{Path: filepath.Join(t.TempDir(), "versions.json")}
+	// Every invocation of devrig has a new client, a client fetches a URL only once
+	fetch := func(url string) (*UpdateInfo, error) {
+		client := &Client{
+			downloader: NewDownloader(),
+			endpoints:  func() (Endpoints, error) { return DefaultEndpoints, nil },
+			verifier:   func() (Verifier, error) { return SSHVerifier{Keys: []string{key}}, nil },
+			versions:   store,
+		}
+		return client.FetchUpdateInfo(url)
+	}
+	url := server.URL + "/latest.json"
+
+	for _, version := range []string{"0.79.6", "0.80.0", "0.80.0"} {
+		metadata = []byte(`{"version": "` + version + `", "binaries": []}`)
+		if _, err := fetch(url); err != nil {
+			t.Fatalf("Failed to fetch %s: %v", version, err)
+		}
+	}
+	if highest, err := store.Highest(url); err != nil || highest != "0.80.0" {
+		t.Fatalf("Expected 0.80.0 to be recorded, got %q: %v", highest, err)
+	}
+
+	// A validly signed latest.json of an older release is a replay
+	metadata = []byte(`{"version": "0.79.6", "binaries": []}`)
+	var invalid *devrigErrors.SignatureInvalidError
+	if _, err := fetch(url); !errors.As(err, &invalid) {
+		t.Fatalf("Expected the older metadata to be rejected, got: %v", err)
+	}
+
+	t.Setenv(EnvAllowDowngrade, "1")
+	if info, err := fetch(url); err != nil || info.Version != "0.79.6" {
+		t.Fatalf("Expected the downgrade to be allowed, got %+v: %v", info, err)
+	}
+	if highest, err := store.Highest(url); err != nil || highest != "0.79.6" {
+		t.Errorf("Expected the allowed downgrade to be recorded, got %q: %v", highest, err)
+	}
+
+	// Other URLs, e.g. the metadata of a pinned release, have their own versions
+	if highest, err := store.Highest(server.URL + "/releases/0.70.0.json"); err != nil || highest != "" {
+		t.Errorf("Expected no version of another URL, got %q: %v", highest, err)
+	}
+}
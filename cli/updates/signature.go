@@ -2,6 +2,7 @@ package updates
 
 import (
 	"bytes"
+	"crypto/rand"
 	"crypto/sha512"
 	_ "embed"
 	"encoding/base64"
@@ -19,6 +20,16 @@ var key1Content string
 //go:embed key2.txt
 var key2Content string
 
+const (
+	// maxSignatureSize limits the armored signature, real signatures are below 2 KiB
+	maxSignatureSize = 64 * 1024
+	// maxSSHStringLength limits a length-prefixed string of the signature blob,
+	// so a malicious length prefix cannot force a huge allocation
+	maxSSHStringLength = 16 * 1024
+	// sshSignatureVersion is the only supported version of the SSHSIG format
+	sshSignatureVersion = 1
+)
+
 // TrustedPublicKeys contains the trusted SSH public keys for signature verification
 // Keys are loaded from embedded resources
 var TrustedPublicKeys = []string{
@@ -28,6 +39,11 @@ var TrustedPublicKeys = []string{
 
 // VerifySignature verifies the SSH signature of the data using trusted public keys
 func VerifySignature(data []byte, signatureData []byte) error {
+	return VerifySignatureWithKeys(data, signatureData, TrustedPublicKeys)
+}
+
+// VerifySignatureWithKeys verifies the SSH signature of the data with one of the keys in the authorized_keys format
+func VerifySignatureWithKeys(data []byte, signatureData []byte, keys []string) error {
 	// Parse the SSH signature format
 	sig, err := parseSSHSignature(signatureData)
 	if err != nil {
@@ -36,7 +52,7 @@ func VerifySignature(data []byte, signatureData []byte) error {
 
 	// Try each trusted public key
 	var lastErr error
-	for i, keyStr := range TrustedPublicKeys {
+	for i, keyStr := range keys {
 		pubKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(keyStr))
 		if err != nil {
 			lastErr = fmt.Errorf("failed to parse public key %d: %w", i, err)
@@ -72,6 +88,58 @@ func VerifySignature(data []byte, signatureData []byte) error {
 	return fmt.Errorf("no valid trusted public keys found")
 }
 
+// VerifySignatureInNamespace verifies the SSH signature like VerifySignatureWithKeys and checks that it was made
+// for the namespace, so a signature of another purpose, e.g. of a git commit, is not accepted
+func VerifySignatureInNamespace(data []byte, signatureData []byte, keys []string, namespace string) error {
+	sig, err := parseSSHSignature(signatureData)
+	if err != nil {
+		return fmt.Errorf("failed to parse SSH signature: %w", err)
+	}
+	if sig.namespace != namespace {
+		return fmt.Errorf("the signature is made for namespace %q, expected %q", sig.namespace, namespace)
+	}
+	return VerifySignatureWithKeys(data, signatureData, keys)
+}
+
+// SignSSH signs the data in the armored format of `ssh-keygen -Y sign -n <namespace>`,
+// RSA keys sign with rsa-sha2-512 like ssh-keygen does
+func SignSSH(signer ssh.Signer, data []byte, namespace string) ([]byte, error) {
+	hash := sha512.Sum512(data)
+	var message bytes.Buffer
+	message.WriteString("SSHSIG")
+	for _, field := range [][]byte{[]byte(namespace), {}, []byte("sha512"), hash[:]} {
+		_ = writeString(&message, field)
+	}
+
+	var signature *ssh.Signature
+	var err error
+	if algorithmSigner, ok := signer.(ssh.AlgorithmSigner); ok && signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, message.Bytes(), ssh.KeyAlgoRSASHA512)
+	} else {
+		signature, err = signer.Sign(rand.Reader, message.Bytes())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+
+	var blob bytes.Buffer
+	blob.WriteString("SSHSIG")
+	_ = binary.Write(&blob, binary.BigEndian, uint32(sshSignatureVersion))
+	for _, field := range [][]byte{signer.PublicKey().Marshal(), []byte(namespace), {}, []byte("sha512"), ssh.Marshal(signature)} {
+		_ = writeString(&blob, field)
+	}
+
+	var armored strings.Builder
+	armored.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	encoded := base64.StdEncoding.EncodeToString(blob.Bytes())
+	for len(encoded) > 70 {
+		armored.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	armored.WriteString(encoded + "\n-----END SSH SIGNATURE-----\n")
+	return []byte(armored.String()), nil
+}
+
 // sshSignature represents a parsed SSH signature
 type sshSignature struct {
 	namespace     string
@@ -81,6 +149,10 @@ type sshSignature struct {
 
 // parseSSHSignature parses an SSH signature in armored format
 func parseSSHSignature(data []byte) (*sshSignature, error) {
+	if len(data) > maxSignatureSize {
+		return nil, fmt.Errorf("invalid SSH signature format: the signature is larger than %d bytes", maxSignatureSize)
+	}
+
 	// Find the signature block
 	beginMarker := []byte("-----BEGIN SSH SIGNATURE-----")
 	endMarker := []byte("-----END SSH SIGNATURE-----")
@@ -91,6 +163,9 @@ func parseSSHSignature(data []byte) (*sshSignature, error) {
 	if beginIdx == -1 || endIdx == -1 {
 		return nil, fmt.Errorf("invalid SSH signature format: missing markers")
 	}
+	if endIdx < beginIdx+len(beginMarker) {
+		return nil, fmt.Errorf("invalid SSH signature format: the end marker precedes the begin marker")
+	}
 
 	// Extract base64 data
 	base64Data := data[beginIdx+len(beginMarker) : endIdx]
@@ -126,6 +201,9 @@ func parseSSHSignatureBlob(blob []byte) (*sshSignature, error) {
 	if err := binary.Read(buf, binary.BigEndian, &version); err != nil {
 		return nil, fmt.Errorf("failed to read version: %w", err)
 	}
+	if version != sshSignatureVersion {
+		return nil, fmt.Errorf("unsupported signature version: %d", version)
+	}
 
 	// Read public key
 	_, err := readString(buf)
@@ -168,6 +246,9 @@ func parseSSHSignatureBlob(blob []byte) (*sshSignature, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read signature data: %w", err)
 	}
+	if len(sigFormat) == 0 || len(sigData) == 0 {
+		return nil, fmt.Errorf("invalid signature: empty format or data")
+	}
 
 	sig := &ssh.Signature{
 		Format: string(sigFormat),
@@ -181,12 +262,19 @@ func parseSSHSignatureBlob(blob []byte) (*sshSignature, error) {
 	}, nil
 }
 
-// readString reads a length-prefixed string from the reader
-func readString(r io.Reader) ([]byte, error) {
+// readString reads a length-prefixed string from the reader.
+// The length is checked against the remaining input before anything is allocated
+func readString(r *bytes.Reader) ([]byte, error) {
 	var length uint32
 	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
 		return nil, err
 	}
+	if length > maxSSHStringLength {
+		return nil, fmt.Errorf("string length %d exceeds the limit of %d bytes", length, maxSSHStringLength)
+	}
+	if int64(length) > int64(r.Len()) {
+		return nil, fmt.Errorf("string length %d exceeds the remaining %d bytes", length, r.Len())
+	}
 
 	data := make([]byte, length)
 	if _, err := io.ReadFull(r, data); err != nil {
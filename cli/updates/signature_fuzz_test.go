@@ -0,0 +1,104 @@
+package updates
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func FuzzParseSSHSignature(f *testing.F) {
+	f.Add(key1Signature)
+	f.Add(key2Signature)
+	f.Add([]byte("-----END SSH SIGNATURE-----\n-----BEGIN SSH SIGNATURE-----"))
+	f.Add([]byte("-----BEGIN SSH SIGNATURE-----\nU1NIU0lH\n-----END SSH SIGNATURE-----"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		sig, err := parseSSHSignature(data)
+		if err == nil && (sig == nil || sig.signature == nil) {
+			t.Fatalf("Expected a signature when there is no error")
+		}
+	})
+}
+
+func FuzzParseSSHSignatureBlob(f *testing.F) {
+	for _, armored := range [][]byte{key1Signature, key2Signature} {
+		f.Add(decodeArmoredSignature(f, armored))
+	}
+	f.Add([]byte("SSHSIG"))
+	f.Add(append([]byte("SSHSIG\x00\x00\x00\x01"), 0xff, 0xff, 0xff, 0xff))
+
+	f.Fuzz(func(t *testing.T, blob []byte) {
+		sig, err := parseSSHSignatureBlob(blob)
+		if err == nil && (sig == nil || sig.signature == nil) {
+			t.Fatalf("Expected a signature when there is no error")
+		}
+	})
+}
+
+func TestParseSSHSignature_EndMarkerFirst(t *testing.T) {
+	_, err := parseSSHSignature([]byte("-----END SSH SIGNATURE-----\n-----BEGIN SSH SIGNATURE-----"))
+	if err == nil {
+		t.Fatal("Expected an error for swapped markers, got nil")
+	}
+}
+
+func TestParseSSHSignature_TooLarge(t *testing.T) {
+	data := []byte("-----BEGIN SSH SIGNATURE-----\n" + strings.Repeat("A", maxSignatureSize) + "\n-----END SSH SIGNATURE-----")
+	_, err := parseSSHSignature(data)
+	if err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("Expected a size limit error, got %v", err)
+	}
+}
+
+func TestParseSSHSignatureBlob_HugeLengthPrefix(t *testing.T) {
+	var blob bytes.Buffer
+	blob.WriteString("SSHSIG")
+	_ = binary.Write(&blob, binary.BigEndian, uint32(sshSignatureVersion))
+	// The public key claims 4 GiB, the parser must fail before allocating it
+	_ = binary.Write(&blob, binary.BigEndian, uint32(0xffffffff))
+
+	_, err := parseSSHSignatureBlob(blob.Bytes())
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Fatalf("Expected a length limit error, got %v", err)
+	}
+}
+
+func TestParseSSHSignatureBlob_TruncatedString(t *testing.T) {
+	var blob bytes.Buffer
+	blob.WriteString("SSHSIG")
+	_ = binary.Write(&blob, binary.BigEndian, uint32(sshSignatureVersion))
+	_ = binary.Write(&blob, binary.BigEndian, uint32(100))
+	blob.WriteString("short")
+
+	_, err := parseSSHSignatureBlob(blob.Bytes())
+	if err == nil || !strings.Contains(err.Error(), "remaining") {
+		t.Fatalf("Expected a truncated string error, got %v", err)
+	}
+}
+
+func TestParseSSHSignatureBlob_UnsupportedVersion(t *testing.T) {
+	blob := decodeArmoredSignature(t, key1Signature)
+	binary.BigEndian.PutUint32(blob[6:10], 2)
+
+	_, err := parseSSHSignatureBlob(blob)
+	if err == nil || !strings.Contains(err.Error(), "unsupported signature version") {
+		t.Fatalf("Expected a version error, got %v", err)
+	}
+}
+
+// decodeArmoredSignature returns the binary blob of the armored SSH signature
+func decodeArmoredSignature(tb testing.TB, armored []byte) []byte {
+	tb.Helper()
+	text := string(armored)
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "-----BEGIN SSH SIGNATURE-----")
+	text = strings.TrimSuffix(text, "-----END SSH SIGNATURE-----")
+	text = strings.Join(strings.Fields(text), "")
+	blob, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		tb.Fatalf("Failed to decode signature: %v", err)
+	}
+	return blob
+}
@@ -0,0 +1,455 @@
+package updates
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SigstoreBundleSuffix is appended to the URL of the metadata to download its bundle,
+// e.g. `cosign sign-blob --bundle latest.json.sigstore.json latest.json`
+const SigstoreBundleSuffix = ".sigstore.json"
+
+// maxBundleSize limits the bundle, real bundles with the certificate chain are below 16 KiB
+const maxBundleSize = 256 * 1024
+
+// The issuer extensions of Fulcio certificates, the first is a raw string, the second a DER UTF8String
+var (
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// TrustedRoot is the trust material of a Sigstore instance, the trusted_root.json of its TUF repository
+// or of `cosign trusted-root create`
+type TrustedRoot struct {
+	Tlogs                  []trustedTlog `json:"tlogs"`
+	CertificateAuthorities []trustedCA   `json:"certificateAuthorities"`
+}
+
+type trustedTlog struct {
+	BaseURL   string `json:"baseUrl"`
+	PublicKey struct {
+		RawBytes []byte   `json:"rawBytes"`
+		ValidFor validity `json:"validFor"`
+	} `json:"publicKey"`
+	LogID struct {
+		KeyID []byte `json:"keyId"`
+	} `json:"logId"`
+}
+
+type trustedCA struct {
+	URI       string `json:"uri"`
+	CertChain struct {
+		Certificates []struct {
+			RawBytes []byte `json:"rawBytes"`
+		} `json:"certificates"`
+	} `json:"certChain"`
+	ValidFor validity `json:"validFor"`
+}
+
+// validity is the period a key or a certificate authority signs in, a zero end is open
+type validity struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+func (v validity) contains(t time.Time) bool {
+	return !t.Before(v.Start) && (v.End.IsZero() || !t.After(v.End))
+}
+
+// LoadTrustedRoot reads the trusted_root.json file
+func LoadTrustedRoot(path string) (*TrustedRoot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the Sigstore trusted root: %w", err)
+	}
+	var root TrustedRoot
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse the Sigstore trusted root %s: %w", path, err)
+	}
+	if len(root.Tlogs) == 0 || len(root.CertificateAuthorities) == 0 {
+		return nil, fmt.Errorf("the Sigstore trusted root %s has no transparency logs or certificate authorities", path)
+	}
+	return &root, nil
+}
+
+// SigstoreVerifier verifies the bundles of cosign: the signing certificate is issued by a certificate authority
+// of the trusted root to the identity, and the signature is in the transparency log at the time the certificate was valid
+type SigstoreVerifier struct {
+	Root     *TrustedRoot
+	Identity string
+	Issuer   string
+}
+
+// Suffix returns the suffix of the bundle URL
+func (v *SigstoreVerifier) Suffix() string {
+	return SigstoreBundleSuffix
+}
+
+// Verify checks the bundle of the data
+func (v *SigstoreVerifier) Verify(data []byte, bundleData []byte) error {
+	entry, err := parseBundle(bundleData)
+	if err != nil {
+		return fmt.Errorf("failed to parse the Sigstore bundle: %w", err)
+	}
+
+	// The log entry proves the signature existed while the short-lived certificate was valid
+	integrated, err := v.verifyTlogEntry(entry)
+	if err != nil {
+		return err
+	}
+	if err := v.verifyCertificate(entry, integrated); err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(data)
+	if err := verifyWithKey(entry.cert.PublicKey, data, digest[:], entry.signature); err != nil {
+		return fmt.Errorf("the signature does not match the data: %w", err)
+	}
+	return verifyLogBody(entry, digest[:])
+}
+
+// verifyTlogEntry checks the signed entry timestamp of the transparency log, it returns the time of the entry
+func (v *SigstoreVerifier) verifyTlogEntry(entry *bundleEntry) (time.Time, error) {
+	integrated := time.Unix(entry.integratedTime, 0)
+	for _, tlog := range v.Root.Tlogs {
+		if !bytes.Equal(tlog.LogID.KeyID, entry.logID) {
+			continue
+		}
+		if !tlog.PublicKey.ValidFor.contains(integrated) {
+			return time.Time{}, fmt.Errorf("the transparency log key of %s is not valid at %s", tlog.BaseURL, integrated.UTC())
+		}
+		key, err := x509.ParsePKIXPublicKey(tlog.PublicKey.RawBytes)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse the transparency log key of %s: %w", tlog.BaseURL, err)
+		}
+
+		// The log signs the canonical JSON of the entry, the keys are sorted and there are no spaces
+		payload, err := json.Marshal(struct {
+			Body           string `json:"body"`
+			IntegratedTime int64  `json:"integratedTime"`
+			LogID          string `json:"logID"`
+			LogIndex       int64  `json:"logIndex"`
+		}{entry.body, entry.integratedTime, hex.EncodeToString(entry.logID), entry.logIndex})
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to encode the transparency log entry: %w", err)
+		}
+		digest := sha256.Sum256(payload)
+		if err := verifyWithKey(key, payload, digest[:], entry.signedEntryTimestamp); err != nil {
+			return time.Time{}, fmt.Errorf("the signed entry timestamp of %s is invalid: %w", tlog.BaseURL, err)
+		}
+		return integrated, nil
+	}
+	return time.Time{}, fmt.Errorf("the bundle is logged in an unknown transparency log %s", hex.EncodeToString(entry.logID))
+}
+
+// verifyCertificate checks the certificate chain at the time of the log entry, the identity and the issuer
+func (v *SigstoreVerifier) verifyCertificate(entry *bundleEntry, at time.Time) error {
+	if err := v.verifyChain(entry.cert, at); err != nil {
+		return err
+	}
+
+	identities := entry.cert.EmailAddresses
+	for _, uri := range entry.cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	if !contains(identities, v.Identity) {
+		return fmt.Errorf("the signing certificate is issued to %v, expected %s", identities, v.Identity)
+	}
+	if issuer := certificateIssuer(entry.cert); issuer != v.Issuer {
+		return fmt.Errorf("the signing certificate is issued by %q, expected %s", issuer, v.Issuer)
+	}
+	return nil
+}
+
+// verifyChain checks that a certificate authority of the trusted root valid at the time issued the certificate
+func (v *SigstoreVerifier) verifyChain(cert *x509.Certificate, at time.Time) error {
+	var lastErr error
+	for _, ca := range v.Root.CertificateAuthorities {
+		if !ca.ValidFor.contains(at) || len(ca.CertChain.Certificates) == 0 {
+			continue
+		}
+		roots := x509.NewCertPool()
+		intermediates := x509.NewCertPool()
+		for i, raw := range ca.CertChain.Certificates {
+			parsed, err := x509.ParseCertificate(raw.RawBytes)
+			if err != nil {
+				return fmt.Errorf("failed to parse the certificate of %s: %w", ca.URI, err)
+			}
+			// The chain starts with the issuing certificate and ends with the root
+			if i == len(ca.CertChain.Certificates)-1 {
+				roots.AddCert(parsed)
+			} else {
+				intermediates.AddCert(parsed)
+			}
+		}
+		_, err := cert.Verify(x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   at,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		})
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		return fmt.Errorf("no certificate authority of the trusted root is valid at %s", at.UTC())
+	}
+	return fmt.Errorf("the signing certificate is not issued by the trusted root: %w", lastErr)
+}
+
+// verifyLogBody checks that the log entry is the hashedrekord of this signature, certificate and data
+func verifyLogBody(entry *bundleEntry, digest []byte) error {
+	decoded, err := base64.StdEncoding.DecodeString(entry.body)
+	if err != nil {
+		return fmt.Errorf("failed to decode the transparency log entry: %w", err)
+	}
+	var body struct {
+		Kind string `json:"kind"`
+		Spec struct {
+			Data struct {
+				Hash struct {
+					Algorithm string `json:"algorithm"`
+					Value     string `json:"value"`
+				} `json:"hash"`
+			} `json:"data"`
+			Signature struct {
+				Content   []byte `json:"content"`
+				PublicKey struct {
+					Content []byte `json:"content"`
+				} `json:"publicKey"`
+			} `json:"signature"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(decoded, &body); err != nil {
+		return fmt.Errorf("failed to parse the transparency log entry: %w", err)
+	}
+	if body.Kind != "hashedrekord" {
+		return fmt.Errorf("unsupported transparency log entry %q, only hashedrekord is supported", body.Kind)
+	}
+	if body.Spec.Data.Hash.Algorithm != "sha256" || body.Spec.Data.Hash.Value != hex.EncodeToString(digest) {
+		return fmt.Errorf("the transparency log entry is for other data")
+	}
+	if !bytes.Equal(body.Spec.Signature.Content, entry.signature) {
+		return fmt.Errorf("the transparency log entry has another signature")
+	}
+	if block, _ := pem.Decode(body.Spec.Signature.PublicKey.Content); block == nil || !bytes.Equal(block.Bytes, entry.cert.Raw) {
+		return fmt.Errorf("the transparency log entry has another certificate")
+	}
+	return nil
+}
+
+// verifyWithKey checks the signature made by the ECDSA or Ed25519 key, digest is the SHA-256 of the message
+func verifyWithKey(key crypto.PublicKey, message []byte, digest []byte, signature []byte) error {
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest, signature) {
+			return fmt.Errorf("ECDSA verification failed")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, message, signature) {
+			return fmt.Errorf("Ed25519 verification failed")
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+	return nil
+}
+
+// certificateIssuer returns the OIDC issuer of a Fulcio certificate
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuerV2) {
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		}
+	}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidIssuerV1) {
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// bundleEntry is what the verification needs from a bundle, in either format
+type bundleEntry struct {
+	cert      *x509.Certificate
+	signature []byte
+	// body is the base64 of the canonical log entry, it is signed as is
+	body                 string
+	integratedTime       int64
+	logIndex             int64
+	logID                []byte
+	signedEntryTimestamp []byte
+}
+
+// sigstoreBundle is the Sigstore bundle of `cosign sign-blob --new-bundle-format`
+type sigstoreBundle struct {
+	MediaType            string `json:"mediaType"`
+	VerificationMaterial struct {
+		Certificate *struct {
+			RawBytes []byte `json:"rawBytes"`
+		} `json:"certificate"`
+		X509CertificateChain *struct {
+			Certificates []struct {
+				RawBytes []byte `json:"rawBytes"`
+			} `json:"certificates"`
+		} `json:"x509CertificateChain"`
+		TlogEntries []struct {
+			LogIndex json.Number `json:"logIndex"`
+			LogID    struct {
+				KeyID []byte `json:"keyId"`
+			} `json:"logId"`
+			IntegratedTime   json.Number `json:"integratedTime"`
+			InclusionPromise *struct {
+				SignedEntryTimestamp []byte `json:"signedEntryTimestamp"`
+			} `json:"inclusionPromise"`
+			CanonicalizedBody []byte `json:"canonicalizedBody"`
+		} `json:"tlogEntries"`
+	} `json:"verificationMaterial"`
+	MessageSignature *struct {
+		Signature []byte `json:"signature"`
+	} `json:"messageSignature"`
+}
+
+// cosignBundle is the bundle of `cosign sign-blob --bundle` before the Sigstore bundle format
+type cosignBundle struct {
+	Base64Signature string `json:"base64Signature"`
+	Cert            string `json:"cert"`
+	RekorBundle     *struct {
+		SignedEntryTimestamp []byte `json:"SignedEntryTimestamp"`
+		Payload              struct {
+			Body           string `json:"body"`
+			IntegratedTime int64  `json:"integratedTime"`
+			LogIndex       int64  `json:"logIndex"`
+			LogID          string `json:"logID"`
+		} `json:"Payload"`
+	} `json:"rekorBundle"`
+}
+
+// parseBundle reads the Sigstore bundle or the cosign bundle
+func parseBundle(data []byte) (*bundleEntry, error) {
+	if len(data) > maxBundleSize {
+		return nil, fmt.Errorf("the bundle is larger than %d bytes", maxBundleSize)
+	}
+	var probe struct {
+		MediaType string `json:"mediaType"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, err
+	}
+	if probe.MediaType != "" {
+		return parseSigstoreBundle(data)
+	}
+	return parseCosignBundle(data)
+}
+
+func parseSigstoreBundle(data []byte) (*bundleEntry, error) {
+	var bundle sigstoreBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, err
+	}
+	material := bundle.VerificationMaterial
+	var rawCert []byte
+	switch {
+	case material.Certificate != nil:
+		rawCert = material.Certificate.RawBytes
+	case material.X509CertificateChain != nil && len(material.X509CertificateChain.Certificates) > 0:
+		rawCert = material.X509CertificateChain.Certificates[0].RawBytes
+	default:
+		return nil, fmt.Errorf("the bundle %s has no signing certificate", bundle.MediaType)
+	}
+	if bundle.MessageSignature == nil || len(material.TlogEntries) == 0 {
+		return nil, fmt.Errorf("the bundle %s has no message signature or transparency log entry", bundle.MediaType)
+	}
+	tlog := material.TlogEntries[0]
+	if tlog.InclusionPromise == nil {
+		return nil, fmt.Errorf("the bundle %s has no signed entry timestamp", bundle.MediaType)
+	}
+
+	cert, err := x509.ParseCertificate(rawCert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the signing certificate: %w", err)
+	}
+	integratedTime, err := tlog.IntegratedTime.Int64()
+	if err != nil {
+		return nil, fmt.Errorf("invalid integrated time: %w", err)
+	}
+	logIndex, err := tlog.LogIndex.Int64()
+	if err != nil {
+		return nil, fmt.Errorf("invalid log index: %w", err)
+	}
+	return &bundleEntry{
+		cert:                 cert,
+		signature:            bundle.MessageSignature.Signature,
+		body:                 base64.StdEncoding.EncodeToString(tlog.CanonicalizedBody),
+		integratedTime:       integratedTime,
+		logIndex:             logIndex,
+		logID:                tlog.LogID.KeyID,
+		signedEntryTimestamp: tlog.InclusionPromise.SignedEntryTimestamp,
+	}, nil
+}
+
+func parseCosignBundle(data []byte) (*bundleEntry, error) {
+	var bundle cosignBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, err
+	}
+	if bundle.RekorBundle == nil {
+		return nil, fmt.Errorf("the cosign bundle has no transparency log entry")
+	}
+	signature, err := base64.StdEncoding.DecodeString(bundle.Base64Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the signature: %w", err)
+	}
+	certPEM, err := base64.StdEncoding.DecodeString(bundle.Cert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the signing certificate: %w", err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("the signing certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the signing certificate: %w", err)
+	}
+	logID, err := hex.DecodeString(bundle.RekorBundle.Payload.LogID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log ID: %w", err)
+	}
+	return &bundleEntry{
+		cert:                 cert,
+		signature:            signature,
+		body:                 bundle.RekorBundle.Payload.Body,
+		integratedTime:       bundle.RekorBundle.Payload.IntegratedTime,
+		logIndex:             bundle.RekorBundle.Payload.LogIndex,
+		logID:                logID,
+		signedEntryTimestamp: bundle.RekorBundle.SignedEntryTimestamp,
+	}, nil
+}
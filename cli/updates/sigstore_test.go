@@ -0,0 +1,363 @@
+package updates
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+)
+
+const (
+	testIdentity = "https://github.com/example/devrig/.github/workflows/release.yml@refs/heads/main"
+	testIssuer   = "https://token.actions.githubusercontent.com"
+)
+
+// testSigstore is a Sigstore instance of the test: a certificate authority and a transparency log
+type testSigstore struct {
+	t        *testing.T
+	caKey    *ecdsa.PrivateKey
+	caCert   *x509.Certificate
+	rekorKey *ecdsa.PrivateKey
+	logID    []byte
+	now      time.Time
+}
+
+func newTestSigstore(t *testing.T) *testSigstore {
+	t.Helper()
+	s := &testSigstore{t: t, caKey: generateKey(t), rekorKey: generateKey(t), now: time.Now().Truncate(time.Second)}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-fulcio"},
+		NotBefore:             s.now.Add(-time.Hour),
+		NotAfter:              s.now.Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &s.caKey.PublicKey, s.caKey)
+	if err != nil {
+		t.Fatalf("Failed to create the CA certificate: %v", err)
+	}
+	if s.caCert, err = x509.ParseCertificate(raw); err != nil {
+		t.Fatalf("Failed to parse the CA certificate: %v", err)
+	}
+	rekorDER, err := x509.MarshalPKIXPublicKey(&s.rekorKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to encode the log key: %v", err)
+	}
+	logID := sha256.Sum256(rekorDER)
+	s.logID = logID[:]
+	return s
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate a key: %v", err)
+	}
+	return key
+}
+
+// trustedRoot returns the trusted_root.json of the instance
+func (s *testSigstore) trustedRoot() []byte {
+	rekorDER, err := x509.MarshalPKIXPublicKey(&s.rekorKey.PublicKey)
+	if err != nil {
+		s.t.Fatalf("Failed to encode the log key: %v", err)
+	}
+	root := map[string]interface{}{
+		"mediaType": "application/vnd.dev.sigstore.trustedroot+json;version=0.1",
+		"tlogs": []interface{}{map[string]interface{}{
+			"baseUrl":       "https://rekor.example.com",
+			"hashAlgorithm": "SHA2_256",
+			"publicKey": map[string]interface{}{
+				"rawBytes":   rekorDER,
+				"keyDetails": "PKIX_ECDSA_P256_SHA_256",
+				"validFor":   map[string]interface{}{"start": s.now.Add(-24 * time.Hour)},
+			},
+			"logId": map[string]interface{}{"keyId": s.logID},
+		}},
+		"certificateAuthorities": []interface{}{map[string]interface{}{
+			"uri":       "https://fulcio.example.com",
+			"certChain": map[string]interface{}{"certificates": []interface{}{map[string]interface{}{"rawBytes": s.caCert.Raw}}},
+			"validFor":  map[string]interface{}{"start": s.now.Add(-24 * time.Hour)},
+		}},
+	}
+	data, err := json.Marshal(root)
+	if err != nil {
+		s.t.Fatalf("Failed to encode the trusted root: %v", err)
+	}
+	return data
+}
+
+func (s *testSigstore) verifier() *SigstoreVerifier {
+	var root TrustedRoot
+	if err := json.Unmarshal(s.trustedRoot(), &root); err != nil {
+		s.t.Fatalf("Failed to parse the trusted root: %v", err)
+	}
+	return &SigstoreVerifier{Root: &root, Identity: testIdentity, Issuer: testIssuer}
+}
+
+// signed is a signature of the data with its short-lived certificate and the log entry
+type signed struct {
+	cert           *x509.Certificate
+	signature      []byte
+	body           []byte
+	integratedTime int64
+	logIndex       int64
+	set            []byte
+}
+
+// sign signs the data with a certificate issued to the identity at the integrated time and logs the signature
+func (s *testSigstore) sign(data []byte, identity string, integratedTime time.Time) *signed {
+	t := s.t
+	key := generateKey(t)
+	issuer, err := asn1.MarshalWithParams(testIssuer, "utf8")
+	if err != nil {
+		t.Fatalf("Failed to encode the issuer: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       s.now.Add(-time.Minute),
+		NotAfter:        s.now.Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+	}
+	if strings.Contains(identity, "@") && !strings.Contains(identity, "/") {
+		template.EmailAddresses = []string{identity}
+	} else {
+		template.URIs = append(template.URIs, mustParseURL(t, identity))
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, s.caCert, &key.PublicKey, s.caKey)
+	if err != nil {
+		t.Fatalf("Failed to create the signing certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatalf("Failed to parse the signing certificate: %v", err)
+	}
+
+	digest := sha256.Sum256(data)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]interface{}{
+			"data": map[string]interface{}{"hash": map[string]string{"algorithm": "sha256", "value": hex.EncodeToString(digest[:])}},
+			"signature": map[string]interface{}{
+				"content":   signature,
+				"publicKey": map[string]interface{}{"content": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw})},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode the log entry: %v", err)
+	}
+
+	entry := &signed{cert: cert, signature: signature, body: body, integratedTime: integratedTime.Unix(), logIndex: 42}
+	payload := fmt.Sprintf(`{"body":%q,"integratedTime":%d,"logID":%q,"logIndex":%d}`,
+		base64.StdEncoding.EncodeToString(body), entry.integratedTime, hex.EncodeToString(s.logID), entry.logIndex)
+	payloadDigest := sha256.Sum256([]byte(payload))
+	if entry.set, err = ecdsa.SignASN1(rand.Reader, s.rekorKey, payloadDigest[:]); err != nil {
+		t.Fatalf("Failed to sign the log entry: %v", err)
+	}
+	return entry
+}
+
+// sigstoreBundle encodes the signature as `cosign sign-blob --new-bundle-format` does
+func (s *testSigstore) sigstoreBundle(entry *signed) []byte {
+	bundle := map[string]interface{}{
+		"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json",
+		"verificationMaterial": map[string]interface{}{
+			"certificate": map[string]interface{}{"rawBytes": entry.cert.Raw},
+			"tlogEntries": []interface{}{map[string]interface{}{
+				"logIndex":          fmt.Sprint(entry.logIndex),
+				"logId":             map[string]interface{}{"keyId": s.logID},
+				"kindVersion":       map[string]string{"kind": "hashedrekord", "version": "0.0.1"},
+				"integratedTime":    fmt.Sprint(entry.integratedTime),
+				"inclusionPromise":  map[string]interface{}{"signedEntryTimestamp": entry.set},
+				"canonicalizedBody": entry.body,
+			}},
+		},
+		"messageSignature": map[string]interface{}{"signature": entry.signature},
+	}
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		s.t.Fatalf("Failed to encode the bundle: %v", err)
+	}
+	return data
+}
+
+// cosignBundle encodes the signature as `cosign sign-blob --bundle` did before the Sigstore bundle format
+func (s *testSigstore) cosignBundle(entry *signed) []byte {
+	bundle := map[string]interface{}{
+		"base64Signature": base64.StdEncoding.EncodeToString(entry.signature),
+		"cert":            base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: entry.cert.Raw})),
+		"rekorBundle": map[string]interface{}{
+			"SignedEntryTimestamp": entry.set,
+			"Payload": map[string]interface{}{
+				"body":           base64.StdEncoding.EncodeToString(entry.body),
+				"integratedTime": entry.integratedTime,
+				"logIndex":       entry.logIndex,
+				"logID":          hex.EncodeToString(s.logID),
+			},
+		},
+	}
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		s.t.Fatalf("Failed to encode the bundle: %v", err)
+	}
+	return data
+}
+
+func TestSigstoreVerifier(t *testing.T) {
+	instance := newTestSigstore(t)
+	data := []byte(`{"version":"0.80.0"}`)
+	entry := instance.sign(data, testIdentity, instance.now)
+
+	for name, bundle := range map[string][]byte{
+		"sigstore bundle": instance.sigstoreBundle(entry),
+		"cosign bundle":   instance.cosignBundle(entry),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := instance.verifier().Verify(data, bundle); err != nil {
+				t.Fatalf("Failed to verify the bundle: %v", err)
+			}
+			if err := instance.verifier().Verify([]byte(`{"version":"0.1.0"}`), bundle); err == nil {
+				t.Error("Expected the bundle of other data to be rejected")
+			}
+		})
+	}
+}
+
+func TestSigstoreVerifier_Rejects(t *testing.T) {
+	instance := newTestSigstore(t)
+	data := []byte(`{"version":"0.80.0"}`)
+
+	otherIssuer := instance.verifier()
+	otherIssuer.Issuer = "https://accounts.example.com"
+
+	untrusted := newTestSigstore(t)
+	untrustedEntry := untrusted.sign(data, testIdentity, untrusted.now)
+
+	tampered := instance.sign(data, testIdentity, instance.now)
+	tampered.integratedTime++
+
+	tests := []struct {
+		name     string
+		verifier *SigstoreVerifier
+		bundle   []byte
+		expected string
+	}{
+		{"another identity", instance.verifier(), instance.sigstoreBundle(instance.sign(data, "release@example.com", instance.now)), "is issued to"},
+		{"another issuer", otherIssuer, instance.sigstoreBundle(instance.sign(data, testIdentity, instance.now)), "is issued by"},
+		{"expired certificate", instance.verifier(), instance.sigstoreBundle(instance.sign(data, testIdentity, instance.now.Add(time.Hour))), "not issued by the trusted root"},
+		{"unknown log", instance.verifier(), untrusted.sigstoreBundle(untrustedEntry), "unknown transparency log"},
+		{"tampered log entry", instance.verifier(), instance.sigstoreBundle(tampered), "signed entry timestamp"},
+		{"not a bundle", instance.verifier(), []byte("-----BEGIN SSH SIGNATURE-----"), "failed to parse"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.verifier.Verify(data, test.bundle)
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Expected an error with %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestResolveVerifier(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte("tools:\n  node: \"20\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	verifier, err := ResolveVerifier(configservice.NewConfigService(configPath))
+	if err != nil {
+		t.Fatalf("Failed to resolve the verifier: %v", err)
+	}
+	if _, ok := verifier.(SSHVerifier); !ok {
+		t.Errorf("Expected the SSH verifier by default, got %T", verifier)
+	}
+
+	instance := newTestSigstore(t)
+	if err := os.WriteFile(filepath.Join(dir, "trusted_root.json"), instance.trustedRoot(), 0644); err != nil {
+		t.Fatalf("Failed to write the trusted root: %v", err)
+	}
+	content := "security:\n  signature_scheme: sigstore\n  sigstore:\n    trusted_root: trusted_root.json\n" +
+		"    identity: " + testIdentity + "\n    issuer: " + testIssuer + "\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	verifier, err = ResolveVerifier(configservice.NewConfigService(configPath))
+	if err != nil {
+		t.Fatalf("Failed to resolve the verifier: %v", err)
+	}
+	sigstore, ok := verifier.(*SigstoreVerifier)
+	if !ok || sigstore.Identity != testIdentity || sigstore.Issuer != testIssuer || len(sigstore.Root.Tlogs) != 1 {
+		t.Errorf("Expected the Sigstore verifier of devrig.yaml, got %+v", verifier)
+	}
+}
+
+func TestClient_FetchUpdateInfo_Sigstore(t *testing.T) {
+	instance := newTestSigstore(t)
+	data := []byte(`{"version":"0.80.0","releaseDate":"2026-10-01","binaries":[]}`)
+	bundle := instance.sigstoreBundle(instance.sign(data, testIdentity, instance.now))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest.json":
+			_, _ = w.Write(data)
+		case "/latest.json" + SigstoreBundleSuffix:
+			_, _ = w.Write(bundle)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := &Client{
+		downloader: NewDownloader(),
+		endpoints:  func() (Endpoints, error) { return DefaultEndpoints, nil },
+		verifier:   func() (Verifier, error) { return instance.verifier(), nil },
+	}
+	info, err := client.FetchUpdateInfo(server.URL + "/latest.json")
+	if err != nil {
+		t.Fatalf("Failed to fetch the update info: %v", err)
+	}
+	if info.Version != "0.80.0" {
+		t.Errorf("Expected version 0.80.0, got %s", info.Version)
+	}
+}
+
+func mustParseURL(t *testing.T, value string) *url.URL {
+	t.Helper()
+	parsed, err := url.Parse(value)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", value, err)
+	}
+	return parsed
+}
@@ -4,9 +4,12 @@ import "runtime"
 
 // UpdateInfo represents the current update information
 type UpdateInfo struct {
-	Version     string       `json:"version"`
-	ReleaseDate string       `json:"release_date"`
-	Binaries    []BinaryInfo `json:"binaries"`
+	Version     string `json:"version"`
+	ReleaseDate string `json:"release_date"`
+	Channel     string `json:"channel,omitempty"`
+	// ReleaseNotes is the summary of the changes of the release or the link to them
+	ReleaseNotes string       `json:"release_notes,omitempty"`
+	Binaries     []BinaryInfo `json:"binaries"`
 }
 
 // BinaryInfo represents a single binary distribution
@@ -16,6 +19,8 @@ type BinaryInfo struct {
 	Arch     string `json:"arch"`
 	SHA512   string `json:"sha512"`
 	URL      string `json:"url"`
+	// Mirrors lists fallback URLs of the same binary
+	Mirrors []string `json:"mirrors,omitempty"`
 }
 
 // SystemInfo provides information about the current system
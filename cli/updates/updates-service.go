@@ -1,22 +1,55 @@
 package updates
 
 import (
+	"fmt"
+	"strings"
 	"sync"
+
+	"jonnyzzz.com/devrig.dev/version"
 )
 
 type UpdateService interface {
 	// LastUpdateInfo function blocks to receive the update info
 	LastUpdateInfo() (*UpdateInfo, error)
 
+	// IsUpdateAvailable checks if the latest release is newer than the running devrig
 	IsUpdateAvailable() (bool, error)
+
+	// CompareWithLatest blocks to compare the running devrig with the latest release
+	CompareWithLatest() (*Comparison, error)
 }
 
-func NewUpdateService(thisVersion string) UpdateService {
-	client := NewClient()
+// Comparison is the order of the running devrig against the latest release of the channel
+type Comparison struct {
+	Running string
+	Latest  string
+	// Order is Older when an update is available, Newer for a development build or a withdrawn release
+	Order        version.Order
+	ReleaseNotes string
+}
+
+// Banner returns the update announcement of the root command, or an empty string if no update is available
+func (c *Comparison) Banner() string {
+	if c.Order != version.Older {
+		return ""
+	}
+	banner := fmt.Sprintf("Update available: devrig %s, you are running %s", c.Latest, c.Running)
+	if notes := strings.TrimSpace(c.ReleaseNotes); notes != "" {
+		banner += "\n" + notes
+	}
+	return banner + "\nRun 'devrig upgrade' to update"
+}
+
+// NewUpdateService creates the UpdateService for the running devrig version.
+// The channel function is called once, on the first request, to resolve the release channel
+// configured in devrig.yaml, an empty channel means stable. The client resolves the endpoints, see NewProjectClient
+func NewUpdateService(thisVersion string, client *Client, channel func() string) UpdateService {
 	impl := updateServiceImpl{
-		client:             client,
-		thisVersion:        thisVersion,
-		computeUpdatesImpl: sync.OnceValues(client.FetchLatestUpdateInfo),
+		client:      client,
+		thisVersion: thisVersion,
+		computeUpdatesImpl: sync.OnceValues(func() (*UpdateInfo, error) {
+			return client.FetchChannelUpdateInfo(channel())
+		}),
 	}
 
 	return &impl
@@ -34,17 +67,29 @@ func (impl *updateServiceImpl) LastUpdateInfo() (*UpdateInfo, error) {
 }
 
 func (impl *updateServiceImpl) IsUpdateAvailable() (bool, error) {
-	info, err := impl.LastUpdateInfo()
+	comparison, err := impl.CompareWithLatest()
 	if err != nil {
 		return false, err
 	}
+	return comparison.Order == version.Older, nil
+}
+
+func (impl *updateServiceImpl) CompareWithLatest() (*Comparison, error) {
+	info, err := impl.LastUpdateInfo()
+	if err != nil {
+		return nil, err
+	}
 
-	//We consider that if our version is not equal to the latest version
-	//it means
-	// - either there is update
-	// - or there is a sudden downgrade or rollback
-	// for both cases, it's time to change that binary
-	return info.Version == impl.thisVersion, nil
+	order, err := version.OrderOf(impl.thisVersion, info.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare the running devrig with the latest release: %w", err)
+	}
+	return &Comparison{
+		Running:      impl.thisVersion,
+		Latest:       info.Version,
+		Order:        order,
+		ReleaseNotes: info.ReleaseNotes,
+	}, nil
 }
 
 type updateServiceImpl struct {
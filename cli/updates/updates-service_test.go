@@ -0,0 +1,55 @@
+package updates
+
+import (
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/version"
+)
+
+func newTestUpdateService(running string, latest *UpdateInfo) UpdateService {
+	return &updateServiceImpl{
+		thisVersion:        running,
+		computeUpdatesImpl: func() (*UpdateInfo, error) { return latest, nil },
+	}
+}
+
+func TestUpdateService_CompareWithLatest(t *testing.T) {
+	latest := &UpdateInfo{Version: "0.80.0", ReleaseNotes: "Faster downloads"}
+	for _, test := range []struct {
+		running  string
+		expected version.Order
+	}{
+		{"0.79.6", version.Older},
+		{"0.80.0", version.Equal},
+		{"v0.80.0", version.Equal},
+		{"0.80.1-SNAPSHOT", version.Newer},
+	} {
+		service := newTestUpdateService(test.running, latest)
+		comparison, err := service.CompareWithLatest()
+		if err != nil {
+			t.Fatalf("Failed to compare %s: %v", test.running, err)
+		}
+		if comparison.Order != test.expected || comparison.Latest != "0.80.0" || comparison.ReleaseNotes != "Faster downloads" {
+			t.Errorf("Unexpected comparison of %s: %+v", test.running, comparison)
+		}
+		available, err := service.IsUpdateAvailable()
+		if err != nil || available != (test.expected == version.Older) {
+			t.Errorf("Unexpected update availability for %s: %v, %v", test.running, available, err)
+		}
+		if (comparison.Banner() != "") != available {
+			t.Errorf("Unexpected banner for %s: %q", test.running, comparison.Banner())
+		}
+	}
+
+	outdated, _ := newTestUpdateService("0.79.6", latest).CompareWithLatest()
+	for _, expected := range []string{"devrig 0.80.0, you are running 0.79.6", "Faster downloads", "devrig upgrade"} {
+		if !strings.Contains(outdated.Banner(), expected) {
+			t.Errorf("Expected %q in the banner: %s", expected, outdated.Banner())
+		}
+	}
+
+	if _, err := newTestUpdateService("dev", latest).CompareWithLatest(); err == nil {
+		t.Errorf("Expected a version that is not semver to fail the comparison")
+	}
+}
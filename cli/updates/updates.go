@@ -3,38 +3,187 @@ package updates
 import (
 	"encoding/json"
 	"fmt"
+	"path"
+	"slices"
+	"sync"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
 )
 
 // Client provides high-level API for fetching and parsing update information
 type Client struct {
 	downloader *Downloader
+	endpoints  func() (Endpoints, error)
+	// verifier is the verifier of the signature scheme, keys.json is applied to it by trustedVerifier
+	verifier func() (Verifier, error)
+	// keys keeps the keys accepted from keys.json, nil disables the key rotation
+	keys *KeyStore
+	// versions keeps the highest verified versions, nil disables the rollback protection
+	versions *VersionStore
+
+	// rotation applies keys.json to the verifier once, see trustedVerifier
+	rotation  sync.Once
+	rotated   Verifier
+	rotateErr error
+
+	mutex sync.Mutex
+	// fetches keeps the metadata of every URL the client fetched, failures included, so the commands and the
+	// background update check of one invocation share a single download and verification
+	fetches map[string]*fetch
 }
 
-// NewClient creates a new update client
+// fetch is a download of release metadata, requests of the same URL wait for the first one and share its result
+type fetch struct {
+	done chan struct{}
+	info *UpdateInfo
+	err  error
+}
+
+// NewClient creates a new update client for the endpoints of devrig.dev
 func NewClient() *Client {
-	return &Client{
+	c := &Client{
 		downloader: NewDownloader(),
+		endpoints: func() (Endpoints, error) {
+			return DefaultEndpoints, nil
+		},
+		keys:     defaultKeyStore(),
+		versions: defaultVersionStore(),
+	}
+	c.verifier = func() (Verifier, error) {
+		return SSHVerifier{}, nil
 	}
+	return c
+}
+
+// NewProjectClient creates a new update client for the endpoints of the updates section of devrig.yaml
+// and the signature scheme of its security section. The configs function is called on the first request,
+// because the path is only known then
+func NewProjectClient(configs func() configservice.ConfigService) *Client {
+	c := &Client{
+		downloader: NewDownloader(),
+		endpoints: sync.OnceValues(func() (Endpoints, error) {
+			return ResolveEndpoints(configs())
+		}),
+		keys:     defaultKeyStore(),
+		versions: defaultVersionStore(),
+	}
+	c.verifier = sync.OnceValues(func() (Verifier, error) {
+		return ResolveVerifier(configs())
+	})
+	return c
+}
+
+// defaultKeyStore returns the key store of the user, the keys are not rotated without the user cache directory
+func defaultKeyStore() *KeyStore {
+	store, err := DefaultKeyStore()
+	if err != nil {
+		return nil
+	}
+	return store
+}
+
+// Endpoints returns the endpoints the client fetches the latest releases from
+func (c *Client) Endpoints() (Endpoints, error) {
+	return c.endpoints()
 }
 
 // FetchLatestUpdateInfo downloads, verifies, and parses the latest update information
 // This is the main entry point for getting update information
 func (c *Client) FetchLatestUpdateInfo() (*UpdateInfo, error) {
-	// Download latest.json
-	data, err := c.downloader.download(LatestJSONURL, "latest.json")
+	endpoints, err := c.endpoints()
+	if err != nil {
+		return nil, err
+	}
+	return c.FetchUpdateInfo(endpoints.LatestURL)
+}
+
+// FetchChannelUpdateInfo downloads, verifies, and parses the latest update information of the release channel
+func (c *Client) FetchChannelUpdateInfo(channel string) (*UpdateInfo, error) {
+	if err := configservice.ValidateChannel(channel); err != nil {
+		return nil, err
+	}
+	endpoints, err := c.endpoints()
+	if err != nil {
+		return nil, err
+	}
+	return c.FetchUpdateInfo(endpoints.ChannelJSONURL(channel))
+}
+
+// FetchUpdateInfo downloads, verifies, and parses the update information from the given URL.
+// The signature is downloaded from the same URL with the suffix of the signature scheme, .sig by default.
+// The URL is fetched once per client, later calls return the same result or error without network requests
+func (c *Client) FetchUpdateInfo(url string) (*UpdateInfo, error) {
+	c.mutex.Lock()
+	if c.fetches == nil {
+		c.fetches = map[string]*fetch{}
+	}
+	f, started := c.fetches[url]
+	if !started {
+		f = &fetch{done: make(chan struct{})}
+		c.fetches[url] = f
+	}
+	c.mutex.Unlock()
+
+	if started {
+		<-f.done
+	} else {
+		f.info, f.err = c.fetchUpdateInfo(url)
+		close(f.done)
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	// Callers may change their copy
+	info := *f.info
+	info.Binaries = slices.Clone(f.info.Binaries)
+	return &info, nil
+}
+
+func (c *Client) fetchUpdateInfo(url string) (*UpdateInfo, error) {
+	name := path.Base(url)
+
+	// The metadata and the signature are downloaded while the keys are rotated, the suffix of the signature
+	// only depends on the signature scheme
+	type download struct {
+		data []byte
+		err  error
+	}
+	metadata := make(chan download, 1)
+	go func() {
+		data, err := c.downloader.download(url, name)
+		metadata <- download{data, err}
+	}()
+
+	verifier, err := c.verifier()
 	if err != nil {
-		return nil, fmt.Errorf("failed to download update info: %w", err)
+		return nil, err
 	}
+	signature := make(chan download, 1)
+	go func() {
+		data, err := c.downloader.download(url+verifier.Suffix(), name+verifier.Suffix())
+		signature <- download{data, err}
+	}()
 
-	// Download signature
-	signature, err := c.downloader.download(LatestJSONSigURL, "latest.json.sig")
+	verifier, err = c.trustedVerifier(verifier)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download signature: %w", err)
+		return nil, err
 	}
 
+	downloaded := <-metadata
+	if downloaded.err != nil {
+		return nil, fmt.Errorf("failed to download update info: %w", downloaded.err)
+	}
+	signed := <-signature
+	if signed.err != nil {
+		return nil, fmt.Errorf("failed to download signature: %w", signed.err)
+	}
+	data := downloaded.data
+
 	// Verify signature
-	if err := VerifySignature(data, signature); err != nil {
-		return nil, fmt.Errorf("signature verification failed: %w", err)
+	if err := verifier.Verify(data, signed.data); err != nil {
+		return nil, &devrigErrors.SignatureInvalidError{Subject: url, Err: err}
 	}
 
 	// Parse JSON
@@ -42,10 +191,45 @@ func (c *Client) FetchLatestUpdateInfo() (*UpdateInfo, error) {
 	if err := json.Unmarshal(data, &updateInfo); err != nil {
 		return nil, fmt.Errorf("failed to parse update info: %w", err)
 	}
+	if err := c.checkRollback(url, &updateInfo); err != nil {
+		return nil, err
+	}
 
 	return &updateInfo, nil
 }
 
+// trustedVerifier applies keys.json to the verifier of the signature scheme once per client
+func (c *Client) trustedVerifier(verifier Verifier) (Verifier, error) {
+	c.rotation.Do(func() {
+		c.rotated, c.rotateErr = c.rotateKeys(verifier)
+	})
+	return c.rotated, c.rotateErr
+}
+
+// ToDevrigSection converts the update information into the devrig section of devrig.yaml
+func (updateInfo *UpdateInfo) ToDevrigSection() *configservice.DevrigSection {
+	binaries := make(map[string]configservice.BinaryInfo)
+	for _, b := range updateInfo.Binaries {
+		binaries[fmt.Sprintf("%s-%s", b.OS, b.Arch)] = configservice.BinaryInfo{
+			URL:     b.URL,
+			SHA512:  b.SHA512,
+			Mirrors: b.Mirrors,
+		}
+	}
+
+	channel := updateInfo.Channel
+	if channel == configservice.ChannelStable {
+		channel = ""
+	}
+
+	return &configservice.DevrigSection{
+		Version:     updateInfo.Version,
+		ReleaseDate: updateInfo.ReleaseDate,
+		Channel:     channel,
+		Binaries:    binaries,
+	}
+}
+
 // FindBinaryForCurrentSystem finds a binary matching the current OS and architecture
 func (updateInfo *UpdateInfo) FindBinaryForCurrentSystem() *BinaryInfo {
 	sys := CurrentSystem{}
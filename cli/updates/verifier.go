@@ -0,0 +1,63 @@
+package updates
+
+import (
+	"errors"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	devrigErrors "jonnyzzz.com/devrig.dev/errors"
+)
+
+// Verifier checks the signature of the release metadata, the signature is downloaded from the URL
+// of the metadata with the suffix of the verifier
+type Verifier interface {
+	// Suffix is appended to the URL of the metadata to download the signature, e.g. .sig
+	Suffix() string
+	// Verify checks the signature of the data
+	Verify(data []byte, signature []byte) error
+}
+
+// SSHVerifier verifies the .sig SSH signatures with the keys, the TrustedPublicKeys built into devrig
+// are used when there are none
+type SSHVerifier struct {
+	Keys []string
+}
+
+// Suffix returns the suffix of the signature URL
+func (SSHVerifier) Suffix() string {
+	return ".sig"
+}
+
+// Verify checks the SSH signature of the data
+func (v SSHVerifier) Verify(data []byte, signature []byte) error {
+	if len(v.Keys) == 0 {
+		return VerifySignature(data, signature)
+	}
+	return VerifySignatureWithKeys(data, signature, v.Keys)
+}
+
+// ResolveVerifier returns the verifier of the signature scheme of the security section of devrig.yaml,
+// the SSH keys built into devrig are used when devrig.yaml or the section is missing. The trusted keys
+// of the section are accepted next to the built-in keys, or instead of them with disable_builtin_keys
+func ResolveVerifier(configs configservice.ConfigService) (Verifier, error) {
+	section, err := configs.Security().ReadSecurity()
+	var notFound *devrigErrors.ConfigNotFoundError
+	if errors.As(err, &notFound) {
+		return SSHVerifier{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if section.Scheme() != configservice.SignatureSchemeSigstore {
+		var keys []string
+		if !section.DisableBuiltinKeys {
+			keys = append(keys, TrustedPublicKeys...)
+		}
+		return SSHVerifier{Keys: append(keys, section.TrustedKeys...)}, nil
+	}
+	root, err := LoadTrustedRoot(section.Sigstore.TrustedRoot)
+	if err != nil {
+		return nil, err
+	}
+	return &SigstoreVerifier{Root: root, Identity: section.Sigstore.Identity, Issuer: section.Sigstore.Issuer}, nil
+}
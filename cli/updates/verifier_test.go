@@ -0,0 +1,84 @@
+package updates
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"jonnyzzz.com/devrig.dev/configservice"
+)
+
+// newOrganizationKey generates the SSH key of an organization, it returns the signer and the authorized_keys line
+func newOrganizationKey(t *testing.T) (ssh.Signer, string) {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate a key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		t.Fatalf("Failed to create a signer: %v", err)
+	}
+	return signer, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))) + " release@example.com"
+}
+
+// signSSH signs the data like `ssh-keygen -Y sign -n file` does
+func signSSH(t *testing.T, signer ssh.Signer, data []byte) []byte {
+	t.Helper()
+	signature, err := SignSSH(signer, data, "file")
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	return signature
+}
+
+func TestResolveVerifier_TrustedKeys(t *testing.T) {
+	signer, authorizedKey := newOrganizationKey(t)
+	data := []byte(testPayload)
+	organizationSignature := signSSH(t, signer, data)
+
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte("security:\n  trusted_keys:\n    - "+authorizedKey+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	verifier, err := ResolveVerifier(configservice.NewConfigService(configPath))
+	if err != nil {
+		t.Fatalf("Failed to resolve the verifier: %v", err)
+	}
+	if err := verifier.Verify(data, organizationSignature); err != nil {
+		t.Errorf("Expected the signature of the organization key to be accepted: %v", err)
+	}
+	if err := verifier.Verify(data, key1Signature); err != nil {
+		t.Errorf("Expected the signature of the built-in key to be accepted: %v", err)
+	}
+
+	content := "security:\n  disable_builtin_keys: true\n  trusted_keys:\n    - " + authorizedKey + "\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	verifier, err = ResolveVerifier(configservice.NewConfigService(configPath))
+	if err != nil {
+		t.Fatalf("Failed to resolve the verifier: %v", err)
+	}
+	if err := verifier.Verify(data, organizationSignature); err != nil {
+		t.Errorf("Expected the signature of the organization key to be accepted: %v", err)
+	}
+	if err := verifier.Verify(data, key1Signature); err == nil {
+		t.Error("Expected the signature of the disabled built-in key to be rejected")
+	}
+}
+
+func TestSSHVerifier_BuiltinKeysByDefault(t *testing.T) {
+	signer, _ := newOrganizationKey(t)
+	data := []byte(testPayload)
+	if err := (SSHVerifier{}).Verify(data, key2Signature); err != nil {
+		t.Errorf("Expected the built-in keys to be used: %v", err)
+	}
+	if err := (SSHVerifier{}).Verify(data, signSSH(t, signer, data)); err == nil {
+		t.Error("Expected the signature of an unknown key to be rejected")
+	}
+}
@@ -0,0 +1,88 @@
+package upgrade
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+)
+
+// DiffSections returns human-readable lines describing the changes between two devrig sections
+func DiffSections(old, new *configservice.DevrigSection) []string {
+	var changes []string
+
+	if old.Version != new.Version {
+		changes = append(changes, fmt.Sprintf("version: %s -> %s", orNone(old.Version), orNone(new.Version)))
+	}
+	if old.Channel != new.Channel {
+		changes = append(changes, fmt.Sprintf("channel: %s -> %s", orStable(old.Channel), orStable(new.Channel)))
+	}
+	if old.ReleaseDate != new.ReleaseDate {
+		changes = append(changes, fmt.Sprintf("release_date: %s -> %s", orNone(old.ReleaseDate), orNone(new.ReleaseDate)))
+	}
+
+	platforms := map[string]bool{}
+	for platform := range old.Binaries {
+		platforms[platform] = true
+	}
+	for platform := range new.Binaries {
+		platforms[platform] = true
+	}
+
+	sorted := make([]string, 0, len(platforms))
+	for platform := range platforms {
+		sorted = append(sorted, platform)
+	}
+	sort.Strings(sorted)
+
+	for _, platform := range sorted {
+		oldBinary, hadOld := old.Binaries[platform]
+		newBinary, hasNew := new.Binaries[platform]
+
+		switch {
+		case !hadOld:
+			changes = append(changes, fmt.Sprintf("+ %s: %s", platform, newBinary.URL))
+		case !hasNew:
+			changes = append(changes, fmt.Sprintf("- %s: %s", platform, oldBinary.URL))
+		case !sameBinary(oldBinary, newBinary):
+			changes = append(changes, fmt.Sprintf("~ %s:", platform))
+			if oldBinary.URL != newBinary.URL {
+				changes = append(changes, fmt.Sprintf("    url:    %s -> %s", oldBinary.URL, newBinary.URL))
+			}
+			if oldBinary.SHA512 != newBinary.SHA512 {
+				changes = append(changes, fmt.Sprintf("    sha512: %s... -> %s...", shortHash(oldBinary.SHA512), shortHash(newBinary.SHA512)))
+			}
+			if !slices.Equal(oldBinary.Mirrors, newBinary.Mirrors) {
+				changes = append(changes, fmt.Sprintf("    mirrors: %d -> %d", len(oldBinary.Mirrors), len(newBinary.Mirrors)))
+			}
+		}
+	}
+
+	return changes
+}
+
+func sameBinary(a, b configservice.BinaryInfo) bool {
+	return a.URL == b.URL && a.SHA512 == b.SHA512 && slices.Equal(a.Mirrors, b.Mirrors)
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
+
+func orStable(channel string) string {
+	if channel == "" {
+		return configservice.ChannelStable
+	}
+	return channel
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 16 {
+		return hash[:16]
+	}
+	return hash
+}
@@ -0,0 +1,92 @@
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/updates"
+	"jonnyzzz.com/devrig.dev/version"
+)
+
+// noticeMaxAge is the age of the cached release metadata it is downloaded again after
+const noticeMaxAge = 24 * time.Hour
+
+// ChannelFetcher downloads and verifies the latest release metadata of a channel
+type ChannelFetcher interface {
+	FetchChannelUpdateInfo(channel string) (*updates.UpdateInfo, error)
+}
+
+// NewUpdateNoticeCommand creates the hidden command the bootstrap scripts call once per day.
+// It prints the updates.Notice line if a newer devrig than the one pinned in devrig.yaml is released,
+// and nothing otherwise. It never fails, a missing update notice must not break the wrapped command
+func NewUpdateNoticeCommand(configs func() configservice.ConfigService, fetcher ChannelFetcher) *cobra.Command {
+	return &cobra.Command{
+		Use:    "update-notice",
+		Short:  "Print the machine-readable update notice for the bootstrap scripts",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cache, err := updates.DefaultMetadataCache()
+			if err != nil {
+				logging.FromContext(cmd.Context()).Debug("failed to resolve the metadata cache", "error", err)
+				return nil
+			}
+			if notice := resolveNotice(cmd.Context(), configs(), fetcher, cache, time.Now()); notice != nil {
+				_, _ = fmt.Fprintln(cmd.OutOrStdout(), notice)
+			}
+			return nil
+		},
+	}
+}
+
+// resolveNotice compares the pinned version with the latest release of the channel from devrig.yaml.
+// The cached release metadata is used while it is fresh, or in offline mode
+func resolveNotice(ctx context.Context, service configservice.ConfigService, fetcher ChannelFetcher, cache *updates.MetadataCache, now time.Time) *updates.Notice {
+	logger := logging.FromContext(ctx)
+
+	section, err := service.Binaries().ReadDevrigSection()
+	if err != nil || section.Version == "" {
+		return nil
+	}
+	channel := section.Channel
+	if channel == "" {
+		channel = configservice.ChannelStable
+	}
+
+	info, fetchedAt, err := cache.Load(channel)
+	if err != nil {
+		logger.Debug("failed to read the cached release metadata", "error", err)
+	}
+	if (info == nil || now.Sub(fetchedAt) > noticeMaxAge) && !offline.Enabled() {
+		if latest, err := fetcher.FetchChannelUpdateInfo(channel); err != nil {
+			logger.Debug("failed to fetch release metadata", "channel", channel, "error", err)
+		} else {
+			info = latest
+			if err := cache.Save(channel, latest, now); err != nil {
+				logger.Debug("failed to cache release metadata", "error", err)
+			}
+		}
+	}
+	if info == nil || info.Version == "" {
+		return nil
+	}
+
+	current := strings.TrimPrefix(section.Version, "v")
+	latest := strings.TrimPrefix(info.Version, "v")
+	// A pinned pre-release or a withdrawn latest release is newer than the channel, not outdated.
+	// Versions that are not semantic versions are outdated when they differ
+	order, err := version.OrderOf(current, latest)
+	if err != nil && current != latest {
+		order = version.Older
+	}
+	if order != version.Older {
+		return nil
+	}
+	return &updates.Notice{Current: current, Latest: latest, Channel: channel}
+}
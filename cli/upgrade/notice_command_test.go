@@ -0,0 +1,93 @@
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/offline"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// mockChannelFetcher counts the requests and returns the prepared release
+type mockChannelFetcher struct {
+	requests int
+	info     *updates.UpdateInfo
+	err      error
+}
+
+func (m *mockChannelFetcher) FetchChannelUpdateInfo(channel string) (*updates.UpdateInfo, error) {
+	m.requests++
+	return m.info, m.err
+}
+
+func newNoticeTestService(t *testing.T) configservice.ConfigService {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte(testConfig), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+	return configservice.NewConfigService(configPath)
+}
+
+func TestResolveNotice(t *testing.T) {
+	service := newNoticeTestService(t)
+	cache := &updates.MetadataCache{Dir: t.TempDir()}
+	fetcher := &mockChannelFetcher{info: newTestRelease()}
+	now := time.Now()
+
+	notice := resolveNotice(context.Background(), service, fetcher, cache, now)
+	expected := updates.Notice{Current: "0.79.5", Latest: "0.79.6", Channel: "stable"}
+	if notice == nil || *notice != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, notice)
+	}
+
+	// The fresh cache is used, the network is not accessed again the same day
+	if notice := resolveNotice(context.Background(), service, fetcher, cache, now.Add(time.Hour)); notice == nil || fetcher.requests != 1 {
+		t.Errorf("Expected the cached metadata to be used, got %+v after %d requests", notice, fetcher.requests)
+	}
+	if resolveNotice(context.Background(), service, fetcher, cache, now.Add(25*time.Hour)); fetcher.requests != 2 {
+		t.Errorf("Expected outdated metadata to be fetched again, got %d requests", fetcher.requests)
+	}
+}
+
+func TestResolveNotice_UpToDate(t *testing.T) {
+	release := newTestRelease()
+	release.Version = "v0.79.5"
+	fetcher := &mockChannelFetcher{info: release}
+
+	notice := resolveNotice(context.Background(), newNoticeTestService(t), fetcher, &updates.MetadataCache{Dir: t.TempDir()}, time.Now())
+	if notice != nil {
+		t.Errorf("Expected no notice for the pinned latest release, got %+v", notice)
+	}
+
+	release.Version = "0.79.0"
+	notice = resolveNotice(context.Background(), newNoticeTestService(t), fetcher, &updates.MetadataCache{Dir: t.TempDir()}, time.Now())
+	if notice != nil {
+		t.Errorf("Expected no notice for a pinned version newer than the channel, got %+v", notice)
+	}
+}
+
+func TestResolveNotice_OfflineAndErrors(t *testing.T) {
+	service := newNoticeTestService(t)
+	cache := &updates.MetadataCache{Dir: t.TempDir()}
+
+	fetcher := &mockChannelFetcher{err: fmt.Errorf("network is down")}
+	if notice := resolveNotice(context.Background(), service, fetcher, cache, time.Now()); notice != nil {
+		t.Errorf("Expected no notice without metadata, got %+v", notice)
+	}
+
+	t.Setenv(offline.EnvOffline, "1")
+	if err := cache.Save(configservice.ChannelStable, newTestRelease(), time.Now().Add(-48*time.Hour)); err != nil {
+		t.Fatalf("Failed to save metadata: %v", err)
+	}
+	fetcher = &mockChannelFetcher{info: newTestRelease()}
+	notice := resolveNotice(context.Background(), service, fetcher, cache, time.Now())
+	if notice == nil || fetcher.requests != 0 {
+		t.Errorf("Expected the cached metadata to be used offline, got %+v after %d requests", notice, fetcher.requests)
+	}
+}
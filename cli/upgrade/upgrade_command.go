@@ -0,0 +1,180 @@
+package upgrade
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"jonnyzzz.com/devrig.dev/configservice"
+	"jonnyzzz.com/devrig.dev/logging"
+	"jonnyzzz.com/devrig.dev/summary"
+	"jonnyzzz.com/devrig.dev/trial"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+// ReleaseFetcher downloads and verifies the release metadata
+type ReleaseFetcher interface {
+	FetchUpdateInfo(url string) (*updates.UpdateInfo, error)
+}
+
+// NewUpgradeCommand creates the upgrade command that bumps the devrig version pinned in devrig.yaml
+func NewUpgradeCommand(configPath func() string, fetcher ReleaseFetcher) *cobra.Command {
+	var version string
+	var channel string
+	var dryRun bool
+	var trialRuns int
+
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Update the devrig version pinned in devrig.yaml",
+		Long: `Update the devrig binaries pinned in the devrig section of devrig.yaml.
+
+The release metadata is downloaded and its signature is verified, then the
+URLs and SHA512 hashes for all platforms are rewritten. The running binary
+is not changed, the bootstrap scripts pick up the new version on the next run.
+
+Examples:
+  devrig upgrade
+  devrig upgrade --version 0.79.6
+  devrig upgrade --channel beta --dry-run
+  devrig upgrade --trial 5
+
+The release channel (stable, beta or nightly) is stored in devrig.yaml,
+next upgrades and update checks follow it.
+
+With --trial, the first runs of the new binary are recorded in .devrig/trial.json.
+devrig.yaml is reverted to the previous version when all of the runs fail
+or crash, the trial ends after the runs or in a week.
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if version != "" && channel != "" {
+				return fmt.Errorf("--version and --channel cannot be used together")
+			}
+			if trialRuns < 0 {
+				return fmt.Errorf("--trial must not be negative, got %d", trialRuns)
+			}
+
+			var steps summary.Summary
+			err := upgradePinnedVersion(cmd, configPath(), fetcher, version, channel, dryRun, nil, trialRuns, &steps)
+			steps.Print(cmd.Context(), cmd.OutOrStdout())
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&version, "version", "", "Release to pin, e.g. 0.79.6 (default is the latest release)")
+	cmd.Flags().StringVar(&channel, "channel", "", "Release channel to switch to: stable, beta or nightly")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the changes")
+	cmd.Flags().IntVar(&trialRuns, "trial", 0, "Revert devrig.yaml when the new binary fails or crashes in all of its first runs")
+	return cmd
+}
+
+// UpgradeConfirmed prints the changes of the upgrade to the latest release of the channel of devrig.yaml and
+// writes them once the user confirms, it is the upgrade action of the dashboard
+func UpgradeConfirmed(cmd *cobra.Command, configPath string, fetcher ReleaseFetcher) error {
+	confirm := func() bool {
+		cmd.Print("Update devrig.yaml? [y/N] ")
+		answer, _ := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes"
+	}
+	var steps summary.Summary
+	err := upgradePinnedVersion(cmd, configPath, fetcher, "", "", false, confirm, 0, &steps)
+	steps.Print(cmd.Context(), cmd.OutOrStdout())
+	return err
+}
+
+// upgradePinnedVersion executes the upgrade steps and records them in the summary,
+// confirm is asked before devrig.yaml is changed unless it is nil
+func upgradePinnedVersion(cmd *cobra.Command, configPath string, fetcher ReleaseFetcher, version string, channel string, dryRun bool, confirm func() bool, trialRuns int, steps *summary.Summary) error {
+	service := configservice.NewConfigService(configPath)
+	var current *configservice.DevrigSection
+	err := steps.Run("Read devrig.yaml", true, func() error {
+		var err error
+		current, err = service.Binaries().ReadDevrigSection()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Stay on the channel from devrig.yaml unless another one is requested
+	targetChannel := current.Channel
+	if channel != "" {
+		if err := configservice.ValidateChannel(channel); err != nil {
+			return err
+		}
+		targetChannel = channel
+	}
+	if targetChannel == configservice.ChannelStable {
+		targetChannel = ""
+	}
+
+	endpoints, err := updates.ResolveEndpoints(service)
+	if err != nil {
+		return err
+	}
+	url := endpoints.ChannelJSONURL(targetChannel)
+	if version != "" {
+		url = endpoints.ReleaseJSONURL(version)
+	}
+	logging.FromContext(cmd.Context()).Debug("fetching release metadata", "url", url)
+
+	var updateInfo *updates.UpdateInfo
+	err = steps.Run("Fetch release metadata", true, func() error {
+		var err error
+		if updateInfo, err = fetcher.FetchUpdateInfo(url); err != nil {
+			return fmt.Errorf("failed to fetch release metadata: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	target := updateInfo.ToDevrigSection()
+	target.Channel = targetChannel
+	changes := DiffSections(current, target)
+	if len(changes) == 0 {
+		cmd.Printf("devrig.yaml already pins devrig %s\n", target.Version)
+		steps.Skip("Update devrig.yaml", "already up to date")
+		return nil
+	}
+
+	for _, change := range changes {
+		cmd.Println(change)
+	}
+
+	if dryRun {
+		cmd.Println("Dry run: devrig.yaml is not changed")
+		steps.Skip("Update devrig.yaml", "--dry-run")
+		return nil
+	}
+	if confirm != nil && !confirm() {
+		cmd.Println("Aborted, devrig.yaml is not changed")
+		steps.Skip("Update devrig.yaml", "not confirmed")
+		return nil
+	}
+
+	err = steps.Run("Update devrig.yaml", true, func() error {
+		if err := service.Binaries().UpdateBinaries(target); err != nil {
+			return fmt.Errorf("failed to update devrig.yaml: %w", err)
+		}
+		cmd.Printf("Upgraded devrig.yaml to devrig %s\n", target.Version)
+		return nil
+	})
+	if err != nil || trialRuns == 0 {
+		return err
+	}
+
+	return steps.Run("Start the trial", true, func() error {
+		if err := trial.Start(cmd.Context(), configPath, target.Version, current, trialRuns, time.Now()); err != nil {
+			return fmt.Errorf("failed to start the trial of devrig %s: %w", target.Version, err)
+		}
+		cmd.Printf("devrig %s is on trial for %d runs, devrig.yaml is reverted to %s if all of them fail or crash\n",
+			target.Version, trialRuns, current.Version)
+		return nil
+	})
+}
@@ -0,0 +1,147 @@
+package upgrade
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"jonnyzzz.com/devrig.dev/trial"
+	"jonnyzzz.com/devrig.dev/updates"
+)
+
+const testConfig = `# devrig.yaml - project comment
+devrig:
+  version: 0.79.5
+  binaries:
+    linux-x86_64:
+      url: https://example.com/v0.79.5/devrig-linux-x86_64
+      sha512: 11111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111111
+    windows-x86_64:
+      url: https://example.com/v0.79.5/devrig-windows-x86_64.exe
+      sha512: 22222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222222
+`
+
+// mockFetcher records the requested URL and returns the prepared release
+type mockFetcher struct {
+	requestedURL string
+	info         *updates.UpdateInfo
+}
+
+func (m *mockFetcher) FetchUpdateInfo(url string) (*updates.UpdateInfo, error) {
+	m.requestedURL = url
+	return m.info, nil
+}
+
+func newTestRelease() *updates.UpdateInfo {
+	return &updates.UpdateInfo{
+		Version: "0.79.6",
+		Binaries: []updates.BinaryInfo{
+			{OS: "linux", Arch: "x86_64", URL: "https://example.com/v0.79.6/devrig-linux-x86_64", SHA512: strings.Repeat("3", 128)},
+			{OS: "darwin", Arch: "arm64", URL: "https://example.com/v0.79.6/devrig-darwin-arm64", SHA512: strings.Repeat("4", 128)},
+		},
+	}
+}
+
+func runUpgrade(t *testing.T, fetcher *mockFetcher, args ...string) (string, string) {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte(testConfig), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+
+	cmd := NewUpgradeCommand(func() string { return configPath }, fetcher)
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(args)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Failed to run upgrade: %v", err)
+	}
+
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read devrig.yaml: %v", err)
+	}
+	return out.String(), string(content)
+}
+
+func TestUpgradeCommand_RewritesBinaries(t *testing.T) {
+	fetcher := &mockFetcher{info: newTestRelease()}
+	output, content := runUpgrade(t, fetcher, "--version", "v0.79.6")
+
+	if fetcher.requestedURL != "https://devrig.dev/download/v0.79.6/release.json" {
+		t.Errorf("Unexpected metadata URL: %s", fetcher.requestedURL)
+	}
+
+	for _, expected := range []string{"version: 0.79.5 -> 0.79.6", "+ darwin-arm64", "- windows-x86_64", "~ linux-x86_64"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in the output:\n%s", expected, output)
+		}
+	}
+
+	if !strings.Contains(content, "# devrig.yaml - project comment") {
+		t.Errorf("Expected comments to be preserved:\n%s", content)
+	}
+	if !strings.Contains(content, "v0.79.6/devrig-darwin-arm64") || strings.Contains(content, "windows-x86_64") {
+		t.Errorf("Expected binaries to be replaced:\n%s", content)
+	}
+}
+
+func TestUpgradeCommand_DryRun(t *testing.T) {
+	fetcher := &mockFetcher{info: newTestRelease()}
+	output, content := runUpgrade(t, fetcher, "--channel", "beta", "--dry-run")
+
+	if fetcher.requestedURL != "https://devrig.dev/download/latest-beta.json" {
+		t.Errorf("Unexpected metadata URL: %s", fetcher.requestedURL)
+	}
+	if !strings.Contains(output, "channel: stable -> beta") {
+		t.Errorf("Expected channel change in the output:\n%s", output)
+	}
+	if !strings.Contains(output, "Dry run") {
+		t.Errorf("Expected dry run message:\n%s", output)
+	}
+	if content != testConfig {
+		t.Errorf("Expected devrig.yaml to be unchanged:\n%s", content)
+	}
+}
+
+func TestUpgradeCommand_StoresChannel(t *testing.T) {
+	fetcher := &mockFetcher{info: newTestRelease()}
+	_, content := runUpgrade(t, fetcher, "--channel", "nightly")
+
+	if !strings.Contains(content, "channel: nightly") {
+		t.Fatalf("Expected channel to be stored in devrig.yaml:\n%s", content)
+	}
+	if fetcher.requestedURL != "https://devrig.dev/download/latest-nightly.json" {
+		t.Errorf("Unexpected metadata URL: %s", fetcher.requestedURL)
+	}
+}
+
+func TestUpgradeCommand_StartsTrial(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "devrig.yaml")
+	if err := os.WriteFile(configPath, []byte(testConfig), 0644); err != nil {
+		t.Fatalf("Failed to write devrig.yaml: %v", err)
+	}
+
+	cmd := NewUpgradeCommand(func() string { return configPath }, &mockFetcher{info: newTestRelease()})
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs([]string{"--version", "0.79.6", "--trial", "3"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Failed to run upgrade: %v", err)
+	}
+
+	state, err := trial.Load(configPath)
+	if err != nil || state == nil {
+		t.Fatalf("Expected the trial to start, got %v, %v", state, err)
+	}
+	if state.Version != "0.79.6" || state.Runs != 3 || state.Previous.Version != "0.79.5" {
+		t.Errorf("Unexpected trial: %+v", state)
+	}
+	if !strings.Contains(out.String(), "on trial for 3 runs") {
+		t.Errorf("Expected the trial to be reported:\n%s", out.String())
+	}
+}
@@ -0,0 +1,136 @@
+// Package version parses and compares the semantic versions of devrig releases
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Compare compares two semantic versions like 0.80.0, v1.2.3 or 1.0.0-rc.1 by the precedence of semver.org,
+// the build metadata after + is ignored. It returns a negative number when a is lower than b
+func Compare(a string, b string) (int, error) {
+	versionA, err := Parse(a)
+	if err != nil {
+		return 0, err
+	}
+	versionB, err := Parse(b)
+	if err != nil {
+		return 0, err
+	}
+	return versionA.Compare(versionB), nil
+}
+
+// Order is the order of a version against another one
+type Order int
+
+const (
+	Older Order = -1
+	Equal Order = 0
+	Newer Order = 1
+)
+
+// String returns older, equal or newer
+func (o Order) String() string {
+	switch o {
+	case Older:
+		return "older"
+	case Newer:
+		return "newer"
+	default:
+		return "equal"
+	}
+}
+
+// OrderOf tells if the version a is older, equal or newer than b
+func OrderOf(a string, b string) (Order, error) {
+	order, err := Compare(a, b)
+	if err != nil {
+		return Equal, err
+	}
+	switch {
+	case order < 0:
+		return Older, nil
+	case order > 0:
+		return Newer, nil
+	default:
+		return Equal, nil
+	}
+}
+
+// Version is a parsed semantic version
+type Version struct {
+	core       [3]int
+	prerelease []string
+}
+
+// Compare returns a negative number when the version is lower than the other one
+func (v Version) Compare(other Version) int {
+	for i := range v.core {
+		if v.core[i] != other.core[i] {
+			return v.core[i] - other.core[i]
+		}
+	}
+	return comparePrerelease(v.prerelease, other.prerelease)
+}
+
+// String formats the version without the v prefix and the build metadata
+func (v Version) String() string {
+	text := fmt.Sprintf("%d.%d.%d", v.core[0], v.core[1], v.core[2])
+	if len(v.prerelease) > 0 {
+		text += "-" + strings.Join(v.prerelease, ".")
+	}
+	return text
+}
+
+// Parse reads a semantic version, the v prefix and the build metadata are optional
+func Parse(text string) (Version, error) {
+	var version Version
+	core, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(text), "v"), "+")
+	core, prerelease, hasPrerelease := strings.Cut(core, "-")
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return version, fmt.Errorf("invalid version %q, expected MAJOR.MINOR.PATCH", text)
+	}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return version, fmt.Errorf("invalid version %q, expected MAJOR.MINOR.PATCH", text)
+		}
+		version.core[i] = number
+	}
+	if hasPrerelease {
+		version.prerelease = strings.Split(prerelease, ".")
+	}
+	return version, nil
+}
+
+// comparePrerelease orders the pre-release identifiers, a release is higher than its pre-releases
+func comparePrerelease(a []string, b []string) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+	for i := 0; i < len(a) && i < len(b); i++ {
+		numberA, errA := strconv.Atoi(a[i])
+		numberB, errB := strconv.Atoi(b[i])
+		switch {
+		case errA == nil && errB == nil:
+			if numberA != numberB {
+				return numberA - numberB
+			}
+		case errA == nil:
+			// Numeric identifiers are lower than alphanumeric ones
+			return -1
+		case errB == nil:
+			return 1
+		case a[i] != b[i]:
+			return strings.Compare(a[i], b[i])
+		}
+	}
+	return len(a) - len(b)
+}
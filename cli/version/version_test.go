@@ -0,0 +1,49 @@
+package version
+
+import "testing"
+
+func TestCompare(t *testing.T) {
+	ordered := []string{"0.9.0", "0.79.6", "0.80.0-alpha", "0.80.0-alpha.1", "0.80.0-alpha.beta", "0.80.0-beta.2", "0.80.0-beta.11", "0.80.0-rc.1", "0.80.0", "v1.0.0"}
+	for i := range ordered {
+		for j := range ordered {
+			order, err := Compare(ordered[i], ordered[j])
+			if err != nil {
+				t.Fatalf("Failed to compare %s and %s: %v", ordered[i], ordered[j], err)
+			}
+			if (order < 0) != (i < j) || (order == 0) != (i == j) {
+				t.Errorf("Unexpected order of %s and %s: %d", ordered[i], ordered[j], order)
+			}
+		}
+	}
+
+	if order, err := Compare("0.80.0+build.5", "0.80.0"); err != nil || order != 0 {
+		t.Errorf("Expected the build metadata to be ignored, got %d: %v", order, err)
+	}
+	for _, invalid := range []string{"", "1.0", "1.0.x", "latest"} {
+		if _, err := Compare(invalid, "1.0.0"); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestOrderOf(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected Order
+	}{
+		{"0.79.6", "0.80.0", Older},
+		{"v0.80.0", "0.80.0", Equal},
+		{"0.80.0", "0.80.0-rc.1", Newer},
+		{"1.0.0-SNAPSHOT", "0.80.0", Newer},
+	} {
+		order, err := OrderOf(test.a, test.b)
+		if err != nil || order != test.expected {
+			t.Errorf("Expected %s to be %s than %s, got %s: %v", test.a, test.expected, test.b, order, err)
+		}
+	}
+
+	parsed, err := Parse("v0.80.0-rc.1+build.5")
+	if err != nil || parsed.String() != "0.80.0-rc.1" {
+		t.Errorf("Unexpected parsed version %s: %v", parsed, err)
+	}
+}